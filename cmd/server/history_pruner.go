@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"time"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// startHistoryPruner prunes the profile trait history and purges the expired idempotency keys every configured
+// interval until the returned function is called. The history is not pruned when it is bounded neither by age nor
+// by count.
+func startHistoryPruner(cfg config.ProfileHistoryConfig,
+	prune func(maxAge time.Duration, maxSnapshots int) (int64, error),
+	purgeIdempotencyKeys func() (int64, error)) (stop func()) {
+
+	pruneHistory := cfg.MaxAge > 0 || cfg.MaxSnapshots > 0
+	interval := cfg.PruneInterval
+	if interval <= 0 {
+		interval = constants.DefaultProfileHistoryPruneInterval
+	}
+
+	stopping := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopping:
+				return
+			case <-ticker.C:
+				if pruneHistory {
+					if _, err := prune(cfg.MaxAge, cfg.MaxSnapshots); err != nil {
+						log.GetLogger().Error("Failed to prune the profile history.", log.Error(err))
+					}
+				}
+				if _, err := purgeIdempotencyKeys(); err != nil {
+					log.GetLogger().Error("Failed to purge the expired idempotency keys.", log.Error(err))
+				}
+			}
+		}
+	}()
+	return func() {
+		close(stopping)
+		<-done
+	}
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"time"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// startJobResumer resumes the profile jobs left running by stopped instances, at startup and then periodically,
+// until the returned function is called.
+func startJobResumer(resume func() (int, error)) (stop func()) {
+
+	resumeJobs := func() {
+		if _, err := resume(); err != nil {
+			log.GetLogger().Error("Failed to resume the stale profile jobs.", log.Error(err))
+		}
+	}
+
+	stopping := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resumeJobs()
+		ticker := time.NewTicker(constants.ProfileJobResumeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopping:
+				return
+			case <-ticker.C:
+				resumeJobs()
+			}
+		}
+	}()
+	return func() {
+		close(stopping)
+		<-done
+	}
+}
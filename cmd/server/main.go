@@ -23,6 +23,7 @@ import (
 	"crypto/tls"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -32,12 +33,22 @@ import (
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	healthCheckService "github.com/wso2/identity-customer-data-service/internal/health_check/service"
+	profileProvider "github.com/wso2/identity-customer-data-service/internal/profile/provider"
+	"github.com/wso2/identity-customer-data-service/internal/system/changestream"
+	_ "github.com/wso2/identity-customer-data-service/internal/system/changestream/kafka" // registers the Kafka change stream publisher
 	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
 	"github.com/wso2/identity-customer-data-service/internal/system/log"
 	"github.com/wso2/identity-customer-data-service/internal/system/managers"
+	"github.com/wso2/identity-customer-data-service/internal/system/metrics"
 	_ "github.com/wso2/identity-customer-data-service/internal/system/queue/activemq" // registers the ActiveMQ queue provider
+	"github.com/wso2/identity-customer-data-service/internal/system/shutdown"
+	"github.com/wso2/identity-customer-data-service/internal/system/tracing"
 	"github.com/wso2/identity-customer-data-service/internal/system/utils"
 	"github.com/wso2/identity-customer-data-service/internal/system/workers"
+	webhookService "github.com/wso2/identity-customer-data-service/internal/webhook/service"
 )
 
 func initDatabaseFromConfig(config *config.Config) {
@@ -90,6 +101,22 @@ func main() {
 
 	// Initialize database
 	initDatabaseFromConfig(cdsConfig)
+	if cdsConfig.DataSource.EnsureIndexes {
+		go func() {
+			if err := provider.EnsureRecommendedIndexes(context.Background()); err != nil {
+				log.GetLogger().Warn("Failed to ensure the recommended database indexes", log.Error(err))
+				return
+			}
+			log.GetLogger().Info("Recommended database indexes are in place")
+		}()
+	}
+
+	// Initialize tracing; spans are exported only when an OTLP endpoint is configured
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		fmt.Println("Failed to initialize tracing.", err)
+		os.Exit(1)
+	}
 
 	// Initialize Profile worker
 	if err := workers.StartProfileWorker(); err != nil {
@@ -103,8 +130,19 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Initialize the profile change stream; changes are published only when a publisher is configured
+	profilesService := profileProvider.NewProfilesProvider().GetProfilesService()
+	if err := changestream.Start(cdsConfig.ChangeStream, profilesService.GetProfileForChangeStream); err != nil {
+		fmt.Println("Failed to start profile change stream.", err)
+		os.Exit(1)
+	}
+	stopHistoryPruner := startHistoryPruner(cdsConfig.ProfileHistory, profilesService.PruneProfileHistory,
+		profilesService.PurgeExpiredIdempotencyKeys)
+	stopJobResumer := startJobResumer(profilesService.ResumeProfileJobs)
+
 	serverAddr := fmt.Sprintf("%s:%d", cdsConfig.Addr.Host, cdsConfig.Addr.Port)
-	mux := enableCORS(initMultiplexer())
+	tracker := &shutdown.RequestTracker{}
+	mux := tracker.Track(enableCORS(tracing.InstrumentHandler(metrics.InstrumentHandler(initMultiplexer()))))
 
 	logger := log.GetLogger()
 	logger.Info(fmt.Sprintf("WSO2 CDS starting securely on: https://%s", serverAddr))
@@ -140,9 +178,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Requests derive their context from requestCtx so that those outliving the shutdown timeout can be cancelled.
+	requestCtx, abortRequests := context.WithCancel(context.Background())
+	defer abortRequests()
+
 	server := &http.Server{
 		Addr:    serverAddr,
 		Handler: mux,
+		BaseContext: func(net.Listener) context.Context {
+			return requestCtx
+		},
 		// explicit TLS settings and HTTP timeouts
 		ReadTimeout:       15 * time.Second,
 		WriteTimeout:      15 * time.Second,
@@ -170,19 +215,55 @@ func main() {
 	<-quit
 	logger.Info("Shutdown signal received, draining connections...")
 
-	// Give in-flight requests up to 15 seconds to complete
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(ctx); err != nil {
+	shutdownTimeout := cdsConfig.Addr.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = constants.DefaultShutdownTimeout
+	}
+	readinessDelay := cdsConfig.Addr.ShutdownReadinessDelay
+	if readinessDelay <= 0 {
+		readinessDelay = constants.DefaultShutdownReadinessDelay
+	}
+	returned, err := shutdown.Drainer{
+		Server:           server,
+		Tracker:          tracker,
+		MarkNotReady:     healthCheckService.MarkShuttingDown,
+		AbortRequests:    abortRequests,
+		ReadinessDelay:   readinessDelay,
+		Timeout:          shutdownTimeout,
+		AbortGracePeriod: constants.ShutdownAbortGracePeriod,
+	}.Drain()
+	if err != nil {
 		logger.Error("HTTP server shutdown error.", log.Error(err))
 	}
+	if !returned {
+		logger.Warn("Some requests were still running when the server shut down.")
+	}
 	if err := workers.StopProfileWorker(); err != nil {
 		logger.Error("Failed to stop profile worker.", log.Error(err))
 	}
 	if err := workers.StopSchemaSyncWorker(); err != nil {
 		logger.Error("Failed to stop schema sync worker.", log.Error(err))
 	}
+	stopHistoryPruner()
+	stopJobResumer()
+	// Queued webhook events look up their webhooks in the database, so they are delivered before it is closed
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), constants.WebhookDrainTimeout)
+	defer cancelDrain()
+	if err := webhookService.StopDispatcher(drainCtx); err != nil {
+		logger.Warn("Some webhook events were not delivered before the server shut down.", log.Error(err))
+	}
+	// Queued profile changes read their snapshots from the database, so they are published before it is closed
+	if err := changestream.Stop(); err != nil {
+		logger.Error("Failed to stop profile change stream.", log.Error(err))
+	}
+	if err := provider.CloseDB(); err != nil {
+		logger.Error("Failed to close database connection pool.", log.Error(err))
+	}
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), constants.ShutdownAbortGracePeriod)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Error("Failed to flush traces.", log.Error(err))
+	}
 
 	logger.Info("Shutdown complete")
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/wso2/identity-customer-data-service/internal/admin_config/provider"
 	"github.com/wso2/identity-customer-data-service/internal/system/constants"
 	"github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/ratelimit"
 	"github.com/wso2/identity-customer-data-service/internal/system/security"
 	"github.com/wso2/identity-customer-data-service/internal/system/utils"
 
@@ -42,7 +43,9 @@ func NewAdminConfigHandler() *AdminConfigHandler {
 // GetAdminConfig handles GET /admin/configs
 func (h *AdminConfigHandler) GetAdminConfig(w http.ResponseWriter, r *http.Request) {
 
-	if err := security.AuthnAndAuthz(r, "admin_config:view"); err != nil {
+	r, err := security.AuthnAndAuthz(r, "admin_config:view")
+
+	if err != nil {
 		utils.HandleError(w, err)
 		return
 	}
@@ -66,7 +69,9 @@ func (h *AdminConfigHandler) GetAdminConfig(w http.ResponseWriter, r *http.Reque
 // UpdateAdminConfig handles PATCH /admin/configs
 func (h *AdminConfigHandler) UpdateAdminConfig(w http.ResponseWriter, r *http.Request) {
 
-	if err := security.AuthnAndAuthz(r, "admin_config:update"); err != nil {
+	r, err := security.AuthnAndAuthz(r, "admin_config:update")
+
+	if err != nil {
 		utils.HandleError(w, err)
 		return
 	}
@@ -120,3 +125,16 @@ func (h *AdminConfigHandler) UpdateAdminConfig(w http.ResponseWriter, r *http.Re
 	}
 	utils.RespondJSON(w, http.StatusOK, resp, constants.AdminConfigResource)
 }
+
+// GetRateLimitUsage handles GET /rate-limits/usage
+func (h *AdminConfigHandler) GetRateLimitUsage(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "admin_config:view")
+
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	utils.RespondJSON(w, http.StatusOK, ratelimit.GetUsage(orgHandle), constants.RateLimitResource)
+}
@@ -38,13 +38,14 @@ func NewConsentCategoryHandler() *ConsentCategoryHandler {
 // GetAllConsentCategories handles GET /consent-categories
 func (h *ConsentCategoryHandler) GetAllConsentCategories(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "consent_category:view")
+	r, err := security.AuthnAndAuthz(r, "consent_category:view")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
 	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
 	service := provider.NewConsentCategoryProvider().GetConsentCategoryService()
-	categories, err := service.GetAllConsentCategories()
+	categories, err := service.GetAllConsentCategories(orgHandle)
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -56,7 +57,7 @@ func (h *ConsentCategoryHandler) GetAllConsentCategories(w http.ResponseWriter,
 // AddConsentCategory handles POST /consent-categories
 func (h *ConsentCategoryHandler) AddConsentCategory(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "consent_category:create")
+	r, err := security.AuthnAndAuthz(r, "consent_category:create")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -91,7 +92,7 @@ func (h *ConsentCategoryHandler) AddConsentCategory(w http.ResponseWriter, r *ht
 // GetConsentCategory handles GET /consent-categories/{id}
 func (h *ConsentCategoryHandler) GetConsentCategory(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "consent_category:view")
+	r, err := security.AuthnAndAuthz(r, "consent_category:view")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -108,8 +109,9 @@ func (h *ConsentCategoryHandler) GetConsentCategory(w http.ResponseWriter, r *ht
 		return
 	}
 
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
 	service := provider.NewConsentCategoryProvider().GetConsentCategoryService()
-	category, err := service.GetConsentCategory(categoryId)
+	category, err := service.GetConsentCategory(categoryId, orgHandle)
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -122,7 +124,7 @@ func (h *ConsentCategoryHandler) GetConsentCategory(w http.ResponseWriter, r *ht
 // UpdateConsentCategory handles PUT /consent-categories/{id}
 func (h *ConsentCategoryHandler) UpdateConsentCategory(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "consent_category:update")
+	r, err := security.AuthnAndAuthz(r, "consent_category:update")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -150,6 +152,10 @@ func (h *ConsentCategoryHandler) UpdateConsentCategory(w http.ResponseWriter, r
 		return
 	}
 
+	// The category is identified by the path and can only be updated within the organization of the request.
+	category.CategoryIdentifier = categoryId
+	category.OrgHandle = utils.ExtractOrgHandleFromPath(r)
+
 	service := provider.NewConsentCategoryProvider().GetConsentCategoryService()
 	if err := service.UpdateConsentCategory(category); err != nil {
 		utils.HandleError(w, err)
@@ -164,7 +170,7 @@ func (h *ConsentCategoryHandler) UpdateConsentCategory(w http.ResponseWriter, r
 // DeleteConsentCategory handles Delete /consent-categories/{id}
 func (h *ConsentCategoryHandler) DeleteConsentCategory(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "consent_category:delete")
+	r, err := security.AuthnAndAuthz(r, "consent_category:delete")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -180,8 +186,9 @@ func (h *ConsentCategoryHandler) DeleteConsentCategory(w http.ResponseWriter, r
 		return
 	}
 
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
 	service := provider.NewConsentCategoryProvider().GetConsentCategoryService()
-	if err := service.DeleteConsentCategory(categoryId); err != nil {
+	if err := service.DeleteConsentCategory(categoryId, orgHandle); err != nil {
 		utils.HandleError(w, err)
 		return
 	}
@@ -30,11 +30,11 @@ import (
 
 // ConsentCategoryServiceInterface defines the service interface.
 type ConsentCategoryServiceInterface interface {
-	GetAllConsentCategories() ([]model.ConsentCategory, error)
-	GetConsentCategory(id string) (*model.ConsentCategory, error)
+	GetAllConsentCategories(orgHandle string) ([]model.ConsentCategory, error)
+	GetConsentCategory(id, orgHandle string) (*model.ConsentCategory, error)
 	AddConsentCategory(category model.ConsentCategory) (*model.ConsentCategory, error)
 	UpdateConsentCategory(category model.ConsentCategory) error
-	DeleteConsentCategory(id string) error
+	DeleteConsentCategory(id, orgHandle string) error
 }
 
 // ConsentCategoryService is the default implementation.
@@ -45,10 +45,10 @@ func GetConsentCategoryService() ConsentCategoryServiceInterface {
 	return &ConsentCategoryService{}
 }
 
-// GetAllConsentCategories retrieves all categories.
-func (cs *ConsentCategoryService) GetAllConsentCategories() ([]model.ConsentCategory, error) {
+// GetAllConsentCategories retrieves all categories of the organization.
+func (cs *ConsentCategoryService) GetAllConsentCategories(orgHandle string) ([]model.ConsentCategory, error) {
 
-	consentCat, err := store.GetAllConsentCategories()
+	consentCat, err := store.GetAllConsentCategories(orgHandle)
 
 	if err != nil {
 		return nil, err
@@ -60,10 +60,11 @@ func (cs *ConsentCategoryService) GetAllConsentCategories() ([]model.ConsentCate
 
 }
 
-// GetConsentCategory retrieves a category by ID.
-func (cs *ConsentCategoryService) GetConsentCategory(id string) (*model.ConsentCategory, error) {
+// GetConsentCategory retrieves a category of the organization by ID. Categories of other organizations are
+// reported as not found.
+func (cs *ConsentCategoryService) GetConsentCategory(id, orgHandle string) (*model.ConsentCategory, error) {
 
-	consentCat, err := store.GetConsentCategoryByID(id)
+	consentCat, err := store.GetConsentCategoryByID(id, orgHandle)
 	if err != nil {
 		return nil, err
 	}
@@ -86,7 +87,7 @@ func (cs *ConsentCategoryService) AddConsentCategory(category model.ConsentCateg
 		return nil, err
 	}
 
-	existingCat, err := store.GetConsentCategoryByName(category.CategoryName)
+	existingCat, err := store.GetConsentCategoryByName(category.CategoryName, category.OrgHandle)
 
 	if err != nil {
 		return nil, err
@@ -140,7 +141,7 @@ func (cs *ConsentCategoryService) validateConsentCat(category model.ConsentCateg
 	return nil, true
 }
 
-// UpdateConsentCategory updates an existing category.
+// UpdateConsentCategory updates an existing category of the organization the category belongs to.
 func (cs *ConsentCategoryService) UpdateConsentCategory(category model.ConsentCategory) error {
 
 	if category.CategoryIdentifier == "" {
@@ -150,11 +151,23 @@ func (cs *ConsentCategoryService) UpdateConsentCategory(category model.ConsentCa
 			Description: "Consent category ID is required for update.",
 		}, http.StatusBadRequest)
 	}
-	return store.UpdateConsentCategory(category)
+	updated, err := store.UpdateConsentCategory(category)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:    errors2.CONSENT_CAT_NOT_FOUND.Code,
+			Message: errors2.CONSENT_CAT_NOT_FOUND.Message,
+			Description: fmt.Sprintf("Consent category not found for the provided categoryId: %s",
+				category.CategoryIdentifier),
+		}, http.StatusNotFound)
+	}
+	return nil
 }
 
-// DeleteConsentCategory deletes an existing category.
-func (cs *ConsentCategoryService) DeleteConsentCategory(categoryId string) error {
+// DeleteConsentCategory deletes an existing category of the organization.
+func (cs *ConsentCategoryService) DeleteConsentCategory(categoryId, orgHandle string) error {
 	if categoryId == "" {
 		return errors2.NewClientError(errors2.ErrorMessage{
 			Code:        errors2.BAD_REQUEST.Code,
@@ -162,5 +175,5 @@ func (cs *ConsentCategoryService) DeleteConsentCategory(categoryId string) error
 			Description: "Consent category Id is required for update.",
 		}, http.StatusBadRequest)
 	}
-	return store.DeleteConsentCategory(categoryId)
+	return store.DeleteConsentCategory(categoryId, orgHandle)
 }
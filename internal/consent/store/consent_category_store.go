@@ -79,8 +79,8 @@ func AddConsentCategory(category model.ConsentCategory) error {
 	return tx.Commit()
 }
 
-// GetAllConsentCategories retrieves all consent categories from the database.
-func GetAllConsentCategories() ([]model.ConsentCategory, error) {
+// GetAllConsentCategories retrieves all consent categories of the organization from the database.
+func GetAllConsentCategories(orgHandle string) ([]model.ConsentCategory, error) {
 
 	dbClient, err := provider.NewDBProvider().GetDBClient()
 	logger := log.GetLogger()
@@ -96,7 +96,7 @@ func GetAllConsentCategories() ([]model.ConsentCategory, error) {
 	defer dbClient.Close()
 
 	query := scripts.GetAllConsentCategories[provider.NewDBProvider().GetDBType()]
-	results, err := dbClient.ExecuteQuery(query)
+	results, err := dbClient.ExecuteQuery(query, orgHandle)
 	if err != nil {
 		errorMsg := "Failed to execute query for fetching consent categories."
 		logger.Debug(errorMsg, log.Error(err))
@@ -125,8 +125,8 @@ func GetAllConsentCategories() ([]model.ConsentCategory, error) {
 	return categories, nil
 }
 
-// GetConsentCategoryByID retrieves a consent category by its ID.
-func GetConsentCategoryByID(id string) (*model.ConsentCategory, error) {
+// GetConsentCategoryByID retrieves a consent category of the organization by its ID.
+func GetConsentCategoryByID(id, orgHandle string) (*model.ConsentCategory, error) {
 
 	dbClient, err := provider.NewDBProvider().GetDBClient()
 	logger := log.GetLogger()
@@ -142,7 +142,7 @@ func GetConsentCategoryByID(id string) (*model.ConsentCategory, error) {
 	defer dbClient.Close()
 
 	query := scripts.GetConsentCategoryById[provider.NewDBProvider().GetDBType()]
-	results, err := dbClient.ExecuteQuery(query, id)
+	results, err := dbClient.ExecuteQuery(query, id, orgHandle)
 	if err != nil {
 		errorMsg := fmt.Sprintf("Failed to execute query for fetching consent category: %s", id)
 		logger.Debug(errorMsg, log.Error(err))
@@ -168,8 +168,8 @@ func GetConsentCategoryByID(id string) (*model.ConsentCategory, error) {
 	return &category, nil
 }
 
-// GetConsentCategoryByName retrieves a consent category by its ID.
-func GetConsentCategoryByName(name string) (*model.ConsentCategory, error) {
+// GetConsentCategoryByName retrieves a consent category of the organization by its name.
+func GetConsentCategoryByName(name, orgHandle string) (*model.ConsentCategory, error) {
 
 	dbClient, err := provider.NewDBProvider().GetDBClient()
 	logger := log.GetLogger()
@@ -185,7 +185,7 @@ func GetConsentCategoryByName(name string) (*model.ConsentCategory, error) {
 	defer dbClient.Close()
 
 	query := scripts.GetConsentCategoryByName[provider.NewDBProvider().GetDBType()]
-	results, err := dbClient.ExecuteQuery(query, name)
+	results, err := dbClient.ExecuteQuery(query, name, orgHandle)
 	if err != nil {
 		errorMsg := fmt.Sprintf("Failed to execute query for fetching consent category: %s", name)
 		logger.Debug(errorMsg, log.Error(err))
@@ -211,15 +211,16 @@ func GetConsentCategoryByName(name string) (*model.ConsentCategory, error) {
 	return &category, nil
 }
 
-// UpdateConsentCategory updates an existing consent category in the database.
-func UpdateConsentCategory(category model.ConsentCategory) error {
+// UpdateConsentCategory updates an existing consent category of the organization in the database. Returns false if
+// the organization has no such category.
+func UpdateConsentCategory(category model.ConsentCategory) (bool, error) {
 
 	dbClient, err := provider.NewDBProvider().GetDBClient()
 	logger := log.GetLogger()
 	if err != nil {
 		errorMsg := fmt.Sprintf("Failed to get db client for updating consent category: %s", category.CategoryIdentifier)
 		logger.Debug(errorMsg, log.Error(err))
-		return errors2.NewServerError(errors2.ErrorMessage{
+		return false, errors2.NewServerError(errors2.ErrorMessage{
 			Code:        errors2.UPDATE_CONSENT_CATEGORY.Code,
 			Message:     errors2.UPDATE_CONSENT_CATEGORY.Message,
 			Description: errorMsg,
@@ -236,23 +237,31 @@ func UpdateConsentCategory(category model.ConsentCategory) error {
 			Message:     errors2.UPDATE_CONSENT_CATEGORY.Message,
 			Description: errorMsg,
 		}, err)
-		return serverError
+		return false, serverError
 	}
 
 	query := scripts.UpdateConsentCategory[provider.NewDBProvider().GetDBType()]
-	_, err = tx.Exec(query, category.CategoryName, category.Purpose, pq.Array(category.Destinations), category.CategoryIdentifier)
+	result, err := tx.Exec(query, category.CategoryName, category.Purpose, pq.Array(category.Destinations),
+		category.CategoryIdentifier, category.OrgHandle)
 	if err != nil {
+		_ = tx.Rollback()
 		logger.Debug("Failed to update consent category", log.Error(err))
-		return errors2.NewServerError(errors2.ErrorMessage{
+		return false, errors2.NewServerError(errors2.ErrorMessage{
 			Code:        errors2.UPDATE_CONSENT_CATEGORY.Code,
 			Message:     errors2.UPDATE_CONSENT_CATEGORY.Message,
 			Description: "Failed to update consent category.",
 		}, err)
 	}
-	return tx.Commit()
+	updated, err := result.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return false, err
+	}
+	return updated > 0, tx.Commit()
 }
 
-func DeleteConsentCategory(categoryId string) error {
+// DeleteConsentCategory deletes a consent category of the organization from the database.
+func DeleteConsentCategory(categoryId, orgHandle string) error {
 	dbClient, err := provider.NewDBProvider().GetDBClient()
 	logger := log.GetLogger()
 	if err != nil {
@@ -280,7 +289,7 @@ func DeleteConsentCategory(categoryId string) error {
 	}
 
 	query := scripts.DeleteConsentCategory[provider.NewDBProvider().GetDBType()]
-	_, err = tx.Exec(query, categoryId)
+	_, err = tx.Exec(query, categoryId, orgHandle)
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to execute query for deleting consent category: %s", categoryId)
 		logger.Debug(errMsg, log.Error(err))
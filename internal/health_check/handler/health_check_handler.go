@@ -21,6 +21,7 @@ package handler
 import (
 	"encoding/json"
 	"github.com/wso2/identity-customer-data-service/internal/health_check/provider"
+	"github.com/wso2/identity-customer-data-service/internal/health_check/service"
 	"net/http"
 )
 
@@ -32,26 +33,28 @@ func NewHealthHandler() *HealthHandler {
 	return &HealthHandler{}
 }
 
-// HandleHealth responds to /health requests.
+// HandleHealth responds to /health and /health/live requests. It does not depend on any component.
 func (h *HealthHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	response := map[string]string{"status": "healthy"}
 	writeJSONResponse(w, http.StatusOK, response)
 }
 
-// HandleReadiness responds to /ready requests.
+// HandleReadiness responds to /ready requests with the status of each component, and with 503 when any of them
+// is down.
 func (h *HealthHandler) HandleReadiness(w http.ResponseWriter, r *http.Request) {
 	healthCheckService := provider.NewHealthCheckProvider().GetHealthCheckService()
-	if err := healthCheckService.CheckReadiness(); err != nil {
-		response := map[string]string{
-			"status": "not ready",
-			"error":  err.Error(),
-		}
-		writeJSONResponse(w, http.StatusServiceUnavailable, response)
+	report := healthCheckService.CheckReadiness(r.Context())
+	if !report.Ready() {
+		writeJSONResponse(w, http.StatusServiceUnavailable, readinessResponse{Status: "not ready", ReadinessReport: report})
 		return
 	}
+	writeJSONResponse(w, http.StatusOK, readinessResponse{Status: "ready", ReadinessReport: report})
+}
 
-	response := map[string]string{"status": "ready"}
-	writeJSONResponse(w, http.StatusOK, response)
+// readinessResponse is the body of the readiness endpoint.
+type readinessResponse struct {
+	Status string `json:"status"`
+	service.ReadinessReport
 }
 
 // writeJSONResponse is a common helper for JSON encoding.
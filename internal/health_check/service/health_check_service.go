@@ -19,15 +19,63 @@
 package service
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"sync/atomic"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/client"
 	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
-	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/scripts"
+)
+
+// Component statuses reported by the readiness check
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
 )
 
+// Components checked for readiness
+const (
+	ComponentServer          = "server"
+	ComponentDatabase        = "database"
+	ComponentConnectionPool  = "connection_pool"
+	ComponentIdempotencyKeys = "idempotency_keys"
+)
+
+// ComponentStatus is the outcome of checking a single component.
+type ComponentStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ReadinessReport holds the status of every component the service needs to serve requests.
+type ReadinessReport struct {
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// Ready reports whether every component is up.
+func (r ReadinessReport) Ready() bool {
+
+	for _, component := range r.Components {
+		if component.Status != StatusUp {
+			return false
+		}
+	}
+	return true
+}
+
+// shuttingDown is set once the server starts draining so that load balancers stop routing requests to it.
+var shuttingDown atomic.Bool
+
+// MarkShuttingDown makes the readiness check fail for the rest of the process lifetime.
+func MarkShuttingDown() {
+	shuttingDown.Store(true)
+}
+
 // HealthCheckServiceInterface defines the service interface.
 type HealthCheckServiceInterface interface {
-	CheckReadiness() error
+	CheckReadiness(ctx context.Context) ReadinessReport
 }
 
 // HealthCheckService is the default implementation.
@@ -38,24 +86,56 @@ func GetHealthCheckService() HealthCheckServiceInterface {
 	return &HealthCheckService{}
 }
 
-func (h HealthCheckService) CheckReadiness() error {
-	logger := log.GetLogger()
-	if logger == nil {
-		return errors.New("logger not initialized")
-	}
+// CheckReadiness checks that the server is not shutting down, database connectivity, the headroom of the connection
+// pool and access to the idempotency key table that profile creations depend on. Each check is bounded by
+// ReadinessCheckTimeout so that an exhausted pool fails the check instead of blocking the probe.
+func (h HealthCheckService) CheckReadiness(ctx context.Context) ReadinessReport {
 
+	report := ReadinessReport{Components: map[string]ComponentStatus{ComponentServer: {Status: StatusUp}}}
+	if shuttingDown.Load() {
+		report.Components[ComponentServer] = ComponentStatus{Status: StatusDown, Error: "server is shutting down"}
+	}
 	dbProvider := provider.NewDBProvider()
 	dbClient, err := dbProvider.GetDBClient()
 	if err != nil {
-		return fmt.Errorf("failed to create database client: %v", err)
+		down := ComponentStatus{Status: StatusDown, Error: fmt.Sprintf("failed to create database client: %v", err)}
+		report.Components[ComponentDatabase] = down
+		report.Components[ComponentConnectionPool] = down
+		report.Components[ComponentIdempotencyKeys] = down
+		return report
 	}
 	defer dbClient.Close()
 
-	// Perform a lightweight query to ensure DB connectivity.
-	_, err = dbClient.ExecuteQuery("SELECT 1;")
-	if err != nil {
-		return fmt.Errorf("database connectivity check failed: %v", err)
+	report.Components[ComponentConnectionPool] = checkConnectionPool()
+	report.Components[ComponentDatabase] = checkQuery(ctx, dbClient, "SELECT 1;")
+	report.Components[ComponentIdempotencyKeys] = checkQuery(ctx, dbClient,
+		scripts.CheckIdempotencyKeysTable[dbProvider.GetDBType()])
+	return report
+}
+
+// checkConnectionPool reports the pool as down when every connection it may open is in use.
+func checkConnectionPool() ComponentStatus {
+
+	stats, ok := provider.PoolStats()
+	if !ok {
+		return ComponentStatus{Status: StatusDown, Error: "connection pool is not open"}
+	}
+	if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections {
+		return ComponentStatus{
+			Status: StatusDown,
+			Error:  fmt.Sprintf("all %d connections are in use", stats.MaxOpenConnections),
+		}
 	}
+	return ComponentStatus{Status: StatusUp}
+}
+
+// checkQuery runs the query within the readiness timeout.
+func checkQuery(ctx context.Context, dbClient client.DBClientInterface, query string) ComponentStatus {
 
-	return nil
+	ctx, cancel := context.WithTimeout(ctx, constants.ReadinessCheckTimeout)
+	defer cancel()
+	if _, err := dbClient.ExecuteQueryContext(ctx, query); err != nil {
+		return ComponentStatus{Status: StatusDown, Error: err.Error()}
+	}
+	return ComponentStatus{Status: StatusUp}
 }
@@ -19,11 +19,17 @@
 package handler
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	adminConfigPkg "github.com/wso2/identity-customer-data-service/internal/admin_config/provider"
 	adminConfigService "github.com/wso2/identity-customer-data-service/internal/admin_config/service"
@@ -58,7 +64,7 @@ func NewProfileHandler() *ProfileHandler {
 // GetProfile handles profile retrieval requests
 func (ph *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "profile:view")
+	r, err := security.AuthnAndAuthz(r, "profile:view")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -87,16 +93,34 @@ func (ph *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	}
 	profilesProvider := provider.NewProfilesProvider()
 	profilesService := profilesProvider.GetProfilesService()
-	profile, err := profilesService.GetProfile(profileId)
-	if err != nil {
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
 		utils.HandleError(w, err)
 		return
 	}
 
+	if fields := r.URL.Query().Get(constants.Fields); fields != "" {
+		projection, err := profilesService.GetProfileProjected(profileId, strings.Split(fields, ","),
+			resolveAppScope(r, orgHandle))
+		if err != nil {
+			utils.HandleError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(projection)
+		return
+	}
+
 	filterParams := parseApplicationDataParams(r)
 	callerAppID := getCallerAppIDFromRequest(r)
 	isSystemApp := isCallerSystemApplication(orgHandle, callerAppID)
 
+	profile, err := profilesService.GetProfileContext(r.Context(), profileId, resolveAppScope(r, orgHandle))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+
 	profile.ApplicationData = profileService.FilterApplicationData(
 		profile.ApplicationData,
 		callerAppID,
@@ -104,6 +128,7 @@ func (ph *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 		filterParams,
 	)
 
+	setProfileETag(w, profile)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(profile)
@@ -112,7 +137,9 @@ func (ph *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 // GetCurrentUserProfile handles retrieval of the current user's profile
 func (ph *ProfileHandler) GetCurrentUserProfile(w http.ResponseWriter, r *http.Request) {
 
-	if err := security.AuthnAndAuthz(r, "profile:view"); err != nil {
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+
+	if err != nil {
 		utils.HandleError(w, err)
 		return
 	}
@@ -192,30 +219,981 @@ func (ph *ProfileHandler) GetCurrentUserProfile(w http.ResponseWriter, r *http.R
 		}
 	}
 
-	// Fetch the profile using the resolved profile ID
-	profile, err := profilesService.GetProfile(profileId)
+	// Fetch the profile using the resolved profile ID
+	profile, err := profilesService.GetProfileContext(r.Context(), profileId, resolveAppScope(r, orgHandle))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+
+	if profile == nil {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: "Profile not found for current user",
+		}, http.StatusNotFound)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, profile, constants.ProfileResource)
+}
+
+// DeleteProfile handles profile deletion
+func (ph *ProfileHandler) DeleteProfile(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:delete")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profileId := r.PathValue("profileId")
+	if profileId == "" {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_PROFILE.Code,
+			Message:     errors2.DELETE_PROFILE.Message,
+			Description: "Invalid path for profile deletion",
+		}, http.StatusNotFound)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	err = profilesService.DeleteProfile(profileId)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreProfile handles restoring a soft-deleted profile
+func (ph *ProfileHandler) RestoreProfile(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:delete")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profileId := r.PathValue("profileId")
+	if profileId == "" {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.RESTORE_PROFILE.Code,
+			Message:     errors2.RESTORE_PROFILE.Message,
+			Description: "Invalid path for profile restore",
+		}, http.StatusNotFound)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	if err = profilesService.RestoreProfile(profileId); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	profile, err := profilesService.GetProfileFromPrimary(profileId, resolveAppScope(r, orgHandle))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, profile, constants.ProfileResource)
+}
+
+// ResolveProfile handles finding the profiles of a person by an identity attribute value
+func (ph *ProfileHandler) ResolveProfile(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	query := r.URL.Query()
+	attrName := query.Get(constants.ResolveAttribute)
+	attrValue := query.Get(constants.ResolveValue)
+	if attrName == "" || attrValue == "" {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:    errors2.BAD_REQUEST.Code,
+			Message: errors2.BAD_REQUEST.Message,
+			Description: fmt.Sprintf("Both '%s' and '%s' query parameters are required", constants.ResolveAttribute,
+				constants.ResolveValue),
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	profiles, err := profilesService.ResolveProfileByIdentifier(orgHandle, attrName, attrValue,
+		resolveAppScope(r, orgHandle))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, profiles, constants.ProfileResource)
+}
+
+// AnonymizeProfile handles erasing the personal data of the person of a profile
+func (ph *ProfileHandler) AnonymizeProfile(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:delete")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profileId := r.PathValue("profileId")
+	if profileId == "" {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: "Invalid path for profile anonymization",
+		}, http.StatusNotFound)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	if err = profilesService.AnonymizeProfile(profileId); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MergeProfiles handles manually merging a profile into the profile in the path
+func (ph *ProfileHandler) MergeProfiles(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:update")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	masterProfileId := r.PathValue("profileId")
+	if masterProfileId == "" {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.MERGE_PROFILE.Code,
+			Message:     errors2.MERGE_PROFILE.Message,
+			Description: "Invalid path for profile merge",
+		}, http.StatusNotFound)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	var body struct {
+		ChildProfileId string `json:"child_profile_id"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil || body.ChildProfileId == "" {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.MERGE_PROFILE.Code,
+			Message:     errors2.MERGE_PROFILE.Message,
+			Description: "Request body must be of the form {\"child_profile_id\": <profile id>}",
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(masterProfileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	if err = profilesService.MergeProfiles(masterProfileId, body.ChildProfileId); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	profile, err := profilesService.GetProfileFromPrimary(masterProfileId, resolveAppScope(r, orgHandle))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, profile, constants.ProfileResource)
+}
+
+// AliasProfiles handles linking the profile of an anonymous visitor to the profile the visitor became known as
+func (ph *ProfileHandler) AliasProfiles(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:update")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	var body struct {
+		FromProfileId string `json:"from_profile_id"`
+		ToProfileId   string `json:"to_profile_id"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil || body.FromProfileId == "" || body.ToProfileId == "" {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.MERGE_PROFILE.Code,
+			Message:     errors2.MERGE_PROFILE.Message,
+			Description: "Request body must be of the form {\"from_profile_id\": <profile id>, \"to_profile_id\": <profile id>}",
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	for _, profileId := range []string{body.FromProfileId, body.ToProfileId} {
+		if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+			utils.HandleError(w, err)
+			return
+		}
+	}
+	profile, err := profilesService.AliasProfiles(body.FromProfileId, body.ToProfileId, resolveAppScope(r, orgHandle))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, profile, constants.ProfileResource)
+}
+
+// UnmergeProfile handles separating a merged profile from its reference profile
+func (ph *ProfileHandler) UnmergeProfile(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:update")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profileId := r.PathValue("profileId")
+	if profileId == "" {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UNMERGE_PROFILE.Code,
+			Message:     errors2.UNMERGE_PROFILE.Message,
+			Description: "Invalid path for profile unmerge",
+		}, http.StatusNotFound)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	profile, err := profilesService.UnmergeProfile(profileId, resolveAppScope(r, orgHandle))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, profile, constants.ProfileResource)
+}
+
+// GetProfileHistory handles fetching the trait history of a profile
+func (ph *ProfileHandler) GetProfileHistory(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profileId := r.PathValue("profileId")
+	if profileId == "" {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: "Invalid path for profile history retrieval",
+		}, http.StatusNotFound)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	history, err := profilesService.GetProfileHistory(profileId, resolveAppScope(r, orgHandle))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, history, constants.ProfileResource)
+}
+
+// GetProfileLineage handles fetching the merge lineage of a profile
+func (ph *ProfileHandler) GetProfileLineage(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profileId := r.PathValue("profileId")
+	if profileId == "" {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: "Invalid path for profile lineage retrieval",
+		}, http.StatusNotFound)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	lineage, err := profilesService.GetProfileLineage(profileId)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, lineage, constants.ProfileResource)
+}
+
+// GetChildProfiles lists the profiles merged to a master profile, or to the master of a merged profile.
+func (ph *ProfileHandler) GetChildProfiles(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profileId := r.PathValue("profileId")
+	profilesService := provider.NewProfilesProvider().GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	children, err := profilesService.GetChildProfiles(profileId)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, children, constants.ProfileResource)
+}
+
+// ExportPortableProfile handles exporting a profile as a signed, portable data package
+func (ph *ProfileHandler) ExportPortableProfile(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profileId := r.PathValue("profileId")
+	if profileId == "" {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: "Invalid path for portable profile export",
+		}, http.StatusNotFound)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	exported, err := profilesService.ExportPortableProfile(profileId)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"profile-%s.json\"", profileId))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(exported)
+}
+
+// ExportProfile handles exporting all the data held about the person of a profile
+func (ph *ProfileHandler) ExportProfile(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profileId := r.PathValue("profileId")
+	if profileId == "" {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: "Invalid path for profile export",
+		}, http.StatusNotFound)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	exported, err := profilesService.ExportProfile(profileId, resolveAppScope(r, orgHandle))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"profile-export-%s.json\"", profileId))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(exported)
+}
+
+// IncrementProfileAttribute handles atomically incrementing or decrementing a numeric profile attribute
+func (ph *ProfileHandler) IncrementProfileAttribute(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:update")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profileId := r.PathValue("profileId")
+	path := r.PathValue("path")
+	if profileId == "" || path == "" {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: "Invalid path for attribute increment",
+		}, http.StatusNotFound)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	var body struct {
+		Delta *float64 `json:"delta"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil || body.Delta == nil {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: "Request body must be of the form {\"delta\": <number>}",
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	value, err := profilesService.IncrementAttribute(profileId, path, *body.Delta)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{"value": value}, constants.ProfileResource)
+}
+
+// PatchApplicationData handles JSON merge patches of the data an application keeps on a profile. Applications other
+// than system applications may only patch their own data.
+func (ph *ProfileHandler) PatchApplicationData(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:update")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profileId := r.PathValue("profileId")
+	appId := r.PathValue("appId")
+	if profileId == "" || appId == "" {
+		http.Error(w, "Invalid path", http.StatusNotFound)
+		return
+	}
+	if appScope := resolveAppScope(r, orgHandle); !appScope.Allows(appId) {
+		description := fmt.Sprintf("Application: %s can not patch the data of application: %s", appScope.AppId(), appId)
+		if appScope.AppId() == "" {
+			description = fmt.Sprintf("Only applications can patch the data of application: %s", appId)
+		}
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.FORBIDDEN.Code,
+			Message:     errors2.FORBIDDEN.Message,
+			Description: description,
+		}, http.StatusForbidden)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	var patch map[string]interface{}
+	if err := utils.NewJSONDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		description := "Request body must be a JSON object"
+		if err != nil {
+			description = utils.HandleDecodeError(err, "application data")
+		}
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_APP_DATA.Code,
+			Message:     errors2.UPDATE_APP_DATA.Message,
+			Description: description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	if err := profilesService.PatchApplicationData(profileId, appId, patch); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteProfilesByFilter handles bulk deletion of the profiles matching the filter query parameters
+func (ph *ProfileHandler) DeleteProfilesByFilter(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:delete")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	deletedCount, err := profilesService.DeleteProfilesByFilter(orgHandle, parseProfileFilters(r))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, map[string]int64{"deleted_count": deletedCount}, constants.ProfileResource)
+}
+
+// PatchProfilesByFilter handles setting traits on all the profiles matching the filter query parameters
+func (ph *ProfileHandler) PatchProfilesByFilter(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:update")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	var patch model.ProfileBulkPatchRequest
+	decoder := utils.NewJSONDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: utils.HandleDecodeError(err, "profile patch"),
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	updatedCount, err := profilesService.PatchProfilesByFilter(orgHandle, parseProfileFilters(r), patch.Traits)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, map[string]int64{"updated_count": updatedCount}, constants.ProfileResource)
+}
+
+// CountProfiles handles counting the profiles matching the filter query parameters, grouped by a trait
+func (ph *ProfileHandler) CountProfiles(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	groupBy := strings.TrimSpace(r.URL.Query().Get(constants.GroupBy))
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	counts, err := profilesService.CountProfilesGroupedBy(orgHandle, groupBy, parseProfileFilters(r),
+		resolveAppScope(r, orgHandle))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, model.ProfileCountResponse{GroupBy: groupBy, Counts: counts},
+		constants.ProfileResource)
+}
+
+// GetHierarchyStats handles fetching the statistics of how the profiles of the organization are unified
+func (ph *ProfileHandler) GetHierarchyStats(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	stats, err := profilesService.GetHierarchyStats(orgHandle)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, stats, constants.ProfileResource)
+}
+
+// ReunifyAll starts splitting all merged profiles of the organization and merging them again by the active
+// unification rules. The job runs in the background; its progress is available at the returned location.
+func (ph *ProfileHandler) ReunifyAll(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "unification_rules:update")
+
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesService := provider.NewProfilesProvider().GetProfilesService()
+	job, err := profilesService.ReunifyAll(orgHandle)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	location := fmt.Sprintf("%s://%s%s/jobs/%s", detectScheme(r), r.Host, constants.ApiBasePath+"/v1", job.JobId)
+	w.Header().Set("Location", location)
+	utils.RespondJSON(w, http.StatusAccepted, job, constants.ProfileResource)
+}
+
+// GetProfileJob returns the status and progress of a profile job.
+func (ph *ProfileHandler) GetProfileJob(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "unification_rules:view")
+
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesService := provider.NewProfilesProvider().GetProfilesService()
+	job, err := profilesService.GetProfileJob(r.PathValue("jobId"), orgHandle)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, job, constants.ProfileResource)
+}
+
+// CancelProfileJob asks a running profile job to stop after its current batch.
+func (ph *ProfileHandler) CancelProfileJob(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "unification_rules:update")
+
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesService := provider.NewProfilesProvider().GetProfilesService()
+	job, err := profilesService.CancelProfileJob(r.PathValue("jobId"), orgHandle)
 	if err != nil {
 		utils.HandleError(w, err)
 		return
 	}
+	utils.RespondJSON(w, http.StatusAccepted, job, constants.ProfileResource)
+}
 
-	if profile == nil {
+// ExportProfilesCSV streams the profiles matching the filters as a CSV file with the requested fields as columns.
+func (ph *ProfileHandler) ExportProfilesCSV(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
 		clientError := errors2.NewClientError(errors2.ErrorMessage{
-			Code:        errors2.GET_PROFILE.Code,
-			Message:     errors2.GET_PROFILE.Message,
-			Description: "Profile not found for current user",
-		}, http.StatusNotFound)
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
 		utils.HandleError(w, clientError)
 		return
 	}
+	var fields []string
+	if f := r.URL.Query().Get(constants.Fields); f != "" {
+		fields = strings.Split(f, ",")
+	}
 
-	utils.RespondJSON(w, http.StatusOK, profile, constants.ProfileResource)
+	stream := newStreamWriter(w, func(header http.Header) {
+		header.Set("Content-Type", "text/csv; charset=utf-8")
+		header.Set("Content-Disposition", `attachment; filename="profiles.csv"`)
+	})
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	err = profilesService.ExportProfilesCSV(r.Context(), orgHandle, parseProfileFilters(r), fields,
+		resolveAppScope(r, orgHandle), stream)
+	if err != nil {
+		if !stream.started {
+			utils.HandleError(w, err)
+			return
+		}
+		// The status line is already sent, so the client only sees the CSV cut short.
+		log.GetLogger().Error(fmt.Sprintf("Profile CSV export of organization: %s ended early", orgHandle),
+			log.Error(err))
+	}
 }
 
-// DeleteProfile handles profile deletion
-func (ph *ProfileHandler) DeleteProfile(w http.ResponseWriter, r *http.Request) {
+// streamWriter writes a streamed response body. The headers are set on the first write, so that an error found
+// before any output can still be sent as an error response, and every write extends the write deadline and is
+// flushed to the client.
+type streamWriter struct {
+	w          http.ResponseWriter
+	rc         *http.ResponseController
+	setHeaders func(header http.Header)
+	started    bool
+}
+
+func newStreamWriter(w http.ResponseWriter, setHeaders func(header http.Header)) *streamWriter {
+
+	return &streamWriter{w: w, rc: http.NewResponseController(w), setHeaders: setHeaders}
+}
+
+func (sw *streamWriter) Write(p []byte) (int, error) {
+
+	if !sw.started {
+		sw.setHeaders(sw.w.Header())
+		sw.w.WriteHeader(http.StatusOK)
+		sw.started = true
+	}
+	_ = sw.rc.SetWriteDeadline(time.Now().Add(constants.ProfileExportIdleTimeout))
+	n, err := sw.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, sw.rc.Flush()
+}
+
+// GetDistinctTraitValues lists the distinct values of a trait, e.g. to offer them as filter choices.
+func (ph *ProfileHandler) GetDistinctTraitValues(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:view")
 
-	err := security.AuthnAndAuthz(r, "profile:delete")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -230,29 +1208,89 @@ func (ph *ProfileHandler) DeleteProfile(w http.ResponseWriter, r *http.Request)
 		utils.HandleError(w, clientError)
 		return
 	}
-	profileId := r.PathValue("profileId")
-	if profileId == "" {
+
+	query := r.URL.Query()
+	limit := constants.DefaultDistinctTraitValues
+	if raw := strings.TrimSpace(query.Get(constants.Limit)); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			clientError := errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.INVALID_DISTINCT_VALUES_REQUEST.Code,
+				Message:     errors2.INVALID_DISTINCT_VALUES_REQUEST.Message,
+				Description: fmt.Sprintf("Invalid value for %s: %s", constants.Limit, raw),
+			}, http.StatusBadRequest)
+			utils.HandleError(w, clientError)
+			return
+		}
+		limit = parsed
+	}
+	byFrequency := false
+	switch orderBy := strings.TrimSpace(query.Get(constants.OrderBy)); orderBy {
+	case "", constants.OrderByValue:
+	case constants.OrderByFrequency:
+		byFrequency = true
+	default:
 		clientError := errors2.NewClientError(errors2.ErrorMessage{
-			Code:        errors2.DELETE_PROFILE.Code,
-			Message:     errors2.DELETE_PROFILE.Message,
-			Description: "Invalid path for profile deletion",
-		}, http.StatusNotFound)
+			Code:        errors2.INVALID_DISTINCT_VALUES_REQUEST.Code,
+			Message:     errors2.INVALID_DISTINCT_VALUES_REQUEST.Message,
+			Description: fmt.Sprintf("Invalid value for %s: %s", constants.OrderBy, orderBy),
+		}, http.StatusBadRequest)
 		utils.HandleError(w, clientError)
 		return
 	}
+
+	trait := strings.TrimSpace(query.Get(constants.Trait))
 	profilesProvider := provider.NewProfilesProvider()
 	profilesService := profilesProvider.GetProfilesService()
-	err = profilesService.DeleteProfile(profileId)
+	values, err := profilesService.GetDistinctTraitValues(orgHandle, trait, limit, byFrequency,
+		resolveAppScope(r, orgHandle))
 	if err != nil {
 		utils.HandleError(w, err)
 		return
 	}
-	w.WriteHeader(http.StatusNoContent)
+	utils.RespondJSON(w, http.StatusOK, model.DistinctTraitValuesResponse{Trait: trait, Values: values},
+		constants.ProfileResource)
+}
+
+// parseProfileFilters collects the filter query parameters, splitting the ones combined with "and". The "and"
+// between the two bounds of a between filter does not split it.
+func parseProfileFilters(r *http.Request) []string {
+
+	filters := make([]string, 0)
+	for _, f := range r.URL.Query()[constants.Filter] {
+		openBetween := false
+		for _, sf := range strings.Split(f, " and ") {
+			sf = strings.TrimSpace(sf)
+			if sf == "" {
+				continue
+			}
+			if openBetween {
+				filters[len(filters)-1] += " and " + sf
+				openBetween = false
+				continue
+			}
+			filters = append(filters, sf)
+			parts := strings.Fields(sf)
+			openBetween = len(parts) == 3 && parts[1] == "between"
+		}
+	}
+	return filters
+}
+
+// parseModifiedSince parses the time of the modifiedSince query parameter, given as epoch seconds or an RFC3339 time.
+func parseModifiedSince(raw string) (time.Time, error) {
+
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return time.Parse(time.RFC3339Nano, raw)
 }
 
 func (ph *ProfileHandler) GetAllProfiles(w http.ResponseWriter, r *http.Request) {
 
-	if err := security.AuthnAndAuthz(r, "profile:view"); err != nil {
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+
+	if err != nil {
 		utils.HandleError(w, err)
 		return
 	}
@@ -293,20 +1331,64 @@ func (ph *ProfileHandler) GetAllProfiles(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Parse filters
-	queryFilters := r.URL.Query()[constants.Filter]
-	filters := make([]string, 0)
-	for _, f := range queryFilters {
-		splitFilters := strings.Split(f, " and ")
-		for _, sf := range splitFilters {
-			sf = strings.TrimSpace(sf)
-			if sf != "" {
-				filters = append(filters, sf)
-			}
+	filters := parseProfileFilters(r)
+
+	sort, serr := model.ParseProfileSort(r.URL.Query().Get(constants.Sort))
+	if serr != nil {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.INVALID_SORT_PARAMETER.Code,
+			Message:     errors2.INVALID_SORT_PARAMETER.Message,
+			Description: serr.Error(),
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	includeDeleted := false
+	if raw := strings.TrimSpace(r.URL.Query().Get(constants.IncludeDeleted)); raw != "" {
+		parsed, perr := strconv.ParseBool(raw)
+		if perr != nil {
+			clientError := errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.GET_PROFILE.Code,
+				Message:     errors2.GET_PROFILE.Message,
+				Description: fmt.Sprintf("Invalid value for %s: %s", constants.IncludeDeleted, raw),
+			}, http.StatusBadRequest)
+			utils.HandleError(w, clientError)
+			return
 		}
+		includeDeleted = parsed
+	}
+
+	var modifiedSince *time.Time
+	if raw := strings.TrimSpace(r.URL.Query().Get(constants.ModifiedSince)); raw != "" {
+		since, perr := parseModifiedSince(raw)
+		if perr != nil {
+			clientError := errors2.NewClientError(errors2.ErrorMessage{
+				Code:    errors2.GET_PROFILE.Code,
+				Message: errors2.GET_PROFILE.Message,
+				Description: fmt.Sprintf("Invalid value for %s: %s. Use epoch seconds or an RFC3339 time.",
+					constants.ModifiedSince, raw),
+			}, http.StatusBadRequest)
+			utils.HandleError(w, clientError)
+			return
+		}
+		if sort != nil {
+			clientError := errors2.NewClientError(errors2.ErrorMessage{
+				Code:    errors2.INVALID_SORT_PARAMETER.Code,
+				Message: errors2.INVALID_SORT_PARAMETER.Message,
+				Description: fmt.Sprintf("Profiles listed with %s are ordered by updated_at and can not be sorted.",
+					constants.ModifiedSince),
+			}, http.StatusBadRequest)
+			utils.HandleError(w, clientError)
+			return
+		}
+		modifiedSince = &since
+		// The cursors of the listing follow its updated_at order.
+		sort = &model.ProfileSort{Field: "updated_at"}
 	}
 
 	requestedAttrs := parseRequestedAttributes(r)
+	appScope := resolveAppScope(r, orgHandle)
 
 	profilesProvider := provider.NewProfilesProvider()
 	profilesService := profilesProvider.GetProfilesService()
@@ -314,15 +1396,25 @@ func (ph *ProfileHandler) GetAllProfiles(w http.ResponseWriter, r *http.Request)
 	var (
 		profiles []model.ProfileResponse
 		hasMore  bool
-		err      error
 	)
 
-	if len(filters) > 0 {
+	query := model.ProfileQuery{
+		Filters:        filters,
+		Sort:           sort,
+		IncludeDeleted: includeDeleted,
+		Limit:          limit,
+		Cursor:         cursor,
+		AppScope:       appScope,
+	}
+	if modifiedSince != nil {
+		logger.Info("Fetching profiles modified since " + modifiedSince.Format(time.RFC3339) + " + cursor pagination")
+		profiles, hasMore, err = profilesService.GetProfilesModifiedSince(orgHandle, *modifiedSince, query)
+	} else if len(filters) > 0 || sort != nil {
 		logger.Info("Fetching profiles with filters + cursor pagination")
-		profiles, hasMore, err = profilesService.GetAllProfilesWithFilterCursor(orgHandle, filters, limit, cursor)
+		profiles, hasMore, err = profilesService.GetAllProfilesWithFilterCursor(orgHandle, query)
 	} else {
 		logger.Info("Fetching all profiles + cursor pagination")
-		profiles, hasMore, err = profilesService.GetAllProfilesCursor(orgHandle, limit, cursor)
+		profiles, hasMore, err = profilesService.GetAllProfilesCursor(orgHandle, includeDeleted, limit, cursor, appScope)
 	}
 
 	if err != nil {
@@ -350,38 +1442,22 @@ func (ph *ProfileHandler) GetAllProfiles(w http.ResponseWriter, r *http.Request)
 		//   so return next cursor if the request had a cursor (you navigated) OR if hasMore in next mode.
 		if reqDir == "next" {
 			if hasMore {
-				nextCursorStr = model.EncodeProfileCursor(model.ProfileCursor{
-					CreatedAt: last.Meta.CreatedAt,
-					ProfileId: last.ProfileId,
-					Direction: "next",
-				})
+				nextCursorStr = encodeListCursor(last, "next", sort)
 			}
 			// prev cursor (go newer): exists whenever this was not the first request
 			if cursor != nil {
-				prevCursorStr = model.EncodeProfileCursor(model.ProfileCursor{
-					CreatedAt: first.Meta.CreatedAt,
-					ProfileId: first.ProfileId,
-					Direction: "prev",
-				})
+				prevCursorStr = encodeListCursor(first, "prev", sort)
 			}
 		} else { // reqDir == "prev"
 			// prev cursor (go newer): ONLY if there are more newer rows (hasMore in prev mode)
 			if hasMore {
-				prevCursorStr = model.EncodeProfileCursor(model.ProfileCursor{
-					CreatedAt: first.Meta.CreatedAt,
-					ProfileId: first.ProfileId,
-					Direction: "prev",
-				})
+				prevCursorStr = encodeListCursor(first, "prev", sort)
 			}
 
 			// next cursor (go older): always provide it if we navigated using a cursor
 			// (lets you go forward again after going back)
 			if cursor != nil {
-				nextCursorStr = model.EncodeProfileCursor(model.ProfileCursor{
-					CreatedAt: last.Meta.CreatedAt,
-					ProfileId: last.ProfileId,
-					Direction: "next",
-				})
+				nextCursorStr = encodeListCursor(last, "next", sort)
 			}
 		}
 	}
@@ -399,16 +1475,117 @@ func (ph *ProfileHandler) GetAllProfiles(w http.ResponseWriter, r *http.Request)
 	utils.RespondJSON(w, http.StatusOK, resp, constants.ProfileResource)
 }
 
+// StreamProfiles writes every profile matching the filters as one JSON array, encoding each profile as it is read
+// instead of paginating. Should the stream fail after it has started, a final element {"error": {...}} carrying the
+// error code and message is appended before the array is closed.
+func (ph *ProfileHandler) StreamProfiles(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	requestedAttrs := parseRequestedAttributes(r)
+	appScope := resolveAppScope(r, orgHandle)
+
+	// Profiles are buffered so that a failure before the first flush can still be sent as an error response.
+	stream := newStreamWriter(w, func(header http.Header) {
+		header.Set("Content-Type", "application/json")
+	})
+	out := bufio.NewWriter(stream)
+	streamed := 0
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	err = profilesService.StreamProfiles(r.Context(), orgHandle, parseProfileFilters(r), appScope,
+		func(profile model.ProfileResponse) error {
+			encoded, err := json.Marshal(buildProfileListResponse([]model.ProfileResponse{profile}, requestedAttrs)[0])
+			if err != nil {
+				return err
+			}
+			separator := byte(',')
+			if streamed == 0 {
+				separator = '['
+			}
+			if err := out.WriteByte(separator); err != nil {
+				return err
+			}
+			if _, err := out.Write(encoded); err != nil {
+				return err
+			}
+			streamed++
+			if streamed%constants.ProfileExportFlushRows == 0 {
+				return out.Flush()
+			}
+			return nil
+		})
+	if err != nil && !stream.started {
+		utils.HandleError(w, err)
+		return
+	}
+
+	closing := "]"
+	if streamed == 0 {
+		closing = "[]"
+	}
+	if err != nil {
+		log.GetLogger().Error(fmt.Sprintf("Profile stream of organization: %s ended early after %d profiles",
+			orgHandle, streamed), log.Error(err))
+		streamErr := errors2.ErrorMessage{Code: errors2.GET_PROFILE.Code, Message: errors2.GET_PROFILE.Message}
+		var clientErr *errors2.ClientError
+		var serverErr *errors2.ServerError
+		if errors.As(err, &clientErr) {
+			streamErr = clientErr.ErrorMessage
+		} else if errors.As(err, &serverErr) {
+			streamErr = serverErr.ErrorMessage
+		}
+		trailer, _ := json.Marshal(map[string]interface{}{
+			"error": map[string]string{"code": streamErr.Code, "message": streamErr.Message},
+		})
+		// Profiles were flushed before the failure, so the array is already open.
+		closing = "," + string(trailer) + "]"
+	}
+	if _, err := out.WriteString(closing + "\n"); err == nil {
+		_ = out.Flush()
+	}
+}
+
+// encodeListCursor encodes the pagination cursor pointing at the given boundary profile of the listing.
+func encodeListCursor(profile model.ProfileResponse, direction string, sort *model.ProfileSort) string {
+
+	cursor := model.ProfileCursor{
+		CreatedAt: profile.Meta.CreatedAt,
+		ProfileId: profile.ProfileId,
+		Direction: direction,
+	}
+	if sort != nil {
+		cursor.Sorted = true
+		cursor.SortValue = sort.SortValueOf(profile)
+	}
+	return model.EncodeProfileCursor(cursor)
+}
+
 func buildProfileListResponse(profiles []model.ProfileResponse, requestedAttrs map[string][]string) []model.ProfileListResponse {
 
 	result := make([]model.ProfileListResponse, 0, len(profiles))
 
 	for _, profile := range profiles {
 		profileRes := model.ProfileListResponse{
-			ProfileId:  profile.ProfileId,
-			Meta:       profile.Meta,
-			UserId:     profile.UserId,
-			MergedFrom: profile.MergedFrom,
+			ProfileId:          profile.ProfileId,
+			CanonicalProfileId: profile.CanonicalProfileId,
+			Meta:               profile.Meta,
+			UserId:             profile.UserId,
+			MergedFrom:         profile.MergedFrom,
 		}
 
 		if requestedAttrs == nil {
@@ -508,7 +1685,7 @@ func parseRequestedAttributes(r *http.Request) map[string][]string {
 // InitProfile initializes a new profile based on the request body and sets a cookie
 func (ph *ProfileHandler) InitProfile(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "profile:create")
+	r, err := security.AuthnAndAuthz(r, "profile:create")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -531,7 +1708,7 @@ func (ph *ProfileHandler) InitProfile(w http.ResponseWriter, r *http.Request) {
 	profilesService := profilesProvider.GetProfilesService()
 
 	var profile model.ProfileRequest
-	decoder := json.NewDecoder(r.Body)
+	decoder := utils.NewJSONDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 	err = decoder.Decode(&profile)
 
@@ -554,7 +1731,8 @@ func (ph *ProfileHandler) InitProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// If no valid cookie, create a new profile and cookie
-	profileResponse, err := profilesService.CreateProfile(profile, orgHandle)
+	profileResponse, err := profilesService.CreateProfileIdempotently(r.Context(), profile, orgHandle,
+		r.Header.Get(constants.IdempotencyKeyHeader), resolveAppScope(r, orgHandle))
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -565,23 +1743,124 @@ func (ph *ProfileHandler) InitProfile(w http.ResponseWriter, r *http.Request) {
 		utils.HandleError(w, err)
 		return
 	}
-	// Create and set a new cookie
-	err = setProfileCookie(w, cookie.CookieId, r)
+	// Create and set a new cookie
+	err = setProfileCookie(w, cookie.CookieId, r)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+
+	// Construct Location header for created resource
+	location := fmt.Sprintf("%s://%s%s/profiles/%s",
+		detectScheme(r),
+		r.Host,
+		constants.ApiBasePath+"/v1",
+		profileResponse.ProfileId,
+	)
+
+	w.Header().Set("Location", location)
+	utils.RespondJSON(w, http.StatusCreated, profileResponse, constants.ProfileResource)
+}
+
+// GetOrCreateProfile returns the profile of the user in the request body, creating it when the user has no profile
+// yet. A new profile is answered with 201 and an existing one, updated or not, with 200.
+func (ph *ProfileHandler) GetOrCreateProfile(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:create")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+
+	if !isCDSEnabled(orgHandle) {
+		errMsg := "CDS is not enabled for organization: " + orgHandle
+		log.GetLogger().Info(errMsg)
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errMsg,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	var profile model.ProfileRequest
+	decoder := utils.NewJSONDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&profile); err != nil {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: utils.HandleDecodeError(err, "profile"),
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	profilesService := provider.NewProfilesProvider().GetProfilesService()
+	profileResponse, created, err := profilesService.GetOrCreateProfile(r.Context(), profile, orgHandle,
+		resolveAppScope(r, orgHandle))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	if !created {
+		utils.RespondJSON(w, http.StatusOK, profileResponse, constants.ProfileResource)
+		return
+	}
+
+	location := fmt.Sprintf("%s://%s%s/profiles/%s",
+		detectScheme(r),
+		r.Host,
+		constants.ApiBasePath+"/v1",
+		profileResponse.ProfileId,
+	)
+	w.Header().Set("Location", location)
+	utils.RespondJSON(w, http.StatusCreated, profileResponse, constants.ProfileResource)
+}
+
+// SimulateProfile returns the profile the request body would produce, including its unification with an existing
+// profile, without storing anything.
+func (ph *ProfileHandler) SimulateProfile(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	var profile model.ProfileRequest
+	decoder := utils.NewJSONDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&profile); err != nil {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: utils.HandleDecodeError(err, "profile"),
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	profilesService := provider.NewProfilesProvider().GetProfilesService()
+	simulation, err := profilesService.SimulateProfile(r.Context(), profile, orgHandle, resolveAppScope(r, orgHandle))
 	if err != nil {
 		utils.HandleError(w, err)
 		return
 	}
-
-	// Construct Location header for created resource
-	location := fmt.Sprintf("%s://%s%s/profiles/%s",
-		detectScheme(r),
-		r.Host,
-		constants.ApiBasePath+"/v1",
-		profileResponse.ProfileId,
-	)
-
-	w.Header().Set("Location", location)
-	utils.RespondJSON(w, http.StatusCreated, profileResponse, constants.ProfileResource)
+	utils.RespondJSON(w, http.StatusOK, simulation, constants.ProfileResource)
 }
 
 // Handles existing cookie logic, returns true if response was already written
@@ -597,7 +1876,8 @@ func (ph *ProfileHandler) handleExistingCookie(w http.ResponseWriter, r *http.Re
 	if !cookieObj.IsActive {
 		return false
 	}
-	profileResponse, err := profilesService.GetProfile(cookieObj.ProfileId)
+	profileResponse, err := profilesService.GetProfileContext(r.Context(), cookieObj.ProfileId,
+		resolveAppScope(r, utils.ExtractOrgHandleFromPath(r)))
 	if err != nil {
 		utils.HandleError(w, err)
 		return true
@@ -636,7 +1916,7 @@ func detectScheme(r *http.Request) string {
 
 func (ph *ProfileHandler) UpdateProfile(writer http.ResponseWriter, request *http.Request) {
 
-	err := security.AuthnAndAuthz(request, "profile:update")
+	request, err := security.AuthnAndAuthz(request, "profile:update")
 	if err != nil {
 		utils.HandleError(writer, err)
 		return
@@ -660,7 +1940,7 @@ func (ph *ProfileHandler) UpdateProfile(writer http.ResponseWriter, request *htt
 	}
 
 	var profile model.ProfileRequest
-	err = json.NewDecoder(request.Body).Decode(&profile)
+	err = utils.NewJSONDecoder(request.Body).Decode(&profile)
 	if err != nil {
 		clientError := errors2.NewClientError(errors2.ErrorMessage{
 			Code:        errors2.UPDATE_PROFILE.Code,
@@ -671,16 +1951,26 @@ func (ph *ProfileHandler) UpdateProfile(writer http.ResponseWriter, request *htt
 		return
 	}
 
+	expectedVersion, err := parseIfMatch(request)
+	if err != nil {
+		utils.HandleError(writer, err)
+		return
+	}
+
 	profilesProvider := provider.NewProfilesProvider()
 	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(writer, err)
+		return
+	}
 
-	_, err = profilesService.UpdateProfile(profileId, orgHandle, profile)
+	_, err = profilesService.UpdateProfile(request.Context(), profileId, orgHandle, profile, expectedVersion)
 	if err != nil {
 		utils.HandleError(writer, err)
 		return
 	}
 
-	profileResponse, err := profilesService.GetProfile(profileId)
+	profileResponse, err := profilesService.GetProfileFromPrimary(profileId, resolveAppScope(request, orgHandle))
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to update profile with profileId: %s", profileId)
 		log.GetLogger().Debug(errMsg, log.Error(err))
@@ -691,13 +1981,14 @@ func (ph *ProfileHandler) UpdateProfile(writer http.ResponseWriter, request *htt
 		}, err)
 		utils.HandleError(writer, serverError)
 	}
+	setProfileETag(writer, profileResponse)
 	utils.RespondJSON(writer, http.StatusOK, profileResponse, constants.ProfileResource)
 }
 
 // PatchProfile handles partial updates to a profile
 func (ph *ProfileHandler) PatchProfile(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "profile:update")
+	r, err := security.AuthnAndAuthz(r, "profile:update")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -721,7 +2012,7 @@ func (ph *ProfileHandler) PatchProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var patchData map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&patchData); err != nil {
+	if err := utils.NewJSONDecoder(r.Body).Decode(&patchData); err != nil {
 		clientError := errors2.NewClientError(errors2.ErrorMessage{
 			Code:        errors2.UPDATE_PROFILE.Code,
 			Message:     errors2.UPDATE_PROFILE.Message,
@@ -730,14 +2021,24 @@ func (ph *ProfileHandler) PatchProfile(w http.ResponseWriter, r *http.Request) {
 		utils.HandleError(w, clientError)
 	}
 
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+
 	profilesProvider := provider.NewProfilesProvider()
 	profilesService := profilesProvider.GetProfilesService()
-	_, err = profilesService.PatchProfile(profileId, orgHandle, patchData)
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	_, err = profilesService.PatchProfile(r.Context(), profileId, orgHandle, patchData, expectedVersion)
 	if err != nil {
 		utils.HandleError(w, err)
 		return
 	}
-	profileResponse, err := profilesService.GetProfile(profileId)
+	profileResponse, err := profilesService.GetProfileFromPrimary(profileId, resolveAppScope(r, orgHandle))
 	if err != nil {
 		errMsg := fmt.Sprintf("Failed to update profile with profileId: %s", profileId)
 		log.GetLogger().Debug(errMsg, log.Error(err))
@@ -748,6 +2049,7 @@ func (ph *ProfileHandler) PatchProfile(w http.ResponseWriter, r *http.Request) {
 		}, err)
 		utils.HandleError(w, serverError)
 	}
+	setProfileETag(w, profileResponse)
 	utils.RespondJSON(w, http.StatusOK, profileResponse, constants.ProfileResource)
 }
 
@@ -755,7 +2057,8 @@ func (ph *ProfileHandler) PatchProfile(w http.ResponseWriter, r *http.Request) {
 func (ph *ProfileHandler) PatchCurrentUserProfile(w http.ResponseWriter, r *http.Request) {
 
 	logger := log.GetLogger()
-	if err := security.AuthnAndAuthz(r, "profile:update"); err != nil {
+	r, err := security.AuthnAndAuthz(r, "profile:update")
+	if err != nil {
 		utils.HandleError(w, err)
 		return
 	}
@@ -772,7 +2075,6 @@ func (ph *ProfileHandler) PatchCurrentUserProfile(w http.ResponseWriter, r *http
 	}
 
 	var profileId string
-	var err error
 	profilesProvider := provider.NewProfilesProvider()
 	profilesService := profilesProvider.GetProfilesService()
 
@@ -833,7 +2135,7 @@ func (ph *ProfileHandler) PatchCurrentUserProfile(w http.ResponseWriter, r *http
 
 	// Parse patch payload
 	var patchData map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&patchData); err != nil {
+	if err := utils.NewJSONDecoder(r.Body).Decode(&patchData); err != nil {
 		clientError := errors2.NewClientError(errors2.ErrorMessage{
 			Code:        errors2.UPDATE_PROFILE.Code,
 			Message:     errors2.UPDATE_PROFILE.Message,
@@ -843,14 +2145,26 @@ func (ph *ProfileHandler) PatchCurrentUserProfile(w http.ResponseWriter, r *http
 		return
 	}
 
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+
 	// Apply patch
-	updatedProfile, err := profilesService.PatchProfile(profileId, orgHandle, patchData)
+	_, err = profilesService.PatchProfile(r.Context(), profileId, orgHandle, patchData, expectedVersion)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	updatedProfile, err := profilesService.GetProfileFromPrimary(profileId, resolveAppScope(r, orgHandle))
 	if err != nil {
 		utils.HandleError(w, err)
 		return
 	}
 
 	// Return updated profile
+	setProfileETag(w, updatedProfile)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(updatedProfile); err != nil {
@@ -865,6 +2179,306 @@ func (ph *ProfileHandler) PatchCurrentUserProfile(w http.ResponseWriter, r *http
 	}
 }
 
+// ImportProfiles handles streaming import of profiles from an NDJSON request body. Lines are decoded as they are
+// read and the result of each record is streamed back as an NDJSON line once it is processed. The optional source
+// query parameter identifies the integration sending the records for quarantine purposes.
+func (ph *ProfileHandler) ImportProfiles(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:create")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	logger := log.GetLogger()
+	rc := http.NewResponseController(w)
+	// Results are written while the request body is still being read.
+	if err := rc.EnableFullDuplex(); err != nil {
+		logger.Debug("Full duplex is not supported for the profile import stream", log.Error(err))
+	}
+	body := http.MaxBytesReader(w, r.Body, constants.MaxProfileImportSize)
+
+	records := make(chan model.ProfileImportRecord)
+	decodeFailures := make(chan model.ProfileImportResult)
+	go func() {
+		defer close(records)
+		defer close(decodeFailures)
+
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), constants.MaxProfileImportLineSize)
+		line := 0
+		for {
+			_ = rc.SetReadDeadline(time.Now().Add(constants.ProfileImportIdleTimeout))
+			if !scanner.Scan() {
+				break
+			}
+			line++
+			raw := bytes.TrimSpace(scanner.Bytes())
+			if len(raw) == 0 {
+				continue
+			}
+			var record model.ProfileImportRecord
+			decoder := utils.NewJSONDecoder(bytes.NewReader(raw))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&record); err != nil {
+				decodeFailures <- model.ProfileImportResult{
+					Line:        line,
+					Status:      http.StatusBadRequest,
+					Code:        errors2.ADD_PROFILE.Code,
+					Description: utils.HandleDecodeError(err, "profile"),
+				}
+				continue
+			}
+			record.Line = line
+			records <- record
+		}
+		if err := scanner.Err(); err != nil {
+			failure := model.ProfileImportResult{
+				Line:        line + 1,
+				Status:      http.StatusBadRequest,
+				Code:        errors2.ADD_PROFILE.Code,
+				Description: "Failed to read the profile import stream.",
+			}
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				failure.Status = http.StatusRequestEntityTooLarge
+				failure.Description = fmt.Sprintf("Profile import stream exceeds the limit of %d bytes.",
+					maxBytesError.Limit)
+			} else if errors.Is(err, bufio.ErrTooLong) {
+				failure.Description = fmt.Sprintf("Profile import line exceeds the limit of %d bytes.",
+					constants.MaxProfileImportLineSize)
+			}
+			decodeFailures <- failure
+		}
+	}()
+
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	source := strings.TrimSpace(r.URL.Query().Get(constants.ImportSource))
+	if source == "" {
+		source = constants.DefaultImportSource
+	}
+	results := profilesService.ImportProfiles(r.Context(), orgHandle, source, records)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	clientGone := false
+	write := func(result model.ProfileImportResult) {
+		if clientGone {
+			return
+		}
+		_ = rc.SetWriteDeadline(time.Now().Add(constants.ProfileImportIdleTimeout))
+		if err := encoder.Encode(result); err != nil {
+			logger.Debug("Failed to write profile import result. Discarding the remaining results.", log.Error(err))
+			clientGone = true
+			return
+		}
+		_ = rc.Flush()
+	}
+
+	// Keep draining both channels until the stream is fully processed so that no goroutine is left blocked.
+	for results != nil || decodeFailures != nil {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				results = nil
+				continue
+			}
+			write(result)
+		case failure, ok := <-decodeFailures:
+			if !ok {
+				decodeFailures = nil
+				continue
+			}
+			write(failure)
+		}
+	}
+}
+
+// ApplyProfilesBatch handles creating and updating a batch of profiles in a single request
+func (ph *ProfileHandler) ApplyProfilesBatch(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:create")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	var records []model.ProfileImportRecord
+	decoder := utils.NewJSONDecoder(http.MaxBytesReader(w, r.Body, constants.MaxProfileBatchRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&records); err != nil {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: utils.HandleDecodeError(err, "profile"),
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	if len(records) == 0 || len(records) > constants.MaxProfileBatchSize {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:    errors2.ADD_PROFILE.Code,
+			Message: errors2.ADD_PROFILE.Message,
+			Description: fmt.Sprintf("A profile batch must contain between 1 and %d profiles.",
+				constants.MaxProfileBatchSize),
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	source := strings.TrimSpace(r.URL.Query().Get(constants.ImportSource))
+	if source == "" {
+		source = constants.DefaultImportSource
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), constants.ProfileBatchTimeout)
+	defer cancel()
+	results := profilesService.ApplyProfilesBatch(ctx, orgHandle, source, records)
+	failed := 0
+	for _, result := range results {
+		if result.Status >= http.StatusBadRequest {
+			failed++
+		}
+	}
+	if failed > 0 {
+		utils.HandleError(w, errors2.NewBatchError(results, failed, len(results)))
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{"results": results}, constants.ProfileResource)
+}
+
+// GetQuarantinedImportRecords handles listing the records held back from quarantined import sources
+func (ph *ProfileHandler) GetQuarantinedImportRecords(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:view")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	records, err := profilesService.GetQuarantinedImportRecords(orgHandle,
+		strings.TrimSpace(r.URL.Query().Get(constants.ImportSource)))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, records, constants.ProfileResource)
+}
+
+// ReplayQuarantinedImportRecord handles applying a quarantined import record
+func (ph *ProfileHandler) ReplayQuarantinedImportRecord(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:create")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	result, err := profilesService.ReplayQuarantinedImportRecord(orgHandle, r.PathValue("recordId"))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, result, constants.ProfileResource)
+}
+
+// DiscardQuarantinedImportRecord handles discarding a quarantined import record
+func (ph *ProfileHandler) DiscardQuarantinedImportRecord(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:delete")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	err = profilesService.DiscardQuarantinedImportRecord(orgHandle, r.PathValue("recordId"))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReleaseImportSource handles lifting the quarantine of an import source
+func (ph *ProfileHandler) ReleaseImportSource(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "profile:create")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	profilesProvider := provider.NewProfilesProvider()
+	profilesService := profilesProvider.GetProfilesService()
+	profilesService.ReleaseImportSource(orgHandle, r.PathValue("source"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (ph *ProfileHandler) SyncProfile(writer http.ResponseWriter, request *http.Request) {
 
 	err := security.AuthnWithAdminCredentials(request)
@@ -875,7 +2489,11 @@ func (ph *ProfileHandler) SyncProfile(writer http.ResponseWriter, request *http.
 
 	var profileSync model.ProfileSync
 	logger := log.GetLogger()
-	err = json.NewDecoder(request.Body).Decode(&profileSync)
+	decoder := utils.NewJSONDecoder(request.Body)
+	if config.GetCDSRuntime().Config.Validation.RejectUnknownSyncFields {
+		decoder.DisallowUnknownFields()
+	}
+	err = decoder.Decode(&profileSync)
 	if err != nil {
 		clientError := errors2.NewClientError(errors2.ErrorMessage{
 			Code:        errors2.UPDATE_PROFILE.Code,
@@ -885,6 +2503,10 @@ func (ph *ProfileHandler) SyncProfile(writer http.ResponseWriter, request *http.
 		utils.HandleError(writer, clientError)
 		return
 	}
+	if err = profileService.ValidateProfileSync(profileSync); err != nil {
+		utils.HandleError(writer, err)
+		return
+	}
 
 	profilesProvider := provider.NewProfilesProvider()
 	profilesService := profilesProvider.GetProfilesService()
@@ -893,19 +2515,6 @@ func (ph *ProfileHandler) SyncProfile(writer http.ResponseWriter, request *http.
 	identityClaims := profileSync.Claims
 	orgHandle := profileSync.OrgHandle
 
-	if orgHandle == "" {
-		//orgHandle = utils.ExtractOrgHandleFromPath(request)
-		//todo: should we expect orgHandle in the path or as body param
-		errMsg := fmt.Sprintf("Organization handle cannot be empty in profile sync event: %s", profileSync.Event)
-		clientError := errors2.NewClientError(errors2.ErrorMessage{
-			Code:        errors2.UPDATE_PROFILE.Code,
-			Message:     errors2.UPDATE_PROFILE.Message,
-			Description: errMsg,
-		}, http.StatusBadRequest)
-		utils.HandleError(writer, clientError)
-		return
-	}
-
 	if !isCDSEnabled(orgHandle) {
 		errMsg := "Unable to process profile sync event as CDS is not enabled for organization: " + orgHandle
 		log.GetLogger().Info(errMsg)
@@ -930,7 +2539,7 @@ func (ph *ProfileHandler) SyncProfile(writer http.ResponseWriter, request *http.
 			}
 
 			// This scenario is when the user anonymously tried and then trying to signup or login. So profile with profile id exists
-			existingProfile, err = profilesService.GetProfile(profileId)
+			existingProfile, err = profilesService.GetProfileFromPrimary(profileId, model.AllApplications)
 			if err != nil {
 				utils.HandleError(writer, err)
 				return
@@ -954,7 +2563,7 @@ func (ph *ProfileHandler) SyncProfile(writer http.ResponseWriter, request *http.
 				}
 
 				// Save updated profile
-				_, err = profilesService.UpdateProfile(existingProfile.ProfileId, orgHandle, profileRequest)
+				_, err = profilesService.UpdateProfile(request.Context(), existingProfile.ProfileId, orgHandle, profileRequest, 0)
 				if err != nil {
 					utils.HandleError(writer, err)
 					return
@@ -983,7 +2592,7 @@ func (ph *ProfileHandler) SyncProfile(writer http.ResponseWriter, request *http.
 					UserId:             profileSync.UserId,
 					IdentityAttributes: identityAttributes,
 				}
-				_, err := profilesService.CreateProfile(profileRequest, orgHandle)
+				_, err := profilesService.CreateProfileContext(request.Context(), profileRequest, orgHandle)
 				if err != nil {
 					utils.HandleError(writer, err)
 					return
@@ -1033,7 +2642,7 @@ func (ph *ProfileHandler) SyncProfile(writer http.ResponseWriter, request *http.
 					UserId:             profileSync.UserId,
 					IdentityAttributes: identityAttributes,
 				}
-				_, err := profilesService.CreateProfile(profileRequest, orgHandle)
+				_, err := profilesService.CreateProfileContext(request.Context(), profileRequest, orgHandle)
 
 				if err != nil {
 					return
@@ -1059,7 +2668,7 @@ func (ph *ProfileHandler) SyncProfile(writer http.ResponseWriter, request *http.
 				}
 
 				// Save updated profile
-				_, err = profilesService.UpdateProfile(existingProfile.ProfileId, orgHandle, profileRequest)
+				_, err = profilesService.UpdateProfile(request.Context(), existingProfile.ProfileId, orgHandle, profileRequest, 0)
 				if err != nil {
 					utils.HandleError(writer, err)
 					return
@@ -1087,7 +2696,7 @@ func (ph *ProfileHandler) GetProfileConsents(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err := security.AuthnAndAuthz(r, "profile:view")
+	r, err := security.AuthnAndAuthz(r, "profile:view")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -1107,6 +2716,10 @@ func (ph *ProfileHandler) GetProfileConsents(w http.ResponseWriter, r *http.Requ
 	// Get the profiles provider and service
 	profilesProvider := provider.NewProfilesProvider()
 	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
 
 	// Verify profile exists first
 	consentRecords, err := profilesService.GetProfileConsents(profileId)
@@ -1129,7 +2742,7 @@ func (ph *ProfileHandler) UpdateProfileConsents(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err := security.AuthnAndAuthz(r, "profile:update")
+	r, err := security.AuthnAndAuthz(r, "profile:update")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -1149,9 +2762,13 @@ func (ph *ProfileHandler) UpdateProfileConsents(w http.ResponseWriter, r *http.R
 	// Get the profiles provider and service
 	profilesProvider := provider.NewProfilesProvider()
 	profilesService := profilesProvider.GetProfilesService()
+	if err := profilesService.EnsureProfileInOrg(profileId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
 
 	// Verify profile exists first
-	_, err = profilesService.GetProfile(profileId)
+	_, err = profilesService.GetProfileFromPrimary(profileId, model.AllApplications)
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -1201,6 +2818,9 @@ func extractClaimKeyFromLocalURI(localURI string) string {
 }
 
 func getCallerAppIDFromRequest(r *http.Request) string {
+	if identity, ok := security.IdentityFromContext(r.Context()); ok {
+		return identity.AppId
+	}
 	authHeader := r.Header.Get("Authorization")
 	if !strings.HasPrefix(authHeader, "Bearer ") {
 		return ""
@@ -1231,6 +2851,49 @@ func getCallerAppIDFromRequest(r *http.Request) string {
 	return extractAppIDFromClaims(introspectionClaims)
 }
 
+// setProfileETag sets the version of the profile as its entity tag, for use in the If-Match header of updates.
+func setProfileETag(w http.ResponseWriter, profile *model.ProfileResponse) {
+
+	if profile == nil || profile.Meta.Version == 0 {
+		return
+	}
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(profile.Meta.Version, 10)))
+}
+
+// parseIfMatch returns the profile version required by the If-Match header of the request, or 0 when the request
+// does not require one.
+func parseIfMatch(r *http.Request) (int64, error) {
+
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`), 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: fmt.Sprintf("Invalid If-Match header: %s. Use the ETag returned for the profile.", ifMatch),
+		}, http.StatusBadRequest)
+	}
+	return version, nil
+}
+
+// resolveAppScope returns the application whose data the caller may read. System applications are not restricted to
+// a single application, while callers whose token does not identify an application are given the zero scope, which
+// reads no application data.
+func resolveAppScope(r *http.Request, orgHandle string) model.AppScope {
+
+	callerAppID := getCallerAppIDFromRequest(r)
+	if callerAppID == "" {
+		return model.AppScope{}
+	}
+	if isCallerSystemApplication(orgHandle, callerAppID) {
+		return model.AllApplications
+	}
+	return model.ApplicationScope(callerAppID)
+}
+
 // extractAppIDFromClaims tries to extract the application ID from standard claims like "azp" or "client_id"
 func extractAppIDFromClaims(claims map[string]interface{}) string {
 	// Try azp  (standard OAuth 2.0 claim for app identification)
@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package model
+
+// AppScope is the application on whose behalf profiles are read. It limits the application data to that
+// application and leaves out the traits it may not read. The zero value belongs to callers that do not identify an
+// application and reads no application data and no restricted traits; reads that may see every application must
+// ask for AllApplications.
+type AppScope struct {
+	appId string
+	all   bool
+}
+
+// AllApplications is the scope of system applications and internal reads, which are not restricted to an
+// application.
+var AllApplications = AppScope{all: true}
+
+// ApplicationScope returns the scope of the given application.
+func ApplicationScope(appId string) AppScope {
+
+	return AppScope{appId: appId}
+}
+
+// IsUnrestricted reports whether the scope covers every application.
+func (s AppScope) IsUnrestricted() bool {
+
+	return s.all
+}
+
+// AppId returns the application of the scope. It is empty for unrestricted scopes and for callers that do not
+// identify an application.
+func (s AppScope) AppId() string {
+
+	return s.appId
+}
+
+// Allows reports whether the scope may read the data of the given application.
+func (s AppScope) Allows(appId string) bool {
+
+	return s.all || (s.appId != "" && s.appId == appId)
+}
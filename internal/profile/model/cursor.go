@@ -25,6 +25,9 @@ import (
 	"time"
 )
 
+// nullSortValue marks a sorted cursor whose boundary row had no value for the sort field.
+const nullSortValue = "~"
+
 func EncodeProfileCursor(c ProfileCursor) string {
 	dir := strings.TrimSpace(c.Direction)
 	if dir == "" {
@@ -37,6 +40,14 @@ func EncodeProfileCursor(c ProfileCursor) string {
 		strings.TrimSpace(c.ProfileId),
 		dir,
 	)
+	if c.Sorted {
+		// Sort key is nested-encoded so that arbitrary attribute values cannot clash with the separator.
+		sortKey := nullSortValue
+		if c.SortValue != nil {
+			sortKey = base64.RawURLEncoding.EncodeToString([]byte(*c.SortValue))
+		}
+		raw += "|" + sortKey
+	}
 	return base64.RawURLEncoding.EncodeToString([]byte(raw))
 }
 
@@ -51,7 +62,7 @@ func DecodeProfileCursor(s string) (*ProfileCursor, error) {
 	}
 
 	parts := strings.Split(string(b), "|")
-	if len(parts) < 2 || len(parts) > 3 {
+	if len(parts) < 2 || len(parts) > 4 {
 		return nil, fmt.Errorf("invalid cursor format")
 	}
 
@@ -76,9 +87,21 @@ func DecodeProfileCursor(s string) (*ProfileCursor, error) {
 		return nil, fmt.Errorf("invalid cursor direction")
 	}
 
-	return &ProfileCursor{
+	cursor := &ProfileCursor{
 		CreatedAt: t.UTC(),
 		ProfileId: id,
 		Direction: dir,
-	}, nil
+	}
+	if len(parts) == 4 {
+		cursor.Sorted = true
+		if parts[3] != nullSortValue {
+			v, err := base64.RawURLEncoding.DecodeString(parts[3])
+			if err != nil {
+				return nil, fmt.Errorf("invalid cursor sort value")
+			}
+			sortValue := string(v)
+			cursor.SortValue = &sortValue
+		}
+	}
+	return cursor, nil
 }
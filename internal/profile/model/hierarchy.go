@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package model
+
+import "errors"
+
+// MaxProfileHierarchyDepth bounds the number of reference links followed from a profile. Unification keeps
+// hierarchies a single level deep, so anything longer indicates a corrupted hierarchy.
+const MaxProfileHierarchyDepth = 8
+
+// ErrProfileHierarchyCycle is returned when the reference links of a profile loop back on themselves or do not
+// reach a reference profile within MaxProfileHierarchyDepth links.
+var ErrProfileHierarchyCycle = errors.New("profile hierarchy cycle")
+
+// ReferenceLookup returns the id of the profile the given profile is merged to, or "" if it is a reference profile.
+type ReferenceLookup func(profileId string) (string, error)
+
+// CheckReferenceLink returns ErrProfileHierarchyCycle if merging the profile to the reference profile would create
+// a loop, i.e. the profile is the reference profile itself or one of the profiles it is merged to.
+func CheckReferenceLink(referenceProfileId, profileId string, lookup ReferenceLookup) error {
+
+	_, err := followReferences(referenceProfileId, map[string]bool{profileId: true}, lookup)
+	return err
+}
+
+// ResolveReferenceProfileId follows the reference links of the profile and returns the reference profile at the
+// top of its hierarchy.
+func ResolveReferenceProfileId(profileId string, lookup ReferenceLookup) (string, error) {
+
+	return followReferences(profileId, map[string]bool{}, lookup)
+}
+
+func followReferences(profileId string, visited map[string]bool, lookup ReferenceLookup) (string, error) {
+
+	current := profileId
+	for depth := 0; ; depth++ {
+		if visited[current] || depth > MaxProfileHierarchyDepth {
+			return "", ErrProfileHierarchyCycle
+		}
+		visited[current] = true
+		next, err := lookup(current)
+		if err != nil {
+			return "", err
+		}
+		if next == "" {
+			return current, nil
+		}
+		current = next
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package model
+
+import (
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Kinds of change of a trait between two versions of a profile.
+const (
+	TraitAdded    = "added"
+	TraitRemoved  = "removed"
+	TraitModified = "modified"
+)
+
+// ProfileSnapshot is the traits of a profile at one of its versions along with how they changed from the version
+// before it. The oldest snapshot retained has no changes, as the version before it is no longer known.
+type ProfileSnapshot struct {
+	Version int64                  `json:"version"`
+	Traits  map[string]interface{} `json:"traits"`
+	// RecordedAt is when the version was written.
+	RecordedAt time.Time     `json:"recorded_at"`
+	Changes    []TraitChange `json:"changes"`
+}
+
+// TraitChange is the change of a single trait, identified by its dotted path (e.g. "address.city").
+type TraitChange struct {
+	Trait    string      `json:"trait"`
+	Change   string      `json:"change"`
+	OldValue interface{} `json:"old_value,omitempty"`
+	NewValue interface{} `json:"new_value,omitempty"`
+}
+
+// DiffTraits lists the traits that differ between two versions of a profile, ordered by path. Nested objects are
+// compared trait by trait while any other values, including lists, are compared as a whole.
+func DiffTraits(before, after map[string]interface{}) []TraitChange {
+
+	changes := make([]TraitChange, 0)
+	diffTraits("", before, after, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Trait < changes[j].Trait })
+	return changes
+}
+
+func diffTraits(prefix string, before, after map[string]interface{}, changes *[]TraitChange) {
+
+	for key, oldValue := range before {
+		path := prefix + key
+		newValue, ok := after[key]
+		if !ok {
+			*changes = append(*changes, TraitChange{Trait: path, Change: TraitRemoved, OldValue: oldValue})
+			continue
+		}
+		oldObject, oldIsObject := oldValue.(map[string]interface{})
+		newObject, newIsObject := newValue.(map[string]interface{})
+		if oldIsObject && newIsObject {
+			diffTraits(path+".", oldObject, newObject, changes)
+			continue
+		}
+		if !reflect.DeepEqual(oldValue, newValue) {
+			*changes = append(*changes, TraitChange{Trait: path, Change: TraitModified, OldValue: oldValue,
+				NewValue: newValue})
+		}
+	}
+	for key, newValue := range after {
+		if _, ok := before[key]; !ok {
+			*changes = append(*changes, TraitChange{Trait: prefix + key, Change: TraitAdded, NewValue: newValue})
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// ProfileLineage describes a unified profile hierarchy: the reference profile and the profiles merged to it, with
+// the reason of each link.
+type ProfileLineage struct {
+	ProfileId        string         `json:"profile_id"`
+	ReferenceProfile LineageProfile `json:"reference_profile"`
+	MergedProfiles   []LineageLink  `json:"merged_profiles"`
+}
+
+// LineageProfile identifies a profile of a hierarchy.
+type LineageProfile struct {
+	ProfileId string `json:"profile_id"`
+	UserId    string `json:"user_id,omitempty"`
+}
+
+// LineageLink explains why a profile is merged to the reference profile of its hierarchy.
+type LineageLink struct {
+	LineageProfile
+	// Reason is the name of the unification rule that merged the profile, or "manual_merge".
+	Reason   string `json:"reason"`
+	RuleId   string `json:"rule_id,omitempty"`
+	Property string `json:"property_name,omitempty"`
+	// MatchedValue is the value of the rule property on the merged profile.
+	MatchedValue interface{} `json:"matched_value,omitempty"`
+}
+
+// ChildProfile is a profile merged directly to a reference profile, with the reason it was merged.
+type ChildProfile struct {
+	ProfileId    string    `json:"profile_id"`
+	UserId       string    `json:"user_id,omitempty"`
+	Reason       string    `json:"reason"`
+	RuleId       string    `json:"rule_id,omitempty"`
+	MatchedValue string    `json:"matched_value,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// PropertyValue returns the value of a unification property (e.g. "user_id", "identity_attributes.email" or
+// "application_data.device_id") on the profile, or nil when the profile has no value for it.
+func (p Profile) PropertyValue(propertyName string) interface{} {
+
+	if propertyName == "user_id" {
+		if p.UserId == "" {
+			return nil
+		}
+		return p.UserId
+	}
+
+	parts := strings.Split(propertyName, ".")
+	if len(parts) < 2 {
+		return nil
+	}
+	var current interface{}
+	switch parts[0] {
+	case "identity_attributes":
+		current = p.IdentityAttributes
+	case "traits":
+		current = p.Traits
+	case "application_data":
+		for _, appData := range p.ApplicationData {
+			if val := lookupPath(appData.AppSpecificData, parts[1:]); val != nil {
+				return val
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+	m, _ := current.(map[string]interface{})
+	return lookupPath(m, parts[1:])
+}
+
+func lookupPath(m map[string]interface{}, path []string) interface{} {
+
+	var current interface{} = m
+	for _, segment := range path {
+		next, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = next[segment]
+	}
+	return current
+}
@@ -36,6 +36,17 @@ type ProfileStatus struct {
 type Reference struct {
 	ProfileId string `json:"profile_id,omitempty" bson:"profile_id,omitempty"`
 	Reason    string `json:"reason,omitempty" bson:"rule_name,omitempty"`
+	// RuleId is the id of the unification rule that linked the profiles. Empty for manual merges.
+	RuleId string `json:"rule_id,omitempty" bson:"rule_id,omitempty"`
+	// MatchedValue is the property value both profiles shared when the rule linked them.
+	MatchedValue string `json:"matched_value,omitempty" bson:"matched_value,omitempty"`
+}
+
+// UnmergeExclusion records that two profiles were unmerged and must not be unified again by the rule.
+type UnmergeExclusion struct {
+	ProfileId         string `json:"profile_id"`
+	ExcludedProfileId string `json:"excluded_profile_id"`
+	RuleName          string `json:"rule_name"`
 }
 
 type Profile struct {
@@ -49,6 +60,10 @@ type Profile struct {
 	Traits             map[string]interface{} `json:"traits,omitempty" bson:"traits,omitempty"`
 	ApplicationData    []ApplicationData      `json:"application_data,omitempty" bson:"application_data,omitempty"`
 	ProfileStatus      *ProfileStatus         `json:"profile_status,omitempty" bson:"profile_status,omitempty"`
+	DeletedAt          *time.Time             `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"` // Set when soft-deleted
+	// Version is incremented on every update of the profile. On updates it holds the expected current version,
+	// or 0 to update regardless of the version.
+	Version int64 `json:"version,omitempty" bson:"version,omitempty"`
 }
 
 type ProfileCookie struct {
@@ -78,4 +93,25 @@ type ProfileCursor struct {
 	CreatedAt time.Time `json:"created_at"`
 	ProfileId string    `json:"profile_id"`
 	Direction string    `json:"direction,omitempty"` // "next" or "prev"
+	// Sorted marks cursors issued for a listing ordered by a custom sort field.
+	Sorted bool `json:"sorted,omitempty"`
+	// SortValue is the sort key of the boundary row. Nil when the row had no value for the sort field.
+	SortValue *string `json:"sort_value,omitempty"`
+}
+
+// ProfileSort describes a requested ordering of the profile listing.
+type ProfileSort struct {
+	Field      string `json:"field"`
+	Descending bool   `json:"descending"`
+	ValueType  string `json:"value_type,omitempty"` // Resolved from the profile schema
+}
+
+// ProfileQuery describes a page of a filtered profile listing.
+type ProfileQuery struct {
+	Filters        []string
+	Sort           *ProfileSort // Nil orders the profiles by creation time
+	IncludeDeleted bool
+	Limit          int
+	Cursor         *ProfileCursor
+	AppScope       AppScope
 }
@@ -21,8 +21,12 @@ package model
 import "time"
 import "github.com/wso2/identity-customer-data-service/internal/system/pagination"
 
+// ProfileResponse is a profile as returned by the API. CanonicalProfileId is the id of the reference profile holding
+// the data of the profile, which is the profile itself unless it was merged, so that clients holding the id of a
+// merged profile can move their references to it.
 type ProfileResponse struct {
 	ProfileId          string                            `json:"profile_id" bson:"profile_id"`
+	CanonicalProfileId string                            `json:"canonical_profile_id,omitempty" bson:"canonical_profile_id,omitempty"`
 	UserId             string                            `json:"user_id,omitempty" bson:"user_id,omitempty"`
 	Meta               Meta                              `json:"meta" bson:"meta"`
 	IdentityAttributes map[string]interface{}            `json:"identity_attributes,omitempty" bson:"identity_attributes,omitempty"`
@@ -32,8 +36,16 @@ type ProfileResponse struct {
 	MergedFrom         []Reference                       `json:"merged_from,omitempty" bson:"merged_from,omitempty"`
 }
 
+// ProfileProjection is a profile holding only the requested top-level traits and identity attributes.
+type ProfileProjection struct {
+	ProfileId          string                 `json:"profile_id"`
+	IdentityAttributes map[string]interface{} `json:"identity_attributes,omitempty"`
+	Traits             map[string]interface{} `json:"traits,omitempty"`
+}
+
 type ProfileListResponse struct {
 	ProfileId          string                            `json:"profile_id" bson:"profile_id"`
+	CanonicalProfileId string                            `json:"canonical_profile_id,omitempty" bson:"canonical_profile_id,omitempty"`
 	UserId             string                            `json:"user_id,omitempty" bson:"user_id,omitempty"`
 	Meta               Meta                              `json:"meta" bson:"meta"`
 	IdentityAttributes map[string]interface{}            `json:"identity_attributes,omitempty" bson:"identity_attributes,omitempty"`
@@ -43,12 +55,16 @@ type ProfileListResponse struct {
 }
 
 type Meta struct {
-	CreatedAt time.Time `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
-	Location  string    `json:"location" bson:"location"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" bson:"updated_at"`
+	Location  string     `json:"location" bson:"location"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	Version   int64      `json:"version,omitempty" bson:"version,omitempty"`
 }
 
 type ProfileRequest struct {
+	// ProfileId is the id of a new profile when the ids of profiles are provided by the caller.
+	ProfileId          string                            `json:"profile_id,omitempty" bson:"profile_id,omitempty"`
 	UserId             string                            `json:"user_id" bson:"user_id"`
 	IdentityAttributes map[string]interface{}            `json:"identity_attributes,omitempty" bson:"identity_attributes,omitempty"`
 	Traits             map[string]interface{}            `json:"traits,omitempty" bson:"traits,omitempty"`
@@ -64,6 +80,43 @@ type ProfileSync struct {
 	OrgHandle     string                 `json:"orgHandle,omitempty" bson:"orgHandle,omitempty"`
 }
 
+// ProfileSimulation is the profile a profile request would produce, computed without storing it. UnifiedProfile is
+// the reference profile the simulated profile would be unified into, as it would look after the merge.
+type ProfileSimulation struct {
+	Profile        ProfileResponse  `json:"profile"`
+	UnifiedProfile *ProfileResponse `json:"unified_profile,omitempty"`
+}
+
+// ProfileBulkPatchRequest holds the traits to set on every profile matching a filter.
+type ProfileBulkPatchRequest struct {
+	Traits map[string]interface{} `json:"traits"`
+}
+
+// ProfileCountResponse holds the number of profiles for each value of the trait they are grouped by.
+type ProfileCountResponse struct {
+	GroupBy string           `json:"group_by"`
+	Counts  map[string]int64 `json:"counts"`
+}
+
+// HierarchyStats summarizes how the profiles of an organization are unified. A master is a reference profile that
+// other profiles are merged to, and its cluster is the master together with those profiles. Orphaned profiles are
+// merged to a reference profile that no longer exists.
+type HierarchyStats struct {
+	TotalProfiles            int64   `json:"total_profiles"`
+	ReferenceProfiles        int64   `json:"reference_profiles"`
+	MergedProfiles           int64   `json:"merged_profiles"`
+	MasterProfiles           int64   `json:"master_profiles"`
+	OrphanedProfiles         int64   `json:"orphaned_profiles"`
+	AverageChildrenPerMaster float64 `json:"average_children_per_master"`
+	LargestClusterSize       int64   `json:"largest_cluster_size"`
+}
+
+// DistinctTraitValuesResponse lists the distinct values a trait has among the profiles.
+type DistinctTraitValuesResponse struct {
+	Trait  string   `json:"trait"`
+	Values []string `json:"values"`
+}
+
 type ProfileListAPIResponse struct {
 	Pagination pagination.Pagination `json:"pagination"`
 	Items      []ProfileListResponse `json:"profiles"`
@@ -0,0 +1,32 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package model
+
+import "time"
+
+// ProfileExport holds all the data kept about a person, for data subject access requests. Profile is the unified
+// view of the person, and ChildProfiles are the profiles merged into it with the data each was created with.
+type ProfileExport struct {
+	ProfileId     string           `json:"profile_id"`
+	OrgHandle     string           `json:"org_handle"`
+	ExportedAt    time.Time        `json:"exported_at"`
+	Profile       *ProfileResponse `json:"profile"`
+	Consents      []ConsentRecord  `json:"consents"`
+	ChildProfiles []Profile        `json:"child_profiles"`
+}
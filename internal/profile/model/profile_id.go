@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+// ProfileIdGenerator generates the ids of new profiles.
+type ProfileIdGenerator interface {
+	NewProfileId() (string, error)
+}
+
+// uuidV4Generator generates random ids.
+type uuidV4Generator struct{}
+
+func (uuidV4Generator) NewProfileId() (string, error) {
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// uuidV7Generator generates ids ordered by their creation time, so that new profiles are appended to the end of the
+// primary key index instead of being scattered across it.
+type uuidV7Generator struct{}
+
+func (uuidV7Generator) NewProfileId() (string, error) {
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// ProfileIdGeneratorFor returns the generator of the given profile id strategy. Caller provided ids are only
+// accepted on profile creations, so profiles created without one get time-ordered ids. Unknown strategies generate
+// random ids.
+func ProfileIdGeneratorFor(strategy string) ProfileIdGenerator {
+
+	switch strategy {
+	case constants.ProfileIdUUIDv7, constants.ProfileIdCallerProvided:
+		return uuidV7Generator{}
+	default:
+		return uuidV4Generator{}
+	}
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package model
+
+import "time"
+
+// ProfileImportRecord is a single line of an NDJSON profile import stream. Records carrying a profile id replace
+// the existing profile while the others create a new profile.
+type ProfileImportRecord struct {
+	Line      int    `json:"-"`
+	ProfileId string `json:"profile_id,omitempty"`
+	ProfileRequest
+}
+
+// ProfileImportResult is the outcome of importing a single record of the stream.
+type ProfileImportResult struct {
+	Line        int    `json:"line"`
+	ProfileId   string `json:"profile_id,omitempty"`
+	Status      int    `json:"status"`
+	Code        string `json:"code,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// QuarantinedImportRecord is an import record held back from a quarantined source until an operator replays or
+// discards it.
+type QuarantinedImportRecord struct {
+	RecordId      string              `json:"record_id"`
+	OrgHandle     string              `json:"-"`
+	Source        string              `json:"source"`
+	Line          int                 `json:"line"`
+	Record        ProfileImportRecord `json:"record"`
+	Reason        string              `json:"reason,omitempty"`
+	QuarantinedAt time.Time           `json:"quarantined_at"`
+}
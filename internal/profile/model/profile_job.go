@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package model
+
+import "time"
+
+// ProfileJob is the state of a long running maintenance job on the profiles of an organization. Processed and Total
+// count the work of the current phase of the job.
+type ProfileJob struct {
+	JobId           string    `json:"job_id"`
+	OrgHandle       string    `json:"-"`
+	Type            string    `json:"type"`
+	Status          string    `json:"status"`
+	Phase           string    `json:"phase"`
+	Processed       int64     `json:"processed"`
+	Total           int64     `json:"total"`
+	MergedProfiles  int64     `json:"merged_profiles"`
+	CancelRequested bool      `json:"cancel_requested"`
+	Error           string    `json:"error,omitempty"`
+	Cursor          string    `json:"-"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// MasterProfile is a reference profile that other profiles are merged to. Listed is false for the masters created by
+// unification, which only hold the data merged from their profiles.
+type MasterProfile struct {
+	ProfileId string
+	Listed    bool
+}
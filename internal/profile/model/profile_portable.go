@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PortablePackage is a self-contained, signed export of a single profile. The manifest and content are kept as
+// raw JSON so that the signed bytes survive re-parsing unchanged.
+type PortablePackage struct {
+	Manifest  json.RawMessage   `json:"manifest"`
+	Content   json.RawMessage   `json:"content"`
+	Signature PortableSignature `json:"signature"`
+}
+
+// PortableManifest describes a portable package. The signature of the package is computed over the manifest, which
+// in turn binds the content through its digest.
+type PortableManifest struct {
+	Format        string         `json:"format"`
+	Version       string         `json:"version"`
+	ProfileId     string         `json:"profile_id"`
+	OrgHandle     string         `json:"org_handle"`
+	ExportedAt    time.Time      `json:"exported_at"`
+	ContentDigest PortableDigest `json:"content_digest"`
+}
+
+type PortableDigest struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"value"`
+}
+
+// PortableSignature holds the signature over the manifest along with the DER encoded (base64) signing certificate.
+type PortableSignature struct {
+	Algorithm   string `json:"algorithm"`
+	Certificate string `json:"certificate"`
+	Value       string `json:"value"`
+}
+
+// PortableContent is the user data carried in a portable package. Schema describes the attributes as returned by
+// the profile schema API.
+type PortableContent struct {
+	Schema   map[string]interface{} `json:"schema"`
+	Profile  *ProfileResponse       `json:"profile"`
+	Consents []ConsentRecord        `json:"consents"`
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SortAscending  = "asc"
+	SortDescending = "desc"
+)
+
+// ParseProfileSort parses a sort expression of the form "<field> [asc|desc]".
+// Only the syntax is checked here; the field is validated against the profile schema by the service.
+func ParseProfileSort(raw string) (*ProfileSort, error) {
+
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	parts := strings.Fields(raw)
+	if len(parts) > 2 {
+		return nil, fmt.Errorf("invalid sort expression: %s", raw)
+	}
+
+	sort := &ProfileSort{Field: parts[0]}
+	if len(parts) == 2 {
+		switch strings.ToLower(parts[1]) {
+		case SortAscending:
+		case SortDescending:
+			sort.Descending = true
+		default:
+			return nil, fmt.Errorf("unsupported sort direction: %s", parts[1])
+		}
+	}
+	return sort, nil
+}
+
+// SortValueOf returns the value of the sort field for the given profile as used in pagination cursors.
+// Returns nil when the profile has no value for the field.
+func (s *ProfileSort) SortValueOf(profile ProfileResponse) *string {
+
+	var val interface{}
+	switch s.Field {
+	case "profile_id":
+		val = profile.ProfileId
+	case "user_id":
+		if profile.UserId == "" {
+			return nil
+		}
+		val = profile.UserId
+	case "created_at", "meta.created_at":
+		val = profile.Meta.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updated_at", "meta.updated_at":
+		val = profile.Meta.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		scopeKey := strings.SplitN(s.Field, ".", 2)
+		if len(scopeKey) != 2 {
+			return nil
+		}
+		var current interface{}
+		switch scopeKey[0] {
+		case "traits":
+			current = profile.Traits
+		case "identity_attributes":
+			current = profile.IdentityAttributes
+		default:
+			return nil
+		}
+		for _, segment := range strings.Split(scopeKey[1], ".") {
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			current = m[segment]
+		}
+		val = current
+	}
+
+	var str string
+	switch v := val.(type) {
+	case nil:
+		return nil
+	case string:
+		str = v
+	case float64:
+		str = strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		str = v.String()
+	case bool:
+		str = strconv.FormatBool(v)
+	default:
+		str = fmt.Sprintf("%v", v)
+	}
+	return &str
+}
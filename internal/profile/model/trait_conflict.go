@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package model
+
+import (
+	"math"
+	"sort"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+// ResolveTraitConflicts merges the traits of a child profile into the traits of its master profile. Traits held by
+// only one of them are kept as they are. When both hold a different value for a trait, the strategy decides:
+//
+//   - highest_priority (default): the master value is kept.
+//   - latest_updated: the value of the profile updated last is kept, the master winning ties.
+//   - non_null: the master value is kept unless it is empty.
+//
+// The traits of the master are not modified.
+func ResolveTraitConflicts(master, child Profile, strategy string) map[string]interface{} {
+
+	resolved := make(map[string]interface{}, len(master.Traits)+len(child.Traits))
+	for key, val := range master.Traits {
+		resolved[key] = val
+	}
+	for key, childVal := range child.Traits {
+		masterVal, exists := resolved[key]
+		if !exists {
+			resolved[key] = childVal
+			continue
+		}
+		switch strategy {
+		case constants.TraitConflictLatestUpdated:
+			if child.UpdatedAt.After(master.UpdatedAt) {
+				resolved[key] = childVal
+			}
+		case constants.TraitConflictNonNull:
+			if isEmptyTraitValue(masterVal) {
+				resolved[key] = childVal
+			}
+		}
+	}
+	return resolved
+}
+
+// ResolveHierarchyTraits resolves the traits of a reference profile and the profiles unified into it. The profiles
+// are ordered by precedence and folded with ResolveTraitConflicts, so that the profile taking precedence acts as the
+// master of each step. With highest_priority the reference profile comes first, followed by its children in the order
+// of the priority of the rule that unified them (lower value first), as given by rulePriority. With latest_updated
+// the most recently updated profile comes first.
+func ResolveHierarchyTraits(master Profile, children []Profile, strategy string,
+	rulePriority func(ruleName string) (int, bool)) map[string]interface{} {
+
+	if len(children) == 0 {
+		return master.Traits
+	}
+
+	ordered := make([]Profile, 0, len(children)+1)
+	ordered = append(ordered, master)
+	ordered = append(ordered, children...)
+
+	priorityOf := func(p Profile) int {
+		if p.ProfileStatus != nil {
+			if priority, ok := rulePriority(p.ProfileStatus.ReferenceReason); ok {
+				return priority
+			}
+		}
+		// Profiles unified by a rule that no longer exists come last.
+		return math.MaxInt
+	}
+	switch strategy {
+	case constants.TraitConflictLatestUpdated:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].UpdatedAt.After(ordered[j].UpdatedAt)
+		})
+	default:
+		sort.SliceStable(ordered[1:], func(i, j int) bool {
+			return priorityOf(ordered[1+i]) < priorityOf(ordered[1+j])
+		})
+	}
+
+	resolved := ordered[0]
+	for _, next := range ordered[1:] {
+		resolved.Traits = ResolveTraitConflicts(resolved, next, strategy)
+	}
+	return resolved.Traits
+}
+
+func isEmptyTraitValue(val interface{}) bool {
+
+	switch v := val.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
@@ -18,6 +18,10 @@
 
 package service
 
+import (
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+)
+
 type ApplicationDataFilterParams struct {
 	IncludeAppData  bool     // Whether to include application data
 	RequestedAppIDs []string // Specific app IDs to include, or "*" for all
@@ -77,3 +81,19 @@ func filterForRegularApp(appData map[string]map[string]interface{}, callerAppID
 
 	return filtered
 }
+
+// restrictApplicationData keeps only the application data the app scope may read.
+func restrictApplicationData(appData []profileModel.ApplicationData,
+	appScope profileModel.AppScope) []profileModel.ApplicationData {
+
+	if appScope.IsUnrestricted() {
+		return appData
+	}
+	restricted := make([]profileModel.ApplicationData, 0, 1)
+	for _, app := range appData {
+		if appScope.Allows(app.AppId) {
+			restricted = append(restricted, app)
+		}
+	}
+	return restricted
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	schemaModel "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaStore "github.com/wso2/identity-customer-data-service/internal/profile_schema/store"
+	"github.com/wso2/identity-customer-data-service/internal/system/changestream"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// AnonymizeProfile erases the personal data of the person of the profile without deleting the profiles, so that
+// they still count in reports. Values of the attributes marked as PII in the profile schema are replaced with random
+// tokens in the reference profile and in every profile merged into it, and the trait history of those profiles is
+// erased. Other attributes and the merge hierarchy are kept as they are.
+func (ps *ProfilesService) AnonymizeProfile(profileId string) error {
+
+	logger := log.GetLogger()
+	storedProfile, err := profileStore.GetProfile(profileId)
+	if err != nil {
+		return err
+	}
+	if storedProfile == nil {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+	masterProfileId, err := referenceProfileIdOf(storedProfile)
+	if err != nil {
+		return err
+	}
+
+	schemaAttributes, err := schemaStore.GetProfileSchemaAttributesForOrg(storedProfile.OrgHandle)
+	if err != nil {
+		return err
+	}
+	var piiAttributes []schemaModel.ProfileSchemaAttribute
+	for _, attribute := range schemaAttributes {
+		if attribute.IsPII {
+			piiAttributes = append(piiAttributes, attribute)
+		}
+	}
+
+	references, err := profileStore.FetchReferencedProfiles(masterProfileId)
+	if err != nil {
+		return err
+	}
+	profileIds := []string{masterProfileId}
+	for _, reference := range references {
+		profileIds = append(profileIds, reference.ProfileId)
+	}
+	anonymized := make([]profileModel.Profile, 0, len(profileIds))
+	for _, id := range profileIds {
+		profile, err := profileStore.GetProfile(id)
+		if err != nil {
+			return err
+		}
+		if profile == nil {
+			continue
+		}
+		if profile.ApplicationData, err = profileStore.FetchApplicationData(id); err != nil {
+			return err
+		}
+		anonymizeProfileData(profile, piiAttributes)
+		profile.UpdatedAt = time.Now().UTC()
+		anonymized = append(anonymized, *profile)
+	}
+	if err = profileStore.StoreAnonymizedProfiles(masterProfileId, anonymized); err != nil {
+		return err
+	}
+	changestream.PublishProfileChange(constants.ProfileChangeUpdated, storedProfile.OrgHandle, masterProfileId, nil)
+	logger.Info(fmt.Sprintf("Anonymized profile: %s and %d profiles merged into it", masterProfileId,
+		len(references)))
+	return nil
+}
+
+// anonymizeProfileData replaces the values of the given attributes in the traits, identity attributes and
+// application data of the profile.
+func anonymizeProfileData(profile *profileModel.Profile, piiAttributes []schemaModel.ProfileSchemaAttribute) {
+
+	for _, attribute := range piiAttributes {
+		scopeKey := strings.SplitN(attribute.AttributeName, ".", 2)
+		if len(scopeKey) != 2 {
+			continue
+		}
+		path := strings.Split(scopeKey[1], ".")
+		switch scopeKey[0] {
+		case constants.Traits:
+			anonymizeValueAt(profile.Traits, path)
+		case constants.IdentityAttributes:
+			anonymizeValueAt(profile.IdentityAttributes, path)
+		case constants.ApplicationData:
+			for _, appData := range profile.ApplicationData {
+				if attribute.ApplicationIdentifier == "" || attribute.ApplicationIdentifier == appData.AppId {
+					anonymizeValueAt(appData.AppSpecificData, path)
+				}
+			}
+		}
+	}
+}
+
+// anonymizeValueAt replaces the value at the path with tokens. Strings, including those within lists and objects,
+// are replaced by a token each so that the shape of the value is kept. Values of other types are removed.
+func anonymizeValueAt(data map[string]interface{}, path []string) {
+
+	for _, segment := range path[:len(path)-1] {
+		nested, ok := data[segment].(map[string]interface{})
+		if !ok {
+			return
+		}
+		data = nested
+	}
+	key := path[len(path)-1]
+	value, ok := data[key]
+	if !ok {
+		return
+	}
+	if anonymized := anonymizeValue(value); anonymized != nil {
+		data[key] = anonymized
+	} else {
+		delete(data, key)
+	}
+}
+
+func anonymizeValue(value interface{}) interface{} {
+
+	switch v := value.(type) {
+	case string:
+		return constants.AnonymizedValuePrefix + uuid.New().String()
+	case []interface{}:
+		anonymized := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if token := anonymizeValue(item); token != nil {
+				anonymized = append(anonymized, token)
+			}
+		}
+		return anonymized
+	case map[string]interface{}:
+		anonymized := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if token := anonymizeValue(item); token != nil {
+				anonymized[key] = token
+			}
+		}
+		return anonymized
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,237 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// defaultExportFields are the columns of a CSV export when no fields are requested.
+var defaultExportFields = []string{"profile_id", "user_id", "created_at", "updated_at"}
+
+// exportField is a column of a CSV export: a core field of the profile or a path into one of its attribute scopes.
+type exportField struct {
+	name  string
+	scope string
+	path  []string
+}
+
+// ExportProfilesCSV writes the reference profiles of the organization matching the filters to w as CSV, streaming
+// them in chunks as they are read from the database. The first row names the fields, which are core fields
+// (profile_id, user_id, created_at, updated_at) or dotted paths into the traits or identity attributes such as
+// "traits.address.city". Fields and filters are validated before anything is written, so an error returned after
+// that leaves the CSV cut short. A restricted appScope may neither export nor filter on the traits the application may
+// not read.
+func (ps *ProfilesService) ExportProfilesCSV(ctx context.Context, orgHandle string, filters, fields []string,
+	appScope profileModel.AppScope, w io.Writer) error {
+
+	columns, err := parseExportFields(fields)
+	if err != nil {
+		return err
+	}
+	restricted, err := restrictedTraitPaths(orgHandle, appScope)
+	if err != nil {
+		return err
+	}
+	traitFields := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if column.scope == constants.Traits {
+			traitFields = append(traitFields, column.name)
+		}
+	}
+	if err := checkTraitFilterAccess(append(traitFields, filters...), restricted); err != nil {
+		return err
+	}
+	rewrittenFilters, err := rewriteProfileFilters(orgHandle, filters)
+	if err != nil {
+		return err
+	}
+	needsTraits := false
+	header := make([]string, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, column.name)
+		needsTraits = needsTraits || column.scope == constants.Traits
+	}
+
+	logger := log.GetLogger()
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return exportWriteError(err)
+	}
+	exported := 0
+	err = streamProfileChunks(ctx, orgHandle, rewrittenFilters, func(profiles []profileModel.Profile) error {
+		if needsTraits {
+			traits, _, err := resolveUnifiedTraits(orgHandle, profiles)
+			if err != nil {
+				return err
+			}
+			for i := range profiles {
+				profiles[i].Traits = redactTraits(traits[profiles[i].ProfileId], restricted)
+			}
+		}
+		for _, profile := range profiles {
+			record := make([]string, 0, len(columns))
+			for _, column := range columns {
+				record = append(record, exportValue(profile, column))
+			}
+			if err := writer.Write(record); err != nil {
+				return exportWriteError(err)
+			}
+		}
+		exported += len(profiles)
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return exportWriteError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return exportWriteError(err)
+	}
+	logger.Info(fmt.Sprintf("Exported %d profiles of organization: %s as CSV", exported, orgHandle))
+	return nil
+}
+
+// parseExportFields validates the requested fields, falling back to the core fields when none are requested.
+func parseExportFields(fields []string) ([]exportField, error) {
+
+	if len(fields) == 0 {
+		fields = defaultExportFields
+	}
+	columns := make([]exportField, 0, len(fields))
+	var invalid []errors2.FieldError
+	for i, field := range fields {
+		field = strings.TrimSpace(field)
+		switch field {
+		case "profile_id", "user_id", "created_at", "updated_at":
+			columns = append(columns, exportField{name: field})
+			continue
+		}
+		scope, key, found := strings.Cut(field, ".")
+		if !found || (scope != constants.Traits && scope != constants.IdentityAttributes) || !isValidFilterKey(key) {
+			invalid = append(invalid, errors2.FieldError{Field: fmt.Sprintf("fields[%d]", i),
+				Message: fmt.Sprintf("Invalid export field: %s", field)})
+			continue
+		}
+		columns = append(columns, exportField{name: field, scope: scope, path: strings.Split(key, ".")})
+	}
+
+	if len(invalid) > 0 {
+		messages := make([]string, 0, len(invalid))
+		for _, fieldErr := range invalid {
+			messages = append(messages, fieldErr.Message)
+		}
+		return nil, errors2.NewClientErrorWithDetails(errors2.ErrorMessage{
+			Code:        errors2.INVALID_EXPORT_FIELD.Code,
+			Message:     errors2.INVALID_EXPORT_FIELD.Message,
+			Description: strings.Join(messages, "; "),
+		}, http.StatusBadRequest, invalid)
+	}
+	return columns, nil
+}
+
+// exportValue renders the value of the field on the profile as a CSV cell. Lists and objects are written as JSON
+// and a missing value as an empty cell.
+func exportValue(profile profileModel.Profile, column exportField) string {
+
+	var value interface{}
+	switch column.name {
+	case "profile_id":
+		return csvSafe(profile.ProfileId)
+	case "user_id":
+		return csvSafe(profile.UserId)
+	case "created_at":
+		return profile.CreatedAt.UTC().Format(time.RFC3339)
+	case "updated_at":
+		return profile.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	if column.scope == constants.Traits {
+		value = valueAtPath(profile.Traits, column.path)
+	} else {
+		value = valueAtPath(profile.IdentityAttributes, column.path)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return csvSafe(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return csvSafe(string(encoded))
+	}
+}
+
+// valueAtPath returns the value at the path of nested objects, or nil when there is none.
+func valueAtPath(attributes map[string]interface{}, path []string) interface{} {
+
+	var value interface{} = attributes
+	for _, key := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// csvSafe keeps spreadsheets from evaluating a text cell as a formula by prefixing the characters that start one.
+func csvSafe(value string) string {
+
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func exportWriteError(err error) error {
+
+	return errors2.NewServerError(errors2.ErrorMessage{
+		Code:        errors2.EXPORT_PROFILE.Code,
+		Message:     errors2.EXPORT_PROFILE.Message,
+		Description: "Failed to write the profile CSV export.",
+	}, err)
+}
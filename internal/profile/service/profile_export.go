@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// ExportProfile assembles everything held about the person of the profile into a single JSON document. A merged
+// profile is resolved to its reference profile, so that exporting any profile of the person exports all of them. A
+// restricted appScope restricts the application data and traits of every exported profile like in GetProfile.
+func (ps *ProfilesService) ExportProfile(profileId string, appScope profileModel.AppScope) ([]byte, error) {
+
+	logger := log.GetLogger()
+	storedProfile, err := profileStore.GetProfile(profileId)
+	if err != nil {
+		return nil, err
+	}
+	if storedProfile == nil {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+	masterProfileId, err := referenceProfileIdOf(storedProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := ps.GetProfileFromPrimary(masterProfileId, appScope)
+	if err != nil {
+		return nil, err
+	}
+	restricted, err := restrictedTraitPaths(storedProfile.OrgHandle, appScope)
+	if err != nil {
+		return nil, err
+	}
+	consents, err := profileStore.GetProfileConsents(masterProfileId)
+	if err != nil {
+		return nil, err
+	}
+	if consents == nil {
+		consents = []profileModel.ConsentRecord{}
+	}
+	references, err := profileStore.FetchReferencedProfiles(masterProfileId)
+	if err != nil {
+		return nil, err
+	}
+	children := make([]profileModel.Profile, 0, len(references))
+	for _, reference := range references {
+		child, err := profileStore.GetProfile(reference.ProfileId)
+		if err != nil {
+			return nil, err
+		}
+		if child == nil {
+			continue
+		}
+		if child.ApplicationData, err = profileStore.FetchApplicationData(child.ProfileId); err != nil {
+			return nil, err
+		}
+		child.ApplicationData = restrictApplicationData(child.ApplicationData, appScope)
+		child.Traits = redactTraits(child.Traits, restricted)
+		children = append(children, *child)
+	}
+
+	exported, err := json.Marshal(profileModel.ProfileExport{
+		ProfileId:     masterProfileId,
+		OrgHandle:     storedProfile.OrgHandle,
+		ExportedAt:    time.Now().UTC(),
+		Profile:       profile,
+		Consents:      consents,
+		ChildProfiles: children,
+	})
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to encode export of profile: %s", profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.EXPORT_PROFILE.Code,
+			Message:     errors2.EXPORT_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	logger.Info(fmt.Sprintf("Exported profile: %s with %d merged profiles", masterProfileId, len(children)))
+	return exported, nil
+}
+
+// referenceProfileIdOf returns the Id of the reference profile that holds the unified data of the profile.
+func referenceProfileIdOf(profile *profileModel.Profile) (string, error) {
+
+	if profile.ProfileStatus.IsReferenceProfile {
+		return profile.ProfileId, nil
+	}
+	masterProfile, err := getReferenceProfile(profile)
+	if err != nil {
+		return "", err
+	}
+	return masterProfile.ProfileId, nil
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"sync"
+
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// ImportProfiles applies the records received from the stream and returns a channel of results which is closed once
+// all the records are processed. Records of the same profile are applied in the order they are received while the
+// rest are processed concurrently, so results may be reported out of order. Once the context is done the remaining
+// records are reported as not processed instead of being applied.
+func (ps *ProfilesService) ImportProfiles(ctx context.Context, orgHandle, source string,
+	records <-chan profileModel.ProfileImportRecord) <-chan profileModel.ProfileImportResult {
+
+	results := make(chan profileModel.ProfileImportResult, constants.ProfileImportWorkers)
+	shards := make([]chan profileModel.ProfileImportRecord, constants.ProfileImportWorkers)
+
+	var wg sync.WaitGroup
+	for i := range shards {
+		shards[i] = make(chan profileModel.ProfileImportRecord, constants.ProfileImportWorkers)
+		wg.Add(1)
+		go func(shard <-chan profileModel.ProfileImportRecord) {
+			defer wg.Done()
+			for record := range shard {
+				if err := ctx.Err(); err != nil {
+					results <- skippedImportResult(record, err)
+					continue
+				}
+				results <- ps.importProfileRecord(ctx, orgHandle, source, record)
+			}
+		}(shards[i])
+	}
+
+	go func() {
+		next := 0
+		for record := range records {
+			shard := next % len(shards)
+			if record.ProfileId != "" {
+				// Pin updates of a profile to a single worker to preserve their order.
+				h := fnv.New32a()
+				_, _ = h.Write([]byte(record.ProfileId))
+				shard = int(h.Sum32() % uint32(len(shards)))
+			} else {
+				next++
+			}
+			shards[shard] <- record
+		}
+		for _, shard := range shards {
+			close(shard)
+		}
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// ApplyProfilesBatch applies a batch of records and returns their results in the order of the records, with the
+// line of each result being the position of its record in the batch. Records of the same profile are applied in
+// order and a failing record does not stop the rest of the batch.
+func (ps *ProfilesService) ApplyProfilesBatch(ctx context.Context, orgHandle, source string,
+	records []profileModel.ProfileImportRecord) []profileModel.ProfileImportResult {
+
+	feed := make(chan profileModel.ProfileImportRecord)
+	go func() {
+		defer close(feed)
+		for i, record := range records {
+			record.Line = i + 1
+			feed <- record
+		}
+	}()
+
+	results := make([]profileModel.ProfileImportResult, len(records))
+	for result := range ps.ImportProfiles(ctx, orgHandle, source, feed) {
+		results[result.Line-1] = result
+	}
+	return results
+}
+
+// skippedImportResult reports a record that was not applied because the request was cancelled or timed out.
+func skippedImportResult(record profileModel.ProfileImportRecord, cause error) profileModel.ProfileImportResult {
+
+	abortErr := abortedRequestError(cause)
+	return profileModel.ProfileImportResult{
+		Line:        record.Line,
+		ProfileId:   record.ProfileId,
+		Status:      abortErr.StatusCode,
+		Code:        abortErr.Code,
+		Description: abortErr.Description,
+	}
+}
+
+// abortedRequestError reports that a request was not processed because its context was cancelled or timed out.
+func abortedRequestError(cause error) *errors2.ClientError {
+
+	if errors.Is(cause, context.DeadlineExceeded) {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.REQUEST_TIMEOUT.Code,
+			Message:     errors2.REQUEST_TIMEOUT.Message,
+			Description: errors2.REQUEST_TIMEOUT.Message,
+		}, http.StatusGatewayTimeout)
+	}
+	return errors2.NewClientError(errors2.ErrorMessage{
+		Code:        errors2.REQUEST_CANCELLED.Code,
+		Message:     errors2.REQUEST_CANCELLED.Message,
+		Description: errors2.REQUEST_CANCELLED.Message,
+	}, http.StatusServiceUnavailable)
+}
+
+// importProfileRecord applies a record of the given source unless the source is quarantined, in which case the
+// quarantine action of the source is taken instead.
+func (ps *ProfilesService) importProfileRecord(ctx context.Context, orgHandle, source string,
+	record profileModel.ProfileImportRecord) profileModel.ProfileImportResult {
+
+	policy, quarantineEnabled := quarantinePolicyFor(source)
+	if quarantineEnabled && isImportSourceQuarantined(orgHandle, source) {
+		return quarantineImportRecord(orgHandle, source, record, policy)
+	}
+
+	result := ps.applyImportRecord(ctx, orgHandle, record)
+	if quarantineEnabled {
+		recordImportOutcome(orgHandle, source, result.Status == http.StatusBadRequest, policy)
+	}
+	return result
+}
+
+// applyImportRecord creates or replaces the profile of a single import record.
+func (ps *ProfilesService) applyImportRecord(ctx context.Context, orgHandle string,
+	record profileModel.ProfileImportRecord) profileModel.ProfileImportResult {
+
+	result := profileModel.ProfileImportResult{Line: record.Line, ProfileId: record.ProfileId}
+
+	var profile *profileModel.ProfileResponse
+	var err error
+	if record.ProfileId == "" {
+		profile, err = ps.CreateProfileContext(ctx, record.ProfileRequest, orgHandle)
+		result.Status = http.StatusCreated
+	} else {
+		profile, err = ps.UpdateProfile(ctx, record.ProfileId, orgHandle, record.ProfileRequest, 0)
+		result.Status = http.StatusOK
+	}
+	if err != nil {
+		var clientError *errors2.ClientError
+		if errors.As(err, &clientError) {
+			result.Status = clientError.StatusCode
+			result.Code = clientError.ErrorMessage.Code
+			result.Description = clientError.ErrorMessage.Description
+			return result
+		}
+		log.GetLogger().Error("Failed to import profile record at line: "+strconv.Itoa(record.Line), log.Error(err))
+		result.Status = http.StatusInternalServerError
+		result.Description = "Internal server error"
+		return result
+	}
+	if profile != nil {
+		result.ProfileId = profile.ProfileId
+	}
+	return result
+}
@@ -0,0 +1,227 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// importSourceHealth tracks the validation failures of an import source within the current window.
+type importSourceHealth struct {
+	windowStart time.Time
+	total       int
+	failures    int
+	quarantined bool
+}
+
+var (
+	importSourcesMu sync.Mutex
+	importSources   = make(map[string]*importSourceHealth)
+)
+
+func importSourceKey(orgHandle, source string) string {
+	return orgHandle + "/" + source
+}
+
+// quarantinePolicyFor resolves the quarantine policy of an import source. Returns false when quarantine is disabled.
+func quarantinePolicyFor(source string) (config.QuarantinePolicy, bool) {
+
+	conf := config.GetCDSRuntime().Config.ImportQuarantine
+	if !conf.Enabled {
+		return config.QuarantinePolicy{}, false
+	}
+	policy := conf.Default
+	if sourcePolicy, ok := conf.Sources[source]; ok {
+		if sourcePolicy.FailureRateThreshold > 0 {
+			policy.FailureRateThreshold = sourcePolicy.FailureRateThreshold
+		}
+		if sourcePolicy.MinRecords > 0 {
+			policy.MinRecords = sourcePolicy.MinRecords
+		}
+		if sourcePolicy.Window > 0 {
+			policy.Window = sourcePolicy.Window
+		}
+		if sourcePolicy.Action != "" {
+			policy.Action = sourcePolicy.Action
+		}
+	}
+	if policy.FailureRateThreshold <= 0 {
+		policy.FailureRateThreshold = constants.DefaultQuarantineFailureRate
+	}
+	if policy.MinRecords <= 0 {
+		policy.MinRecords = constants.DefaultQuarantineMinRecords
+	}
+	if policy.Window <= 0 {
+		policy.Window = constants.DefaultQuarantineWindow
+	}
+	if policy.Action != constants.QuarantineActionDiscard {
+		policy.Action = constants.QuarantineActionStore
+	}
+	return policy, true
+}
+
+func isImportSourceQuarantined(orgHandle, source string) bool {
+
+	importSourcesMu.Lock()
+	defer importSourcesMu.Unlock()
+	health, ok := importSources[importSourceKey(orgHandle, source)]
+	return ok && health.quarantined
+}
+
+// recordImportOutcome accounts the outcome of an applied record and quarantines the source once its failure rate
+// within the window crosses the threshold of the policy.
+func recordImportOutcome(orgHandle, source string, failed bool, policy config.QuarantinePolicy) {
+
+	importSourcesMu.Lock()
+	defer importSourcesMu.Unlock()
+
+	key := importSourceKey(orgHandle, source)
+	now := time.Now()
+	health, ok := importSources[key]
+	if !ok || now.Sub(health.windowStart) > policy.Window {
+		health = &importSourceHealth{windowStart: now}
+		importSources[key] = health
+	}
+	if health.quarantined {
+		return
+	}
+	health.total++
+	if failed {
+		health.failures++
+	}
+	if health.total < policy.MinRecords {
+		return
+	}
+	if float64(health.failures)/float64(health.total) >= policy.FailureRateThreshold {
+		health.quarantined = true
+		// Raise an alert for operators to review the source.
+		log.GetLogger().Error(fmt.Sprintf("ALERT: Profile import source: %s of organization: %s is quarantined after "+
+			"%d of %d records failed validation. Subsequent records are handled with the '%s' action until the "+
+			"source is released.", source, orgHandle, health.failures, health.total, policy.Action))
+	}
+}
+
+// quarantineImportRecord takes the quarantine action of the source for a record instead of applying it.
+func quarantineImportRecord(orgHandle, source string, record profileModel.ProfileImportRecord,
+	policy config.QuarantinePolicy) profileModel.ProfileImportResult {
+
+	result := profileModel.ProfileImportResult{
+		Line:      record.Line,
+		ProfileId: record.ProfileId,
+		Code:      errors2.PROFILE_IMPORT_QUARANTINED.Code,
+	}
+	if policy.Action == constants.QuarantineActionDiscard {
+		result.Status = http.StatusUnprocessableEntity
+		result.Description = fmt.Sprintf("Record discarded as import source: %s is quarantined.", source)
+		return result
+	}
+
+	quarantined := profileModel.QuarantinedImportRecord{
+		RecordId:      uuid.New().String(),
+		OrgHandle:     orgHandle,
+		Source:        source,
+		Line:          record.Line,
+		Record:        record,
+		Reason:        "Import source is quarantined due to a high rate of validation failures.",
+		QuarantinedAt: time.Now().UTC(),
+	}
+	if err := profileStore.AddQuarantinedImportRecord(quarantined); err != nil {
+		log.GetLogger().Error(fmt.Sprintf("Failed to quarantine import record at line: %d of source: %s",
+			record.Line, source), log.Error(err))
+		result.Status = http.StatusInternalServerError
+		result.Description = "Internal server error"
+		return result
+	}
+	result.Status = http.StatusAccepted
+	result.Description = fmt.Sprintf("Record quarantined as: %s for review.", quarantined.RecordId)
+	return result
+}
+
+// GetQuarantinedImportRecords retrieves the records held back from quarantined import sources. Records of all
+// sources are returned when the source is empty.
+func (ps *ProfilesService) GetQuarantinedImportRecords(orgHandle, source string) (
+	[]profileModel.QuarantinedImportRecord, error) {
+
+	return profileStore.GetQuarantinedImportRecords(orgHandle, source)
+}
+
+// ReplayQuarantinedImportRecord applies a quarantined record, regardless of the state of its source, and removes it
+// from the quarantine once it is applied successfully.
+func (ps *ProfilesService) ReplayQuarantinedImportRecord(orgHandle, recordId string) (
+	*profileModel.ProfileImportResult, error) {
+
+	record, err := getQuarantinedImportRecord(orgHandle, recordId)
+	if err != nil {
+		return nil, err
+	}
+	result := ps.applyImportRecord(context.Background(), orgHandle, record.Record)
+	if result.Status < http.StatusMultipleChoices {
+		if err := profileStore.DeleteQuarantinedImportRecord(orgHandle, recordId); err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+// DiscardQuarantinedImportRecord removes a quarantined record without applying it.
+func (ps *ProfilesService) DiscardQuarantinedImportRecord(orgHandle, recordId string) error {
+
+	if _, err := getQuarantinedImportRecord(orgHandle, recordId); err != nil {
+		return err
+	}
+	return profileStore.DeleteQuarantinedImportRecord(orgHandle, recordId)
+}
+
+// ReleaseImportSource lifts the quarantine of an import source so that its records are applied again.
+func (ps *ProfilesService) ReleaseImportSource(orgHandle, source string) {
+
+	importSourcesMu.Lock()
+	defer importSourcesMu.Unlock()
+	delete(importSources, importSourceKey(orgHandle, source))
+	log.GetLogger().Info(fmt.Sprintf("Profile import source: %s of organization: %s is released from quarantine",
+		source, orgHandle))
+}
+
+func getQuarantinedImportRecord(orgHandle, recordId string) (*profileModel.QuarantinedImportRecord, error) {
+
+	record, err := profileStore.GetQuarantinedImportRecord(orgHandle, recordId)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.QUARANTINED_RECORD_NOT_FOUND.Code,
+			Message:     errors2.QUARANTINED_RECORD_NOT_FOUND.Message,
+			Description: fmt.Sprintf("Quarantined import record: %s is not found.", recordId),
+		}, http.StatusNotFound)
+	}
+	return record, nil
+}
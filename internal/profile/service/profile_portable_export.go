@@ -0,0 +1,246 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/system/utils"
+)
+
+// Signature algorithms of portable packages and how they are verified against the signing certificate
+var portableSignatureAlgorithms = map[string]x509.SignatureAlgorithm{
+	"RS256": x509.SHA256WithRSA,
+	"ES256": x509.ECDSAWithSHA256,
+	"EdDSA": x509.PureEd25519,
+}
+
+// ExportPortableProfile builds a signed, self-contained package of the profile's data for data portability.
+// The package carries the profile, its consents and the profile schema of the organization, and is signed with
+// the configured export signing key so that recipients can verify where it came from and that it is unaltered.
+func (ps *ProfilesService) ExportPortableProfile(profileId string) ([]byte, error) {
+
+	logger := log.GetLogger()
+	storedProfile, err := profileStore.GetProfile(profileId)
+	if err != nil {
+		return nil, err
+	}
+	if storedProfile == nil {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+	profile, err := ps.GetProfile(profileId, profileModel.AllApplications)
+	if err != nil {
+		return nil, err
+	}
+	consents, err := profileStore.GetProfileConsents(profileId)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := schemaService.GetProfileSchemaService().GetProfileSchema(storedProfile.OrgHandle)
+	if err != nil {
+		return nil, err
+	}
+	if consents == nil {
+		consents = []profileModel.ConsentRecord{}
+	}
+
+	exportError := func(errorMsg string, cause error) error {
+		logger.Debug(errorMsg, log.Error(cause))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.EXPORT_PORTABLE_PROFILE.Code,
+			Message:     errors2.EXPORT_PORTABLE_PROFILE.Message,
+			Description: errorMsg,
+		}, cause)
+	}
+
+	content, err := json.Marshal(profileModel.PortableContent{Schema: schema, Profile: profile, Consents: consents})
+	if err != nil {
+		return nil, exportError(fmt.Sprintf("Failed to encode portable export of profile: %s", profileId), err)
+	}
+	digest := sha256.Sum256(content)
+	manifest, err := json.Marshal(profileModel.PortableManifest{
+		Format:     constants.PortableProfileFormat,
+		Version:    constants.PortableProfileVersion,
+		ProfileId:  profileId,
+		OrgHandle:  storedProfile.OrgHandle,
+		ExportedAt: time.Now().UTC(),
+		ContentDigest: profileModel.PortableDigest{
+			Algorithm: constants.PortableProfileDigest,
+			Value:     base64.StdEncoding.EncodeToString(digest[:]),
+		},
+	})
+	if err != nil {
+		return nil, exportError(fmt.Sprintf("Failed to encode portable export manifest of profile: %s", profileId), err)
+	}
+
+	signer, certificate, err := loadPortableExportSigner()
+	if err != nil {
+		return nil, exportError("Failed to load the signing key for portable profile exports", err)
+	}
+	signature, err := signPortableManifest(signer, manifest)
+	if err != nil {
+		return nil, exportError(fmt.Sprintf("Failed to sign portable export of profile: %s", profileId), err)
+	}
+	signature.Certificate = base64.StdEncoding.EncodeToString(certificate)
+
+	exported, err := json.Marshal(profileModel.PortablePackage{
+		Manifest:  manifest,
+		Content:   content,
+		Signature: *signature,
+	})
+	if err != nil {
+		return nil, exportError(fmt.Sprintf("Failed to encode portable export of profile: %s", profileId), err)
+	}
+	logger.Info(fmt.Sprintf("Exported portable package of profile: %s", profileId))
+	return exported, nil
+}
+
+// VerifyPortableProfile checks the signature and content digest of a portable package against the certificate it
+// carries and returns its manifest and content. Whether the certificate itself is trusted is left to the caller.
+func VerifyPortableProfile(data []byte) (*profileModel.PortableManifest, *profileModel.PortableContent, *x509.Certificate,
+	error) {
+
+	var pkg profileModel.PortablePackage
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid portable package: %w", err)
+	}
+	algorithm, ok := portableSignatureAlgorithms[pkg.Signature.Algorithm]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("unsupported signature algorithm: %s", pkg.Signature.Algorithm)
+	}
+	der, err := base64.StdEncoding.DecodeString(pkg.Signature.Certificate)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid signing certificate encoding: %w", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid signing certificate: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(pkg.Signature.Value)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if err := certificate.CheckSignature(algorithm, pkg.Manifest, signature); err != nil {
+		return nil, nil, nil, fmt.Errorf("signature verification failed: %w", err)
+	}
+
+	var manifest profileModel.PortableManifest
+	if err := json.Unmarshal(pkg.Manifest, &manifest); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid portable package manifest: %w", err)
+	}
+	if manifest.Format != constants.PortableProfileFormat || manifest.ContentDigest.Algorithm != constants.PortableProfileDigest {
+		return nil, nil, nil, fmt.Errorf("unsupported portable package format: %s", manifest.Format)
+	}
+	digest := sha256.Sum256(pkg.Content)
+	if base64.StdEncoding.EncodeToString(digest[:]) != manifest.ContentDigest.Value {
+		return nil, nil, nil, fmt.Errorf("content digest does not match the manifest")
+	}
+
+	var content profileModel.PortableContent
+	if err := utils.UnmarshalJSON(pkg.Content, &content); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid portable package content: %w", err)
+	}
+	return &manifest, &content, certificate, nil
+}
+
+// loadPortableExportSigner loads the export signing key and returns it with the DER encoded certificate.
+func loadPortableExportSigner() (crypto.Signer, []byte, error) {
+
+	runtimeConfig := config.GetCDSRuntime().Config
+	certFile := runtimeConfig.PortableExport.SigningCert
+	keyFile := runtimeConfig.PortableExport.SigningKey
+	if certFile == "" || keyFile == "" {
+		certFile = runtimeConfig.TLS.CDSPublicCert
+		keyFile = runtimeConfig.TLS.CDSPrivateKey
+	}
+	certDir := runtimeConfig.TLS.CertDir
+	if certDir == "" {
+		certDir = filepath.Join(utils.GetCDSHome(), "etc", "certs")
+	}
+	if !filepath.IsAbs(certFile) {
+		certFile = filepath.Join(certDir, certFile)
+	}
+	if !filepath.IsAbs(keyFile) {
+		keyFile = filepath.Join(certDir, keyFile)
+	}
+
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load cert/key (%s, %s): %w", certFile, keyFile, err)
+	}
+	signer, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("private key in %s can not be used for signing", keyFile)
+	}
+	return signer, pair.Certificate[0], nil
+}
+
+func signPortableManifest(signer crypto.Signer, manifest []byte) (*profileModel.PortableSignature, error) {
+
+	var (
+		algorithm string
+		signature []byte
+		err       error
+	)
+	digest := sha256.Sum256(manifest)
+	switch signer.Public().(type) {
+	case *rsa.PublicKey:
+		algorithm = "RS256"
+		signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case *ecdsa.PublicKey:
+		algorithm = "ES256"
+		signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case ed25519.PublicKey:
+		algorithm = "EdDSA"
+		signature, err = signer.Sign(rand.Reader, manifest, crypto.Hash(0))
+	default:
+		return nil, fmt.Errorf("unsupported signing key type: %T", signer.Public())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &profileModel.PortableSignature{
+		Algorithm: algorithm,
+		Value:     base64.StdEncoding.EncodeToString(signature),
+	}, nil
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+// GetProfileProjected retrieves a profile holding only the requested fields. Fields are top-level keys of the
+// traits or identity attributes, given as "traits.<key>" or "identity_attributes.<key>". Only the requested keys
+// are read from the database. A merged profile is resolved to its reference profile like in GetProfile. A
+// restricted appScope leaves out the traits the application may not read.
+func (ps *ProfilesService) GetProfileProjected(profileId string, fields []string,
+	appScope profileModel.AppScope) (*profileModel.ProfileProjection, error) {
+
+	traitKeys, identityKeys, err := parseProjectionFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	fetchProjected := func(id string) (*profileModel.Profile, error) {
+		return profileStore.GetProjectedProfile(id, traitKeys, identityKeys)
+	}
+
+	profile, err := fetchProjected(profileId)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+
+	masterProfile := profile
+	if !profile.ProfileStatus.IsReferenceProfile {
+		masterProfileId, err := referenceProfileIdOf(profile)
+		if err != nil {
+			return nil, err
+		}
+		if masterProfile, err = fetchProjected(masterProfileId); err != nil {
+			return nil, err
+		}
+		if masterProfile == nil {
+			return nil, errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.PROFILE_NOT_FOUND.Code,
+				Message:     errors2.PROFILE_NOT_FOUND.Message,
+				Description: errors2.PROFILE_NOT_FOUND.Description,
+			}, http.StatusNotFound)
+		}
+	}
+
+	traits := masterProfile.Traits
+	if len(traitKeys) > 0 {
+		references, err := profileStore.FetchReferencedProfiles(masterProfile.ProfileId)
+		if err != nil {
+			return nil, err
+		}
+		traits, err = resolveHierarchyTraitsOf(masterProfile.OrgHandle, masterProfile, references, fetchProjected)
+		if err != nil {
+			return nil, err
+		}
+		restricted, err := restrictedTraitPaths(masterProfile.OrgHandle, appScope)
+		if err != nil {
+			return nil, err
+		}
+		traits = redactTraits(traits, restricted)
+	}
+
+	return &profileModel.ProfileProjection{
+		ProfileId:          profile.ProfileId,
+		IdentityAttributes: masterProfile.IdentityAttributes,
+		Traits:             traits,
+	}, nil
+}
+
+// parseProjectionFields splits the requested fields into the keys of the traits and of the identity attributes.
+func parseProjectionFields(fields []string) ([]string, []string, error) {
+
+	var traitKeys, identityKeys, invalid []string
+	for _, field := range fields {
+		scope, key, found := strings.Cut(strings.TrimSpace(field), ".")
+		if !found || key == "" || strings.Contains(key, ".") {
+			invalid = append(invalid, field)
+			continue
+		}
+		switch scope {
+		case constants.Traits:
+			if !slices.Contains(traitKeys, key) {
+				traitKeys = append(traitKeys, key)
+			}
+		case constants.IdentityAttributes:
+			if !slices.Contains(identityKeys, key) {
+				identityKeys = append(identityKeys, key)
+			}
+		default:
+			invalid = append(invalid, field)
+		}
+	}
+
+	if len(invalid) > 0 || len(traitKeys)+len(identityKeys) == 0 {
+		return nil, nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:    errors2.BAD_REQUEST.Code,
+			Message: errors2.BAD_REQUEST.Message,
+			Description: fmt.Sprintf("Fields must be given as top-level keys of traits or identity_attributes, "+
+				"got: %s", strings.Join(fields, ",")),
+		}, http.StatusBadRequest)
+	}
+	return traitKeys, identityKeys, nil
+}
@@ -0,0 +1,274 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	"github.com/wso2/identity-customer-data-service/internal/system/changestream"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	unificationProvider "github.com/wso2/identity-customer-data-service/internal/unification_rules/provider"
+)
+
+var (
+	// errProfileJobCancelled stops a job that was asked to be cancelled.
+	errProfileJobCancelled = errors.New("profile job cancelled")
+	// errProfileJobLost stops a job that is no longer running, such as one taken over by another instance.
+	errProfileJobLost = errors.New("profile job no longer running")
+)
+
+// ReunifyAll starts a job that splits every merged profile of the organization from its master and merges the
+// profiles again by the active unification rules, so that the unification reflects the current rules. The job runs
+// in the background in batches, reporting its progress, and can be cancelled between batches. A job left running by
+// a stopped instance is resumed by ResumeProfileJobs. Only one such job may run for an organization at a time.
+func (ps *ProfilesService) ReunifyAll(orgHandle string) (*profileModel.ProfileJob, error) {
+
+	now := time.Now().UTC()
+	job := profileModel.ProfileJob{
+		JobId:     uuid.New().String(),
+		OrgHandle: orgHandle,
+		Type:      constants.ProfileJobReunifyAll,
+		Status:    constants.ProfileJobRunning,
+		Phase:     constants.ProfileJobPhaseSplit,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	added, err := profileStore.InsertProfileJob(job)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_JOB_ALREADY_RUNNING.Code,
+			Message:     errors2.PROFILE_JOB_ALREADY_RUNNING.Message,
+			Description: fmt.Sprintf("A reunification of the profiles of organization: %s is already running", orgHandle),
+		}, http.StatusConflict)
+	}
+	log.GetLogger().Info(fmt.Sprintf("Started reunification job: %s for organization: %s", job.JobId, orgHandle))
+	go ps.runProfileJob(job)
+	return &job, nil
+}
+
+// GetProfileJob returns the state of a profile job of the organization.
+func (ps *ProfilesService) GetProfileJob(jobId, orgHandle string) (*profileModel.ProfileJob, error) {
+
+	job, err := profileStore.GetProfileJob(jobId)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || job.OrgHandle != orgHandle {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_JOB_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_JOB_NOT_FOUND.Message,
+			Description: fmt.Sprintf("Profile job with id %s not found", jobId),
+		}, http.StatusNotFound)
+	}
+	return job, nil
+}
+
+// CancelProfileJob asks a running profile job of the organization to stop after its current batch. A reunification
+// job still splitting the profiles finishes the split before it stops. Cancelling a job that already stopped has no
+// effect.
+func (ps *ProfilesService) CancelProfileJob(jobId, orgHandle string) (*profileModel.ProfileJob, error) {
+
+	if _, err := ps.GetProfileJob(jobId, orgHandle); err != nil {
+		return nil, err
+	}
+	if _, err := profileStore.RequestProfileJobCancellation(jobId); err != nil {
+		return nil, err
+	}
+	return ps.GetProfileJob(jobId, orgHandle)
+}
+
+// ResumeProfileJobs resumes the running profile jobs whose progress has not been reported for a while, as the
+// instance running them has stopped, and returns the number of jobs resumed.
+func (ps *ProfilesService) ResumeProfileJobs() (int, error) {
+
+	now := time.Now().UTC()
+	jobs, err := profileStore.ClaimStaleProfileJobs(now, now.Add(-constants.ProfileJobStaleAfter))
+	if err != nil {
+		return 0, err
+	}
+	for _, job := range jobs {
+		log.GetLogger().Info(fmt.Sprintf("Resuming profile job: %s of organization: %s in phase: %s", job.JobId,
+			job.OrgHandle, job.Phase))
+		go ps.runProfileJob(job)
+	}
+	return len(jobs), nil
+}
+
+// runProfileJob runs a job to its end and records how it ended.
+func (ps *ProfilesService) runProfileJob(job profileModel.ProfileJob) {
+
+	logger := log.GetLogger()
+	err := ps.runReunifyJob(&job)
+	status, errorMessage := constants.ProfileJobCompleted, ""
+	switch {
+	case errors.Is(err, errProfileJobLost):
+		logger.Info(fmt.Sprintf("Profile job: %s is no longer run by this instance", job.JobId))
+		return
+	case errors.Is(err, errProfileJobCancelled):
+		status = constants.ProfileJobCancelled
+	case err != nil:
+		status, errorMessage = constants.ProfileJobFailed, err.Error()
+		logger.Error(fmt.Sprintf("Profile job: %s of organization: %s failed", job.JobId, job.OrgHandle),
+			log.Error(err))
+	}
+	if err := profileStore.FinishProfileJob(job.JobId, status, errorMessage, time.Now().UTC()); err != nil {
+		logger.Error(fmt.Sprintf("Failed to record the end of profile job: %s", job.JobId), log.Error(err))
+		return
+	}
+	logger.Info(fmt.Sprintf("Profile job: %s of organization: %s ended as %s, merging %d profiles", job.JobId,
+		job.OrgHandle, status, job.MergedProfiles))
+}
+
+// runReunifyJob runs the remaining phases of a reunification job from its last checkpoint.
+func (ps *ProfilesService) runReunifyJob(job *profileModel.ProfileJob) error {
+
+	if job.Phase == constants.ProfileJobPhaseSplit {
+		if err := ps.splitAllProfiles(job); err != nil {
+			return err
+		}
+		job.Phase, job.Processed, job.Total, job.Cursor = constants.ProfileJobPhaseMerge, 0, 0, ""
+	}
+	return ps.remergeAllProfiles(job)
+}
+
+// splitAllProfiles splits the profiles merged by unification rules from their masters in batches of masters. Masters
+// created by unification only hold merged data, hence they are deleted along with their clusters unless profiles
+// merged otherwise stay with them. Once begun, the split is not cancelled midway, as that would leave the
+// organization partly unified; a cancellation requested meanwhile stops the job as the merge phase begins.
+func (ps *ProfilesService) splitAllProfiles(job *profileModel.ProfileJob) error {
+
+	if job.Cursor == "" && job.Processed == 0 {
+		if _, err := ps.RepairOrphanedProfiles(job.OrgHandle); err != nil {
+			return err
+		}
+		stats, err := profileStore.GetHierarchyStats(job.OrgHandle)
+		if err != nil {
+			return err
+		}
+		job.Total = stats.MasterProfiles
+		if err := checkpointProfileJob(job); err != nil {
+			return err
+		}
+	}
+
+	for {
+		masters, err := profileStore.ListMasterProfilesAfter(job.OrgHandle, job.Cursor, constants.ProfileJobBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(masters) == 0 {
+			return nil
+		}
+		for _, master := range masters {
+			splitAt := time.Now().UTC()
+			promoted, dissolved, err := profileStore.SplitProfileCluster(master.ProfileId, !master.Listed, splitAt)
+			if err != nil {
+				return err
+			}
+			for _, profileId := range promoted {
+				changestream.PublishProfileChange(constants.ProfileChangeUpdated, job.OrgHandle, profileId, nil)
+			}
+			if dissolved {
+				changestream.PublishProfileChange(constants.ProfileChangeDeleted, job.OrgHandle, master.ProfileId,
+					nil)
+			} else {
+				changestream.PublishProfileChange(constants.ProfileChangeUpdated, job.OrgHandle, master.ProfileId,
+					nil)
+			}
+			job.Cursor = master.ProfileId
+			job.Processed++
+		}
+		// Profiles may have been merged since the total was taken.
+		job.Total = max(job.Total, job.Processed)
+		if err := checkpointProfileJob(job); err != nil && !errors.Is(err, errProfileJobCancelled) {
+			return err
+		}
+	}
+}
+
+// remergeAllProfiles applies the active unification rules in order of priority, resuming from the rule the job
+// stopped at. Processed counts the rules applied.
+func (ps *ProfilesService) remergeAllProfiles(job *profileModel.ProfileJob) error {
+
+	ruleService := unificationProvider.NewUnificationRuleProvider().GetUnificationRuleService()
+	rules, err := ruleService.GetActiveUnificationRules(job.OrgHandle)
+	if err != nil {
+		return err
+	}
+	job.Total = int64(len(rules))
+	if err := checkpointProfileJob(job); err != nil {
+		return err
+	}
+
+	for job.Processed < job.Total {
+		rule := rules[job.Processed]
+		candidates, err := ruleService.PreviewUnificationRule(context.Background(), rule)
+		if err != nil {
+			return err
+		}
+		for i, candidate := range candidates {
+			merged, err := ps.applyMergeCandidate(rule, candidate)
+			job.MergedProfiles += int64(merged)
+			if err != nil {
+				return err
+			}
+			if (i+1)%constants.ProfileJobBatchSize == 0 {
+				if err := checkpointProfileJob(job); err != nil {
+					return err
+				}
+			}
+		}
+		job.Processed++
+		if err := checkpointProfileJob(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkpointProfileJob records the progress of a job, and stops the job if it was cancelled or is no longer run by
+// this instance.
+func checkpointProfileJob(job *profileModel.ProfileJob) error {
+
+	job.UpdatedAt = time.Now().UTC()
+	cancelRequested, running, err := profileStore.UpdateProfileJobProgress(*job, job.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if !running {
+		return errProfileJobLost
+	}
+	if cancelRequested {
+		job.CancelRequested = true
+		return errProfileJobCancelled
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/system/utils"
+	UnificationModel "github.com/wso2/identity-customer-data-service/internal/unification_rules/model"
+	unificationProvider "github.com/wso2/identity-customer-data-service/internal/unification_rules/provider"
+	unificationStore "github.com/wso2/identity-customer-data-service/internal/unification_rules/store"
+)
+
+// ApplyUnificationRule merges the existing profiles matched by an active rule, so that a newly added rule takes
+// effect without waiting for the profiles to change. Profiles are grouped as in the rule preview. Merged profiles
+// are no longer matched by the rule, hence applying it again merges nothing more. Returns the number of profiles
+// merged.
+func (ps *ProfilesService) ApplyUnificationRule(ruleId, orgHandle string) (int, error) {
+
+	logger := log.GetLogger()
+	rule, err := unificationStore.GetUnificationRule(ruleId)
+	if err != nil {
+		return 0, err
+	}
+	if rule == nil || rule.OrgHandle != orgHandle {
+		return 0, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UNIFICATION_RULE_NOT_FOUND.Code,
+			Message:     errors2.UNIFICATION_RULE_NOT_FOUND.Message,
+			Description: fmt.Sprintf("Unification rule with id %s not found", ruleId),
+		}, http.StatusNotFound)
+	}
+	if !rule.IsActive {
+		return 0, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UNIFICATION_RULE_INACTIVE.Code,
+			Message:     errors2.UNIFICATION_RULE_INACTIVE.Message,
+			Description: fmt.Sprintf("Unification rule: %s must be activated before it is applied", rule.RuleName),
+		}, http.StatusBadRequest)
+	}
+
+	ruleService := unificationProvider.NewUnificationRuleProvider().GetUnificationRuleService()
+	candidates, err := ruleService.PreviewUnificationRule(context.Background(), *rule)
+	if err != nil {
+		return 0, err
+	}
+
+	mergedCount := 0
+	for _, candidate := range candidates {
+		merged, err := ps.applyMergeCandidate(*rule, candidate)
+		mergedCount += merged
+		if err != nil {
+			return mergedCount, err
+		}
+	}
+	logger.Info(fmt.Sprintf("Merged %d existing profiles by applying unification rule: %s", mergedCount,
+		rule.RuleName))
+	return mergedCount, nil
+}
+
+// applyMergeCandidate merges the profiles of a group into its master, creating the master when the group has none.
+// Groups holding profiles that were unmerged under the rule, or permanent profiles of different users, are skipped.
+func (ps *ProfilesService) applyMergeCandidate(rule UnificationModel.UnificationRule,
+	candidate UnificationModel.MergeCandidate) (int, error) {
+
+	logger := log.GetLogger()
+	profileIds := slices.Clone(candidate.ChildProfileIds)
+	if candidate.MasterProfileId != "" {
+		profileIds = append(profileIds, candidate.MasterProfileId)
+	}
+	exclusions, err := profileStore.GetUnmergeExclusions(profileIds)
+	if err != nil {
+		return 0, err
+	}
+	for _, exclusion := range exclusions {
+		if exclusion.RuleName == rule.RuleName && slices.Contains(profileIds, exclusion.ProfileId) &&
+			slices.Contains(profileIds, exclusion.ExcludedProfileId) {
+			logger.Info(fmt.Sprintf("Skipping unification of profiles: %v by rule: %s as profile: %s was unmerged "+
+				"from profile: %s before", profileIds, rule.RuleName, exclusion.ProfileId, exclusion.ExcludedProfileId))
+			return 0, nil
+		}
+	}
+
+	masterProfileId := candidate.MasterProfileId
+	if masterProfileId == "" {
+		masterProfileId, err = createUnifiedMasterProfile(rule.OrgHandle, candidate.ChildProfileIds)
+		if err != nil || masterProfileId == "" {
+			return 0, err
+		}
+	}
+
+	reference := profileModel.Reference{
+		Reason: rule.RuleName,
+		RuleId: rule.RuleId,
+	}
+	if len(candidate.PropertyValues) == 1 {
+		reference.MatchedValue = candidate.PropertyValues[0]
+	}
+	merged := 0
+	for _, childProfileId := range candidate.ChildProfileIds {
+		if err := ps.mergeProfiles(masterProfileId, childProfileId, reference, rule.MergeMode); err != nil {
+			return merged, err
+		}
+		merged++
+	}
+	return merged, nil
+}
+
+// createUnifiedMasterProfile adds an empty reference profile for a group of profiles to be merged into. The master
+// is permanent when the group holds permanent profiles of a single user. No master is created, and an empty id is
+// returned, when the group holds permanent profiles of different users.
+func createUnifiedMasterProfile(orgHandle string, profileIds []string) (string, error) {
+
+	logger := log.GetLogger()
+	userId := ""
+	for _, profileId := range profileIds {
+		profile, err := profileStore.GetProfile(profileId)
+		if err != nil {
+			return "", err
+		}
+		if profile == nil || profile.UserId == "" {
+			continue
+		}
+		if userId != "" && userId != profile.UserId {
+			logger.Info(fmt.Sprintf("Skipping unification of profiles: %v as they belong to different users",
+				profileIds))
+			return "", nil
+		}
+		userId = profile.UserId
+	}
+
+	now := time.Now().UTC()
+	masterProfileId, err := generateProfileId()
+	if err != nil {
+		return "", err
+	}
+	master := profileModel.Profile{
+		ProfileId:          masterProfileId,
+		UserId:             userId,
+		OrgHandle:          orgHandle,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		Location:           utils.BuildProfileLocation(orgHandle, masterProfileId),
+		Traits:             map[string]interface{}{},
+		IdentityAttributes: map[string]interface{}{},
+		ProfileStatus: &profileModel.ProfileStatus{
+			IsReferenceProfile: true,
+		},
+	}
+	if _, err := profileStore.InsertProfile(master); err != nil {
+		return "", err
+	}
+	logger.Info(fmt.Sprintf("Created master profile: %s for unifying profiles: %v", masterProfileId, profileIds))
+	return masterProfileId, nil
+}
@@ -19,39 +19,104 @@
 package service
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/changestream"
 	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/system/metrics"
+	"github.com/wso2/identity-customer-data-service/internal/system/tracing"
 	"github.com/wso2/identity-customer-data-service/internal/system/utils"
 	"github.com/wso2/identity-customer-data-service/internal/system/workers"
 
 	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
 	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
 	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	schemaStore "github.com/wso2/identity-customer-data-service/internal/profile_schema/store"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
 	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
 	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
 	UnificationModel "github.com/wso2/identity-customer-data-service/internal/unification_rules/model"
+	unificationStore "github.com/wso2/identity-customer-data-service/internal/unification_rules/store"
+	webhookService "github.com/wso2/identity-customer-data-service/internal/webhook/service"
 )
 
 type ProfilesServiceInterface interface {
+	EnsureProfileInOrg(profileId, orgHandle string) error
 	DeleteProfile(profileId string) error
-	GetAllProfilesCursor(orgHandle string, limit int, cursor *profileModel.ProfileCursor) ([]profileModel.ProfileResponse, bool, error)
+	DeleteProfilesByFilter(orgHandle string, filters []string) (int64, error)
+	PatchProfilesByFilter(orgHandle string, filters []string, traits map[string]interface{}) (int64, error)
+	RestoreProfile(profileId string) error
+	PurgeDeletedProfiles(olderThan time.Duration) (int64, error)
+	RepairOrphanedProfiles(orgHandle string) (int64, error)
+	GetAllProfilesCursor(orgHandle string, includeDeleted bool, limit int, cursor *profileModel.ProfileCursor, appScope profileModel.AppScope) ([]profileModel.ProfileResponse, bool, error)
 	CreateProfile(profile profileModel.ProfileRequest, orgHandle string) (*profileModel.ProfileResponse, error)
-	UpdateProfile(profileId, orgHandle string, update profileModel.ProfileRequest) (*profileModel.ProfileResponse, error)
-	GetProfile(profileId string) (*profileModel.ProfileResponse, error)
+	CreateProfileContext(ctx context.Context, profile profileModel.ProfileRequest, orgHandle string) (*profileModel.ProfileResponse, error)
+	CreateProfileIdempotently(ctx context.Context, profile profileModel.ProfileRequest, orgHandle, idempotencyKey string,
+		appScope profileModel.AppScope) (*profileModel.ProfileResponse, error)
+	GetOrCreateProfile(ctx context.Context, profile profileModel.ProfileRequest, orgHandle string,
+		appScope profileModel.AppScope) (*profileModel.ProfileResponse, bool, error)
+	SimulateProfile(ctx context.Context, profile profileModel.ProfileRequest, orgHandle string, appScope profileModel.AppScope) (*profileModel.ProfileSimulation, error)
+	ImportProfiles(ctx context.Context, orgHandle, source string, records <-chan profileModel.ProfileImportRecord) <-chan profileModel.ProfileImportResult
+	ApplyProfilesBatch(ctx context.Context, orgHandle, source string, records []profileModel.ProfileImportRecord) []profileModel.ProfileImportResult
+	GetQuarantinedImportRecords(orgHandle, source string) ([]profileModel.QuarantinedImportRecord, error)
+	ReplayQuarantinedImportRecord(orgHandle, recordId string) (*profileModel.ProfileImportResult, error)
+	DiscardQuarantinedImportRecord(orgHandle, recordId string) error
+	ReleaseImportSource(orgHandle, source string)
+	UpdateProfile(ctx context.Context, profileId, orgHandle string, update profileModel.ProfileRequest, expectedVersion int64) (*profileModel.ProfileResponse, error)
+	GetProfile(profileId string, appScope profileModel.AppScope) (*profileModel.ProfileResponse, error)
+	GetProfileContext(ctx context.Context, profileId string, appScope profileModel.AppScope) (*profileModel.ProfileResponse, error)
+	GetProfileFromPrimary(profileId string, appScope profileModel.AppScope) (*profileModel.ProfileResponse, error)
 	FindProfileByUserId(userId string) (*profileModel.ProfileResponse, error)
-	GetAllProfilesWithFilterCursor(orgHandle string, filters []string, limit int, cursor *profileModel.ProfileCursor) ([]profileModel.ProfileResponse, bool, error)
+	GetProfileProjected(profileId string, fields []string, appScope profileModel.AppScope) (*profileModel.ProfileProjection, error)
+	ResolveProfileByIdentifier(orgHandle, attrName, attrValue string, appScope profileModel.AppScope) ([]profileModel.ProfileResponse, error)
+	GetAllProfilesWithFilterCursor(orgHandle string, query profileModel.ProfileQuery) ([]profileModel.ProfileResponse, bool, error)
+	GetProfilesModifiedSince(orgHandle string, since time.Time, query profileModel.ProfileQuery) ([]profileModel.ProfileResponse, bool, error)
+	CountProfilesGroupedBy(orgHandle, trait string, filters []string, appScope profileModel.AppScope) (map[string]int64, error)
+	GetHierarchyStats(orgHandle string) (*profileModel.HierarchyStats, error)
+	GetDistinctTraitValues(orgHandle, trait string, limit int, byFrequency bool, appScope profileModel.AppScope) ([]string, error)
+	StreamProfiles(ctx context.Context, orgHandle string, filters []string, appScope profileModel.AppScope,
+		handle func(profile profileModel.ProfileResponse) error) error
+	ExportProfilesCSV(ctx context.Context, orgHandle string, filters, fields []string, appScope profileModel.AppScope,
+		w io.Writer) error
 	GetProfileConsents(profileId string) ([]profileModel.ConsentRecord, error)
 	UpdateProfileConsents(profileId string, consents []profileModel.ConsentRecord) error
-	PatchProfile(profileId, orgHandle string, data map[string]interface{}) (*profileModel.ProfileResponse, error)
+	PatchProfile(ctx context.Context, profileId, orgHandle string, data map[string]interface{}, expectedVersion int64) (*profileModel.ProfileResponse, error)
+	IncrementAttribute(profileId, path string, delta float64) (float64, error)
+	PatchApplicationData(profileId, appId string, patch map[string]interface{}) error
+	UnmergeProfile(childProfileId string, appScope profileModel.AppScope) (*profileModel.ProfileResponse, error)
+	GetProfileLineage(profileId string) (*profileModel.ProfileLineage, error)
+	GetChildProfiles(masterProfileId string) ([]profileModel.ChildProfile, error)
+	GetProfileHistory(profileId string, appScope profileModel.AppScope) ([]profileModel.ProfileSnapshot, error)
+	PruneProfileHistory(maxAge time.Duration, maxSnapshots int) (int64, error)
+	PurgeExpiredIdempotencyKeys() (int64, error)
+	MergeProfiles(masterProfileId, childProfileId string) error
+	AliasProfiles(fromProfileId, toProfileId string, appScope profileModel.AppScope) (*profileModel.ProfileResponse, error)
+	ApplyUnificationRule(ruleId, orgHandle string) (int, error)
+	ReunifyAll(orgHandle string) (*profileModel.ProfileJob, error)
+	GetProfileJob(jobId, orgHandle string) (*profileModel.ProfileJob, error)
+	CancelProfileJob(jobId, orgHandle string) (*profileModel.ProfileJob, error)
+	ResumeProfileJobs() (int, error)
+	ExportPortableProfile(profileId string) ([]byte, error)
+	ExportProfile(profileId string, appScope profileModel.AppScope) ([]byte, error)
+	GetProfileForChangeStream(profileId string) (*profileModel.ProfileResponse, error)
+	AnonymizeProfile(profileId string) error
 	GetProfileCookieByProfileId(profileId string) (*profileModel.ProfileCookie, error)
 	GetProfileCookie(cookie string) (*profileModel.ProfileCookie, error)
 	CreateProfileCookie(profileId string) (*profileModel.ProfileCookie, error)
@@ -91,6 +156,16 @@ func ConvertAppData(input map[string]map[string]interface{}) []profileModel.Appl
 // CreateProfile creates a new profile.
 func (ps *ProfilesService) CreateProfile(profileRequest profileModel.ProfileRequest, orgHandle string) (*profileModel.ProfileResponse, error) {
 
+	return ps.CreateProfileContext(context.Background(), profileRequest, orgHandle)
+}
+
+// CreateProfileContext creates a new profile like CreateProfile, tracing the creation as part of the context.
+func (ps *ProfilesService) CreateProfileContext(ctx context.Context, profileRequest profileModel.ProfileRequest,
+	orgHandle string) (_ *profileModel.ProfileResponse, err error) {
+
+	ctx, span := tracing.Start(ctx, "ProfilesService.CreateProfile")
+	defer func() { tracing.End(span, err) }()
+
 	rawSchema, err := schemaService.GetProfileSchemaService().GetProfileSchema(orgHandle)
 	logger := log.GetLogger()
 	if err != nil {
@@ -117,6 +192,7 @@ func (ps *ProfilesService) CreateProfile(profileRequest profileModel.ProfileRequ
 		return nil, serverError
 	}
 
+	normalizeProfileRequest(&profileRequest)
 	err = ValidateProfileAgainstSchema(profileRequest, profileModel.Profile{}, schema, false)
 	if err != nil {
 		return nil, err
@@ -124,7 +200,11 @@ func (ps *ProfilesService) CreateProfile(profileRequest profileModel.ProfileRequ
 
 	// convert profile request to model
 	createdTime := time.Now().UTC()
-	profileId := uuid.New().String()
+	profileId, err := newProfileId(profileRequest.ProfileId)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(tracing.ProfileIdKey.String(profileId))
 	profile := profileModel.Profile{
 		ProfileId:          profileId,
 		OrgHandle:          orgHandle,
@@ -140,15 +220,50 @@ func (ps *ProfilesService) CreateProfile(profileRequest profileModel.ProfileRequ
 		UpdatedAt: createdTime,
 		Location:  utils.BuildProfileLocation(orgHandle, profileId),
 	}
+	profile.Traits = applyComputedTraits(profile.Traits, schema.Traits)
 
-	if err := profileStore.InsertProfile(profile); err != nil {
+	// Nothing has been written yet, so a request abandoned by its client is dropped here.
+	if ctx.Err() != nil {
+		return nil, abortedRequestError(ctx.Err())
+	}
+	persisted, inserted, err := profileStore.InsertProfileContext(ctx, profile)
+	if err != nil {
 		logger.Debug(fmt.Sprintf("Error inserting profile: %s", profile.ProfileId), log.Error(err))
+		if ctx.Err() != nil {
+			return nil, abortedRequestError(ctx.Err())
+		}
 		return nil, err
 	}
-	profileFetched, errWait := ps.GetProfile(profileId)
-	if errWait != nil || profileFetched == nil {
-		logger.Warn(fmt.Sprintf("Profile: %s not available after insertion: %v", profile.ProfileId, errWait))
-		return nil, errWait
+	if inserted {
+		metrics.ProfileUpserts.Inc("create")
+		webhookService.NotifyProfileCreated(orgHandle, persisted.ProfileId)
+		changestream.PublishProfileChange(constants.ProfileChangeCreated, orgHandle, persisted.ProfileId, nil)
+	} else {
+		// A concurrent create of the same id won the insert, so this one is an update of its profile.
+		metrics.ProfileUpserts.Inc("update")
+		changestream.PublishProfileChange(constants.ProfileChangeUpdated, orgHandle, persisted.ProfileId, nil)
+	}
+	profileFetched := &profileModel.ProfileResponse{
+		ProfileId:          persisted.ProfileId,
+		CanonicalProfileId: persisted.ProfileId,
+		UserId:             persisted.UserId,
+		ApplicationData:    ConvertAppDataToMap(persisted.ApplicationData),
+		Traits:             persisted.Traits,
+		IdentityAttributes: persisted.IdentityAttributes,
+		Meta: profileModel.Meta{
+			CreatedAt: persisted.CreatedAt,
+			UpdatedAt: persisted.UpdatedAt,
+			Location:  persisted.Location,
+			Version:   persisted.Version,
+		},
+	}
+	if config.GetCDSRuntime().Config.DataSource.VerifyWrites {
+		var errWait error
+		profileFetched, errWait = ps.getProfile(ctx, profileId, profileModel.AllApplications, profileStore.GetProfileContext)
+		if errWait != nil || profileFetched == nil {
+			logger.Warn(fmt.Sprintf("Profile: %s not available after insertion: %v", profile.ProfileId, errWait))
+			return nil, errWait
+		}
 	}
 
 	queue := &workers.ProfileWorkerQueue{}
@@ -165,20 +280,228 @@ func (ps *ProfilesService) CreateProfile(profileRequest profileModel.ProfileRequ
 	return profileFetched, nil
 }
 
+// newProfileId returns the id of a new profile. The requested id is used when the caller provides the ids of
+// profiles. Whether it is taken is left to the insert, so that a create repeating the id of a profile of the
+// organization is merged into it whether or not it races the first create, and ids of other organizations are
+// rejected without telling that they exist. Otherwise no id may be requested and one is generated by the configured
+// strategy.
+func newProfileId(requested string) (string, error) {
+
+	invalid := func(description string, status int) error {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: description,
+		}, status)
+	}
+	if requested == "" {
+		return generateProfileId()
+	}
+	if config.GetCDSRuntime().Config.ProfileIds.Strategy != constants.ProfileIdCallerProvided {
+		return "", invalid("profile_id is generated by the service and can not be provided.", http.StatusBadRequest)
+	}
+	if len(requested) > constants.MaxProfileIdLength || strings.IndexFunc(requested, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r) || r == '/'
+	}) >= 0 {
+		return "", invalid(fmt.Sprintf("profile_id must be at most %d characters long without whitespace or "+
+			"slashes.", constants.MaxProfileIdLength), http.StatusBadRequest)
+	}
+	return requested, nil
+}
+
+// generateProfileId generates the id of a new profile by the configured strategy.
+func generateProfileId() (string, error) {
+
+	profileId, err := profileModel.ProfileIdGeneratorFor(config.GetCDSRuntime().Config.ProfileIds.Strategy).
+		NewProfileId()
+	if err != nil {
+		errMsg := "Failed to generate the id of a new profile."
+		log.GetLogger().Debug(errMsg, log.Error(err))
+		return "", errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: errMsg,
+		}, err)
+	}
+	return profileId, nil
+}
+
+// CreateProfileIdempotently creates a new profile unless a profile was already created with the same idempotency
+// key, in which case that profile is returned. A retry while the first request is still in progress waits for it
+// up to the configured wait timeout and is then rejected with a conflict. Without a key it behaves like
+// CreateProfile. The profile is returned restricted to what appScope may read like in GetProfile, since the profile
+// created with the key may have been written to by other applications since.
+func (ps *ProfilesService) CreateProfileIdempotently(ctx context.Context, profileRequest profileModel.ProfileRequest,
+	orgHandle, idempotencyKey string, appScope profileModel.AppScope) (*profileModel.ProfileResponse, error) {
+
+	if idempotencyKey == "" {
+		profile, err := ps.CreateProfileContext(ctx, profileRequest, orgHandle)
+		if err != nil {
+			return nil, err
+		}
+		return restrictProfileResponse(orgHandle, profile, appScope)
+	}
+	if len(idempotencyKey) > constants.MaxIdempotencyKeyLength {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:    errors2.ADD_PROFILE.Code,
+			Message: errors2.ADD_PROFILE.Message,
+			Description: fmt.Sprintf("%s must not be longer than %d characters.", constants.IdempotencyKeyHeader,
+				constants.MaxIdempotencyKeyLength),
+		}, http.StatusBadRequest)
+	}
+
+	idempotency := config.GetCDSRuntime().Config.Idempotency
+	ttl := idempotency.KeyTTL
+	if ttl <= 0 {
+		ttl = constants.DefaultIdempotencyKeyTTL
+	}
+	claimToken := uuid.New().String()
+	claimed, profileId, err := acquireIdempotencyKey(ctx, orgHandle, idempotencyKey, claimToken, idempotency)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, abortedRequestError(ctx.Err())
+		}
+		return nil, err
+	}
+	if !claimed {
+		if profileId == "" {
+			return nil, errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.IDEMPOTENCY_KEY_IN_USE.Code,
+				Message:     errors2.IDEMPOTENCY_KEY_IN_USE.Message,
+				Description: "A request with the same idempotency key is still being processed.",
+			}, http.StatusConflict)
+		}
+		log.GetLogger().Info(fmt.Sprintf("Returning profile: %s already created with the idempotency key", profileId))
+		return ps.GetProfileFromPrimary(profileId, appScope)
+	}
+
+	profile, err := ps.CreateProfileContext(ctx, profileRequest, orgHandle)
+	if err != nil {
+		if releaseErr := profileStore.ReleaseIdempotencyKey(orgHandle, idempotencyKey,
+			claimToken); releaseErr != nil {
+			log.GetLogger().Warn("Failed to release idempotency key of a failed profile creation",
+				log.Error(releaseErr))
+		}
+		return nil, err
+	}
+	expiresAt := time.Now().UTC().Add(ttl)
+	err = profileStore.CompleteIdempotencyKey(orgHandle, idempotencyKey, claimToken, profile.ProfileId, expiresAt)
+	if err != nil {
+		// The profile exists, so report success. The key is left to the request that took it over, or retries are
+		// rejected as in progress until the claim expires.
+		log.GetLogger().Warn(fmt.Sprintf("Failed to record idempotency key of profile: %s", profile.ProfileId),
+			log.Error(err))
+	}
+	return restrictProfileResponse(orgHandle, profile, appScope)
+}
+
+// acquireIdempotencyKey claims the idempotency key for a new profile creation under the claim token, which the
+// creation completes or releases the key with. When another request holds the key
+// it waits up to the configured wait timeout for that request to finish, taking the key over if its claim expires
+// meanwhile. It returns the id of the profile created with the key, or an empty id if the key is still in progress.
+func acquireIdempotencyKey(ctx context.Context, orgHandle, key, claimToken string,
+	idempotency config.IdempotencyConfig) (claimed bool, profileId string, err error) {
+
+	claimTTL := idempotency.ClaimTTL
+	if claimTTL <= 0 {
+		claimTTL = constants.DefaultIdempotencyClaimTTL
+	}
+	start := time.Now()
+	for {
+		now := time.Now().UTC()
+		claimed, err = profileStore.ClaimIdempotencyKey(ctx, orgHandle, key, claimToken, now, now.Add(claimTTL))
+		if err != nil || claimed {
+			return claimed, "", err
+		}
+		profileId, _, err = profileStore.GetIdempotencyKey(orgHandle, key, now)
+		if err != nil || profileId != "" {
+			return false, profileId, err
+		}
+		waited := time.Since(start)
+		if waited >= idempotency.WaitTimeout {
+			if waited > 0 {
+				log.GetLogger().Debug(fmt.Sprintf("Idempotency key of organization: %s is still in progress after "+
+					"waiting %s", orgHandle, waited))
+			}
+			return false, "", nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, "", ctx.Err()
+		case <-time.After(constants.IdempotencyWaitPollInterval):
+		}
+	}
+}
+
+// computedTraitDependingOn returns the name of a computed trait of the schema that depends on the attribute, or an
+// empty string if none does.
+func computedTraitDependingOn(traitSchema []model.ProfileSchemaAttribute, attrName string) string {
+
+	for _, attr := range traitSchema {
+		if attr.ComputationExpression == "" {
+			continue
+		}
+		computation, err := model.ParseComputation(attr.ComputationExpression)
+		if err != nil {
+			continue
+		}
+		for _, reference := range computation.References() {
+			if reference == attrName || strings.HasPrefix(reference, attrName+".") ||
+				strings.HasPrefix(attrName, reference+".") {
+				return attr.AttributeName
+			}
+		}
+	}
+	return ""
+}
+
+// applyComputedTraits recomputes the computed traits of the schema on the given traits.
+func applyComputedTraits(traits map[string]interface{}, traitSchema []model.ProfileSchemaAttribute) map[string]interface{} {
+
+	computed, err := model.ApplyComputedTraits(traits, traitSchema)
+	if err != nil {
+		log.GetLogger().Warn("Skipping computed traits as they could not be evaluated", log.Error(err))
+		return traits
+	}
+	return computed
+}
+
+// normalizeProfileRequest normalizes the string attribute values of the request before they are validated and stored.
+func normalizeProfileRequest(profileRequest *profileModel.ProfileRequest) {
+
+	utils.NormalizeMap(profileRequest.IdentityAttributes)
+	utils.NormalizeMap(profileRequest.Traits)
+	for _, appData := range profileRequest.ApplicationData {
+		utils.NormalizeMap(appData)
+	}
+}
+
+// ValidateProfileAgainstSchema validates the attributes of a profile request against the profile schema. Attributes
+// that are not in the schema or do not match their declared type are collected and reported together, so that
+// the caller can fix all of them at once. Attributes that are not in the schema are accepted unvalidated when the
+// profile validation config passes them through, and are replaced in the request by their string form when it
+// coerces them.
 func ValidateProfileAgainstSchema(profile profileModel.ProfileRequest, existingProfile profileModel.Profile,
 	schema model.ProfileSchema, isUpdate bool) error {
 
+	unknownAttributes := config.GetCDSRuntime().Config.Validation.UnknownAttributes
+	coerceUnknown := unknownAttributes == constants.UnknownAttributesCoerce
+	passThroughUnknown := unknownAttributes == constants.UnknownAttributesPassThrough || coerceUnknown
+	var violations []errors2.FieldError
+
 	// Validate identity attributes
 	for key, val := range profile.IdentityAttributes {
 		attrName := "identity_attributes." + key
 		attr, found := findAttributeInSchema(schema.IdentityAttributes, attrName)
 		if !found {
-			clientError := errors2.NewClientError(errors2.ErrorMessage{
-				Code:        errors2.UPDATE_PROFILE.Code,
-				Message:     errors2.UPDATE_PROFILE.Message,
-				Description: fmt.Sprintf("identity attribute '%s' not defined in schema", attrName),
-			}, http.StatusBadRequest)
-			return clientError
+			if coerceUnknown {
+				profile.IdentityAttributes[key] = coerceToString(val)
+			}
+			if !passThroughUnknown {
+				violations = append(violations, errors2.FieldError{Field: attrName,
+					Message: fmt.Sprintf("identity attribute '%s' not defined in schema", attrName)})
+			}
+			continue
 		}
 		if isUpdate && existingProfile.IdentityAttributes != nil {
 			if !(attr.AttributeName == "identity_attributes.modified" || attr.AttributeName == "identity_attributes.created" || attr.AttributeName == "identity_attributes.userid") {
@@ -194,12 +517,9 @@ func ValidateProfileAgainstSchema(profile profileModel.ProfileRequest, existingP
 			}
 		}
 		if !isValidType(val, attr.ValueType, attr.MultiValued, nil) {
-			clientError := errors2.NewClientError(errors2.ErrorMessage{
-				Code:        errors2.UPDATE_PROFILE.Code,
-				Message:     errors2.UPDATE_PROFILE.Message,
-				Description: fmt.Sprintf("identity attribute '%s': type mismatch", key),
-			}, http.StatusBadRequest)
-			return clientError
+			violations = append(violations, errors2.FieldError{Field: attrName,
+				Message: typeMismatch("identity attribute", key, attr)})
+			continue
 		}
 		if !isValidCanonicalValue(val, attr.CanonicalValues) {
 			clientError := errors2.NewClientError(errors2.ErrorMessage{
@@ -216,12 +536,14 @@ func ValidateProfileAgainstSchema(profile profileModel.ProfileRequest, existingP
 		attrName := "traits." + key
 		attr, found := findAttributeInSchema(schema.Traits, attrName)
 		if !found {
-			clientError := errors2.NewClientError(errors2.ErrorMessage{
-				Code:        errors2.UPDATE_PROFILE.Code,
-				Message:     errors2.UPDATE_PROFILE.Message,
-				Description: fmt.Sprintf("trait '%s' not defined in schema", attrName),
-			}, http.StatusBadRequest)
-			return clientError
+			if coerceUnknown {
+				profile.Traits[key] = coerceToString(val)
+			}
+			if !passThroughUnknown {
+				violations = append(violations, errors2.FieldError{Field: attrName,
+					Message: fmt.Sprintf("trait '%s' not defined in schema", attrName)})
+			}
+			continue
 		}
 		if isUpdate && existingProfile.Traits != nil {
 			if err := validateMutability(attr.Mutability, isUpdate, existingProfile.Traits[key], val); err != nil {
@@ -233,12 +555,8 @@ func ValidateProfileAgainstSchema(profile profileModel.ProfileRequest, existingP
 			}
 		}
 		if !isValidType(val, attr.ValueType, attr.MultiValued, nil) {
-			clientError := errors2.NewClientError(errors2.ErrorMessage{
-				Code:        errors2.UPDATE_PROFILE.Code,
-				Message:     errors2.UPDATE_PROFILE.Message,
-				Description: fmt.Sprintf("trait '%s': type mismatch", key),
-			}, http.StatusBadRequest)
-			return clientError
+			violations = append(violations, errors2.FieldError{Field: attrName, Message: typeMismatch("trait", key, attr)})
+			continue
 		}
 		if !isValidCanonicalValue(val, attr.CanonicalValues) {
 			clientError := errors2.NewClientError(errors2.ErrorMessage{
@@ -256,12 +574,14 @@ func ValidateProfileAgainstSchema(profile profileModel.ProfileRequest, existingP
 			attrName := "application_data." + key
 			attr, found := findAppAttributeInSchema(schema.ApplicationData, appID, attrName)
 			if !found {
-				clientError := errors2.NewClientError(errors2.ErrorMessage{
-					Code:        errors2.UPDATE_PROFILE.Code,
-					Message:     errors2.UPDATE_PROFILE.Message,
-					Description: fmt.Sprintf("application_data '%s.%s' not defined in schema", appID, key),
-				}, http.StatusBadRequest)
-				return clientError
+				if coerceUnknown {
+					attrs[key] = coerceToString(val)
+				}
+				if !passThroughUnknown {
+					violations = append(violations, errors2.FieldError{Field: "application_data." + appID + "." + key,
+						Message: fmt.Sprintf("application_data '%s.%s' not defined in schema", appID, key)})
+				}
+				continue
 			}
 
 			var existingVal interface{}
@@ -274,12 +594,9 @@ func ValidateProfileAgainstSchema(profile profileModel.ProfileRequest, existingP
 			}
 
 			if !isValidType(val, attr.ValueType, attr.MultiValued, nil) {
-				clientError := errors2.NewClientError(errors2.ErrorMessage{
-					Code:        errors2.UPDATE_PROFILE.Code,
-					Message:     errors2.UPDATE_PROFILE.Message,
-					Description: fmt.Sprintf("application_data '%s.%s': type mismatch", appID, key),
-				}, http.StatusBadRequest)
-				return clientError
+				violations = append(violations, errors2.FieldError{Field: "application_data." + appID + "." + key,
+					Message: typeMismatch("application_data", appID+"."+key, attr)})
+				continue
 			}
 			if !isValidCanonicalValue(val, attr.CanonicalValues) {
 				clientError := errors2.NewClientError(errors2.ErrorMessage{
@@ -292,9 +609,46 @@ func ValidateProfileAgainstSchema(profile profileModel.ProfileRequest, existingP
 		}
 	}
 
+	if len(violations) > 0 {
+		sort.Slice(violations, func(i, j int) bool { return violations[i].Message < violations[j].Message })
+		messages := make([]string, 0, len(violations))
+		for _, violation := range violations {
+			messages = append(messages, violation.Message)
+		}
+		return errors2.NewClientErrorWithDetails(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: strings.Join(messages, "; "),
+		}, http.StatusBadRequest, violations)
+	}
 	return nil
 }
 
+// coerceToString returns the string form of an attribute value that is not in the schema. Strings are kept, other
+// values are stored as their JSON text and nil is kept so that it still clears the attribute.
+func coerceToString(val interface{}) interface{} {
+
+	switch v := val.(type) {
+	case nil, string:
+		return v
+	}
+	encoded, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+	return string(encoded)
+}
+
+// typeMismatch describes an attribute whose value does not match the type declared in the schema.
+func typeMismatch(scope, key string, attr model.ProfileSchemaAttribute) string {
+
+	expected := attr.ValueType
+	if attr.MultiValued {
+		expected = "multi-valued " + expected
+	}
+	return fmt.Sprintf("%s '%s': type mismatch, expected %s", scope, key, expected)
+}
+
 // isValidCanonicalValue checks if the value is valid against the canonical values defined in the schema.
 func isValidCanonicalValue(val interface{}, values []model.CanonicalValue) bool {
 	if len(values) == 0 {
@@ -392,6 +746,38 @@ func validateMutability(mutability string, isUpdate bool, oldVal, newVal interfa
 	return nil
 }
 
+// isDecimalValue reports whether the decoded JSON value is a number.
+func isDecimalValue(value interface{}) bool {
+	switch v := value.(type) {
+	case float64:
+		return true
+	case json.Number:
+		_, err := v.Float64()
+		return err == nil
+	default:
+		return false
+	}
+}
+
+// isIntegerValue reports whether the decoded JSON value is a whole number. A json.Number is checked on its text
+// first, so that integers beyond 2^53 are accepted as they are.
+func isIntegerValue(value interface{}) bool {
+	switch v := value.(type) {
+	case float64:
+		return v == float64(int(v)) // JSON numbers are float64
+	case int:
+		return true
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return true
+		}
+		num, err := v.Float64()
+		return err == nil && num == float64(int(num))
+	default:
+		return false
+	}
+}
+
 func isValidType(value interface{}, expected string, multiValued bool, subAttrs []model.ProfileSchemaAttribute) bool {
 	log.GetLogger().Info("Validating value type", log.String("expected", expected), log.Any("value", value))
 	switch expected {
@@ -418,14 +804,13 @@ func isValidType(value interface{}, expected string, multiValued bool, subAttrs
 				return false
 			}
 			for _, v := range arr {
-				if _, ok := v.(float64); !ok {
+				if !isDecimalValue(v) {
 					return false
 				}
 			}
 			return true
 		}
-		_, ok := value.(float64)
-		return ok
+		return isDecimalValue(value)
 
 	case constants.IntegerDataType:
 		if multiValued {
@@ -434,20 +819,13 @@ func isValidType(value interface{}, expected string, multiValued bool, subAttrs
 				return false
 			}
 			for _, v := range arr {
-				if num, ok := v.(float64); !ok || num != float64(int(num)) {
+				if !isIntegerValue(v) {
 					return false
 				}
 			}
 			return true
 		}
-		switch v := value.(type) {
-		case float64:
-			return v == float64(int(v)) // JSON numbers are float64
-		case int:
-			return true
-		default:
-			return false
-		}
+		return isIntegerValue(value)
 
 	case constants.BooleanDataType:
 		if multiValued {
@@ -536,10 +914,23 @@ func isValidType(value interface{}, expected string, multiValued bool, subAttrs
 	}
 }
 
-// UpdateProfile creates or updates a profile
-func (ps *ProfilesService) UpdateProfile(profileId, orgHandle string, updatedProfile profileModel.ProfileRequest) (*profileModel.ProfileResponse, error) {
+// UpdateProfile creates or updates a profile. A non-zero expectedVersion makes the update fail with a conflict when
+// the profile, or the master it is merged to, has been updated since that version was read.
+func (ps *ProfilesService) UpdateProfile(ctx context.Context, profileId, orgHandle string,
+	updatedProfile profileModel.ProfileRequest, expectedVersion int64) (_ *profileModel.ProfileResponse, err error) {
+
+	ctx, span := tracing.Start(ctx, "ProfilesService.UpdateProfile", tracing.ProfileIdKey.String(profileId))
+	defer func() { tracing.End(span, err) }()
+
+	if updatedProfile.ProfileId != "" && updatedProfile.ProfileId != profileId {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: "profile_id of a profile can not be changed.",
+		}, http.StatusBadRequest)
+	}
 
-	profile, err := profileStore.GetProfile(profileId) //todo: need to get the reference to see what to updatedProfile (see if its the master)
+	profile, err := profileStore.GetProfileContext(ctx, profileId) //todo: need to get the reference to see what to updatedProfile (see if its the master)
 	logger := log.GetLogger()
 	if err != nil {
 		errMsg := fmt.Sprintf("Error fetching profile for updatedProfile: %s", profileId)
@@ -580,6 +971,7 @@ func (ps *ProfilesService) UpdateProfile(profileId, orgHandle string, updatedPro
 		return nil, serverError
 	}
 
+	normalizeProfileRequest(&updatedProfile)
 	err = ValidateProfileAgainstSchema(updatedProfile, *profile, schema, true)
 	if err != nil {
 		return nil, err
@@ -599,10 +991,11 @@ func (ps *ProfilesService) UpdateProfile(profileId, orgHandle string, updatedPro
 			CreatedAt:          profile.CreatedAt,
 			Location:           profile.Location,
 			ProfileStatus:      profile.ProfileStatus,
+			Version:            expectedVersion,
 		}
 	} else {
 		// If it is a child profile, we need to update the master profile
-		masterProfile, err := profileStore.GetProfile(profile.ProfileStatus.ReferenceProfileId)
+		masterProfile, err := getReferenceProfile(profile)
 		if err != nil {
 			errMsg := fmt.Sprintf("Error fetching master profile for updatedProfile: %s", profile.ProfileId)
 			logger.Debug(errMsg, log.Error(err))
@@ -624,15 +1017,35 @@ func (ps *ProfilesService) UpdateProfile(profileId, orgHandle string, updatedPro
 			CreatedAt:          masterProfile.CreatedAt,
 			Location:           masterProfile.Location,
 			ProfileStatus:      masterProfile.ProfileStatus,
+			Version:            expectedVersion,
 		}
 	}
+	profileToUpDate.Traits = applyComputedTraits(profileToUpDate.Traits, schema.Traits)
 
-	if err := profileStore.UpdateProfile(profileToUpDate); err != nil {
+	if ctx.Err() != nil {
+		return nil, abortedRequestError(ctx.Err())
+	}
+	if err := profileStore.UpdateProfileContext(ctx, profileToUpDate); err != nil {
+		if ctx.Err() != nil {
+			return nil, abortedRequestError(ctx.Err())
+		}
+		if errors.Is(err, profileModel.ErrProfileVersionConflict) {
+			metrics.ProfileVersionConflicts.Inc()
+			return nil, errors2.NewClientError(errors2.ErrorMessage{
+				Code:    errors2.PROFILE_VERSION_CONFLICT.Code,
+				Message: errors2.PROFILE_VERSION_CONFLICT.Message,
+				Description: fmt.Sprintf("Profile: %s has been modified since version %d. Fetch the profile and "+
+					"retry the update.", profileId, expectedVersion),
+			}, http.StatusConflict)
+		}
 		logger.Error(fmt.Sprintf("Error inserting/updating profile: %s", profile.ProfileId), log.Error(err))
 		return nil, err
 	}
+	metrics.ProfileUpserts.Inc("update")
+	changestream.PublishProfileChange(constants.ProfileChangeUpdated, orgHandle, profileId, nil)
 
-	profileFetched, errWait := ps.GetProfile(profile.ProfileId)
+	profileFetched, errWait := ps.getProfile(ctx, profile.ProfileId, profileModel.AllApplications,
+		profileStore.GetProfileContext)
 	if errWait != nil || profileFetched == nil {
 		logger.Warn(fmt.Sprintf("Profile: %s not visible after insert/updatedProfile: %v", profile.ProfileId, errWait))
 		// todo: should we throw an error here?
@@ -672,10 +1085,80 @@ func ConvertAppDataToMap(appDataList []profileModel.ApplicationData) map[string]
 	return result
 }
 
-// GetProfile retrieves a profile
-func (ps *ProfilesService) GetProfile(ProfileId string) (*profileModel.ProfileResponse, error) {
+// EnsureProfileInOrg verifies that the profile belongs to the organization. Profiles of other organizations are
+// reported as not found so that their existence is not revealed. Soft-deleted profiles are included so that they
+// can still be restored.
+func (ps *ProfilesService) EnsureProfileInOrg(profileId, orgHandle string) error {
 
-	profile, err := profileStore.GetProfile(ProfileId)
+	profileOrgHandle, err := profileStore.GetProfileOrgHandle(profileId)
+	if err != nil {
+		return err
+	}
+	if profileOrgHandle != orgHandle {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+	return nil
+}
+
+// GetProfile retrieves a profile. A restricted appScope limits the application data to that application, including
+// the data taken from the master of a merged profile, and leaves out the traits the application may not read. The
+// profile is read through the read replica when one is configured.
+func (ps *ProfilesService) GetProfile(ProfileId string,
+	appScope profileModel.AppScope) (*profileModel.ProfileResponse, error) {
+
+	return ps.GetProfileContext(context.Background(), ProfileId, appScope)
+}
+
+// GetProfileContext retrieves a profile like GetProfile, tracing the retrieval as part of the context.
+func (ps *ProfilesService) GetProfileContext(ctx context.Context, ProfileId string,
+	appScope profileModel.AppScope) (*profileModel.ProfileResponse, error) {
+
+	ctx, span := tracing.Start(ctx, "ProfilesService.GetProfile", tracing.ProfileIdKey.String(ProfileId))
+	profile, err := ps.getProfile(ctx, ProfileId, appScope, profileStore.GetProfileFromReadReplicaContext)
+	tracing.End(span, err)
+	return profile, err
+}
+
+// GetProfileFromPrimary retrieves a profile like GetProfile but always from the primary database, so that a
+// profile can be read back right after it was written.
+func (ps *ProfilesService) GetProfileFromPrimary(ProfileId string,
+	appScope profileModel.AppScope) (*profileModel.ProfileResponse, error) {
+
+	return ps.getProfile(context.Background(), ProfileId, appScope, profileStore.GetProfileContext)
+}
+
+// GetProfileForChangeStream retrieves the snapshot of a profile published to the profile change stream. The
+// consumers of the stream are not applications of the organization, so the traits restricted to particular
+// applications are left out.
+func (ps *ProfilesService) GetProfileForChangeStream(profileId string) (*profileModel.ProfileResponse, error) {
+
+	profile, err := ps.GetProfile(profileId, profileModel.AllApplications)
+	if err != nil {
+		return nil, err
+	}
+	storedProfile, err := profileStore.GetProfile(profileId)
+	if err != nil {
+		return nil, err
+	}
+	if storedProfile == nil {
+		return profile, nil
+	}
+	restricted, err := appRestrictedTraitPaths(storedProfile.OrgHandle)
+	if err != nil {
+		return nil, err
+	}
+	profile.Traits = redactTraits(profile.Traits, restricted)
+	return profile, nil
+}
+
+func (ps *ProfilesService) getProfile(ctx context.Context, ProfileId string, appScope profileModel.AppScope,
+	fetchProfile func(context.Context, string) (*profileModel.Profile, error)) (*profileModel.ProfileResponse, error) {
+
+	profile, err := fetchProfile(ctx, ProfileId)
 	if err != nil {
 		return nil, err
 	}
@@ -706,69 +1189,368 @@ func (ps *ProfilesService) GetProfile(ProfileId string) (*profileModel.ProfileRe
 		if len(alias) == 0 {
 			alias = nil
 		}
+		traits, err := resolveHierarchyTraits(profile.OrgHandle, profile, alias)
+		if err != nil {
+			return nil, err
+		}
+		restricted, err := restrictedTraitPaths(profile.OrgHandle, appScope)
+		if err != nil {
+			return nil, err
+		}
 
 		profileResponse := &profileModel.ProfileResponse{
 			ProfileId:          profile.ProfileId,
+			CanonicalProfileId: profile.ProfileId,
 			UserId:             profile.UserId,
-			ApplicationData:    ConvertAppDataToMap(profile.ApplicationData),
-			Traits:             profile.Traits,
+			ApplicationData:    ConvertAppDataToMap(restrictApplicationData(profile.ApplicationData, appScope)),
+			Traits:             redactTraits(traits, restricted),
 			IdentityAttributes: profile.IdentityAttributes,
 			Meta: profileModel.Meta{
 				CreatedAt: profile.CreatedAt,
 				UpdatedAt: profile.UpdatedAt,
 				Location:  profile.Location,
+				Version:   profile.Version,
 			},
 			MergedFrom: alias,
 		}
 		return profileResponse, nil
 	} else {
 		// fetching merged master profile
-		masterProfile, err := profileStore.GetProfile(profile.ProfileStatus.ReferenceProfileId)
+		masterProfile, err := getReferenceProfile(profile)
 
 		if err != nil {
 			return nil, err
 		}
-		if masterProfile != nil {
-			masterProfile.ApplicationData, err = profileStore.FetchApplicationData(masterProfile.ProfileId)
-			if err != nil {
-				return nil, err
-			}
-
-			alias := &profileModel.Reference{
-				ProfileId: profile.ProfileStatus.ReferenceProfileId,
-				Reason:    profile.ProfileStatus.ReferenceReason,
-			}
+		if masterProfile.ProfileId == profile.ProfileId {
+			// The profile was orphaned and has been promoted to a reference profile.
+			return ps.getProfile(ctx, ProfileId, appScope, profileStore.GetProfileContext)
+		}
+		masterProfile.ApplicationData, err = profileStore.FetchApplicationData(masterProfile.ProfileId)
+		if err != nil {
+			return nil, err
+		}
 
-			profileResponse := &profileModel.ProfileResponse{
-				ProfileId:          profile.ProfileId,
-				UserId:             masterProfile.UserId,
-				ApplicationData:    ConvertAppDataToMap(masterProfile.ApplicationData),
-				Traits:             masterProfile.Traits,
-				IdentityAttributes: masterProfile.IdentityAttributes,
-				Meta: profileModel.Meta{
-					CreatedAt: masterProfile.CreatedAt,
-					UpdatedAt: masterProfile.UpdatedAt,
-					Location:  masterProfile.Location,
-				},
-				MergedTo: alias,
-			}
+		// The profile may be linked to the master through intermediate profiles, so the master is reported rather
+		// than the profile it was directly merged to.
+		alias := &profileModel.Reference{
+			ProfileId: masterProfile.ProfileId,
+			Reason:    profile.ProfileStatus.ReferenceReason,
+		}
+		siblings, err := profileStore.FetchReferencedProfiles(masterProfile.ProfileId)
+		if err != nil {
+			return nil, err
+		}
+		traits, err := resolveHierarchyTraits(profile.OrgHandle, masterProfile, siblings)
+		if err != nil {
+			return nil, err
+		}
+		restricted, err := restrictedTraitPaths(profile.OrgHandle, appScope)
+		if err != nil {
+			return nil, err
+		}
 
-			return profileResponse, nil
+		profileResponse := &profileModel.ProfileResponse{
+			ProfileId:          profile.ProfileId,
+			CanonicalProfileId: masterProfile.ProfileId,
+			UserId:             masterProfile.UserId,
+			ApplicationData:    ConvertAppDataToMap(restrictApplicationData(masterProfile.ApplicationData, appScope)),
+			Traits:             redactTraits(traits, restricted),
+			IdentityAttributes: masterProfile.IdentityAttributes,
+			Meta: profileModel.Meta{
+				CreatedAt: masterProfile.CreatedAt,
+				UpdatedAt: masterProfile.UpdatedAt,
+				Location:  masterProfile.Location,
+				Version:   masterProfile.Version,
+			},
+			MergedTo: alias,
 		}
-		return nil, err
+
+		return profileResponse, nil
 	}
 }
 
-// GetProfileConsents retrieves a profile
-func (ps *ProfilesService) GetProfileConsents(ProfileId string) ([]profileModel.ConsentRecord, error) {
+// resolveHierarchyTraits returns the traits of a reference profile resolved against the traits of the profiles
+// unified into it, following the configured trait conflict strategy.
+func resolveHierarchyTraits(orgHandle string, master *profileModel.Profile,
+	references []profileModel.Reference) (map[string]interface{}, error) {
 
-	consentRecords, err := profileStore.GetProfileConsents(ProfileId)
-	if err != nil {
-		return nil, err
+	return resolveHierarchyTraitsOf(orgHandle, master, references, profileStore.GetProfile)
+}
+
+// resolveHierarchyTraitsOf resolves the traits of a reference profile like resolveHierarchyTraits, fetching the
+// unified profiles with the given function.
+func resolveHierarchyTraitsOf(orgHandle string, master *profileModel.Profile, references []profileModel.Reference,
+	fetchProfile func(string) (*profileModel.Profile, error)) (map[string]interface{}, error) {
+
+	if len(references) == 0 {
+		return master.Traits, nil
 	}
-	if consentRecords == nil {
-		clientError := errors2.NewClientError(errors2.ErrorMessage{
-			Code:        errors2.PROFILE_NOT_FOUND.Code,
+
+	children := make([]profileModel.Profile, 0, len(references))
+	for _, reference := range references {
+		child, err := fetchProfile(reference.ProfileId)
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			children = append(children, *child)
+		}
+	}
+
+	resolver, err := newTraitResolver(orgHandle)
+	if err != nil {
+		return nil, err
+	}
+	return resolver.resolve(*master, children), nil
+}
+
+// traitResolver resolves the traits of the reference profiles of an organization following the configured trait
+// conflict strategy and the unification rules of the organization, keyed by their name.
+type traitResolver struct {
+	strategy string
+	rules    map[string]UnificationModel.UnificationRule
+}
+
+// newTraitResolver returns the trait resolver of the organization.
+func newTraitResolver(orgHandle string) (*traitResolver, error) {
+
+	rules, err := unificationStore.GetUnificationRules(orgHandle)
+	if err != nil {
+		return nil, err
+	}
+	resolver := &traitResolver{
+		strategy: config.GetCDSRuntime().Config.TraitConflicts.Strategy,
+		rules:    make(map[string]UnificationModel.UnificationRule, len(rules)),
+	}
+	for _, rule := range rules {
+		resolver.rules[rule.RuleName] = rule
+	}
+	return resolver, nil
+}
+
+// resolve returns the traits of the reference profile resolved against the traits of the profiles unified into it.
+// Profiles linked to it by a link only rule keep their traits to themselves and are left out, as they are when the
+// rule links them.
+func (tr *traitResolver) resolve(master profileModel.Profile, children []profileModel.Profile) map[string]interface{} {
+
+	merged := make([]profileModel.Profile, 0, len(children))
+	for _, child := range children {
+		if child.ProfileStatus != nil &&
+			tr.rules[child.ProfileStatus.ReferenceReason].MergeMode == constants.MergeModeLinkOnly {
+			continue
+		}
+		merged = append(merged, child)
+	}
+	return profileModel.ResolveHierarchyTraits(master, merged, tr.strategy, func(ruleName string) (int, bool) {
+		rule, ok := tr.rules[ruleName]
+		return rule.Priority, ok
+	})
+}
+
+// resolveListedProfiles builds the responses of a page of listed profiles. The profiles unified into the listed
+// reference profiles are fetched together in one pass instead of once per profile. Profiles that are not reference
+// profiles are left out. A restricted appScope restricts the application data and traits like GetProfile.
+func resolveListedProfiles(orgHandle string, profiles []profileModel.Profile, appScope profileModel.AppScope,
+	restricted [][]string) ([]profileModel.ProfileResponse, error) {
+
+	masters := make([]profileModel.Profile, 0, len(profiles))
+	for _, profile := range profiles {
+		if profile.ProfileStatus.IsReferenceProfile {
+			masters = append(masters, profile)
+		}
+	}
+	traits, aliases, err := resolveUnifiedTraits(orgHandle, masters)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]profileModel.ProfileResponse, 0, len(masters))
+	for _, profile := range masters {
+		alias := aliases[profile.ProfileId]
+		result = append(result, profileModel.ProfileResponse{
+			ProfileId:          profile.ProfileId,
+			CanonicalProfileId: profile.ProfileId,
+			UserId:             profile.UserId,
+			ApplicationData:    ConvertAppDataToMap(restrictApplicationData(profile.ApplicationData, appScope)),
+			Traits:             redactTraits(traits[profile.ProfileId], restricted),
+			IdentityAttributes: profile.IdentityAttributes,
+			// The meta of the listed row must be preserved for cursor correctness.
+			Meta: profileModel.Meta{
+				CreatedAt: profile.CreatedAt,
+				UpdatedAt: profile.UpdatedAt,
+				Location:  profile.Location,
+				DeletedAt: profile.DeletedAt,
+			},
+			MergedFrom: alias,
+		})
+	}
+	return result, nil
+}
+
+// resolveUnifiedTraits resolves the traits of the reference profiles against the traits of the profiles unified
+// into them, fetching the unified profiles of all of them together in one pass. It returns the resolved traits and
+// the profiles unified into each reference profile, both keyed by the reference profile id.
+func resolveUnifiedTraits(orgHandle string, masters []profileModel.Profile) (map[string]map[string]interface{},
+	map[string][]profileModel.Reference, error) {
+
+	masterIds := make([]string, 0, len(masters))
+	for _, profile := range masters {
+		masterIds = append(masterIds, profile.ProfileId)
+	}
+	aliases, err := profileStore.FetchReferencedProfilesBatch(masterIds)
+	if err != nil {
+		return nil, nil, err
+	}
+	var childIds []string
+	for _, references := range aliases {
+		for _, reference := range references {
+			childIds = append(childIds, reference.ProfileId)
+		}
+	}
+	children, err := profileStore.GetProfilesBatch(childIds)
+	if err != nil {
+		return nil, nil, err
+	}
+	var resolver *traitResolver
+	if len(children) > 0 {
+		if resolver, err = newTraitResolver(orgHandle); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	traits := make(map[string]map[string]interface{}, len(masters))
+	for _, profile := range masters {
+		alias := aliases[profile.ProfileId]
+		unified := make([]profileModel.Profile, 0, len(alias))
+		for _, reference := range alias {
+			if child, ok := children[reference.ProfileId]; ok {
+				unified = append(unified, *child)
+			}
+		}
+		traits[profile.ProfileId] = profile.Traits
+		if len(unified) > 0 {
+			traits[profile.ProfileId] = resolver.resolve(profile, unified)
+		}
+	}
+	return traits, aliases, nil
+}
+
+// streamProfileChunks streams the reference profiles of the organization matching the rewritten filters to handle
+// in chunks of up to ProfileExportFlushRows profiles, so that what each of them needs beyond its own row is fetched
+// once per chunk instead of once per profile. The chunk is reused after handle returns.
+func streamProfileChunks(ctx context.Context, orgHandle string, filters []string,
+	handle func(profiles []profileModel.Profile) error) error {
+
+	chunk := make([]profileModel.Profile, 0, constants.ProfileExportFlushRows)
+	err := profileStore.StreamProfilesWithFilter(ctx, orgHandle, filters, func(profile profileModel.Profile) error {
+		chunk = append(chunk, profile)
+		if len(chunk) < constants.ProfileExportFlushRows {
+			return nil
+		}
+		err := handle(chunk)
+		chunk = chunk[:0]
+		return err
+	})
+	if err != nil || len(chunk) == 0 {
+		return err
+	}
+	return handle(chunk)
+}
+
+// getReferenceProfile returns the reference profile at the top of the hierarchy of a merged profile. The reference
+// links are followed only up to a bounded depth so that a corrupted hierarchy can not loop. If the hierarchy ends
+// at a reference profile that no longer exists, the last existing profile is promoted to a reference profile and
+// returned, which is the profile itself when it was merged to the missing profile directly.
+func getReferenceProfile(profile *profileModel.Profile) (*profileModel.Profile, error) {
+
+	fetched := make(map[string]*profileModel.Profile)
+	orphanId := profile.ProfileId
+	lookup := func(profileId string) (string, error) {
+		referenceProfile, err := profileStore.GetProfile(profileId)
+		if err != nil {
+			return "", err
+		}
+		fetched[profileId] = referenceProfile
+		if referenceProfile == nil || referenceProfile.ProfileStatus.IsReferenceProfile {
+			return "", nil
+		}
+		orphanId = profileId
+		return referenceProfile.ProfileStatus.ReferenceProfileId, nil
+	}
+	referenceProfileId, err := profileModel.ResolveReferenceProfileId(profile.ProfileStatus.ReferenceProfileId, lookup)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to resolve the reference profile of profile: %s", profile.ProfileId)
+		log.GetLogger().Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if referenceProfile := fetched[referenceProfileId]; referenceProfile != nil {
+		return referenceProfile, nil
+	}
+
+	if err := promoteOrphanedProfile(profile.OrgHandle, orphanId); err != nil {
+		return nil, err
+	}
+	promoted := fetched[orphanId]
+	if promoted == nil {
+		promoted = profile
+	}
+	promoted.ProfileStatus.IsReferenceProfile = true
+	promoted.ProfileStatus.ReferenceProfileId = ""
+	promoted.ProfileStatus.ReferenceReason = ""
+	return promoted, nil
+}
+
+// promoteOrphanedProfile turns a merged profile whose reference profile no longer exists into a reference profile.
+func promoteOrphanedProfile(orgHandle, profileId string) error {
+
+	logger := log.GetLogger()
+	promoted, err := profileStore.PromoteOrphanedProfiles(orgHandle, profileId)
+	if err != nil {
+		return err
+	}
+	if len(promoted) == 0 {
+		errorMsg := fmt.Sprintf("Profile: %s is merged to a reference profile that does not exist and could not "+
+			"be promoted", profileId)
+		logger.Debug(errorMsg)
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ORPHANED_PROFILE.Code,
+			Message:     errors2.ORPHANED_PROFILE.Message,
+			Description: errorMsg,
+		}, nil)
+	}
+	logger.Warn(fmt.Sprintf("Promoted orphaned profile: %s to a reference profile", profileId))
+	return nil
+}
+
+// RepairOrphanedProfiles promotes the merged profiles of an organization whose reference profile no longer exists
+// to reference profiles, so that they are listed again, and returns the number of profiles promoted.
+func (ps *ProfilesService) RepairOrphanedProfiles(orgHandle string) (int64, error) {
+
+	promoted, err := profileStore.PromoteOrphanedProfiles(orgHandle, "")
+	if err != nil {
+		return 0, err
+	}
+	if len(promoted) > 0 {
+		log.GetLogger().Warn(fmt.Sprintf("Promoted %d orphaned profile(s) of organization: %s to reference profiles",
+			len(promoted), orgHandle))
+	}
+	return int64(len(promoted)), nil
+}
+
+// GetProfileConsents retrieves a profile
+func (ps *ProfilesService) GetProfileConsents(ProfileId string) ([]profileModel.ConsentRecord, error) {
+
+	consentRecords, err := profileStore.GetProfileConsents(ProfileId)
+	if err != nil {
+		return nil, err
+	}
+	if consentRecords == nil {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
 			Message:     errors2.PROFILE_NOT_FOUND.Message,
 			Description: errors2.PROFILE_NOT_FOUND.Description,
 		}, http.StatusNotFound)
@@ -797,43 +1579,103 @@ func (ps *ProfilesService) UpdateProfileConsents(profileId string, consents []pr
 		logger.Debug(errorMsg, log.Error(err))
 		return err
 	}
+	if orgHandle, err := profileStore.GetProfileOrgHandle(profileId); err == nil && orgHandle != "" {
+		changestream.PublishProfileChange(constants.ProfileChangeUpdated, orgHandle, profileId, nil)
+	}
 
 	return nil
 }
 
-// DeleteProfile removes a profile from MongoDB by `perma_id`
+// DeleteProfile soft-deletes a profile along with the profiles it is unified with. The data is retained so that
+// the deletion can be reverted with RestoreProfile until it is purged by PurgeDeletedProfiles.
 func (ps *ProfilesService) DeleteProfile(ProfileId string) error {
 
 	// Fetch the existing profile before deletion
 	profile, err := profileStore.GetProfile(ProfileId)
 	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Error deleting profile with profile_id: %s", ProfileId)
+		logger.Debug(errorMsg, log.Error(err))
+		serverError := errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_PROFILE.Code,
+			Message:     errors2.DELETE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+		return serverError
+	}
 	if profile == nil {
 		logger.Warn(fmt.Sprintf("Profile with profile_id: %s that is requested for deletion is not found",
 			ProfileId))
 		return nil
 	}
+
+	// All profiles deleted in this operation share the same deletion time so that they can be restored together.
+	deletedAt := time.Now().UTC()
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
 	if err != nil {
-		errorMsg := fmt.Sprintf("Error deleting profile with profile_id: %s", ProfileId)
+		errorMsg := fmt.Sprintf("Failed to get db client for deleting profile: %s", ProfileId)
 		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
+		return errors2.NewServerError(errors2.ErrorMessage{
 			Code:        errors2.DELETE_PROFILE.Code,
 			Message:     errors2.DELETE_PROFILE.Message,
 			Description: errorMsg,
 		}, err)
-		return serverError
+	}
+	defer dbClient.Close()
+
+	err = dbClient.RunInTx(func(tx *sql.Tx) error {
+		return deleteProfileHierarchy(tx, ProfileId, deletedAt)
+	})
+	if err != nil {
+		// Errors of the deletion itself are already reported; those of beginning or committing the transaction are not.
+		switch err.(type) {
+		case *errors2.ServerError, *errors2.ClientError:
+			return err
+		}
+		errorMsg := fmt.Sprintf("Failed to delete profile: %s", ProfileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_PROFILE.Code,
+			Message:     errors2.DELETE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	webhookService.NotifyProfileDeleted(profile.OrgHandle, ProfileId)
+	changestream.PublishProfileChange(constants.ProfileChangeDeleted, profile.OrgHandle, ProfileId, nil)
+	return nil
+}
+
+// deleteProfileHierarchy soft-deletes a profile within the transaction, together with its merged profiles when it
+// is a reference profile, or with its reference profile when it is the last profile merged to it. The hierarchy is
+// read within the transaction, with the profile and its reference profile locked, so that it is not changed by a
+// concurrent merge or deletion in between.
+func deleteProfileHierarchy(tx *sql.Tx, profileId string, deletedAt time.Time) error {
+
+	logger := log.GetLogger()
+	profile, err := profileStore.LockProfile(tx, profileId)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		logger.Debug(fmt.Sprintf("Profile: %s was deleted concurrently", profileId))
+		return nil
 	}
 
 	if profile.ProfileStatus.IsReferenceProfile {
 		// fetching the child if its parent
-		profile.ProfileStatus.References, _ = profileStore.FetchReferencedProfiles(profile.ProfileId)
+		profile.ProfileStatus.References, err = profileStore.FetchReferencedProfilesInTx(tx, profile.ProfileId)
+		if err != nil {
+			return err
+		}
 	}
 
 	if profile.ProfileStatus.IsReferenceProfile && len(profile.ProfileStatus.References) == 0 {
-		logger.Info(fmt.Sprintf("Deleting parent profile: %s with no children", ProfileId))
+		logger.Info(fmt.Sprintf("Deleting parent profile: %s with no children", profileId))
 		// Delete the parent with no children
-		err = profileStore.DeleteProfile(ProfileId)
+		err = profileStore.SoftDeleteProfile(tx, profileId, deletedAt)
 		if err != nil {
-			errorMsg := fmt.Sprintf("Error deleting profile with profile_id: %s which is a parent and no children", ProfileId)
+			errorMsg := fmt.Sprintf("Error deleting profile with profile_id: %s which is a parent and no children", profileId)
 			logger.Debug(errorMsg, log.Error(err))
 			serverError := errors2.NewServerError(errors2.ErrorMessage{
 				Code:        errors2.DELETE_PROFILE.Code,
@@ -848,9 +1690,9 @@ func (ps *ProfilesService) DeleteProfile(ProfileId string) error {
 	if profile.ProfileStatus.IsReferenceProfile && len(profile.ProfileStatus.References) > 0 {
 		//get all child profiles and delete
 		for _, childProfile := range profile.ProfileStatus.References {
-			err = profileStore.DeleteProfile(childProfile.ProfileId)
+			err = profileStore.SoftDeleteProfile(tx, childProfile.ProfileId, deletedAt)
 			logger.Info(fmt.Sprintf("Deleting child  profile: %s with of parent: %s",
-				childProfile.ProfileId, ProfileId))
+				childProfile.ProfileId, profileId))
 
 			if err != nil {
 				errorMsg := fmt.Sprintf("Error while deleting profile with profile_id: %s ", childProfile.ProfileId)
@@ -864,10 +1706,10 @@ func (ps *ProfilesService) DeleteProfile(ProfileId string) error {
 			}
 		}
 		// now delete master
-		err = profileStore.DeleteProfile(ProfileId)
-		logger.Info(fmt.Sprintf("Deleting parent profile: %s with children", ProfileId))
+		err = profileStore.SoftDeleteProfile(tx, profileId, deletedAt)
+		logger.Info(fmt.Sprintf("Deleting parent profile: %s with children", profileId))
 		if err != nil {
-			errorMsg := fmt.Sprintf("Error while deleting parent profile: %s ", ProfileId)
+			errorMsg := fmt.Sprintf("Error while deleting parent profile: %s ", profileId)
 			logger.Debug(errorMsg, log.Error(err))
 			serverError := errors2.NewServerError(errors2.ErrorMessage{
 				Code:        errors2.DELETE_PROFILE.Code,
@@ -882,11 +1724,11 @@ func (ps *ProfilesService) DeleteProfile(ProfileId string) error {
 	// If it is a child profile, delete it
 	if !(profile.ProfileStatus.IsReferenceProfile) {
 
-		logger.Info(fmt.Sprintf("Deleting child profile: %s with parent: %s", ProfileId,
+		logger.Info(fmt.Sprintf("Deleting child profile: %s with parent: %s", profileId,
 			profile.ProfileStatus.ReferenceProfileId))
-		parentProfile, err := profileStore.GetProfile(profile.ProfileStatus.ReferenceProfileId)
+		parentProfile, err := profileStore.LockProfile(tx, profile.ProfileStatus.ReferenceProfileId)
 		if err != nil {
-			errorMsg := fmt.Sprintf("Error while deleting the child profile: %s ", ProfileId)
+			errorMsg := fmt.Sprintf("Error while deleting the child profile: %s ", profileId)
 			logger.Debug(errorMsg, log.Error(err))
 			serverError := errors2.NewServerError(errors2.ErrorMessage{
 				Code:        errors2.DELETE_PROFILE.Code,
@@ -895,15 +1737,25 @@ func (ps *ProfilesService) DeleteProfile(ProfileId string) error {
 			}, err)
 			return serverError
 		}
-		parentProfile.ProfileStatus.References, _ = profileStore.FetchReferencedProfiles(parentProfile.ProfileId)
+		if parentProfile == nil {
+			// The parent is already gone, so only the orphaned profile itself is left to delete.
+			logger.Warn(fmt.Sprintf("Parent profile: %s of profile: %s does not exist. Deleting the orphaned profile.",
+				profile.ProfileStatus.ReferenceProfileId, profileId))
+			return profileStore.SoftDeleteProfile(tx, profileId, deletedAt)
+		}
+		parentProfile.ProfileStatus.References, err = profileStore.FetchReferencedProfilesInTx(tx,
+			parentProfile.ProfileId)
+		if err != nil {
+			return err
+		}
 
 		if len(parentProfile.ProfileStatus.References) == 1 {
 			// delete the parent as this is the only child
 			logger.Info(fmt.Sprintf("Deleting parent profile: %s with of current : %s",
-				profile.ProfileStatus.ReferenceProfileId, ProfileId))
-			err = profileStore.DeleteProfile(profile.ProfileStatus.ReferenceProfileId)
+				profile.ProfileStatus.ReferenceProfileId, profileId))
+			err = profileStore.SoftDeleteProfile(tx, profile.ProfileStatus.ReferenceProfileId, deletedAt)
 			if err != nil {
-				errorMsg := fmt.Sprintf("Error while deleting the master profile: %s ", ProfileId)
+				errorMsg := fmt.Sprintf("Error while deleting the master profile: %s ", profileId)
 				logger.Debug(errorMsg, log.Error(err))
 				serverError := errors2.NewServerError(errors2.ErrorMessage{
 					Code:        errors2.DELETE_PROFILE.Code,
@@ -913,10 +1765,10 @@ func (ps *ProfilesService) DeleteProfile(ProfileId string) error {
 				return serverError
 			}
 			//todo: Ensure the need to detach the referer profile from the reference
-			//err = profileStore.DetachRefererProfileFromReference(profile.ProfileStatus.ReferenceProfileId, ProfileId)
-			err = profileStore.DeleteProfile(ProfileId)
+			//err = profileStore.DetachRefererProfileFromReference(profile.ProfileStatus.ReferenceProfileId, profileId)
+			err = profileStore.SoftDeleteProfile(tx, profileId, deletedAt)
 			if err != nil {
-				errorMsg := fmt.Sprintf("Error while deleting the  profile: %s ", ProfileId)
+				errorMsg := fmt.Sprintf("Error while deleting the  profile: %s ", profileId)
 				logger.Debug(errorMsg, log.Error(err))
 				serverError := errors2.NewServerError(errors2.ErrorMessage{
 					Code:        errors2.DELETE_PROFILE.Code,
@@ -925,25 +1777,16 @@ func (ps *ProfilesService) DeleteProfile(ProfileId string) error {
 				}, err)
 				return serverError
 			}
-			logger.Info(fmt.Sprintf("Deleted current profile: %s with parent: %s", ProfileId,
+			logger.Info(fmt.Sprintf("Deleted current profile: %s with parent: %s", profileId,
 				profile.ProfileStatus.ReferenceProfileId))
 		} else {
-			err = profileStore.DetachRefererProfileFromReference(profile.ProfileStatus.ReferenceProfileId, ProfileId)
-			if err != nil {
-				errorMsg := fmt.Sprintf("Error while current profile from parent: %s ", ProfileId)
-				logger.Debug(errorMsg, log.Error(err))
-				serverError := errors2.NewServerError(errors2.ErrorMessage{
-					Code:        errors2.DELETE_PROFILE.Code,
-					Message:     errors2.DELETE_PROFILE.Message,
-					Description: errorMsg,
-				}, err)
-				return serverError
-			}
-			logger.Debug(fmt.Sprintf("Detaching current profile: %s from parent: %s", ProfileId,
+			// The reference is kept so that the profile can be restored. Deleted profiles are not listed as
+			// references of the parent, which detaches the profile logically.
+			logger.Debug(fmt.Sprintf("Detaching current profile: %s from parent: %s", profileId,
 				profile.ProfileStatus.ReferenceProfileId))
-			err = profileStore.DeleteProfile(ProfileId)
+			err = profileStore.SoftDeleteProfile(tx, profileId, deletedAt)
 			if err != nil {
-				errorMsg := fmt.Sprintf("Error while deleting the current profile: %s ", ProfileId)
+				errorMsg := fmt.Sprintf("Error while deleting the current profile: %s ", profileId)
 				logger.Debug(errorMsg, log.Error(err))
 				serverError := errors2.NewServerError(errors2.ErrorMessage{
 					Code:        errors2.DELETE_PROFILE.Code,
@@ -953,7 +1796,7 @@ func (ps *ProfilesService) DeleteProfile(ProfileId string) error {
 				return serverError
 			}
 			logger.Info(fmt.Sprintf("Deleted current profile: %s with parent: %s",
-				ProfileId, profile.ProfileStatus.ReferenceProfileId))
+				profileId, profile.ProfileStatus.ReferenceProfileId))
 		}
 
 	}
@@ -961,134 +1804,1099 @@ func (ps *ProfilesService) DeleteProfile(ProfileId string) error {
 	return nil
 }
 
-// GetAllProfilesCursor retrieves all master profiles with pagination using cursor.
-// Merged profiles are not included in list but provided in the reference
-func (ps *ProfilesService) GetAllProfilesCursor(
-	orgHandle string,
-	limit int,
-	cursor *profileModel.ProfileCursor,
-) ([]profileModel.ProfileResponse, bool, error) {
+// MergeProfiles links the child profile to the master profile regardless of the unification rules. The data of
+// the child is merged into the master and any profiles already merged to the child are moved under the master.
+func (ps *ProfilesService) MergeProfiles(masterProfileId, childProfileId string) error {
+
+	return ps.mergeProfiles(masterProfileId, childProfileId, profileModel.Reference{
+		Reason: constants.ManualMergeReason,
+	}, constants.MergeModeFull)
+}
 
-	existingProfiles, hasMore, err := profileStore.GetAllProfiles(orgHandle, limit, cursor)
+// AliasProfiles links the profile of an anonymous visitor to the profile the visitor became known as. The from
+// profile, with its application data and any profiles already merged into it, is merged into the reference profile
+// of the to profile, so aliasing to a merged profile links to the top of its hierarchy. Aliasing profiles that are
+// already linked succeeds without changes so that a repeated alias is harmless. The reference profile is returned,
+// restricted to what appScope may read like in GetProfile.
+func (ps *ProfilesService) AliasProfiles(fromProfileId, toProfileId string,
+	appScope profileModel.AppScope) (*profileModel.ProfileResponse, error) {
+
+	fromProfile, err := profileStore.GetProfile(fromProfileId)
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
-	if existingProfiles == nil {
-		return []profileModel.ProfileResponse{}, false, nil
+	toProfile, err := profileStore.GetProfile(toProfileId)
+	if err != nil {
+		return nil, err
 	}
-
-	if len(existingProfiles) > limit {
-		hasMore = true
-		existingProfiles = existingProfiles[:limit]
+	if fromProfile == nil || toProfile == nil {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
 	}
-
-	result := make([]profileModel.ProfileResponse, 0, len(existingProfiles))
-
-	for _, profile := range existingProfiles {
-
-		//  Base row meta must be preserved for cursor correctness
-		baseMeta := profileModel.Meta{
-			CreatedAt: profile.CreatedAt,
-			UpdatedAt: profile.UpdatedAt,
-			Location:  profile.Location,
+	if !toProfile.ProfileStatus.IsReferenceProfile {
+		if toProfile, err = getReferenceProfile(toProfile); err != nil {
+			return nil, err
 		}
-
-		alias, err := profileStore.FetchReferencedProfiles(profile.ProfileId)
+	}
+	linked := fromProfile.ProfileId == toProfile.ProfileId
+	if !linked && !fromProfile.ProfileStatus.IsReferenceProfile {
+		referenceProfile, err := getReferenceProfile(fromProfile)
 		if err != nil {
-			errorMsg := fmt.Sprintf("Error fetching references for profile: %s", profile.ProfileId)
-			logger := log.GetLogger()
-			logger.Debug(errorMsg, log.Error(err))
-			serverError := errors2.NewServerError(errors2.ErrorMessage{
-				Code:        errors2.GET_PROFILE.Code,
-				Message:     errors2.GET_PROFILE.Message,
-				Description: errorMsg,
-			}, err)
-			return nil, false, serverError
-		}
-		if len(alias) == 0 {
-			alias = nil
+			return nil, err
 		}
-
-		if profile.ProfileStatus.IsReferenceProfile {
-			result = append(result, profileModel.ProfileResponse{
-				ProfileId:          profile.ProfileId,
-				UserId:             profile.UserId,
-				ApplicationData:    ConvertAppDataToMap(profile.ApplicationData),
-				Traits:             profile.Traits,
-				IdentityAttributes: profile.IdentityAttributes,
-				Meta:               baseMeta,
-				MergedFrom:         alias,
-			})
-			continue
+		linked = referenceProfile.ProfileId == toProfile.ProfileId
+	}
+	if !linked {
+		err = ps.mergeProfiles(toProfile.ProfileId, fromProfileId, profileModel.Reference{
+			Reason: constants.AliasMergeReason,
+		}, constants.MergeModeFull)
+		if err != nil {
+			return nil, err
 		}
 	}
-
-	return result, hasMore, nil
+	return ps.GetProfileFromPrimary(toProfile.ProfileId, appScope)
 }
 
-// GetAllProfilesWithFilterCursor retrieves filtered master profiles with pagination using cursor.
-// Merged profiles are not included in list but provided in the reference
-func (ps *ProfilesService) GetAllProfilesWithFilterCursor(
-	orgHandle string,
-	filters []string,
-	limit int,
-	cursor *profileModel.ProfileCursor,
-) ([]profileModel.ProfileResponse, bool, error) {
-
-	propertyTypeMap := make(map[string]string)
+// mergeProfiles merges the child profile into the master profile in the given merge mode, recording the given
+// reference on the child.
+func (ps *ProfilesService) mergeProfiles(masterProfileId, childProfileId string, reference profileModel.Reference,
+	mergeMode string) error {
 
-	// Rewrite filters (keep your logic)
-	rewrittenFilters := make([]string, 0, len(filters))
-	for _, f := range filters {
-		parts := strings.SplitN(f, " ", 3)
-		if len(parts) != 3 {
-			return nil, false, errors2.NewClientError(errors2.ErrorMessage{
-				Code:        errors2.FILTER_PROFILE.Code,
-				Message:     errors2.FILTER_PROFILE.Message,
-				Description: "Invalid filter format when filtering profiles.",
-			}, http.StatusBadRequest)
-		}
+	logger := log.GetLogger()
+	invalidMerge := func(description string, status int) error {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.MERGE_PROFILE.Code,
+			Message:     errors2.MERGE_PROFILE.Message,
+			Description: description,
+		}, status)
+	}
+	profileNotFound := func() error {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+	if masterProfileId == childProfileId {
+		return invalidMerge("A profile can not be merged into itself.", http.StatusBadRequest)
+	}
 
-		field, operator, rawValue := parts[0], parts[1], parts[2]
+	masterProfile, err := profileStore.GetProfile(masterProfileId)
+	if err != nil {
+		return err
+	}
+	childProfile, err := profileStore.GetProfile(childProfileId)
+	if err != nil {
+		return err
+	}
+	if masterProfile == nil || childProfile == nil {
+		return profileNotFound()
+	}
+	if masterProfile.OrgHandle != childProfile.OrgHandle {
+		return invalidMerge("Profiles of different organizations can not be merged.", http.StatusBadRequest)
+	}
+	if !childProfile.ProfileStatus.IsReferenceProfile {
+		return invalidMerge(fmt.Sprintf("Profile: %s is already merged to profile: %s. Unmerge it first.",
+			childProfileId, childProfile.ProfileStatus.ReferenceProfileId), http.StatusConflict)
+	}
+	err = profileModel.CheckReferenceLink(masterProfileId, childProfileId, profileStore.LookupReferenceProfileId)
+	if errors.Is(err, profileModel.ErrProfileHierarchyCycle) {
+		return invalidMerge(fmt.Sprintf("Merging profile: %s into profile: %s would create a cycle.",
+			childProfileId, masterProfileId), http.StatusBadRequest)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !masterProfile.ProfileStatus.IsReferenceProfile {
+		return invalidMerge(fmt.Sprintf("Profile: %s is merged to profile: %s. Merge into that profile instead.",
+			masterProfileId, masterProfile.ProfileStatus.ReferenceProfileId), http.StatusBadRequest)
+	}
+	if childProfile.UserId != "" {
+		if masterProfile.UserId == "" {
+			return invalidMerge("A permanent profile can not be merged into a temporary profile.",
+				http.StatusBadRequest)
+		}
+		if masterProfile.UserId != childProfile.UserId {
+			return invalidMerge("Permanent profiles of different users can not be merged.", http.StatusConflict)
+		}
+	}
+
+	childReferences, err := profileStore.FetchReferencedProfiles(childProfileId)
+	if err != nil {
+		return err
+	}
+	schemaRules, err := schemaStore.GetProfileSchemaAttributesForOrg(masterProfile.OrgHandle)
+	if err != nil {
+		return err
+	}
+
+	// The merged data and the references are written in one transaction, at the version the master is read at. The
+	// master is read and merged again when it changes in between, so that concurrent updates to it are kept.
+	reference.ProfileId = childProfileId
+	references := append(childReferences, reference)
+	err = workers.RetryMergeWrite(masterProfileId, func() error {
+		stored, err := profileStore.GetProfile(masterProfileId)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			return profileNotFound()
+		}
+		merged := workers.MergeProfilesByMode(*stored, *childProfile, schemaRules, mergeMode)
+		master := workers.ApplyMergedData(*stored, merged)
+		master.ApplicationData = merged.ApplicationData
+		return profileStore.MergeIntoProfile(master, references)
+	})
+	if err != nil {
+		return err
+	}
+	switch reference.Reason {
+	case constants.ManualMergeReason:
+		metrics.ProfileMerges.Inc("manual")
+	case constants.AliasMergeReason:
+		metrics.ProfileMerges.Inc("alias")
+	default:
+		metrics.ProfileMerges.Inc("unification_rule")
+	}
+	logger.Info(fmt.Sprintf("Merged profile: %s into profile: %s by: %s", childProfileId, masterProfileId,
+		reference.Reason))
+	webhookService.NotifyProfileMerged(masterProfile.OrgHandle, masterProfileId, []string{childProfileId},
+		reference.Reason)
+	changestream.PublishProfileChange(constants.ProfileChangeMerged, masterProfile.OrgHandle, masterProfileId,
+		[]string{childProfileId})
+	return nil
+}
+
+// UnmergeProfile separates a merged profile from its reference profile and makes it a reference profile of its
+// own again. The profile keeps the traits, identity attributes and application data it was created with. A
+// reference profile that was created by unification is dissolved when at most one profile is left referring to it.
+// The unmerge is recorded so that the rule that merged the profiles does not unify them again. The separated profile
+// is returned, restricted to what appScope may read like in GetProfile.
+func (ps *ProfilesService) UnmergeProfile(childProfileId string,
+	appScope profileModel.AppScope) (*profileModel.ProfileResponse, error) {
+
+	logger := log.GetLogger()
+	profile, err := profileStore.GetProfile(childProfileId)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+	if profile.ProfileStatus.IsReferenceProfile || profile.ProfileStatus.ReferenceProfileId == "" {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UNMERGE_PROFILE.Code,
+			Message:     errors2.UNMERGE_PROFILE.Message,
+			Description: fmt.Sprintf("Profile: %s is not merged to another profile.", childProfileId),
+		}, http.StatusConflict)
+	}
+
+	referenceProfileId := profile.ProfileStatus.ReferenceProfileId
+	referenceProfile, err := profileStore.GetProfile(referenceProfileId)
+	if err != nil {
+		return nil, err
+	}
+	references, err := profileStore.FetchReferencedProfiles(referenceProfileId)
+	if err != nil {
+		return nil, err
+	}
+
+	excludedProfileIds := []string{referenceProfileId}
+	for _, reference := range references {
+		if reference.ProfileId != childProfileId {
+			excludedProfileIds = append(excludedProfileIds, reference.ProfileId)
+		}
+	}
+	// Reference profiles created by unification are not listed and only hold the merged data of their children.
+	dissolveReference := referenceProfile != nil && !referenceProfile.ProfileStatus.ListProfile &&
+		len(excludedProfileIds) <= 2
+
+	child := profileModel.Reference{ProfileId: childProfileId, Reason: profile.ProfileStatus.ReferenceReason}
+	err = profileStore.UnmergeProfile(profile.OrgHandle, referenceProfileId, child, excludedProfileIds,
+		dissolveReference, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	logger.Info(fmt.Sprintf("Unmerged profile: %s from reference profile: %s", childProfileId, referenceProfileId))
+	changestream.PublishProfileChange(constants.ProfileChangeUpdated, profile.OrgHandle, childProfileId, nil)
+	if dissolveReference {
+		for _, profileId := range excludedProfileIds[1:] {
+			changestream.PublishProfileChange(constants.ProfileChangeUpdated, profile.OrgHandle, profileId, nil)
+		}
+		changestream.PublishProfileChange(constants.ProfileChangeDeleted, profile.OrgHandle, referenceProfileId, nil)
+	} else {
+		changestream.PublishProfileChange(constants.ProfileChangeUpdated, profile.OrgHandle, referenceProfileId, nil)
+	}
+	return ps.GetProfileFromPrimary(childProfileId, appScope)
+}
+
+// GetProfileHistory returns the traits of each retained version of the profile, oldest first and ending with the
+// current version, along with the traits that changed from the version before. A restricted appScope leaves out the
+// traits the application may not read from every version.
+func (ps *ProfilesService) GetProfileHistory(profileId string,
+	appScope profileModel.AppScope) ([]profileModel.ProfileSnapshot, error) {
+
+	profile, err := profileStore.GetProfile(profileId)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+
+	restricted, err := restrictedTraitPaths(profile.OrgHandle, appScope)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := profileStore.GetProfileTraitHistory(profileId)
+	if err != nil {
+		return nil, err
+	}
+	snapshots = append(snapshots, profileModel.ProfileSnapshot{
+		Version:    profile.Version,
+		Traits:     profile.Traits,
+		RecordedAt: profile.UpdatedAt,
+	})
+	for i := range snapshots {
+		snapshots[i].Traits = redactTraits(snapshots[i].Traits, restricted)
+		if snapshots[i].Traits == nil {
+			snapshots[i].Traits = map[string]interface{}{}
+		}
+		if i == 0 {
+			snapshots[i].Changes = []profileModel.TraitChange{}
+			continue
+		}
+		snapshots[i].Changes = profileModel.DiffTraits(snapshots[i-1].Traits, snapshots[i].Traits)
+	}
+	return snapshots, nil
+}
+
+// PruneProfileHistory removes the trait history recorded more than maxAge ago and keeps at most maxSnapshots
+// earlier versions per profile. A zero maxAge or maxSnapshots does not limit the history by it.
+func (ps *ProfilesService) PruneProfileHistory(maxAge time.Duration, maxSnapshots int) (int64, error) {
+
+	var recordedBefore time.Time
+	if maxAge > 0 {
+		recordedBefore = time.Now().UTC().Add(-maxAge)
+	}
+	pruned, err := profileStore.PruneProfileTraitHistory(recordedBefore, maxSnapshots)
+	if err != nil {
+		return 0, err
+	}
+	if pruned > 0 {
+		log.GetLogger().Info(fmt.Sprintf("Pruned %d entries of the profile history", pruned))
+	}
+	return pruned, nil
+}
+
+// PurgeExpiredIdempotencyKeys removes the idempotency keys that can no longer be replayed, along with the claims
+// abandoned by profile creations that never completed.
+func (ps *ProfilesService) PurgeExpiredIdempotencyKeys() (int64, error) {
+
+	purged, err := profileStore.PurgeExpiredIdempotencyKeys(time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	if purged > 0 {
+		log.GetLogger().Info(fmt.Sprintf("Purged %d expired idempotency keys", purged))
+	}
+	return purged, nil
+}
+
+// GetChildProfiles lists the profiles merged to the master profile. When the given profile is itself merged, the
+// profiles merged to its master are listed.
+func (ps *ProfilesService) GetChildProfiles(masterProfileId string) ([]profileModel.ChildProfile, error) {
+
+	profile, err := profileStore.GetProfile(masterProfileId)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+	if !profile.ProfileStatus.IsReferenceProfile {
+		if profile, err = getReferenceProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+	return profileStore.FetchChildProfiles(profile.ProfileId)
+}
+
+// GetProfileLineage returns the hierarchy the given profile belongs to: its reference profile and the profiles
+// merged to it, each with the unification rule that linked it and the value of the rule property that matched.
+func (ps *ProfilesService) GetProfileLineage(profileId string) (*profileModel.ProfileLineage, error) {
+
+	profile, err := profileStore.GetProfile(profileId)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+
+	referenceProfile := profile
+	if !profile.ProfileStatus.IsReferenceProfile {
+		referenceProfile, err = getReferenceProfile(profile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	references, err := profileStore.FetchReferencedProfiles(referenceProfile.ProfileId)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := unificationStore.GetUnificationRules(referenceProfile.OrgHandle)
+	if err != nil {
+		return nil, err
+	}
+	rulesById := make(map[string]UnificationModel.UnificationRule, len(rules))
+	rulesByName := make(map[string]UnificationModel.UnificationRule, len(rules))
+	for _, rule := range rules {
+		rulesById[rule.RuleId] = rule
+		rulesByName[rule.RuleName] = rule
+	}
+
+	lineage := &profileModel.ProfileLineage{
+		ProfileId: profileId,
+		ReferenceProfile: profileModel.LineageProfile{
+			ProfileId: referenceProfile.ProfileId,
+			UserId:    referenceProfile.UserId,
+		},
+		MergedProfiles: make([]profileModel.LineageLink, 0, len(references)),
+	}
+	for _, reference := range references {
+		link := profileModel.LineageLink{
+			LineageProfile: profileModel.LineageProfile{ProfileId: reference.ProfileId},
+			Reason:         reference.Reason,
+			RuleId:         reference.RuleId,
+		}
+		// Links recorded before rule ids were stored are resolved by the rule name.
+		rule, found := rulesById[reference.RuleId]
+		if !found && reference.RuleId == "" {
+			rule, found = rulesByName[reference.Reason]
+		}
+
+		child, err := profileStore.GetProfile(reference.ProfileId)
+		if err != nil {
+			return nil, err
+		}
+		if child != nil {
+			link.UserId = child.UserId
+			if found {
+				link.RuleId = rule.RuleId
+				link.Property = rule.PropertyName
+				if reference.MatchedValue != "" {
+					link.MatchedValue = reference.MatchedValue
+				} else {
+					// Links recorded before matched values were stored fall back to the child's current value.
+					if strings.HasPrefix(rule.PropertyName, constants.ApplicationData+".") {
+						child.ApplicationData, err = profileStore.FetchApplicationData(child.ProfileId)
+						if err != nil {
+							return nil, err
+						}
+					}
+					link.MatchedValue = child.PropertyValue(rule.PropertyName)
+				}
+			}
+		}
+		lineage.MergedProfiles = append(lineage.MergedProfiles, link)
+	}
+	return lineage, nil
+}
+
+// IncrementAttribute atomically adds delta (negative to decrement) to a numeric trait or identity attribute and
+// returns the new value. For a merged profile the attribute of its reference profile is incremented. The computed
+// traits that depend on an incremented trait are evaluated again in the same transaction.
+func (ps *ProfilesService) IncrementAttribute(profileId, path string, delta float64) (float64, error) {
+
+	profile, err := profileStore.GetProfile(profileId)
+	if err != nil {
+		return 0, err
+	}
+	if profile == nil {
+		return 0, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+
+	invalidAttribute := func(description string) error {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: description,
+		}, http.StatusBadRequest)
+	}
+	scopeKey := strings.SplitN(path, ".", 2)
+	if len(scopeKey) != 2 || (scopeKey[0] != constants.Traits && scopeKey[0] != constants.IdentityAttributes) {
+		return 0, invalidAttribute(fmt.Sprintf("Attribute '%s' can not be incremented. Only traits and "+
+			"identity attributes are supported.", path))
+	}
+	attribute, err := schemaService.GetProfileSchemaService().GetProfileSchemaAttributeByName(path, profile.OrgHandle)
+	if err != nil {
+		return 0, err
+	}
+	if attribute == nil {
+		return 0, invalidAttribute(fmt.Sprintf("Attribute '%s' is not defined in the profile schema.", path))
+	}
+	if attribute.MultiValued ||
+		(attribute.ValueType != constants.IntegerDataType && attribute.ValueType != constants.DecimalDataType) {
+		return 0, invalidAttribute(fmt.Sprintf("Attribute '%s' is not a single valued numeric attribute.", path))
+	}
+	if attribute.Mutability != "" && attribute.Mutability != constants.MutabilityReadWrite &&
+		attribute.Mutability != constants.MutabilityWriteOnly {
+		return 0, invalidAttribute(fmt.Sprintf("Attribute '%s' is %s and can not be incremented.", path,
+			attribute.Mutability))
+	}
+	if attribute.ValueType == constants.IntegerDataType && delta != math.Trunc(delta) {
+		return 0, invalidAttribute(fmt.Sprintf("Attribute '%s' is an integer and can only be incremented by a "+
+			"whole number.", path))
+	}
+
+	var traitSchema []model.ProfileSchemaAttribute
+	if scopeKey[0] == constants.Traits {
+		schemaAttributes, err := schemaStore.GetProfileSchemaAttributesForOrg(profile.OrgHandle)
+		if err != nil {
+			return 0, err
+		}
+		for _, attr := range schemaAttributes {
+			if strings.HasPrefix(attr.AttributeName, constants.Traits+".") {
+				traitSchema = append(traitSchema, attr)
+			}
+		}
+	}
+	recompute := computedTraitDependingOn(traitSchema, path) != ""
+
+	if !profile.ProfileStatus.IsReferenceProfile {
+		if profile, err = getReferenceProfile(profile); err != nil {
+			return 0, err
+		}
+	}
+	targetProfileId := profile.ProfileId
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for incrementing attribute of profile: %s", targetProfileId)
+		log.GetLogger().Debug(errorMsg, log.Error(err))
+		return 0, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	var value *float64
+	err = dbClient.RunInTx(func(tx *sql.Tx) error {
+		value, err = profileStore.IncrementProfileAttributeInTx(tx, targetProfileId, scopeKey[0],
+			strings.Split(scopeKey[1], "."), delta)
+		if err != nil || value == nil || !recompute {
+			return err
+		}
+		incremented, err := profileStore.LockProfile(tx, targetProfileId)
+		if err != nil || incremented == nil {
+			return err
+		}
+		return profileStore.SetRecomputedTraitsInTx(tx, targetProfileId,
+			applyComputedTraits(incremented.Traits, traitSchema))
+	})
+	if err != nil {
+		switch err.(type) {
+		case *errors2.ServerError, *errors2.ClientError:
+			return 0, err
+		}
+		errorMsg := fmt.Sprintf("Failed to commit the increment of an attribute of profile: %s", targetProfileId)
+		log.GetLogger().Debug(errorMsg, log.Error(err))
+		return 0, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if value == nil {
+		return 0, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+	changestream.PublishProfileChange(constants.ProfileChangeUpdated, profile.OrgHandle, targetProfileId, nil)
+	return *value, nil
+}
+
+// PatchApplicationData applies a JSON merge patch (RFC 7386) to the data the application keeps on the profile, so
+// that applications can update the keys they own without replacing the rest. Keys set to null are removed. For a
+// merged profile the data of its reference profile is patched.
+func (ps *ProfilesService) PatchApplicationData(profileId, appId string, patch map[string]interface{}) error {
+
+	if appId == "" {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_APP_DATA.Code,
+			Message:     errors2.UPDATE_APP_DATA.Message,
+			Description: "Application id is required to patch application data.",
+		}, http.StatusBadRequest)
+	}
+	profile, err := profileStore.GetProfile(profileId)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+
+	rawSchema, err := schemaService.GetProfileSchemaService().GetProfileSchema(profile.OrgHandle)
+	if err != nil {
+		return err
+	}
+	var schema model.ProfileSchema
+	schemaBytes, _ := json.Marshal(rawSchema)
+	if err := json.Unmarshal(schemaBytes, &schema); err != nil {
+		errMsg := fmt.Sprintf("Invalid schema format for profile: %s while validating application data patch.",
+			profileId)
+		log.GetLogger().Debug(errMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_APP_DATA.Code,
+			Message:     errors2.UPDATE_APP_DATA.Message,
+			Description: errMsg,
+		}, err)
+	}
+
+	// Only the keys named by the patch are validated, with their merged values, so that data written before an
+	// attribute was added to the schema doesn't block patches of other keys.
+	validate := func(stored, patched map[string]interface{}) error {
+		changed := make(map[string]interface{}, len(patch))
+		for key := range patch {
+			if val, kept := patched[key]; kept {
+				changed[key] = val
+				continue
+			}
+			if _, had := stored[key]; !had {
+				continue
+			}
+			attr, found := findAppAttributeInSchema(schema.ApplicationData, appId, constants.ApplicationData+"."+key)
+			if !found {
+				continue
+			}
+			if err := validateMutability(attr.Mutability, true, stored[key], nil); err != nil {
+				return err
+			}
+		}
+		request := profileModel.ProfileRequest{ApplicationData: map[string]map[string]interface{}{appId: changed}}
+		existing := profileModel.Profile{
+			ApplicationData: []profileModel.ApplicationData{{AppId: appId, AppSpecificData: stored}},
+		}
+		normalizeProfileRequest(&request)
+		if err := ValidateProfileAgainstSchema(request, existing, schema, true); err != nil {
+			return err
+		}
+		for key, val := range changed {
+			patched[key] = val
+		}
+		return nil
+	}
+
+	targetProfileId := profile.ProfileId
+	if !profile.ProfileStatus.IsReferenceProfile && profile.ProfileStatus.ReferenceProfileId != "" {
+		targetProfileId = profile.ProfileStatus.ReferenceProfileId
+	}
+	if err := profileStore.PatchApplicationData(targetProfileId, appId, patch, validate); err != nil {
+		return err
+	}
+	changestream.PublishProfileChange(constants.ProfileChangeUpdated, profile.OrgHandle, targetProfileId, nil)
+	return nil
+}
+
+// RestoreProfile reverts the soft-deletion of a profile. Profiles unified with it that were deleted in the same
+// operation are restored as well.
+func (ps *ProfilesService) RestoreProfile(profileId string) error {
+
+	logger := log.GetLogger()
+	profile, err := profileStore.GetDeletedProfile(profileId)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		existingProfile, err := profileStore.GetProfile(profileId)
+		if err != nil {
+			return err
+		}
+		if existingProfile != nil {
+			return errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.RESTORE_PROFILE.Code,
+				Message:     errors2.RESTORE_PROFILE.Message,
+				Description: fmt.Sprintf("Profile: %s is not deleted", profileId),
+			}, http.StatusConflict)
+		}
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: errors2.PROFILE_NOT_FOUND.Description,
+		}, http.StatusNotFound)
+	}
+
+	referenceProfileId := profileId
+	if !profile.ProfileStatus.IsReferenceProfile && profile.ProfileStatus.ReferenceProfileId != "" {
+		referenceProfileId = profile.ProfileStatus.ReferenceProfileId
+	}
+	restored, err := profileStore.RestoreProfiles(referenceProfileId, *profile.DeletedAt)
+	if err != nil {
+		return err
+	}
+	for _, restoredProfileId := range restored {
+		changestream.PublishProfileChange(constants.ProfileChangeUpdated, profile.OrgHandle, restoredProfileId, nil)
+	}
+	logger.Info(fmt.Sprintf("Restored profile: %s along with %d unified profile(s)", profileId, len(restored)-1))
+	return nil
+}
+
+// PurgeDeletedProfiles permanently removes the profiles that were soft-deleted more than olderThan ago.
+// Intended to be run periodically with constants.DeletedProfileRetentionPeriod.
+func (ps *ProfilesService) PurgeDeletedProfiles(olderThan time.Duration) (int64, error) {
+
+	purged, err := profileStore.PurgeDeletedProfiles(time.Now().UTC().Add(-olderThan))
+	if err != nil {
+		return 0, err
+	}
+	log.GetLogger().Info(fmt.Sprintf("Purged %d profile(s) deleted more than %s ago", purged, olderThan))
+	return purged, nil
+}
+
+// GetAllProfilesCursor retrieves all master profiles with pagination using cursor.
+// Merged profiles are not included in list but provided in the reference.
+// Soft-deleted profiles are only included when includeDeleted is set. A restricted appScope limits the application
+// data to that application and leaves out the traits it may not read.
+func (ps *ProfilesService) GetAllProfilesCursor(
+	orgHandle string,
+	includeDeleted bool,
+	limit int,
+	cursor *profileModel.ProfileCursor,
+	appScope profileModel.AppScope,
+) ([]profileModel.ProfileResponse, bool, error) {
+
+	restricted, err := restrictedTraitPaths(orgHandle, appScope)
+	if err != nil {
+		return nil, false, err
+	}
+	existingProfiles, hasMore, err := profileStore.GetAllProfiles(orgHandle, includeDeleted, limit, cursor)
+	if err != nil {
+		return nil, false, err
+	}
+	if existingProfiles == nil {
+		return []profileModel.ProfileResponse{}, false, nil
+	}
+
+	if len(existingProfiles) > limit {
+		hasMore = true
+		existingProfiles = existingProfiles[:limit]
+	}
+
+	result, err := resolveListedProfiles(orgHandle, existingProfiles, appScope, restricted)
+	if err != nil {
+		return nil, false, err
+	}
+	return result, hasMore, nil
+}
+
+// DeleteProfilesByFilter soft-deletes the profiles matching the filters together with the profiles they are
+// unified with, and returns the number of profiles deleted. At least one filter is required.
+func (ps *ProfilesService) DeleteProfilesByFilter(orgHandle string, filters []string) (int64, error) {
+
+	if len(filters) == 0 {
+		return 0, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.FILTER_PROFILE.Code,
+			Message:     errors2.FILTER_PROFILE.Message,
+			Description: "At least one filter is required to delete profiles.",
+		}, http.StatusBadRequest)
+	}
+	rewrittenFilters, err := rewriteProfileFilters(orgHandle, filters)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := profileStore.SoftDeleteProfilesByFilter(orgHandle, rewrittenFilters, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	for _, profileId := range deleted {
+		changestream.PublishProfileChange(constants.ProfileChangeDeleted, orgHandle, profileId, nil)
+	}
+	return int64(len(deleted)), nil
+}
+
+// PatchProfilesByFilter sets the given top-level traits on every reference profile matching the filters in a single
+// update, and returns the number of profiles changed. At least one filter is required. The traits are validated
+// against the profile schema once for all profiles, so only read-write and write-only traits that no computed trait
+// depends on can be patched; the others need the existing values of each profile and are updated per profile. Like
+// single updates, every patched profile is published as updated and queued for unification.
+func (ps *ProfilesService) PatchProfilesByFilter(orgHandle string, filters []string,
+	traits map[string]interface{}) (int64, error) {
+
+	invalidPatch := func(description string) error {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: description,
+		}, http.StatusBadRequest)
+	}
+	if len(filters) == 0 {
+		return 0, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.FILTER_PROFILE.Code,
+			Message:     errors2.FILTER_PROFILE.Message,
+			Description: "At least one filter is required to patch profiles.",
+		}, http.StatusBadRequest)
+	}
+	if len(traits) == 0 {
+		return 0, invalidPatch("At least one trait is required to patch profiles.")
+	}
+
+	schemaAttributes, err := schemaStore.GetProfileSchemaAttributesForOrg(orgHandle)
+	if err != nil {
+		return 0, err
+	}
+	var schema model.ProfileSchema
+	for _, attr := range schemaAttributes {
+		if strings.HasPrefix(attr.AttributeName, constants.Traits+".") {
+			schema.Traits = append(schema.Traits, attr)
+		}
+	}
+	request := profileModel.ProfileRequest{Traits: traits}
+	normalizeProfileRequest(&request)
+	if err := ValidateProfileAgainstSchema(request, profileModel.Profile{}, schema, false); err != nil {
+		return 0, err
+	}
+	for key := range traits {
+		attrName := constants.Traits + "." + key
+		if attr, found := findAttributeInSchema(schema.Traits, attrName); found &&
+			attr.Mutability != constants.MutabilityReadWrite && attr.Mutability != constants.MutabilityWriteOnly {
+			return 0, invalidPatch(fmt.Sprintf("Trait '%s' is %s and can only be updated per profile.", key,
+				attr.Mutability))
+		}
+		if computed := computedTraitDependingOn(schema.Traits, attrName); computed != "" {
+			return 0, invalidPatch(fmt.Sprintf("Trait '%s' is used by the computed trait '%s' and can only be "+
+				"updated per profile.", key, computed))
+		}
+	}
+
+	rewrittenFilters, err := rewriteProfileFilters(orgHandle, filters)
+	if err != nil {
+		return 0, err
+	}
+	patched, err := profileStore.PatchProfileTraitsByFilter(orgHandle, rewrittenFilters, request.Traits,
+		time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	// The worker loads each profile again before unifying it, so the ids are enough to enqueue.
+	enqueue := UnificationModel.DefaultConfig().ProfileUnificationTrigger.TriggerType == constants.SyncProfileOnUpdate
+	queue := &workers.ProfileWorkerQueue{}
+	for _, profileId := range patched {
+		changestream.PublishProfileChange(constants.ProfileChangeUpdated, orgHandle, profileId, nil)
+		if enqueue {
+			queue.Enqueue(profileModel.Profile{ProfileId: profileId, OrgHandle: orgHandle})
+		}
+	}
+	return int64(len(patched)), nil
+}
+
+// GetHierarchyStats reports how the profiles of the organization are unified, for monitoring the unification rules.
+// A sudden growth of the largest cluster usually means that a rule matches more profiles than intended.
+func (ps *ProfilesService) GetHierarchyStats(orgHandle string) (*profileModel.HierarchyStats, error) {
+
+	return profileStore.GetHierarchyStats(orgHandle)
+}
+
+// CountProfilesGroupedBy counts the profiles matching the filters by the value of the trait, given with or without
+// the "traits." prefix. The trait must be a single valued attribute of a simple type in the profile schema. A
+// restricted appScope may neither group by nor filter on the traits the application may not read.
+func (ps *ProfilesService) CountProfilesGroupedBy(orgHandle, trait string, filters []string,
+	appScope profileModel.AppScope) (map[string]int64, error) {
+
+	invalidGroupBy := func(description string) error {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.INVALID_GROUP_BY_PARAMETER.Code,
+			Message:     errors2.INVALID_GROUP_BY_PARAMETER.Message,
+			Description: description,
+		}, http.StatusBadRequest)
+	}
+
+	traitPath, err := resolveSimpleTraitPath(orgHandle, trait, invalidGroupBy)
+	if err != nil {
+		return nil, err
+	}
+	restricted, err := restrictedTraitPaths(orgHandle, appScope)
+	if err != nil {
+		return nil, err
+	}
+	groupBy := constants.Traits + "." + strings.Join(traitPath, ".")
+	if err := checkTraitFilterAccess(append([]string{groupBy}, filters...), restricted); err != nil {
+		return nil, err
+	}
+	rewrittenFilters, err := rewriteProfileFilters(orgHandle, filters)
+	if err != nil {
+		return nil, err
+	}
+	return profileStore.CountProfilesGroupedBy(orgHandle, traitPath, rewrittenFilters)
+}
+
+// GetDistinctTraitValues lists the distinct values the trait, given with or without the "traits." prefix, has among
+// the profiles of the organization, at most limit of them and never more than constants.MaxDistinctTraitValues.
+// The values are ordered by the number of profiles having them when byFrequency is set and lexically otherwise. The
+// trait must be a single valued attribute of a simple type in the profile schema. A restricted appScope may not list
+// the values of the traits the application may not read.
+func (ps *ProfilesService) GetDistinctTraitValues(orgHandle, trait string, limit int, byFrequency bool,
+	appScope profileModel.AppScope) ([]string, error) {
+
+	invalidRequest := func(description string) error {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.INVALID_DISTINCT_VALUES_REQUEST.Code,
+			Message:     errors2.INVALID_DISTINCT_VALUES_REQUEST.Message,
+			Description: description,
+		}, http.StatusBadRequest)
+	}
+
+	if limit <= 0 {
+		return nil, invalidRequest(fmt.Sprintf("The limit must be a positive number, got: %d", limit))
+	}
+	limit = min(limit, constants.MaxDistinctTraitValues)
+	traitPath, err := resolveSimpleTraitPath(orgHandle, trait, invalidRequest)
+	if err != nil {
+		return nil, err
+	}
+	restricted, err := restrictedTraitPaths(orgHandle, appScope)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTraitFilterAccess([]string{constants.Traits + "." + strings.Join(traitPath, ".")},
+		restricted); err != nil {
+		return nil, err
+	}
+	return profileStore.GetDistinctTraitValues(orgHandle, traitPath, limit, byFrequency)
+}
+
+// resolveSimpleTraitPath checks that the trait, given with or without the "traits." prefix, is a single valued
+// attribute of a simple type in the profile schema of the organization and returns its path within the traits.
+// Violations are reported through invalid.
+func resolveSimpleTraitPath(orgHandle, trait string, invalid func(description string) error) ([]string, error) {
+
+	field := trait
+	if !strings.HasPrefix(field, constants.Traits+".") {
+		field = constants.Traits + "." + field
+	}
+	if trait == "" || !isValidFilterKey(field) {
+		return nil, invalid(fmt.Sprintf("Invalid trait: %s", trait))
+	}
+	attribute, err := schemaService.GetProfileSchemaService().GetProfileSchemaAttributeByName(field, orgHandle)
+	if err != nil {
+		return nil, err
+	}
+	if attribute == nil {
+		return nil, invalid(fmt.Sprintf("Trait: %s is not defined in the profile schema", field))
+	}
+	if attribute.ValueType == constants.ComplexDataType || attribute.MultiValued {
+		return nil, invalid(fmt.Sprintf("Trait: %s must be a single valued attribute of a simple type", field))
+	}
+	return strings.Split(strings.TrimPrefix(field, constants.Traits+"."), "."), nil
+}
+
+// rewriteProfileFilters validates the "field operator value" filters and normalizes their values for the store.
+// An eq filter on an attribute declared case-insensitive in the profile schema of the organization becomes eqi, and
+// the bounds of gte, lte and between filters are converted to the type of the attribute they compare. The exists and
+// notexists filters take no value, as in "traits.phone exists". The contains filter matches the profiles whose multi
+// valued attribute holds the value as one of its elements, as in "traits.tags contains vip", and is typed by the
+// element type of the attribute.
+// All invalid filters are reported together, each identified by its position in the filter list, and the
+// description quotes the text of every invalid filter. A filter on a property outside the profile is invalid rather
+// than ignored, so that a mistyped filter can not silently widen the result.
+func rewriteProfileFilters(orgHandle string, filters []string) ([]string, error) {
+
+	rewrittenFilters := make([]string, 0, len(filters))
+	var invalid []errors2.FieldError
+	clauseText := make(map[string]string, len(filters))
+	for i, f := range filters {
+		clause := fmt.Sprintf("filter[%d]", i)
+		clauseText[clause] = f
+		parts := strings.SplitN(f, " ", 3)
+		if len(parts) == 2 && (parts[1] == "exists" || parts[1] == "notexists") {
+			field := parts[0]
+			if field != "user_id" && (!isValidFilterKey(field) || (!strings.HasPrefix(field, constants.Traits+".") &&
+				!strings.HasPrefix(field, constants.IdentityAttributes+"."))) {
+				invalid = append(invalid, errors2.FieldError{Field: clause,
+					Message: fmt.Sprintf("The %s operator is not supported on %s", parts[1], field)})
+				continue
+			}
+			rewrittenFilters = append(rewrittenFilters, field+" "+parts[1])
+			continue
+		}
+		if len(parts) != 3 {
+			invalid = append(invalid, errors2.FieldError{Field: clause,
+				Message: "Invalid filter format when filtering profiles."})
+			continue
+		}
+
+		field, operator, rawValue := parts[0], parts[1], parts[2]
 
 		// Validate operator
 		switch operator {
-		case "eq", "co", "sw":
+		case "eq", "eqi", "nei", "co", "sw", "contains", "gte", "lte", "between":
+		case "exists", "notexists":
+			invalid = append(invalid, errors2.FieldError{Field: clause,
+				Message: fmt.Sprintf("The %s operator takes no value", operator)})
+			continue
 		default:
-			return nil, false, errors2.NewClientError(errors2.ErrorMessage{
-				Code:        errors2.FILTER_PROFILE.Code,
-				Message:     errors2.FILTER_PROFILE.Message,
-				Description: fmt.Sprintf("Unsupported operator: %s", operator),
-			}, http.StatusBadRequest)
+			invalid = append(invalid, errors2.FieldError{Field: clause,
+				Message: fmt.Sprintf("Unsupported operator: %s", operator)})
+			continue
 		}
 
 		// Validate field/key
-		if field != "user_id" && field != "profile_id" {
+		if field != "user_id" && field != "profile_id" && field != "created_at" && field != "updated_at" {
 			if !isValidFilterKey(field) {
-				return nil, false, errors2.NewClientError(errors2.ErrorMessage{
-					Code:        errors2.FILTER_PROFILE.Code,
-					Message:     errors2.FILTER_PROFILE.Message,
-					Description: "Invalid filter key: " + field,
-				}, http.StatusBadRequest)
+				invalid = append(invalid, errors2.FieldError{Field: clause, Message: "Invalid filter key: " + field})
+				continue
+			}
+			if !isProfileFilterField(field) {
+				invalid = append(invalid, errors2.FieldError{Field: clause, Message: "Unknown filter property: " + field})
+				continue
+			}
+		}
+		if operator == "gte" || operator == "lte" || operator == "between" {
+			bounds, problem, err := rangeFilterBounds(orgHandle, field, operator, rawValue)
+			if err != nil {
+				return nil, err
+			}
+			if problem != "" {
+				invalid = append(invalid, errors2.FieldError{Field: clause, Message: problem})
+				continue
+			}
+			rewrittenFilters = append(rewrittenFilters, fmt.Sprintf("%s %s %s", field, operator, bounds))
+			continue
+		}
+		if field == "created_at" || field == "updated_at" {
+			invalid = append(invalid, errors2.FieldError{Field: clause,
+				Message: fmt.Sprintf("Only gte, lte and between are supported on %s", field)})
+			continue
+		}
+		var attribute *model.ProfileSchemaAttribute
+		if strings.HasPrefix(field, constants.Traits+".") || strings.HasPrefix(field, constants.IdentityAttributes+".") {
+			var err error
+			attribute, err = schemaService.GetProfileSchemaService().GetProfileSchemaAttributeByName(field, orgHandle)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if operator == "contains" && (attribute == nil || !attribute.MultiValued ||
+			attribute.ValueType == constants.ComplexDataType) {
+			invalid = append(invalid, errors2.FieldError{Field: clause,
+				Message: fmt.Sprintf("The contains operator requires a multi valued attribute of a simple type: %s",
+					field)})
+			continue
+		}
+		// The text of a multi valued attribute is its whole array, so eq keeps matching any of its elements.
+		if operator == "eq" && attribute != nil && attribute.CaseInsensitive && !attribute.MultiValued {
+			operator = "eqi"
+		}
+
+		// Normalize the same way as stored values so that visually identical values match.
+		value := utils.NormalizeString(rawValue)
+		if (operator == "eq" && field != "user_id" && field != "profile_id") || operator == "contains" {
+			literal, ok := filterValueLiteral(attribute, value)
+			if !ok {
+				invalid = append(invalid, errors2.FieldError{Field: clause,
+					Message: fmt.Sprintf("Invalid %s value for %s: %s", attribute.ValueType, field, rawValue)})
+				continue
 			}
+			value = literal
+		}
+		rewrittenFilters = append(rewrittenFilters, fmt.Sprintf("%s %s %s", field, operator, value))
+	}
+
+	if len(invalid) > 0 {
+		messages := make([]string, 0, len(invalid))
+		for _, fieldErr := range invalid {
+			messages = append(messages, fmt.Sprintf("%s (filter: %q)", fieldErr.Message, clauseText[fieldErr.Field]))
 		}
+		return nil, errors2.NewClientErrorWithDetails(errors2.ErrorMessage{
+			Code:        errors2.FILTER_PROFILE.Code,
+			Message:     errors2.FILTER_PROFILE.Message,
+			Description: strings.Join(messages, "; "),
+		}, http.StatusBadRequest, invalid)
+	}
+	return rewrittenFilters, nil
+}
 
-		valueType := propertyTypeMap[field]
-		typedVal := parseTypedValueForFilters(valueType, rawValue)
+// GetProfilesModifiedSince lists the master profiles updated at or after the given time, least recently updated
+// first, so that a sync job can pull the changes since its last run page by page. Merging profiles, unmerging them
+// and writing their application data all mark the profiles updated. The filters of the query narrow the listing
+// further, while its sort is replaced by the update time.
+func (ps *ProfilesService) GetProfilesModifiedSince(orgHandle string, since time.Time,
+	query profileModel.ProfileQuery) ([]profileModel.ProfileResponse, bool, error) {
 
-		var valueStr string
-		switch v := typedVal.(type) {
-		case string:
-			valueStr = v
-		default:
-			valueStr = fmt.Sprintf("%v", v)
+	query.Filters = append(slices.Clone(query.Filters), "updated_at gte "+since.UTC().Format(time.RFC3339Nano))
+	query.Sort = &profileModel.ProfileSort{Field: "updated_at"}
+	return ps.GetAllProfilesWithFilterCursor(orgHandle, query)
+}
+
+// GetAllProfilesWithFilterCursor retrieves filtered master profiles with pagination using cursor.
+// Merged profiles are not included in list but provided in the reference.
+// When the query has a sort, profiles are ordered by the given field instead of the creation time.
+// A restricted app scope limits the application data to that application and leaves out the traits it may not read,
+// which it may not filter on either.
+func (ps *ProfilesService) GetAllProfilesWithFilterCursor(orgHandle string,
+	query profileModel.ProfileQuery) ([]profileModel.ProfileResponse, bool, error) {
+
+	if query.Sort != nil {
+		if err := resolveProfileSort(orgHandle, query.Sort); err != nil {
+			return nil, false, err
 		}
+		if query.Cursor != nil && !query.Cursor.Sorted {
+			return nil, false, errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.INVALID_SORT_PARAMETER.Code,
+				Message:     errors2.INVALID_SORT_PARAMETER.Message,
+				Description: "The cursor was not issued for a sorted listing. Restart pagination with the sort parameter.",
+			}, http.StatusBadRequest)
+		}
+	}
 
-		rewrittenFilters = append(rewrittenFilters, fmt.Sprintf("%s %s %s", field, operator, valueStr))
+	restricted, err := restrictedTraitPaths(orgHandle, query.AppScope)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := checkTraitFilterAccess(query.Filters, restricted); err != nil {
+		return nil, false, err
+	}
+	rewrittenFilters, err := rewriteProfileFilters(orgHandle, query.Filters)
+	if err != nil {
+		return nil, false, err
 	}
 
 	// Fetch matching profiles WITH cursor + limit
-	filteredProfiles, hasMore, err := profileStore.GetAllProfilesWithFilter(orgHandle, rewrittenFilters, limit, cursor)
+	query.Filters = rewrittenFilters
+	filteredProfiles, hasMore, err := profileStore.GetAllProfilesWithFilter(orgHandle, query)
 	if err != nil {
 		return nil, false, err
 	}
@@ -1097,41 +2905,83 @@ func (ps *ProfilesService) GetAllProfilesWithFilterCursor(
 	}
 
 	// Optional safety if store forgot trimming
-	if len(filteredProfiles) > limit {
+	if len(filteredProfiles) > query.Limit {
 		hasMore = true
-		filteredProfiles = filteredProfiles[:limit]
+		filteredProfiles = filteredProfiles[:query.Limit]
 	}
 
-	result := make([]profileModel.ProfileResponse, 0, len(filteredProfiles))
+	result, err := resolveListedProfiles(orgHandle, filteredProfiles, query.AppScope, restricted)
+	if err != nil {
+		return nil, false, err
+	}
+	return result, hasMore, nil
+}
 
-	for _, profile := range filteredProfiles {
+// StreamProfiles hands the master profiles matching the filters to handle one at a time, newest first. The profiles
+// are read and resolved a chunk at a time, so that listing every profile of a large organization does not hold them
+// all in memory. Soft-deleted profiles are left out. A restricted appScope restricts the application data and traits
+// like in GetAllProfilesWithFilterCursor. The filters are validated before the first profile is read; an error
+// returned by handle stops the stream and is returned.
+func (ps *ProfilesService) StreamProfiles(ctx context.Context, orgHandle string, filters []string,
+	appScope profileModel.AppScope, handle func(profile profileModel.ProfileResponse) error) error {
 
-		// meta data must be preserved for cursor correctness
-		baseMeta := profileModel.Meta{
-			CreatedAt: profile.CreatedAt,
-			UpdatedAt: profile.UpdatedAt,
-			Location:  profile.Location,
+	restricted, err := restrictedTraitPaths(orgHandle, appScope)
+	if err != nil {
+		return err
+	}
+	if err := checkTraitFilterAccess(filters, restricted); err != nil {
+		return err
+	}
+	rewrittenFilters, err := rewriteProfileFilters(orgHandle, filters)
+	if err != nil {
+		return err
+	}
+	return streamProfileChunks(ctx, orgHandle, rewrittenFilters, func(profiles []profileModel.Profile) error {
+		traits, aliases, err := resolveUnifiedTraits(orgHandle, profiles)
+		if err != nil {
+			return err
 		}
-
-		alias, err := profileStore.FetchReferencedProfiles(profile.ProfileId)
+		profileIds := make([]string, 0, len(profiles))
+		for _, profile := range profiles {
+			profileIds = append(profileIds, profile.ProfileId)
+		}
+		appData, err := profileStore.FetchApplicationDataBatch(profileIds)
 		if err != nil {
-			return nil, false, err
+			return err
 		}
-		if profile.ProfileStatus.IsReferenceProfile {
-			result = append(result, profileModel.ProfileResponse{
+		for _, profile := range profiles {
+			err := handle(profileModel.ProfileResponse{
 				ProfileId:          profile.ProfileId,
+				CanonicalProfileId: profile.ProfileId,
 				UserId:             profile.UserId,
-				ApplicationData:    ConvertAppDataToMap(profile.ApplicationData),
-				Traits:             profile.Traits,
+				ApplicationData:    ConvertAppDataToMap(restrictApplicationData(appData[profile.ProfileId], appScope)),
+				Traits:             redactTraits(traits[profile.ProfileId], restricted),
 				IdentityAttributes: profile.IdentityAttributes,
-				Meta:               baseMeta,
-				MergedFrom:         alias,
+				Meta: profileModel.Meta{
+					CreatedAt: profile.CreatedAt,
+					UpdatedAt: profile.UpdatedAt,
+					Location:  profile.Location,
+				},
+				MergedFrom: aliases[profile.ProfileId],
 			})
-			continue
+			if err != nil {
+				return err
+			}
 		}
-	}
+		return nil
+	})
+}
 
-	return result, hasMore, nil
+// isProfileFilterField tells whether a filter field names an attribute within the identity attributes, traits or
+// application data of a profile.
+func isProfileFilterField(field string) bool {
+
+	for _, scope := range []string{constants.IdentityAttributes, constants.Traits, constants.ApplicationData} {
+		if key, found := strings.CutPrefix(field, scope+"."); found && key != "" {
+			return true
+		}
+	}
+	return false
 }
 
 // isValidFilterKey ensures the filter key is valid and does not contain any malicious patterns.
@@ -1154,21 +3004,157 @@ func isValidFilterKey(key string) bool {
 	return true
 }
 
-func parseTypedValueForFilters(valueType string, raw string) interface{} {
-	switch valueType {
-	case "int":
-		i, _ := strconv.Atoi(raw)
-		return i
-	case "float", "double":
-		f, _ := strconv.ParseFloat(raw, 64)
-		return f
-	case "boolean":
-		return raw == "true"
-	case "string":
-		return raw
-	default:
-		return raw
+// resolveProfileSort validates the sort field against the core profile fields and the profile schema of the
+// organization and resolves the value type used to order the values.
+func resolveProfileSort(orgHandle string, sort *profileModel.ProfileSort) error {
+
+	invalidSort := func(description string) error {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.INVALID_SORT_PARAMETER.Code,
+			Message:     errors2.INVALID_SORT_PARAMETER.Message,
+			Description: description,
+		}, http.StatusBadRequest)
+	}
+
+	switch sort.Field {
+	case "profile_id", "user_id":
+		sort.ValueType = constants.StringDataType
+		return nil
+	case "created_at", "updated_at", "meta.created_at", "meta.updated_at":
+		sort.ValueType = constants.DateTimeDataType
+		return nil
+	}
+
+	if !isValidFilterKey(sort.Field) {
+		return invalidSort(fmt.Sprintf("Invalid sort field: %s", sort.Field))
+	}
+	if !strings.HasPrefix(sort.Field, constants.Traits+".") &&
+		!strings.HasPrefix(sort.Field, constants.IdentityAttributes+".") {
+		return invalidSort(fmt.Sprintf("Sorting is only supported on core fields, traits and identity attributes. "+
+			"Invalid sort field: %s", sort.Field))
+	}
+
+	attribute, err := schemaService.GetProfileSchemaService().GetProfileSchemaAttributeByName(sort.Field, orgHandle)
+	if err != nil {
+		return err
+	}
+	if attribute == nil {
+		return invalidSort(fmt.Sprintf("Sort field: %s is not defined in the profile schema", sort.Field))
+	}
+	if attribute.ValueType == constants.ComplexDataType || attribute.MultiValued {
+		return invalidSort(fmt.Sprintf("Sort field: %s must be a single valued attribute of a simple type",
+			sort.Field))
+	}
+	sort.ValueType = attribute.ValueType
+	return nil
+}
+
+// rangeFilterBounds converts the bounds of a gte, lte or between filter, the latter given as "<from> and <to>", to the
+// type of the field: RFC3339 timestamps for the creation and update times and date attributes, and plain numbers for
+// numeric and epoch attributes. Times may be given as RFC3339, as a date or in epoch seconds. A bound that does not
+// fit the field is reported as the problem.
+func rangeFilterBounds(orgHandle, field, operator, raw string) (string, string, error) {
+
+	valueType := constants.DateTimeDataType
+	if field != "created_at" && field != "updated_at" {
+		if !strings.HasPrefix(field, constants.Traits+".") && !strings.HasPrefix(field, constants.IdentityAttributes+".") {
+			return "", fmt.Sprintf("Range operators are not supported on %s", field), nil
+		}
+		attribute, err := schemaService.GetProfileSchemaService().GetProfileSchemaAttributeByName(field, orgHandle)
+		if err != nil {
+			return "", "", err
+		}
+		if attribute == nil {
+			return "", fmt.Sprintf("Attribute: %s is not defined in the profile schema", field), nil
+		}
+		if attribute.MultiValued {
+			return "", fmt.Sprintf("Range operators are not supported on the multi valued attribute %s", field), nil
+		}
+		valueType = attribute.ValueType
+	}
+
+	bounds := []string{strings.TrimSpace(raw)}
+	if operator == "between" {
+		from, to, found := strings.Cut(raw, " and ")
+		if !found {
+			return "", fmt.Sprintf("The between operator takes two bounds joined by \"and\", got: %s", raw), nil
+		}
+		bounds = []string{strings.TrimSpace(from), strings.TrimSpace(to)}
+	}
+	for i, bound := range bounds {
+		switch valueType {
+		case constants.DateTimeDataType, constants.DateDataType:
+			t, ok := parseFilterTime(bound)
+			if !ok {
+				return "", fmt.Sprintf("Invalid date for %s: %s", field, bound), nil
+			}
+			bounds[i] = t.UTC().Format(time.RFC3339Nano)
+		case constants.EpochDataType:
+			t, ok := parseFilterTime(bound)
+			if !ok {
+				return "", fmt.Sprintf("Invalid date for %s: %s", field, bound), nil
+			}
+			bounds[i] = strconv.FormatInt(t.Unix(), 10)
+		case constants.IntegerDataType, constants.DecimalDataType:
+			number, err := strconv.ParseFloat(bound, 64)
+			if err != nil {
+				return "", fmt.Sprintf("Invalid number for %s: %s", field, bound), nil
+			}
+			bounds[i] = strconv.FormatFloat(number, 'f', -1, 64)
+		default:
+			return "", fmt.Sprintf("Range operators need a numeric or date attribute, but %s is of type %s", field,
+				valueType), nil
+		}
+	}
+	return strings.Join(bounds, " and "), "", nil
+}
+
+// parseFilterTime parses a filter value given as an RFC3339 time, a date or a number of epoch seconds.
+func parseFilterTime(value string) (time.Time, bool) {
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), true
 	}
+	return time.Time{}, false
+}
+
+// filterValueLiteral returns the JSON literal an eq filter value is matched as, typed by the profile schema attribute
+// it filters on: a number for numeric attributes, a boolean for boolean ones and a string otherwise. Values of a
+// multi valued attribute are wrapped in an array so that they match any element. It reports whether the value fits
+// the type.
+func filterValueLiteral(attribute *model.ProfileSchemaAttribute, value string) (string, bool) {
+
+	var typed interface{} = value
+	if attribute != nil {
+		switch attribute.ValueType {
+		case constants.IntegerDataType, constants.DecimalDataType:
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil || (attribute.ValueType == constants.IntegerDataType && number != math.Trunc(number)) {
+				return "", false
+			}
+			typed = number
+		case constants.BooleanDataType:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return "", false
+			}
+			typed = b
+		}
+		if attribute.MultiValued {
+			typed = []interface{}{typed}
+		}
+	}
+	literal, err := json.Marshal(typed)
+	if err != nil {
+		return "", false
+	}
+	return string(literal), true
 }
 
 // FindProfileByUserId retrieves a profile by user_id
@@ -1195,8 +3181,13 @@ func (ps *ProfilesService) FindProfileByUserId(userId string) (*profileModel.Pro
 	if len(alias) == 0 {
 		alias = nil
 	}
+	canonicalProfileId := profile.ProfileId
+	if !profile.ProfileStatus.IsReferenceProfile && profile.ProfileStatus.ReferenceProfileId != "" {
+		canonicalProfileId = profile.ProfileStatus.ReferenceProfileId
+	}
 	profileResponse := &profileModel.ProfileResponse{
 		ProfileId:          profile.ProfileId,
+		CanonicalProfileId: canonicalProfileId,
 		UserId:             profile.UserId,
 		ApplicationData:    ConvertAppDataToMap(profile.ApplicationData),
 		Traits:             profile.Traits,
@@ -1211,10 +3202,72 @@ func (ps *ProfilesService) FindProfileByUserId(userId string) (*profileModel.Pro
 	return profileResponse, nil
 }
 
-// PatchProfile applies a partial update to an existing profile
-func (ps *ProfilesService) PatchProfile(profileId, orgHandle string, patch map[string]interface{}) (*profileModel.ProfileResponse, error) {
+// ResolveProfileByIdentifier finds the profiles of the person holding the given identity attribute value, such as
+// an email address. Each matching profile is resolved to the reference profile holding its unified data. Profiles
+// that have not been unified yet resolve separately, in which case all of them are returned as candidates. A
+// restricted appScope restricts the application data and traits of the profiles like in GetProfile.
+func (ps *ProfilesService) ResolveProfileByIdentifier(orgHandle, attrName, attrValue string,
+	appScope profileModel.AppScope) ([]profileModel.ProfileResponse, error) {
+
+	attrName = strings.TrimPrefix(attrName, constants.IdentityAttributes+".")
+	attribute, err := schemaStore.GetProfileSchemaAttributeByName(orgHandle, constants.IdentityAttributes+"."+attrName)
+	if err != nil {
+		return nil, err
+	}
+	if attribute == nil || attrValue == "" {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:    errors2.BAD_REQUEST.Code,
+			Message: errors2.BAD_REQUEST.Message,
+			Description: fmt.Sprintf("A value of a known identity attribute is required to resolve a profile, "+
+				"got: %s", attrName),
+		}, http.StatusBadRequest)
+	}
+
+	profileIds, err := profileStore.FindProfilesByIdentityAttribute(orgHandle, attrName, utils.NormalizeString(attrValue))
+	if err != nil {
+		return nil, err
+	}
+	var referenceProfileIds []string
+	for _, profileId := range profileIds {
+		profile, err := profileStore.GetProfile(profileId)
+		if err != nil {
+			return nil, err
+		}
+		if profile == nil {
+			continue
+		}
+		referenceProfileId, err := referenceProfileIdOf(profile)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(referenceProfileIds, referenceProfileId) {
+			referenceProfileIds = append(referenceProfileIds, referenceProfileId)
+		}
+	}
+	if len(referenceProfileIds) == 0 {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.PROFILE_NOT_FOUND.Code,
+			Message:     errors2.PROFILE_NOT_FOUND.Message,
+			Description: fmt.Sprintf("No profile found with %s: %s", attrName, attrValue),
+		}, http.StatusNotFound)
+	}
+
+	profiles := make([]profileModel.ProfileResponse, 0, len(referenceProfileIds))
+	for _, referenceProfileId := range referenceProfileIds {
+		profile, err := ps.GetProfile(referenceProfileId, appScope)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, *profile)
+	}
+	return profiles, nil
+}
+
+// PatchProfile applies a partial update to an existing profile. expectedVersion is checked as in UpdateProfile.
+func (ps *ProfilesService) PatchProfile(ctx context.Context, profileId, orgHandle string, patch map[string]interface{},
+	expectedVersion int64) (*profileModel.ProfileResponse, error) {
 
-	existingProfile, err := profileStore.GetProfile(profileId)
+	existingProfile, err := profileStore.GetProfileContext(ctx, profileId)
 	if err != nil {
 		return nil, err
 	}
@@ -1226,10 +3279,17 @@ func (ps *ProfilesService) PatchProfile(profileId, orgHandle string, patch map[s
 		}, http.StatusNotFound)
 	}
 
+	// Computed traits are derived on every write, so the stored values are not carried into the patched profile
+	schemaAttributes, err := schemaStore.GetProfileSchemaAttributesForOrg(existingProfile.OrgHandle)
+	if err != nil {
+		return nil, err
+	}
+	model.StripComputedTraits(existingProfile.Traits, schemaAttributes)
+
 	// Convert the full profile to map to allow patching
 	fullData, _ := json.Marshal(existingProfile)
 	var merged map[string]interface{}
-	_ = json.Unmarshal(fullData, &merged)
+	_ = utils.UnmarshalJSON(fullData, &merged)
 
 	// Handle deep merge for nested objects first
 	if traitsPatch, ok := patch["traits"].(map[string]interface{}); ok {
@@ -1266,7 +3326,7 @@ func (ps *ProfilesService) PatchProfile(profileId, orgHandle string, patch map[s
 	// Convert merged data back to ProfileRequest
 	mergedBytes, _ := json.Marshal(merged)
 	var updatedProfileReq profileModel.ProfileRequest
-	if err := json.Unmarshal(mergedBytes, &updatedProfileReq); err != nil {
+	if err := utils.UnmarshalJSON(mergedBytes, &updatedProfileReq); err != nil {
 		logger := log.GetLogger()
 		errMsg := fmt.Sprintf("Error unmarshalling merged profile data for profile_id: %s", profileId)
 		logger.Debug(errMsg, log.Error(err))
@@ -1279,7 +3339,7 @@ func (ps *ProfilesService) PatchProfile(profileId, orgHandle string, patch map[s
 	}
 
 	// Reuse the PUT logic to update the profile
-	return ps.UpdateProfile(profileId, orgHandle, updatedProfileReq)
+	return ps.UpdateProfile(ctx, profileId, orgHandle, updatedProfileReq, expectedVersion)
 }
 
 func (ps *ProfilesService) GetProfileCookieByProfileId(profileId string) (*profileModel.ProfileCookie, error) {
@@ -0,0 +1,184 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	"github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	schemaStore "github.com/wso2/identity-customer-data-service/internal/profile_schema/store"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/system/workers"
+)
+
+// SimulateProfile computes the profile the request would create without storing anything. The request is
+// normalized and validated and its computed traits are derived as in CreateProfileContext. When an active
+// unification rule unifies it with an existing reference profile, the merge is reported the way profile unification
+// would carry it out and the unified reference profile is returned as it would look after the merge, restricted to
+// what appScope may read like in GetProfile. A reference profile that would not exist before the merge, a new master or
+// the simulated profile itself, is reported without an id. Nothing is written, so the result only reflects the
+// profiles stored at the time of the call.
+func (ps *ProfilesService) SimulateProfile(ctx context.Context, profileRequest profileModel.ProfileRequest,
+	orgHandle string, appScope profileModel.AppScope) (*profileModel.ProfileSimulation, error) {
+
+	rawSchema, err := schemaService.GetProfileSchemaService().GetProfileSchema(orgHandle)
+	if err != nil {
+		return nil, err
+	}
+	var schema model.ProfileSchema
+	schemaBytes, _ := json.Marshal(rawSchema)
+	if err := json.Unmarshal(schemaBytes, &schema); err != nil {
+		errMsg := fmt.Sprintf("Invalid schema format for organization: %s while simulating a profile.", orgHandle)
+		log.GetLogger().Debug(errMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: errMsg,
+		}, err)
+	}
+
+	normalizeProfileRequest(&profileRequest)
+	if err := ValidateProfileAgainstSchema(profileRequest, profileModel.Profile{}, schema, false); err != nil {
+		return nil, err
+	}
+
+	// The id only identifies the simulated profile while matching it and is not reported.
+	now := time.Now().UTC()
+	profile := profileModel.Profile{
+		ProfileId:          uuid.NewString(),
+		OrgHandle:          orgHandle,
+		UserId:             profileRequest.UserId,
+		ApplicationData:    ConvertAppData(profileRequest.ApplicationData),
+		Traits:             applyComputedTraits(profileRequest.Traits, schema.Traits),
+		IdentityAttributes: profileRequest.IdentityAttributes,
+		ProfileStatus: &profileModel.ProfileStatus{
+			IsReferenceProfile: true,
+			ListProfile:        true,
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if ctx.Err() != nil {
+		return nil, abortedRequestError(ctx.Err())
+	}
+
+	simulation := &profileModel.ProfileSimulation{
+		Profile: profileModel.ProfileResponse{
+			UserId:             profile.UserId,
+			ApplicationData:    ConvertAppDataToMap(profile.ApplicationData),
+			Traits:             profile.Traits,
+			IdentityAttributes: profile.IdentityAttributes,
+			Meta:               profileModel.Meta{CreatedAt: now, UpdatedAt: now},
+		},
+	}
+
+	merge, err := workers.FindUnificationMerge(profile)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to find the unification match of a simulated profile of organization: %s",
+			orgHandle)
+		log.GetLogger().Debug(errMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: errMsg,
+		}, err)
+	}
+	if merge == nil {
+		return simulation, nil
+	}
+
+	match := merge.Match
+	match.ApplicationData, err = profileStore.FetchApplicationData(match.ProfileId)
+	if err != nil {
+		return nil, err
+	}
+	schemaAttributes, err := schemaStore.GetProfileSchemaAttributesForOrg(orgHandle)
+	if err != nil {
+		return nil, err
+	}
+	var restricted [][]string
+	if !appScope.IsUnrestricted() {
+		restricted = restrictedTraitPathsOf(schemaAttributes, appScope.AppId())
+	}
+	reference := func(profileId string) profileModel.Reference {
+		return profileModel.Reference{
+			ProfileId:    profileId,
+			Reason:       merge.Rule.RuleName,
+			RuleId:       merge.Rule.RuleId,
+			MatchedValue: merge.MatchedValue,
+		}
+	}
+	merged := workers.MergeProfilesByMode(match, profile, schemaAttributes, merge.Rule.MergeMode)
+	linkOnly := merge.Rule.MergeMode == constants.MergeModeLinkOnly
+
+	// A master that would be created for the profiles, like the simulated profile itself, is reported without an id.
+	var unifiedProfileId, location string
+	var mergedFrom []profileModel.Reference
+	switch merge.Outcome {
+	case workers.MergedToMatch:
+		mergedTo := reference(match.ProfileId)
+		simulation.Profile.MergedTo = &mergedTo
+		simulation.Profile.CanonicalProfileId = match.ProfileId
+		unifiedProfileId, location, mergedFrom = match.ProfileId, match.Location, match.ProfileStatus.References
+		merged.UserId = match.UserId
+		if linkOnly {
+			// The profiles are only linked, so the unified profile keeps the data of the match.
+			merged.Traits, merged.IdentityAttributes, merged.ApplicationData = match.Traits,
+				match.IdentityAttributes, match.ApplicationData
+		}
+	case workers.MatchMergedToProfile:
+		mergedFrom = append(append([]profileModel.Reference{}, match.ProfileStatus.References...),
+			reference(match.ProfileId))
+		simulation.Profile.MergedFrom = mergedFrom
+		merged.UserId = profile.UserId
+		if linkOnly {
+			merged.Traits, merged.IdentityAttributes, merged.ApplicationData = profile.Traits,
+				profile.IdentityAttributes, profile.ApplicationData
+		}
+	case workers.MergedToNewMaster:
+		mergedTo := reference("")
+		simulation.Profile.MergedTo = &mergedTo
+		mergedFrom = []profileModel.Reference{reference(match.ProfileId)}
+		merged.UserId = profile.UserId
+	}
+	simulation.UnifiedProfile = &profileModel.ProfileResponse{
+		ProfileId:          unifiedProfileId,
+		CanonicalProfileId: unifiedProfileId,
+		UserId:             merged.UserId,
+		ApplicationData:    ConvertAppDataToMap(restrictApplicationData(merged.ApplicationData, appScope)),
+		Traits:             redactTraits(merged.Traits, restricted),
+		IdentityAttributes: merged.IdentityAttributes,
+		Meta: profileModel.Meta{
+			CreatedAt: merged.CreatedAt,
+			UpdatedAt: merged.UpdatedAt,
+			Location:  location,
+		},
+		MergedFrom: mergedFrom,
+	}
+	return simulation, nil
+}
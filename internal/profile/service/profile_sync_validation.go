@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+// Profile sync events that the server acts on
+var supportedProfileSyncEvents = []string{
+	constants.AddUserEvent,
+	constants.DeleteUserEvent,
+	constants.UpdateUserClaimEvent,
+	constants.UpdateUserClaimsEvent,
+}
+
+// ValidateProfileSync checks that a profile sync event carries the fields needed to process it, so that an invalid
+// event is rejected before any profile is looked up. All the invalid fields are reported in the error details.
+func ValidateProfileSync(event profileModel.ProfileSync) error {
+
+	var violations []errors2.FieldError
+	if event.Event == "" {
+		violations = append(violations, errors2.FieldError{Field: "event", Message: "event is required"})
+	} else if !slices.Contains(supportedProfileSyncEvents, event.Event) {
+		violations = append(violations, errors2.FieldError{Field: "event",
+			Message: fmt.Sprintf("event '%s' is not supported", event.Event)})
+	}
+	if event.OrgHandle == "" {
+		violations = append(violations, errors2.FieldError{Field: "orgHandle", Message: "orgHandle is required"})
+	}
+	if event.UserId == "" {
+		violations = append(violations, errors2.FieldError{Field: "userId", Message: "userId is required"})
+	}
+
+	if len(violations) > 0 {
+		messages := make([]string, 0, len(violations))
+		for _, violation := range violations {
+			messages = append(messages, violation.Message)
+		}
+		return errors2.NewClientErrorWithDetails(errors2.ErrorMessage{
+			Code:        errors2.INVALID_PROFILE_SYNC_EVENT.Code,
+			Message:     errors2.INVALID_PROFILE_SYNC_EVENT.Message,
+			Description: strings.Join(messages, "; "),
+		}, http.StatusBadRequest, violations)
+	}
+	return nil
+}
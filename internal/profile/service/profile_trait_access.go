@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	"github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaStore "github.com/wso2/identity-customer-data-service/internal/profile_schema/store"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+// restrictedTraitPaths returns the paths of the traits the application may not read in the organization. A trait
+// is restricted by the allowed apps of its schema attribute; traits without allowed apps are readable by every
+// application. Unrestricted app scopes, used by system applications and internal reads, are never restricted.
+func restrictedTraitPaths(orgHandle string, appScope profileModel.AppScope) ([][]string, error) {
+
+	if appScope.IsUnrestricted() {
+		return nil, nil
+	}
+	attributes, err := schemaStore.GetProfileSchemaAttributesForOrg(orgHandle)
+	if err != nil {
+		return nil, err
+	}
+	return restrictedTraitPathsOf(attributes, appScope.AppId()), nil
+}
+
+// appRestrictedTraitPaths returns the paths of the traits of the organization that only some applications may read.
+func appRestrictedTraitPaths(orgHandle string) ([][]string, error) {
+
+	attributes, err := schemaStore.GetProfileSchemaAttributesForOrg(orgHandle)
+	if err != nil {
+		return nil, err
+	}
+	return restrictedTraitPathsOf(attributes, ""), nil
+}
+
+// restrictedTraitPathsOf returns the paths of the trait attributes whose allowed apps do not include the
+// application.
+func restrictedTraitPathsOf(attributes []model.ProfileSchemaAttribute, appId string) [][]string {
+
+	var paths [][]string
+	for _, attr := range attributes {
+		if len(attr.AllowedApps) == 0 || slices.Contains(attr.AllowedApps, appId) {
+			continue
+		}
+		if !strings.HasPrefix(attr.AttributeName, constants.Traits+".") {
+			continue
+		}
+		paths = append(paths, strings.Split(strings.TrimPrefix(attr.AttributeName, constants.Traits+"."), "."))
+	}
+	return paths
+}
+
+// redactTraits returns the traits without the values at the restricted paths. The maps along a removed path are
+// copied, so the given traits are left untouched.
+func redactTraits(traits map[string]interface{}, restricted [][]string) map[string]interface{} {
+
+	for _, path := range restricted {
+		traits, _ = withoutPath(traits, path)
+	}
+	return traits
+}
+
+// restrictProfileResponse returns a copy of the profile with only the application data and traits appScope may read,
+// for profiles that were not read through GetProfile.
+func restrictProfileResponse(orgHandle string, profile *profileModel.ProfileResponse,
+	appScope profileModel.AppScope) (*profileModel.ProfileResponse, error) {
+
+	if profile == nil || appScope.IsUnrestricted() {
+		return profile, nil
+	}
+	restricted, err := restrictedTraitPaths(orgHandle, appScope)
+	if err != nil {
+		return nil, err
+	}
+	scoped := *profile
+	scoped.Traits = redactTraits(profile.Traits, restricted)
+	scoped.ApplicationData = make(map[string]map[string]interface{}, 1)
+	for appId, data := range profile.ApplicationData {
+		if appScope.Allows(appId) {
+			scoped.ApplicationData[appId] = data
+		}
+	}
+	return &scoped, nil
+}
+
+// withoutPath returns a copy of the map without the value at the path, or the map itself when there is no value
+// to remove. removed reports whether a value was removed.
+func withoutPath(m map[string]interface{}, path []string) (_ map[string]interface{}, removed bool) {
+
+	value, ok := m[path[0]]
+	if !ok {
+		return m, false
+	}
+	if len(path) > 1 {
+		nested, isMap := value.(map[string]interface{})
+		if !isMap {
+			return m, false
+		}
+		if value, removed = withoutPath(nested, path[1:]); !removed {
+			return m, false
+		}
+	}
+
+	copied := make(map[string]interface{}, len(m))
+	for key, v := range m {
+		copied[key] = v
+	}
+	if len(path) == 1 {
+		delete(copied, path[0])
+	} else {
+		copied[path[0]] = value
+	}
+	return copied, true
+}
+
+// checkTraitFilterAccess rejects filters on traits the application may not read, as the profiles they match would
+// reveal the values of those traits.
+func checkTraitFilterAccess(filters []string, restricted [][]string) error {
+
+	for _, filter := range filters {
+		field, _, _ := strings.Cut(strings.TrimSpace(filter), " ")
+		if !strings.HasPrefix(field, constants.Traits+".") {
+			continue
+		}
+		fieldPath := strings.Split(strings.TrimPrefix(field, constants.Traits+"."), ".")
+		for _, path := range restricted {
+			if len(fieldPath) < len(path) || !slices.Equal(fieldPath[:len(path)], path) {
+				continue
+			}
+			return errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.FORBIDDEN.Code,
+				Message:     errors2.FORBIDDEN.Message,
+				Description: fmt.Sprintf("Filtering on %s is not permitted for the application.", field),
+			}, http.StatusForbidden)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	"github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaStore "github.com/wso2/identity-customer-data-service/internal/profile_schema/store"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/system/utils"
+)
+
+// GetOrCreateProfile returns the profile of the user the request belongs to, creating it when the user has no
+// profile in the organization yet. The attributes of the request are merged into an existing profile like a patch,
+// but the profile is only written when that changes one of its values, so that resending the same data does not
+// produce updates. created reports whether a new profile was created. Concurrent calls for the same user are
+// serialized so that only the first of them creates the profile. The profile is returned restricted to what appScope
+// may read like in GetProfile.
+func (ps *ProfilesService) GetOrCreateProfile(ctx context.Context, profileRequest profileModel.ProfileRequest,
+	orgHandle string, appScope profileModel.AppScope) (_ *profileModel.ProfileResponse, created bool, err error) {
+
+	if profileRequest.UserId == "" {
+		return nil, false, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: "user_id is required to get or create a profile.",
+		}, http.StatusBadRequest)
+	}
+
+	var profile *profileModel.ProfileResponse
+	err = profileStore.RunWithUserProfileLock(ctx, orgHandle, profileRequest.UserId, func() error {
+		existing, err := profileStore.GetProfileWithUserIdInOrg(orgHandle, profileRequest.UserId)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			profile, err = ps.CreateProfileContext(ctx, profileRequest, orgHandle)
+			created = true
+			return err
+		}
+		profile, err = ps.upsertExistingProfile(ctx, existing.ProfileId, profileRequest, orgHandle)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	profile, err = restrictProfileResponse(orgHandle, profile, appScope)
+	if err != nil {
+		return nil, false, err
+	}
+	return profile, created, nil
+}
+
+// upsertExistingProfile merges the attributes of the request into the existing profile of its user, writing the
+// profile only when that changes one of its values.
+func (ps *ProfilesService) upsertExistingProfile(ctx context.Context, profileId string,
+	profileRequest profileModel.ProfileRequest, orgHandle string) (*profileModel.ProfileResponse, error) {
+
+	current, err := ps.GetProfileFromPrimary(profileId, profileModel.AllApplications)
+	if err != nil {
+		return nil, err
+	}
+
+	// Computed traits are derived on every write, so they take no part in the comparison
+	schemaAttributes, err := schemaStore.GetProfileSchemaAttributesForOrg(orgHandle)
+	if err != nil {
+		return nil, err
+	}
+	before, err := upsertBaseline(current, schemaAttributes)
+	if err != nil {
+		return nil, err
+	}
+	after, err := upsertBaseline(current, schemaAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizeProfileRequest(&profileRequest)
+	DeepMerge(after.IdentityAttributes, profileRequest.IdentityAttributes)
+	DeepMerge(after.Traits, profileRequest.Traits)
+	for appId, appData := range profileRequest.ApplicationData {
+		if after.ApplicationData[appId] == nil {
+			after.ApplicationData[appId] = make(map[string]interface{})
+		}
+		DeepMerge(after.ApplicationData[appId], appData)
+	}
+
+	if reflect.DeepEqual(before, after) {
+		log.GetLogger().Debug(fmt.Sprintf("Profile: %s of user: %s is unchanged, skipping the update",
+			current.ProfileId, profileRequest.UserId))
+		return current, nil
+	}
+	return ps.UpdateProfile(ctx, current.ProfileId, orgHandle, *after, current.Meta.Version)
+}
+
+// upsertBaseline copies the stored attributes of a profile into a profile request without its computed traits. The
+// copy goes through JSON so that its values compare equal to decoded request values and can be merged into safely.
+func upsertBaseline(profile *profileModel.ProfileResponse,
+	schemaAttributes []model.ProfileSchemaAttribute) (*profileModel.ProfileRequest, error) {
+
+	data, err := json.Marshal(profileModel.ProfileRequest{
+		UserId:             profile.UserId,
+		IdentityAttributes: profile.IdentityAttributes,
+		Traits:             profile.Traits,
+		ApplicationData:    profile.ApplicationData,
+	})
+	var baseline profileModel.ProfileRequest
+	if err == nil {
+		err = utils.UnmarshalJSON(data, &baseline)
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("Error copying the attributes of profile: %s", profile.ProfileId)
+		log.GetLogger().Debug(errMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errMsg,
+		}, err)
+	}
+	if baseline.IdentityAttributes == nil {
+		baseline.IdentityAttributes = make(map[string]interface{})
+	}
+	if baseline.Traits == nil {
+		baseline.Traits = make(map[string]interface{})
+	}
+	if baseline.ApplicationData == nil {
+		baseline.ApplicationData = make(map[string]map[string]interface{})
+	}
+	model.StripComputedTraits(baseline.Traits, schemaAttributes)
+	return &baseline, nil
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/wso2/identity-customer-data-service/internal/profile/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/scripts"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// StoreAnonymizedProfiles writes the anonymized profiles of the person held by the reference profile in a single
+// transaction. The application data of each profile is replaced, and the trait history of the profiles and the
+// values their merges matched on are erased along with it, as they still hold the data that was anonymized.
+func StoreAnonymizedProfiles(referenceProfileId string, profiles []model.Profile) error {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get database client for anonymizing profile: %s", referenceProfileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	fail := func(errorMsg string, cause error) error {
+		logger.Debug(errorMsg, log.Error(cause))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, cause)
+	}
+
+	dbType := provider.NewDBProvider().GetDBType()
+	profileIds := make([]string, 0, len(profiles))
+	err = dbClient.RunInTx(func(tx *sql.Tx) error {
+		profileIds = profileIds[:0]
+		for _, profile := range profiles {
+			traitsJSON, _ := json.Marshal(profile.Traits)
+			identityJSON, _ := json.Marshal(profile.IdentityAttributes)
+			if _, err := tx.Exec(scripts.UpdateProfile[dbType], profile.UserId, profile.ProfileStatus.ListProfile,
+				profile.ProfileStatus.DeleteProfile, traitsJSON, identityJSON, profile.UpdatedAt, profile.ProfileId,
+				0); err != nil {
+				return fail(fmt.Sprintf("Failed to anonymize profile: %s", profile.ProfileId), err)
+			}
+			for _, app := range profile.ApplicationData {
+				appDataJSON, err := json.Marshal(struct {
+					AppSpecificData map[string]interface{} `json:"app_specific_data,omitempty"`
+				}{AppSpecificData: app.AppSpecificData})
+				if err != nil {
+					return fail(fmt.Sprintf("Failed to marshal application data of app: %s for profile: %s",
+						app.AppId, profile.ProfileId), err)
+				}
+				if _, err := tx.Exec(scripts.InsertApplicationData[dbType], profile.ProfileId, app.AppId,
+					appDataJSON, profile.UpdatedAt); err != nil {
+					return fail(fmt.Sprintf("Failed to anonymize application data of app: %s for profile: %s",
+						app.AppId, profile.ProfileId), err)
+				}
+			}
+			profileIds = append(profileIds, profile.ProfileId)
+		}
+		if _, err := tx.Exec(scripts.DeleteProfileTraitHistory[dbType], pq.Array(profileIds)); err != nil {
+			return fail(fmt.Sprintf("Failed to erase the history of profile: %s", referenceProfileId), err)
+		}
+		if _, err := tx.Exec(scripts.ClearReferenceMatchedValues[dbType], referenceProfileId); err != nil {
+			return fail(fmt.Sprintf("Failed to clear matched values of profiles merged into profile: %s",
+				referenceProfileId), err)
+		}
+		return nil
+	})
+	if _, reported := err.(*errors2.ServerError); err != nil && !reported {
+		return fail(fmt.Sprintf("Failed to commit anonymization of profile: %s", referenceProfileId), err)
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package store
+
+import (
+	"github.com/lib/pq"
+	"github.com/wso2/identity-customer-data-service/internal/profile/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/scripts"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// FetchReferencedProfilesBatch fetches the profiles unified into each of the given reference profiles with a single
+// query, keyed by the reference profile id. Reference profiles without unified profiles are not in the map.
+func FetchReferencedProfilesBatch(referenceProfileIds []string) (map[string][]model.Reference, error) {
+
+	result := make(map[string][]model.Reference)
+	if len(referenceProfileIds) == 0 {
+		return result, nil
+	}
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed to get database client for fetching referenced profiles batch."
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.FetchReferencedProfilesByReferenceIds[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, pq.Array(referenceProfileIds))
+	if err != nil {
+		errorMsg := "Failed fetching referenced profiles batch."
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	for _, row := range results {
+		referenceProfileId := row["reference_profile_id"].(string)
+		result[referenceProfileId] = append(result[referenceProfileId], model.Reference{
+			ProfileId:    row["profile_id"].(string),
+			Reason:       row["reference_reason"].(string),
+			RuleId:       row["matched_rule_id"].(string),
+			MatchedValue: row["matched_value"].(string),
+		})
+	}
+	return result, nil
+}
+
+// GetProfilesBatch fetches the given profiles with a single query, keyed by profile id. Application data is not
+// fetched; FetchApplicationDataBatch loads it for profiles that need it. Profiles that do not exist or are
+// soft-deleted are not in the map.
+func GetProfilesBatch(profileIds []string) (map[string]*model.Profile, error) {
+
+	result := make(map[string]*model.Profile)
+	if len(profileIds) == 0 {
+		return result, nil
+	}
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed to get database client for fetching profiles batch."
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.GetProfilesByIds[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, pq.Array(profileIds))
+	if err != nil {
+		errorMsg := "Failed fetching profiles batch."
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	for _, row := range results {
+		profile, err := scanProfileRow(row)
+		if err != nil {
+			return nil, err
+		}
+		result[profile.ProfileId] = &profile
+	}
+	return result, nil
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wso2/identity-customer-data-service/internal/profile/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/scripts"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/system/utils"
+)
+
+// GetProfileTraitHistory returns the traits of the earlier versions of the profile recorded when they were replaced,
+// oldest first. The current version of the profile is not part of it.
+func GetProfileTraitHistory(profileId string) ([]model.ProfileSnapshot, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for fetching the history of profile: %s", profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.GetProfileTraitHistory[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, profileId)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to fetch the history of profile: %s", profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	snapshots := make([]model.ProfileSnapshot, 0, len(results))
+	for _, row := range results {
+		snapshot := model.ProfileSnapshot{
+			Version:    row["version"].(int64),
+			RecordedAt: row["recorded_at"].(time.Time),
+		}
+		if err := utils.UnmarshalJSON(row["traits"].([]byte), &snapshot.Traits); err != nil {
+			errorMsg := fmt.Sprintf("Failed to unmarshal traits of version: %d of profile: %s", snapshot.Version,
+				profileId)
+			logger.Debug(errorMsg, log.Error(err))
+			return nil, errors2.NewServerError(errors2.ErrorMessage{
+				Code:        errors2.GET_PROFILE.Code,
+				Message:     errors2.GET_PROFILE.Message,
+				Description: errorMsg,
+			}, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// PruneProfileTraitHistory removes the trait history recorded before the given time and keeps at most maxSnapshots
+// entries per profile, unless it is 0. It returns the number of entries removed.
+func PruneProfileTraitHistory(recordedBefore time.Time, maxSnapshots int) (int64, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed to get db client for pruning the profile history."
+		logger.Debug(errorMsg, log.Error(err))
+		return 0, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_PROFILE.Code,
+			Message:     errors2.DELETE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.PruneProfileTraitHistory[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, recordedBefore, maxSnapshots)
+	if err != nil {
+		errorMsg := "Failed to prune the profile history."
+		logger.Debug(errorMsg, log.Error(err))
+		return 0, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_PROFILE.Code,
+			Message:     errors2.DELETE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	pruned, _ := results[0]["pruned"].(int64)
+	return pruned, nil
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/scripts"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// ClaimIdempotencyKey records the idempotency key as in progress until expiresAt, held by the given claim token.
+// Returns false if the key is already held by another request that has not expired, in which case
+// GetIdempotencyKey tells its outcome.
+//
+// The claim is a row of the profile_idempotency_keys table rather than a session-scoped advisory lock, so it
+// outlives the connection and the process that took it. A claim that is never completed or released is only
+// recovered once expiresAt passes, which is why claims are taken with a short lease that
+// CompleteIdempotencyKey extends.
+func ClaimIdempotencyKey(ctx context.Context, orgHandle, key, claimToken string, now,
+	expiresAt time.Time) (bool, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for claiming idempotency key: %s", key)
+		logger.Debug(errorMsg, log.Error(err))
+		return false, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.ClaimIdempotencyKey[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQueryContext(ctx, query, orgHandle, key, claimToken, now, expiresAt)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to claim idempotency key: %s of organization: %s", key, orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return false, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return len(results) > 0, nil
+}
+
+// GetIdempotencyKey returns the id of the profile created with the idempotency key. The id is empty while the
+// creation is still in progress, and found is false if the key is unknown or expired.
+func GetIdempotencyKey(orgHandle, key string, now time.Time) (profileId string, found bool, err error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for fetching idempotency key: %s", key)
+		logger.Debug(errorMsg, log.Error(err))
+		return "", false, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.GetIdempotencyKey[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, orgHandle, key, now)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to fetch idempotency key: %s of organization: %s", key, orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return "", false, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if len(results) == 0 {
+		return "", false, nil
+	}
+	return results[0]["profile_id"].(string), true, nil
+}
+
+// CompleteIdempotencyKey maps a claimed idempotency key to the profile created for it and keeps it until expiresAt.
+// It fails without touching the key if the claim token no longer holds it, which happens when the claim lease ran
+// out and another request took the key over.
+func CompleteIdempotencyKey(orgHandle, key, claimToken, profileId string, expiresAt time.Time) error {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for completing idempotency key: %s", key)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.CompleteIdempotencyKey[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, orgHandle, key, claimToken, profileId, expiresAt)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to map idempotency key: %s to profile: %s", key, profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if len(results) == 0 {
+		errorMsg := fmt.Sprintf("Idempotency key: %s was taken over by another request before profile: %s was "+
+			"mapped to it", key, profileId)
+		logger.Debug(errorMsg)
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.IDEMPOTENCY_KEY_IN_USE.Code,
+			Message:     errors2.IDEMPOTENCY_KEY_IN_USE.Message,
+			Description: errorMsg,
+		}, errors.New(errorMsg))
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey drops a claimed idempotency key whose profile creation failed so that it can be retried. A
+// key the claim token no longer holds is left to the request that took it over.
+func ReleaseIdempotencyKey(orgHandle, key, claimToken string) error {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for releasing idempotency key: %s", key)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.ReleaseIdempotencyKey[provider.NewDBProvider().GetDBType()]
+	_, err = dbClient.ExecuteQuery(query, orgHandle, key, claimToken)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to release idempotency key: %s of organization: %s", key, orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return nil
+}
+
+// PurgeExpiredIdempotencyKeys removes the idempotency keys of every organization that expired by the given time. It
+// returns the number of keys removed.
+func PurgeExpiredIdempotencyKeys(expiredBy time.Time) (int64, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed to get db client for purging expired idempotency keys."
+		logger.Debug(errorMsg, log.Error(err))
+		return 0, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_PROFILE.Code,
+			Message:     errors2.DELETE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.PurgeExpiredIdempotencyKeys[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, expiredBy)
+	if err != nil {
+		errorMsg := "Failed to purge expired idempotency keys."
+		logger.Debug(errorMsg, log.Error(err))
+		return 0, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_PROFILE.Code,
+			Message:     errors2.DELETE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	purged, _ := results[0]["purged"].(int64)
+	return purged, nil
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/wso2/identity-customer-data-service/internal/profile/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/scripts"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/system/utils"
+)
+
+// AddQuarantinedImportRecord stores an import record held back from a quarantined source.
+func AddQuarantinedImportRecord(record model.QuarantinedImportRecord) error {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for quarantining import record of source: %s", record.Source)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.IMPORT_QUARANTINE.Code,
+			Message:     errors2.IMPORT_QUARANTINE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	payload, err := json.Marshal(record.Record)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to marshal import record at line: %d of source: %s", record.Line, record.Source)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.IMPORT_QUARANTINE.Code,
+			Message:     errors2.IMPORT_QUARANTINE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	query := scripts.InsertQuarantinedImportRecord[provider.NewDBProvider().GetDBType()]
+	_, err = dbClient.ExecuteQuery(query, record.RecordId, record.OrgHandle, record.Source, record.Line, payload,
+		record.Reason, record.QuarantinedAt)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to quarantine import record at line: %d of source: %s", record.Line,
+			record.Source)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.IMPORT_QUARANTINE.Code,
+			Message:     errors2.IMPORT_QUARANTINE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return nil
+}
+
+// GetQuarantinedImportRecords retrieves the quarantined import records of an organization. All sources are
+// included when the source is empty.
+func GetQuarantinedImportRecords(orgHandle, source string) ([]model.QuarantinedImportRecord, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for fetching quarantined import records of: %s", orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.IMPORT_QUARANTINE.Code,
+			Message:     errors2.IMPORT_QUARANTINE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.GetQuarantinedImportRecords[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, orgHandle, source)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed fetching quarantined import records of: %s", orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.IMPORT_QUARANTINE.Code,
+			Message:     errors2.IMPORT_QUARANTINE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	records := make([]model.QuarantinedImportRecord, 0, len(results))
+	for _, row := range results {
+		record, err := scanQuarantinedImportRecord(row)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// GetQuarantinedImportRecord retrieves a quarantined import record. Returns nil if there is no such record.
+func GetQuarantinedImportRecord(orgHandle, recordId string) (*model.QuarantinedImportRecord, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for fetching quarantined import record: %s", recordId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.IMPORT_QUARANTINE.Code,
+			Message:     errors2.IMPORT_QUARANTINE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.GetQuarantinedImportRecord[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, recordId, orgHandle)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed fetching quarantined import record: %s", recordId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.IMPORT_QUARANTINE.Code,
+			Message:     errors2.IMPORT_QUARANTINE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	record, err := scanQuarantinedImportRecord(results[0])
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// DeleteQuarantinedImportRecord removes a quarantined import record.
+func DeleteQuarantinedImportRecord(orgHandle, recordId string) error {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for deleting quarantined import record: %s", recordId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.IMPORT_QUARANTINE.Code,
+			Message:     errors2.IMPORT_QUARANTINE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.DeleteQuarantinedImportRecord[provider.NewDBProvider().GetDBType()]
+	if _, err = dbClient.ExecuteQuery(query, recordId, orgHandle); err != nil {
+		errorMsg := fmt.Sprintf("Failed deleting quarantined import record: %s", recordId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.IMPORT_QUARANTINE.Code,
+			Message:     errors2.IMPORT_QUARANTINE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return nil
+}
+
+func scanQuarantinedImportRecord(row map[string]interface{}) (model.QuarantinedImportRecord, error) {
+
+	record := model.QuarantinedImportRecord{
+		RecordId:      row["record_id"].(string),
+		OrgHandle:     row["org_handle"].(string),
+		Source:        row["source"].(string),
+		Line:          int(row["line"].(int64)),
+		QuarantinedAt: row["quarantined_at"].(time.Time),
+	}
+	if reason, ok := row["reason"].(string); ok {
+		record.Reason = reason
+	}
+	if err := utils.UnmarshalJSON(row["payload"].([]byte), &record.Record); err != nil {
+		errorMsg := fmt.Sprintf("Failed to unmarshal quarantined import record: %s", record.RecordId)
+		log.GetLogger().Debug(errorMsg, log.Error(err))
+		return record, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.IMPORT_QUARANTINE.Code,
+			Message:     errors2.IMPORT_QUARANTINE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	record.Record.Line = record.Line
+	return record, nil
+}
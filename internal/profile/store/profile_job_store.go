@@ -0,0 +1,315 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/wso2/identity-customer-data-service/internal/profile/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/client"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/scripts"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// profileJobError logs the failure of a profile job query and wraps it in a server error.
+func profileJobError(errorMsg string, err error) error {
+
+	log.GetLogger().Debug(errorMsg, log.Error(err))
+	return errors2.NewServerError(errors2.ErrorMessage{
+		Code:        errors2.PROFILE_JOB.Code,
+		Message:     errors2.PROFILE_JOB.Message,
+		Description: errorMsg,
+	}, err)
+}
+
+// InsertProfileJob records a new running job. Returns false without recording it when a job of the same type is
+// already running for the organization.
+func InsertProfileJob(job model.ProfileJob) (bool, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	if err != nil {
+		return false, profileJobError(fmt.Sprintf("Failed to get db client for adding profile job: %s", job.JobId),
+			err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.InsertProfileJob[provider.NewDBProvider().GetDBType()]
+	_, err = dbClient.ExecuteQuery(query, job.JobId, job.OrgHandle, job.Type, job.Status, job.Phase, job.CreatedAt)
+	if client.IsUniqueViolation(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, profileJobError(fmt.Sprintf("Failed to add profile job: %s of organization: %s", job.JobId,
+			job.OrgHandle), err)
+	}
+	return true, nil
+}
+
+// GetProfileJob returns the job with the given id, or nil if there is none.
+func GetProfileJob(jobId string) (*model.ProfileJob, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	if err != nil {
+		return nil, profileJobError(fmt.Sprintf("Failed to get db client for fetching profile job: %s", jobId), err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.GetProfileJob[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, jobId)
+	if err != nil {
+		return nil, profileJobError(fmt.Sprintf("Failed to fetch profile job: %s", jobId), err)
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	job, err := profileJobFromRow(results[0])
+	if err != nil {
+		return nil, profileJobError(fmt.Sprintf("Invalid profile job: %s", jobId), err)
+	}
+	return &job, nil
+}
+
+// UpdateProfileJobProgress records the progress of a running job and renews its heartbeat. Returns whether the job
+// is asked to be cancelled, and running as false when the job is no longer running.
+func UpdateProfileJobProgress(job model.ProfileJob, updatedAt time.Time) (cancelRequested, running bool,
+	err error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	if err != nil {
+		return false, false, profileJobError(fmt.Sprintf("Failed to get db client for updating profile job: %s",
+			job.JobId), err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.UpdateProfileJobProgress[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, job.JobId, job.Phase, job.Processed, job.Total, job.MergedProfiles,
+		job.Cursor, updatedAt)
+	if err != nil {
+		return false, false, profileJobError(fmt.Sprintf("Failed to update progress of profile job: %s", job.JobId),
+			err)
+	}
+	if len(results) == 0 {
+		return false, false, nil
+	}
+	cancelRequested, err = client.GetBool(results[0], "cancel_requested")
+	if err != nil {
+		return false, false, profileJobError(fmt.Sprintf("Invalid progress of profile job: %s", job.JobId), err)
+	}
+	return cancelRequested, true, nil
+}
+
+// FinishProfileJob ends a running job with the given status and error message.
+func FinishProfileJob(jobId, status, errorMessage string, finishedAt time.Time) error {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	if err != nil {
+		return profileJobError(fmt.Sprintf("Failed to get db client for finishing profile job: %s", jobId), err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.FinishProfileJob[provider.NewDBProvider().GetDBType()]
+	if _, err = dbClient.ExecuteQuery(query, jobId, status, errorMessage, finishedAt); err != nil {
+		return profileJobError(fmt.Sprintf("Failed to finish profile job: %s as %s", jobId, status), err)
+	}
+	return nil
+}
+
+// RequestProfileJobCancellation asks a running job to stop after its current batch. Returns false if the job is not
+// running.
+func RequestProfileJobCancellation(jobId string) (bool, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	if err != nil {
+		return false, profileJobError(fmt.Sprintf("Failed to get db client for cancelling profile job: %s", jobId),
+			err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.RequestProfileJobCancellation[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, jobId)
+	if err != nil {
+		return false, profileJobError(fmt.Sprintf("Failed to cancel profile job: %s", jobId), err)
+	}
+	return len(results) > 0, nil
+}
+
+// ClaimStaleProfileJobs takes over the running jobs whose heartbeat is older than staleBefore, so that they can be
+// resumed from where they stopped.
+func ClaimStaleProfileJobs(now, staleBefore time.Time) ([]model.ProfileJob, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	if err != nil {
+		return nil, profileJobError("Failed to get db client for claiming stale profile jobs.", err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.ClaimStaleProfileJobs[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, now, staleBefore)
+	if err != nil {
+		return nil, profileJobError("Failed to claim stale profile jobs.", err)
+	}
+	jobs := make([]model.ProfileJob, 0, len(results))
+	for _, row := range results {
+		job, err := profileJobFromRow(row)
+		if err != nil {
+			return nil, profileJobError("Invalid stale profile job.", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// ListMasterProfilesAfter lists up to limit live reference profiles of the organization that have profiles merged to
+// them, in id order after the given id.
+func ListMasterProfilesAfter(orgHandle, afterProfileId string, limit int) ([]model.MasterProfile, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	if err != nil {
+		return nil, profileJobError(fmt.Sprintf("Failed to get db client for listing master profiles of: %s",
+			orgHandle), err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.ListMasterProfilesAfter[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, orgHandle, constants.MergedTo, afterProfileId, limit)
+	if err != nil {
+		return nil, profileJobError(fmt.Sprintf("Failed to list master profiles of organization: %s", orgHandle),
+			err)
+	}
+	masters := make([]model.MasterProfile, 0, len(results))
+	for _, row := range results {
+		profileId, err := client.GetString(row, "reference_profile_id")
+		if err != nil {
+			return nil, profileJobError("Invalid master profile.", err)
+		}
+		listed, err := client.GetBool(row, "list_profile")
+		if err != nil {
+			return nil, profileJobError(fmt.Sprintf("Invalid master profile: %s", profileId), err)
+		}
+		masters = append(masters, model.MasterProfile{ProfileId: profileId, Listed: listed})
+	}
+	return masters, nil
+}
+
+// SplitProfileCluster promotes the profiles merged to the reference profile by unification rules back to reference
+// profiles of their own within a single transaction and returns their ids. Profiles merged by an administrator or
+// as aliases stay merged, as no rule would merge them again. Unlike an unmerge, the split is not recorded, so the
+// profiles may be unified again by any rule. When dissolveReference is set, the reference profile is soft-deleted
+// as well unless profiles stay merged to it; dissolved reports whether it was.
+func SplitProfileCluster(referenceProfileId string, dissolveReference bool,
+	splitAt time.Time) (promoted []string, dissolved bool, err error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	if err != nil {
+		return nil, false, profileJobError(fmt.Sprintf("Failed to get db client for splitting profile: %s",
+			referenceProfileId), err)
+	}
+	defer dbClient.Close()
+
+	dbType := provider.NewDBProvider().GetDBType()
+	err = dbClient.RunInTx(func(tx *sql.Tx) error {
+		promoted, dissolved = promoted[:0], false
+		rows, err := tx.Query(scripts.SplitProfileCluster[dbType], referenceProfileId, constants.ReferenceProfile,
+			constants.MergedTo, constants.ManualMergeReason, constants.AliasMergeReason)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var profileId string
+			if err := rows.Scan(&profileId); err != nil {
+				return err
+			}
+			promoted = append(promoted, profileId)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		touched := append([]string{referenceProfileId}, promoted...)
+		if _, err := tx.Exec(scripts.TouchProfiles[dbType], pq.Array(touched), splitAt); err != nil {
+			return err
+		}
+		if !dissolveReference {
+			return nil
+		}
+		var keptMerged bool
+		if err := tx.QueryRow(scripts.HasMergedProfiles[dbType], referenceProfileId,
+			constants.MergedTo).Scan(&keptMerged); err != nil || keptMerged {
+			return err
+		}
+		if _, err := tx.Exec(scripts.SoftDeleteProfile[dbType], splitAt, referenceProfileId); err != nil {
+			return err
+		}
+		dissolved = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, profileJobError(fmt.Sprintf("Failed to split the profiles merged to profile: %s",
+			referenceProfileId), err)
+	}
+	return promoted, dissolved, nil
+}
+
+func profileJobFromRow(row map[string]interface{}) (model.ProfileJob, error) {
+
+	var job model.ProfileJob
+	var err error
+	for _, field := range []struct {
+		column string
+		target *string
+	}{
+		{"job_id", &job.JobId},
+		{"org_handle", &job.OrgHandle},
+		{"job_type", &job.Type},
+		{"status", &job.Status},
+		{"phase", &job.Phase},
+		{"job_cursor", &job.Cursor},
+		{"error", &job.Error},
+	} {
+		if *field.target, err = client.GetString(row, field.column); err != nil {
+			return job, err
+		}
+	}
+	for _, field := range []struct {
+		column string
+		target *int64
+	}{
+		{"processed", &job.Processed},
+		{"total", &job.Total},
+		{"merged", &job.MergedProfiles},
+	} {
+		if *field.target, err = client.GetInt64(row, field.column); err != nil {
+			return job, err
+		}
+	}
+	if job.CancelRequested, err = client.GetBool(row, "cancel_requested"); err != nil {
+		return job, err
+	}
+	if job.CreatedAt, err = client.GetTime(row, "created_at"); err != nil {
+		return job, err
+	}
+	job.UpdatedAt, err = client.GetTime(row, "updated_at")
+	return job, err
+}
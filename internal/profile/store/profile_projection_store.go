@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package store
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/wso2/identity-customer-data-service/internal/profile/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/scripts"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// GetProjectedProfile retrieves a profile through the read client holding only the given top-level keys of its
+// traits and identity attributes. Application data is not fetched. Returns nil when the profile does not exist.
+func GetProjectedProfile(profileId string, traitKeys, identityAttributeKeys []string) (*model.Profile, error) {
+
+	dbClient, err := provider.NewDBProvider().GetReadDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client while fetching projected profile with Id: %s", profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.GetProjectedProfileById[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, profileId, pq.Array(traitKeys), pq.Array(identityAttributeKeys))
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed fetching projected profile with Id: %s", profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if len(results) == 0 {
+		logger.Debug(fmt.Sprintf("No profile found with the given Id: %s", profileId))
+		return nil, nil
+	}
+	profile, err := scanProfileRow(results[0])
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
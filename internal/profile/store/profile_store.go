@@ -1,21 +1,27 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/wso2/identity-customer-data-service/internal/profile/model"
 	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/client"
 	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
 	"github.com/wso2/identity-customer-data-service/internal/system/database/scripts"
 	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
 	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/system/tracing"
+	"github.com/wso2/identity-customer-data-service/internal/system/utils"
 )
 
 // Unmarshal JSONB fields separately
@@ -51,12 +57,18 @@ func scanProfileRow(row map[string]interface{}) (model.Profile, error) {
 	}
 
 	profile.ProfileStatus.ListProfile = row["list_profile"].(bool)
+	if deletedAt, ok := row["deleted_at"].(time.Time); ok {
+		profile.DeletedAt = &deletedAt
+	}
+	if version, ok := row["version"].(int64); ok {
+		profile.Version = version
+	}
 	traitsJSON = row["traits"].([]byte)
 	identityAttrsJSON = row["identity_attributes"].([]byte)
 
 	logger := log.GetLogger()
 	// Unmarshal JSON fields
-	if err := json.Unmarshal(traitsJSON, &profile.Traits); err != nil {
+	if err := utils.UnmarshalJSON(traitsJSON, &profile.Traits); err != nil {
 		errorMsg := "Failed to unmarshal traits"
 		logger.Debug(errorMsg, log.Error(err))
 		serverError := errors2.NewServerError(errors2.ErrorMessage{
@@ -66,7 +78,7 @@ func scanProfileRow(row map[string]interface{}) (model.Profile, error) {
 		}, err)
 		return model.Profile{}, serverError
 	}
-	if err := json.Unmarshal(identityAttrsJSON, &profile.IdentityAttributes); err != nil {
+	if err := utils.UnmarshalJSON(identityAttrsJSON, &profile.IdentityAttributes); err != nil {
 		errorMsg := "Failed to unmarshal identity attributes."
 		logger.Debug(errorMsg, log.Error(err))
 		serverError := errors2.NewServerError(errors2.ErrorMessage{
@@ -88,8 +100,21 @@ func scanProfileConsentRow(row map[string]interface{}) (model.ConsentRecord, err
 	return profileConsent, nil
 }
 
-// InsertProfile inserts a new profile into the database
-func InsertProfile(profile model.Profile) error {
+// InsertProfile inserts a new profile into the database and returns the persisted profile. When a profile with the
+// same id already exists in the organization, it is merged into as described in InsertProfileContext.
+func InsertProfile(profile model.Profile) (*model.Profile, error) {
+
+	persisted, _, err := InsertProfileContext(context.Background(), profile)
+	return persisted, err
+}
+
+// InsertProfileContext inserts a profile like InsertProfile, running its queries in a single transaction as part of
+// the context. A profile with the same id that was added concurrently is merged into instead of failing the insert,
+// in which case inserted is false and the merged profile is returned.
+func InsertProfileContext(ctx context.Context, profile model.Profile) (_ *model.Profile, inserted bool, err error) {
+
+	ctx, span := tracing.Start(ctx, "store.InsertProfile", tracing.ProfileIdKey.String(profile.ProfileId))
+	defer func() { tracing.End(span, err) }()
 
 	dbClient, err := provider.NewDBProvider().GetDBClient()
 	logger := log.GetLogger()
@@ -101,7 +126,7 @@ func InsertProfile(profile model.Profile) error {
 			Message:     errors2.ADD_PROFILE.Message,
 			Description: errorMsg,
 		}, err)
-		return serverError
+		return nil, false, serverError
 	}
 	defer dbClient.Close()
 
@@ -118,68 +143,94 @@ func InsertProfile(profile model.Profile) error {
 		profileStatus = constants.MergedTo
 	}
 
-	query := scripts.InsertProfile[provider.NewDBProvider().GetDBType()]
-
-	_, err = dbClient.ExecuteQuery(query,
-		profile.ProfileId,
-		profile.UserId,
-		profile.OrgHandle,
-		profile.CreatedAt,
-		profile.UpdatedAt,
-		profile.Location,
-		profile.ProfileStatus.ListProfile,
-		false, // delete_profile is not used in this context, set to false
-		traitsJSON,
-		identityJSON,
-	)
-
-	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to insert profile with Id: %s", profile.ProfileId)
-		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
+	fail := func(errorMsg string, cause error) error {
+		logger.Debug(errorMsg, log.Error(cause))
+		return errors2.NewServerError(errors2.ErrorMessage{
 			Code:        errors2.ADD_PROFILE.Code,
 			Message:     errors2.ADD_PROFILE.Message,
 			Description: errorMsg,
-		}, err)
-		return serverError
-	}
+		}, cause)
+	}
+
+	// The profile, its reference and its application data are written together, so that a cancelled request never
+	// leaves a profile behind without its reference.
+	dbType := provider.NewDBProvider().GetDBType()
+	var persisted model.Profile
+	err = dbClient.RunInTxContext(ctx, func(tx *sql.Tx) error {
+		results, err := client.QueryInTx(ctx, tx, scripts.InsertProfile[dbType],
+			profile.ProfileId,
+			profile.UserId,
+			profile.OrgHandle,
+			profile.CreatedAt,
+			profile.UpdatedAt,
+			profile.Location,
+			profile.ProfileStatus.ListProfile,
+			false, // delete_profile is not used in this context, set to false
+			traitsJSON,
+			identityJSON,
+		)
+		if err != nil {
+			return fail(fmt.Sprintf("Failed to insert profile with Id: %s", profile.ProfileId), err)
+		}
+		if len(results) == 0 {
+			// The id is taken by a deleted profile or one of another organization. The description does not tell
+			// which, so that callers can't learn about the profiles of other organizations.
+			logger.Debug(fmt.Sprintf("Profile id: %s is taken by a profile that can't be merged into",
+				profile.ProfileId))
+			return errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.ADD_PROFILE.Code,
+				Message:     errors2.ADD_PROFILE.Message,
+				Description: fmt.Sprintf("The profile id: %s is not available.", profile.ProfileId),
+			}, http.StatusConflict)
+		}
 
-	referenceQuery := scripts.InsertProfileReference[provider.NewDBProvider().GetDBType()]
+		// The returned row carries the profile columns only, so the status is taken from the inserted reference.
+		row := results[0]
+		row["profile_status"] = profileStatus
+		row["reference_profile_id"] = profile.ProfileStatus.ReferenceProfileId
+		row["reference_reason"] = profile.ProfileStatus.ReferenceReason
+		inserted, _ = row["inserted"].(bool)
+		persisted, err = scanProfileRow(row)
+		if err != nil {
+			return err
+		}
 
-	_, err = dbClient.ExecuteQuery(referenceQuery,
-		profile.ProfileId,
-		profileStatus,
-		profile.ProfileStatus.ReferenceProfileId,
-		profile.ProfileStatus.ReferenceReason,
-		profile.OrgHandle,
-		profile.OrgHandle,
-	)
+		if _, err := tx.ExecContext(ctx, scripts.InsertProfileReference[dbType],
+			profile.ProfileId,
+			profileStatus,
+			profile.ProfileStatus.ReferenceProfileId,
+			profile.ProfileStatus.ReferenceReason,
+			profile.OrgHandle,
+			profile.OrgHandle,
+		); err != nil {
+			return fail(fmt.Sprintf("Failed to insert profile reference with Id: %s", profile.ProfileId), err)
+		}
 
-	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to insert profile referencea with Id: %s", profile.ProfileId)
-		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.ADD_PROFILE.Code,
-			Message:     errors2.ADD_PROFILE.Message,
-			Description: errorMsg,
-		}, err)
-		return serverError
+		for _, app := range profile.ApplicationData {
+			if err := upsertAppDatumInTx(ctx, tx, profile.ProfileId, app); err != nil {
+				return fail(fmt.Sprintf("Failed to insert application data of app: %s for profile: %s",
+					app.AppId, profile.ProfileId), err)
+			}
+			// Writing the application data bumps the version of the profile.
+			persisted.Version++
+		}
+		return nil
+	})
+	switch err.(type) {
+	case nil:
+	case *errors2.ServerError, *errors2.ClientError:
+		return nil, false, err
+	default:
+		return nil, false, fail(fmt.Sprintf("Failed to commit profile with Id: %s", profile.ProfileId), err)
 	}
 
-	err = InsertApplicationData(profile.ProfileId, profile.ApplicationData)
-	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to insert profile with Id: %s", profile.ProfileId)
-		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.ADD_PROFILE.Code,
-			Message:     errors2.ADD_PROFILE.Message,
-			Description: errorMsg,
-		}, err)
-		return serverError
+	persisted.ApplicationData = profile.ApplicationData
+	if !inserted {
+		logger.Info("Profile added concurrently, merged into the existing profile: " + profile.ProfileId)
+		return &persisted, false, nil
 	}
-
 	logger.Info("Profile added successfully: " + profile.ProfileId)
-	return nil
+	return &persisted, true, nil
 }
 
 func InsertApplicationData(profileId string, apps []model.ApplicationData) error {
@@ -211,11 +262,106 @@ func InsertApplicationData(profileId string, apps []model.ApplicationData) error
 	return nil
 }
 
+// upsertAppDatumInTx merges the application data of the app into the data stored for the profile, like
+// UpsertAppDatum does, as part of the transaction.
+func upsertAppDatumInTx(ctx context.Context, tx *sql.Tx, profileId string, app model.ApplicationData) error {
+
+	dbType := provider.NewDBProvider().GetDBType()
+	results, err := client.QueryInTx(ctx, tx, scripts.GetAppDataByAppId[dbType], profileId, app.AppId)
+	if err != nil {
+		return err
+	}
+	var stored model.ApplicationData
+	for _, row := range results {
+		if err := utils.UnmarshalJSON(row["application_data"].([]byte), &stored); err != nil {
+			return err
+		}
+	}
+	if stored.AppSpecificData == nil {
+		stored.AppSpecificData = make(map[string]interface{})
+	}
+	for key, incomingVal := range app.AppSpecificData {
+		stored.AppSpecificData[key] = enrichFieldValues(stored.AppSpecificData[key], incomingVal)
+	}
+
+	appDataJSON, err := json.Marshal(struct {
+		AppSpecificData map[string]interface{} `json:"app_specific_data,omitempty"`
+	}{AppSpecificData: stored.AppSpecificData})
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, scripts.InsertApplicationData[dbType], profileId, app.AppId, appDataJSON,
+		time.Now().UTC())
+	return err
+}
+
 // GetProfile retrieves a profile by its Id
 func GetProfile(profileId string) (*model.Profile, error) {
 
+	return GetProfileContext(context.Background(), profileId)
+}
+
+// GetProfileContext retrieves a profile like GetProfile, running its queries as part of the context.
+func GetProfileContext(ctx context.Context, profileId string) (*model.Profile, error) {
+
+	return getProfile(ctx, provider.NewDBProvider().GetDBClient, profileId)
+}
+
+// GetProfileFromReadReplica retrieves a profile by its Id through the read client. The result may lag behind
+// recent writes, so it must not be used to read back a profile that was just written.
+func GetProfileFromReadReplica(profileId string) (*model.Profile, error) {
+
+	return GetProfileFromReadReplicaContext(context.Background(), profileId)
+}
+
+// GetProfileFromReadReplicaContext retrieves a profile like GetProfileFromReadReplica, running its queries as part
+// of the context.
+func GetProfileFromReadReplicaContext(ctx context.Context, profileId string) (*model.Profile, error) {
+
+	return getProfile(ctx, provider.NewDBProvider().GetReadDBClient, profileId)
+}
+
+// GetProfileOrgHandle returns the organization of the profile, or an empty string if there is no such profile.
+func GetProfileOrgHandle(profileId string) (string, error) {
+
 	dbClient, err := provider.NewDBProvider().GetDBClient()
 	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client while fetching organization of profile: %s", profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return "", errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.GetProfileOrgHandle[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, profileId)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to fetch organization of profile: %s", profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return "", errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if len(results) == 0 {
+		return "", nil
+	}
+	return results[0]["org_handle"].(string), nil
+}
+
+func getProfile(ctx context.Context, getDBClient func() (client.DBClientInterface, error),
+	profileId string) (_ *model.Profile, err error) {
+
+	ctx, span := tracing.Start(ctx, "store.GetProfile", tracing.ProfileIdKey.String(profileId))
+	defer func() { tracing.End(span, err) }()
+
+	dbClient, err := getDBClient()
+	logger := log.GetLogger()
 	if err != nil {
 		errorMsg := fmt.Sprintf("Failed to get db client while fetching profile with Id: %s", profileId)
 		logger.Debug(errorMsg, log.Error(err))
@@ -230,13 +376,22 @@ func GetProfile(profileId string) (*model.Profile, error) {
 
 	query := scripts.GetProfileById[provider.NewDBProvider().GetDBType()]
 
-	results, err := dbClient.ExecuteQuery(query, profileId)
+	results, err := dbClient.ExecuteQueryContext(ctx, query, profileId)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		logger.Debug(fmt.Sprintf("No profile found with the given Id: %s", profileId))
 		// todo: should we return a client error with 404 here?
 		return nil, nil
 	}
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed fetching profile with Id: %s", profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
 	if len(results) == 0 {
 		logger.Debug(fmt.Sprintf("No profile found with the given Id: %s", profileId))
 		// todo: should we return a client error with 404 here?
@@ -253,7 +408,37 @@ func GetProfile(profileId string) (*model.Profile, error) {
 		}, err)
 		return nil, serverError
 	}
-	profile.ApplicationData, _ = FetchApplicationData(profileId)
+	profile.ApplicationData, err = fetchApplicationData(dbClient, profileId)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// LockProfile fetches the profile without its application data and locks it for the rest of the transaction, so that
+// it is not merged, unmerged or deleted concurrently. It returns nil if there is no such live profile.
+func LockProfile(tx *sql.Tx, profileId string) (*model.Profile, error) {
+
+	logger := log.GetLogger()
+	query := scripts.LockProfileById[provider.NewDBProvider().GetDBType()]
+	results, err := client.QueryInTx(context.Background(), tx, query, profileId)
+	if err == nil && len(results) == 0 {
+		logger.Debug(fmt.Sprintf("No profile found with the given Id: %s", profileId))
+		return nil, nil
+	}
+	var profile model.Profile
+	if err == nil {
+		profile, err = scanProfileRow(results[0])
+	}
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed locking profile with Id: %s", profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
 	return &profile, nil
 }
 
@@ -283,9 +468,21 @@ func GetProfileConsents(profileId string) ([]model.ConsentRecord, error) {
 		// todo: should we return a client error with 404 here?
 		return nil, nil
 	}
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed fetching profile consents with Id: %s", profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
 	if len(results) == 0 {
 		logger.Debug(fmt.Sprintf("No profile found with the given Id: %s", profileId))
-		var profile, _ = GetProfile(profileId)
+		profile, err := GetProfile(profileId)
+		if err != nil {
+			return nil, err
+		}
 		if profile != nil {
 			// If no consents found and the user exists, return an empty slice instead of nil
 			return []model.ConsentRecord{}, nil
@@ -328,6 +525,12 @@ func FetchApplicationData(profileId string) ([]model.ApplicationData, error) {
 		return nil, serverError
 	}
 	defer dbClient.Close()
+	return fetchApplicationData(dbClient, profileId)
+}
+
+func fetchApplicationData(dbClient client.DBClientInterface, profileId string) ([]model.ApplicationData, error) {
+
+	logger := log.GetLogger()
 	query := scripts.GetAppDataByProfileId[provider.NewDBProvider().GetDBType()]
 	results, err := dbClient.ExecuteQuery(query, profileId)
 	if err != nil {
@@ -352,7 +555,7 @@ func FetchApplicationData(profileId string) ([]model.ApplicationData, error) {
 		appId = row["app_id"].(string)
 		appBlob = row["application_data"].([]byte)
 
-		if err := json.Unmarshal(appBlob, &appParsed); err != nil {
+		if err := utils.UnmarshalJSON(appBlob, &appParsed); err != nil {
 			errorMsg := fmt.Sprintf("Failed to un marshal application data for profile with Id: %s", profileId)
 			logger.Debug(errorMsg, log.Error(err))
 			serverError := errors2.NewServerError(errors2.ErrorMessage{
@@ -410,7 +613,7 @@ func FetchApplicationDataWithAppId(profileId string, appId string) (model.Applic
 		appId = row["app_id"].(string)
 		appBlob = row["application_data"].([]byte)
 
-		if err := json.Unmarshal(appBlob, &appParsed); err != nil {
+		if err := utils.UnmarshalJSON(appBlob, &appParsed); err != nil {
 			errorMsg := fmt.Sprintf("Failed unmarshalling application data of app:%s for profile: %s", appId, profileId)
 			logger.Debug(errorMsg, log.Error(err))
 			serverError := errors2.NewServerError(errors2.ErrorMessage{
@@ -427,9 +630,19 @@ func FetchApplicationDataWithAppId(profileId string, appId string) (model.Applic
 	return app, nil
 }
 
-// UpdateProfile updates the profile
+// UpdateProfile updates the profile. When the profile carries a version, the update only applies if the stored
+// profile is still at that version, and ErrProfileVersionConflict is returned otherwise.
 func UpdateProfile(profile model.Profile) error {
 
+	return UpdateProfileContext(context.Background(), profile)
+}
+
+// UpdateProfileContext updates a profile like UpdateProfile, running its queries as part of the context.
+func UpdateProfileContext(ctx context.Context, profile model.Profile) (err error) {
+
+	ctx, span := tracing.Start(ctx, "store.UpdateProfile", tracing.ProfileIdKey.String(profile.ProfileId))
+	defer func() { tracing.End(span, err) }()
+
 	dbClient, err := provider.NewDBProvider().GetDBClient()
 	logger := log.GetLogger()
 	if err != nil {
@@ -444,6 +657,40 @@ func UpdateProfile(profile model.Profile) error {
 	}
 	defer dbClient.Close()
 
+	// The profile, its reference and its application data are written together, so that a failure part way never
+	// leaves a profile with a reference or application data that don't match it.
+	err = dbClient.RunInTxContext(ctx, func(tx *sql.Tx) error {
+		return updateProfileInTx(ctx, tx, profile)
+	})
+	if errors.Is(err, model.ErrProfileVersionConflict) {
+		return err
+	}
+	if _, reported := err.(*errors2.ServerError); err != nil && !reported {
+		errorMsg := fmt.Sprintf("Failed to commit the update of profile: %s", profile.ProfileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return err
+}
+
+// updateProfileInTx writes the profile, its reference and its application data as part of the transaction, like
+// UpdateProfileContext does. ErrProfileVersionConflict is returned as is when the profile is no longer at its version.
+func updateProfileInTx(ctx context.Context, tx *sql.Tx, profile model.Profile) error {
+
+	logger := log.GetLogger()
+	fail := func(errorMsg string, cause error) error {
+		logger.Debug(errorMsg, log.Error(cause))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, cause)
+	}
+
 	traitsJSON, _ := json.Marshal(profile.Traits)
 	identityJSON, _ := json.Marshal(profile.IdentityAttributes)
 
@@ -458,9 +705,8 @@ func UpdateProfile(profile model.Profile) error {
 		profileStatus = constants.MergedTo
 	}
 
-	query := scripts.UpdateProfile[provider.NewDBProvider().GetDBType()]
-
-	_, err = dbClient.ExecuteQuery(query,
+	dbType := provider.NewDBProvider().GetDBType()
+	results, err := client.QueryInTx(ctx, tx, scripts.UpdateProfile[dbType],
 		profile.UserId,
 		profile.ProfileStatus.ListProfile,
 		profile.ProfileStatus.DeleteProfile,
@@ -468,58 +714,41 @@ func UpdateProfile(profile model.Profile) error {
 		identityJSON,
 		profile.UpdatedAt,
 		profile.ProfileId,
+		profile.Version,
 	)
 	if err != nil {
-		errorMsg := fmt.Sprintf("Failed updating the profile: %s", profile.ProfileId)
-		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.UPDATE_PROFILE.Code,
-			Message:     errors2.UPDATE_PROFILE.Message,
-			Description: errorMsg,
-		}, err)
-		return serverError
+		return fail(fmt.Sprintf("Failed updating the profile: %s", profile.ProfileId), err)
+	}
+	if len(results) == 0 && profile.Version != 0 {
+		logger.Debug(fmt.Sprintf("Profile: %s is no longer at version: %d", profile.ProfileId, profile.Version))
+		return model.ErrProfileVersionConflict
 	}
 
-	query = scripts.UpsertProfileReference[provider.NewDBProvider().GetDBType()]
-
-	_, err = dbClient.ExecuteQuery(query,
+	if _, err := tx.ExecContext(ctx, scripts.UpsertProfileReference[dbType],
 		profile.ProfileId,
 		profileStatus,
 		profile.ProfileStatus.ReferenceProfileId,
 		profile.ProfileStatus.ReferenceReason,
 		profile.ProfileId,
-	)
-	if err != nil {
-		errorMsg := fmt.Sprintf("Failed updating the profile: %s", profile.ProfileId)
-		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.UPDATE_PROFILE.Code,
-			Message:     errors2.UPDATE_PROFILE.Message,
-			Description: errorMsg,
-		}, err)
-		return serverError
-	}
-	// Update application data
-	err = InsertApplicationData(profile.ProfileId, profile.ApplicationData)
-	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to insert profile with Id: %s", profile.ProfileId)
-		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.ADD_PROFILE.Code,
-			Message:     errors2.ADD_PROFILE.Message,
-			Description: errorMsg,
-		}, err)
-		return serverError
+	); err != nil {
+		return fail(fmt.Sprintf("Failed updating the reference of profile: %s", profile.ProfileId), err)
 	}
 
+	for _, app := range profile.ApplicationData {
+		if err := upsertAppDatumInTx(ctx, tx, profile.ProfileId, app); err != nil {
+			return fail(fmt.Sprintf("Failed to update application data of app: %s for profile: %s",
+				app.AppId, profile.ProfileId), err)
+		}
+	}
 	return nil
 }
 
 // GetAllProfiles retrieves profiles using cursor-based pagination.
 // It returns up to `limit` profiles and a boolean indicating if more records exist.
-func GetAllProfiles(orgHandle string, limit int, cursor *model.ProfileCursor) ([]model.Profile, bool, error) {
+// Soft-deleted profiles are skipped unless includeDeleted is set.
+func GetAllProfiles(orgHandle string, includeDeleted bool, limit int, cursor *model.ProfileCursor) ([]model.Profile, bool, error) {
 
-	dbClient, err := provider.NewDBProvider().GetDBClient()
+	dbClient, err := provider.NewDBProvider().GetReadDBClient()
 	logger := log.GetLogger()
 	if err != nil {
 		errorMsg := "Failed to get database client for fetching all profiles"
@@ -560,7 +789,8 @@ func GetAllProfiles(orgHandle string, limit int, cursor *model.ProfileCursor) ([
 	// lookahead
 	limitPlusOne := limit + 1
 
-	results, err := dbClient.ExecuteQuery(query, orgHandle, cursorTime, cursorProfileId, direction, limitPlusOne)
+	results, err := dbClient.ExecuteQuery(query, orgHandle, cursorTime, cursorProfileId, direction, limitPlusOne,
+		includeDeleted)
 	if err != nil {
 		errorMsg := "Failed fetching all profiles"
 		logger.Debug(errorMsg, log.Error(err))
@@ -661,7 +891,7 @@ func FetchApplicationDataBatch(profileIDs []string) (map[string][]model.Applicat
 		appBlob := row["application_data"].([]byte)
 
 		var parsed model.ApplicationData
-		if err := json.Unmarshal(appBlob, &parsed); err != nil {
+		if err := utils.UnmarshalJSON(appBlob, &parsed); err != nil {
 			errorMsg := fmt.Sprintf("Failed to unmarshal application data for profile: %s", pid)
 			logger.Debug(errorMsg, log.Error(err))
 			return nil, errors2.NewServerError(errors2.ErrorMessage{
@@ -758,71 +988,510 @@ func DeleteProfile(profileId string) error {
 	return nil
 }
 
-func UpsertAppDatum(profileId string, appId string, updates map[string]interface{}) error {
-
-	// Fetch existing application_data for the given app
-	appData, err := FetchApplicationDataWithAppId(profileId, appId)
-	if err != nil {
-		return err
-	}
-
-	if appData.AppSpecificData == nil {
-		appData.AppSpecificData = make(map[string]interface{})
-	}
+// SoftDeleteProfile marks a profile as deleted within the given transaction, without removing its data so that it
+// can be restored until purged.
+func SoftDeleteProfile(tx *sql.Tx, profileId string, deletedAt time.Time) error {
 
 	logger := log.GetLogger()
-	// Separate handling for "devices" key (top-level)
-	for key, incomingVal := range updates {
-		actualKey := strings.TrimPrefix(key, "application_data.")
-		// Merge into app_specific_data
-		existingVal := appData.AppSpecificData[actualKey]
-		log.GetLogger().Info("Merging key: " + actualKey)
-		appData.AppSpecificData[actualKey] = enrichFieldValues(existingVal, incomingVal)
-
-	}
-
-	// Final wrapper for marshaling
-	type ApplicationDataJSON struct {
-		AppSpecificData map[string]interface{} `json:"app_specific_data,omitempty"`
-	}
-
-	wrapper := ApplicationDataJSON{
-		AppSpecificData: appData.AppSpecificData,
-	}
-
-	jsonBytes, err := json.Marshal(wrapper)
-	errorMsg := fmt.Sprintf("Failed to marshal devices for application data for profile: %s", profileId)
+	query := scripts.SoftDeleteProfile[provider.NewDBProvider().GetDBType()]
+	_, err := tx.Exec(query, deletedAt, profileId)
 	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to soft delete profile: %s", profileId)
 		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.UPDATE_APP_DATA.Code,
-			Message:     errors2.UPDATE_APP_DATA.Message,
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_PROFILE.Code,
+			Message:     errors2.DELETE_PROFILE.Message,
 			Description: errorMsg,
 		}, err)
-		return serverError
 	}
+	logger.Info(fmt.Sprintf("Profile: %s marked as deleted", profileId))
+	return nil
+}
 
-	// Upsert into application_data table
-	query := scripts.InsertApplicationData[provider.NewDBProvider().GetDBType()]
+// GetDeletedProfile retrieves a soft-deleted profile by its Id. Returns nil if there is no such deleted profile.
+func GetDeletedProfile(profileId string) (*model.Profile, error) {
 
 	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
 	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to get database client for upserting application data for profile: %s", profileId)
+		errorMsg := fmt.Sprintf("Failed to get db client while fetching deleted profile with Id: %s", profileId)
 		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.UPDATE_APP_DATA.Code,
-			Message:     errors2.UPDATE_APP_DATA.Message,
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
 			Description: errorMsg,
 		}, err)
-		return serverError
 	}
 	defer dbClient.Close()
 
-	_, err = dbClient.ExecuteQuery(query, profileId, appId, jsonBytes)
+	query := scripts.GetDeletedProfileById[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, profileId)
 	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to upsert application data for profile: %s", profileId)
+		errorMsg := fmt.Sprintf("Failed fetching deleted profile with Id: %s", profileId)
 		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if len(results) == 0 {
+		logger.Debug(fmt.Sprintf("No deleted profile found with the given Id: %s", profileId))
+		return nil, nil
+	}
+	profile, err := scanProfileRow(results[0])
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// RestoreProfiles clears the deletion mark of the reference profile and its referring profiles that were deleted
+// at the given time, and returns the restored profile ids.
+func RestoreProfiles(referenceProfileId string, deletedAt time.Time) ([]string, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed getting db client for restoring the profile: %s", referenceProfileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.RESTORE_PROFILE.Code,
+			Message:     errors2.RESTORE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.RestoreProfiles[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, referenceProfileId, deletedAt, time.Now().UTC())
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to restore profile: %s", referenceProfileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.RESTORE_PROFILE.Code,
+			Message:     errors2.RESTORE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	restored := make([]string, 0, len(results))
+	for _, row := range results {
+		if id, ok := row["profile_id"].(string); ok {
+			restored = append(restored, id)
+		}
+	}
+	return restored, nil
+}
+
+// PurgeDeletedProfiles permanently removes the profiles soft-deleted before the given time along with their
+// associated data, and returns the number of purged profiles.
+func PurgeDeletedProfiles(deletedBefore time.Time) (int64, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed getting db client for purging deleted profiles."
+		logger.Debug(errorMsg, log.Error(err))
+		return 0, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_PROFILE.Code,
+			Message:     errors2.DELETE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.PurgeDeletedProfiles[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, deletedBefore)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to purge profiles deleted before: %s", deletedBefore.Format(time.RFC3339))
+		logger.Debug(errorMsg, log.Error(err))
+		return 0, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_PROFILE.Code,
+			Message:     errors2.DELETE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return int64(len(results)), nil
+}
+
+// PromoteOrphanedProfiles turns the merged profiles of an organization whose reference profile no longer exists
+// into reference profiles, and returns their ids. Only the given profile is considered when profileId is not empty.
+func PromoteOrphanedProfiles(orgHandle, profileId string) ([]string, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed getting db client for promoting orphaned profiles of: %s", orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ORPHANED_PROFILE.Code,
+			Message:     errors2.ORPHANED_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.PromoteOrphanedProfiles[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, orgHandle, constants.ReferenceProfile, constants.MergedTo, profileId,
+		time.Now().UTC())
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to promote orphaned profiles of: %s", orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ORPHANED_PROFILE.Code,
+			Message:     errors2.ORPHANED_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	promoted := make([]string, 0, len(results))
+	for _, row := range results {
+		promoted = append(promoted, row["profile_id"].(string))
+	}
+	return promoted, nil
+}
+
+// IncrementProfileAttributeInTx atomically adds delta to the numeric attribute at the given path of the traits or
+// identity_attributes scope as part of the transaction and returns the new value. Returns nil if the profile does not
+// exist.
+func IncrementProfileAttributeInTx(tx *sql.Tx, profileId, scope string, path []string,
+	delta float64) (*float64, error) {
+
+	logger := log.GetLogger()
+	query := scripts.IncrementProfileTrait[provider.NewDBProvider().GetDBType()]
+	if scope == constants.IdentityAttributes {
+		query = scripts.IncrementProfileIdentityAttribute[provider.NewDBProvider().GetDBType()]
+	}
+	results, err := client.QueryInTx(context.Background(), tx, query, profileId, pq.Array(path), delta,
+		time.Now().UTC())
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to increment attribute: %s.%s of profile: %s", scope,
+			strings.Join(path, "."), profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	raw, _ := results[0]["value"].(string)
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Invalid value: %s after incrementing attribute of profile: %s", raw, profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return &value, nil
+}
+
+// SetRecomputedTraitsInTx stores the traits of the profile with its computed traits evaluated again, as part of the
+// transaction that updated the profile.
+func SetRecomputedTraitsInTx(tx *sql.Tx, profileId string, traits map[string]interface{}) error {
+
+	traitsJSON, err := json.Marshal(traits)
+	if err == nil {
+		_, err = tx.Exec(scripts.SetRecomputedTraits[provider.NewDBProvider().GetDBType()], profileId, traitsJSON)
+	}
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to store the computed traits of profile: %s", profileId)
+		log.GetLogger().Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return nil
+}
+
+// SoftDeleteProfilesByFilter marks the profiles matching the filters as deleted within a single transaction and
+// returns the ids of the profiles deleted. Deleting a reference profile deletes the profiles merged to it as well,
+// while deleting a merged profile detaches it from its reference profile, which is deleted once no merged profiles
+// remain.
+func SoftDeleteProfilesByFilter(orgHandle string, filters []string, deletedAt time.Time) ([]string, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed getting db client for deleting profiles by filter."
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_PROFILE.Code,
+			Message:     errors2.DELETE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	filterQuery, err := buildProfileFilterQuery(orgHandle, filters)
+	if err != nil {
+		return nil, err
+	}
+	conditions := append(filterQuery.conditions, "p.deleted_at IS NULL")
+	selectQuery := scripts.GetProfileHierarchyWithFilterBase[provider.NewDBProvider().GetDBType()] +
+		filterQuery.joins + " WHERE " + strings.Join(conditions, " AND ")
+
+	fail := func(errorMsg string, cause error) error {
+		logger.Debug(errorMsg, log.Error(cause))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_PROFILE.Code,
+			Message:     errors2.DELETE_PROFILE.Message,
+			Description: errorMsg,
+		}, cause)
+	}
+
+	var deleted []string
+	err = dbClient.RunInTx(func(tx *sql.Tx) error {
+		deleted = nil
+		// matched profile id -> reference profile id, empty for reference profiles
+		matched := make(map[string]string)
+		rows, err := tx.Query(selectQuery, filterQuery.args...)
+		if err != nil {
+			return fail("Failed to fetch profiles matching the filter for deletion.", err)
+		}
+		for rows.Next() {
+			var profileId string
+			var status, referenceProfileId sql.NullString
+			if err := rows.Scan(&profileId, &status, &referenceProfileId); err != nil {
+				_ = rows.Close()
+				return fail("Failed to read profiles matching the filter for deletion.", err)
+			}
+			if status.String == constants.MergedTo {
+				matched[profileId] = referenceProfileId.String
+			} else {
+				matched[profileId] = ""
+			}
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return fail("Failed to read profiles matching the filter for deletion.", err)
+		}
+
+		liveReferences := func(referenceProfileId string) ([]string, error) {
+			query := scripts.FetchReferencedProfiles[provider.NewDBProvider().GetDBType()]
+			refRows, err := tx.Query(query, referenceProfileId)
+			if err != nil {
+				return nil, err
+			}
+			defer refRows.Close()
+			var ids []string
+			for refRows.Next() {
+				var profileId, reason, status string
+				if err := refRows.Scan(&profileId, &reason, &status); err != nil {
+					return nil, err
+				}
+				if profileId != referenceProfileId {
+					ids = append(ids, profileId)
+				}
+			}
+			return ids, refRows.Err()
+		}
+
+		toDelete := make(map[string]bool)
+		affectedParents := make(map[string]bool)
+		for profileId, referenceProfileId := range matched {
+			toDelete[profileId] = true
+			if referenceProfileId == "" {
+				// Deleting a reference profile removes the profiles merged to it.
+				children, err := liveReferences(profileId)
+				if err != nil {
+					return fail(fmt.Sprintf("Failed to fetch merged profiles of profile: %s", profileId), err)
+				}
+				for _, child := range children {
+					toDelete[child] = true
+				}
+			} else {
+				affectedParents[referenceProfileId] = true
+			}
+		}
+		// A reference profile left without any merged profiles is deleted along with its last child.
+		for parentId := range affectedParents {
+			if toDelete[parentId] {
+				continue
+			}
+			children, err := liveReferences(parentId)
+			if err != nil {
+				return fail(fmt.Sprintf("Failed to fetch merged profiles of profile: %s", parentId), err)
+			}
+			remaining := 0
+			for _, child := range children {
+				if !toDelete[child] {
+					remaining++
+				}
+			}
+			if remaining == 0 {
+				toDelete[parentId] = true
+			}
+		}
+
+		deleteQuery := scripts.SoftDeleteProfile[provider.NewDBProvider().GetDBType()]
+		for profileId := range toDelete {
+			result, err := tx.Exec(deleteQuery, deletedAt, profileId)
+			if err != nil {
+				return fail(fmt.Sprintf("Failed to soft delete profile: %s", profileId), err)
+			}
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return fail(fmt.Sprintf("Failed to soft delete profile: %s", profileId), err)
+			}
+			if affected > 0 {
+				deleted = append(deleted, profileId)
+			}
+		}
+		return nil
+	})
+	if _, reported := err.(*errors2.ServerError); err != nil && !reported {
+		return nil, fail("Failed to commit deletion of profiles by filter.", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	logger.Info(fmt.Sprintf("%d profiles of organization: %s marked as deleted by filter", len(deleted), orgHandle))
+	return deleted, nil
+}
+
+// PatchProfileTraitsByFilter merges the traits into the top-level traits of the reference profiles matching the
+// filters with a single update within a transaction, and returns the ids of the profiles changed. The matched rows are
+// locked and their version bumped as in UpdateProfileContext, so a concurrent update expecting an earlier version
+// fails with a conflict instead of overwriting the patch.
+func PatchProfileTraitsByFilter(orgHandle string, filters []string, traits map[string]interface{},
+	updatedAt time.Time) ([]string, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed getting db client for patching profiles by filter."
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	filterQuery, err := buildProfileFilterQuery(orgHandle, filters)
+	if err != nil {
+		return nil, err
+	}
+	traitsJSON, err := json.Marshal(traits)
+	if err != nil {
+		errorMsg := "Failed to marshal the traits for patching profiles by filter."
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	conditions := append(filterQuery.conditions, "r.profile_status = 'REFERENCE_PROFILE'", "p.deleted_at IS NULL")
+	query := fmt.Sprintf(scripts.PatchProfileTraitsByFilter[provider.NewDBProvider().GetDBType()],
+		filterQuery.argID, filterQuery.argID+1, filterQuery.joins, strings.Join(conditions, " AND "))
+	args := append(filterQuery.args, string(traitsJSON), updatedAt)
+
+	var patched []string
+	err = dbClient.RunInTx(func(tx *sql.Tx) error {
+		patched = nil
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var profileId string
+			if err := rows.Scan(&profileId); err != nil {
+				return err
+			}
+			patched = append(patched, profileId)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to patch the traits of profiles of organization: %s by filter", orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	logger.Info(fmt.Sprintf("%d profiles of organization: %s patched by filter", len(patched), orgHandle))
+	return patched, nil
+}
+
+func UpsertAppDatum(profileId string, appId string, updates map[string]interface{}) error {
+
+	// Fetch existing application_data for the given app
+	appData, err := FetchApplicationDataWithAppId(profileId, appId)
+	if err != nil {
+		return err
+	}
+
+	if appData.AppSpecificData == nil {
+		appData.AppSpecificData = make(map[string]interface{})
+	}
+
+	logger := log.GetLogger()
+	// Separate handling for "devices" key (top-level)
+	for key, incomingVal := range updates {
+		actualKey := strings.TrimPrefix(key, "application_data.")
+		// Merge into app_specific_data
+		existingVal := appData.AppSpecificData[actualKey]
+		log.GetLogger().Info("Merging key: " + actualKey)
+		appData.AppSpecificData[actualKey] = enrichFieldValues(existingVal, incomingVal)
+
+	}
+
+	// Final wrapper for marshaling
+	type ApplicationDataJSON struct {
+		AppSpecificData map[string]interface{} `json:"app_specific_data,omitempty"`
+	}
+
+	wrapper := ApplicationDataJSON{
+		AppSpecificData: appData.AppSpecificData,
+	}
+
+	jsonBytes, err := json.Marshal(wrapper)
+	errorMsg := fmt.Sprintf("Failed to marshal devices for application data for profile: %s", profileId)
+	if err != nil {
+		logger.Debug(errorMsg, log.Error(err))
+		serverError := errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_APP_DATA.Code,
+			Message:     errors2.UPDATE_APP_DATA.Message,
+			Description: errorMsg,
+		}, err)
+		return serverError
+	}
+
+	// Upsert into application_data table
+	query := scripts.InsertApplicationData[provider.NewDBProvider().GetDBType()]
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get database client for upserting application data for profile: %s", profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		serverError := errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_APP_DATA.Code,
+			Message:     errors2.UPDATE_APP_DATA.Message,
+			Description: errorMsg,
+		}, err)
+		return serverError
+	}
+	defer dbClient.Close()
+
+	_, err = dbClient.ExecuteQuery(query, profileId, appId, jsonBytes, time.Now().UTC())
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to upsert application data for profile: %s", profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		serverError := errors2.NewServerError(errors2.ErrorMessage{
 			Code:        errors2.UPDATE_APP_DATA.Code,
 			Message:     errors2.UPDATE_APP_DATA.Message,
 			Description: errorMsg,
@@ -833,6 +1502,102 @@ func UpsertAppDatum(profileId string, appId string, updates map[string]interface
 	return nil
 }
 
+// PatchApplicationData applies the JSON merge patch (RFC 7386) to the data of the application of the profile, creating
+// it if absent. The data is locked while the patch is applied, so concurrent patches of different keys don't overwrite
+// each other. validate is called with the stored and the patched data before the patched data is written; an error
+// from it rolls back the patch and is returned as is.
+func PatchApplicationData(profileId, appId string, patch map[string]interface{},
+	validate func(stored, patched map[string]interface{}) error) error {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get database client for patching application data for profile: %s",
+			profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_APP_DATA.Code,
+			Message:     errors2.UPDATE_APP_DATA.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	fail := func(errorMsg string, cause error) error {
+		logger.Debug(errorMsg, log.Error(cause))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_APP_DATA.Code,
+			Message:     errors2.UPDATE_APP_DATA.Message,
+			Description: errorMsg,
+		}, cause)
+	}
+
+	dbType := provider.NewDBProvider().GetDBType()
+	err = dbClient.RunInTx(func(tx *sql.Tx) error {
+		var raw []byte
+		if err := tx.QueryRow(scripts.LockApplicationData[dbType], profileId, appId).Scan(&raw); err != nil {
+			return fail(fmt.Sprintf("Failed to lock application data of app: %s for profile: %s", appId,
+				profileId), err)
+		}
+		// The patch is merged in place, so the stored data is decoded twice to keep the copy given to validate.
+		var stored, patched struct {
+			AppSpecificData map[string]interface{} `json:"app_specific_data,omitempty"`
+		}
+		if len(raw) > 0 {
+			if err := utils.UnmarshalJSON(raw, &stored); err != nil {
+				return fail(fmt.Sprintf("Invalid application data of app: %s for profile: %s", appId, profileId),
+					err)
+			}
+			if err := utils.UnmarshalJSON(raw, &patched); err != nil {
+				return fail(fmt.Sprintf("Invalid application data of app: %s for profile: %s", appId, profileId),
+					err)
+			}
+		}
+		patched.AppSpecificData, _ = applyMergePatch(patched.AppSpecificData, patch).(map[string]interface{})
+		if err := validate(stored.AppSpecificData, patched.AppSpecificData); err != nil {
+			return err
+		}
+		jsonBytes, err := json.Marshal(patched)
+		if err != nil {
+			return fail(fmt.Sprintf("Failed to marshal application data of app: %s for profile: %s", appId,
+				profileId), err)
+		}
+		if _, err := tx.Exec(scripts.UpdateApplicationData[dbType], profileId, appId, jsonBytes,
+			time.Now().UTC()); err != nil {
+			return fail(fmt.Sprintf("Failed to patch application data of app: %s for profile: %s", appId,
+				profileId), err)
+		}
+		return nil
+	})
+	switch err.(type) {
+	case nil, *errors2.ServerError, *errors2.ClientError:
+		return err
+	}
+	return fail(fmt.Sprintf("Failed to commit application data of app: %s for profile: %s", appId, profileId), err)
+}
+
+// applyMergePatch returns the target with the merge patch applied as defined in RFC 7386: objects are merged key by
+// key, null removes a key and any other value replaces the target.
+func applyMergePatch(target interface{}, patch interface{}) interface{} {
+
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok || targetObject == nil {
+		targetObject = make(map[string]interface{}, len(patchObject))
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = applyMergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
 // DetachRefererProfileFromReference removes a child from a parent's child_profile_ids list
 func DetachRefererProfileFromReference(referenceProfileId, profileId string) error {
 
@@ -852,7 +1617,7 @@ func DetachRefererProfileFromReference(referenceProfileId, profileId string) err
 
 	// todo: decide if we need to delete the references as well.
 	query := scripts.DeleteProfileReference[provider.NewDBProvider().GetDBType()]
-	result, err := dbClient.ExecuteQuery(query, referenceProfileId, profileId)
+	result, err := dbClient.ExecuteQuery(query, referenceProfileId, profileId, time.Now().UTC())
 	if err != nil {
 		errorMsg := fmt.Sprintf("Failed to delete child relationship of child: %s of parent: %s",
 			referenceProfileId, profileId)
@@ -916,208 +1681,268 @@ func InsertMergedMasterProfileAppData(profileId string, newAppCtx model.Applicat
 			resultAppData = append(resultAppData, existing)
 		}
 	}
-
-	if !updated {
-		resultAppData = append(resultAppData, newAppCtx)
-	}
-
-	profile.ApplicationData = resultAppData
-	// this inserts the entire application_data blob with the update.
-	return InsertApplicationData(profile.ProfileId, profile.ApplicationData)
-}
-
-// InsertMergedMasterProfileTraitData replaces (PUT) the traits data inside Profile
-func InsertMergedMasterProfileTraitData(profileId string, traitsData map[string]interface{}) error {
-
-	profile, err := GetProfile(profileId)
-	logger := log.GetLogger()
-	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to fetch profile %s for trait data update.", profileId)
-		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.UPDATE_PROFILE.Code,
-			Message:     errors2.UPDATE_PROFILE.Message,
-			Description: errorMsg,
-		}, err)
-		return serverError
-	}
-	if profile == nil {
-		errorMsg := fmt.Sprintf("Profile: %s not found for trait data update", profileId)
-		logger.Debug(errorMsg, log.Error(err))
-		clientError := errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.UPDATE_PROFILE.Code,
-			Message:     errors2.UPDATE_PROFILE.Message,
-			Description: errorMsg,
-		}, err)
-		return clientError
+
+	if !updated {
+		resultAppData = append(resultAppData, newAppCtx)
 	}
 
-	profile.Traits = traitsData
-	return UpdateProfile(*profile) // Update existing profile
+	profile.ApplicationData = resultAppData
+	// this inserts the entire application_data blob with the update.
+	return InsertApplicationData(profile.ProfileId, profile.ApplicationData)
 }
 
-// MergeIdentityDataOfProfiles replaces or adds to identity_attributes in Profile
-func MergeIdentityDataOfProfiles(profileId string, identityData map[string]interface{}) error {
+// profileFilterQuery holds the SQL fragments translated from profile filters.
+type profileFilterQuery struct {
+	joins      string
+	conditions []string
+	args       []interface{}
+	argID      int // next positional parameter index
+}
 
-	profile, err := GetProfile(profileId)
-	logger := log.GetLogger()
-	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to fetch profile %s for identity data update.", profileId)
-		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.UPDATE_PROFILE.Code,
-			Message:     errors2.UPDATE_PROFILE.Message,
+// jsonPathText returns the expression extracting, as text, the value at the dotted path within the JSONB expression.
+// The path is expected to be validated as a filter key.
+func jsonPathText(jsonExpr, path string) string {
+
+	return fmt.Sprintf("(%s #>> '{%s}')", jsonExpr, strings.ReplaceAll(path, ".", ","))
+}
+
+// nestedJSONObject wraps the JSON literal of an eq filter in objects along the path, so that a containment check
+// matches the value at that path: ["address", "city"] and "Boston" give {"address":{"city":"Boston"}}.
+func nestedJSONObject(path []string, literal string) (string, error) {
+
+	if !json.Valid([]byte(literal)) {
+		errorMsg := fmt.Sprintf("Invalid filter value for key: %s", strings.Join(path, "."))
+		return "", errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.FILTER_PROFILE.Code,
+			Message:     errors2.FILTER_PROFILE.Message,
 			Description: errorMsg,
-		}, err)
-		return serverError
+		}, errors.New(errorMsg))
 	}
-	if profile == nil {
-		errorMsg := fmt.Sprintf("Profile: %s not found for identity data update", profileId)
-		logger.Debug(errorMsg, log.Error(err))
-		clientError := errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.UPDATE_PROFILE.Code,
-			Message:     errors2.UPDATE_PROFILE.Message,
-			Description: errorMsg,
-		}, err)
-		return clientError
+	object := literal
+	for i := len(path) - 1; i >= 0; i-- {
+		key, _ := json.Marshal(path[i])
+		object = "{" + string(key) + ":" + object + "}"
 	}
+	return object, nil
+}
+
+// existenceCondition returns the condition matching the profiles that have, or lack, a value for the field. An
+// attribute set to null or an empty user id counts as missing.
+func existenceCondition(field string, exists bool) (string, bool) {
 
-	if profile.IdentityAttributes == nil {
-		profile.IdentityAttributes = make(map[string]interface{})
+	if field == "user_id" {
+		if exists {
+			return "NULLIF(p.user_id, '') IS NOT NULL", true
+		}
+		return "NULLIF(p.user_id, '') IS NULL", true
 	}
-	for k, v := range identityData {
-		profile.IdentityAttributes[k] = v // Overwrites or adds
+	scopeKey := strings.SplitN(field, ".", 2)
+	if len(scopeKey) != 2 || (scopeKey[0] != "traits" && scopeKey[0] != "identity_attributes") {
+		return "", false
 	}
-
-	return UpdateProfile(*profile)
+	condition := jsonKeyCondition("p."+scopeKey[0], scopeKey[1])
+	if exists {
+		return condition, true
+	}
+	return fmt.Sprintf("NOT COALESCE(%s, false)", condition), true
 }
 
-// GetAllProfilesWithFilter retrieves profiles using dynamic filters and cursor-based pagination.
-func GetAllProfilesWithFilter(
-	orgHandle string,
-	filters []string,
-	limit int,
-	cursor *model.ProfileCursor,
-) ([]model.Profile, bool, error) {
+// jsonKeyCondition returns the condition that the JSONB expression has a non-null value at the dotted path, in the
+// forms the GIN indexes of the attributes serve: the key existence operator for a top-level key, and a JSON path
+// match for a nested one. The path is expected to be validated as a filter key.
+func jsonKeyCondition(jsonExpr, path string) string {
 
-	dbClient, err := provider.NewDBProvider().GetDBClient()
-	logger := log.GetLogger()
-	if err != nil {
-		errorMsg := "Failed to get database client filtering profiles."
-		logger.Debug(errorMsg, log.Error(err))
-		return nil, false, errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.FILTER_PROFILE.Code,
-			Message:     errors2.FILTER_PROFILE.Message,
-			Description: errorMsg,
-		}, err)
+	keys := strings.Split(path, ".")
+	if len(keys) == 1 {
+		return fmt.Sprintf("(%s ? '%s' AND %s -> '%s' <> 'null'::jsonb)", jsonExpr, path, jsonExpr, path)
 	}
-	defer dbClient.Close()
+	return fmt.Sprintf(`%s @? 'strict $."%s" ? (@.type() != "null")'`, jsonExpr, strings.Join(keys, `"."`))
+}
 
-	if limit == 0 {
-		return []model.Profile{}, false, nil
+// addRangeCondition adds the condition of a gte, lte or between filter on the expression. The bounds, joined by
+// "and" for between, are compared as timestamps when they are RFC3339 times and as numbers otherwise, as normalized
+// by the service. A JSON text expression is only cast to a number when it has the form of one, and to a timestamp
+// through try_cast_timestamptz, so that a malformed stored value does not match instead of failing the query.
+func (q *profileFilterQuery) addRangeCondition(expr string, jsonText bool, operator, value string) error {
+
+	bounds := []string{value}
+	if operator == "between" {
+		bounds = strings.SplitN(value, " and ", 2)
+		if len(bounds) != 2 {
+			errorMsg := fmt.Sprintf("Invalid bounds for between filter: %s", value)
+			return errors2.NewServerError(errors2.ErrorMessage{
+				Code:        errors2.FILTER_PROFILE.Code,
+				Message:     errors2.FILTER_PROFILE.Message,
+				Description: errorMsg,
+			}, errors.New(errorMsg))
+		}
 	}
-	if limit < 0 {
-		limit = 50
+	cast := "numeric"
+	if _, err := time.Parse(time.RFC3339Nano, bounds[0]); err == nil {
+		cast = "timestamptz"
 	}
-	if limit > 200 {
-		limit = 200
+	switch {
+	case jsonText && cast == "timestamptz":
+		expr = fmt.Sprintf("try_cast_timestamptz(%s)", expr)
+	case jsonText:
+		expr = fmt.Sprintf("(CASE WHEN %s ~ '^-?[0-9]+(\\.[0-9]+)?$' THEN %s::numeric END)", expr, expr)
 	}
 
-	baseSQL := scripts.GetAllProfilesWithFilterBase[provider.NewDBProvider().GetDBType()]
+	switch operator {
+	case "gte":
+		q.conditions = append(q.conditions, fmt.Sprintf("%s >= $%d::%s", expr, q.argID, cast))
+	case "lte":
+		q.conditions = append(q.conditions, fmt.Sprintf("%s <= $%d::%s", expr, q.argID, cast))
+	case "between":
+		q.conditions = append(q.conditions, fmt.Sprintf("%s BETWEEN $%d::%s AND $%d::%s", expr, q.argID, cast,
+			q.argID+1, cast))
+	}
+	for _, bound := range bounds {
+		q.args = append(q.args, bound)
+		q.argID++
+	}
+	return nil
+}
+
+// buildProfileFilterQuery translates "field operator value" filters into SQL joins and conditions on the
+// profiles table (aliased p) scoped to the given organization. The operators are eq, co, sw, the case-insensitive
+// eqi and nei, the range operators gte, lte and between, and exists and notexists, which take no value. Attribute
+// keys may be dotted paths into nested objects, and the value of an eq filter on an attribute is a JSON literal.
+func buildProfileFilterQuery(orgHandle string, filters []string) (*profileFilterQuery, error) {
 
-	var conditions []string
-	var args []interface{}
-	argID := 1
+	logger := log.GetLogger()
+	q := &profileFilterQuery{argID: 1}
 	joinedAppIDs := map[string]bool{}
 
-	conditions = append(conditions, fmt.Sprintf("p.org_handle = $%d", argID))
-	args = append(args, orgHandle)
-	argID++
+	q.conditions = append(q.conditions, fmt.Sprintf("p.org_handle = $%d", q.argID))
+	q.args = append(q.args, orgHandle)
+	q.argID++
 
 	// dynamic filter conditions
 	for _, f := range filters {
 		parts := strings.SplitN(f, " ", 3)
+		if len(parts) == 2 && (parts[1] == "exists" || parts[1] == "notexists") {
+			condition, ok := existenceCondition(parts[0], parts[1] == "exists")
+			if !ok {
+				return nil, unsupportedFilterError(f)
+			}
+			q.conditions = append(q.conditions, condition)
+			continue
+		}
 		if len(parts) != 3 {
 			errorMsg := fmt.Sprintf("Invalid filter format: %s", f)
 			logger.Debug(errorMsg)
-			return nil, false, errors2.NewServerError(errors2.ErrorMessage{
+			return nil, errors2.NewServerError(errors2.ErrorMessage{
 				Code:        errors2.FILTER_PROFILE.Code,
 				Message:     errors2.FILTER_PROFILE.Message,
 				Description: errorMsg,
-			}, err)
+			}, errors.New(errorMsg))
 		}
 		field, operator, value := parts[0], parts[1], parts[2]
 
 		var scope, key string
-		if field == "user_id" || field == "profile_id" {
+		if field == "user_id" || field == "profile_id" || field == "created_at" || field == "updated_at" {
 			scope = field
 			key = ""
 		} else {
 			scopeKey := strings.SplitN(field, ".", 2)
 			if len(scopeKey) != 2 {
-				continue
+				return nil, unsupportedFilterError(f)
 			}
 			scope, key = scopeKey[0], scopeKey[1]
 		}
 
+		if operator == "gte" || operator == "lte" || operator == "between" {
+			var expr string
+			switch scope {
+			case "created_at", "updated_at":
+				expr = "p." + scope
+			case "identity_attributes", "traits":
+				expr = jsonPathText("p."+scope, key)
+			default:
+				return nil, unsupportedFilterError(f)
+			}
+			if err := q.addRangeCondition(expr, scope == "identity_attributes" || scope == "traits", operator,
+				value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		switch scope {
 		case "identity_attributes", "traits":
 			jsonCol := "p." + scope
+			text := jsonPathText(jsonCol, key)
 			switch operator {
-			case "eq":
-				valBytes, err := json.Marshal(value)
+			case "eq", "contains":
+				// The value of a contains filter is an array literal, so the containment matches any element.
+				jsonObj, err := nestedJSONObject(strings.Split(key, "."), value)
 				if err != nil {
-					return nil, false, errors2.NewServerError(errors2.ErrorMessage{
-						Code:        errors2.FILTER_PROFILE.Code,
-						Message:     errors2.FILTER_PROFILE.Message,
-						Description: fmt.Sprintf("Invalid filter value for key: %s", key),
-					}, err)
+					return nil, err
 				}
-				jsonObj := fmt.Sprintf(`{"%s": %s}`, key, string(valBytes))
-				conditions = append(conditions, fmt.Sprintf("%s @> $%d::jsonb", jsonCol, argID))
-				args = append(args, jsonObj)
+				q.conditions = append(q.conditions, fmt.Sprintf("%s @> $%d::jsonb", jsonCol, q.argID))
+				q.args = append(q.args, jsonObj)
+			case "eqi":
+				q.conditions = append(q.conditions, fmt.Sprintf("LOWER(%s) = LOWER($%d)", text, q.argID))
+				q.args = append(q.args, value)
+			case "nei":
+				// Profiles without the attribute are not equal to the value either.
+				q.conditions = append(q.conditions, fmt.Sprintf("LOWER(%s) IS DISTINCT FROM LOWER($%d)", text, q.argID))
+				q.args = append(q.args, value)
 			case "co":
-				conditions = append(conditions, fmt.Sprintf("%s ->> '%s' ILIKE $%d", jsonCol, key, argID))
-				args = append(args, "%"+value+"%")
+				q.conditions = append(q.conditions, fmt.Sprintf("%s ILIKE $%d", text, q.argID))
+				q.args = append(q.args, "%"+value+"%")
 			case "sw":
-				conditions = append(conditions, fmt.Sprintf("%s ->> '%s' ILIKE $%d", jsonCol, key, argID))
-				args = append(args, value+"%")
+				q.conditions = append(q.conditions, fmt.Sprintf("%s ILIKE $%d", text, q.argID))
+				q.args = append(q.args, value+"%")
 			default:
-				continue
+				return nil, unsupportedFilterError(f)
 			}
-			argID++
+			q.argID++
 
 		case "user_id":
 			switch operator {
 			case "eq":
-				conditions = append(conditions, fmt.Sprintf("p.user_id = $%d", argID))
-				args = append(args, value)
+				q.conditions = append(q.conditions, fmt.Sprintf("p.user_id = $%d", q.argID))
+				q.args = append(q.args, value)
+			case "eqi":
+				q.conditions = append(q.conditions, fmt.Sprintf("LOWER(p.user_id) = LOWER($%d)", q.argID))
+				q.args = append(q.args, value)
+			case "nei":
+				q.conditions = append(q.conditions, fmt.Sprintf("LOWER(p.user_id) IS DISTINCT FROM LOWER($%d)", q.argID))
+				q.args = append(q.args, value)
 			case "co":
-				conditions = append(conditions, fmt.Sprintf("p.user_id ILIKE $%d", argID))
-				args = append(args, "%"+value+"%")
+				q.conditions = append(q.conditions, fmt.Sprintf("p.user_id ILIKE $%d", q.argID))
+				q.args = append(q.args, "%"+value+"%")
 			case "sw":
-				conditions = append(conditions, fmt.Sprintf("p.user_id ILIKE $%d", argID))
-				args = append(args, value+"%")
+				q.conditions = append(q.conditions, fmt.Sprintf("p.user_id ILIKE $%d", q.argID))
+				q.args = append(q.args, value+"%")
 			default:
-				continue
+				return nil, unsupportedFilterError(f)
 			}
-			argID++
+			q.argID++
 
 		case "profile_id":
 			switch operator {
 			case "eq":
-				conditions = append(conditions, fmt.Sprintf("p.profile_id = $%d", argID))
-				args = append(args, value)
+				q.conditions = append(q.conditions, fmt.Sprintf("p.profile_id = $%d", q.argID))
+				q.args = append(q.args, value)
+			case "eqi":
+				q.conditions = append(q.conditions, fmt.Sprintf("LOWER(p.profile_id) = LOWER($%d)", q.argID))
+				q.args = append(q.args, value)
+			case "nei":
+				q.conditions = append(q.conditions, fmt.Sprintf("LOWER(p.profile_id) IS DISTINCT FROM LOWER($%d)", q.argID))
+				q.args = append(q.args, value)
 			case "co":
-				conditions = append(conditions, fmt.Sprintf("p.profile_id ILIKE $%d", argID))
-				args = append(args, "%"+value+"%")
+				q.conditions = append(q.conditions, fmt.Sprintf("p.profile_id ILIKE $%d", q.argID))
+				q.args = append(q.args, "%"+value+"%")
 			case "sw":
-				conditions = append(conditions, fmt.Sprintf("p.profile_id ILIKE $%d", argID))
-				args = append(args, value+"%")
+				q.conditions = append(q.conditions, fmt.Sprintf("p.profile_id ILIKE $%d", q.argID))
+				q.args = append(q.args, value+"%")
 			default:
-				continue
+				return nil, unsupportedFilterError(f)
 			}
-			argID++
+			q.argID++
 
 		case "application_data":
 			var appAlias, appKey string
@@ -1129,53 +1954,116 @@ func GetAllProfilesWithFilter(
 				appAlias = "a_" + sanitizeForAlias(appID)
 
 				if !joinedAppIDs[appID] {
-					baseSQL += fmt.Sprintf(`
+					q.joins += fmt.Sprintf(`
                 INNER JOIN application_data %s
-                  ON %s.profile_id = p.profile_id AND %s.app_id = $%d`, appAlias, appAlias, appAlias, argID)
-					args = append(args, appID)
-					argID++
+                  ON %s.profile_id = p.profile_id AND %s.app_id = $%d`, appAlias, appAlias, appAlias, q.argID)
+					q.args = append(q.args, appID)
+					q.argID++
 					joinedAppIDs[appID] = true
 				}
 			} else {
 				appKey = key
 				appAlias = "a"
 				if !joinedAppIDs["__generic"] {
-					baseSQL += `
+					q.joins += `
                         INNER JOIN application_data a ON a.profile_id = p.profile_id
                     `
 					joinedAppIDs["__generic"] = true
 				}
 			}
 
+			appData := appAlias + ".application_data"
+			text := jsonPathText(appData+" -> 'app_specific_data'", appKey)
 			switch operator {
 			case "eq":
-				valBytes, err := json.Marshal(value)
+				jsonObj, err := nestedJSONObject(append([]string{"app_specific_data"}, strings.Split(appKey, ".")...),
+					value)
 				if err != nil {
-					return nil, false, errors2.NewServerError(errors2.ErrorMessage{
-						Code:        errors2.FILTER_PROFILE.Code,
-						Message:     errors2.FILTER_PROFILE.Message,
-						Description: fmt.Sprintf("Invalid filter value for key: %s", appKey),
-					}, err)
+					return nil, err
 				}
-				jsonObj := fmt.Sprintf(`{"app_specific_data": {"%s": %s}}`, appKey, string(valBytes))
-				conditions = append(conditions, fmt.Sprintf("%s.application_data @> $%d::jsonb", appAlias, argID))
-				args = append(args, jsonObj)
-				argID++
+				q.conditions = append(q.conditions, fmt.Sprintf("%s @> $%d::jsonb", appData, q.argID))
+				q.args = append(q.args, jsonObj)
+				q.argID++
+			case "eqi":
+				q.conditions = append(q.conditions, fmt.Sprintf("LOWER(%s) = LOWER($%d)", text, q.argID))
+				q.args = append(q.args, value)
+				q.argID++
+			case "nei":
+				q.conditions = append(q.conditions, fmt.Sprintf("LOWER(%s) IS DISTINCT FROM LOWER($%d)", text, q.argID))
+				q.args = append(q.args, value)
+				q.argID++
 			case "co":
-				conditions = append(conditions,
-					fmt.Sprintf("%s.application_data -> 'app_specific_data' ->> '%s' ILIKE $%d", appAlias, appKey, argID))
-				args = append(args, "%"+value+"%")
-				argID++
+				q.conditions = append(q.conditions, fmt.Sprintf("%s ILIKE $%d", text, q.argID))
+				q.args = append(q.args, "%"+value+"%")
+				q.argID++
 			case "sw":
-				conditions = append(conditions,
-					fmt.Sprintf("%s.application_data -> 'app_specific_data' ->> '%s' ILIKE $%d", appAlias, appKey, argID))
-				args = append(args, value+"%")
-				argID++
+				q.conditions = append(q.conditions, fmt.Sprintf("%s ILIKE $%d", text, q.argID))
+				q.args = append(q.args, value+"%")
+				q.argID++
 			default:
-				continue
+				return nil, unsupportedFilterError(f)
 			}
+
+		default:
+			return nil, unsupportedFilterError(f)
 		}
 	}
+	return q, nil
+}
+
+// unsupportedFilterError reports a filter the query builder can not apply. The service validates filters before
+// they reach the store, so this guards against a filter being dropped and the query matching more than asked for.
+func unsupportedFilterError(filter string) error {
+
+	errorMsg := fmt.Sprintf("Unsupported filter: %s", filter)
+	log.GetLogger().Debug(errorMsg)
+	return errors2.NewServerError(errors2.ErrorMessage{
+		Code:        errors2.FILTER_PROFILE.Code,
+		Message:     errors2.FILTER_PROFILE.Message,
+		Description: errorMsg,
+	}, errors.New(errorMsg))
+}
+
+// GetAllProfilesWithFilter retrieves profiles using dynamic filters and cursor-based pagination.
+// When a sort is given, the profiles are ordered by the sort field with created_at and profile_id as tie-breakers.
+// Soft-deleted profiles are skipped unless the query includes them. The app scope of the query is left to the service.
+func GetAllProfilesWithFilter(orgHandle string, query model.ProfileQuery) ([]model.Profile, bool, error) {
+
+	filters, sort, cursor, limit := query.Filters, query.Sort, query.Cursor, query.Limit
+
+	dbClient, err := provider.NewDBProvider().GetReadDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed to get database client filtering profiles."
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, false, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.FILTER_PROFILE.Code,
+			Message:     errors2.FILTER_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	if limit == 0 {
+		return []model.Profile{}, false, nil
+	}
+	if limit < 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	baseSQL := scripts.GetAllProfilesWithFilterBase[provider.NewDBProvider().GetDBType()]
+
+	filterQuery, err := buildProfileFilterQuery(orgHandle, filters)
+	if err != nil {
+		return nil, false, err
+	}
+	baseSQL += filterQuery.joins
+	conditions := filterQuery.conditions
+	args := filterQuery.args
+	argID := filterQuery.argID
 
 	// cursor seek (created_at + profile_id)
 	var cursorTime interface{} = nil
@@ -1195,18 +2083,30 @@ func GetAllProfilesWithFilter(
 	// Add cursor predicates as parameterized conditions
 	// Params: $argID = cursorTime, $(argID+1)=cursorProfileId
 	if cursor != nil {
+		var tiePredicate string
 		if direction == "next" {
-			conditions = append(conditions,
-				fmt.Sprintf("(p.created_at, p.profile_id) < ($%d::timestamptz, $%d::text)", argID, argID+1))
+			tiePredicate = fmt.Sprintf("(p.created_at, p.profile_id) < ($%d::timestamptz, $%d::text)", argID, argID+1)
 		} else { // prev
-			conditions = append(conditions,
-				fmt.Sprintf("(p.created_at, p.profile_id) > ($%d::timestamptz, $%d::text)", argID, argID+1))
+			tiePredicate = fmt.Sprintf("(p.created_at, p.profile_id) > ($%d::timestamptz, $%d::text)", argID, argID+1)
 		}
 		args = append(args, cursorTime, cursorProfileId)
 		argID += 2
+
+		if sort != nil && cursor.Sorted {
+			sortExpr, sortCast := profileSortExpression(sort)
+			var seekPredicate string
+			seekPredicate, args, argID = buildSortSeekPredicate(sortExpr, sortCast, sort.Descending,
+				direction == "prev", cursor.SortValue, tiePredicate, args, argID)
+			conditions = append(conditions, seekPredicate)
+		} else {
+			conditions = append(conditions, tiePredicate)
+		}
 	}
 
 	conditions = append(conditions, "r.profile_status = 'REFERENCE_PROFILE'")
+	if !query.IncludeDeleted {
+		conditions = append(conditions, "p.deleted_at IS NULL")
+	}
 
 	whereClause := "WHERE " + strings.Join(conditions, " AND ")
 
@@ -1216,6 +2116,22 @@ func GetAllProfilesWithFilter(
 		// fetch "newer" rows closest to cursor
 		orderClause = "ORDER BY p.created_at ASC, p.profile_id ASC"
 	}
+	if sort != nil {
+		sortExpr, _ := profileSortExpression(sort)
+		// prev pages are fetched in the reverse order and flipped back below, so the NULL placement flips too.
+		sortOrder := "ASC NULLS LAST"
+		if sort.Descending {
+			sortOrder = "DESC NULLS LAST"
+		}
+		if direction == "prev" {
+			sortOrder = "DESC NULLS FIRST"
+			if sort.Descending {
+				sortOrder = "ASC NULLS FIRST"
+			}
+		}
+		orderClause = fmt.Sprintf("ORDER BY %s %s, %s", sortExpr, sortOrder,
+			strings.TrimPrefix(orderClause, "ORDER BY "))
+	}
 
 	limitPlusOne := limit + 1
 	finalSQL := fmt.Sprintf("%s\n%s\n%s\nLIMIT $%d", baseSQL, whereClause, orderClause, argID)
@@ -1274,6 +2190,246 @@ func GetAllProfilesWithFilter(
 	return profiles, hasMore, nil
 }
 
+// StreamProfilesWithFilter hands the reference profiles matching the filters to handle one at a time, newest first,
+// as they are read from the database. Soft-deleted profiles are skipped and application data is not loaded. An error
+// returned by handle stops the stream and is returned as is.
+func StreamProfilesWithFilter(ctx context.Context, orgHandle string, filters []string,
+	handle func(profile model.Profile) error) error {
+
+	dbClient, err := provider.NewDBProvider().GetReadDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed to get database client for streaming profiles."
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.FILTER_PROFILE.Code,
+			Message:     errors2.FILTER_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	filterQuery, err := buildProfileFilterQuery(orgHandle, filters)
+	if err != nil {
+		return err
+	}
+	conditions := append(filterQuery.conditions, "r.profile_status = 'REFERENCE_PROFILE'", "p.deleted_at IS NULL")
+	query := scripts.GetAllProfilesWithFilterBase[provider.NewDBProvider().GetDBType()] + filterQuery.joins +
+		"\nWHERE " + strings.Join(conditions, " AND ") + "\nORDER BY p.created_at DESC, p.profile_id DESC"
+
+	// Errors of handle and of reading a row are returned as they are; only the query itself failing is wrapped.
+	var rowErr error
+	err = dbClient.StreamQueryContext(ctx, query, func(row map[string]interface{}) error {
+		profile, err := scanProfileRow(row)
+		if err == nil {
+			err = handle(profile)
+		}
+		rowErr = err
+		return err
+	}, filterQuery.args...)
+	if err == nil || err == rowErr {
+		return err
+	}
+	errorMsg := fmt.Sprintf("Failed to stream profiles of organization: %s", orgHandle)
+	logger.Debug(errorMsg, log.Error(err))
+	return errors2.NewServerError(errors2.ErrorMessage{
+		Code:        errors2.FILTER_PROFILE.Code,
+		Message:     errors2.FILTER_PROFILE.Message,
+		Description: errorMsg,
+	}, err)
+}
+
+// CountProfilesGroupedBy counts the reference profiles matching the filters by the value of the trait at the given
+// path. Soft-deleted profiles and profiles without the trait are not counted.
+func CountProfilesGroupedBy(orgHandle string, traitPath []string, filters []string) (map[string]int64, error) {
+
+	dbClient, err := provider.NewDBProvider().GetReadDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed to get database client for counting profiles."
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.COUNT_PROFILES.Code,
+			Message:     errors2.COUNT_PROFILES.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	filterQuery, err := buildProfileFilterQuery(orgHandle, filters)
+	if err != nil {
+		return nil, err
+	}
+	groupExpr := fmt.Sprintf("p.traits #>> $%d", filterQuery.argID)
+	conditions := append(filterQuery.conditions, "r.profile_status = 'REFERENCE_PROFILE'",
+		"p.deleted_at IS NULL", groupExpr+" IS NOT NULL")
+	args := append(filterQuery.args, pq.Array(traitPath))
+	query := fmt.Sprintf(scripts.CountProfilesGroupedByTrait[provider.NewDBProvider().GetDBType()],
+		filterQuery.argID, filterQuery.joins, strings.Join(conditions, " AND "))
+
+	results, err := dbClient.ExecuteQuery(query, args...)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to count profiles of organization: %s by trait: %s", orgHandle,
+			strings.Join(traitPath, "."))
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.COUNT_PROFILES.Code,
+			Message:     errors2.COUNT_PROFILES.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	counts := make(map[string]int64, len(results))
+	for _, row := range results {
+		value, _ := row["value"].(string)
+		count, _ := row["profile_count"].(int64)
+		counts[value] = count
+	}
+	return counts, nil
+}
+
+// GetHierarchyStats summarizes how the live profiles of the organization are unified.
+func GetHierarchyStats(orgHandle string) (*model.HierarchyStats, error) {
+
+	dbClient, err := provider.NewDBProvider().GetReadDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed to get database client for fetching profile hierarchy statistics."
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_HIERARCHY_STATS.Code,
+			Message:     errors2.GET_HIERARCHY_STATS.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.GetProfileHierarchyStats[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, orgHandle, constants.ReferenceProfile, constants.MergedTo)
+	if err == nil && len(results) != 1 {
+		err = fmt.Errorf("expected a single row of statistics, got %d", len(results))
+	}
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to fetch profile hierarchy statistics of organization: %s", orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_HIERARCHY_STATS.Code,
+			Message:     errors2.GET_HIERARCHY_STATS.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	row := results[0]
+	stats := &model.HierarchyStats{}
+	stats.TotalProfiles, _ = row["total_profiles"].(int64)
+	stats.ReferenceProfiles, _ = row["reference_profiles"].(int64)
+	stats.MergedProfiles, _ = row["merged_profiles"].(int64)
+	stats.MasterProfiles, _ = row["master_profiles"].(int64)
+	stats.OrphanedProfiles, _ = row["orphaned_profiles"].(int64)
+	stats.AverageChildrenPerMaster, _ = row["average_children_per_master"].(float64)
+	stats.LargestClusterSize, _ = row["largest_cluster_size"].(int64)
+	return stats, nil
+}
+
+// GetDistinctTraitValues returns up to limit distinct values of the trait at the given path among the reference
+// profiles of the organization, the most common first when byFrequency is set and in lexical order otherwise.
+// Soft-deleted profiles are left out.
+func GetDistinctTraitValues(orgHandle string, traitPath []string, limit int, byFrequency bool) ([]string, error) {
+
+	dbClient, err := provider.NewDBProvider().GetReadDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed to get database client for fetching distinct trait values."
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_DISTINCT_TRAIT_VALUES.Code,
+			Message:     errors2.GET_DISTINCT_TRAIT_VALUES.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	orderBy := "value"
+	if byFrequency {
+		orderBy = "profile_count DESC, value"
+	}
+	query := fmt.Sprintf(scripts.GetDistinctTraitValues[provider.NewDBProvider().GetDBType()], orderBy)
+	results, err := dbClient.ExecuteQuery(query, orgHandle, pq.Array(traitPath), limit)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to fetch distinct values of trait: %s for organization: %s",
+			strings.Join(traitPath, "."), orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_DISTINCT_TRAIT_VALUES.Code,
+			Message:     errors2.GET_DISTINCT_TRAIT_VALUES.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	values := make([]string, 0, len(results))
+	for _, row := range results {
+		value, _ := row["value"].(string)
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// profileSortExpression returns the SQL expression used to order profiles by the given sort field along with
+// the type its values are compared as. The field is expected to be validated against the profile schema.
+func profileSortExpression(sort *model.ProfileSort) (string, string) {
+
+	switch sort.Field {
+	case "profile_id", "user_id":
+		return "p." + sort.Field, "text"
+	case "created_at", "meta.created_at":
+		return "p.created_at", "timestamptz"
+	case "updated_at", "meta.updated_at":
+		return "p.updated_at", "timestamptz"
+	}
+
+	scopeKey := strings.SplitN(sort.Field, ".", 2)
+	path := strings.ReplaceAll(scopeKey[1], ".", ",")
+	expr := fmt.Sprintf("(p.%s #>> '{%s}')", scopeKey[0], path)
+
+	switch sort.ValueType {
+	case constants.IntegerDataType, constants.DecimalDataType, constants.EpochDataType:
+		return expr + "::numeric", "numeric"
+	case constants.BooleanDataType:
+		return expr + "::boolean", "boolean"
+	default:
+		return expr, "text"
+	}
+}
+
+// buildSortSeekPredicate builds the keyset predicate selecting the rows after the cursor when the listing is ordered
+// by a custom sort expression. NULL sort values are ordered last, or first when fetching a previous page.
+func buildSortSeekPredicate(sortExpr, sortCast string, descending, reversed bool, sortValue *string,
+	tiePredicate string, args []interface{}, argID int) (string, []interface{}, int) {
+
+	nullsLast := !reversed
+	if sortValue == nil {
+		if nullsLast {
+			return fmt.Sprintf("(%s IS NULL AND %s)", sortExpr, tiePredicate), args, argID
+		}
+		return fmt.Sprintf("(%s IS NOT NULL OR (%s IS NULL AND %s))", sortExpr, sortExpr, tiePredicate),
+			args, argID
+	}
+
+	operator := ">"
+	if descending != reversed {
+		operator = "<"
+	}
+	param := fmt.Sprintf("$%d::%s", argID, sortCast)
+	args = append(args, *sortValue)
+	argID++
+
+	predicate := fmt.Sprintf("%s %s %s OR (%s = %s AND %s)", sortExpr, operator, param, sortExpr, param, tiePredicate)
+	if nullsLast {
+		predicate += fmt.Sprintf(" OR %s IS NULL", sortExpr)
+	}
+	return "(" + predicate + ")", args, argID
+}
+
 // sanitizeForAlias converts a string to a valid SQL alias by replacing special characters
 func sanitizeForAlias(input string) string {
 	// Replace any non-alphanumeric character with underscore
@@ -1355,7 +2511,7 @@ func GetAllReferenceProfilesExceptForCurrent(currentProfile model.Profile) ([]mo
 			DeleteProfile:      deleteProfile,
 		}
 
-		if err := json.Unmarshal(traitsJSON, &profile.Traits); err != nil {
+		if err := utils.UnmarshalJSON(traitsJSON, &profile.Traits); err != nil {
 			errMsg := fmt.Sprintf("Failed to unmarshal traits for profile: %s", profile.ProfileId)
 			logger.Debug(errMsg, log.Error(err))
 			serverError := errors2.NewServerError(errors2.ErrorMessage{
@@ -1365,7 +2521,7 @@ func GetAllReferenceProfilesExceptForCurrent(currentProfile model.Profile) ([]mo
 			}, err)
 			return nil, serverError
 		}
-		if err := json.Unmarshal(identityJSON, &profile.IdentityAttributes); err != nil {
+		if err := utils.UnmarshalJSON(identityJSON, &profile.IdentityAttributes); err != nil {
 			errMsg := fmt.Sprintf("Failed to unmarshal identity attributes for profile: %s", profile.ProfileId)
 			logger.Debug(errMsg, log.Error(err))
 			serverError := errors2.NewServerError(errors2.ErrorMessage{
@@ -1376,7 +2532,10 @@ func GetAllReferenceProfilesExceptForCurrent(currentProfile model.Profile) ([]mo
 			return nil, serverError
 		}
 
-		profile.ApplicationData, _ = FetchApplicationData(profile.ProfileId)
+		profile.ApplicationData, err = FetchApplicationData(profile.ProfileId)
+		if err != nil {
+			return nil, err
+		}
 
 		profiles = append(profiles, profile)
 	}
@@ -1390,58 +2549,147 @@ func UpdateProfileReferences(parentProfile model.Profile, children []model.Refer
 	dbClient, err := provider.NewDBProvider().GetDBClient()
 	logger := log.GetLogger()
 	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to get database client for adding child profiles for parent: %s",
-			parentProfile.ProfileId)
+		errorMsg := fmt.Sprintf("Failed to get database client for adding child profiles for parent: %s",
+			parentProfile.ProfileId)
+		logger.Debug(errorMsg, log.Error(err))
+		serverError := errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+		return serverError
+	}
+	defer dbClient.Close()
+
+	if err := checkReferenceLinks(parentProfile.ProfileId, children); err != nil {
+		return err
+	}
+	err = dbClient.RunInTx(func(tx *sql.Tx) error {
+		return updateProfileReferencesInTx(tx, parentProfile.ProfileId, children)
+	})
+	if _, reported := err.(*errors2.ServerError); err != nil && !reported {
+		errorMsg := fmt.Sprintf("Failed to commit child profiles for parent: %s", parentProfile.ProfileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return err
+}
+
+// MergeIntoProfile stores a merge into the master profile in one transaction, so that a failure part way never leaves
+// the profiles half merged. The master is written like in UpdateProfile, at the version it carries, with its
+// application data merged into the stored one, and the children are referenced to it like in UpdateProfileReferences.
+// ErrProfileVersionConflict is returned as is, with nothing stored, when the master changed since it was read.
+func MergeIntoProfile(master model.Profile, children []model.Reference) error {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get database client for merging into profile: %s", master.ProfileId)
 		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
+		return errors2.NewServerError(errors2.ErrorMessage{
 			Code:        errors2.UPDATE_PROFILE.Code,
 			Message:     errors2.UPDATE_PROFILE.Message,
 			Description: errorMsg,
 		}, err)
-		return serverError
 	}
 	defer dbClient.Close()
 
-	tx, err := dbClient.BeginTx()
-	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to begin transaction for adding child profiles for parent: %s",
-			parentProfile.ProfileId)
+	if err := checkReferenceLinks(master.ProfileId, children); err != nil {
+		return err
+	}
+	// The master is written first, as referencing the children bumps its version.
+	err = dbClient.RunInTx(func(tx *sql.Tx) error {
+		if err := updateProfileInTx(context.Background(), tx, master); err != nil {
+			return err
+		}
+		return updateProfileReferencesInTx(tx, master.ProfileId, children)
+	})
+	if errors.Is(err, model.ErrProfileVersionConflict) {
+		return err
+	}
+	if _, reported := err.(*errors2.ServerError); err != nil && !reported {
+		errorMsg := fmt.Sprintf("Failed to commit the merge into profile: %s", master.ProfileId)
 		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
+		return errors2.NewServerError(errors2.ErrorMessage{
 			Code:        errors2.UPDATE_PROFILE.Code,
 			Message:     errors2.UPDATE_PROFILE.Message,
 			Description: errorMsg,
 		}, err)
-		return serverError
 	}
-	query := scripts.UpdateProfileReference[provider.NewDBProvider().GetDBType()]
+	return err
+}
+
+// checkReferenceLinks verifies that each of the children may be referenced to the parent profile.
+func checkReferenceLinks(parentProfileId string, children []model.Reference) error {
+
+	for _, child := range children {
+		if err := model.CheckReferenceLink(parentProfileId, child.ProfileId, LookupReferenceProfileId); err != nil {
+			errorMsg := fmt.Sprintf("Referencing profile: %s to profile: %s is not allowed", child.ProfileId,
+				parentProfileId)
+			log.GetLogger().Debug(errorMsg, log.Error(err))
+			return errors2.NewServerError(errors2.ErrorMessage{
+				Code:        errors2.UPDATE_PROFILE.Code,
+				Message:     errors2.UPDATE_PROFILE.Message,
+				Description: errorMsg,
+			}, err)
+		}
+	}
+	return nil
+}
 
+// updateProfileReferencesInTx references the children to the parent profile and marks them, as well as the parent, as
+// updated, as part of the transaction.
+func updateProfileReferencesInTx(tx *sql.Tx, parentProfileId string, children []model.Reference) error {
+
+	logger := log.GetLogger()
+	dbType := provider.NewDBProvider().GetDBType()
 	for _, child := range children {
-		_, err := tx.Exec(query, parentProfile.ProfileId, child.Reason, constants.MergedTo, child.ProfileId)
+		_, err := tx.Exec(scripts.UpdateProfileReference[dbType], parentProfileId, child.Reason, constants.MergedTo,
+			child.RuleId, child.MatchedValue, child.ProfileId)
 		if err != nil {
-			errRoll := tx.Rollback()
-			if errRoll != nil {
-				errorMsg := fmt.Sprintf("Failed to rollback transaction after error: %s", err)
-				logger.Debug(errorMsg, log.Error(err))
-				serverError := errors2.NewServerError(errors2.ErrorMessage{
-					Code:        errors2.UPDATE_PROFILE.Code,
-					Message:     errors2.UPDATE_PROFILE.Message,
-					Description: errorMsg,
-				}, errRoll)
-				return serverError
-			}
-			errorMsg := fmt.Sprintf("Failed to insert referenced profile: %s for parent profile: %s", child.ProfileId, parentProfile.ProfileId)
+			errorMsg := fmt.Sprintf("Failed to insert referenced profile: %s for parent profile: %s",
+				child.ProfileId, parentProfileId)
 			logger.Debug(errorMsg, log.Error(err))
-			serverError := errors2.NewServerError(errors2.ErrorMessage{
+			return errors2.NewServerError(errors2.ErrorMessage{
 				Code:        errors2.UPDATE_PROFILE.Code,
 				Message:     errors2.UPDATE_PROFILE.Message,
 				Description: errorMsg,
 			}, err)
-			return serverError
 		}
 	}
+	touched := []string{parentProfileId}
+	for _, child := range children {
+		touched = append(touched, child.ProfileId)
+	}
+	if _, err := tx.Exec(scripts.TouchProfiles[dbType], pq.Array(touched), time.Now().UTC()); err != nil {
+		errorMsg := fmt.Sprintf("Failed to mark the profiles merged to parent profile: %s as updated",
+			parentProfileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_PROFILE.Code,
+			Message:     errors2.UPDATE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return nil
+}
+
+// LookupReferenceProfileId returns the id of the profile the given profile is merged to, or "" if it is a
+// reference profile or does not exist.
+func LookupReferenceProfileId(profileId string) (string, error) {
 
-	return tx.Commit()
+	profile, err := GetProfile(profileId)
+	if err != nil {
+		return "", err
+	}
+	if profile == nil || profile.ProfileStatus.IsReferenceProfile {
+		return "", nil
+	}
+	return profile.ProfileStatus.ReferenceProfileId, nil
 }
 
 func FetchReferencedProfiles(referenceProfileId string) ([]model.Reference, error) {
@@ -1476,22 +2724,118 @@ func FetchReferencedProfiles(referenceProfileId string) ([]model.Reference, erro
 		return nil, serverError
 	}
 
-	var children []model.Reference
+	children := scanReferences(results)
+	if len(children) == 0 {
+		logger.Info(fmt.Sprintf("No referenced profiles found for parent profile: %s", referenceProfileId))
+	} else {
+		logger.Info(fmt.Sprintf("Successfully fetched child profiles for parent profile: %s", referenceProfileId))
+	}
+	return children, nil
+}
+
+// FetchReferencedProfilesInTx lists the profiles merged to the reference profile like FetchReferencedProfiles, as part
+// of the transaction.
+func FetchReferencedProfilesInTx(tx *sql.Tx, referenceProfileId string) ([]model.Reference, error) {
+
+	query := scripts.FetchReferencedProfiles[provider.NewDBProvider().GetDBType()]
+	results, err := client.QueryInTx(context.Background(), tx, query, referenceProfileId)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed fetching referenced profiles for profile: %s", referenceProfileId)
+		log.GetLogger().Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return scanReferences(results), nil
+}
+
+func scanReferences(results []map[string]interface{}) []model.Reference {
+
+	var references []model.Reference
 	for _, row := range results {
 		var reference model.Reference
 		reference.ProfileId = row["profile_id"].(string)
 		reference.Reason = row["reference_reason"].(string)
-		children = append(children, reference)
+		reference.RuleId = row["matched_rule_id"].(string)
+		reference.MatchedValue = row["matched_value"].(string)
+		references = append(references, reference)
 	}
+	return references
+}
 
-	if len(children) == 0 {
-		logger.Info(fmt.Sprintf("No referenced profiles found for parent profile: %s", referenceProfileId))
-	} else {
-		logger.Info(fmt.Sprintf("Successfully fetched child profiles for parent profile: %s", referenceProfileId))
+// FetchChildProfiles lists the profiles merged directly to the reference profile.
+func FetchChildProfiles(referenceProfileId string) ([]model.ChildProfile, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get database client for fetching child profiles of: %s", referenceProfileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.FetchChildProfiles[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, referenceProfileId, constants.MergedTo)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed fetching child profiles of profile: %s", referenceProfileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	children := make([]model.ChildProfile, 0, len(results))
+	for _, row := range results {
+		child, err := scanChildProfile(row)
+		if err != nil {
+			errorMsg := fmt.Sprintf("Invalid child profile of profile: %s", referenceProfileId)
+			logger.Debug(errorMsg, log.Error(err))
+			return nil, errors2.NewServerError(errors2.ErrorMessage{
+				Code:        errors2.GET_PROFILE.Code,
+				Message:     errors2.GET_PROFILE.Message,
+				Description: errorMsg,
+			}, err)
+		}
+		children = append(children, child)
 	}
 	return children, nil
 }
 
+func scanChildProfile(row map[string]interface{}) (model.ChildProfile, error) {
+
+	var child model.ChildProfile
+	var err error
+	if child.ProfileId, err = client.GetString(row, "profile_id"); err != nil {
+		return child, err
+	}
+	if child.UserId, err = client.GetNullableString(row, "user_id"); err != nil {
+		return child, err
+	}
+	if child.Reason, err = client.GetNullableString(row, "reference_reason"); err != nil {
+		return child, err
+	}
+	if child.RuleId, err = client.GetString(row, "matched_rule_id"); err != nil {
+		return child, err
+	}
+	if child.MatchedValue, err = client.GetString(row, "matched_value"); err != nil {
+		return child, err
+	}
+	if child.CreatedAt, err = client.GetTime(row, "created_at"); err != nil {
+		return child, err
+	}
+	child.UpdatedAt, err = client.GetTime(row, "updated_at")
+	return child, err
+}
+
 func enrichFieldValues(existingVal, incomingVal interface{}) interface{} {
 	logger := log.GetLogger()
 	switch incoming := incomingVal.(type) {
@@ -1644,6 +2988,15 @@ func GetProfileWithUserId(userId string) (*model.Profile, error) {
 		// todo: should we return a client error with 404 here?
 		return nil, nil
 	}
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed fetching profile with userId: %s", userId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
 	if len(results) == 0 {
 		logger.Debug(fmt.Sprintf("No profile found with the given userId: %s", userId))
 		// todo: should we return a client error with 404 here?
@@ -1660,10 +3013,127 @@ func GetProfileWithUserId(userId string) (*model.Profile, error) {
 		}, err)
 		return nil, serverError
 	}
-	profile.ApplicationData, _ = FetchApplicationData(profile.ProfileId)
+	profile.ApplicationData, err = FetchApplicationData(profile.ProfileId)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// GetProfileWithUserIdInOrg returns the reference profile of the user in the organization, or nil if the user has
+// no profile there.
+func GetProfileWithUserIdInOrg(orgHandle, userId string) (*model.Profile, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client while fetching profile with userId: %s", userId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.GetProfileByUserIdInOrg[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, userId, orgHandle)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed fetching profile with userId: %s of organization: %s", userId, orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	profile, err := scanProfileRow(results[0])
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed fetching profile with userId: %s of organization: %s", userId, orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
 	return &profile, nil
 }
 
+// RunWithUserProfileLock runs the function while holding a lock on the profile of the user in the organization, so
+// that no other caller, in this instance or another one, finds the user without a profile and creates one meanwhile.
+func RunWithUserProfileLock(ctx context.Context, orgHandle, userId string, fn func() error) error {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for locking the profile of user: %s", userId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	// Errors of the function are returned as they are, only failures to take the lock are reported here
+	var fnErr error
+	err = dbClient.RunWithAdvisoryLock(ctx, fmt.Sprintf("user-profile:%s:%s", orgHandle, userId), func() error {
+		fnErr = fn()
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		errorMsg := fmt.Sprintf("Failed to lock the profile of user: %s of organization: %s", userId, orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_PROFILE.Code,
+			Message:     errors2.ADD_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return err
+}
+
+// FindProfilesByIdentityAttribute returns the Ids of the profiles of the organization holding the value for the
+// identity attribute, either as its value or within a list of values.
+func FindProfilesByIdentityAttribute(orgHandle, attributePath, value string) ([]string, error) {
+
+	dbClient, err := provider.NewDBProvider().GetReadDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client while resolving profiles by: %s", attributePath)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.FindProfilesByIdentityAttribute[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, orgHandle, attributePath, value)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed resolving profiles by: %s", attributePath)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	profileIds := make([]string, 0, len(results))
+	for _, row := range results {
+		profileIds = append(profileIds, row["profile_id"].(string))
+	}
+	return profileIds, nil
+}
+
 // CreateProfileCookie creates a new profile cookie
 func CreateProfileCookie(profileCookie model.ProfileCookie) error {
 	dbClient, err := provider.NewDBProvider().GetDBClient()
@@ -1722,6 +3192,15 @@ func GetProfileCookieByProfileId(profileId string) (*model.ProfileCookie, error)
 		logger.Debug(fmt.Sprintf("No profile cookie found with the given profileId: %s", profileId))
 		return nil, nil
 	}
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed fetching profile cookie of profile: %s", profileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE_COOKIE.Code,
+			Message:     errors2.GET_PROFILE_COOKIE.Message,
+			Description: errorMsg,
+		}, err)
+	}
 	if len(results) == 0 {
 		logger.Debug(fmt.Sprintf("No profile cookie found with the given profileId: %s", profileId))
 		return nil, nil
@@ -1760,6 +3239,15 @@ func GetProfileCookie(cookie string) (*model.ProfileCookie, error) {
 		logger.Debug(fmt.Sprintf("No profile cookie found with the given cookie: %s", cookie))
 		return nil, nil
 	}
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed fetching profile cookie: %s", cookie)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE_COOKIE.Code,
+			Message:     errors2.GET_PROFILE_COOKIE.Message,
+			Description: errorMsg,
+		}, err)
+	}
 	if len(results) == 0 {
 		logger.Debug(fmt.Sprintf("No profile cookie found with the given cookie: %s", cookie))
 		return nil, nil
@@ -1856,69 +3344,45 @@ func UpdateProfileConsents(profileId string, consents []model.ConsentRecord) err
 	}
 	defer dbClient.Close()
 
-	// Start a transaction to ensure atomicity of consent updates
-	tx, err := dbClient.BeginTx()
-	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to begin transaction for updating consents for profile: %s", profileId)
-		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.UPDATE_PROFILE.Code,
-			Message:     errors2.UPDATE_PROFILE.Message,
-			Description: errorMsg,
-		}, err)
-		return serverError
-	}
-
-	// First, delete existing consents for this profile to ensure a clean slate
-
-	deleteQuery := scripts.DeleteProfileConsentsByProfileId[provider.NewDBProvider().GetDBType()]
-	_, err = tx.Exec(deleteQuery, profileId)
-	if err != nil {
-		_ = tx.Rollback()
-		errorMsg := fmt.Sprintf("Failed to delete existing consents for profile: %s", profileId)
-		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
+	fail := func(errorMsg string, cause error) error {
+		logger.Debug(errorMsg, log.Error(cause))
+		return errors2.NewServerError(errors2.ErrorMessage{
 			Code:        errors2.UPDATE_PROFILE.Code,
 			Message:     errors2.UPDATE_PROFILE.Message,
 			Description: errorMsg,
-		}, err)
-		return serverError
+		}, cause)
 	}
 
-	// Insert new consent records
-	insertQuery := scripts.InsertProfileConsentsByProfileId[provider.NewDBProvider().GetDBType()]
-	for _, consent := range consents {
+	// Run in a transaction to ensure atomicity of consent updates
+	err = dbClient.RunInTx(func(tx *sql.Tx) error {
+		// First, delete existing consents for this profile to ensure a clean slate
+		deleteQuery := scripts.DeleteProfileConsentsByProfileId[provider.NewDBProvider().GetDBType()]
+		if _, err := tx.Exec(deleteQuery, profileId); err != nil {
+			return fail(fmt.Sprintf("Failed to delete existing consents for profile: %s", profileId), err)
+		}
 
-		_, err = tx.Exec(insertQuery,
-			profileId,
-			consent.CategoryIdentifier,
-			consent.IsConsented,
-			consent.ConsentedAt)
+		// Insert new consent records
+		insertQuery := scripts.InsertProfileConsentsByProfileId[provider.NewDBProvider().GetDBType()]
+		for _, consent := range consents {
+			if _, err := tx.Exec(insertQuery, profileId, consent.CategoryIdentifier, consent.IsConsented,
+				consent.ConsentedAt); err != nil {
+				return fail(fmt.Sprintf("Failed to insert consent for profile: %s, category: %s",
+					profileId, consent.CategoryIdentifier), err)
+			}
+		}
 
-		if err != nil {
-			_ = tx.Rollback()
-			errorMsg := fmt.Sprintf("Failed to insert consent for profile: %s, category: %s",
-				profileId, consent.CategoryIdentifier)
-			logger.Debug(errorMsg, log.Error(err))
-			serverError := errors2.NewServerError(errors2.ErrorMessage{
-				Code:        errors2.UPDATE_PROFILE.Code,
-				Message:     errors2.UPDATE_PROFILE.Message,
-				Description: errorMsg,
-			}, err)
-			return serverError
+		touchQuery := scripts.TouchProfiles[provider.NewDBProvider().GetDBType()]
+		if _, err := tx.Exec(touchQuery, pq.Array([]string{profileId}), time.Now().UTC()); err != nil {
+			return fail(fmt.Sprintf("Failed to mark profile: %s updated after updating consents", profileId), err)
 		}
+		return nil
+	})
+	if _, reported := err.(*errors2.ServerError); err != nil && !reported {
+		return fail(fmt.Sprintf("Failed to commit transaction for updating consents for profile: %s", profileId),
+			err)
 	}
-
-	// Commit the transaction
-	if err = tx.Commit(); err != nil {
-		errorMsg := fmt.Sprintf("Failed to commit transaction for updating consents for profile: %s", profileId)
-		logger.Debug(errorMsg, log.Error(err))
-		serverError := errors2.NewServerError(errors2.ErrorMessage{
-			Code:        errors2.UPDATE_PROFILE.Code,
-			Message:     errors2.UPDATE_PROFILE.Message,
-			Description: errorMsg,
-		}, err)
-		return serverError
+	if err != nil {
+		return err
 	}
 
 	logger.Info(fmt.Sprintf("Successfully updated consents for profile: %s", profileId))
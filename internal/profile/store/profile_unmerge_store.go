@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/wso2/identity-customer-data-service/internal/profile/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/scripts"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// UnmergeProfile detaches the child from its reference profile and promotes it back to a reference profile,
+// recording an exclusion against each of the given profiles so that the rule does not unify them again.
+// When dissolveReference is set, the remaining children are promoted as well and the reference profile is
+// soft-deleted.
+func UnmergeProfile(orgHandle, referenceProfileId string, child model.Reference, excludedProfileIds []string,
+	dissolveReference bool, unmergedAt time.Time) error {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get database client for unmerging profile: %s", child.ProfileId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UNMERGE_PROFILE.Code,
+			Message:     errors2.UNMERGE_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	fail := func(errorMsg string, cause error) error {
+		logger.Debug(errorMsg, log.Error(cause))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UNMERGE_PROFILE.Code,
+			Message:     errors2.UNMERGE_PROFILE.Message,
+			Description: errorMsg,
+		}, cause)
+	}
+
+	dbType := provider.NewDBProvider().GetDBType()
+	promoted := []string{child.ProfileId}
+	if dissolveReference {
+		for _, profileId := range excludedProfileIds {
+			if profileId != referenceProfileId {
+				promoted = append(promoted, profileId)
+			}
+		}
+	}
+	err = dbClient.RunInTx(func(tx *sql.Tx) error {
+		for _, profileId := range promoted {
+			_, err := tx.Exec(scripts.UpdateProfileReference[dbType], "", "", constants.ReferenceProfile, "", "",
+				profileId)
+			if err != nil {
+				return fail(fmt.Sprintf("Failed to promote profile: %s to a reference profile", profileId), err)
+			}
+		}
+
+		for _, profileId := range excludedProfileIds {
+			_, err := tx.Exec(scripts.InsertUnmergeExclusion[dbType], child.ProfileId, profileId, orgHandle,
+				child.Reason, unmergedAt)
+			if err != nil {
+				return fail(fmt.Sprintf("Failed to record unmerge of profile: %s from profile: %s",
+					child.ProfileId, profileId), err)
+			}
+		}
+
+		touched := append([]string{referenceProfileId}, promoted...)
+		if _, err := tx.Exec(scripts.TouchProfiles[dbType], pq.Array(touched), unmergedAt); err != nil {
+			return fail(fmt.Sprintf("Failed to mark the profiles unmerged from profile: %s as updated",
+				referenceProfileId), err)
+		}
+
+		if dissolveReference {
+			_, err := tx.Exec(scripts.SoftDeleteProfile[dbType], unmergedAt, referenceProfileId)
+			if err != nil {
+				return fail(fmt.Sprintf("Failed to remove reference profile: %s after unmerge", referenceProfileId),
+					err)
+			}
+		}
+		return nil
+	})
+	if _, reported := err.(*errors2.ServerError); err != nil && !reported {
+		return fail(fmt.Sprintf("Failed to commit unmerge of profile: %s", child.ProfileId), err)
+	}
+	return err
+}
+
+// GetUnmergeExclusions returns the recorded unmerges that involve any of the given profiles.
+func GetUnmergeExclusions(profileIds []string) ([]model.UnmergeExclusion, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed to get database client for fetching unmerge exclusions"
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.GetUnmergeExclusions[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, pq.Array(profileIds))
+	if err != nil {
+		errorMsg := "Failed to fetch unmerge exclusions"
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_PROFILE.Code,
+			Message:     errors2.GET_PROFILE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	exclusions := make([]model.UnmergeExclusion, 0, len(results))
+	for _, row := range results {
+		exclusions = append(exclusions, model.UnmergeExclusion{
+			ProfileId:         row["profile_id"].(string),
+			ExcludedProfileId: row["excluded_profile_id"].(string),
+			RuleName:          row["rule_name"].(string),
+		})
+	}
+	return exclusions, nil
+}
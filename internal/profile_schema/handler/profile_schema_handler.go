@@ -56,7 +56,7 @@ func (psh *ProfileSchemaHandler) AddProfileSchemaAttributesForScope(w http.Respo
 
 	scope := r.PathValue("scope")
 	orgHandle := utils.ExtractOrgHandleFromPath(r)
-	err := security.AuthnAndAuthz(r, "profile_schema:create")
+	r, err := security.AuthnAndAuthz(r, "profile_schema:create")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -154,7 +154,7 @@ func (psh *ProfileSchemaHandler) AddProfileSchemaAttributesForScope(w http.Respo
 func (psh *ProfileSchemaHandler) GetProfileSchema(w http.ResponseWriter, r *http.Request) {
 
 	orgHandle := utils.ExtractOrgHandleFromPath(r)
-	err := security.AuthnAndAuthz(r, "profile_schema:view")
+	r, err := security.AuthnAndAuthz(r, "profile_schema:view")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -185,7 +185,7 @@ func (psh *ProfileSchemaHandler) GetProfileSchemaAttributeById(w http.ResponseWr
 	scope := r.PathValue("scope")
 	attributeId := r.PathValue("attrID")
 	orgHandle := utils.ExtractOrgHandleFromPath(r)
-	err := security.AuthnAndAuthz(r, "profile_schema:view")
+	r, err := security.AuthnAndAuthz(r, "profile_schema:view")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -226,7 +226,7 @@ func (psh *ProfileSchemaHandler) GetProfileSchemaAttributeForScope(w http.Respon
 
 	scope := r.PathValue("scope")
 	orgHandle := utils.ExtractOrgHandleFromPath(r)
-	err := security.AuthnAndAuthz(r, "profile_schema:view")
+	r, err := security.AuthnAndAuthz(r, "profile_schema:view")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -289,7 +289,7 @@ func (psh *ProfileSchemaHandler) PatchProfileSchemaAttributeById(w http.Response
 
 	attributeId := r.PathValue("attrID")
 	orgHandle := utils.ExtractOrgHandleFromPath(r)
-	err := security.AuthnAndAuthz(r, "profile_schema:update")
+	r, err := security.AuthnAndAuthz(r, "profile_schema:update")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -349,7 +349,7 @@ func (psh *ProfileSchemaHandler) PatchProfileSchemaAttributeById(w http.Response
 func (psh *ProfileSchemaHandler) DeleteProfileSchema(w http.ResponseWriter, r *http.Request) {
 
 	orgHandle := utils.ExtractOrgHandleFromPath(r)
-	err := security.AuthnAndAuthz(r, "profile_schema:delete")
+	r, err := security.AuthnAndAuthz(r, "profile_schema:delete")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -381,7 +381,7 @@ func (psh *ProfileSchemaHandler) DeleteProfileSchemaAttributeById(w http.Respons
 
 	attributeId := r.PathValue("attrID")
 	orgHandle := utils.ExtractOrgHandleFromPath(r)
-	err := security.AuthnAndAuthz(r, "profile_schema:delete")
+	r, err := security.AuthnAndAuthz(r, "profile_schema:delete")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -420,7 +420,7 @@ func (psh *ProfileSchemaHandler) DeleteProfileSchemaAttributeById(w http.Respons
 
 func (psh *ProfileSchemaHandler) DeleteProfileSchemaAttributeForScope(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "profile_schema:delete")
+	r, err := security.AuthnAndAuthz(r, "profile_schema:delete")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -0,0 +1,742 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+// Computation is a parsed computation expression of a derived trait.
+//
+// An expression references other traits by their attribute name (e.g. traits.orders_total) and supports number and
+// string literals, the arithmetic operators + - * /, comparisons (== != < <= > >=), && || !, parentheses and the
+// conditional "cond ? a : b", which can be chained for bucketing:
+//
+//	traits.orders_total > 1000 ? "gold" : traits.orders_total > 100 ? "silver" : "bronze"
+//
+// + concatenates when either operand is a string. A missing trait evaluates to null. Arithmetic, concatenation,
+// ordering comparisons and conditionals over null are null as well, so a derived trait is only set when its inputs
+// are available. == and != can be used to test for null explicitly.
+type Computation struct {
+	expression string
+	root       computationNode
+	references []string
+}
+
+// ParseComputation parses a computation expression.
+func ParseComputation(expression string) (*Computation, error) {
+
+	p := &computationParser{input: expression}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("computation expression is empty")
+	}
+	root, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.atEnd() {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", p.peek().text, p.peek().pos)
+	}
+
+	refs := make([]string, 0, len(p.references))
+	for ref := range p.references {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return &Computation{expression: expression, root: root, references: refs}, nil
+}
+
+// References returns the attribute names of the traits the expression reads, sorted by name.
+func (c *Computation) References() []string {
+	return c.references
+}
+
+// Evaluate evaluates the expression against the given traits. A nil result means the value could not be derived
+// because one of its inputs is missing.
+func (c *Computation) Evaluate(traits map[string]interface{}) (interface{}, error) {
+	return c.root.eval(traits)
+}
+
+// OrderComputedTraits returns the computed traits among the given attributes in an order in which each one is
+// evaluated after the computed traits it references. An error is returned when the expressions reference each other
+// in a cycle.
+func OrderComputedTraits(attrs []ProfileSchemaAttribute) ([]ProfileSchemaAttribute, map[string]*Computation, error) {
+
+	computed := make(map[string]ProfileSchemaAttribute)
+	computations := make(map[string]*Computation)
+	names := make([]string, 0)
+	for _, attr := range attrs {
+		if attr.ComputationExpression == "" {
+			continue
+		}
+		computation, err := ParseComputation(attr.ComputationExpression)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid computation expression for '%s': %w", attr.AttributeName, err)
+		}
+		computed[attr.AttributeName] = attr
+		computations[attr.AttributeName] = computation
+		names = append(names, attr.AttributeName)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(names))
+	ordered := make([]ProfileSchemaAttribute, 0, len(names))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			start := 0
+			for i, n := range path {
+				if n == name {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("computed traits form a cycle: %s", strings.Join(cycle, " -> "))
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, ref := range computations[name].References() {
+			if _, ok := computed[ref]; ok {
+				if err := visit(ref); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		ordered = append(ordered, computed[name])
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, nil, err
+		}
+	}
+	return ordered, computations, nil
+}
+
+// ApplyComputedTraits recomputes the computed traits of the schema on the given traits and returns the resulting
+// traits. A computed trait whose inputs are missing, or whose expression fails to evaluate (e.g. a division by zero),
+// is removed rather than left with a stale value.
+func ApplyComputedTraits(traits map[string]interface{}, attrs []ProfileSchemaAttribute) (map[string]interface{}, error) {
+
+	ordered, computations, err := OrderComputedTraits(attrs)
+	if err != nil {
+		return traits, err
+	}
+	if len(ordered) == 0 {
+		return traits, nil
+	}
+	if traits == nil {
+		traits = make(map[string]interface{})
+	}
+
+	for _, attr := range ordered {
+		path := strings.Split(strings.TrimPrefix(attr.AttributeName, constants.Traits+"."), ".")
+		val, err := computations[attr.AttributeName].Evaluate(traits)
+		if err == nil && val != nil {
+			val = coerceComputedValue(val, attr.ValueType)
+		}
+		if err != nil || val == nil {
+			deleteTraitPath(traits, path)
+			continue
+		}
+		setTraitPath(traits, path, val)
+	}
+	return traits, nil
+}
+
+// StripComputedTraits removes the computed traits of the schema from the given traits.
+func StripComputedTraits(traits map[string]interface{}, attrs []ProfileSchemaAttribute) {
+
+	for _, attr := range attrs {
+		if attr.ComputationExpression == "" {
+			continue
+		}
+		deleteTraitPath(traits, strings.Split(strings.TrimPrefix(attr.AttributeName, constants.Traits+"."), "."))
+	}
+}
+
+// coerceComputedValue converts a computed value to the value type of the trait. Returns nil when the value cannot
+// be represented in that type.
+func coerceComputedValue(val interface{}, valueType string) interface{} {
+
+	switch valueType {
+	case constants.StringDataType:
+		switch v := val.(type) {
+		case string:
+			return v
+		case bool:
+			return strconv.FormatBool(v)
+		}
+		if _, ok := numberValue(val); ok {
+			return formatOperand(val)
+		}
+	case constants.IntegerDataType, constants.EpochDataType:
+		// Exact integers kept as their JSON text stay exact.
+		if v, ok := val.(json.Number); ok {
+			if _, err := v.Int64(); err == nil {
+				return v
+			}
+		}
+		if v, ok := numberValue(val); ok {
+			return math.Trunc(v)
+		}
+	case constants.DecimalDataType:
+		if v, ok := val.(json.Number); ok {
+			if _, err := v.Float64(); err == nil {
+				return v
+			}
+		}
+		if v, ok := numberValue(val); ok {
+			return v
+		}
+	case constants.BooleanDataType:
+		if v, ok := val.(bool); ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func lookupTraitPath(traits map[string]interface{}, path []string) interface{} {
+
+	var current interface{} = traits
+	for _, segment := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[segment]
+	}
+	return current
+}
+
+func setTraitPath(traits map[string]interface{}, path []string, val interface{}) {
+
+	current := traits
+	for _, segment := range path[:len(path)-1] {
+		next, ok := current[segment].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[segment] = next
+		}
+		current = next
+	}
+	current[path[len(path)-1]] = val
+}
+
+func deleteTraitPath(traits map[string]interface{}, path []string) {
+
+	current := traits
+	for _, segment := range path[:len(path)-1] {
+		next, ok := current[segment].(map[string]interface{})
+		if !ok {
+			return
+		}
+		current = next
+	}
+	delete(current, path[len(path)-1])
+}
+
+type computationNode interface {
+	eval(traits map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type traitNode struct {
+	path []string
+}
+
+func (n traitNode) eval(traits map[string]interface{}) (interface{}, error) {
+
+	switch v := lookupTraitPath(traits, n.path).(type) {
+	case nil, string, bool, float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case json.Number:
+		if _, err := v.Float64(); err != nil {
+			return nil, err
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("trait '%s' is not a scalar value", strings.Join(n.path, "."))
+	}
+}
+
+type unaryNode struct {
+	op      string
+	operand computationNode
+}
+
+func (n unaryNode) eval(traits map[string]interface{}) (interface{}, error) {
+
+	val, err := n.operand.eval(traits)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		return !truthy(val), nil
+	}
+	if val == nil {
+		return nil, nil
+	}
+	f, ok := numberValue(val)
+	if !ok {
+		return nil, fmt.Errorf("operator '-' requires a number, got %v", val)
+	}
+	return -f, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right computationNode
+}
+
+func (n binaryNode) eval(traits map[string]interface{}) (interface{}, error) {
+
+	left, err := n.left.eval(traits)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+		right, err := n.right.eval(traits)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+		right, err := n.right.eval(traits)
+		if err != nil {
+			return nil, err
+		}
+		return truthy(right), nil
+	}
+
+	right, err := n.right.eval(traits)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equalValues(left, right), nil
+	case "!=":
+		return !equalValues(left, right), nil
+	case "<", "<=", ">", ">=":
+		return compareValues(n.op, left, right)
+	}
+
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	if n.op == "+" {
+		_, leftIsString := left.(string)
+		_, rightIsString := right.(string)
+		if leftIsString || rightIsString {
+			return formatOperand(left) + formatOperand(right), nil
+		}
+	}
+	l, lok := numberValue(left)
+	r, rok := numberValue(right)
+	if !lok || !rok {
+		return nil, fmt.Errorf("operator '%s' requires numbers, got %v and %v", n.op, left, right)
+	}
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	}
+	return nil, fmt.Errorf("unsupported operator '%s'", n.op)
+}
+
+type conditionalNode struct {
+	condition, then, otherwise computationNode
+}
+
+func (n conditionalNode) eval(traits map[string]interface{}) (interface{}, error) {
+
+	cond, err := n.condition.eval(traits)
+	if err != nil {
+		return nil, err
+	}
+	if cond == nil {
+		return nil, nil
+	}
+	if truthy(cond) {
+		return n.then.eval(traits)
+	}
+	return n.otherwise.eval(traits)
+}
+
+func truthy(val interface{}) bool {
+
+	switch v := val.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	if f, ok := numberValue(val); ok {
+		return f != 0
+	}
+	return true
+}
+
+// numberValue returns the value of a number operand, which is a float64 or, when number precision is preserved, a
+// json.Number.
+func numberValue(val interface{}) (float64, bool) {
+
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// equalValues reports whether two operands are equal, comparing numbers by their value.
+func equalValues(left, right interface{}) bool {
+
+	l, lok := numberValue(left)
+	r, rok := numberValue(right)
+	if lok && rok {
+		return l == r
+	}
+	return left == right
+}
+
+func compareValues(op string, left, right interface{}) (interface{}, error) {
+
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	var cmp int
+	if l, ok := numberValue(left); ok {
+		r, ok := numberValue(right)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %v with %v", left, right)
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	} else if l, ok := left.(string); ok {
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %v with %v", left, right)
+		}
+		cmp = strings.Compare(l, r)
+	} else {
+		return nil, fmt.Errorf("operator '%s' requires numbers or strings, got %v", op, left)
+	}
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+func formatOperand(val interface{}) string {
+
+	switch v := val.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+type computationToken struct {
+	kind string // "number", "string", "ident" or "op"
+	text string
+	pos  int
+}
+
+type computationParser struct {
+	input      string
+	tokens     []computationToken
+	current    int
+	references map[string]bool
+}
+
+func (p *computationParser) tokenize() error {
+
+	input := p.input
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(input) && input[i+1] >= '0' && input[i+1] <= '9':
+			start := i
+			for i < len(input) && (input[i] >= '0' && input[i] <= '9' || input[i] == '.') {
+				i++
+			}
+			p.tokens = append(p.tokens, computationToken{kind: "number", text: input[start:i], pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for i < len(input) && input[i] != c {
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+				}
+				sb.WriteByte(input[i])
+				i++
+			}
+			if i >= len(input) {
+				return fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			p.tokens = append(p.tokens, computationToken{kind: "string", text: sb.String(), pos: start})
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := i
+			for i < len(input) && (input[i] == '_' || input[i] == '.' || input[i] >= 'a' && input[i] <= 'z' ||
+				input[i] >= 'A' && input[i] <= 'Z' || input[i] >= '0' && input[i] <= '9') {
+				i++
+			}
+			p.tokens = append(p.tokens, computationToken{kind: "ident", text: input[start:i], pos: start})
+		default:
+			if i+1 < len(input) {
+				switch two := input[i : i+2]; two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					p.tokens = append(p.tokens, computationToken{kind: "op", text: two, pos: i})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("+-*/()<>!?:", rune(c)) {
+				return fmt.Errorf("unexpected character '%c' at position %d", c, i)
+			}
+			p.tokens = append(p.tokens, computationToken{kind: "op", text: string(c), pos: i})
+			i++
+		}
+	}
+	return nil
+}
+
+func (p *computationParser) atEnd() bool {
+	return p.current >= len(p.tokens)
+}
+
+func (p *computationParser) peek() computationToken {
+
+	if p.atEnd() {
+		return computationToken{pos: len(p.input)}
+	}
+	return p.tokens[p.current]
+}
+
+func (p *computationParser) acceptOp(ops ...string) (string, bool) {
+
+	tok := p.peek()
+	if tok.kind != "op" {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.current++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *computationParser) parseExpression() (computationNode, error) {
+
+	condition, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.acceptOp("?"); !ok {
+		return condition, nil
+	}
+	then, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.acceptOp(":"); !ok {
+		return nil, fmt.Errorf("expected ':' at position %d", p.peek().pos)
+	}
+	otherwise, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return conditionalNode{condition: condition, then: then, otherwise: otherwise}, nil
+}
+
+// binaryPrecedence lists the binary operators from the lowest to the highest precedence.
+var binaryPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+func (p *computationParser) parseBinary(level int) (computationNode, error) {
+
+	if level == len(binaryPrecedence) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp(binaryPrecedence[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *computationParser) parseUnary() (computationNode, error) {
+
+	if op, ok := p.acceptOp("-", "!"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *computationParser) parsePrimary() (computationNode, error) {
+
+	if p.atEnd() {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	tok := p.tokens[p.current]
+	p.current++
+	switch tok.kind {
+	case "number":
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at position %d", tok.text, tok.pos)
+		}
+		return literalNode{value: f}, nil
+	case "string":
+		return literalNode{value: tok.text}, nil
+	case "ident":
+		switch tok.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null":
+			return literalNode{value: nil}, nil
+		}
+		path := strings.Split(tok.text, ".")
+		if len(path) < 2 || path[0] != constants.Traits {
+			return nil, fmt.Errorf("'%s' at position %d must reference a trait as traits.<name>", tok.text, tok.pos)
+		}
+		for _, segment := range path[1:] {
+			if segment == "" {
+				return nil, fmt.Errorf("invalid trait reference '%s' at position %d", tok.text, tok.pos)
+			}
+		}
+		if p.references == nil {
+			p.references = make(map[string]bool)
+		}
+		p.references[tok.text] = true
+		return traitNode{path: path[1:]}, nil
+	case "op":
+		if tok.text == "(" {
+			expr, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.acceptOp(")"); !ok {
+				return nil, fmt.Errorf("expected ')' at position %d", p.peek().pos)
+			}
+			return expr, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected '%s' at position %d", tok.text, tok.pos)
+}
@@ -33,6 +33,10 @@ type ProfileSchemaAttribute struct {
 	CanonicalValues       []CanonicalValue `json:"canonical_values,omitempty" bson:"canonical_values,omitempty"` // String of options for the attribute
 	SubAttributes         []SubAttribute   `json:"sub_attributes,omitempty" bson:"sub_attributes,omitempty"`     // If the datatype is object
 	SCIMDialect           string           `json:"scim_dialect,omitempty" bson:"scim_dialect,omitempty"`         // Need to skip this in the response
+	ComputationExpression string           `json:"computation_expression,omitempty" bson:"computation_expression,omitempty"`
+	IsPII                 bool             `json:"is_pii,omitempty" bson:"is_pii,omitempty"`                     // Values are replaced when the profile is anonymized
+	CaseInsensitive       bool             `json:"case_insensitive,omitempty" bson:"case_insensitive,omitempty"` // Equality filters on the attribute ignore case
+	AllowedApps           []string         `json:"allowed_apps,omitempty" bson:"allowed_apps,omitempty"`         // Applications that may read the trait, all when empty
 }
 
 type SubAttribute struct {
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
@@ -116,6 +117,10 @@ func (s *ProfileSchemaService) AddProfileSchemaAttributesForScope(schemaAttribut
 		}
 	}
 
+	if err := validateComputedTraits(orgId, validAttrs); err != nil {
+		return nil, err
+	}
+
 	return validAttrs, psstr.AddProfileSchemaAttributesForScope(validAttrs, scope, orgId)
 }
 
@@ -293,9 +298,127 @@ func (s *ProfileSchemaService) validateSchemaAttribute(attr model.ProfileSchemaA
 		}, http.StatusBadRequest)
 		return clientError, false
 	}
+
+	if attr.ComputationExpression != "" {
+		if err := validateComputationExpression(attr); err != nil {
+			return err, false
+		}
+	}
+
+	if len(attr.AllowedApps) > 0 {
+		if scope != constants.Traits {
+			clientError := errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.INVALID_ATTRIBUTE_NAME.Code,
+				Message:     errors2.INVALID_ATTRIBUTE_NAME.Message,
+				Description: "allowed_apps is only supported for traits",
+			}, http.StatusBadRequest)
+			return clientError, false
+		}
+		if slices.Contains(attr.AllowedApps, "") {
+			clientError := errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.INVALID_ATTRIBUTE_NAME.Code,
+				Message:     errors2.INVALID_ATTRIBUTE_NAME.Message,
+				Description: "allowed_apps must not contain empty application identifiers",
+			}, http.StatusBadRequest)
+			return clientError, false
+		}
+	}
 	return nil, true
 }
 
+// validateComputationExpression checks that a computed attribute is a single-valued, read-only trait with a
+// well-formed expression.
+func validateComputationExpression(attr model.ProfileSchemaAttribute) error {
+
+	var reason string
+	if !strings.HasPrefix(attr.AttributeName, constants.Traits+".") {
+		reason = "Computation expressions are only supported for traits"
+	} else if attr.MultiValued || attr.ValueType == constants.ComplexDataType {
+		reason = "Computed traits must be single-valued and of a simple value_type"
+	} else if attr.Mutability != constants.MutabilityReadOnly {
+		reason = fmt.Sprintf("Computed traits must have mutability '%s'", constants.MutabilityReadOnly)
+	} else if _, err := model.ParseComputation(attr.ComputationExpression); err != nil {
+		reason = fmt.Sprintf("Invalid computation expression for '%s': %s", attr.AttributeName, err.Error())
+	}
+	if reason == "" {
+		return nil
+	}
+	return errors2.NewClientError(errors2.ErrorMessage{
+		Code:        errors2.INVALID_ATTRIBUTE_NAME.Code,
+		Message:     errors2.INVALID_ATTRIBUTE_NAME.Message,
+		Description: reason,
+	}, http.StatusBadRequest)
+}
+
+// validateComputedTraits checks the computed traits of the organization's schema, with the given attributes added
+// or replaced, for references to undefined traits and for cycles between them.
+func validateComputedTraits(orgId string, changed []model.ProfileSchemaAttribute) error {
+
+	hasComputation := false
+	for _, attr := range changed {
+		if attr.ComputationExpression != "" {
+			hasComputation = true
+			break
+		}
+	}
+	if !hasComputation {
+		return nil
+	}
+
+	existing, err := psstr.GetProfileSchemaAttributesForOrg(orgId)
+	if err != nil {
+		return err
+	}
+	changedIds := make(map[string]bool, len(changed))
+	for _, attr := range changed {
+		if attr.AttributeId != "" {
+			changedIds[attr.AttributeId] = true
+		}
+	}
+	byName := make(map[string]model.ProfileSchemaAttribute, len(existing)+len(changed))
+	for _, attr := range existing {
+		// A replaced attribute may have been renamed, so it is not kept under its old name.
+		if !changedIds[attr.AttributeId] {
+			byName[attr.AttributeName] = attr
+		}
+	}
+	for _, attr := range changed {
+		byName[attr.AttributeName] = attr
+	}
+
+	attrs := make([]model.ProfileSchemaAttribute, 0, len(byName))
+	for _, attr := range byName {
+		attrs = append(attrs, attr)
+	}
+	for _, attr := range changed {
+		if attr.ComputationExpression == "" {
+			continue
+		}
+		computation, err := model.ParseComputation(attr.ComputationExpression)
+		if err != nil {
+			return err
+		}
+		for _, ref := range computation.References() {
+			if _, ok := byName[ref]; !ok {
+				return errors2.NewClientError(errors2.ErrorMessage{
+					Code:    errors2.INVALID_ATTRIBUTE_NAME.Code,
+					Message: errors2.INVALID_ATTRIBUTE_NAME.Message,
+					Description: fmt.Sprintf("Computation expression of '%s' references undefined trait '%s'",
+						attr.AttributeName, ref),
+				}, http.StatusBadRequest)
+			}
+		}
+	}
+	if _, _, err := model.OrderComputedTraits(attrs); err != nil {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.INVALID_ATTRIBUTE_NAME.Code,
+			Message:     errors2.INVALID_ATTRIBUTE_NAME.Message,
+			Description: err.Error(),
+		}, http.StatusBadRequest)
+	}
+	return nil
+}
+
 var validateApplicationIdentifierFn = defaultValidateApplicationIdentifier
 
 func defaultValidateApplicationIdentifier(appID, orgHandle string) (error, bool) {
@@ -428,7 +551,58 @@ func (s *ProfileSchemaService) UpdateProfileSchemaAttributeById(orgId, attribute
 		log.GetLogger().Debug(fmt.Sprintf("multi_valued not provided in patch; defaulting to false for attribute: %s", attributeId))
 	}
 
-	err, isValid := s.validateSchemaAttribute(model.ProfileSchemaAttribute{
+	computationExpression := attribute.ComputationExpression
+	if ce, ok := updates["computation_expression"]; ok {
+		ceStr, ok := ce.(string)
+		if !ok {
+			return errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.INVALID_ATTRIBUTE_NAME.Code,
+				Message:     "Invalid value for computation_expression",
+				Description: "computation_expression must be a string",
+			}, http.StatusBadRequest)
+		}
+		computationExpression = ceStr
+	}
+	if isPII, ok := updates["is_pii"]; ok {
+		if _, ok := isPII.(bool); !ok {
+			return errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.INVALID_ATTRIBUTE_NAME.Code,
+				Message:     "Invalid value for is_pii",
+				Description: "is_pii must be a boolean",
+			}, http.StatusBadRequest)
+		}
+	}
+	if caseInsensitive, ok := updates["case_insensitive"]; ok {
+		if _, ok := caseInsensitive.(bool); !ok {
+			return errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.INVALID_ATTRIBUTE_NAME.Code,
+				Message:     "Invalid value for case_insensitive",
+				Description: "case_insensitive must be a boolean",
+			}, http.StatusBadRequest)
+		}
+	}
+	allowedApps := attribute.AllowedApps
+	if raw, ok := updates["allowed_apps"]; ok && raw != nil {
+		items, ok := raw.([]interface{})
+		allowedApps = make([]string, 0, len(items))
+		for _, item := range items {
+			appId, isString := item.(string)
+			if !isString {
+				ok = false
+				break
+			}
+			allowedApps = append(allowedApps, appId)
+		}
+		if !ok {
+			return errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.INVALID_ATTRIBUTE_NAME.Code,
+				Message:     "Invalid value for allowed_apps",
+				Description: "allowed_apps must be a list of application identifiers",
+			}, http.StatusBadRequest)
+		}
+	}
+
+	updatedAttribute := model.ProfileSchemaAttribute{
 		OrgId:                 orgId,
 		AttributeId:           attributeId,
 		AttributeName:         updates["attribute_name"].(string),
@@ -439,7 +613,10 @@ func (s *ProfileSchemaService) UpdateProfileSchemaAttributeById(orgId, attribute
 		CanonicalValues:       canonicalValues,
 		SubAttributes:         subAttributes,
 		ApplicationIdentifier: applicationIdentifier,
-	})
+		ComputationExpression: computationExpression,
+		AllowedApps:           allowedApps,
+	}
+	err, isValid := s.validateSchemaAttribute(updatedAttribute)
 	if !isValid {
 		if err != nil {
 			return err
@@ -451,6 +628,9 @@ func (s *ProfileSchemaService) UpdateProfileSchemaAttributeById(orgId, attribute
 			Description: "Invalid updates provided for the profile schema attribute",
 		}, http.StatusBadRequest)
 	}
+	if err := validateComputedTraits(orgId, []model.ProfileSchemaAttribute{updatedAttribute}); err != nil {
+		return err
+	}
 	return psstr.PatchProfileSchemaAttributeById(orgId, attributeId, updates)
 }
 
@@ -55,10 +55,10 @@ func AddProfileSchemaAttributesForScope(attrs []model.ProfileSchemaAttribute, sc
 
 	baseQuery := scripts.InsertProfileSchemaAttributesForScope[provider.NewDBProvider().GetDBType()]
 	valueStrings := make([]string, 0, len(attrs))
-	valueArgs := make([]interface{}, 0, len(attrs)*12)
+	valueArgs := make([]interface{}, 0, len(attrs)*14)
 
 	for i, attr := range attrs {
-		idx := i * 12
+		idx := i * 16
 		subAttrsJSON, err := json.Marshal(attr.SubAttributes)
 		if err != nil {
 			errorMsg := fmt.Sprintf("Failed to marshal sub attributes for attribute %s", attr.AttributeId)
@@ -79,11 +79,13 @@ func AddProfileSchemaAttributesForScope(attrs []model.ProfileSchemaAttribute, sc
 			}, err)
 		}
 
-		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d,  $%d, $%d,  $%d, $%d, $%d, $%d) ",
-			idx+1, idx+2, idx+3, idx+4, idx+5, idx+6, idx+7, idx+8, idx+9, idx+10, idx+11, idx+12))
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d,  $%d, $%d,  $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d) ",
+			idx+1, idx+2, idx+3, idx+4, idx+5, idx+6, idx+7, idx+8, idx+9, idx+10, idx+11, idx+12, idx+13, idx+14, idx+15,
+			idx+16))
 		valueArgs = append(valueArgs, orgId, attr.AttributeId, attr.AttributeName, attr.ValueType,
 			attr.MergeStrategy, attr.ApplicationIdentifier, attr.Mutability, attr.MultiValued, subAttrsJSON,
-			canonicalJSON, scope, attr.DisplayName)
+			canonicalJSON, scope, attr.DisplayName, attr.ComputationExpression, attr.IsPII, attr.CaseInsensitive,
+			allowedAppsJSON(attr.AllowedApps))
 
 	}
 
@@ -257,6 +259,10 @@ func GetProfileSchemaAttributeByName(orgId, attributeName string) (*model.Profil
 		}
 	}
 
+	computationExpression, _ := row["computation_expression"].(string)
+	isPII, _ := row["is_pii"].(bool)
+	caseInsensitive, _ := row["case_insensitive"].(bool)
+	allowedApps := allowedAppsOf(row)
 	attr := &model.ProfileSchemaAttribute{
 		OrgId:                 orgId,
 		AttributeName:         row["attribute_name"].(string),
@@ -269,6 +275,10 @@ func GetProfileSchemaAttributeByName(orgId, attributeName string) (*model.Profil
 		MultiValued:           row["multi_valued"].(bool),
 		SubAttributes:         subAttrs,
 		CanonicalValues:       canonicalValues,
+		ComputationExpression: computationExpression,
+		IsPII:                 isPII,
+		CaseInsensitive:       caseInsensitive,
+		AllowedApps:           allowedApps,
 	}
 
 	logger.Info(fmt.Sprintf("Successfully fetched profile schema attribute '%s' for organizaton '%s'",
@@ -315,9 +325,42 @@ func GetProfileSchemaAttributesForOrg(orgId string) ([]model.ProfileSchemaAttrib
 	return schema, nil
 }
 
+// patchableSchemaColumns are the columns a profile schema attribute patch may update. The keys of a patch are
+// written into the query as column names, so any other key must be rejected.
+var patchableSchemaColumns = map[string]bool{
+	"attribute_name":         true,
+	"display_name":           true,
+	"value_type":             true,
+	"merge_strategy":         true,
+	"application_identifier": true,
+	"mutability":             true,
+	"multi_valued":           true,
+	"canonical_values":       true,
+	"sub_attributes":         true,
+	"scim_dialect":           true,
+	"computation_expression": true,
+	"is_pii":                 true,
+	"case_insensitive":       true,
+	"allowed_apps":           true,
+}
+
 // PatchProfileSchemaAttributeById updates a specific profile schema attribute for a given organization.
 func PatchProfileSchemaAttributeById(orgId, attributeId string, updates map[string]interface{}) error {
 
+	for key, value := range updates {
+		// The Id may be echoed back in the patch but can not be changed.
+		if key == "attribute_id" && value == attributeId {
+			continue
+		}
+		if !patchableSchemaColumns[key] {
+			return errors.NewClientError(errors.ErrorMessage{
+				Code:        errors.ATTRIBUTE_UPATE_NOT_SUPPORTED.Code,
+				Message:     errors.ATTRIBUTE_UPATE_NOT_SUPPORTED.Message,
+				Description: fmt.Sprintf("Field '%s' of a profile schema attribute can not be updated", key),
+			}, http.StatusBadRequest)
+		}
+	}
+
 	dbClient, err := provider.NewDBProvider().GetDBClient()
 	logger := log.GetLogger()
 	if err != nil {
@@ -336,6 +379,9 @@ func PatchProfileSchemaAttributeById(orgId, attributeId string, updates map[stri
 	argIndex := 1
 
 	for key, value := range updates {
+		if key == "attribute_id" {
+			continue
+		}
 		switch v := value.(type) {
 		case []interface{}, map[string]interface{}:
 			// Marshal slices/maps to JSON
@@ -509,6 +555,10 @@ func PatchProfileSchemaAttributesForScope(orgId string, scope string, updates []
 			canonicalJSON,
 			subAttrsJSON,
 			attr.DisplayName,
+			attr.ComputationExpression,
+			attr.IsPII,
+			attr.CaseInsensitive,
+			allowedAppsJSON(attr.AllowedApps),
 			orgId,
 			attr.AttributeId,
 			scope,
@@ -599,6 +649,11 @@ func mapRowToProfileAttribute(row map[string]interface{}) model.ProfileSchemaAtt
 		}
 	}
 
+	computationExpression, _ := row["computation_expression"].(string)
+	isPII, _ := row["is_pii"].(bool)
+	caseInsensitive, _ := row["case_insensitive"].(bool)
+	allowedApps := allowedAppsOf(row)
+
 	return model.ProfileSchemaAttribute{
 		AttributeId:           fmt.Sprint(row["attribute_id"]),
 		AttributeName:         row["attribute_name"].(string),
@@ -610,7 +665,37 @@ func mapRowToProfileAttribute(row map[string]interface{}) model.ProfileSchemaAtt
 		MultiValued:           row["multi_valued"].(bool),
 		SubAttributes:         subAttrs,
 		CanonicalValues:       canonicalValues,
+		ComputationExpression: computationExpression,
+		IsPII:                 isPII,
+		CaseInsensitive:       caseInsensitive,
+		AllowedApps:           allowedApps,
+	}
+}
+
+// allowedAppsJSON serializes the applications allowed to read an attribute for the allowed_apps column.
+func allowedAppsJSON(allowedApps []string) string {
+
+	if allowedApps == nil {
+		allowedApps = []string{}
+	}
+	data, _ := json.Marshal(allowedApps)
+	return string(data)
+}
+
+// allowedAppsOf reads the applications allowed to read an attribute from a database row. A missing or malformed
+// value leaves the attribute unrestricted.
+func allowedAppsOf(row map[string]interface{}) []string {
+
+	var allowedApps []string
+	if raw, ok := row["allowed_apps"].(string); ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &allowedApps); err != nil {
+			log.GetLogger().Debug("Failed to unmarshal allowed_apps", log.Error(err))
+		}
+	}
+	if len(allowedApps) == 0 {
+		return nil
 	}
+	return allowedApps
 }
 
 func UpsertIdentityAttributes(orgID string, attrs []model.ProfileSchemaAttribute) error {
@@ -655,9 +740,9 @@ func UpsertIdentityAttributes(orgID string, attrs []model.ProfileSchemaAttribute
 		attrKey := extractClaimKeyFromURI(attr.AttributeName)
 		attr.AttributeName = attrKey
 
-		valueStrings = append(valueStrings, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d, $%d, $%d)",
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d, $%d, $%d, $%d)",
 			argIndex, argIndex+1, argIndex+2, argIndex+3, argIndex+4, argIndex+5, argIndex+6,
-			argIndex+7, argIndex+8, argIndex+9, argIndex+10, argIndex+11, argIndex+12))
+			argIndex+7, argIndex+8, argIndex+9, argIndex+10, argIndex+11, argIndex+12, argIndex+13))
 		valueArgs = append(valueArgs,
 			orgID,
 			attr.AttributeId,
@@ -672,8 +757,9 @@ func UpsertIdentityAttributes(orgID string, attrs []model.ProfileSchemaAttribute
 			attr.SCIMDialect,
 			constants.IdentityAttributes,
 			attr.DisplayName,
+			attr.CaseInsensitive,
 		)
-		argIndex += 13
+		argIndex += 14
 	}
 
 	insertQuery += strings.Join(valueStrings, ",")
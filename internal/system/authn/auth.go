@@ -45,8 +45,16 @@ func ValidateAuthenticationAndReturnClaims(token, orgHandle string) (map[string]
 	if IsJWT(token) {
 		logger.Debug(fmt.Sprintf("Token is identified as JWT. Validating JWT for organization: '%s'", orgHandle))
 
-		// Parse JWT to get claims (contains org information)
+		// Parse JWT to get claims (contains org information), verifying its signature when a JWKS is configured. The
+		// JWKS of the organization is only fetched for tokens claiming to be issued for it.
 		claims, err := ParseJWTClaims(token)
+		if err == nil && cfg.AuthServer.JWKSEndpoint != "" {
+			if !validateClaims(orgHandle, claims) {
+				logger.Debug("JWT claims validation failed")
+				return nil, unauthorizedError()
+			}
+			claims, err = verifyWithOrgJWKS(token, orgHandle)
+		}
 		if err != nil {
 			logger.Debug(fmt.Sprintf("Failed to parse JWT claims for organization: '%s'", orgHandle),
 				log.Error(err))
@@ -0,0 +1,247 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package authn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/wso2/identity-customer-data-service/internal/system/client"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+// jsonWebKey is a public key of a JSON Web Key Set. Only RSA and EC signing keys are supported.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS returns the signing keys of a JSON Web Key Set by their key id. Keys of unsupported types and
+// encryption keys are skipped.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q in JWKS: %w", jwk.Kid, err)
+		}
+		if key != nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// VerifyJWT verifies the signature and the time based claims of the token against the given keys and returns its
+// claims. The key is selected by the kid header of the token, or used directly when the set has a single key.
+func VerifyJWT(token string, keys map[string]crypto.PublicKey) (map[string]interface{}, error) {
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return selectKey(t, keys)
+	}, jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384",
+		"ES512"}))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+var errUnknownSigningKey = errors.New("token is signed with an unknown key")
+
+func selectKey(t *jwt.Token, keys map[string]crypto.PublicKey) (crypto.PublicKey, error) {
+
+	kid, _ := t.Header["kid"].(string)
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, errUnknownSigningKey
+}
+
+// cachedJWKS is the key set of an organization, or the error the server answered with when it does not provide one,
+// and when it was fetched.
+type cachedJWKS struct {
+	keys      map[string]crypto.PublicKey
+	err       error
+	fetchedAt time.Time
+}
+
+// jwksFetch is a fetch of the key set of an organization in progress. Callers needing the keys of the organization
+// meanwhile wait for done instead of fetching them again.
+type jwksFetch struct {
+	done chan struct{}
+	keys map[string]crypto.PublicKey
+	err  error
+}
+
+var (
+	jwksCache   = map[string]*cachedJWKS{}
+	jwksFetches = map[string]*jwksFetch{}
+	jwksCacheMu sync.Mutex
+)
+
+// verifyWithOrgJWKS verifies the token against the JWKS of the organization, refreshing the cached key set once it
+// expires or when the token is signed with a key it does not know yet.
+func verifyWithOrgJWKS(token, orgHandle string) (map[string]interface{}, error) {
+
+	keys, err := orgJWKS(orgHandle, false)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := VerifyJWT(token, keys)
+	if errors.Is(err, errUnknownSigningKey) {
+		// The keys may have been rotated since they were cached.
+		if keys, err = orgJWKS(orgHandle, true); err != nil {
+			return nil, err
+		}
+		return VerifyJWT(token, keys)
+	}
+	return claims, err
+}
+
+// orgJWKS returns the cached key set of the organization, fetching it when it is not cached, has expired, or refresh
+// is requested and it is older than constants.JWKSMinRefreshInterval. Concurrent fetches for an organization are
+// shared, and the lock on the cache is not held across them. An organization the server provides no key set for is
+// not asked again before constants.JWKSMinRefreshInterval passes.
+func orgJWKS(orgHandle string, refresh bool) (map[string]crypto.PublicKey, error) {
+
+	jwksCacheMu.Lock()
+	if cached := jwksCache[orgHandle]; cached != nil {
+		age := time.Since(cached.fetchedAt)
+		if cached.err != nil && age < constants.JWKSMinRefreshInterval {
+			jwksCacheMu.Unlock()
+			return nil, cached.err
+		}
+		if cached.err == nil && age < constants.JWKSCacheTTL && (!refresh || age < constants.JWKSMinRefreshInterval) {
+			jwksCacheMu.Unlock()
+			return cached.keys, nil
+		}
+	}
+	if fetch := jwksFetches[orgHandle]; fetch != nil {
+		jwksCacheMu.Unlock()
+		<-fetch.done
+		return fetch.keys, fetch.err
+	}
+	fetch := &jwksFetch{done: make(chan struct{})}
+	jwksFetches[orgHandle] = fetch
+	jwksCacheMu.Unlock()
+
+	fetch.keys, fetch.err = fetchJWKS(orgHandle)
+
+	jwksCacheMu.Lock()
+	delete(jwksFetches, orgHandle)
+	switch {
+	case fetch.err == nil:
+		jwksCache[orgHandle] = &cachedJWKS{keys: fetch.keys, fetchedAt: time.Now()}
+	case errors.Is(fetch.err, client.ErrJWKSUnavailable):
+		jwksCache[orgHandle] = &cachedJWKS{err: fetch.err, fetchedAt: time.Now()}
+	}
+	jwksCacheMu.Unlock()
+	close(fetch.done)
+	return fetch.keys, fetch.err
+}
+
+func fetchJWKS(orgHandle string) (map[string]crypto.PublicKey, error) {
+
+	data, err := client.NewIdentityClient(config.GetCDSRuntime().Config).FetchJWKS(orgHandle)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package kafka publishes profile changes to a Kafka topic through a Kafka REST proxy (v2 API). Importing the
+// package registers the "kafka" change stream publisher. The proxy partitions the records by key, so the changes
+// of a profile hierarchy keep their order.
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/changestream"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+// TypeKafka is the change stream type that selects this publisher.
+const TypeKafka = "kafka"
+
+const (
+	contentType = "application/vnd.kafka.json.v2+json"
+	accept      = "application/vnd.kafka.v2+json"
+)
+
+func init() {
+	changestream.RegisterPublisher(TypeKafka, func(cfg config.ChangeStreamConfig) (changestream.ProfileChangePublisher, error) {
+		return NewPublisher(cfg.Kafka)
+	})
+}
+
+// Publisher produces profile changes to a topic through the REST proxy.
+type Publisher struct {
+	endpoint string
+	username string
+	password string
+	timeout  time.Duration
+	client   *http.Client
+}
+
+// NewPublisher creates a publisher for the configured REST proxy and topic.
+func NewPublisher(cfg config.KafkaStreamConfig) (*Publisher, error) {
+
+	if cfg.RestProxyURL == "" {
+		return nil, fmt.Errorf("kafka: rest_proxy_url is required to publish profile changes")
+	}
+	if _, err := url.ParseRequestURI(cfg.RestProxyURL); err != nil {
+		return nil, fmt.Errorf("kafka: invalid rest_proxy_url: %w", err)
+	}
+	topic := cfg.Topic
+	if topic == "" {
+		topic = constants.DefaultChangeStreamKafkaTopic
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = constants.DefaultChangeStreamKafkaTimeout
+	}
+	return &Publisher{
+		endpoint: strings.TrimSuffix(cfg.RestProxyURL, "/") + "/topics/" + url.PathEscape(topic),
+		username: cfg.Username,
+		password: cfg.Password,
+		timeout:  timeout,
+		client:   &http.Client{},
+	}, nil
+}
+
+type produceRecord struct {
+	Key   string                     `json:"key"`
+	Value changestream.ProfileChange `json:"value"`
+}
+
+type produceRequest struct {
+	Records []produceRecord `json:"records"`
+}
+
+type produceResponse struct {
+	Offsets []struct {
+		Partition int     `json:"partition"`
+		Offset    int64   `json:"offset"`
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+// Publish produces the change as a JSON record with the key, returning once the proxy has written it to the topic.
+func (p *Publisher) Publish(ctx context.Context, key string, change changestream.ProfileChange) error {
+
+	body, err := json.Marshal(produceRequest{Records: []produceRecord{{Key: key, Value: change}}})
+	if err != nil {
+		return fmt.Errorf("kafka: failed to serialize change %s: %w", change.EventId, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	request.Header.Set("Accept", accept)
+	if p.username != "" {
+		request.SetBasicAuth(p.username, p.password)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("kafka: failed to reach the REST proxy: %w", err)
+	}
+	defer response.Body.Close()
+	var produced produceResponse
+	_ = json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(&produced)
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("kafka: REST proxy responded with status %d: %s", response.StatusCode, produced.Message)
+	}
+	for _, offset := range produced.Offsets {
+		if offset.ErrorCode != nil {
+			message := ""
+			if offset.Error != nil {
+				message = *offset.Error
+			}
+			return fmt.Errorf("kafka: record was not written (error code %d): %s", *offset.ErrorCode, message)
+		}
+	}
+	return nil
+}
+
+// Close releases idle connections to the REST proxy.
+func (p *Publisher) Close() error {
+
+	p.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package changestream publishes the changes of profiles to an event stream so that other systems can follow
+// them. Publishers are pluggable: a publisher registers itself by name and is selected by the change_stream
+// configuration. Nothing is published when no publisher is configured.
+package changestream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+)
+
+// ProfileChange is a change of a profile. Changes are keyed by MasterProfileId so that all changes of a unified
+// profile hierarchy are ordered within the stream.
+type ProfileChange struct {
+	EventId   string `json:"event_id"`
+	EventType string `json:"event_type"`
+	OrgHandle string `json:"org_handle"`
+	// ProfileId is the profile that changed. For merges it is the reference profile the others were merged into.
+	ProfileId       string    `json:"profile_id"`
+	MasterProfileId string    `json:"master_profile_id"`
+	ChildProfileIds []string  `json:"child_profile_ids,omitempty"`
+	OccurredAt      time.Time `json:"occurred_at"`
+	// Profile is the unified view of the reference profile when the change was published. It is omitted for
+	// deleted profiles.
+	Profile *profileModel.ProfileResponse `json:"profile,omitempty"`
+}
+
+// ProfileChangePublisher writes profile changes to an event stream.
+type ProfileChangePublisher interface {
+	// Publish writes the change under the key, returning once the stream has accepted it.
+	Publish(ctx context.Context, key string, change ProfileChange) error
+
+	// Close releases the resources of the publisher. It is safe to call Close more than once.
+	Close() error
+}
+
+// PublisherProvider is the constructor signature of a ProfileChangePublisher. It receives the change stream
+// configuration of the deployment.
+type PublisherProvider func(cfg config.ChangeStreamConfig) (ProfileChangePublisher, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]PublisherProvider{}
+)
+
+// RegisterPublisher registers a PublisherProvider under the given name. Call this inside an init() function of
+// the publisher package so that it is available as soon as the package is imported.
+func RegisterPublisher(name string, p PublisherProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = p
+}
+
+// NewPublisher returns the publisher named in cfg.Type, or nil when no publisher is configured. An error is
+// returned if no publisher is registered under the name.
+func NewPublisher(cfg config.ChangeStreamConfig) (ProfileChangePublisher, error) {
+	if cfg.Type == "" {
+		return nil, nil
+	}
+	providersMu.RLock()
+	p, ok := providers[cfg.Type]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("changestream: unknown publisher %q; register it by importing its package", cfg.Type)
+	}
+	return p(cfg)
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package changestream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// ProfileFetcher returns the unified view of a profile, as served by the profile API.
+type ProfileFetcher func(profileId string) (*profileModel.ProfileResponse, error)
+
+// stream publishes the changes queued by PublishProfileChange one at a time, in the order they were queued.
+type stream struct {
+	publisher    ProfileChangePublisher
+	fetchProfile ProfileFetcher
+	changes      chan ProfileChange
+	done         chan struct{}
+}
+
+// activeStream is set by Start and cleared by Stop. All access is guarded by streamMu so that no change is queued
+// on a stopped stream.
+var (
+	streamMu     sync.RWMutex
+	activeStream *stream
+)
+
+// Start creates the configured publisher and starts publishing profile changes. It is a no-op when no publisher
+// is configured. The fetcher provides the profile snapshots included in the changes.
+func Start(cfg config.ChangeStreamConfig, fetchProfile ProfileFetcher) error {
+
+	publisher, err := NewPublisher(cfg)
+	if err != nil {
+		return err
+	}
+	if publisher == nil {
+		return nil
+	}
+	s := &stream{
+		publisher:    publisher,
+		fetchProfile: fetchProfile,
+		changes:      make(chan ProfileChange, constants.ProfileChangeQueueSize),
+		done:         make(chan struct{}),
+	}
+	go s.run()
+	streamMu.Lock()
+	activeStream = s
+	streamMu.Unlock()
+	log.GetLogger().Info(fmt.Sprintf("Publishing profile changes with the %s publisher", cfg.Type))
+	return nil
+}
+
+// Stop publishes the changes that are already queued and closes the publisher. Changes raised afterwards are
+// dropped.
+func Stop() error {
+
+	streamMu.Lock()
+	s := activeStream
+	activeStream = nil
+	if s != nil {
+		close(s.changes)
+	}
+	streamMu.Unlock()
+	if s == nil {
+		return nil
+	}
+	<-s.done
+	return s.publisher.Close()
+}
+
+// PublishProfileChange queues a change of the profile for publishing. Merges pass the reference profile and the
+// profiles merged into it. It never blocks the caller: the change is dropped when the queue is full.
+func PublishProfileChange(eventType, orgHandle, profileId string, childProfileIds []string) {
+
+	streamMu.RLock()
+	defer streamMu.RUnlock()
+	if activeStream == nil {
+		return
+	}
+	change := ProfileChange{
+		EventId:         uuid.New().String(),
+		EventType:       eventType,
+		OrgHandle:       orgHandle,
+		ProfileId:       profileId,
+		MasterProfileId: profileId,
+		ChildProfileIds: childProfileIds,
+		OccurredAt:      time.Now().UTC(),
+	}
+	select {
+	case activeStream.changes <- change:
+	default:
+		log.GetLogger().Error(fmt.Sprintf("Profile change queue is full, dropping %s change: %s of profile: %s",
+			eventType, change.EventId, profileId))
+	}
+}
+
+func (s *stream) run() {
+
+	defer close(s.done)
+	for change := range s.changes {
+		s.publish(change)
+	}
+}
+
+// publish attaches the snapshot of the reference profile to the change and publishes it under the reference
+// profile id. Failed attempts are retried so that later changes of the profile are not published ahead of it.
+func (s *stream) publish(change ProfileChange) {
+
+	logger := log.GetLogger()
+	if change.EventType != constants.ProfileChangeDeleted {
+		s.attachSnapshot(&change)
+	}
+	var err error
+	for attempt := 1; attempt <= constants.ProfileChangePublishAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(time.Duration(attempt-1) * constants.ProfileChangePublishBackoff)
+		}
+		if err = s.publisher.Publish(context.Background(), change.MasterProfileId, change); err == nil {
+			return
+		}
+		logger.Warn(fmt.Sprintf("Failed to publish %s change: %s of profile: %s (attempt %d)", change.EventType,
+			change.EventId, change.ProfileId, attempt), log.Error(err))
+	}
+	logger.Error("Publishing profile change failed permanently, dropping the change",
+		log.String("event_id", change.EventId),
+		log.String("event_type", change.EventType),
+		log.String("org_handle", change.OrgHandle),
+		log.String("profile_id", change.ProfileId),
+		log.Error(err))
+}
+
+// attachSnapshot resolves the reference profile of the changed profile and includes its unified view. The change
+// is published without a snapshot if the profile can no longer be read.
+func (s *stream) attachSnapshot(change *ProfileChange) {
+
+	snapshot, err := s.fetchProfile(change.ProfileId)
+	if err == nil && snapshot != nil && snapshot.MergedTo != nil && snapshot.MergedTo.ProfileId != "" {
+		snapshot, err = s.fetchProfile(snapshot.MergedTo.ProfileId)
+	}
+	if err != nil || snapshot == nil {
+		log.GetLogger().Warn(fmt.Sprintf("Publishing %s change: %s of profile: %s without a snapshot",
+			change.EventType, change.EventId, change.ProfileId), log.Error(err))
+		return
+	}
+	change.MasterProfileId = snapshot.ProfileId
+	change.Profile = snapshot
+}
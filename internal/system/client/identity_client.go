@@ -22,6 +22,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -404,6 +405,39 @@ func (c *IdentityClient) IntrospectToken(token, orgHandle string) (map[string]in
 	return result, nil
 }
 
+// ErrJWKSUnavailable is returned by FetchJWKS when the server does not provide a key set for the organization.
+var ErrJWKSUnavailable = errors.New("JWKS is not available")
+
+// FetchJWKS returns the JSON Web Key Set that the organization signs its access tokens with. Key sets larger than
+// constants.MaxJWKSSize are rejected.
+func (c *IdentityClient) FetchJWKS(orgHandle string) ([]byte, error) {
+
+	authConfig := config.GetCDSRuntime().Config.AuthServer
+	host := c.BaseURL
+	if authConfig.IsSystemAppGrantEnabled {
+		host = authConfig.ADUISHostname
+	}
+	jwksEndpoint := "https://" + host + "/t/" + orgHandle + authConfig.JWKSEndpoint
+
+	resp, err := c.HTTPClient.Get(jwksEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS from %s: %w", jwksEndpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: endpoint %s returned status %d", ErrJWKSUnavailable, jwksEndpoint,
+			resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, constants.MaxJWKSSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS from %s: %w", jwksEndpoint, err)
+	}
+	if len(data) > constants.MaxJWKSSize {
+		return nil, fmt.Errorf("JWKS from %s exceeds %d bytes", jwksEndpoint, constants.MaxJWKSSize)
+	}
+	return data, nil
+}
+
 func (c *IdentityClient) GetProfileSchema(orgHandle string) ([]model.ProfileSchemaAttribute, error) {
 
 	logger := log.GetLogger()
@@ -745,6 +779,7 @@ func ConvertSCIMClaimWithLocal(
 			CanonicalValues: canonicalValues,
 			SubAttributes:   nil,
 			SCIMDialect:     dialectURI,
+			CaseInsensitive: isEmailClaim(attrKey),
 		}, &subAttr, parentAttrName
 	}
 
@@ -760,9 +795,16 @@ func ConvertSCIMClaimWithLocal(
 		CanonicalValues: canonicalValues,
 		SubAttributes:   subAttrs,
 		SCIMDialect:     dialectURI,
+		CaseInsensitive: isEmailClaim(attrKey),
 	}, nil, ""
 }
 
+// isEmailClaim tells whether the claim holds email addresses, which are compared ignoring case when filtering.
+func isEmailClaim(attrKey string) bool {
+
+	return strings.Contains(strings.ToLower(attrKey), "email")
+}
+
 func ifThenElse(cond bool, a, b string) string {
 	if cond {
 		return a
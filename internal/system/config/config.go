@@ -18,9 +18,16 @@
 
 package config
 
+import "time"
+
 type AddrConfig struct {
 	Port int    `yaml:"port"`
 	Host string `yaml:"host"`
+	// ShutdownTimeout is how long in-flight requests are given to complete once a shutdown signal is received.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// ShutdownReadinessDelay is how long the server keeps serving after it reports not ready on shutdown, so that
+	// load balancers stop routing requests to it first.
+	ShutdownReadinessDelay time.Duration `yaml:"shutdown_readiness_delay"`
 }
 
 type LogConfig struct {
@@ -37,6 +44,7 @@ type AuthServerConfig struct {
 	ADUISHostname             string              `yaml:"adu_is_hostname"`
 	CookieDomain              string              `yaml:"cookieDomain"`
 	IntrospectionEndPoint     string              `yaml:"introspectionEndpoint"`
+	JWKSEndpoint              string              `yaml:"jwks_endpoint"`
 	TokenEndpoint             string              `yaml:"tokenEndpoint"`
 	RevocationEndpoint        string              `yaml:"revocationEndpoint"`
 	ClaimEndpoint             string              `yaml:"claim_endpoint"`
@@ -58,6 +66,51 @@ type DataSourceConfig struct {
 	Username string `yaml:"username"`
 	Password string `yaml:"password"`
 	SSLMode  string `yaml:"sslmode"`
+	// QueryTimeout bounds queries that are not run with a request context (e.g. "30s").
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+	// VerifyWrites re-reads a created profile instead of using the row returned by the insert. Only needed
+	// when writes and reads may be served by different database nodes.
+	VerifyWrites bool `yaml:"verify_writes"`
+	// MaxOpenConns caps the connections of the pool shared by all database clients.
+	MaxOpenConns int `yaml:"max_open_conns"`
+	// MaxIdleConns is the number of idle connections kept in the pool.
+	MaxIdleConns int `yaml:"max_idle_conns"`
+	// ConnMaxLifetime is how long a connection is reused before it is replaced (e.g. "30m").
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	// ConnMaxIdleTime is how long a connection may stay idle before it is closed (e.g. "5m").
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// Retry controls the retries of transient errors for the queries that opt in to them.
+	Retry DBRetryConfig `yaml:"retry"`
+	// SlowQueryThreshold logs the queries taking longer than it with their duration and the types of their
+	// arguments, but not their values (e.g. "500ms"). Slow queries are not logged when it is not set.
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+	// EnsureIndexes creates the recommended indexes missing from the database in the background at startup, so
+	// that databases set up before they were added to the schema get them too.
+	EnsureIndexes bool `yaml:"ensure_indexes"`
+	// StatementCacheSize is the number of distinct queries whose prepared statements are kept and reused per
+	// connection pool. Defaults to 256; a negative value runs every query unprepared, which is needed behind
+	// poolers that do not support prepared statements, such as PgBouncer in transaction mode.
+	StatementCacheSize int `yaml:"statement_cache_size"`
+	// ReadReplica is the database that serves read-only queries. Reads go to the primary when it is not set.
+	ReadReplica ReadReplicaConfig `yaml:"read_replica"`
+}
+
+// ReadReplicaConfig is the connection of a read replica. The credentials default to those of the primary.
+type ReadReplicaConfig struct {
+	Hostname string `yaml:"hostname"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// DBRetryConfig configures the retries of transient database errors.
+type DBRetryConfig struct {
+	// MaxAttempts is the total number of attempts of a query, including the first one. It also bounds the attempts
+	// of transactions aborted by serialization failures or deadlocks.
+	MaxAttempts int `yaml:"max_attempts"`
+	// InitialBackoff is the wait before the first retry; later waits double up to MaxBackoff (e.g. "100ms").
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
 }
 
 // ExternalBrokerConfig holds the connection settings that are common to
@@ -91,14 +144,172 @@ type MessageQueueConfig struct {
 	Broker ExternalBrokerConfig `yaml:"broker"`
 }
 
+// ValidationConfig controls how profile attributes are validated against the profile schema.
+type ValidationConfig struct {
+	// UnknownAttributes is "reject" (default) to fail writes carrying attributes that are not in the schema,
+	// "pass_through" to store them without validation, or "coerce" to store them as strings.
+	UnknownAttributes string `yaml:"unknown_attributes"`
+	// RejectUnknownSyncFields fails profile sync events carrying fields that are not known to the server.
+	RejectUnknownSyncFields bool `yaml:"reject_unknown_sync_fields"`
+	// PreserveNumberPrecision keeps attribute numbers as their exact JSON text instead of float64, so integers
+	// beyond 2^53 such as 64-bit external ids are not rounded.
+	PreserveNumberPrecision bool `yaml:"preserve_number_precision"`
+}
+
+// TraitConflictConfig controls which value is shown when the profiles unified into a reference profile hold
+// different values for the same trait.
+type TraitConflictConfig struct {
+	// Strategy is "highest_priority" (default) to prefer the reference profile and then the profiles unified by
+	// higher priority rules, "latest_updated" to prefer the most recently updated profile, or "non_null" to prefer
+	// the reference profile unless its value is empty.
+	Strategy string `yaml:"strategy"`
+}
+
+// ProfileIdConfig controls how the ids of new profiles are generated.
+type ProfileIdConfig struct {
+	// Strategy is "uuid_v4" (default) for random ids, "uuid_v7" for time-ordered ids that keep the index of new
+	// profiles compact, or "caller_provided" to let profile creations carry their own profile_id. With
+	// "caller_provided", profiles created without one, such as the reference profiles of unified profiles, get
+	// time-ordered ids.
+	Strategy string `yaml:"strategy"`
+}
+
+// IdempotencyConfig controls how long the idempotency key of a profile creation is remembered.
+type IdempotencyConfig struct {
+	// KeyTTL is how long a retried request with the same key returns the profile created by the first one
+	// (e.g. "24h"). Defaults to 24 hours.
+	KeyTTL time.Duration `yaml:"key_ttl"`
+	// ClaimTTL is how long a creation in progress holds its key. A claim left behind by a request that never
+	// finished, e.g. because the process crashed, is released after it. Defaults to 2 minutes.
+	ClaimTTL time.Duration `yaml:"claim_ttl"`
+	// WaitTimeout is how long a retry waits for a creation in progress with the same key to finish before it is
+	// rejected with a conflict. Defaults to 0, which rejects it right away.
+	WaitTimeout time.Duration `yaml:"wait_timeout"`
+}
+
+// WebhookConfig controls the delivery of profile events to the webhooks subscribed to them.
+type WebhookConfig struct {
+	// MaxAttempts is the total number of delivery attempts of an event, including the first one.
+	MaxAttempts int `yaml:"max_attempts"`
+	// InitialBackoff is the wait before the first retry; later waits double up to MaxBackoff (e.g. "1s").
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	// Timeout bounds a single delivery attempt (e.g. "10s").
+	Timeout time.Duration `yaml:"timeout"`
+	// Workers is the number of events delivered at the same time. Defaults to 4.
+	Workers int `yaml:"workers"`
+	// AllowPrivateNetworks lets webhooks point at loopback, private and link-local addresses. It is off by default
+	// so that webhooks cannot reach internal services; only enable it where every webhook is trusted.
+	AllowPrivateNetworks bool `yaml:"allow_private_networks"`
+}
+
+// ChangeStreamConfig selects the publisher of profile change events. Changes are not published when Type is empty.
+type ChangeStreamConfig struct {
+	// Type is the name of a registered publisher (e.g. "kafka").
+	Type  string            `yaml:"type"`
+	Kafka KafkaStreamConfig `yaml:"kafka"`
+}
+
+// KafkaStreamConfig configures the publishing of profile change events to a Kafka topic through a Kafka REST proxy.
+type KafkaStreamConfig struct {
+	// RestProxyURL is the base URL of the REST proxy (e.g. "http://kafka-rest:8082").
+	RestProxyURL string `yaml:"rest_proxy_url"`
+	Topic        string `yaml:"topic"`
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	// Timeout bounds a single publish request (e.g. "10s").
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// ProfileHistoryConfig bounds the trait history kept for each profile. Every PruneInterval, the history recorded
+// more than MaxAge ago and the entries of a profile beyond its latest MaxSnapshots are removed. A zero MaxAge or
+// MaxSnapshots does not limit the history by it.
+type ProfileHistoryConfig struct {
+	MaxAge        time.Duration `yaml:"max_age"`
+	MaxSnapshots  int           `yaml:"max_snapshots"`
+	PruneInterval time.Duration `yaml:"prune_interval"`
+}
+
+// RateLimit is a token bucket that refills at RequestsPerSecond and holds at most Burst requests. A zero
+// RequestsPerSecond leaves the requests unlimited.
+type RateLimit struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+}
+
+// RateLimitConfig throttles the tenant APIs. Requests of an organization share its bucket and, when Application
+// sets a limit, requests of each calling application also draw from a bucket of their own. Organizations listed
+// in Organizations use that limit instead of Organization.
+type RateLimitConfig struct {
+	Enabled       bool                 `yaml:"enabled"`
+	Organization  RateLimit            `yaml:"organization"`
+	Application   RateLimit            `yaml:"application"`
+	Organizations map[string]RateLimit `yaml:"organizations"`
+}
+
+// NormalizationConfig controls how string values of profile attributes and
+// filters are normalized before they are stored or matched. Both
+// normalizations are applied unless explicitly disabled.
+type NormalizationConfig struct {
+	// DisableUnicodeNormalization skips converting values to Unicode NFC.
+	DisableUnicodeNormalization bool `yaml:"disable_unicode_normalization"`
+	// DisableWhitespaceTrimming keeps leading and trailing whitespace and
+	// non-breaking spaces in values as received.
+	DisableWhitespaceTrimming bool `yaml:"disable_whitespace_trimming"`
+}
+
+// QuarantinePolicy decides when a profile import source is quarantined and
+// what happens to its records afterwards. Zero values fall back to defaults.
+type QuarantinePolicy struct {
+	// FailureRateThreshold is the ratio (0-1] of records failing validation
+	// within the window that trips the quarantine.
+	FailureRateThreshold float64 `yaml:"failure_rate_threshold"`
+	// MinRecords is the number of records needed in the window before the
+	// failure rate is evaluated.
+	MinRecords int `yaml:"min_records"`
+	// Window is the period over which failures are counted (e.g. "5m").
+	Window time.Duration `yaml:"window"`
+	// Action is applied to records of a quarantined source: "quarantine"
+	// stores them for review while "discard" drops them.
+	Action string `yaml:"action"`
+}
+
+// ImportQuarantineConfig configures the quarantine of profile import sources
+// that keep sending records failing validation. Sources not listed use the
+// default policy.
+type ImportQuarantineConfig struct {
+	Enabled bool                        `yaml:"enabled"`
+	Default QuarantinePolicy            `yaml:"default"`
+	Sources map[string]QuarantinePolicy `yaml:"sources"`
+}
+
+// PortableExportConfig points to the certificate and private key used to sign
+// portable profile exports. Relative paths are resolved against the TLS cert
+// directory. When unset, the server TLS certificate and key are used.
+type PortableExportConfig struct {
+	SigningCert string `yaml:"signing_cert"`
+	SigningKey  string `yaml:"signing_key"`
+}
+
 type Config struct {
-	Addr         AddrConfig         `yaml:"addr"`
-	Log          LogConfig          `yaml:"log"`
-	Auth         AuthConfig         `yaml:"auth"`
-	AuthServer   AuthServerConfig   `yaml:"auth_server"`
-	DataSource   DataSourceConfig   `yaml:"datasource"`
-	TLS          TLSConfig          `yaml:"tls"`
-	MessageQueue MessageQueueConfig `yaml:"message_queue"`
+	Addr             AddrConfig             `yaml:"addr"`
+	Log              LogConfig              `yaml:"log"`
+	Auth             AuthConfig             `yaml:"auth"`
+	AuthServer       AuthServerConfig       `yaml:"auth_server"`
+	DataSource       DataSourceConfig       `yaml:"datasource"`
+	TLS              TLSConfig              `yaml:"tls"`
+	MessageQueue     MessageQueueConfig     `yaml:"message_queue"`
+	Normalization    NormalizationConfig    `yaml:"normalization"`
+	Validation       ValidationConfig       `yaml:"profile_validation"`
+	TraitConflicts   TraitConflictConfig    `yaml:"trait_conflicts"`
+	ProfileIds       ProfileIdConfig        `yaml:"profile_ids"`
+	Idempotency      IdempotencyConfig      `yaml:"idempotency"`
+	ImportQuarantine ImportQuarantineConfig `yaml:"import_quarantine"`
+	PortableExport   PortableExportConfig   `yaml:"portable_export"`
+	Webhooks         WebhookConfig          `yaml:"webhooks"`
+	ChangeStream     ChangeStreamConfig     `yaml:"change_stream"`
+	RateLimit        RateLimitConfig        `yaml:"rate_limit"`
+	ProfileHistory   ProfileHistoryConfig   `yaml:"profile_history"`
 }
 
 type TLSConfig struct {
@@ -1,5 +1,7 @@
 package constants
 
+import "time"
+
 const ApiBasePath = "/cds/api"
 const ProfileApiPath = "profiles"
 const UnificationRulesApiPath = "unification-rules"
@@ -7,8 +9,19 @@ const ConsentApiPath = "consent"
 const ProfileSchemaApiPath = "profile-schema"
 const IdentityServerDialectsPath = "/api/server/v1/claim-dialects"
 const Filter = "filter"
-const Attributes = "attributes"     // Query parameter to filter attributes in the request.
-const ProfileCookie = "cds_profile" // Cookie name to store cookie that corresponds to profile ID.
+const Attributes = "attributes"         // Query parameter to filter attributes in the request.
+const Sort = "sort"                     // Query parameter to order the profile listing.
+const IncludeDeleted = "includeDeleted" // Query parameter to include soft-deleted profiles in the listing.
+const ModifiedSince = "modifiedSince"   // Query parameter to list only the profiles updated since a time.
+const RuleName = "ruleName"             // Query parameter to look up a unification rule by its name.
+const ResolveAttribute = "attr"         // Query parameter naming the identity attribute to resolve a profile by.
+const ResolveValue = "value"            // Query parameter holding the identity attribute value to resolve.
+const Fields = "fields"                 // Query parameter to project a profile to the given fields.
+const GroupBy = "groupBy"               // Query parameter naming the trait to count profiles by.
+const Trait = "trait"                   // Query parameter naming the trait to list the distinct values of.
+const Limit = "limit"                   // Query parameter capping the number of distinct trait values.
+const OrderBy = "orderBy"               // Query parameter ordering distinct trait values by "value" or "frequency".
+const ProfileCookie = "cds_profile"     // Cookie name to store cookie that corresponds to profile ID.
 const DefaultTenant = "carbon.super"
 const SpaceSeparator = " "
 const SystemAppHeader = "SystemApp"
@@ -21,6 +34,17 @@ const ActiveClaim = "active"
 const OrgHandleClaim = "org_handle"
 const AudienceClaim = "aud"
 const ExpiryClaim = "exp"
+const SubjectClaim = "sub"
+const ScopeClaim = "scope"
+
+// The JWKS of an organization is cached for JWKSCacheTTL. A token signed with an unknown key refreshes it early,
+// at most once per JWKSMinRefreshInterval, which also spaces the fetches of a JWKS the server does not provide.
+// Key sets larger than MaxJWKSSize bytes are rejected.
+const (
+	JWKSCacheTTL           = 10 * time.Minute
+	JWKSMinRefreshInterval = time.Minute
+	MaxJWKSSize            = 1 << 20
+)
 const FilterRegex = `^[a-zA-Z0-9._-]+$`
 
 type contextKey string
@@ -150,6 +174,193 @@ const (
 	MergedTo         = "MERGED_TO"
 )
 
+// ManualMergeReason is the reference reason of profiles merged by an administrator instead of a unification rule.
+const ManualMergeReason = "manual_merge"
+
+// AliasMergeReason is the reference reason of profiles merged by an alias linking an anonymous visitor to a known
+// profile.
+const AliasMergeReason = "alias"
+
+// Merge modes of unification rules. A full merge combines the data of the profiles by the merge strategies of the
+// profile schema, a trait union keeps the values of the master and adds the values it lacks, combining multi valued
+// attributes, and a link only merge links the profiles without combining their data. Rules added without a merge
+// mode, and the rules added before merge modes existed, merge fully.
+const (
+	MergeModeFull       = "full"
+	MergeModeLinkOnly   = "link-only"
+	MergeModeTraitUnion = "trait-union"
+)
+
+// Profile job types, statuses and phases
+const (
+	ProfileJobReunifyAll     = "REUNIFY_ALL"
+	ProfileJobRunning        = "RUNNING"
+	ProfileJobCompleted      = "COMPLETED"
+	ProfileJobFailed         = "FAILED"
+	ProfileJobCancelled      = "CANCELLED"
+	ProfileJobPhaseSplit     = "SPLIT"
+	ProfileJobPhaseMerge     = "MERGE"
+	ProfileJobBatchSize      = 100
+	ProfileJobStaleAfter     = time.Minute
+	ProfileJobResumeInterval = 30 * time.Second
+)
+
+// AnonymizedValuePrefix prefixes the random tokens that replace personal data of anonymized profiles.
+const AnonymizedValuePrefix = "anonymized-"
+
+// DefaultDBQueryTimeout is used when the datasource does not configure a query timeout.
+const DefaultDBQueryTimeout = 30 * time.Second
+
+// Connection pool settings used when the datasource does not configure them
+const (
+	DefaultDBMaxOpenConns    = 25
+	DefaultDBMaxIdleConns    = 10
+	DefaultDBConnMaxLifetime = 30 * time.Minute
+	DefaultDBConnMaxIdleTime = 5 * time.Minute
+)
+
+// Retry settings of transient database errors used when the datasource does not configure them
+const (
+	DefaultDBRetryMaxAttempts    = 3
+	DefaultDBRetryInitialBackoff = 100 * time.Millisecond
+	DefaultDBRetryMaxBackoff     = 2 * time.Second
+)
+
+// DefaultDBStatementCacheSize is the number of distinct queries whose prepared statements are kept per connection
+// pool when the datasource does not configure it.
+const DefaultDBStatementCacheSize = 256
+
+// Graceful shutdown settings. The server reports not ready for the readiness delay before it stops accepting
+// requests. Requests still running after the shutdown timeout are cancelled and given the abort grace period to roll
+// back before the database is closed.
+const (
+	DefaultShutdownReadinessDelay = 5 * time.Second
+	DefaultShutdownTimeout        = 15 * time.Second
+	ShutdownAbortGracePeriod      = 5 * time.Second
+)
+
+// ReadinessCheckTimeout bounds each component check of the readiness endpoint.
+const ReadinessCheckTimeout = 2 * time.Second
+
+// DeletedProfileRetentionPeriod is how long soft-deleted profiles are kept restorable before they are purged.
+const DeletedProfileRetentionPeriod = 30 * 24 * time.Hour
+
+// Idempotency keys of profile creations
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	MaxIdempotencyKeyLength  = 255
+	DefaultIdempotencyKeyTTL = 24 * time.Hour
+	// DefaultIdempotencyClaimTTL comfortably exceeds a creation retried up to the default query timeout.
+	DefaultIdempotencyClaimTTL  = 2 * time.Minute
+	IdempotencyWaitPollInterval = 100 * time.Millisecond
+)
+
+// Profile events delivered to webhooks, and the delivery settings used when they are not configured
+const (
+	WebhookEventProfileCreated   = "profile.created"
+	WebhookEventProfileMerged    = "profile.merged"
+	WebhookEventProfileDeleted   = "profile.deleted"
+	WebhookSignatureHeader       = "X-CDS-Signature"
+	WebhookTimestampHeader       = "X-CDS-Timestamp"
+	WebhookEventHeader           = "X-CDS-Event"
+	WebhookDeliveryHeader        = "X-CDS-Delivery"
+	DefaultWebhookMaxAttempts    = 5
+	DefaultWebhookInitialBackoff = time.Second
+	DefaultWebhookMaxBackoff     = 30 * time.Second
+	DefaultWebhookTimeout        = 10 * time.Second
+	DefaultWebhookWorkers        = 4
+	WebhookQueueSize             = 10000
+	WebhookDrainTimeout          = 10 * time.Second
+	WebhookResolveTimeout        = 5 * time.Second
+)
+
+// Profile change events published to the change stream
+const (
+	ProfileChangeCreated            = "created"
+	ProfileChangeUpdated            = "updated"
+	ProfileChangeMerged             = "merged"
+	ProfileChangeDeleted            = "deleted"
+	ProfileChangeQueueSize          = 10000
+	ProfileChangePublishAttempts    = 5
+	ProfileChangePublishBackoff     = 500 * time.Millisecond
+	DefaultChangeStreamKafkaTopic   = "cds-profile-changes"
+	DefaultChangeStreamKafkaTimeout = 10 * time.Second
+)
+
+// Streaming profile import limits
+const (
+	MaxProfileImportSize     = 1 << 30 // ceiling for the whole NDJSON stream
+	MaxProfileImportLineSize = 1 << 20
+	ProfileImportWorkers     = 4
+	ProfileImportIdleTimeout = 30 * time.Second
+)
+
+// Distinct trait values are listed DefaultDistinctTraitValues at a time unless a limit of at most
+// MaxDistinctTraitValues is given.
+const (
+	DefaultDistinctTraitValues = 100
+	MaxDistinctTraitValues     = 1000
+	OrderByValue               = "value"
+	OrderByFrequency           = "frequency"
+)
+
+// Streamed profile listings and exports resolve the unified profiles of ProfileExportFlushRows profiles at a time and
+// flush them to the client, and are abandoned when the client does not take a write within ProfileExportIdleTimeout.
+const (
+	ProfileExportFlushRows   = 100
+	ProfileExportIdleTimeout = 30 * time.Second
+)
+
+// MaxUnificationRuleBatchSize caps the number of unification rules added in one batch.
+const MaxUnificationRuleBatchSize = 200
+
+// Batch profile ingestion limits
+const (
+	MaxProfileBatchSize        = 1000
+	MaxProfileBatchRequestSize = 10 << 20
+	ProfileBatchTimeout        = 30 * time.Second
+)
+
+// Profile import quarantine
+const (
+	ImportSource                 = "source"
+	DefaultImportSource          = "default"
+	QuarantineActionStore        = "quarantine"
+	QuarantineActionDiscard      = "discard"
+	DefaultQuarantineFailureRate = 0.5
+	DefaultQuarantineMinRecords  = 20
+	DefaultQuarantineWindow      = 5 * time.Minute
+)
+
+// Handling of profile attributes that are not in the profile schema
+const (
+	UnknownAttributesReject      = "reject"
+	UnknownAttributesPassThrough = "pass_through"
+	UnknownAttributesCoerce      = "coerce"
+)
+
+// Generation of the ids of new profiles
+const (
+	ProfileIdUUIDv4         = "uuid_v4"
+	ProfileIdUUIDv7         = "uuid_v7"
+	ProfileIdCallerProvided = "caller_provided"
+	MaxProfileIdLength      = 255
+)
+
+// Resolution of conflicting trait values between the profiles of a unified hierarchy
+const (
+	TraitConflictHighestPriority = "highest_priority"
+	TraitConflictLatestUpdated   = "latest_updated"
+	TraitConflictNonNull         = "non_null"
+)
+
+// Portable profile export package
+const (
+	PortableProfileFormat  = "wso2-cds-portable-profile"
+	PortableProfileVersion = "1.0"
+	PortableProfileDigest  = "SHA-256"
+)
+
 var AllowedFilterFieldsForSchema = map[string]bool{
 	"attribute_name":         true,
 	"application_identifier": true,
@@ -160,3 +371,15 @@ const (
 	ConfigInitialSchemaSyncDone = "initial_schema_sync_done"
 	ConfigSystemApplications    = "system_applications"
 )
+
+// Rate limiting of the tenant APIs. Buckets left untouched for RateLimitIdleTimeout are dropped, and the usage of
+// each bucket is reported under one of the scopes.
+const (
+	RateLimitIdleTimeout      = 10 * time.Minute
+	RateLimitScopeOrg         = "organization"
+	RateLimitScopeApplication = "application"
+	RateLimitResource         = "rate limit usage"
+)
+
+// DefaultProfileHistoryPruneInterval is how often the profile trait history is pruned when it is not configured.
+const DefaultProfileHistoryPruneInterval = time.Hour
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// RunWithAdvisoryLock runs the function while holding the Postgres advisory lock of the key, waiting for any other
+// holder of the lock, in this process or another one, to release it first. The lock is held on a connection of its
+// own, so the function runs its queries on other connections of the pool as usual.
+func (client *DBClient) RunWithAdvisoryLock(ctx context.Context, key string, fn func() error) error {
+
+	conn, err := client.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext($1))`, key); err != nil {
+		return err
+	}
+	defer func() {
+		// The lock is released with the session if unlocking fails, as the connection is then discarded.
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`,
+			key); err != nil {
+			if logger := log.GetLogger(); logger != nil {
+				logger.Debug("Failed to release advisory lock", log.String("key", key), log.Error(err))
+			}
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+	}()
+	return fn()
+}
@@ -19,68 +19,263 @@
 package client
 
 import (
+	"context"
 	"database/sql"
-	"os"
 	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/wso2/identity-customer-data-service/internal/system/metrics"
+	"github.com/wso2/identity-customer-data-service/internal/system/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // DBClientInterface defines the interface for database operations.
 type DBClientInterface interface {
 	ExecuteQuery(query string, args ...interface{}) ([]map[string]interface{}, error)
+	ExecuteQueryContext(ctx context.Context, query string, args ...interface{}) ([]map[string]interface{}, error)
+	ExecuteQueryWithRetry(opts RetryOptions, query string, args ...interface{}) ([]map[string]interface{}, error)
+	ExecuteQueryWithRetryContext(ctx context.Context, opts RetryOptions, query string, args ...interface{}) ([]map[string]interface{}, error)
+	ExecuteQueryTyped(query string, args ...interface{}) ([]map[string]interface{}, []ColumnType, error)
+	StreamQueryContext(ctx context.Context, query string, handle func(row map[string]interface{}) error, args ...interface{}) error
 	BeginTx() (*sql.Tx, error)
+	BeginTxContext(ctx context.Context) (*sql.Tx, error)
+	RunInTx(fn func(tx *sql.Tx) error) error
+	RunInTxContext(ctx context.Context, fn func(tx *sql.Tx) error) error
+	RunWithAdvisoryLock(ctx context.Context, key string, fn func() error) error
 	Close() error
 }
 
 // DBClient is the implementation of DBClientInterface.
 type DBClient struct {
-	db *sql.DB
+	db                 *sql.DB
+	queryTimeout       time.Duration
+	retryPolicy        RetryPolicy
+	slowQueryThreshold time.Duration
+	statements         *statementCache
 }
 
-// NewDBClient creates a new instance of DBClient with the provided database connection.
-func NewDBClient(db *sql.DB) DBClientInterface {
+// NewDBClient creates a new instance of DBClient with the provided database connection. Queries run through
+// ExecuteQuery are cancelled after the query timeout, and ExecuteQueryWithRetry retries by the retry policy.
+// Queries taking longer than a positive slow query threshold are logged. With a positive statement cache size,
+// queries are run through prepared statements shared by all clients of the connection pool, up to that many
+// distinct queries.
+func NewDBClient(db *sql.DB, queryTimeout time.Duration, retryPolicy RetryPolicy,
+	slowQueryThreshold time.Duration, statementCacheSize int) DBClientInterface {
 
-	return &DBClient{
-		db: db,
+	client := &DBClient{
+		db:                 db,
+		queryTimeout:       queryTimeout,
+		retryPolicy:        retryPolicy,
+		slowQueryThreshold: slowQueryThreshold,
 	}
+	if statementCacheSize > 0 {
+		client.statements = statementCacheOf(db, statementCacheSize)
+	}
+	return client
 }
 
 // ExecuteQuery executes a SELECT query and returns the result as a slice of maps.
 func (client *DBClient) ExecuteQuery(query string, args ...interface{}) ([]map[string]interface{}, error) {
 
-	rows, err := client.db.Query(query, args...)
+	return client.ExecuteQueryContext(context.Background(), query, args...)
+}
+
+// ExecuteQueryContext executes a SELECT query and returns the result as a slice of maps. The query is cancelled
+// when the context is done or after the query timeout, whichever comes first. When the context carries a span,
+// the query is traced as its child.
+func (client *DBClient) ExecuteQueryContext(ctx context.Context, query string,
+	args ...interface{}) ([]map[string]interface{}, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, client.queryTimeout)
+	defer cancel()
+	results, _, err := client.query(ctx, query, args...)
+	return results, err
+}
+
+// ExecuteQueryWithRetry executes a query like ExecuteQuery and runs it again, with a jittered exponential backoff,
+// when it fails with a transient error. Only reads are retried unless the options allow writes. Each attempt
+// gets its own query timeout.
+func (client *DBClient) ExecuteQueryWithRetry(opts RetryOptions, query string,
+	args ...interface{}) ([]map[string]interface{}, error) {
+
+	return client.ExecuteQueryWithRetryContext(context.Background(), opts, query, args...)
+}
+
+// ExecuteQueryWithRetryContext executes a query like ExecuteQueryWithRetry as part of the context. Once the
+// context is done, no further attempt is made and the wait for the next attempt is cut short.
+func (client *DBClient) ExecuteQueryWithRetryContext(ctx context.Context, opts RetryOptions, query string,
+	args ...interface{}) ([]map[string]interface{}, error) {
+
+	retryable := opts.AllowWrites || isReadQuery(query)
+	for attempt := 1; ; attempt++ {
+		results, err := client.ExecuteQueryContext(ctx, query, args...)
+		if err == nil {
+			metrics.DBQueryAttempts.Inc("success")
+			return results, nil
+		}
+		if !retryable || attempt >= client.retryPolicy.MaxAttempts || !IsRetryableError(err) || ctx.Err() != nil {
+			metrics.DBQueryAttempts.Inc("failure")
+			return results, err
+		}
+		metrics.DBQueryAttempts.Inc("retry")
+		timer := time.NewTimer(client.retryPolicy.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			metrics.DBQueryAttempts.Inc("cancelled")
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// ExecuteQueryTyped executes a SELECT query like ExecuteQuery and also returns the type metadata of the result
+// columns, so that callers can read values through the row accessors instead of asserting driver types.
+func (client *DBClient) ExecuteQueryTyped(query string,
+	args ...interface{}) ([]map[string]interface{}, []ColumnType, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), client.queryTimeout)
+	defer cancel()
+	return client.query(ctx, query, args...)
+}
+
+func (client *DBClient) query(ctx context.Context, query string,
+	args ...interface{}) (_ []map[string]interface{}, _ []ColumnType, err error) {
+
+	label := queryLabel(query)
+	start := time.Now()
+	defer func() {
+		elapsed := time.Since(start)
+		metrics.DBQueryDuration.Observe(elapsed.Seconds(), label)
+		client.logSlowQuery(query, elapsed, args)
+	}()
+	if trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		var span trace.Span
+		ctx, span = tracing.Start(ctx, "db.query", tracing.QueryNameKey.String(label))
+		defer func() { tracing.End(span, err) }()
+	}
+
+	rows, err := client.queryRows(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
 	columns, err := rows.Columns()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	types := make([]ColumnType, len(columnTypes))
+	for i, columnType := range columnTypes {
+		nullable, _ := columnType.Nullable()
+		types[i] = ColumnType{
+			Name:         strings.ToLower(columnType.Name()),
+			DatabaseType: columnType.DatabaseTypeName(),
+			Nullable:     nullable,
+		}
 	}
 
 	var results []map[string]interface{}
 	for rows.Next() {
-		row := make([]interface{}, len(columns))
-		rowPointers := make([]interface{}, len(columns))
-		for i := range row {
-			rowPointers[i] = &row[i]
+		result, err := scanRow(rows, columns)
+		if err != nil {
+			return nil, nil, err
 		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
 
-		if err := rows.Scan(rowPointers...); err != nil {
-			return nil, err
-		}
+	return results, types, nil
+}
 
-		result := map[string]interface{}{}
-		for i, col := range columns {
-			// Normalize column names to lowercase for consistency.
-			result[strings.ToLower(col)] = row[i]
+// StreamQueryContext executes a SELECT query and hands each row to handle as soon as it is read, so that large
+// results are never held in memory. The query is bounded by the context only, not by the query timeout, as reading
+// the rows takes as long as handling them. An error returned by handle stops the query and is returned as is.
+func (client *DBClient) StreamQueryContext(ctx context.Context, query string,
+	handle func(row map[string]interface{}) error, args ...interface{}) (err error) {
+
+	label := queryLabel(query)
+	start := time.Now()
+	defer func() {
+		metrics.DBQueryDuration.Observe(time.Since(start).Seconds(), label)
+	}()
+	if trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		var span trace.Span
+		ctx, span = tracing.Start(ctx, "db.query", tracing.QueryNameKey.String(label))
+		defer func() { tracing.End(span, err) }()
+	}
+
+	rows, err := client.queryRows(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		row, err := scanRow(rows, columns)
+		if err != nil {
+			return err
+		}
+		if err := handle(row); err != nil {
+			return err
 		}
-		results = append(results, result)
 	}
+	return rows.Err()
+}
 
-	return results, nil
+// queryRows runs the query through its prepared statement when the client caches statements, and directly on the
+// pool otherwise. Queries without arguments are never prepared, so that they may hold several statements. A
+// statement whose plan went stale after a schema change is prepared again on its next use.
+func (client *DBClient) queryRows(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+
+	if client.statements == nil || len(args) == 0 {
+		return client.db.QueryContext(ctx, query, args...)
+	}
+	stmt, err := client.statements.prepare(ctx, client.db, query)
+	if err != nil {
+		return nil, err
+	}
+	if stmt == nil {
+		return client.db.QueryContext(ctx, query, args...)
+	}
+	rows, err := stmt.QueryContext(ctx, args...)
+	if isStalePlanError(err) {
+		client.statements.discard(query, stmt)
+		return client.db.QueryContext(ctx, query, args...)
+	}
+	return rows, err
+}
+
+// scanRow reads the current row into a map keyed by the lower-cased column names.
+func scanRow(rows *sql.Rows, columns []string) (map[string]interface{}, error) {
+
+	row := make([]interface{}, len(columns))
+	rowPointers := make([]interface{}, len(columns))
+	for i := range row {
+		rowPointers[i] = &row[i]
+	}
+	if err := rows.Scan(rowPointers...); err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{}
+	for i, col := range columns {
+		// Normalize column names to lowercase for consistency.
+		result[strings.ToLower(col)] = row[i]
+	}
+	return result, nil
 }
 
 // BeginTx starts a new database transaction.
@@ -89,10 +284,33 @@ func (client *DBClient) BeginTx() (*sql.Tx, error) {
 	return client.db.Begin()
 }
 
-// Close closes the database connection.
+// BeginTxContext starts a new database transaction that is rolled back when the context is done before it is
+// committed. Waiting for a connection from the pool is also abandoned when the context is done.
+func (client *DBClient) BeginTxContext(ctx context.Context) (*sql.Tx, error) {
+
+	return client.db.BeginTx(ctx, nil)
+}
+
+// Close releases the client. The connection pool is shared by all clients and stays open; connections are
+// returned to it as soon as each query or transaction completes.
 func (c *DBClient) Close() error {
-	if os.Getenv("TEST_MODE") == "true" {
-		return nil
+	return nil
+}
+
+// queryLabel names a query by its statement and the table it targets, such as "select profiles", so that query
+// metrics are grouped without the cardinality of the full statement.
+func queryLabel(query string) string {
+
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return "unknown"
+	}
+	statement := words[0]
+	for i := 0; i < len(words)-1; i++ {
+		switch words[i] {
+		case "from", "into", "update":
+			return statement + " " + strings.Trim(words[i+1], "(;")
+		}
 	}
-	return c.db.Close()
+	return statement
 }
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package client
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RetryPolicy controls how transient database errors are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// RetryOptions are the per-call settings of ExecuteQueryWithRetry.
+type RetryOptions struct {
+	// AllowWrites permits retrying statements that are not reads. Only set it for writes that are safe to
+	// apply more than once.
+	AllowWrites bool
+}
+
+// retryableSQLStates are the Postgres error codes after which a statement may succeed when run again.
+var retryableSQLStates = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"08000": true, // connection_exception
+	"08001": true, // sqlclient_unable_to_establish_sqlconnection
+	"08003": true, // connection_does_not_exist
+	"08004": true, // sqlserver_rejected_establishment_of_sqlconnection
+	"08006": true, // connection_failure
+	"53300": true, // too_many_connections
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// IsRetryableError reports whether the error is a transient database error worth retrying.
+func IsRetryableError(err error) bool {
+
+	if err == nil {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return retryableSQLStates[pqErr.Code]
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Backoff returns how long to wait before the given retry (starting at 1). The wait grows exponentially up to
+// the maximum backoff and is jittered so that clients failing together do not retry together.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+
+	if p.InitialBackoff <= 0 {
+		return 0
+	}
+	backoff := p.InitialBackoff
+	for i := 1; i < retry && (p.MaxBackoff <= 0 || backoff < p.MaxBackoff); i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff/2 + rand.N(backoff/2+1)
+}
+
+// isReadQuery reports whether the statement only reads data and can therefore be repeated safely.
+func isReadQuery(query string) bool {
+
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(query)), "SELECT")
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ColumnType describes a column of a query result.
+type ColumnType struct {
+	Name         string
+	DatabaseType string
+	Nullable     bool
+}
+
+// GetString reads a text column of a result row. Drivers may return text as string or []byte.
+func GetString(row map[string]interface{}, col string) (string, error) {
+
+	switch v := row[col].(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case nil:
+		return "", nullColumnError(row, col)
+	default:
+		return "", unexpectedTypeError(col, v)
+	}
+}
+
+// GetNullableString reads a text column of a result row, returning an empty string when the value is NULL.
+func GetNullableString(row map[string]interface{}, col string) (string, error) {
+
+	if row[col] == nil {
+		if _, exists := row[col]; !exists {
+			return "", fmt.Errorf("column %s is not in the result", col)
+		}
+		return "", nil
+	}
+	return GetString(row, col)
+}
+
+// GetInt64 reads an integer column of a result row, accepting any integer width the driver returns.
+func GetInt64(row map[string]interface{}, col string) (int64, error) {
+
+	switch v := row[col].(type) {
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case []byte:
+		parsed, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("column %s does not hold an integer: %w", col, err)
+		}
+		return parsed, nil
+	case nil:
+		return 0, nullColumnError(row, col)
+	default:
+		return 0, unexpectedTypeError(col, v)
+	}
+}
+
+// GetBool reads a boolean column of a result row.
+func GetBool(row map[string]interface{}, col string) (bool, error) {
+
+	switch v := row[col].(type) {
+	case bool:
+		return v, nil
+	case []byte:
+		parsed, err := strconv.ParseBool(string(v))
+		if err != nil {
+			return false, fmt.Errorf("column %s does not hold a boolean: %w", col, err)
+		}
+		return parsed, nil
+	case nil:
+		return false, nullColumnError(row, col)
+	default:
+		return false, unexpectedTypeError(col, v)
+	}
+}
+
+// GetTime reads a timestamp column of a result row.
+func GetTime(row map[string]interface{}, col string) (time.Time, error) {
+
+	switch v := row[col].(type) {
+	case time.Time:
+		return v, nil
+	case nil:
+		return time.Time{}, nullColumnError(row, col)
+	default:
+		return time.Time{}, unexpectedTypeError(col, v)
+	}
+}
+
+func nullColumnError(row map[string]interface{}, col string) error {
+
+	if _, exists := row[col]; !exists {
+		return fmt.Errorf("column %s is not in the result", col)
+	}
+	return fmt.Errorf("column %s is null", col)
+}
+
+func unexpectedTypeError(col string, value interface{}) error {
+
+	return fmt.Errorf("column %s has unexpected type %T", col, value)
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+)
+
+// logSlowQuery logs the query when it took longer than the slow query threshold of the client. The arguments are
+// described by their type and size only, as they carry profile data.
+func (client *DBClient) logSlowQuery(query string, elapsed time.Duration, args []interface{}) {
+
+	if client.slowQueryThreshold <= 0 || elapsed < client.slowQueryThreshold {
+		return
+	}
+	logger := log.GetLogger()
+	if logger == nil {
+		return
+	}
+	logger.Warn("Slow database query",
+		log.String("query", strings.Join(strings.Fields(query), " ")),
+		log.String("duration", elapsed.String()),
+		log.Any("args", describeQueryArgs(args)))
+}
+
+// describeQueryArgs describes the query arguments without their values, apart from numbers and booleans that do not
+// identify anyone and tell which branch of a query was taken.
+func describeQueryArgs(args []interface{}) []string {
+
+	described := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch value := arg.(type) {
+		case nil:
+			described = append(described, "NULL")
+		case bool, int, int32, int64, float32, float64:
+			described = append(described, fmt.Sprint(value))
+		case string:
+			described = append(described, fmt.Sprintf("<string len=%d>", len(value)))
+		case []byte:
+			described = append(described, fmt.Sprintf("<bytes len=%d>", len(value)))
+		case time.Time:
+			described = append(described, "<time>")
+		default:
+			described = append(described, fmt.Sprintf("<%T>", value))
+		}
+	}
+	return described
+}
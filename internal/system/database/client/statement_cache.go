@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// statementCache holds the prepared statements of a connection pool, keyed by query text. It stops taking new
+// statements once full, so that queries built with varying text can not grow it without bound; those run
+// unprepared.
+type statementCache struct {
+	mu         sync.Mutex
+	size       int
+	statements map[string]*sql.Stmt
+	// stale are the statements replaced after the schema changed under them. They are only closed with the cache,
+	// as queries may still be running through them.
+	stale []*sql.Stmt
+}
+
+var (
+	statementCaches   = make(map[*sql.DB]*statementCache)
+	statementCachesMu sync.Mutex
+)
+
+// statementCacheOf returns the statement cache of the connection pool, creating it with the given size on first use.
+func statementCacheOf(db *sql.DB, size int) *statementCache {
+
+	statementCachesMu.Lock()
+	defer statementCachesMu.Unlock()
+	cache, ok := statementCaches[db]
+	if !ok {
+		cache = &statementCache{size: size, statements: make(map[string]*sql.Stmt)}
+		statementCaches[db] = cache
+	}
+	return cache
+}
+
+// CloseStatements closes the prepared statements cached for the connection pool. It must be called before the pool
+// is closed.
+func CloseStatements(db *sql.DB) error {
+
+	statementCachesMu.Lock()
+	cache, ok := statementCaches[db]
+	delete(statementCaches, db)
+	statementCachesMu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	var errs []error
+	for _, stmt := range cache.statements {
+		errs = append(errs, stmt.Close())
+	}
+	for _, stmt := range cache.stale {
+		errs = append(errs, stmt.Close())
+	}
+	cache.statements = make(map[string]*sql.Stmt)
+	cache.stale = nil
+	return errors.Join(errs...)
+}
+
+// prepare returns the prepared statement of the query, preparing it on first use. It returns nil when the cache is
+// full and the query has not been prepared before.
+func (cache *statementCache) prepare(ctx context.Context, db *sql.DB, query string) (*sql.Stmt, error) {
+
+	cache.mu.Lock()
+	stmt, ok := cache.statements[query]
+	full := len(cache.statements) >= cache.size
+	cache.mu.Unlock()
+	if ok {
+		return stmt, nil
+	}
+	if full {
+		return nil, nil
+	}
+
+	// Prepare without holding the lock so that lookups of other statements are not held up by the round trip.
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cached, ok := cache.statements[query]; ok {
+		// Another client prepared the same query meanwhile.
+		_ = stmt.Close()
+		return cached, nil
+	}
+	cache.statements[query] = stmt
+	return stmt, nil
+}
+
+// discard drops the prepared statement of the query so that it is prepared again on its next use.
+func (cache *statementCache) discard(query string, stmt *sql.Stmt) {
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.statements[query] == stmt {
+		delete(cache.statements, query)
+		cache.stale = append(cache.stale, stmt)
+	}
+}
+
+// isStalePlanError reports whether a prepared statement failed because the tables it reads changed since it was
+// prepared.
+func isStalePlanError(err error) bool {
+
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "0A000" &&
+		strings.Contains(pqErr.Message, "cached plan must not change result type")
+}
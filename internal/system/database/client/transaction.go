@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/system/metrics"
+)
+
+// txConflictSQLStates are the Postgres error codes that abort a transaction only because it ran concurrently with
+// another one, so that running it again from the start may succeed.
+var txConflictSQLStates = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+}
+
+// RunInTx runs the function in a transaction like RunInTxContext.
+func (client *DBClient) RunInTx(fn func(tx *sql.Tx) error) error {
+
+	return client.RunInTxContext(context.Background(), fn)
+}
+
+// RunInTxContext runs the function in a transaction that is committed when the function returns nil and rolled back
+// otherwise. A transaction aborted by a serialization failure or a deadlock is run again from the start, function
+// included, by the retry policy of the client, so the function must not have effects outside the transaction. The
+// error of the last attempt is returned as is.
+func (client *DBClient) RunInTxContext(ctx context.Context, fn func(tx *sql.Tx) error) error {
+
+	for attempt := 1; ; attempt++ {
+		err := client.runTxOnce(ctx, fn)
+		if err == nil {
+			metrics.DBTxAttempts.Inc("success")
+			return nil
+		}
+		if attempt >= client.retryPolicy.MaxAttempts || !IsTxConflictError(err) || ctx.Err() != nil {
+			metrics.DBTxAttempts.Inc("failure")
+			return err
+		}
+		metrics.DBTxAttempts.Inc("retry")
+		if logger := log.GetLogger(); logger != nil {
+			logger.Debug("Retrying transaction aborted by a concurrent transaction", log.Int("attempt", attempt),
+				log.Error(err))
+		}
+		timer := time.NewTimer(client.retryPolicy.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			metrics.DBTxAttempts.Inc("cancelled")
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// runTxOnce makes a single attempt of RunInTxContext. The transaction is rolled back if the function panics.
+func (client *DBClient) runTxOnce(ctx context.Context, fn func(tx *sql.Tx) error) error {
+
+	tx, err := client.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if errRoll := tx.Rollback(); errRoll != nil && !errors.Is(errRoll, sql.ErrTxDone) {
+			if logger := log.GetLogger(); logger != nil {
+				logger.Debug("Failed to rollback transaction", log.Error(errRoll))
+			}
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	committed = true
+	return tx.Commit()
+}
+
+// QueryInTx executes a query in the transaction and returns its rows as a slice of maps, like ExecuteQuery does.
+func QueryInTx(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]map[string]interface{}, error) {
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var results []map[string]interface{}
+	for rows.Next() {
+		result, err := scanRow(rows, columns)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// IsTxConflictError reports whether the error aborted a transaction because of a concurrent transaction, which is
+// when RunInTx runs the transaction again.
+func IsTxConflictError(err error) bool {
+
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && txConflictSQLStates[pqErr.Code]
+}
@@ -20,8 +20,13 @@ package provider
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
+
 	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
 	"github.com/wso2/identity-customer-data-service/internal/system/database/client"
 )
 
@@ -33,6 +38,12 @@ type DBConfig struct {
 
 var testDBOverride *sql.DB
 
+var (
+	sharedDB     *sql.DB
+	sharedReadDB *sql.DB
+	sharedDBMu   sync.Mutex
+)
+
 func SetTestDB(db *sql.DB) {
 	testDBOverride = db
 }
@@ -40,6 +51,7 @@ func SetTestDB(db *sql.DB) {
 // DBProviderInterface defines the interface for getting database clients.
 type DBProviderInterface interface {
 	GetDBClient() (client.DBClientInterface, error)
+	GetReadDBClient() (client.DBClientInterface, error)
 	GetDBType() string
 }
 
@@ -56,23 +68,166 @@ func NewDBProvider() DBProviderInterface {
 func (d *DBProvider) GetDBClient() (client.DBClientInterface, error) {
 
 	if testDBOverride != nil {
-		return client.NewDBClient(testDBOverride), nil
+		runtimeConfig := config.GetCDSRuntime().Config
+		return client.NewDBClient(testDBOverride, queryTimeout(runtimeConfig), retryPolicy(runtimeConfig),
+			runtimeConfig.DataSource.SlowQueryThreshold, statementCacheSize(runtimeConfig)), nil
 	}
 	// Production DB setup
 	runtimeConfig := config.GetCDSRuntime().Config
-	dbConfig := getDBConfig(runtimeConfig)
+	db, err := getSharedDB(&sharedDB, getDBConfig(runtimeConfig), runtimeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return client.NewDBClient(db, queryTimeout(runtimeConfig), retryPolicy(runtimeConfig),
+		runtimeConfig.DataSource.SlowQueryThreshold, statementCacheSize(runtimeConfig)), nil
+}
+
+// GetReadDBClient returns a database client for read-only queries. It connects to the read replica when one is
+// configured and to the primary otherwise. Reads through it may lag behind writes made through GetDBClient.
+func (d *DBProvider) GetReadDBClient() (client.DBClientInterface, error) {
+
+	runtimeConfig := config.GetCDSRuntime().Config
+	if testDBOverride != nil || runtimeConfig.DataSource.ReadReplica.Hostname == "" {
+		return d.GetDBClient()
+	}
+	db, err := getSharedDB(&sharedReadDB, getReadDBConfig(runtimeConfig), runtimeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return client.NewDBClient(db, queryTimeout(runtimeConfig), retryPolicy(runtimeConfig),
+		runtimeConfig.DataSource.SlowQueryThreshold, statementCacheSize(runtimeConfig)), nil
+}
+
+// getSharedDB returns the connection pool held in the given handle, opening it on first use.
+func getSharedDB(handle **sql.DB, dbConfig DBConfig, runtimeConfig config.Config) (*sql.DB, error) {
+
+	sharedDBMu.Lock()
+	defer sharedDBMu.Unlock()
+	if *handle != nil {
+		return *handle, nil
+	}
 
 	db, err := sql.Open(dbConfig.driverName, dbConfig.dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
+	configurePool(db, runtimeConfig.DataSource)
 
 	// Test the database connection.
 	if err := db.Ping(); err != nil {
+		_ = db.Close()
 		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
+	*handle = db
+	return db, nil
+}
+
+// CloseDB closes the shared connection pools of the primary and the read replica. Clients obtained afterwards
+// open new pools.
+func CloseDB() error {
+
+	sharedDBMu.Lock()
+	defer sharedDBMu.Unlock()
+	var errs []error
+	for _, handle := range []**sql.DB{&sharedDB, &sharedReadDB} {
+		if *handle == nil {
+			continue
+		}
+		if err := client.CloseStatements(*handle); err != nil {
+			errs = append(errs, err)
+		}
+		if err := (*handle).Close(); err != nil {
+			errs = append(errs, err)
+		}
+		*handle = nil
+	}
+	return errors.Join(errs...)
+}
+
+// PoolStats returns the statistics of the primary connection pool. It reports false when no pool has been opened.
+func PoolStats() (sql.DBStats, bool) {
 
-	return client.NewDBClient(db), nil
+	if testDBOverride != nil {
+		return testDBOverride.Stats(), true
+	}
+	sharedDBMu.Lock()
+	defer sharedDBMu.Unlock()
+	if sharedDB == nil {
+		return sql.DBStats{}, false
+	}
+	return sharedDB.Stats(), true
+}
+
+// configurePool applies the connection pool settings of the datasource, falling back to the defaults for the
+// settings that are not configured.
+func configurePool(db *sql.DB, dataSource config.DataSourceConfig) {
+
+	maxOpenConns := dataSource.MaxOpenConns
+	if maxOpenConns <= 0 {
+		maxOpenConns = constants.DefaultDBMaxOpenConns
+	}
+	maxIdleConns := dataSource.MaxIdleConns
+	if maxIdleConns <= 0 {
+		maxIdleConns = constants.DefaultDBMaxIdleConns
+	}
+	connMaxLifetime := dataSource.ConnMaxLifetime
+	if connMaxLifetime <= 0 {
+		connMaxLifetime = constants.DefaultDBConnMaxLifetime
+	}
+	connMaxIdleTime := dataSource.ConnMaxIdleTime
+	if connMaxIdleTime <= 0 {
+		connMaxIdleTime = constants.DefaultDBConnMaxIdleTime
+	}
+
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(connMaxLifetime)
+	db.SetConnMaxIdleTime(connMaxIdleTime)
+}
+
+// queryTimeout returns the configured query timeout, falling back to the default when it is not set.
+func queryTimeout(runtimeConfig config.Config) time.Duration {
+
+	if runtimeConfig.DataSource.QueryTimeout > 0 {
+		return runtimeConfig.DataSource.QueryTimeout
+	}
+	return constants.DefaultDBQueryTimeout
+}
+
+// statementCacheSize returns the configured number of prepared statements cached per connection pool, falling back
+// to the default when it is not set. A negative size disables the cache.
+func statementCacheSize(runtimeConfig config.Config) int {
+
+	size := runtimeConfig.DataSource.StatementCacheSize
+	if size < 0 {
+		return 0
+	}
+	if size == 0 {
+		return constants.DefaultDBStatementCacheSize
+	}
+	return size
+}
+
+// retryPolicy returns the configured retry policy of transient errors, falling back to the defaults for the
+// settings that are not configured.
+func retryPolicy(runtimeConfig config.Config) client.RetryPolicy {
+
+	retry := runtimeConfig.DataSource.Retry
+	policy := client.RetryPolicy{
+		MaxAttempts:    retry.MaxAttempts,
+		InitialBackoff: retry.InitialBackoff,
+		MaxBackoff:     retry.MaxBackoff,
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = constants.DefaultDBRetryMaxAttempts
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = constants.DefaultDBRetryInitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = constants.DefaultDBRetryMaxBackoff
+	}
+	return policy
 }
 
 // getDBConfig returns the database configuration based on the provided data source.
@@ -88,6 +243,23 @@ func getDBConfig(dataSource config.Config) DBConfig {
 	return dbConfig
 }
 
+// getReadDBConfig returns the database configuration of the read replica, taking the settings it does not
+// override from the primary.
+func getReadDBConfig(runtimeConfig config.Config) DBConfig {
+
+	replica := runtimeConfig.DataSource.ReadReplica
+	dataSource := runtimeConfig
+	dataSource.DataSource.Hostname = replica.Hostname
+	if replica.Port != 0 {
+		dataSource.DataSource.Port = replica.Port
+	}
+	if replica.Username != "" {
+		dataSource.DataSource.Username = replica.Username
+		dataSource.DataSource.Password = replica.Password
+	}
+	return getDBConfig(dataSource)
+}
+
 // GetDBType returns the database configuration based on the provided data source.
 func (d *DBProvider) GetDBType() string {
 
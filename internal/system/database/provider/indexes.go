@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/scripts"
+)
+
+// recommendedIndexesLockKey is the advisory lock held while the recommended indexes are built.
+const recommendedIndexesLockKey = "recommended-indexes"
+
+// EnsureRecommendedIndexes creates the recommended indexes that are missing from the primary database. An index left
+// invalid by a failed or interrupted build is dropped and built again. Instances starting together take turns through
+// an advisory lock, so an index is built by one of them only. Building an index on a large table takes a while, so
+// the statements are bounded by the context only and not by the query timeout.
+func EnsureRecommendedIndexes(ctx context.Context) error {
+
+	db := testDBOverride
+	if db == nil {
+		runtimeConfig := config.GetCDSRuntime().Config
+		var err error
+		if db, err = getSharedDB(&sharedDB, getDBConfig(runtimeConfig), runtimeConfig); err != nil {
+			return err
+		}
+	}
+	dbClient, err := NewDBProvider().GetDBClient()
+	if err != nil {
+		return err
+	}
+	defer dbClient.Close()
+
+	dbType := NewDBProvider().GetDBType()
+	indexes := scripts.RecommendedIndexes[dbType]
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return dbClient.RunWithAdvisoryLock(ctx, recommendedIndexesLockKey, func() error {
+		for _, name := range names {
+			var invalid bool
+			if err := db.QueryRowContext(ctx, scripts.IsIndexInvalid[dbType], name).Scan(&invalid); err != nil {
+				return fmt.Errorf("failed to check recommended index %s: %w", name, err)
+			}
+			if invalid {
+				if _, err := db.ExecContext(ctx, fmt.Sprintf(scripts.DropIndex[dbType], name)); err != nil {
+					return fmt.Errorf("failed to drop invalid recommended index %s: %w", name, err)
+				}
+			}
+			if _, err := db.ExecContext(ctx, indexes[name]); err != nil {
+				return fmt.Errorf("failed to create recommended index %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+}
@@ -25,7 +25,7 @@ var DeleteProfileSchemaForOrg = map[string]string{
 
 var GetProfileSchemaByOrg = map[string]string{
 	"postgres": `SELECT attribute_id, attribute_name, display_name, value_type, merge_strategy , application_identifier, mutability, 
-       multi_valued, sub_attributes::text, canonical_values::text FROM profile_schema WHERE org_handle = $1`,
+       multi_valued, sub_attributes::text, canonical_values::text, computation_expression, is_pii, case_insensitive, allowed_apps::text FROM profile_schema WHERE org_handle = $1`,
 }
 
 var DeleteIdentityClaimsOfProfileSchema = map[string]string{
@@ -35,22 +35,23 @@ var DeleteIdentityClaimsOfProfileSchema = map[string]string{
 var InsertIdentityClaimsForProfileSchema = map[string]string{
 	"postgres": `INSERT INTO profile_schema 
 	(org_handle, attribute_id, attribute_name, value_type, merge_strategy, mutability, application_identifier, 
-	 multi_valued, canonical_values, sub_attributes, scim_dialect, scope, display_name) VALUES `,
+	 multi_valued, canonical_values, sub_attributes, scim_dialect, scope, display_name, case_insensitive) VALUES `,
 }
 
 var GetProfileSchemaAttributeByName = map[string]string{
 	"postgres": `SELECT attribute_id, attribute_name, display_name, value_type, merge_strategy, mutability , application_identifier, 
-       multi_valued, sub_attributes::text, canonical_values::text FROM profile_schema WHERE org_handle = $1 
+       multi_valued, sub_attributes::text, canonical_values::text, computation_expression, is_pii, case_insensitive, allowed_apps::text FROM profile_schema WHERE org_handle = $1 
        AND attribute_name = $2 LIMIT 1`,
 }
 
 var InsertProfileSchemaAttributesForScope = map[string]string{
 	"postgres": `INSERT INTO profile_schema (org_handle, attribute_id, attribute_name, value_type, merge_strategy, 
-                            application_identifier, mutability, multi_valued, sub_attributes, canonical_values, scope, display_name) VALUES `,
+                            application_identifier, mutability, multi_valued, sub_attributes, canonical_values, scope, display_name,
+                            computation_expression, is_pii, case_insensitive, allowed_apps) VALUES `,
 }
 var GetProfileSchemaAttributeByScope = map[string]string{
 	"postgres": `SELECT attribute_id, org_handle, attribute_name, display_name, value_type, merge_strategy, mutability, application_identifier, multi_valued,   sub_attributes::text,
-  canonical_values::text FROM profile_schema WHERE org_handle = $1 AND scope = $2`,
+  canonical_values::text, computation_expression, is_pii, case_insensitive, allowed_apps::text FROM profile_schema WHERE org_handle = $1 AND scope = $2`,
 }
 
 var UpdateProfileSchemaAttributesForSchema = map[string]string{
@@ -64,8 +65,12 @@ var UpdateProfileSchemaAttributesForSchema = map[string]string{
 			multi_valued = $6,
 			canonical_values = $7,
 			sub_attributes = $8,
-			display_name = $9
-		WHERE org_handle = $10 AND attribute_id = $11 AND scope = $12
+			display_name = $9,
+			computation_expression = $10,
+			is_pii = $11,
+			case_insensitive = $12,
+			allowed_apps = $13
+		WHERE org_handle = $14 AND attribute_id = $15 AND scope = $16
 	`,
 }
 
@@ -75,13 +80,13 @@ var DeleteProfileSchemaAttributeForScope = map[string]string{
 
 var GetProfileSchemaAttributeById = map[string]string{
 	"postgres": `SELECT attribute_id, attribute_name, display_name, value_type, merge_strategy, mutability , application_identifier, multi_valued,   sub_attributes::text,
-  canonical_values::text
+  canonical_values::text, computation_expression, is_pii, case_insensitive, allowed_apps::text
 	          FROM profile_schema WHERE org_handle = $1 AND attribute_id = $2`,
 }
 
 var FilterProfileSchemaAttributes = map[string]string{
 	"postgres": `SELECT attribute_id, org_handle, attribute_name, display_name, value_type, merge_strategy, mutability, application_identifier, multi_valued, sub_attributes::text,
-  canonical_values::text FROM profile_schema WHERE org_handle = $1`,
+  canonical_values::text, computation_expression, is_pii, case_insensitive, allowed_apps::text FROM profile_schema WHERE org_handle = $1`,
 }
 
 var DeleteProfileSchemaAttributeById = map[string]string{
@@ -89,33 +94,109 @@ var DeleteProfileSchemaAttributeById = map[string]string{
 }
 
 var GetUnificationRules = map[string]string{
-	"postgres": `SELECT rule_id, rule_name, property_name, property_id, priority, is_active, created_at, updated_at 
-FROM unification_rules WHERE org_handle = $1`,
+	"postgres": `SELECT rule_id, org_handle, rule_name, property_name, property_id, priority, is_active, merge_mode, created_at, updated_at 
+FROM unification_rules WHERE org_handle = $1 ORDER BY priority, rule_id`,
+}
+
+var GetActiveUnificationRules = map[string]string{
+	"postgres": `SELECT rule_id, org_handle, rule_name, property_name, property_id, priority, is_active, merge_mode, created_at, updated_at 
+FROM unification_rules WHERE org_handle = $1 AND is_active = true ORDER BY priority, rule_id`,
 }
 
 var GetUnificationRule = map[string]string{
-	"postgres": `SELECT rule_id, rule_name, property_name, property_id, priority, is_active, created_at, updated_at FROM unification_rules WHERE rule_id = $1`,
+	"postgres": `SELECT rule_id, org_handle, rule_name, property_name, property_id, priority, is_active, merge_mode, created_at, updated_at FROM unification_rules WHERE rule_id = $1`,
+}
+
+var GetUnificationRuleByName = map[string]string{
+	"postgres": `SELECT rule_id, org_handle, rule_name, property_name, property_id, priority, is_active, merge_mode, created_at, updated_at FROM unification_rules WHERE org_handle = $1 AND rule_name = $2`,
 }
 
 var DeleteUnificationRule = map[string]string{
 	"postgres": `DELETE FROM unification_rules WHERE rule_id = $1`,
 }
+
+// GetProfilesSharingPropertyValue lists the string values of a property ($2 path of $3 scope, or user_id) shared by
+// more than one reference profile of the organization, one row per profile and value.
+var GetProfilesSharingPropertyValue = map[string]string{
+	"postgres": `
+		SELECT profile_id, user_id, value FROM (
+			SELECT matched.profile_id, matched.user_id, matched.value,
+			       COUNT(*) OVER (PARTITION BY matched.value) AS profile_count
+			FROM (
+				SELECT DISTINCT p.profile_id, p.user_id, e.value #>> '{}' AS value
+				FROM profiles p
+				JOIN profile_reference r ON p.profile_id = r.profile_id
+				CROSS JOIN LATERAL (
+					SELECT CASE $3
+						WHEN 'traits' THEN p.traits #> string_to_array($2, '.')
+						WHEN 'identity_attributes' THEN p.identity_attributes #> string_to_array($2, '.')
+						ELSE to_jsonb(NULLIF(p.user_id, ''))
+					END AS attr
+				) a
+				CROSS JOIN LATERAL jsonb_array_elements(
+					CASE WHEN jsonb_typeof(a.attr) = 'array' THEN a.attr ELSE jsonb_build_array(a.attr) END
+				) AS e(value)
+				WHERE p.org_handle = $1
+				  AND p.deleted_at IS NULL
+				  AND r.profile_status = 'REFERENCE_PROFILE'
+				  AND jsonb_typeof(e.value) = 'string'
+			) matched
+		) shared
+		WHERE profile_count > 1
+		ORDER BY value, profile_id;`,
+}
+
+var InsertUnificationRulesBase = map[string]string{
+	"postgres": `INSERT INTO unification_rules (rule_id, org_handle, rule_name, property_name, property_id, priority, is_active, merge_mode, created_at, updated_at) 
+			VALUES `,
+}
+
 var InsertUnificationRule = map[string]string{
-	"postgres": `INSERT INTO unification_rules (rule_id, org_handle, rule_name, property_name, property_id, priority, is_active, created_at, updated_at) 
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+	"postgres": `INSERT INTO unification_rules (rule_id, org_handle, rule_name, property_name, property_id, priority, is_active, merge_mode, created_at, updated_at) 
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+}
+
+var UpdateUnificationRulePriority = map[string]string{
+	"postgres": `UPDATE unification_rules SET priority = $1, updated_at = $2 WHERE rule_id = $3 AND org_handle = $4`,
+}
+
+var UpdateUnificationRuleName = map[string]string{
+	"postgres": `UPDATE unification_rules SET rule_name = $1, updated_at = $2 WHERE rule_id = $3`,
+}
+
+var UpdateUnificationRuleProperty = map[string]string{
+	"postgres": `UPDATE unification_rules SET property_name = $1, property_id = $2, updated_at = $3 WHERE rule_id = $4`,
+}
+
+var UpdateUnificationRuleStatus = map[string]string{
+	"postgres": `UPDATE unification_rules SET is_active = $1, updated_at = $2 WHERE rule_id = $3 RETURNING rule_id`,
 }
 
 var UpdateUnificationRule = map[string]string{
-	"postgres": `UPDATE unification_rules SET rule_name = $1, priority = $2, is_active = $3,updated_at = $4
-		 WHERE rule_id = $5;`,
+	"postgres": `UPDATE unification_rules SET rule_name = $1, priority = $2, is_active = $3, merge_mode = $4, updated_at = $5
+		 WHERE rule_id = $6;`,
 }
 
+// InsertProfile adds a profile. When a profile with the same id was added concurrently, the data of both converge
+// on the existing row: the top-level traits and identity attributes given here are merged into it and its user id
+// is kept unless empty. A deleted profile or one of another organization is not merged into, and no row is returned.
+// The inserted column tells whether the row was added rather than merged into.
 var InsertProfile = map[string]string{
 	"postgres": `
 		INSERT INTO profiles (
 		profile_id, user_id, org_handle, created_at, updated_at, location, list_profile, delete_profile, traits, identity_attributes
 	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-	ON CONFLICT (profile_id) DO NOTHING;`,
+	ON CONFLICT (profile_id) DO UPDATE SET
+		user_id = COALESCE(NULLIF(profiles.user_id, ''), EXCLUDED.user_id),
+		traits = COALESCE(NULLIF(profiles.traits, 'null'::jsonb), '{}'::jsonb) ||
+			COALESCE(NULLIF(EXCLUDED.traits, 'null'::jsonb), '{}'::jsonb),
+		identity_attributes = COALESCE(NULLIF(profiles.identity_attributes, 'null'::jsonb), '{}'::jsonb) ||
+			COALESCE(NULLIF(EXCLUDED.identity_attributes, 'null'::jsonb), '{}'::jsonb),
+		updated_at = GREATEST(profiles.updated_at, EXCLUDED.updated_at),
+		version = profiles.version + 1
+	WHERE profiles.org_handle = EXCLUDED.org_handle AND profiles.deleted_at IS NULL
+	RETURNING profile_id, user_id, org_handle, created_at, updated_at, location, list_profile, traits, identity_attributes,
+		version, (xmax = 0) AS inserted;`,
 }
 
 var InsertProfileReference = map[string]string{
@@ -125,16 +206,297 @@ var InsertProfileReference = map[string]string{
 		ON CONFLICT (profile_id) DO NOTHING;`,
 }
 
+// GetProjectedProfileById fetches a profile like GetProfileById but only with the given top-level keys of its
+// traits ($2) and identity attributes ($3), so that large JSONB documents are not transferred when not needed.
+var GetProjectedProfileById = map[string]string{
+	"postgres": `
+		SELECT p.profile_id, p.user_id, p.created_at, p.updated_at, p.location, p.org_handle, p.list_profile, p.delete_profile,
+		       COALESCE((SELECT jsonb_object_agg(t.key, t.value) FROM jsonb_each(p.traits) t
+		                 WHERE t.key = ANY($2)), '{}'::jsonb) AS traits,
+		       COALESCE((SELECT jsonb_object_agg(i.key, i.value) FROM jsonb_each(p.identity_attributes) i
+		                 WHERE i.key = ANY($3)), '{}'::jsonb) AS identity_attributes,
+		       p.version, r.profile_status, r.reference_profile_id, r.reference_reason
+		FROM 
+			profiles p
+		LEFT JOIN 
+			profile_reference r ON p.profile_id = r.profile_id
+		WHERE 
+			p.profile_id = $1
+			AND p.deleted_at IS NULL;`,
+}
+
+// GetProfileOrgHandle returns the organization of a profile, including a soft-deleted one.
+var GetProfileOrgHandle = map[string]string{
+	"postgres": `SELECT org_handle FROM profiles WHERE profile_id = $1`,
+}
+
 var GetProfileById = map[string]string{
 	"postgres": `
 		SELECT p.profile_id, p.user_id, p.created_at, p.updated_at,p.location, p.org_handle, p.list_profile, p.delete_profile, 
-		       p.traits, p.identity_attributes, r.profile_status, r.reference_profile_id, r.reference_reason
+		       p.traits, p.identity_attributes, p.version, r.profile_status, r.reference_profile_id, r.reference_reason
+		FROM 
+			profiles p
+		LEFT JOIN 
+			profile_reference r ON p.profile_id = r.profile_id
+		WHERE 
+			p.profile_id = $1
+			AND p.deleted_at IS NULL;`,
+}
+
+// LockProfileById fetches profile $1 like GetProfileById and locks it for the rest of the transaction.
+var LockProfileById = map[string]string{
+	"postgres": `
+		SELECT p.profile_id, p.user_id, p.created_at, p.updated_at, p.location, p.org_handle, p.list_profile,
+		       p.delete_profile, p.traits, p.identity_attributes, p.version, r.profile_status, r.reference_profile_id,
+		       r.reference_reason
+		FROM profiles p
+		LEFT JOIN profile_reference r ON p.profile_id = r.profile_id
+		WHERE p.profile_id = $1
+		  AND p.deleted_at IS NULL
+		FOR UPDATE OF p;`,
+}
+
+// GetProfilesByIds fetches the profiles in $1 like GetProfileById.
+var GetProfilesByIds = map[string]string{
+	"postgres": `
+		SELECT p.profile_id, p.user_id, p.created_at, p.updated_at, p.location, p.org_handle, p.list_profile,
+		       p.delete_profile, p.traits, p.identity_attributes, p.version, r.profile_status, r.reference_profile_id,
+		       r.reference_reason
+		FROM profiles p
+		LEFT JOIN profile_reference r ON p.profile_id = r.profile_id
+		WHERE p.profile_id = ANY($1)
+		  AND p.deleted_at IS NULL;`,
+}
+
+var GetDeletedProfileById = map[string]string{
+	"postgres": `
+		SELECT p.profile_id, p.user_id, p.created_at, p.updated_at,p.location, p.org_handle, p.list_profile, p.delete_profile, 
+		       p.traits, p.identity_attributes, p.deleted_at, r.profile_status, r.reference_profile_id, r.reference_reason
 		FROM 
 			profiles p
 		LEFT JOIN 
 			profile_reference r ON p.profile_id = r.profile_id
 		WHERE 
-			p.profile_id = $1;`,
+			p.profile_id = $1
+			AND p.deleted_at IS NOT NULL;`,
+}
+
+var SoftDeleteProfile = map[string]string{
+	"postgres": `UPDATE profiles SET deleted_at = $1, updated_at = GREATEST(updated_at, $1), version = version + 1
+                 WHERE profile_id = $2 AND deleted_at IS NULL;`,
+}
+
+// RestoreProfiles restores the reference profile and its referring profiles that were deleted together, marking
+// them updated at $3 and bumping their version.
+var RestoreProfiles = map[string]string{
+	"postgres": `
+		UPDATE profiles SET deleted_at = NULL, updated_at = GREATEST(updated_at, $3), version = version + 1
+		WHERE deleted_at = $2
+		  AND (
+			profile_id = $1
+			OR profile_id IN (SELECT profile_id FROM profile_reference WHERE reference_profile_id = $1)
+		  )
+		RETURNING profile_id;`,
+}
+
+var PurgeDeletedProfiles = map[string]string{
+	"postgres": `
+		WITH purged AS (
+			DELETE FROM profiles WHERE deleted_at IS NOT NULL AND deleted_at < $1
+			RETURNING profile_id
+		), purged_references AS (
+			DELETE FROM profile_reference WHERE profile_id IN (SELECT profile_id FROM purged)
+		)
+		SELECT profile_id FROM purged;`,
+}
+
+// GetProfileHierarchyWithFilterBase is extended with the filter joins and conditions to select the matching
+// profiles along with their position in the profile hierarchy.
+var GetProfileHierarchyWithFilterBase = map[string]string{
+	"postgres": `SELECT DISTINCT p.profile_id, r.profile_status, r.reference_profile_id
+FROM profiles p
+LEFT JOIN profile_reference r
+    ON p.profile_id = r.profile_id`,
+}
+
+// CountProfilesGroupedByTrait counts the reference profiles by the value of the trait at the path parameter. It is
+// formatted with the index of that parameter, the filter joins and the filter conditions.
+var CountProfilesGroupedByTrait = map[string]string{
+	"postgres": `SELECT p.traits #>> $%d AS value, COUNT(DISTINCT p.profile_id) AS profile_count
+FROM profiles p
+LEFT JOIN profile_reference r
+    ON p.profile_id = r.profile_id%s
+WHERE %s
+GROUP BY 1`,
+}
+
+// GetProfileHierarchyStats summarizes how the live profiles of organization $1 are unified, given the status $2 of
+// reference profiles and $3 of merged profiles. A master is a reference profile with merged profiles, and an orphan a
+// merged profile whose reference profile no longer exists.
+var GetProfileHierarchyStats = map[string]string{
+	"postgres": `
+		WITH live AS (
+			SELECT p.profile_id, r.profile_status, r.reference_profile_id
+			FROM profiles p
+			JOIN profile_reference r ON r.profile_id = p.profile_id
+			WHERE p.org_handle = $1 AND p.deleted_at IS NULL
+		), clusters AS (
+			SELECT c.reference_profile_id, COUNT(*) AS child_count
+			FROM live c
+			JOIN live m ON m.profile_id = c.reference_profile_id AND m.profile_status = $2
+			WHERE c.profile_status = $3
+			GROUP BY c.reference_profile_id
+		)
+		SELECT
+			(SELECT COUNT(*) FROM live) AS total_profiles,
+			(SELECT COUNT(*) FROM live WHERE profile_status = $2) AS reference_profiles,
+			(SELECT COUNT(*) FROM live WHERE profile_status = $3) AS merged_profiles,
+			(SELECT COUNT(*) FROM live c WHERE c.profile_status = $3 AND NOT EXISTS (
+				SELECT 1 FROM live m WHERE m.profile_id = c.reference_profile_id
+			)) AS orphaned_profiles,
+			(SELECT COUNT(*) FROM clusters) AS master_profiles,
+			(SELECT COALESCE(AVG(child_count), 0)::float8 FROM clusters) AS average_children_per_master,
+			(SELECT COALESCE(MAX(child_count) + 1, 0) FROM clusters) AS largest_cluster_size;`,
+}
+
+var InsertProfileJob = map[string]string{
+	"postgres": `
+		INSERT INTO profile_jobs (job_id, org_handle, job_type, status, phase, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6);`,
+}
+
+var GetProfileJob = map[string]string{
+	"postgres": `
+		SELECT job_id, org_handle, job_type, status, phase, processed, total, merged, job_cursor, cancel_requested,
+			error, created_at, updated_at
+		FROM profile_jobs WHERE job_id = $1;`,
+}
+
+// UpdateProfileJobProgress records the progress of running job $1 at time $7, which also serves as the heartbeat of
+// the instance running it. It returns whether the job was asked to be cancelled, and no row once the job stopped.
+var UpdateProfileJobProgress = map[string]string{
+	"postgres": `
+		UPDATE profile_jobs SET phase = $2, processed = $3, total = $4, merged = $5, job_cursor = $6, updated_at = $7
+		WHERE job_id = $1 AND status = 'RUNNING'
+		RETURNING cancel_requested;`,
+}
+
+var FinishProfileJob = map[string]string{
+	"postgres": `
+		UPDATE profile_jobs SET status = $2, error = $3, updated_at = $4
+		WHERE job_id = $1 AND status = 'RUNNING';`,
+}
+
+var RequestProfileJobCancellation = map[string]string{
+	"postgres": `
+		UPDATE profile_jobs SET cancel_requested = true
+		WHERE job_id = $1 AND status = 'RUNNING'
+		RETURNING job_id;`,
+}
+
+// ClaimStaleProfileJobs takes over the running jobs whose last heartbeat is older than $2 by renewing it to $1, so
+// that a job left behind by a stopped instance is resumed by a single other instance.
+var ClaimStaleProfileJobs = map[string]string{
+	"postgres": `
+		UPDATE profile_jobs SET updated_at = $1
+		WHERE status = 'RUNNING' AND updated_at < $2
+		RETURNING job_id, org_handle, job_type, status, phase, processed, total, merged, job_cursor, cancel_requested,
+			error, created_at, updated_at;`,
+}
+
+// ListMasterProfilesAfter lists up to $4 live reference profiles of organization $1 that profiles with status $2 are
+// merged to, in id order after id $3, and whether each is listed.
+var ListMasterProfilesAfter = map[string]string{
+	"postgres": `
+		SELECT DISTINCT r.reference_profile_id, m.list_profile
+		FROM profile_reference r
+		JOIN profiles c ON c.profile_id = r.profile_id
+		JOIN profiles m ON m.profile_id = r.reference_profile_id
+		WHERE c.org_handle = $1 AND r.profile_status = $2 AND r.reference_profile_id > $3 AND m.deleted_at IS NULL
+		ORDER BY r.reference_profile_id
+		LIMIT $4;`,
+}
+
+// SplitProfileCluster promotes the profiles merged to reference profile $1 by unification rules back to reference
+// profiles with status $2 and returns their ids. Profiles merged for reasons $4 and $5, which no rule re-merges,
+// are kept merged.
+var SplitProfileCluster = map[string]string{
+	"postgres": `
+		UPDATE profile_reference
+		SET reference_profile_id = '',
+			reference_reason = '',
+			profile_status = $2,
+			matched_rule_id = NULL,
+			matched_value = NULL
+		WHERE reference_profile_id = $1 AND profile_status = $3
+		  AND reference_reason NOT IN ($4, $5)
+		RETURNING profile_id;`,
+}
+
+// HasMergedProfiles reports whether any profile is merged to reference profile $1, $2 being the merged status.
+var HasMergedProfiles = map[string]string{
+	"postgres": `SELECT EXISTS (
+		SELECT 1 FROM profile_reference WHERE reference_profile_id = $1 AND profile_status = $2);`,
+}
+
+// GetDistinctTraitValues lists up to $3 distinct values of the trait at path $2 among the reference profiles of
+// organization $1 with the number of profiles having each. It is formatted with the ORDER BY clause.
+var GetDistinctTraitValues = map[string]string{
+	"postgres": `SELECT p.traits #>> $2 AS value, COUNT(*) AS profile_count
+FROM profiles p
+JOIN profile_reference r
+    ON p.profile_id = r.profile_id
+WHERE p.org_handle = $1 AND r.profile_status = 'REFERENCE_PROFILE' AND p.deleted_at IS NULL
+    AND p.traits #>> $2 IS NOT NULL
+GROUP BY 1
+ORDER BY %s
+LIMIT $3`,
+}
+
+// IncrementProfileTrait adds $3 to the numeric trait at path $2 in a single statement so that concurrent increments
+// are serialized by the row lock. A missing trait is treated as 0. The traits it replaces are kept in the trait
+// history.
+var IncrementProfileTrait = map[string]string{
+	"postgres": `
+		WITH prior AS (
+			SELECT profile_id, version, traits, updated_at FROM profiles
+			WHERE profile_id = $1 AND deleted_at IS NULL
+			FOR UPDATE
+		), updated AS (
+			UPDATE profiles p
+			SET traits = jsonb_set(COALESCE(p.traits, '{}'::jsonb), $2::text[],
+					to_jsonb(COALESCE((p.traits #>> $2::text[])::numeric, 0) + $3::numeric), true),
+				updated_at = $4,
+				version = p.version + 1
+			FROM prior
+			WHERE p.profile_id = prior.profile_id
+			RETURNING p.traits
+		), snapshot AS (
+			INSERT INTO profile_trait_history (profile_id, version, traits, recorded_at)
+			SELECT prior.profile_id, prior.version, COALESCE(prior.traits, '{}'::jsonb), prior.updated_at
+			FROM prior, updated
+			WHERE prior.traits IS DISTINCT FROM updated.traits
+			ON CONFLICT DO NOTHING
+		)
+		SELECT traits #>> $2::text[] AS value FROM updated;`,
+}
+
+var IncrementProfileIdentityAttribute = map[string]string{
+	"postgres": `
+		UPDATE profiles
+		SET identity_attributes = jsonb_set(COALESCE(identity_attributes, '{}'::jsonb), $2::text[],
+				to_jsonb(COALESCE((identity_attributes #>> $2::text[])::numeric, 0) + $3::numeric), true),
+			updated_at = $4,
+			version = version + 1
+		WHERE profile_id = $1 AND deleted_at IS NULL
+		RETURNING identity_attributes #>> $2::text[] AS value;`,
+}
+
+// SetRecomputedTraits replaces the traits of profile $1 with $2, the traits of the profile with its computed traits
+// evaluated again. It neither bumps the version nor records the trait history, so it is only to follow, in the same
+// transaction, the update of the profile that did both.
+var SetRecomputedTraits = map[string]string{
+	"postgres": `UPDATE profiles SET traits = $2 WHERE profile_id = $1 AND deleted_at IS NULL;`,
 }
 
 var GetProfileConsentsByProfileId = map[string]string{
@@ -161,16 +523,100 @@ var GetAppDataByAppId = map[string]string{
 	"postgres": `SELECT app_id, application_data FROM application_data WHERE profile_id = $1 AND app_id = $2;`,
 }
 
+// UpdateProfile updates the profile if it is still at version $8, or regardless of its version when $8 is 0. Traits
+// replaced by the update are kept in the trait history under the version they belonged to.
 var UpdateProfile = map[string]string{
 	"postgres": `
-		UPDATE profiles SET
-			user_id = $1,
-			list_profile = $2,
-			delete_profile = $3,
-			traits = $4,
-			identity_attributes = $5,
-			updated_at = $6
-		 WHERE profile_id = $7;`,
+		WITH prior AS (
+			SELECT profile_id, version, traits, updated_at FROM profiles
+			WHERE profile_id = $7 AND ($8::bigint = 0 OR version = $8)
+			FOR UPDATE
+		), updated AS (
+			UPDATE profiles p SET
+				user_id = $1,
+				list_profile = $2,
+				delete_profile = $3,
+				traits = $4,
+				identity_attributes = $5,
+				updated_at = $6,
+				version = p.version + 1
+			FROM prior
+			WHERE p.profile_id = prior.profile_id
+			RETURNING p.version, p.traits
+		), snapshot AS (
+			INSERT INTO profile_trait_history (profile_id, version, traits, recorded_at)
+			SELECT prior.profile_id, prior.version, COALESCE(prior.traits, '{}'::jsonb), prior.updated_at
+			FROM prior, updated
+			WHERE prior.traits IS DISTINCT FROM updated.traits
+			ON CONFLICT DO NOTHING
+		)
+		SELECT version FROM updated;`,
+}
+
+// PatchProfileTraitsByFilter merges the traits in the parameter at the first index into the traits of the reference
+// profiles matching the filter and sets their update time to the parameter at the second index. It is formatted
+// with those indexes, the filter joins and the filter conditions. Profiles are locked in id order and their version is
+// bumped like in UpdateProfile, and profiles already holding the traits are left untouched. The replaced traits are
+// kept in the trait history. The ids of the profiles changed are returned.
+var PatchProfileTraitsByFilter = map[string]string{
+	"postgres": `
+		WITH matched AS (
+			SELECT DISTINCT p.profile_id
+			FROM profiles p
+			LEFT JOIN profile_reference r
+				ON p.profile_id = r.profile_id%[3]s
+			WHERE %[4]s
+		), prior AS (
+			SELECT profile_id, version, traits, updated_at FROM profiles
+			WHERE profile_id IN (SELECT profile_id FROM matched)
+				AND COALESCE(traits, '{}'::jsonb) || $%[1]d::jsonb IS DISTINCT FROM COALESCE(traits, '{}'::jsonb)
+			ORDER BY profile_id
+			FOR UPDATE
+		), updated AS (
+			UPDATE profiles p SET
+				traits = COALESCE(p.traits, '{}'::jsonb) || $%[1]d::jsonb,
+				updated_at = $%[2]d,
+				version = p.version + 1
+			FROM prior
+			WHERE p.profile_id = prior.profile_id
+			RETURNING p.profile_id
+		), snapshot AS (
+			INSERT INTO profile_trait_history (profile_id, version, traits, recorded_at)
+			SELECT prior.profile_id, prior.version, COALESCE(prior.traits, '{}'::jsonb), prior.updated_at
+			FROM prior JOIN updated ON prior.profile_id = updated.profile_id
+			ON CONFLICT DO NOTHING
+		)
+		SELECT profile_id FROM updated;`,
+}
+
+// GetProfileTraitHistory returns the recorded traits of the earlier versions of a profile, oldest first.
+var GetProfileTraitHistory = map[string]string{
+	"postgres": `SELECT version, traits, recorded_at FROM profile_trait_history WHERE profile_id = $1 ORDER BY version;`,
+}
+
+// DeleteProfileTraitHistory removes the trait history of the profiles in $1.
+var DeleteProfileTraitHistory = map[string]string{
+	"postgres": `DELETE FROM profile_trait_history WHERE profile_id = ANY($1);`,
+}
+
+// PruneProfileTraitHistory removes the trait history recorded before $1 along with the entries of each profile
+// beyond its latest $2, unless $2 is 0, and returns the number of entries removed.
+var PruneProfileTraitHistory = map[string]string{
+	"postgres": `
+		WITH pruned AS (
+			DELETE FROM profile_trait_history
+			WHERE recorded_at < $1
+			   OR (profile_id, version) IN (
+					SELECT profile_id, version FROM (
+						SELECT profile_id, version,
+							   ROW_NUMBER() OVER (PARTITION BY profile_id ORDER BY version DESC) AS position
+						FROM profile_trait_history
+					) ranked
+					WHERE $2::int > 0 AND position > $2::int
+			   )
+			RETURNING 1
+		)
+		SELECT COUNT(*) AS pruned FROM pruned;`,
 }
 
 var UpsertProfileReference = map[string]string{
@@ -187,8 +633,37 @@ var UpdateProfileReference = map[string]string{
 		UPDATE profile_reference
 		SET reference_profile_id = $1,
 			reference_reason = $2,
-			profile_status = $3
-		WHERE profile_id = $4`,
+			profile_status = $3,
+			matched_rule_id = $4,
+			matched_value = $5
+		WHERE profile_id = $6`,
+}
+
+// PromoteOrphanedProfiles turns merged profiles whose reference profile no longer exists into reference profiles
+// and marks them updated at $5, bumping their version. Only the profile $4 is considered when it is not empty.
+var PromoteOrphanedProfiles = map[string]string{
+	"postgres": `
+		WITH promoted AS (
+		UPDATE profile_reference r
+		SET reference_profile_id = '',
+			reference_reason = '',
+			profile_status = $2,
+			matched_rule_id = NULL,
+			matched_value = NULL
+		FROM profiles c
+		WHERE c.profile_id = r.profile_id
+		  AND c.org_handle = $1
+		  AND c.deleted_at IS NULL
+		  AND r.profile_status = $3
+		  AND ($4 = '' OR r.profile_id = $4)
+		  AND NOT EXISTS (
+			SELECT 1 FROM profiles p WHERE p.profile_id = r.reference_profile_id AND p.deleted_at IS NULL
+		  )
+		RETURNING r.profile_id
+		)
+		UPDATE profiles SET updated_at = GREATEST(updated_at, $5), version = version + 1
+		WHERE profile_id IN (SELECT profile_id FROM promoted)
+		RETURNING profile_id;`,
 }
 
 var GetProfilesByOrgId = map[string]string{
@@ -205,12 +680,14 @@ var GetProfilesByOrgId = map[string]string{
 			r.reference_reason, 
 			p.list_profile, 
 			p.traits, 
-			p.identity_attributes
+			p.identity_attributes,
+			p.deleted_at
 		FROM profiles p
 		LEFT JOIN profile_reference r ON p.profile_id = r.profile_id
 		WHERE 
 			r.profile_status = 'REFERENCE_PROFILE'
 			AND p.org_handle = $1
+			AND ($6::boolean OR p.deleted_at IS NULL)
 			AND (
 				$2::timestamptz IS NULL
 				OR (
@@ -231,17 +708,62 @@ var DeleteProfileByProfileId = map[string]string{
 	"postgres": `DELETE FROM application_data WHERE profile_id = $1`,
 }
 
+// InsertApplicationData upserts the application data of an application of a profile and marks the profile updated
+// at $4, so that listings of the profiles modified since a time include it. The version of the profile is bumped, so
+// that updates expecting an earlier version fail.
 var InsertApplicationData = map[string]string{
+	"postgres": `
+		WITH upserted AS (
+			INSERT INTO application_data (profile_id, app_id, application_data)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (profile_id, app_id)
+			DO UPDATE SET application_data = EXCLUDED.application_data
+			RETURNING profile_id
+		)
+		UPDATE profiles SET updated_at = GREATEST(updated_at, $4), version = version + 1
+		WHERE profile_id IN (SELECT profile_id FROM upserted);
+	`,
+}
+
+// LockApplicationData returns the application data of the application of a profile, creating it empty if absent,
+// and locks it until the end of the transaction. The no-op update is what takes the lock on an existing row.
+var LockApplicationData = map[string]string{
 	"postgres": `
 		INSERT INTO application_data (profile_id, app_id, application_data)
-		VALUES ($1, $2, $3)
+		VALUES ($1, $2, '{}'::jsonb)
 		ON CONFLICT (profile_id, app_id)
-		DO UPDATE SET application_data = EXCLUDED.application_data;
-	`,
+		DO UPDATE SET application_data = application_data.application_data
+		RETURNING application_data;`,
+}
+
+var UpdateApplicationData = map[string]string{
+	"postgres": `
+		WITH updated AS (
+			UPDATE application_data SET application_data = $3 WHERE profile_id = $1 AND app_id = $2
+			RETURNING profile_id
+		)
+		UPDATE profiles SET updated_at = GREATEST(updated_at, $4), version = version + 1
+		WHERE profile_id IN (SELECT profile_id FROM updated);`,
+}
+
+// TouchProfiles marks the given profiles updated at $2 and bumps their version without changing their data, for
+// changes kept outside the profiles table such as the links between merged profiles.
+var TouchProfiles = map[string]string{
+	"postgres": `UPDATE profiles SET updated_at = GREATEST(updated_at, $2), version = version + 1
+		WHERE profile_id = ANY($1);`,
 }
 
+// DeleteProfileReference detaches profile $2 from reference profile $1, marks both updated at $3 and bumps their
+// version.
 var DeleteProfileReference = map[string]string{
-	"postgres": `DELETE FROM profile_reference WHERE reference_profile_id = $1 AND profile_id = $2;`,
+	"postgres": `
+		WITH detached AS (
+			DELETE FROM profile_reference WHERE reference_profile_id = $1 AND profile_id = $2
+			RETURNING profile_id
+		)
+		UPDATE profiles SET updated_at = GREATEST(updated_at, $3), version = version + 1
+		WHERE profile_id IN ($1, $2) AND EXISTS (SELECT 1 FROM detached)
+		RETURNING profile_id;`,
 }
 
 var GetAllProfilesWithFilterBase = map[string]string{
@@ -256,7 +778,8 @@ var GetAllProfilesWithFilterBase = map[string]string{
                 r.reference_reason,
                 p.list_profile,
                 p.traits,
-                p.identity_attributes
+                p.identity_attributes,
+                p.deleted_at
 FROM profiles p
 LEFT JOIN profile_reference r
     ON p.profile_id = r.profile_id`,
@@ -282,14 +805,75 @@ var GetAllReferenceProfileExceptCurrent = map[string]string{
 	WHERE 
 		r.profile_status = 'REFERENCE_PROFILE'
 		AND p.profile_id != $1
-		AND p.org_handle = $2;`,
+		AND p.org_handle = $2
+		AND p.deleted_at IS NULL;`,
+}
+
+// ClearReferenceMatchedValues removes the property values that profiles were unified into $1 by.
+var ClearReferenceMatchedValues = map[string]string{
+	"postgres": `UPDATE profile_reference SET matched_value = NULL WHERE reference_profile_id = $1`,
+}
+
+// FindProfilesByIdentityAttribute lists the profiles of organization $1 whose identity attribute at the dot
+// separated path $2 is the string $3 or a list holding it.
+var FindProfilesByIdentityAttribute = map[string]string{
+	"postgres": `
+		SELECT p.profile_id FROM profiles p
+		WHERE p.org_handle = $1
+		  AND p.deleted_at IS NULL
+		  AND (p.identity_attributes #> string_to_array($2, '.') = to_jsonb($3::text)
+		       OR p.identity_attributes #> string_to_array($2, '.') @> jsonb_build_array($3::text))
+		ORDER BY p.created_at, p.profile_id`,
 }
 
 var FetchReferencedProfiles = map[string]string{
 	"postgres": `
-		SELECT profile_id, reference_reason, profile_status 
-		FROM profile_reference 
-		WHERE reference_profile_id = $1;`,
+		SELECT r.profile_id, r.reference_reason, r.profile_status, COALESCE(r.matched_rule_id, '') AS matched_rule_id,
+			COALESCE(r.matched_value, '') AS matched_value
+		FROM profile_reference r
+		JOIN profiles p ON p.profile_id = r.profile_id
+		WHERE r.reference_profile_id = $1
+		  AND p.deleted_at IS NULL;`,
+}
+
+// FetchChildProfiles lists the live profiles merged with status $2 to reference profile $1, in id order.
+var FetchChildProfiles = map[string]string{
+	"postgres": `
+		SELECT r.profile_id, p.user_id, r.reference_reason, COALESCE(r.matched_rule_id, '') AS matched_rule_id,
+			COALESCE(r.matched_value, '') AS matched_value, p.created_at, p.updated_at
+		FROM profile_reference r
+		JOIN profiles p ON p.profile_id = r.profile_id
+		WHERE r.reference_profile_id = $1
+		  AND r.profile_status = $2
+		  AND p.deleted_at IS NULL
+		ORDER BY r.profile_id;`,
+}
+
+// FetchReferencedProfilesByReferenceIds lists the profiles unified into any of the reference profiles in $1.
+var FetchReferencedProfilesByReferenceIds = map[string]string{
+	"postgres": `
+		SELECT r.reference_profile_id, r.profile_id, r.reference_reason, COALESCE(r.matched_rule_id, '') AS matched_rule_id,
+			COALESCE(r.matched_value, '') AS matched_value
+		FROM profile_reference r
+		JOIN profiles p ON p.profile_id = r.profile_id
+		WHERE r.reference_profile_id = ANY($1)
+		  AND p.deleted_at IS NULL;`,
+}
+
+// GetProfileByUserIdInOrg fetches the reference profile of user $1 in organization $2.
+var GetProfileByUserIdInOrg = map[string]string{
+	"postgres": `
+		SELECT p.profile_id, p.user_id, p.created_at, p.updated_at, p.location, p.org_handle, p.list_profile, 
+		       p.delete_profile, p.traits, p.identity_attributes, r.profile_status, r.reference_profile_id, 
+		       r.reference_reason
+		FROM profiles p
+		JOIN profile_reference r ON p.profile_id = r.profile_id
+		WHERE p.user_id = $1
+		  AND p.org_handle = $2
+		  AND r.profile_status = 'REFERENCE_PROFILE'
+		  AND p.deleted_at IS NULL
+		ORDER BY p.created_at
+		LIMIT 1;`,
 }
 
 var GetProfileByUserId = map[string]string{
@@ -302,7 +886,8 @@ var GetProfileByUserId = map[string]string{
 			profile_reference r ON p.profile_id = r.profile_id
 		WHERE 
 			p.user_id = $1
-			AND r.profile_status = 'REFERENCE_PROFILE';`,
+			AND r.profile_status = 'REFERENCE_PROFILE'
+			AND p.deleted_at IS NULL;`,
 }
 
 var InsertConsentCategory = map[string]string{
@@ -311,23 +896,24 @@ var InsertConsentCategory = map[string]string{
 }
 
 var GetAllConsentCategories = map[string]string{
-	"postgres": `SELECT category_name, category_identifier, org_handle, purpose, destinations FROM consent_categories`,
+	"postgres": `SELECT category_name, category_identifier, org_handle, purpose, destinations FROM consent_categories WHERE org_handle = $1`,
 }
 
 var GetConsentCategoryById = map[string]string{
-	"postgres": `SELECT category_name, category_identifier, org_handle, purpose, destinations FROM consent_categories WHERE category_identifier = $1`,
+	"postgres": `SELECT category_name, category_identifier, org_handle, purpose, destinations FROM consent_categories WHERE category_identifier = $1 AND org_handle = $2`,
 }
 
 var GetConsentCategoryByName = map[string]string{
-	"postgres": `SELECT category_name, category_identifier, org_handle, purpose, destinations FROM consent_categories WHERE category_name = $1`,
+	"postgres": `SELECT category_name, category_identifier, org_handle, purpose, destinations FROM consent_categories WHERE category_name = $1 AND org_handle = $2`,
 }
 
 var UpdateConsentCategory = map[string]string{
-	"postgres": `UPDATE consent_categories SET category_name=$1, purpose=$2, destinations=$3 WHERE category_identifier=$4`,
+	"postgres": `UPDATE consent_categories SET category_name=$1, purpose=$2, destinations=$3 
+                 WHERE category_identifier=$4 AND org_handle=$5`,
 }
 
 var DeleteConsentCategory = map[string]string{
-	"postgres": `DELETE FROM consent_categories WHERE category_identifier=$1`,
+	"postgres": `DELETE FROM consent_categories WHERE category_identifier=$1 AND org_handle=$2`,
 }
 
 var InsertCookie = map[string]string{
@@ -354,6 +940,81 @@ var DeleteCookieByProfileId = map[string]string{
 	"postgres": `DELETE FROM profile_cookies WHERE profile_id = $1`,
 }
 
+var InsertQuarantinedImportRecord = map[string]string{
+	"postgres": `INSERT INTO profile_import_quarantine (record_id, org_handle, source, line, payload, reason, quarantined_at) 
+                 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+}
+
+var GetQuarantinedImportRecords = map[string]string{
+	"postgres": `SELECT record_id, org_handle, source, line, payload, reason, quarantined_at FROM profile_import_quarantine 
+                 WHERE org_handle = $1 AND ($2 = '' OR source = $2) ORDER BY quarantined_at, line`,
+}
+
+var GetQuarantinedImportRecord = map[string]string{
+	"postgres": `SELECT record_id, org_handle, source, line, payload, reason, quarantined_at FROM profile_import_quarantine 
+                 WHERE record_id = $1 AND org_handle = $2`,
+}
+
+var DeleteQuarantinedImportRecord = map[string]string{
+	"postgres": `DELETE FROM profile_import_quarantine WHERE record_id = $1 AND org_handle = $2`,
+}
+
+var InsertUnmergeExclusion = map[string]string{
+	"postgres": `INSERT INTO profile_unmerge_exclusions (profile_id, excluded_profile_id, org_handle, rule_name, created_at) 
+                 VALUES ($1, $2, $3, $4, $5) 
+                 ON CONFLICT (profile_id, excluded_profile_id, rule_name) DO NOTHING`,
+}
+
+// CheckIdempotencyKeysTable reads from the idempotency key table to confirm it is accessible.
+var CheckIdempotencyKeysTable = map[string]string{
+	"postgres": `SELECT 1 FROM profile_idempotency_keys LIMIT 1`,
+}
+
+// ClaimIdempotencyKey records the key as in progress under the claim token. An expired key, including a claim whose
+// lease ran out, is claimed again; a live key returns no row.
+var ClaimIdempotencyKey = map[string]string{
+	"postgres": `INSERT INTO profile_idempotency_keys (org_handle, idempotency_key, claim_token, created_at, expires_at) 
+                 VALUES ($1, $2, $3, $4, $5) 
+                 ON CONFLICT (org_handle, idempotency_key) DO UPDATE 
+                 SET profile_id = NULL, claim_token = EXCLUDED.claim_token, created_at = EXCLUDED.created_at, 
+                     expires_at = EXCLUDED.expires_at 
+                 WHERE profile_idempotency_keys.expires_at <= EXCLUDED.created_at 
+                 RETURNING idempotency_key`,
+}
+
+var GetIdempotencyKey = map[string]string{
+	"postgres": `SELECT COALESCE(profile_id, '') AS profile_id FROM profile_idempotency_keys 
+                 WHERE org_handle = $1 AND idempotency_key = $2 AND expires_at > $3`,
+}
+
+// CompleteIdempotencyKey maps the key to the created profile only while the claim token still holds it, and returns
+// no row once another request took the key over.
+var CompleteIdempotencyKey = map[string]string{
+	"postgres": `UPDATE profile_idempotency_keys SET profile_id = $4, expires_at = $5 
+                 WHERE org_handle = $1 AND idempotency_key = $2 AND claim_token = $3 
+                 RETURNING idempotency_key`,
+}
+
+var ReleaseIdempotencyKey = map[string]string{
+	"postgres": `DELETE FROM profile_idempotency_keys 
+                 WHERE org_handle = $1 AND idempotency_key = $2 AND claim_token = $3 AND profile_id IS NULL`,
+}
+
+// PurgeExpiredIdempotencyKeys removes the idempotency keys, including abandoned claims, that expired before the
+// given time.
+var PurgeExpiredIdempotencyKeys = map[string]string{
+	"postgres": `
+		WITH purged AS (
+			DELETE FROM profile_idempotency_keys WHERE expires_at <= $1 RETURNING 1
+		)
+		SELECT COUNT(*) AS purged FROM purged;`,
+}
+
+var GetUnmergeExclusions = map[string]string{
+	"postgres": `SELECT profile_id, excluded_profile_id, rule_name FROM profile_unmerge_exclusions 
+                 WHERE profile_id = ANY($1) OR excluded_profile_id = ANY($1)`,
+}
+
 var GetOrgConfigurations = map[string]string{
 	"postgres": `SELECT config, value FROM cds_config WHERE org_handle = $1`,
 }
@@ -375,3 +1036,59 @@ var UpdateInitialSchemaSyncDoneConfig = map[string]string{
                  ON CONFLICT (org_handle, config) 
                  DO UPDATE SET value = EXCLUDED.value`,
 }
+
+var InsertWebhook = map[string]string{
+	"postgres": `INSERT INTO webhooks (webhook_id, org_handle, url, events, secret, is_active, created_at) 
+                 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+}
+
+var GetWebhooks = map[string]string{
+	"postgres": `SELECT webhook_id, org_handle, url, events, secret, is_active, created_at FROM webhooks 
+                 WHERE org_handle = $1 ORDER BY created_at, webhook_id`,
+}
+
+var GetWebhook = map[string]string{
+	"postgres": `SELECT webhook_id, org_handle, url, events, secret, is_active, created_at FROM webhooks 
+                 WHERE org_handle = $1 AND webhook_id = $2`,
+}
+
+var GetActiveWebhooksForEvent = map[string]string{
+	"postgres": `SELECT webhook_id, org_handle, url, events, secret, is_active, created_at FROM webhooks 
+                 WHERE org_handle = $1 AND is_active AND $2 = ANY(events)`,
+}
+
+var UpdateWebhook = map[string]string{
+	"postgres": `UPDATE webhooks SET url = $3, events = $4, secret = $5, is_active = $6 
+                 WHERE org_handle = $1 AND webhook_id = $2 RETURNING webhook_id`,
+}
+
+var DeleteWebhook = map[string]string{
+	"postgres": `DELETE FROM webhooks WHERE org_handle = $1 AND webhook_id = $2 RETURNING webhook_id`,
+}
+
+// RecommendedIndexes are the indexes of the schema that speed up filtering profiles on their JSONB documents,
+// filtering them on their email ignoring case and listing the profiles modified since a time, keyed by index name.
+// They are built concurrently so that adding them to an existing database does not block writes.
+var RecommendedIndexes = map[string]map[string]string{
+	"postgres": {
+		"idx_profiles_traits": `CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_profiles_traits ON profiles USING GIN (traits)`,
+		"idx_profiles_identity_attributes": `CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_profiles_identity_attributes
+			ON profiles USING GIN (identity_attributes)`,
+		"idx_profiles_org_updated_at": `CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_profiles_org_updated_at
+			ON profiles (org_handle, updated_at, profile_id)`,
+		"idx_profiles_email_lower": `CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_profiles_email_lower ON profiles
+			(org_handle, LOWER((identity_attributes #>> '{email}')))`,
+	},
+}
+
+// IsIndexInvalid reports whether index $1 exists but is not valid, as left behind by a concurrent build that failed
+// or was interrupted. Such an index is not used by queries and is skipped by CREATE INDEX IF NOT EXISTS.
+var IsIndexInvalid = map[string]string{
+	"postgres": `SELECT EXISTS (SELECT 1 FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid
+		WHERE c.relname = $1 AND NOT i.indisvalid)`,
+}
+
+// DropIndex drops the index named by the format argument without blocking writes.
+var DropIndex = map[string]string{
+	"postgres": `DROP INDEX CONCURRENTLY IF EXISTS %s`,
+}
@@ -18,7 +18,10 @@
 
 package errors
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type ErrorMessage struct {
 	Code        string `json:"error_code"`
@@ -26,9 +29,20 @@ type ErrorMessage struct {
 	Description string `json:"error_description"`
 }
 
+// FieldError identifies a single field of a request, such as an attribute or a filter clause, that failed
+// validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 type ClientError struct {
 	ErrorMessage
 	StatusCode int
+	// Details lists the individual fields that failed validation, if known.
+	Details []FieldError
+	// RetryAfter tells the client how long to wait before retrying, if set.
+	RetryAfter time.Duration
 }
 
 type ServerError struct {
@@ -40,10 +54,36 @@ func (e *ServerError) Error() string {
 	return fmt.Sprintf("[%s] %s: %v", e.Code, e.Message, e.Err)
 }
 
+// Unwrap returns the cause so that it can be matched with errors.Is and errors.As.
+func (e *ServerError) Unwrap() error {
+	return e.Err
+}
+
 func (e *ClientError) Error() string {
 	return fmt.Sprintf("[%s] %s", e.Code, e.Message)
 }
 
+// BatchError reports a batch request of which some items failed. It carries the results of all the items, so that
+// they are sent in a multi-status response and the caller can retry only the items that failed.
+type BatchError struct {
+	Results any
+	Failed  int
+	Total   int
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d items of the batch failed", e.Failed, e.Total)
+}
+
+// NewBatchError creates a batch error from the results of all the items of a batch.
+func NewBatchError(results any, failed, total int) *BatchError {
+	return &BatchError{
+		Results: results,
+		Failed:  failed,
+		Total:   total,
+	}
+}
+
 func NewServerError(msg ErrorMessage, cause error) *ServerError {
 	return &ServerError{
 		ErrorMessage: msg,
@@ -58,6 +98,15 @@ func NewClientError(msg ErrorMessage, code int) *ClientError {
 	}
 }
 
+// NewClientErrorWithDetails creates a client error that also reports which fields failed validation.
+func NewClientErrorWithDetails(msg ErrorMessage, code int, details []FieldError) *ClientError {
+	return &ClientError{
+		ErrorMessage: msg,
+		StatusCode:   code,
+		Details:      details,
+	}
+}
+
 func NewClientErrorWithoutCode(msg ErrorMessage) *ClientError {
 	return &ClientError{
 		ErrorMessage: msg,
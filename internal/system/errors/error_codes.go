@@ -28,6 +28,7 @@ var (
 	//   152xx - Unification Rules Management
 	//   153xx - Consent Management
 	//   154xx - Profiles & Cookie Management
+	//   155xx - Webhooks
 	//   159xx - Other Server Errors
 
 	GET_ADMIN_CONFIG = ErrorMessage{
@@ -118,6 +119,11 @@ var (
 		Message: "Error while deleting unification rule(s).",
 	}
 
+	PREVIEW_UNIFICATION_RULE = ErrorMessage{
+		Code:    errorPrefix + "15205",
+		Message: "Error while previewing unification rule.",
+	}
+
 	ADD_CONSENT_CATEGORY = ErrorMessage{
 		Code:    errorPrefix + "15301",
 		Message: "Adding consent category failed.",
@@ -158,6 +164,39 @@ var (
 		Message: "Fetching profile(s) failed.",
 	}
 
+	IMPORT_QUARANTINE = ErrorMessage{
+		Code:    errorPrefix + "15405",
+		Message: "Error while managing quarantined profile import records.",
+	}
+
+	EXPORT_PORTABLE_PROFILE = ErrorMessage{
+		Code:    errorPrefix + "15406",
+		Message: "Exporting portable profile failed.",
+	}
+	ORPHANED_PROFILE = ErrorMessage{
+		Code:    errorPrefix + "15407",
+		Message: "Reference profile of the merged profile does not exist.",
+	}
+	EXPORT_PROFILE = ErrorMessage{
+		Code:    errorPrefix + "15408",
+		Message: "Exporting profile failed.",
+	}
+	ADD_WEBHOOK = ErrorMessage{
+		Code:    errorPrefix + "15501",
+		Message: "Adding webhook failed.",
+	}
+	FETCH_WEBHOOKS = ErrorMessage{
+		Code:    errorPrefix + "15502",
+		Message: "Fetching webhook(s) failed.",
+	}
+	UPDATE_WEBHOOK = ErrorMessage{
+		Code:    errorPrefix + "15503",
+		Message: "Updating webhook failed.",
+	}
+	DELETE_WEBHOOK = ErrorMessage{
+		Code:    errorPrefix + "15504",
+		Message: "Deleting webhook failed.",
+	}
 	PARSING_ERROR = ErrorMessage{
 		Code:    errorPrefix + "15901",
 		Message: "Parsing token failed.",
@@ -169,6 +208,7 @@ var (
 	//   130xx - Profile Schema
 	//   140xx - Consent Management
 	//   160xx - Admin Configurations
+	//   170xx - Webhooks
 	//   190xx - Other Client Errors
 	BAD_REQUEST = ErrorMessage{
 		Code:    errorPrefix + "10001",
@@ -268,6 +308,116 @@ var (
 		Description: "Multiple user profiles record found for the given user_id",
 	}
 
+	INVALID_SORT_PARAMETER = ErrorMessage{
+		Code:    errorPrefix + "11017",
+		Message: "Invalid sort parameter.",
+	}
+
+	RESTORE_PROFILE = ErrorMessage{
+		Code:    errorPrefix + "11018",
+		Message: "Profile restore failed.",
+	}
+
+	PROFILE_IMPORT_QUARANTINED = ErrorMessage{
+		Code:    errorPrefix + "11019",
+		Message: "Profile import source is quarantined.",
+	}
+
+	QUARANTINED_RECORD_NOT_FOUND = ErrorMessage{
+		Code:    errorPrefix + "11020",
+		Message: "Quarantined import record not found.",
+	}
+
+	UNMERGE_PROFILE = ErrorMessage{
+		Code:    errorPrefix + "11021",
+		Message: "Profile unmerge failed.",
+	}
+
+	MERGE_PROFILE = ErrorMessage{
+		Code:    errorPrefix + "11022",
+		Message: "Profile merge failed.",
+	}
+
+	REQUEST_CANCELLED = ErrorMessage{
+		Code:    errorPrefix + "11023",
+		Message: "The request was cancelled before the profile was processed.",
+	}
+
+	REQUEST_TIMEOUT = ErrorMessage{
+		Code:    errorPrefix + "11024",
+		Message: "The request timed out before the profile was processed.",
+	}
+
+	PROFILE_VERSION_CONFLICT = ErrorMessage{
+		Code:    errorPrefix + "11025",
+		Message: "Profile version conflict.",
+	}
+
+	IDEMPOTENCY_KEY_IN_USE = ErrorMessage{
+		Code:    errorPrefix + "11026",
+		Message: "Idempotency key in use.",
+	}
+
+	INVALID_PROFILE_SYNC_EVENT = ErrorMessage{
+		Code:    errorPrefix + "11027",
+		Message: "Invalid profile sync event.",
+	}
+
+	RATE_LIMIT_EXCEEDED = ErrorMessage{
+		Code:    errorPrefix + "11028",
+		Message: "Rate limit exceeded.",
+	}
+
+	INVALID_GROUP_BY_PARAMETER = ErrorMessage{
+		Code:    errorPrefix + "11029",
+		Message: "Invalid group by parameter.",
+	}
+
+	COUNT_PROFILES = ErrorMessage{
+		Code:    errorPrefix + "11030",
+		Message: "Counting profiles failed.",
+	}
+
+	INVALID_EXPORT_FIELD = ErrorMessage{
+		Code:    errorPrefix + "11031",
+		Message: "Invalid export field.",
+	}
+
+	INVALID_DISTINCT_VALUES_REQUEST = ErrorMessage{
+		Code:    errorPrefix + "11032",
+		Message: "Invalid distinct trait values request.",
+	}
+
+	GET_DISTINCT_TRAIT_VALUES = ErrorMessage{
+		Code:    errorPrefix + "11033",
+		Message: "Fetching distinct trait values failed.",
+	}
+
+	PROFILE_ALREADY_EXISTS = ErrorMessage{
+		Code:    errorPrefix + "11034",
+		Message: "Profile already exists.",
+	}
+
+	GET_HIERARCHY_STATS = ErrorMessage{
+		Code:    errorPrefix + "11035",
+		Message: "Fetching profile hierarchy statistics failed.",
+	}
+
+	PROFILE_JOB = ErrorMessage{
+		Code:    errorPrefix + "11036",
+		Message: "Profile job failed.",
+	}
+
+	PROFILE_JOB_NOT_FOUND = ErrorMessage{
+		Code:    errorPrefix + "11037",
+		Message: "Profile job not found.",
+	}
+
+	PROFILE_JOB_ALREADY_RUNNING = ErrorMessage{
+		Code:    errorPrefix + "11038",
+		Message: "Profile job already running.",
+	}
+
 	UNIFICATION_RULE_NOT_FOUND = ErrorMessage{
 		Code:    errorPrefix + "12001",
 		Message: "No unification rule found.",
@@ -292,6 +442,26 @@ var (
 		Message: "Unification rule Id is required.",
 	}
 
+	UNIFICATION_RULE_NAME_EXISTS = ErrorMessage{
+		Code:    errorPrefix + "12006",
+		Message: "Unification rule with same name already exists.",
+	}
+
+	UNIFICATION_RULE_INVALID_PRIORITY = ErrorMessage{
+		Code:    errorPrefix + "12007",
+		Message: "Invalid unification rule priority.",
+	}
+
+	UNIFICATION_RULE_INACTIVE = ErrorMessage{
+		Code:    errorPrefix + "12008",
+		Message: "Unification rule is not active.",
+	}
+
+	UNIFICATION_RULE_INVALID_MERGE_MODE = ErrorMessage{
+		Code:    errorPrefix + "12009",
+		Message: "Invalid unification rule merge mode.",
+	}
+
 	PROFILE_SCHEMA_ADD_BAD_REQUEST = ErrorMessage{
 		Code:    errorPrefix + "13001",
 		Message: "Invalid request payload.",
@@ -358,6 +528,16 @@ var (
 		Description: "Customer data service is not enabled for the organization",
 	}
 
+	WEBHOOK_VALIDATION = ErrorMessage{
+		Code:    errorPrefix + "17001",
+		Message: "Webhook validation failed.",
+	}
+
+	WEBHOOK_NOT_FOUND = ErrorMessage{
+		Code:    errorPrefix + "17002",
+		Message: "Webhook not found.",
+	}
+
 	INVALID_FILTER_FORMAT = ErrorMessage{
 		Code:    errorPrefix + "19001",
 		Message: "Invalid filter format.",
@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/wso2/identity-customer-data-service/internal/system/metrics"
 	"github.com/wso2/identity-customer-data-service/internal/system/services"
 	"github.com/wso2/identity-customer-data-service/internal/system/utils"
 )
@@ -48,6 +49,7 @@ func (sm *ServiceManager) RegisterServices() error {
 	rootMux := http.NewServeMux()
 	_ = services.NewHealthService(rootMux) // registers /cds/api/v1/health, /ready
 	sm.mux.Handle("/cds/", rootMux)
+	sm.mux.Handle("GET /metrics", metrics.Handler())
 
 	// Initialize services with the shared tenant routes mux so they don't create their own mux
 	routesMux := http.NewServeMux()
@@ -57,6 +59,7 @@ func (sm *ServiceManager) RegisterServices() error {
 	_ = services.NewUnificationRulesService(routesMux)
 	_ = services.NewConsentCategoryService(routesMux)
 	_ = services.NewAdminConfigService(routesMux)
+	_ = services.NewWebhookService(routesMux)
 
 	// Single tenant dispatcher for all services; services own the versioned path (e.g., /api/v1/...)
 	utils.MountTenantDispatcher(sm.mux, func(w http.ResponseWriter, r *http.Request) {
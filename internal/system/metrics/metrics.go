@@ -0,0 +1,278 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package metrics keeps the counters and histograms of the service and exposes them in the Prometheus text
+// exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultBuckets are the upper bounds, in seconds, of the duration histograms.
+var defaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+var (
+	HTTPRequests = NewCounterVec("cds_http_requests_total",
+		"Number of HTTP requests served, by method and status code.", "method", "status")
+	HTTPRequestDuration = NewHistogramVec("cds_http_request_duration_seconds",
+		"Time taken to serve HTTP requests, by method.", defaultBuckets, "method")
+	ProfileUpserts = NewCounterVec("cds_profile_upserts_total",
+		"Number of profiles created or updated, by operation.", "operation")
+	ProfileVersionConflicts = NewCounterVec("cds_profile_version_conflicts_total",
+		"Number of profile updates rejected because the profile was modified concurrently.")
+	ProfileMerges = NewCounterVec("cds_profile_merges_total",
+		"Number of profiles merged into a reference profile, by trigger.", "trigger")
+	DBQueryDuration = NewHistogramVec("cds_db_query_duration_seconds",
+		"Time taken by database queries, by query label.", defaultBuckets, "query")
+	DBQueryAttempts = NewCounterVec("cds_db_query_retry_attempts_total",
+		"Number of attempts of database queries run with retries, by outcome.", "outcome")
+	DBTxAttempts = NewCounterVec("cds_db_transaction_attempts_total",
+		"Number of attempts of database transactions, by outcome.", "outcome")
+	WebhookDeliveries = NewCounterVec("cds_webhook_delivery_attempts_total",
+		"Number of attempts to deliver profile events to webhooks, by event type and outcome.", "event", "outcome")
+	RateLimitedRequests = NewCounterVec("cds_rate_limited_requests_total",
+		"Number of requests rejected by rate limiting, by the scope of the exhausted bucket.", "scope")
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   []collector
+)
+
+type collector interface {
+	write(w io.Writer)
+}
+
+func register(c collector) {
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, c)
+}
+
+// Write renders all metrics in the Prometheus text exposition format.
+func Write(w io.Writer) {
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, c := range registry {
+		c.write(w)
+	}
+}
+
+// Handler serves the metrics in the Prometheus text exposition format.
+func Handler() http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Write(w)
+	})
+}
+
+// CounterVec is a set of monotonically increasing counters partitioned by label values.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec creates and registers a counter with the given label names.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+
+	c := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]float64)}
+	register(c)
+	return c
+}
+
+// Inc increments the counter of the given label values by one.
+func (c *CounterVec) Inc(labelValues ...string) {
+
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter of the given label values by the given non-negative delta.
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+
+	if delta < 0 {
+		return
+	}
+	key := labelKey(c.labels, labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] += delta
+}
+
+// Value returns the current value of the counter of the given label values.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+
+	key := labelKey(c.labels, labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func (c *CounterVec) write(w io.Writer) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeHeader(w, c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		_, _ = fmt.Fprintf(w, "%s%s %s\n", c.name, braced(key), formatFloat(c.values[key]))
+	}
+}
+
+// HistogramVec is a set of histograms partitioned by label values.
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec creates and registers a histogram with the given bucket upper bounds and label names.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets,
+		values: make(map[string]*histogram)}
+	register(h)
+	return h
+}
+
+// Observe records a value, such as a duration in seconds, for the given label values.
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+
+	key := labelKey(h.labels, labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hist, ok := h.values[key]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hist
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			hist.counts[i]++
+		}
+	}
+	hist.count++
+	hist.sum += value
+}
+
+// Count returns the number of values observed for the given label values.
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+
+	key := labelKey(h.labels, labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hist, ok := h.values[key]; ok {
+		return hist.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, key := range sortedKeys(h.values) {
+		hist := h.values[key]
+		for i, bound := range h.buckets {
+			_, _ = fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, braced(joinLabels(key, "le", formatFloat(bound))),
+				hist.counts[i])
+		}
+		_, _ = fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, braced(joinLabels(key, "le", "+Inf")), hist.count)
+		_, _ = fmt.Fprintf(w, "%s_sum%s %s\n", h.name, braced(key), formatFloat(hist.sum))
+		_, _ = fmt.Fprintf(w, "%s_count%s %d\n", h.name, braced(key), hist.count)
+	}
+}
+
+// labelEscaper escapes label values as required by the exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelKey renders the label pairs of a series, such as `method="GET",status="200"`. Missing values are empty.
+func labelKey(names, values []string) string {
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(value))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func joinLabels(key, name, value string) string {
+
+	pair := fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(value))
+	if key == "" {
+		return pair
+	}
+	return key + "," + pair
+}
+
+func braced(key string) string {
+
+	if key == "" {
+		return ""
+	}
+	return "{" + key + "}"
+}
+
+func writeHeader(w io.Writer, name, help, metricType string) {
+
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func formatFloat(value float64) string {
+
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,242 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package ratelimit throttles the tenant APIs with token buckets kept per organization and per calling application,
+// so that one client retrying in a loop cannot starve the database for everyone else.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/metrics"
+)
+
+// Usage is the state of the bucket of an organization or of one of its applications.
+type Usage struct {
+	AppId             string    `json:"app_id,omitempty"`
+	RequestsPerSecond float64   `json:"requests_per_second"`
+	Burst             int       `json:"burst"`
+	AvailableTokens   float64   `json:"available_tokens"`
+	AllowedRequests   uint64    `json:"allowed_requests"`
+	RejectedRequests  uint64    `json:"rejected_requests"`
+	Since             time.Time `json:"since"`
+}
+
+// UsageReport is the usage of the buckets of an organization. Counts start over when a bucket is dropped after
+// being idle.
+type UsageReport struct {
+	Enabled      bool    `json:"enabled"`
+	Organization Usage   `json:"organization"`
+	Applications []Usage `json:"applications"`
+}
+
+type bucketKey struct {
+	scope     string
+	orgHandle string
+	appId     string
+}
+
+type bucket struct {
+	tokens   float64
+	updated  time.Time
+	created  time.Time
+	allowed  uint64
+	rejected uint64
+}
+
+// limiter holds the buckets. A bucket starts full, so dropping an idle bucket loses nothing but its counts.
+type limiter struct {
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+var defaultLimiter = newLimiter(time.Now)
+
+func newLimiter(now func() time.Time) *limiter {
+	return &limiter{
+		buckets:   make(map[bucketKey]*bucket),
+		lastSweep: now(),
+		now:       now,
+	}
+}
+
+// Allow takes a token for a request of the application in the organization. It returns a client error carrying
+// the time to wait before retrying when the bucket of the organization or of the application is empty.
+func Allow(orgHandle, appId string) error {
+
+	cfg := config.GetCDSRuntime().Config.RateLimit
+	if !cfg.Enabled {
+		return nil
+	}
+	limits := map[bucketKey]config.RateLimit{
+		{scope: constants.RateLimitScopeOrg, orgHandle: orgHandle}: orgLimit(cfg, orgHandle),
+	}
+	if appId != "" && cfg.Application.RequestsPerSecond > 0 {
+		limits[bucketKey{scope: constants.RateLimitScopeApplication, orgHandle: orgHandle, appId: appId}] =
+			cfg.Application
+	}
+
+	exhausted, wait := defaultLimiter.take(limits)
+	if exhausted == nil {
+		return nil
+	}
+	metrics.RateLimitedRequests.Inc(exhausted.scope)
+	clientError := errors.NewClientError(errors.ErrorMessage{
+		Code:    errors.RATE_LIMIT_EXCEEDED.Code,
+		Message: errors.RATE_LIMIT_EXCEEDED.Message,
+		Description: fmt.Sprintf("Too many requests for the %s. Retry after %s.", exhausted.scope,
+			wait.Round(time.Millisecond)),
+	}, http.StatusTooManyRequests)
+	clientError.RetryAfter = wait
+	return clientError
+}
+
+// GetUsage reports the buckets of the organization as they are now.
+func GetUsage(orgHandle string) UsageReport {
+
+	cfg := config.GetCDSRuntime().Config.RateLimit
+	return defaultLimiter.usage(orgHandle, cfg)
+}
+
+// orgLimit returns the limit of the organization, falling back to the default one.
+func orgLimit(cfg config.RateLimitConfig, orgHandle string) config.RateLimit {
+
+	if limit, ok := cfg.Organizations[orgHandle]; ok {
+		return limit
+	}
+	return cfg.Organization
+}
+
+// capacity is the burst of the limit, which is at least one request so that a bucket can ever allow a request.
+func capacity(limit config.RateLimit) float64 {
+
+	if limit.Burst > 0 {
+		return float64(limit.Burst)
+	}
+	return math.Max(1, math.Ceil(limit.RequestsPerSecond))
+}
+
+// take takes a token from each of the buckets, or from none of them when one is empty, in which case it returns
+// the key of the empty bucket and how long until it holds a token again. Unlimited buckets are not tracked.
+func (l *limiter) take(limits map[bucketKey]config.RateLimit) (*bucketKey, time.Duration) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	taken := make([]*bucket, 0, len(limits))
+	for key, limit := range limits {
+		if limit.RequestsPerSecond <= 0 {
+			continue
+		}
+		b := l.refill(key, limit, now)
+		if b.tokens < 1 {
+			b.rejected++
+			wait := time.Duration((1 - b.tokens) / limit.RequestsPerSecond * float64(time.Second))
+			return &key, wait
+		}
+		taken = append(taken, b)
+	}
+	for _, b := range taken {
+		b.tokens--
+		b.allowed++
+	}
+	return nil, 0
+}
+
+// refill returns the bucket of the key topped up for the time elapsed since it was last used.
+func (l *limiter) refill(key bucketKey, limit config.RateLimit, now time.Time) *bucket {
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity(limit), updated: now, created: now}
+		l.buckets[key] = b
+		return b
+	}
+	elapsed := now.Sub(b.updated).Seconds()
+	b.tokens = math.Min(capacity(limit), b.tokens+elapsed*limit.RequestsPerSecond)
+	b.updated = now
+	return b
+}
+
+// sweep drops the buckets left idle for RateLimitIdleTimeout, at most once per that period.
+func (l *limiter) sweep(now time.Time) {
+
+	if now.Sub(l.lastSweep) < constants.RateLimitIdleTimeout {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= constants.RateLimitIdleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *limiter) usage(orgHandle string, cfg config.RateLimitConfig) UsageReport {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	report := UsageReport{Enabled: cfg.Enabled, Applications: []Usage{}}
+	orgKey := bucketKey{scope: constants.RateLimitScopeOrg, orgHandle: orgHandle}
+	report.Organization = l.bucketUsage(orgKey, orgLimit(cfg, orgHandle), now)
+	for key := range l.buckets {
+		if key.scope == constants.RateLimitScopeApplication && key.orgHandle == orgHandle {
+			report.Applications = append(report.Applications, l.bucketUsage(key, cfg.Application, now))
+		}
+	}
+	sort.Slice(report.Applications, func(i, j int) bool {
+		return report.Applications[i].AppId < report.Applications[j].AppId
+	})
+	return report
+}
+
+// bucketUsage reports a bucket without taking from it. A bucket not used yet is reported full, and an unlimited
+// one is reported with neither burst nor tokens.
+func (l *limiter) bucketUsage(key bucketKey, limit config.RateLimit, now time.Time) Usage {
+
+	usage := Usage{AppId: key.appId, Since: now}
+	if limit.RequestsPerSecond <= 0 {
+		return usage
+	}
+	usage.RequestsPerSecond = limit.RequestsPerSecond
+	usage.Burst = int(capacity(limit))
+	usage.AvailableTokens = capacity(limit)
+	if b, ok := l.buckets[key]; ok {
+		elapsed := now.Sub(b.updated).Seconds()
+		usage.AvailableTokens = math.Min(capacity(limit), b.tokens+elapsed*limit.RequestsPerSecond)
+		usage.AllowedRequests = b.allowed
+		usage.RejectedRequests = b.rejected
+		usage.Since = b.created
+	}
+	return usage
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package security
+
+import (
+	"context"
+	"strings"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	// Subject is the user or client the token was issued to.
+	Subject string
+	// AppId is the application the token was issued to, taken from the azp claim or else the client_id claim.
+	AppId     string
+	OrgHandle string
+	Scopes    []string
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of the context carrying the identity.
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity attached by AuthnAndAuthz, and false if the request was not
+// authenticated through it.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// identityFromClaims builds the identity of the caller from the claims of its token.
+func identityFromClaims(orgHandle string, claims map[string]interface{}) Identity {
+
+	identity := Identity{OrgHandle: orgHandle}
+	identity.Subject, _ = claims[constants.SubjectClaim].(string)
+	if azp, ok := claims[constants.AZPClaim].(string); ok && azp != "" {
+		identity.AppId = azp
+	} else {
+		identity.AppId, _ = claims[constants.ClientIdClaim].(string)
+	}
+	if scope, ok := claims[constants.ScopeClaim].(string); ok {
+		identity.Scopes = strings.Fields(scope)
+	}
+	return identity
+}
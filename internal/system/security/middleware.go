@@ -27,8 +27,10 @@ import (
 	"github.com/wso2/identity-customer-data-service/internal/system/authn"
 	"github.com/wso2/identity-customer-data-service/internal/system/authz"
 	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
 	"github.com/wso2/identity-customer-data-service/internal/system/errors"
 	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/system/ratelimit"
 	"github.com/wso2/identity-customer-data-service/internal/system/utils"
 )
 
@@ -83,8 +85,10 @@ func validateAdminCredentials(token string) (bool, error) {
 	return false, nil
 }
 
-// AuthnAndAuthz performs authentication and authorization for the given HTTP request and operation.
-func AuthnAndAuthz(r *http.Request, operation string) error {
+// AuthnAndAuthz performs authentication and authorization for the given HTTP request and operation. Authenticated
+// callers are rate limited per organization and application. On success it returns the request with the identity of
+// the caller attached to its context, where IdentityFromContext finds it.
+func AuthnAndAuthz(r *http.Request, operation string) (*http.Request, error) {
 
 	authHeader := r.Header.Get("Authorization")
 	if !strings.HasPrefix(authHeader, "Bearer ") || authHeader == "" {
@@ -93,7 +97,7 @@ func AuthnAndAuthz(r *http.Request, operation string) error {
 			Message:     errors.UN_AUTHORIZED.Message,
 			Description: "Missing or invalid Authorization header",
 		}, http.StatusUnauthorized)
-		return clientError
+		return nil, clientError
 	}
 
 	orgHandle := utils.ExtractOrgHandleFromPath(r)
@@ -108,18 +112,24 @@ func AuthnAndAuthz(r *http.Request, operation string) error {
 			Message:     errors.UN_AUTHORIZED.Message,
 			Description: "Missing or invalid Authorization header",
 		}, http.StatusUnauthorized)
-		return clientError
+		return nil, clientError
+	}
+	identity := identityFromClaims(orgHandle, claims)
+
+	// Throttle the caller before the handler does any work for it
+	if err := ratelimit.Allow(orgHandle, identity.AppId); err != nil {
+		return nil, err
 	}
 
 	//  Validate authorization
-	scope, ok := claims["scope"]
+	scope, ok := claims[constants.ScopeClaim]
 	if !ok || scope == nil {
 		clientError := errors.NewClientError(errors.ErrorMessage{
 			Code:        errors.FORBIDDEN.Code,
 			Message:     errors.FORBIDDEN.Message,
 			Description: errors.FORBIDDEN.Description,
 		}, http.StatusForbidden)
-		return clientError
+		return nil, clientError
 	}
 
 	if !authz.ValidatePermission(scope.(string), operation) {
@@ -128,7 +138,7 @@ func AuthnAndAuthz(r *http.Request, operation string) error {
 			Message:     errors.FORBIDDEN.Message,
 			Description: "Do not have permission to perform this operation",
 		}, http.StatusForbidden)
-		return clientError
+		return nil, clientError
 	}
-	return nil
+	return r.WithContext(WithIdentity(r.Context(), identity)), nil
 }
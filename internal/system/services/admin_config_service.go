@@ -41,6 +41,7 @@ func NewAdminConfigService(mux *http.ServeMux) *AdminConfigService {
 	// Register routes with Go 1.22 ServeMux patterns on shared mux
 	s.mux.HandleFunc("GET "+base+"/config", s.handler.GetAdminConfig)
 	s.mux.HandleFunc("PATCH "+base+"/config", s.handler.UpdateAdminConfig)
+	s.mux.HandleFunc("GET "+base+"/rate-limits/usage", s.handler.GetRateLimitUsage)
 
 	return s
 }
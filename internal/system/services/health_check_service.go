@@ -43,6 +43,8 @@ func NewHealthService(mux *http.ServeMux) *HealthService {
 	const base = constants.ApiBasePath + "/v1"
 	s.mux.HandleFunc("GET "+base+"/health", s.handler.HandleHealth)
 	s.mux.HandleFunc("GET "+base+"/ready", s.handler.HandleReadiness)
+	s.mux.HandleFunc("GET "+base+"/health/live", s.handler.HandleHealth)
+	s.mux.HandleFunc("GET "+base+"/health/ready", s.handler.HandleReadiness)
 
 	return s
 }
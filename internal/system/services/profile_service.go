@@ -41,14 +41,45 @@ func NewProfileService(mux *http.ServeMux) *ProfileService {
 	// Register routes using Go 1.22+ ServeMux patterns on the shared mux
 	ps.mux.HandleFunc("GET "+base+"/profiles", ps.profileHandler.GetAllProfiles)
 	ps.mux.HandleFunc("POST "+base+"/profiles", ps.profileHandler.InitProfile)
+	ps.mux.HandleFunc("DELETE "+base+"/profiles", ps.profileHandler.DeleteProfilesByFilter)
+	ps.mux.HandleFunc("PATCH "+base+"/profiles", ps.profileHandler.PatchProfilesByFilter)
 	ps.mux.HandleFunc("GET "+base+"/profiles/Me", ps.profileHandler.GetCurrentUserProfile)
+	ps.mux.HandleFunc("GET "+base+"/profiles/resolve", ps.profileHandler.ResolveProfile)
+	ps.mux.HandleFunc("GET "+base+"/profiles/count", ps.profileHandler.CountProfiles)
+	ps.mux.HandleFunc("GET "+base+"/profiles/stats", ps.profileHandler.GetHierarchyStats)
+	ps.mux.HandleFunc("POST "+base+"/profiles/reunify", ps.profileHandler.ReunifyAll)
+	ps.mux.HandleFunc("GET "+base+"/profiles/distinct-values", ps.profileHandler.GetDistinctTraitValues)
+	ps.mux.HandleFunc("GET "+base+"/profiles/export", ps.profileHandler.ExportProfilesCSV)
+	ps.mux.HandleFunc("GET "+base+"/profiles/stream", ps.profileHandler.StreamProfiles)
 	ps.mux.HandleFunc("PATCH "+base+"/profiles/Me", ps.profileHandler.PatchCurrentUserProfile)
 	ps.mux.HandleFunc("POST "+base+"/profiles/sync", ps.profileHandler.SyncProfile)
+	ps.mux.HandleFunc("POST "+base+"/profiles/upsert", ps.profileHandler.GetOrCreateProfile)
+	ps.mux.HandleFunc("POST "+base+"/profiles/simulate", ps.profileHandler.SimulateProfile)
+	ps.mux.HandleFunc("POST "+base+"/profiles/import", ps.profileHandler.ImportProfiles)
+	ps.mux.HandleFunc("POST "+base+"/profiles/batch", ps.profileHandler.ApplyProfilesBatch)
+	ps.mux.HandleFunc("POST "+base+"/profiles/alias", ps.profileHandler.AliasProfiles)
+	ps.mux.HandleFunc("GET "+base+"/profiles/import/quarantine", ps.profileHandler.GetQuarantinedImportRecords)
+	ps.mux.HandleFunc("POST "+base+"/profiles/import/quarantine/{recordId}/replay", ps.profileHandler.ReplayQuarantinedImportRecord)
+	ps.mux.HandleFunc("DELETE "+base+"/profiles/import/quarantine/{recordId}", ps.profileHandler.DiscardQuarantinedImportRecord)
+	ps.mux.HandleFunc("POST "+base+"/profiles/import/sources/{source}/release", ps.profileHandler.ReleaseImportSource)
 
 	// Routes with path variables
+	ps.mux.HandleFunc("GET "+base+"/jobs/{jobId}", ps.profileHandler.GetProfileJob)
+	ps.mux.HandleFunc("POST "+base+"/jobs/{jobId}/cancel", ps.profileHandler.CancelProfileJob)
 	ps.mux.HandleFunc("GET "+base+"/profiles/{profileId}", ps.profileHandler.GetProfile)
 	ps.mux.HandleFunc("PATCH "+base+"/profiles/{profileId}", ps.profileHandler.PatchProfile)
 	ps.mux.HandleFunc("DELETE "+base+"/profiles/{profileId}", ps.profileHandler.DeleteProfile)
+	ps.mux.HandleFunc("POST "+base+"/profiles/{profileId}/restore", ps.profileHandler.RestoreProfile)
+	ps.mux.HandleFunc("POST "+base+"/profiles/{profileId}/anonymize", ps.profileHandler.AnonymizeProfile)
+	ps.mux.HandleFunc("POST "+base+"/profiles/{profileId}/merge", ps.profileHandler.MergeProfiles)
+	ps.mux.HandleFunc("POST "+base+"/profiles/{profileId}/unmerge", ps.profileHandler.UnmergeProfile)
+	ps.mux.HandleFunc("GET "+base+"/profiles/{profileId}/lineage", ps.profileHandler.GetProfileLineage)
+	ps.mux.HandleFunc("GET "+base+"/profiles/{profileId}/children", ps.profileHandler.GetChildProfiles)
+	ps.mux.HandleFunc("GET "+base+"/profiles/{profileId}/history", ps.profileHandler.GetProfileHistory)
+	ps.mux.HandleFunc("GET "+base+"/profiles/{profileId}/portable-export", ps.profileHandler.ExportPortableProfile)
+	ps.mux.HandleFunc("GET "+base+"/profiles/{profileId}/export", ps.profileHandler.ExportProfile)
+	ps.mux.HandleFunc("POST "+base+"/profiles/{profileId}/attributes/{path}/increment", ps.profileHandler.IncrementProfileAttribute)
+	ps.mux.HandleFunc("PATCH "+base+"/profiles/{profileId}/application-data/{appId}", ps.profileHandler.PatchApplicationData)
 	ps.mux.HandleFunc("GET "+base+"/profiles/{profileId}/consents", ps.profileHandler.GetProfileConsents)
 	ps.mux.HandleFunc("PUT "+base+"/profiles/{profileId}/consents", ps.profileHandler.UpdateProfileConsents)
 
@@ -41,10 +41,19 @@ func NewUnificationRulesService(mux *http.ServeMux) *UnificationRulesService {
 	const base = constants.ApiBasePath + "/v1"
 	// Register routes using Go 1.22 ServeMux patterns on shared mux
 	s.mux.HandleFunc("POST "+base+"/unification-rules", s.unificationRulesHandler.AddUnificationRule)
+	s.mux.HandleFunc("POST "+base+"/unification-rules/batch", s.unificationRulesHandler.AddUnificationRules)
+	s.mux.HandleFunc("POST "+base+"/unification-rules/reorder", s.unificationRulesHandler.ReorderUnificationRules)
+	s.mux.HandleFunc("POST "+base+"/unification-rules/preview", s.unificationRulesHandler.PreviewUnificationRule)
 	s.mux.HandleFunc("GET "+base+"/unification-rules", s.unificationRulesHandler.GetUnificationRules)
 	s.mux.HandleFunc("GET "+base+"/unification-rules/{ruleId}", s.unificationRulesHandler.GetUnificationRule)
 	s.mux.HandleFunc("PATCH "+base+"/unification-rules/{ruleId}", s.unificationRulesHandler.PatchUnificationRule)
 	s.mux.HandleFunc("DELETE "+base+"/unification-rules/{ruleId}", s.unificationRulesHandler.DeleteUnificationRule)
+	s.mux.HandleFunc("PUT "+base+"/unification-rules/{ruleId}/priority", s.unificationRulesHandler.SetUnificationRulePriority)
+	s.mux.HandleFunc("PUT "+base+"/unification-rules/{ruleId}/name", s.unificationRulesHandler.RenameUnificationRule)
+	s.mux.HandleFunc("PUT "+base+"/unification-rules/{ruleId}/property", s.unificationRulesHandler.SetUnificationRuleProperty)
+	s.mux.HandleFunc("POST "+base+"/unification-rules/{ruleId}/activate", s.unificationRulesHandler.ActivateUnificationRule)
+	s.mux.HandleFunc("POST "+base+"/unification-rules/{ruleId}/deactivate", s.unificationRulesHandler.DeactivateUnificationRule)
+	s.mux.HandleFunc("POST "+base+"/unification-rules/{ruleId}/apply", s.unificationRulesHandler.ApplyUnificationRule)
 
 	return s
 }
@@ -0,0 +1,47 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package services
+
+import (
+	"net/http"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/webhook/handler"
+)
+
+type WebhookService struct {
+	handler *handler.WebhookHandler
+	mux     *http.ServeMux
+}
+
+func NewWebhookService(mux *http.ServeMux) *WebhookService {
+	s := &WebhookService{
+		handler: handler.NewWebhookHandler(),
+		mux:     mux,
+	}
+
+	const base = constants.ApiBasePath + "/v1"
+	s.mux.HandleFunc("GET "+base+"/webhooks", s.handler.GetWebhooks)
+	s.mux.HandleFunc("POST "+base+"/webhooks", s.handler.AddWebhook)
+	s.mux.HandleFunc("GET "+base+"/webhooks/{webhookId}", s.handler.GetWebhook)
+	s.mux.HandleFunc("PUT "+base+"/webhooks/{webhookId}", s.handler.UpdateWebhook)
+	s.mux.HandleFunc("DELETE "+base+"/webhooks/{webhookId}", s.handler.DeleteWebhook)
+
+	return s
+}
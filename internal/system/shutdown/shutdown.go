@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package shutdown
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestTracker counts the requests being served so that shutdown can wait for them after the server has stopped
+// accepting new ones.
+type RequestTracker struct {
+	inFlight sync.WaitGroup
+}
+
+// Track wraps the handler so that every request is counted until its handler returns.
+func (t *RequestTracker) Track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.inFlight.Add(1)
+		defer t.inFlight.Done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Wait blocks until every tracked request has returned or the timeout elapses, and reports whether they all
+// returned. It must only be called once the server no longer accepts requests.
+func (t *RequestTracker) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		t.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Drainer stops an HTTP server whose requests are counted by Tracker and derive their context from the one that
+// AbortRequests cancels.
+type Drainer struct {
+	Server        *http.Server
+	Tracker       *RequestTracker
+	MarkNotReady  func()
+	AbortRequests context.CancelFunc
+	// ReadinessDelay is how long the server keeps serving after it reports not ready, so that load balancers stop
+	// routing requests to it before it stops accepting them.
+	ReadinessDelay time.Duration
+	// Timeout is how long the requests in flight are given to complete once the server stops accepting requests.
+	Timeout time.Duration
+	// AbortGracePeriod is how long the requests cancelled after Timeout are given to return.
+	AbortGracePeriod time.Duration
+}
+
+// Drain reports the server not ready, waits for ReadinessDelay and shuts the server down, giving the requests in
+// flight Timeout to complete. Requests still running then are cancelled, so that their transactions roll back
+// instead of being cut off when the database is closed. Drain reports whether every request returned.
+func (d Drainer) Drain() (bool, error) {
+
+	d.MarkNotReady()
+	time.Sleep(d.ReadinessDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout)
+	defer cancel()
+	err := d.Server.Shutdown(ctx)
+	d.AbortRequests()
+	return d.Tracker.Wait(d.AbortGracePeriod), err
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// httpClient uploads spans to an OTLP/HTTP collector using the binary protobuf encoding.
+type httpClient struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+func newHTTPClient(endpoint string, headers map[string]string) *httpClient {
+
+	return &httpClient{
+		endpoint: endpoint,
+		headers:  headers,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Start does nothing as connections are opened per upload.
+func (c *httpClient) Start(context.Context) error {
+
+	return nil
+}
+
+// Stop releases idle connections to the collector.
+func (c *httpClient) Stop(context.Context) error {
+
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// UploadTraces posts the spans as an export request to the collector. TracesData has the same wire format as
+// the ExportTraceServiceRequest of the collector API.
+func (c *httpClient) UploadTraces(ctx context.Context, protoSpans []*tracepb.ResourceSpans) error {
+
+	body, err := proto.Marshal(&tracepb.TracesData{ResourceSpans: protoSpans})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	for name, value := range c.headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("exporting spans to %s failed with status: %s", c.endpoint, response.Status)
+	}
+	return nil
+}
+
+// parseHeaders parses headers given as comma separated, URL encoded key=value pairs.
+func parseHeaders(raw string) map[string]string {
+
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			continue
+		}
+		key, errKey := url.PathUnescape(strings.TrimSpace(key))
+		value, errValue := url.PathUnescape(strings.TrimSpace(value))
+		if errKey != nil || errValue != nil || key == "" {
+			continue
+		}
+		headers[key] = value
+	}
+	return headers
+}
@@ -24,7 +24,9 @@ import (
 	"encoding/json"
 	"errors" // Standard Go errors package
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/wso2/identity-customer-data-service/internal/system/constants"
@@ -33,20 +35,44 @@ import (
 	"github.com/wso2/identity-customer-data-service/internal/system/log"
 )
 
-// HandleError sends an HTTP error response based on the provided error
+// HandleError sends an HTTP error response based on the provided error. A batch error is sent as a 207 Multi-Status
+// response carrying the result of every item of the batch.
 func HandleError(w http.ResponseWriter, err error) {
 	var clientError *customerrors.ClientError
 	w.Header().Set("Content-Type", "application/json")
+	if err == nil {
+		log.GetLogger().Error("No error was given to be sent as the error response")
+		writeInternalServerError(w)
+		return
+	}
 	if ok := errors.As(err, &clientError); ok {
+		if clientError.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(clientError.RetryAfter.Seconds()))))
+		}
 		w.WriteHeader(clientError.StatusCode)
 		_ = json.NewEncoder(w).Encode(struct {
-			Code        string `json:"code"`
-			Message     string `json:"message"`
-			Description string `json:"description"`
+			Code        string                    `json:"code"`
+			Message     string                    `json:"message"`
+			Description string                    `json:"description"`
+			Details     []customerrors.FieldError `json:"details,omitempty"`
 		}{
 			Code:        clientError.ErrorMessage.Code,
 			Message:     clientError.ErrorMessage.Message,
 			Description: clientError.ErrorMessage.Description,
+			Details:     clientError.Details,
+		})
+		return
+	}
+
+	var batchError *customerrors.BatchError
+	if ok := errors.As(err, &batchError); ok {
+		w.WriteHeader(http.StatusMultiStatus)
+		_ = json.NewEncoder(w).Encode(struct {
+			Results any `json:"results"`
+			Failed  int `json:"failed"`
+		}{
+			Results: batchError.Results,
+			Failed:  batchError.Failed,
 		})
 		return
 	}
@@ -55,12 +81,22 @@ func HandleError(w http.ResponseWriter, err error) {
 	if ok := errors.As(err, &serverError); ok {
 		logger := log.GetLogger()
 		logger.Error(err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"error": "Internal server error",
-		})
+		writeInternalServerError(w)
 		return
 	}
+
+	// Errors that are not wrapped in a client or server error are treated as unexpected server errors.
+	log.GetLogger().Error("Unexpected error while processing the request", log.Error(err))
+	writeInternalServerError(w)
+}
+
+// writeInternalServerError writes the generic response of a server error, without exposing its cause.
+func writeInternalServerError(w http.ResponseWriter) {
+
+	w.WriteHeader(http.StatusInternalServerError)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error": "Internal server error",
+	})
 }
 
 func ExtractOrgHandleFromPath(r *http.Request) string {
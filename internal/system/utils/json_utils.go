@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+)
+
+// NewJSONDecoder returns a decoder for profile data. When number precision is preserved, numbers decoded into
+// interface values are kept as json.Number instead of being rounded to float64.
+func NewJSONDecoder(r io.Reader) *json.Decoder {
+
+	decoder := json.NewDecoder(r)
+	if config.GetCDSRuntime().Config.Validation.PreserveNumberPrecision {
+		decoder.UseNumber()
+	}
+	return decoder
+}
+
+// UnmarshalJSON is json.Unmarshal with the number handling of NewJSONDecoder.
+func UnmarshalJSON(data []byte, v interface{}) error {
+
+	if !config.GetCDSRuntime().Config.Validation.PreserveNumberPrecision {
+		return json.Unmarshal(data, v)
+	}
+	decoder := NewJSONDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"golang.org/x/text/unicode/norm"
+)
+
+// nonBreakingSpaces are rendered like a regular space but never match one.
+var nonBreakingSpaces = strings.NewReplacer("\u00a0", " ", "\u2007", " ", "\u202f", " ")
+
+// NormalizeString applies the configured unicode and whitespace normalization to a value so that values which
+// look identical are stored and matched identically.
+func NormalizeString(value string) string {
+
+	normalization := config.GetCDSRuntime().Config.Normalization
+	if !normalization.DisableWhitespaceTrimming {
+		value = nonBreakingSpaces.Replace(value)
+		value = strings.TrimFunc(value, unicode.IsSpace)
+	}
+	if !normalization.DisableUnicodeNormalization {
+		value = norm.NFC.String(value)
+	}
+	return value
+}
+
+// NormalizeValue normalizes every string found in the given value, descending into maps and slices.
+func NormalizeValue(value interface{}) interface{} {
+
+	switch v := value.(type) {
+	case string:
+		return NormalizeString(v)
+	case []string:
+		for i := range v {
+			v[i] = NormalizeString(v[i])
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = NormalizeValue(v[i])
+		}
+		return v
+	case map[string]interface{}:
+		return NormalizeMap(v)
+	default:
+		return value
+	}
+}
+
+// NormalizeMap normalizes the string values of the given map in place and returns it.
+func NormalizeMap(values map[string]interface{}) map[string]interface{} {
+
+	for k, v := range values {
+		values[k] = NormalizeValue(v)
+	}
+	return values
+}
@@ -20,23 +20,26 @@ package workers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/google/uuid"
 	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
 	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
 	schemaModel "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
 	schemaStore "github.com/wso2/identity-customer-data-service/internal/profile_schema/store"
+	"github.com/wso2/identity-customer-data-service/internal/system/changestream"
 	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
 	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/system/metrics"
 	"github.com/wso2/identity-customer-data-service/internal/system/queue"
 	"github.com/wso2/identity-customer-data-service/internal/system/utils"
 	"github.com/wso2/identity-customer-data-service/internal/unification_rules/model"
 	"github.com/wso2/identity-customer-data-service/internal/unification_rules/provider"
+	webhookService "github.com/wso2/identity-customer-data-service/internal/webhook/service"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -114,358 +117,353 @@ func StopProfileWorker() error {
 	return nil
 }
 
-// unifyProfiles unifies profiles based on unification rules
-func unifyProfiles(newProfile profileModel.Profile) {
+// UnificationOutcome tells which profile a profile and the reference profile matched with it are unified into.
+type UnificationOutcome string
+
+const (
+	// MergedToMatch adds the profile to the matched reference profile.
+	MergedToMatch UnificationOutcome = "merged_to_match"
+	// MatchMergedToProfile makes the profile the reference profile of the match and of the profiles merged to it.
+	MatchMergedToProfile UnificationOutcome = "match_merged_to_profile"
+	// MergedToNewMaster creates a new reference profile for the profile and the match.
+	MergedToNewMaster UnificationOutcome = "merged_to_new_master"
+)
 
-	// Step 1: Fetch all unification rules
-	ruleProvider := provider.NewUnificationRuleProvider()
-	ruleService := ruleProvider.GetUnificationRuleService()
-	unificationRules, err := ruleService.GetUnificationRules(newProfile.OrgHandle)
+// UnificationMerge is how the active unification rules of an organization unify a profile: the reference profile a
+// rule matched it with, with its references loaded, the rule and the value they matched on, and the outcome.
+type UnificationMerge struct {
+	Match        profileModel.Profile
+	Rule         model.UnificationRule
+	MatchedValue string
+	Outcome      UnificationOutcome
+}
+
+// FindUnificationMerge decides how the profile is unified by the active unification rules of its organization,
+// without unifying them. Rules are tried in the order of their priority and the first match that can be merged is
+// returned. A temporary profile is added to a permanent one, two temporary profiles or two permanent profiles of the
+// same user are added to the match when it already has references and to a new master otherwise, and two permanent
+// profiles of different users are never merged. Profiles unmerged before are not unified again by the same rule.
+// Nil is returned when the profile is not unified.
+func FindUnificationMerge(newProfile profileModel.Profile) (*UnificationMerge, error) {
+
+	ruleService := provider.NewUnificationRuleProvider().GetUnificationRuleService()
+	unificationRules, err := ruleService.GetActiveUnificationRules(newProfile.OrgHandle)
 	logger := log.GetLogger()
+	if err != nil {
+		return nil, err
+	}
 	if len(unificationRules) == 0 {
-		logger.Info(fmt.Sprintf("No unification rules found for tenant: %s", newProfile.OrgHandle))
-		return
+		logger.Info(fmt.Sprintf("No active unification rules found for tenant: %s", newProfile.OrgHandle))
+		return nil, nil
 	}
 	logger.Info(fmt.Sprintf("Beginning to evaluate unification for profile: %s", newProfile.ProfileId))
+
+	existingMasterProfiles, err := profileStore.GetAllReferenceProfilesExceptForCurrent(newProfile)
 	if err != nil {
-		logger.Error(fmt.Sprintf("Failed to fetch unification rules for unifying profile: %s",
-			newProfile.ProfileId), log.Error(err))
+		return nil, err
 	}
 
-	// 🔹 Step 2: Fetch all existing profiles from DB
-	existingMasterProfiles, err := profileStore.GetAllReferenceProfilesExceptForCurrent(newProfile)
+	// Profiles that were unmerged before must not be unified again by the same rule
+	unifiedProfileIds := []string{newProfile.ProfileId}
+	if newProfile.ProfileStatus != nil && newProfile.ProfileStatus.IsReferenceProfile {
+		references, err := profileStore.FetchReferencedProfiles(newProfile.ProfileId)
+		if err != nil {
+			return nil, err
+		}
+		for _, reference := range references {
+			unifiedProfileIds = append(unifiedProfileIds, reference.ProfileId)
+		}
+	}
+	unmergeExclusions, err := profileStore.GetUnmergeExclusions(unifiedProfileIds)
 	if err != nil {
-		logger.Error(fmt.Sprintf("Failed to fetch existing master profiles for unification of profile: %s",
-			newProfile.ProfileId), log.Error(err))
+		return nil, err
 	}
 
-	unificationRules = filterActiveRulesAndSortByPriority(unificationRules)
-	// 🔹 Step 3: Loop through unification rules and compare profiles
 	for _, rule := range unificationRules {
-
 		for _, existingMasterProfile := range existingMasterProfiles {
 
-			if existingMasterProfile.ProfileId == newProfile.ProfileStatus.ReferenceProfileId {
-				// Skip if the existing master profile is the parent of the new profile
-				return
+			if newProfile.ProfileStatus != nil &&
+				existingMasterProfile.ProfileId == newProfile.ProfileStatus.ReferenceProfileId {
+				// The profile is already merged to this master
+				return nil, nil
+			}
+			matchedValue, matched := doesProfileMatch(existingMasterProfile, newProfile, rule)
+			if !matched {
+				continue
 			}
+			existingMasterProfile.ProfileStatus.References, err =
+				profileStore.FetchReferencedProfiles(existingMasterProfile.ProfileId)
+			if err != nil {
+				return nil, err
+			}
+			if isUnmergeExcluded(unmergeExclusions, unifiedProfileIds, existingMasterProfile, rule.RuleName) {
+				logger.Info(fmt.Sprintf("Skipping unification of profile: %s with profile: %s by rule: %s as "+
+					"they were unmerged before", newProfile.ProfileId, existingMasterProfile.ProfileId, rule.RuleName))
+				continue
+			}
+			outcome, ok := unificationOutcome(existingMasterProfile, newProfile)
+			if !ok {
+				continue
+			}
+			return &UnificationMerge{
+				Match:        existingMasterProfile,
+				Rule:         rule,
+				MatchedValue: matchedValue,
+				Outcome:      outcome,
+			}, nil
+		}
+	}
+	return nil, nil
+}
 
-			if doesProfileMatch(existingMasterProfile, newProfile, rule) {
-
-				existingMasterProfile.ProfileStatus.References, _ = profileStore.FetchReferencedProfiles(existingMasterProfile.ProfileId)
-
-				//  Merge the existing master to the old master of current
-				schemaRules, _ := schemaStore.GetProfileSchemaAttributesForOrg(newProfile.OrgHandle)
-				newMasterProfile := MergeProfiles(existingMasterProfile, newProfile, schemaRules)
-
-				if len(existingMasterProfile.ProfileStatus.References) == 0 {
-
-					hasUserIDExisting := existingMasterProfile.UserId != ""
-					hasUserIDNew := newProfile.UserId != ""
-
-					// Case 1: perm-temp or temp-perm
-					if hasUserIDExisting != hasUserIDNew {
-						logger.Info(fmt.Sprintf("Stitching Temporray profile: %s to the permnanent profile:%s ",
-							newProfile.ProfileId, existingMasterProfile.ProfileId))
-
-						var newChild profileModel.Reference
-						if hasUserIDExisting {
-							newMasterProfile.ProfileId = existingMasterProfile.ProfileId
-							newMasterProfile.UserId = existingMasterProfile.UserId
-							newChild = profileModel.Reference{
-								ProfileId: newProfile.ProfileId,
-								Reason:    rule.RuleName,
-							}
-						} else {
-							newMasterProfile.ProfileId = newProfile.ProfileId
-							newMasterProfile.UserId = newProfile.UserId
-							newChild = profileModel.Reference{
-								ProfileId: existingMasterProfile.ProfileId,
-								Reason:    rule.RuleName,
-							}
-						}
-
-						children := []profileModel.Reference{newChild}
-
-						err = profileStore.UpdateProfileReferences(newMasterProfile, children)
-						if err != nil {
-							logger.Error(fmt.Sprintf("Failed to add child profiles to the master profile: %s",
-								newProfile.ProfileId), log.Error(err))
-							return
-						}
-
-						// Update ApplicationData
-						for _, appCtx := range newMasterProfile.ApplicationData {
-							err := profileStore.InsertMergedMasterProfileAppData(newMasterProfile.ProfileId, appCtx)
-							if err != nil {
-								logger.Error(fmt.Sprintf("Failed to update app data for master profile: %s while unifying profile: %s",
-									newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
-								return
-							}
-						}
-
-						// Update Traits
-						if newMasterProfile.Traits != nil {
-							err := profileStore.InsertMergedMasterProfileTraitData(newMasterProfile.ProfileId, newMasterProfile.Traits)
-							if err != nil {
-								logger.Error(fmt.Sprintf("Failed to update traits for master profile: %s while unifying profile: %s",
-									newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
-								return
-							}
-						}
-
-						// Update Identity
-						if newMasterProfile.IdentityAttributes != nil {
-							err := profileStore.MergeIdentityDataOfProfiles(newMasterProfile.ProfileId, newMasterProfile.IdentityAttributes)
-							if err != nil {
-								logger.Error(fmt.Sprintf("Failed to update IdentityData for master profile: %s while unifying profile: %s",
-									newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
-								return
-							}
-						}
-						return
-					} else {
-						userId := ""
-						if hasUserIDExisting && hasUserIDNew {
-							if existingMasterProfile.UserId == newProfile.UserId {
-								userId = existingMasterProfile.UserId
-								logger.Info(fmt.Sprintf("Both profiles are permanent profiles. Hence creating a new master profile: %s",
-									newProfile.ProfileId))
-							} else {
-								logger.Info("We are not handling merging two permanent profiles with different userIds")
-								continue
-							}
-						} else {
-							logger.Info(fmt.Sprintf("Both profiles are temporary profiles. Hence creating a new master profile: %s",
-								newProfile.ProfileId))
-						}
-						newMasterProfile.ProfileId = uuid.New().String()
-						newMasterProfile.UserId = userId
-						newMasterProfile.Location = utils.BuildProfileLocation(newMasterProfile.OrgHandle, newMasterProfile.ProfileId)
-						childProfile1 := profileModel.Reference{
-							ProfileId: newProfile.ProfileId,
-							Reason:    rule.RuleName,
-						}
-						childProfile2 := profileModel.Reference{
-							ProfileId: existingMasterProfile.ProfileId,
-							Reason:    rule.RuleName,
-						}
-						newMasterProfile.ProfileStatus = &profileModel.ProfileStatus{
-							IsReferenceProfile: true,
-							ListProfile:        false,
-							References:         []profileModel.Reference{childProfile1, childProfile2},
-						}
-
-						err := profileStore.InsertProfile(newMasterProfile)
-						if err != nil {
-							logger.Error(fmt.Sprintf("Failed to insert master profile while unifying profile: %s",
-								newProfile.ProfileId), log.Error(err))
-							return
-						}
-
-						children := []profileModel.Reference{childProfile1, childProfile2}
-
-						err = profileStore.UpdateProfileReferences(newMasterProfile, children)
-
-						if err != nil {
-							logger.Error(fmt.Sprintf("Failed to add child profiles to the master profile: %s",
-								newMasterProfile.ProfileId), log.Error(err))
-							return
-						}
-						// Update ApplicationData
-						for _, appCtx := range newMasterProfile.ApplicationData {
-							err := profileStore.InsertMergedMasterProfileAppData(newMasterProfile.ProfileId, appCtx)
-							if err != nil {
-								logger.Error(fmt.Sprintf("Failed to update app data for master profile: %s while unifying profile: %s",
-									newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
-								return
-							}
-						}
-
-						// Update Traits
-						if newMasterProfile.Traits != nil {
-							err := profileStore.InsertMergedMasterProfileTraitData(newMasterProfile.ProfileId, newMasterProfile.Traits)
-							if err != nil {
-								logger.Error(fmt.Sprintf("Failed to update traits for master profile: %s while unifying profile: %s",
-									newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
-								return
-							}
-						}
-
-						// Update Identity
-						if newMasterProfile.IdentityAttributes != nil {
-							err := profileStore.MergeIdentityDataOfProfiles(newMasterProfile.ProfileId, newMasterProfile.IdentityAttributes)
-							if err != nil {
-								logger.Error(fmt.Sprintf("Failed to update IdentityData for master profile: %s while unifying profile: %s",
-									newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
-								return
-							}
-						}
-						return
-					}
+// unificationOutcome returns into which profile the matched reference profile and the new profile are unified, and
+// false when they can not be unified.
+func unificationOutcome(existingMasterProfile, newProfile profileModel.Profile) (UnificationOutcome, bool) {
 
-				} else if (len(existingMasterProfile.ProfileStatus.References) > 0) && existingMasterProfile.ProfileStatus.IsReferenceProfile {
-
-					hasUserID_existing := existingMasterProfile.UserId != ""
-					hasUserID_new := newProfile.UserId != ""
-					children := []profileModel.Reference{}
-
-					// Case 1: perm-temp or temp-perm
-					if hasUserID_existing != hasUserID_new {
-						logger.Info(fmt.Sprintf("Stitching Temporray profile: %s to the permnanent profile: %s",
-							newProfile.ProfileId, existingMasterProfile.ProfileId))
-
-						var newChild profileModel.Reference
-						if hasUserID_existing {
-							newMasterProfile.ProfileId = existingMasterProfile.ProfileId
-							newMasterProfile.UserId = existingMasterProfile.UserId
-							newChild = profileModel.Reference{
-								ProfileId: newProfile.ProfileId,
-								Reason:    rule.RuleName,
-							}
-							children = append(children, newChild)
-						} else {
-							newMasterProfile.ProfileId = newProfile.ProfileId
-							newMasterProfile.UserId = newProfile.UserId
-
-							err = profileStore.UpdateProfileReferences(newMasterProfile, existingMasterProfile.ProfileStatus.References)
-
-							if err != nil {
-								logger.Error(fmt.Sprintf("Failed to update profile references for master profile: %s while unifying profile: %s",
-									newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
-								return
-							}
-
-							newChild = profileModel.Reference{
-								ProfileId: existingMasterProfile.ProfileId,
-								Reason:    rule.RuleName,
-							}
-							children = append(children, newChild)
-						}
-
-						err = profileStore.UpdateProfileReferences(newMasterProfile, children)
-						if err != nil {
-							logger.Error(fmt.Sprintf("Failed to add child profiles to the master profile: %s",
-								newProfile.ProfileId), log.Error(err))
-							return
-						}
-
-						// Update ApplicationData
-						for _, appCtx := range newMasterProfile.ApplicationData {
-							err := profileStore.InsertMergedMasterProfileAppData(newMasterProfile.ProfileId, appCtx)
-							if err != nil {
-								logger.Error(fmt.Sprintf("Failed to update app data for master profile: %s while unifying profile: %s",
-									newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
-								return
-							}
-						}
-
-						// Update Traits
-						if newMasterProfile.Traits != nil {
-							err := profileStore.InsertMergedMasterProfileTraitData(newMasterProfile.ProfileId, newMasterProfile.Traits)
-							if err != nil {
-								logger.Error(fmt.Sprintf("Failed to update traits for master profile: %s while unifying profile: %s",
-									newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
-								return
-							}
-						}
-
-						// Update Identity
-						if newMasterProfile.IdentityAttributes != nil {
-							err := profileStore.MergeIdentityDataOfProfiles(newMasterProfile.ProfileId, newMasterProfile.IdentityAttributes)
-							if err != nil {
-								logger.Error(fmt.Sprintf("Failed to update IdentityData for master profile: %s while unifying profile: %s",
-									newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
-								return
-							}
-						}
-						return
-					} else {
-						// Case 2: Both temporary OR both permanent with same user_id
-						// In both cases, merge into existing master (no new master creation)
-
-						if hasUserID_existing && hasUserID_new {
-							// Both permanent profiles
-							if existingMasterProfile.UserId != newProfile.UserId {
-								logger.Info("We are not handling merging two permanent profiles with different userIds")
-								continue
-							}
-							logger.Info(fmt.Sprintf("Both profiles are permanent profiles with same user_id. Merging new profile %s into existing master: %s",
-								newProfile.ProfileId, existingMasterProfile.ProfileId))
-						} else {
-							// Both temporary profiles
-							logger.Info(fmt.Sprintf("Both profiles are temporary profiles. Merging new profile %s into existing master: %s",
-								newProfile.ProfileId, existingMasterProfile.ProfileId))
-						}
-
-						// Use the existing master profile ID and update it with merged data
-						newMasterProfile.ProfileId = existingMasterProfile.ProfileId
-						newMasterProfile.UserId = existingMasterProfile.UserId
-
-						// Add new profile as a child
-						childProfile1 := profileModel.Reference{
-							ProfileId: newProfile.ProfileId,
-							Reason:    rule.RuleName,
-						}
-
-						children := []profileModel.Reference{childProfile1}
-
-						err = profileStore.UpdateProfileReferences(newMasterProfile, children)
-						if err != nil {
-							logger.Error(fmt.Sprintf("Failed to add child profiles to the master profile: %s",
-								newMasterProfile.ProfileId), log.Error(err))
-							return
-						}
-
-						// Update ApplicationData
-						for _, appCtx := range newMasterProfile.ApplicationData {
-							err := profileStore.InsertMergedMasterProfileAppData(newMasterProfile.ProfileId, appCtx)
-							if err != nil {
-								logger.Error(fmt.Sprintf("Failed to update app data for master profile: %s while unifying profile: %s",
-									newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
-								return
-							}
-						}
-
-						// Update Traits
-						if newMasterProfile.Traits != nil {
-							err := profileStore.InsertMergedMasterProfileTraitData(newMasterProfile.ProfileId, newMasterProfile.Traits)
-							if err != nil {
-								logger.Error(fmt.Sprintf("Failed to update traits for master profile: %s while unifying profile: %s",
-									newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
-								return
-							}
-						}
-
-						// Update Identity
-						if newMasterProfile.IdentityAttributes != nil {
-							err := profileStore.MergeIdentityDataOfProfiles(newMasterProfile.ProfileId, newMasterProfile.IdentityAttributes)
-							if err != nil {
-								logger.Error(fmt.Sprintf("Failed to update IdentityData for master profile: %s while unifying profile: %s",
-									newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
-								return
-							}
-						}
-						return
-					}
+	hasReferences := len(existingMasterProfile.ProfileStatus.References) > 0
+	if hasReferences && !existingMasterProfile.ProfileStatus.IsReferenceProfile {
+		return "", false
+	}
+	hasUserIDExisting := existingMasterProfile.UserId != ""
+	hasUserIDNew := newProfile.UserId != ""
 
-				}
-			}
+	// perm-temp or temp-perm: the temporary profile is stitched to the permanent one
+	if hasUserIDExisting != hasUserIDNew {
+		if hasUserIDExisting {
+			return MergedToMatch, true
 		}
+		return MatchMergedToProfile, true
 	}
+	if hasUserIDExisting && existingMasterProfile.UserId != newProfile.UserId {
+		log.GetLogger().Info("We are not handling merging two permanent profiles with different userIds")
+		return "", false
+	}
+	if hasReferences {
+		return MergedToMatch, true
+	}
+	return MergedToNewMaster, true
 }
 
-func filterActiveRulesAndSortByPriority(rules []model.UnificationRule) []model.UnificationRule {
-	activeRules := make([]model.UnificationRule, 0, len(rules))
-	for _, r := range rules {
-		if r.IsActive {
-			activeRules = append(activeRules, r)
+// unifyProfiles unifies profiles based on unification rules
+func unifyProfiles(newProfile profileModel.Profile) {
+
+	logger := log.GetLogger()
+	merge, err := FindUnificationMerge(newProfile)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to evaluate the unification of profile: %s", newProfile.ProfileId),
+			log.Error(err))
+		return
+	}
+	if merge == nil {
+		return
+	}
+	existingMasterProfile, rule := merge.Match, merge.Rule
+	newChild := func(profileId string) profileModel.Reference {
+		return profileModel.Reference{
+			ProfileId:    profileId,
+			Reason:       rule.RuleName,
+			RuleId:       rule.RuleId,
+			MatchedValue: merge.MatchedValue,
+		}
+	}
+
+	//  Merge the existing master to the old master of current
+	schemaRules, _ := schemaStore.GetProfileSchemaAttributesForOrg(newProfile.OrgHandle)
+	newMasterProfile := MergeProfilesByMode(existingMasterProfile, newProfile, schemaRules, rule.MergeMode)
+
+	var children []profileModel.Reference
+	switch merge.Outcome {
+	case MergedToMatch:
+		logger.Info(fmt.Sprintf("Merging profile: %s into the existing master profile: %s",
+			newProfile.ProfileId, existingMasterProfile.ProfileId))
+		newMasterProfile.ProfileId = existingMasterProfile.ProfileId
+		newMasterProfile.UserId = existingMasterProfile.UserId
+		children = []profileModel.Reference{newChild(newProfile.ProfileId)}
+	case MatchMergedToProfile:
+		logger.Info(fmt.Sprintf("Stitching the temporary profile: %s to the permanent profile: %s",
+			existingMasterProfile.ProfileId, newProfile.ProfileId))
+		newMasterProfile.ProfileId = newProfile.ProfileId
+		newMasterProfile.UserId = newProfile.UserId
+		if len(existingMasterProfile.ProfileStatus.References) > 0 {
+			err = profileStore.UpdateProfileReferences(newMasterProfile, existingMasterProfile.ProfileStatus.References)
+			if err != nil {
+				logger.Error(fmt.Sprintf("Failed to update profile references for master profile: %s while "+
+					"unifying profile: %s", newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
+				return
+			}
+		}
+		children = []profileModel.Reference{newChild(existingMasterProfile.ProfileId)}
+	case MergedToNewMaster:
+		logger.Info(fmt.Sprintf("Creating a new master profile for profile: %s and profile: %s",
+			newProfile.ProfileId, existingMasterProfile.ProfileId))
+		newMasterProfileId, err := profileModel.ProfileIdGeneratorFor(
+			config.GetCDSRuntime().Config.ProfileIds.Strategy).NewProfileId()
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to generate the id of a master profile while unifying "+
+				"profile: %s", newProfile.ProfileId), log.Error(err))
+			return
+		}
+		children = []profileModel.Reference{newChild(newProfile.ProfileId), newChild(existingMasterProfile.ProfileId)}
+		newMasterProfile.ProfileId = newMasterProfileId
+		// Both profiles are temporary or belong to the same user, which the new master keeps
+		newMasterProfile.UserId = newProfile.UserId
+		newMasterProfile.Location = utils.BuildProfileLocation(newMasterProfile.OrgHandle, newMasterProfile.ProfileId)
+		newMasterProfile.ProfileStatus = &profileModel.ProfileStatus{
+			IsReferenceProfile: true,
+			ListProfile:        false,
+			References:         children,
+		}
+		if _, err = profileStore.InsertProfile(newMasterProfile); err != nil {
+			logger.Error(fmt.Sprintf("Failed to insert master profile while unifying profile: %s",
+				newProfile.ProfileId), log.Error(err))
+			return
+		}
+	}
+
+	err = profileStore.UpdateProfileReferences(newMasterProfile, children)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to add child profiles to the master profile: %s",
+			newMasterProfile.ProfileId), log.Error(err))
+		return
+	}
+	metrics.ProfileMerges.Inc("unification_rule")
+
+	// Update ApplicationData
+	for _, appCtx := range newMasterProfile.ApplicationData {
+		err := profileStore.InsertMergedMasterProfileAppData(newMasterProfile.ProfileId, appCtx)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Failed to update app data for master profile: %s while unifying profile: %s",
+				newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
+			return
+		}
+	}
+
+	// Update Traits and Identity. The profiles are merged again into the stored master, so that updates made to it
+	// since it was read for the merge are kept.
+	err = WriteMergedData(newMasterProfile.ProfileId, func(stored profileModel.Profile) (profileModel.Profile, error) {
+		switch merge.Outcome {
+		case MergedToMatch:
+			return MergeProfilesByMode(stored, newProfile, schemaRules, rule.MergeMode), nil
+		case MatchMergedToProfile:
+			match, err := profileStore.GetProfile(existingMasterProfile.ProfileId)
+			if err != nil {
+				return profileModel.Profile{}, err
+			}
+			if match == nil {
+				return profileModel.Profile{}, fmt.Errorf("merged profile: %s not found",
+					existingMasterProfile.ProfileId)
+			}
+			return MergeProfilesByMode(*match, stored, schemaRules, rule.MergeMode), nil
 		}
+		return newMasterProfile, nil
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to update traits and identity data for master profile: %s while unifying "+
+			"profile: %s", newMasterProfile.ProfileId, newProfile.ProfileId), log.Error(err))
+		return
 	}
-	sort.Slice(activeRules, func(i, j int) bool {
-		return activeRules[i].Priority < activeRules[j].Priority
+	notifyUnification(newProfile.OrgHandle, newMasterProfile.ProfileId, children, rule.RuleName)
+}
+
+// maxMergeWriteAttempts bounds the writes of the merged data of a master profile that lose to concurrent updates.
+const maxMergeWriteAttempts = 5
+
+// WriteMergedData stores the traits and identity attributes that merge derives from the stored master profile. The
+// master is written at the version it was read at, so a concurrent update in between fails the write with a version
+// conflict, upon which the master is read and merged again. Identity attributes are added to those of the master,
+// and nothing is written when merge returns neither traits nor identity attributes, as for linked profiles.
+func WriteMergedData(masterProfileId string,
+	merge func(stored profileModel.Profile) (profileModel.Profile, error)) error {
+
+	return RetryMergeWrite(masterProfileId, func() error {
+		stored, err := profileStore.GetProfile(masterProfileId)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			return fmt.Errorf("master profile: %s not found", masterProfileId)
+		}
+		merged, err := merge(*stored)
+		if err != nil {
+			return err
+		}
+		if merged.Traits == nil && merged.IdentityAttributes == nil {
+			return nil
+		}
+		return profileStore.UpdateProfile(ApplyMergedData(*stored, merged))
 	})
-	return activeRules
+}
+
+// RetryMergeWrite runs write, which reads a master profile, merges into it and writes it at the version it read,
+// again while it fails with a version conflict, up to maxMergeWriteAttempts times. It is used by unification as
+// well as by the merges requested through the API.
+func RetryMergeWrite(masterProfileId string, write func() error) error {
+
+	for attempt := 1; ; attempt++ {
+		err := write()
+		if !errors.Is(err, profileModel.ErrProfileVersionConflict) || attempt >= maxMergeWriteAttempts {
+			return err
+		}
+		metrics.ProfileVersionConflicts.Inc()
+		log.GetLogger().Debug(fmt.Sprintf("Master profile: %s changed while merging, merging again",
+			masterProfileId), log.Int("attempt", attempt))
+	}
+}
+
+// ApplyMergedData returns the stored master profile with the traits of the merged profile, when it has any, and
+// with the identity attributes of the merged profile added to its own, to be written at the version of the master.
+func ApplyMergedData(stored, merged profileModel.Profile) profileModel.Profile {
+
+	if merged.Traits != nil {
+		stored.Traits = merged.Traits
+	}
+	identityAttributes := make(map[string]interface{}, len(stored.IdentityAttributes)+len(merged.IdentityAttributes))
+	for key, value := range stored.IdentityAttributes {
+		identityAttributes[key] = value
+	}
+	for key, value := range merged.IdentityAttributes {
+		identityAttributes[key] = value
+	}
+	if len(identityAttributes) > 0 {
+		stored.IdentityAttributes = identityAttributes
+	}
+	stored.UpdatedAt = time.Now().UTC()
+	return stored
+}
+
+// notifyUnification notifies the webhooks of the organization and the profile change stream of the profiles merged
+// into the reference profile by the unification rule.
+func notifyUnification(orgHandle, masterProfileId string, children []profileModel.Reference, ruleName string) {
+
+	childProfileIds := make([]string, 0, len(children))
+	for _, child := range children {
+		childProfileIds = append(childProfileIds, child.ProfileId)
+	}
+	webhookService.NotifyProfileMerged(orgHandle, masterProfileId, childProfileIds, ruleName)
+	changestream.PublishProfileChange(constants.ProfileChangeMerged, orgHandle, masterProfileId, childProfileIds)
+}
+
+// isUnmergeExcluded reports whether any of the given profiles was unmerged from the existing reference profile or
+// one of its children under the rule.
+func isUnmergeExcluded(exclusions []profileModel.UnmergeExclusion, profileIds []string,
+	existingProfile profileModel.Profile, ruleName string) bool {
+
+	existingIds := map[string]bool{existingProfile.ProfileId: true}
+	for _, reference := range existingProfile.ProfileStatus.References {
+		existingIds[reference.ProfileId] = true
+	}
+	for _, profileId := range profileIds {
+		for _, exclusion := range exclusions {
+			if exclusion.RuleName != ruleName {
+				continue
+			}
+			if (exclusion.ProfileId == profileId && existingIds[exclusion.ExcludedProfileId]) ||
+				(exclusion.ExcludedProfileId == profileId && existingIds[exclusion.ProfileId]) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // MergeProfiles merges two profiles based on unification rules
@@ -524,6 +522,14 @@ func MergeProfiles(existingProfile profileModel.Profile, incomingProfile profile
 		}
 	}
 
+	// Computed traits are derived from the merged traits rather than merged themselves.
+	computedTraits, err := schemaModel.ApplyComputedTraits(merged.Traits, schemaRules)
+	if err != nil {
+		logger.Warn("Skipping computed traits of the merged profile: "+merged.ProfileId, log.Error(err))
+	} else {
+		merged.Traits = computedTraits
+	}
+
 	// Merge devices by default.
 	merged.ApplicationData = mergeAppData(existingProfile.ApplicationData, incomingProfile.ApplicationData, schemaRules)
 
@@ -541,8 +547,44 @@ func MergeProfiles(existingProfile profileModel.Profile, incomingProfile profile
 	return merged
 }
 
-// doesProfileMatch checks if two profiles have matching attributes based on a unification rule
-func doesProfileMatch(existingProfile profileModel.Profile, newProfile profileModel.Profile, rule model.UnificationRule) bool {
+// MergeProfilesByMode merges the incoming profile into the existing one as the merge mode of a unification rule asks.
+// A link only merge combines no data, so the returned profile carries no traits, identity attributes or application
+// data to be stored on the master.
+func MergeProfilesByMode(existingProfile profileModel.Profile, incomingProfile profileModel.Profile,
+	schemaRules []schemaModel.ProfileSchemaAttribute, mergeMode string) profileModel.Profile {
+
+	switch mergeMode {
+	case constants.MergeModeLinkOnly:
+		linked := existingProfile
+		linked.Traits = nil
+		linked.IdentityAttributes = nil
+		linked.ApplicationData = nil
+		if linked.UserId == "" {
+			linked.UserId = incomingProfile.UserId
+		}
+		linked.OrgHandle = incomingProfile.OrgHandle
+		linked.UpdatedAt = time.Now().UTC()
+		return linked
+	case constants.MergeModeTraitUnion:
+		unionRules := make([]schemaModel.ProfileSchemaAttribute, len(schemaRules))
+		for i, rule := range schemaRules {
+			unionRules[i] = rule
+			if rule.MultiValued {
+				unionRules[i].MergeStrategy = "combine"
+			} else {
+				unionRules[i].MergeStrategy = "ignore"
+			}
+		}
+		return MergeProfiles(existingProfile, incomingProfile, unionRules)
+	default:
+		return MergeProfiles(existingProfile, incomingProfile, schemaRules)
+	}
+}
+
+// doesProfileMatch checks if two profiles have matching attributes based on a unification rule and returns the
+// value they matched on.
+func doesProfileMatch(existingProfile profileModel.Profile, newProfile profileModel.Profile,
+	rule model.UnificationRule) (string, bool) {
 
 	log.GetLogger().Debug(fmt.Sprintf("Checking if profiles match for existing id: %s, new id: %s for the rule: %s",
 		existingProfile.ProfileId, newProfile.ProfileId, rule.RuleName))
@@ -550,23 +592,23 @@ func doesProfileMatch(existingProfile profileModel.Profile, newProfile profileMo
 		if existingProfile.UserId != "" && newProfile.UserId != "" {
 			if existingProfile.UserId == newProfile.UserId {
 				log.GetLogger().Info("Profiles have same user_id. Hence proceeding to merge the profile.")
-				return true
+				return existingProfile.UserId, true
 			}
-			return false
+			return "", false
 		}
-		return false
+		return "", false
 	} else {
 		existingJSON, _ := json.Marshal(existingProfile)
 		newJSON, _ := json.Marshal(newProfile)
 		existingValues := extractFieldFromJSON(existingJSON, rule.PropertyName)
 		newValues := extractFieldFromJSON(newJSON, rule.PropertyName)
 		logger := log.GetLogger()
-		if checkForMatch(existingValues, newValues) {
+		if matchedValue, matched := checkForMatch(existingValues, newValues); matched {
 			logger.Info(fmt.Sprintf("Profiles %s, %s has matched for unification rule: %s ", existingProfile.ProfileId,
 				newProfile.ProfileId, rule.RuleName))
-			return true
+			return matchedValue, true
 		}
-		return false
+		return "", false
 	}
 }
 
@@ -611,8 +653,9 @@ func getNestedJSONField(jsonObj interface{}, fieldPath string) []interface{} {
 	return []interface{}{value} // Wrap a single value in a list
 }
 
-// checkForMatch checks if at least one value from `newProfile` exists in `existingProfile`
-func checkForMatch(existingValues, newValues []interface{}) bool {
+// checkForMatch checks if at least one value from `newProfile` exists in `existingProfile` and returns the first
+// such value.
+func checkForMatch(existingValues, newValues []interface{}) (string, bool) {
 	existingSet := make(map[string]bool)
 	for _, val := range existingValues {
 		if str, ok := val.(string); ok {
@@ -624,11 +667,11 @@ func checkForMatch(existingValues, newValues []interface{}) bool {
 	for _, val := range newValues {
 		if str, ok := val.(string); ok {
 			if existingSet[str] {
-				return true
+				return str, true
 			}
 		}
 	}
-	return false
+	return "", false
 }
 
 func MergeTraitValue(existing interface{}, incoming interface{}, strategy string, valueType string, multiValued bool) interface{} {
@@ -740,6 +783,10 @@ func toIntSlice(value interface{}) []int {
 				result = append(result, int(i))
 			} else if i, ok := item.(int); ok {
 				result = append(result, i)
+			} else if i, ok := item.(json.Number); ok {
+				if n, err := i.Int64(); err == nil {
+					result = append(result, int(n))
+				}
 			}
 		}
 		return result
@@ -747,6 +794,11 @@ func toIntSlice(value interface{}) []int {
 		return []int{v}
 	case float64:
 		return []int{int(v)}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return []int{int(n)}
+		}
+		return []int{}
 	default:
 		return []int{}
 	}
@@ -788,6 +840,10 @@ func toFloatSlice(value interface{}) []float64 {
 				result = append(result, i)
 			case int:
 				result = append(result, float64(i))
+			case json.Number:
+				if f, err := i.Float64(); err == nil {
+					result = append(result, f)
+				}
 			}
 		}
 		return result
@@ -795,6 +851,11 @@ func toFloatSlice(value interface{}) []float64 {
 		return []float64{v}
 	case int:
 		return []float64{float64(v)}
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return []float64{f}
+		}
+		return []float64{}
 	default:
 		return []float64{}
 	}
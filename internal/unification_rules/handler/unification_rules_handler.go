@@ -20,17 +20,21 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	adminConfigService "github.com/wso2/identity-customer-data-service/internal/admin_config/service"
+	profileProvider "github.com/wso2/identity-customer-data-service/internal/profile/provider"
 	"github.com/wso2/identity-customer-data-service/internal/system/constants"
 	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
 	"github.com/wso2/identity-customer-data-service/internal/system/security"
 	"github.com/wso2/identity-customer-data-service/internal/system/utils"
 	"github.com/wso2/identity-customer-data-service/internal/unification_rules/model"
 	"github.com/wso2/identity-customer-data-service/internal/unification_rules/provider"
+	"github.com/wso2/identity-customer-data-service/internal/unification_rules/service"
 
 	"github.com/google/uuid"
 )
@@ -51,7 +55,7 @@ func NewUnificationRulesHandler() *UnificationRulesHandler {
 // AddUnificationRule handles adding a new rule
 func (urh *UnificationRulesHandler) AddUnificationRule(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "unification_rules:create")
+	r, err := security.AuthnAndAuthz(r, "unification_rules:create")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -89,6 +93,7 @@ func (urh *UnificationRulesHandler) AddUnificationRule(w http.ResponseWriter, r
 		PropertyName: ruleInRequest.PropertyName,
 		Priority:     ruleInRequest.Priority,
 		IsActive:     ruleInRequest.IsActive,
+		MergeMode:    ruleInRequest.MergeMode,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
@@ -100,13 +105,14 @@ func (urh *UnificationRulesHandler) AddUnificationRule(w http.ResponseWriter, r
 		utils.HandleError(w, err)
 		return
 	}
-	addedRule, err := ruleService.GetUnificationRule(rule.RuleId)
+	addedRule, err := ruleService.GetUnificationRule(rule.RuleId, orgHandle)
 	addedRuleResponse := model.UnificationRuleAPIResponse{
 		RuleId:       addedRule.RuleId,
 		RuleName:     addedRule.RuleName,
 		PropertyName: addedRule.PropertyName,
 		Priority:     addedRule.Priority,
 		IsActive:     addedRule.IsActive,
+		MergeMode:    addedRule.MergeMode,
 	}
 	if err != nil {
 		utils.HandleError(w, err)
@@ -115,10 +121,139 @@ func (urh *UnificationRulesHandler) AddUnificationRule(w http.ResponseWriter, r
 	utils.RespondJSON(w, http.StatusCreated, addedRuleResponse, constants.UnificationRuleResource)
 }
 
-// GetUnificationRules handles fetching all rules
+// AddUnificationRules handles adding a batch of rules in one request
+func (urh *UnificationRulesHandler) AddUnificationRules(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "unification_rules:create")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+
+	var rulesInRequest []model.UnificationRuleAPIRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rulesInRequest); err != nil {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.BAD_REQUEST.Code,
+			Message:     errors2.BAD_REQUEST.Message,
+			Description: utils.HandleDecodeError(err, "unification rule"),
+		}, http.StatusBadRequest)
+		utils.WriteErrorResponse(w, clientError)
+		return
+	}
+	if len(rulesInRequest) == 0 || len(rulesInRequest) > constants.MaxUnificationRuleBatchSize {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:    errors2.BAD_REQUEST.Code,
+			Message: errors2.BAD_REQUEST.Message,
+			Description: fmt.Sprintf("A unification rule batch must contain between 1 and %d rules.",
+				constants.MaxUnificationRuleBatchSize),
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	now := time.Now().UTC()
+	rules := make([]model.UnificationRule, 0, len(rulesInRequest))
+	for _, ruleInRequest := range rulesInRequest {
+		rules = append(rules, model.UnificationRule{
+			RuleId:       uuid.New().String(),
+			OrgHandle:    orgHandle,
+			RuleName:     ruleInRequest.RuleName,
+			PropertyName: ruleInRequest.PropertyName,
+			Priority:     ruleInRequest.Priority,
+			IsActive:     ruleInRequest.IsActive,
+			MergeMode:    ruleInRequest.MergeMode,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		})
+	}
+
+	ruleProvider := provider.NewUnificationRuleProvider()
+	ruleService := ruleProvider.GetUnificationRuleService()
+	if err = ruleService.AddUnificationRules(rules, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	addedRules := make([]model.UnificationRuleAPIResponse, 0, len(rules))
+	for _, rule := range rules {
+		addedRules = append(addedRules, model.UnificationRuleAPIResponse{
+			RuleId:       rule.RuleId,
+			RuleName:     rule.RuleName,
+			PropertyName: rule.PropertyName,
+			Priority:     rule.Priority,
+			IsActive:     rule.IsActive,
+			MergeMode:    rule.MergeMode,
+		})
+	}
+	utils.RespondJSON(w, http.StatusCreated, addedRules, constants.UnificationRuleResource)
+}
+
+// PreviewUnificationRule handles a dry run of a rule, listing the profiles it would merge without merging them
+func (urh *UnificationRulesHandler) PreviewUnificationRule(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "unification_rules:view")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+
+	var ruleInRequest model.UnificationRuleAPIRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&ruleInRequest); err != nil {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.BAD_REQUEST.Code,
+			Message:     errors2.BAD_REQUEST.Message,
+			Description: utils.HandleDecodeError(err, "unification rule"),
+		}, http.StatusBadRequest)
+		utils.WriteErrorResponse(w, clientError)
+		return
+	}
+
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+	rule := model.UnificationRule{
+		OrgHandle:    orgHandle,
+		RuleName:     ruleInRequest.RuleName,
+		PropertyName: ruleInRequest.PropertyName,
+		Priority:     ruleInRequest.Priority,
+		IsActive:     ruleInRequest.IsActive,
+		MergeMode:    ruleInRequest.MergeMode,
+	}
+
+	ruleProvider := provider.NewUnificationRuleProvider()
+	ruleService := ruleProvider.GetUnificationRuleService()
+	candidates, err := ruleService.PreviewUnificationRule(r.Context(), rule)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, candidates, constants.UnificationRuleResource)
+}
+
+// GetUnificationRules handles fetching all rules, or the rule named by the ruleName query parameter
 func (urh *UnificationRulesHandler) GetUnificationRules(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "unification_rules:view")
+	r, err := security.AuthnAndAuthz(r, "unification_rules:view")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -135,6 +270,22 @@ func (urh *UnificationRulesHandler) GetUnificationRules(w http.ResponseWriter, r
 		utils.HandleError(w, clientError)
 		return
 	}
+	if ruleName := strings.TrimSpace(r.URL.Query().Get(constants.RuleName)); ruleName != "" {
+		rule, err := ruleService.GetUnificationRuleByName(ruleName, orgHandle)
+		if err != nil {
+			utils.HandleError(w, err)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, model.UnificationRuleAPIResponse{
+			RuleId:       rule.RuleId,
+			RuleName:     rule.RuleName,
+			PropertyName: rule.PropertyName,
+			Priority:     rule.Priority,
+			IsActive:     rule.IsActive,
+			MergeMode:    rule.MergeMode,
+		}, constants.UnificationRuleResource)
+		return
+	}
 	rules, err := ruleService.GetUnificationRules(orgHandle)
 	if err != nil {
 		utils.HandleError(w, err)
@@ -149,6 +300,7 @@ func (urh *UnificationRulesHandler) GetUnificationRules(w http.ResponseWriter, r
 			PropertyName: rule.PropertyName,
 			Priority:     rule.Priority,
 			IsActive:     rule.IsActive,
+			MergeMode:    rule.MergeMode,
 		}
 		rulesResponse = append(rulesResponse, tempRule)
 	}
@@ -158,7 +310,7 @@ func (urh *UnificationRulesHandler) GetUnificationRules(w http.ResponseWriter, r
 // GetUnificationRule Fetches a specific resolution rule.
 func (urh *UnificationRulesHandler) GetUnificationRule(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "unification_rules:view")
+	r, err := security.AuthnAndAuthz(r, "unification_rules:view")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -185,7 +337,7 @@ func (urh *UnificationRulesHandler) GetUnificationRule(w http.ResponseWriter, r
 	}
 	ruleProvider := provider.NewUnificationRuleProvider()
 	ruleService := ruleProvider.GetUnificationRuleService()
-	rule, err := ruleService.GetUnificationRule(ruleId)
+	rule, err := ruleService.GetUnificationRule(ruleId, orgHandle)
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -196,6 +348,7 @@ func (urh *UnificationRulesHandler) GetUnificationRule(w http.ResponseWriter, r
 		PropertyName: rule.PropertyName,
 		Priority:     rule.Priority,
 		IsActive:     rule.IsActive,
+		MergeMode:    rule.MergeMode,
 	}
 	utils.RespondJSON(w, http.StatusOK, ruleResponse, constants.UnificationRuleResource)
 }
@@ -203,7 +356,7 @@ func (urh *UnificationRulesHandler) GetUnificationRule(w http.ResponseWriter, r
 // PatchUnificationRule applies partial updates to a unification rule.
 func (urh *UnificationRulesHandler) PatchUnificationRule(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "unification_rules:update")
+	r, err := security.AuthnAndAuthz(r, "unification_rules:update")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -237,7 +390,7 @@ func (urh *UnificationRulesHandler) PatchUnificationRule(w http.ResponseWriter,
 	}
 	ruleProvider := provider.NewUnificationRuleProvider()
 	ruleService := ruleProvider.GetUnificationRuleService()
-	updatedRule, err := ruleService.GetUnificationRule(ruleId)
+	updatedRule, err := ruleService.GetUnificationRule(ruleId, orgHandle)
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -255,13 +408,17 @@ func (urh *UnificationRulesHandler) PatchUnificationRule(w http.ResponseWriter,
 		updatedRule.IsActive = *ruleUpdateRequest.IsActive
 	}
 
+	if ruleUpdateRequest.MergeMode != nil {
+		updatedRule.MergeMode = *ruleUpdateRequest.MergeMode
+	}
+
 	err = ruleService.PatchUnificationRule(ruleId, orgHandle, *updatedRule)
 	if err != nil {
 		utils.HandleError(w, err)
 		return
 	}
 
-	rule, err := ruleService.GetUnificationRule(ruleId)
+	rule, err := ruleService.GetUnificationRule(ruleId, orgHandle)
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -272,14 +429,233 @@ func (urh *UnificationRulesHandler) PatchUnificationRule(w http.ResponseWriter,
 		PropertyName: rule.PropertyName,
 		Priority:     rule.Priority,
 		IsActive:     rule.IsActive,
+		MergeMode:    rule.MergeMode,
 	}
 	utils.RespondJSON(w, http.StatusOK, ruleResponse, constants.UnificationRuleResource)
 }
 
+// ActivateUnificationRule handles activating a rule
+func (urh *UnificationRulesHandler) ActivateUnificationRule(w http.ResponseWriter, r *http.Request) {
+
+	urh.setUnificationRuleStatus(w, r, true)
+}
+
+// DeactivateUnificationRule handles deactivating a rule
+func (urh *UnificationRulesHandler) DeactivateUnificationRule(w http.ResponseWriter, r *http.Request) {
+
+	urh.setUnificationRuleStatus(w, r, false)
+}
+
+func (urh *UnificationRulesHandler) setUnificationRuleStatus(w http.ResponseWriter, r *http.Request, isActive bool) {
+
+	r, err := security.AuthnAndAuthz(r, "unification_rules:update")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	ruleId := r.PathValue("ruleId")
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	ruleProvider := provider.NewUnificationRuleProvider()
+	ruleService := ruleProvider.GetUnificationRuleService()
+	if isActive {
+		err = ruleService.ActivateUnificationRule(ruleId, orgHandle)
+	} else {
+		err = ruleService.DeactivateUnificationRule(ruleId, orgHandle)
+	}
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	rule, err := ruleService.GetUnificationRule(ruleId, orgHandle)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	ruleResponse := model.UnificationRuleAPIResponse{
+		RuleId:       rule.RuleId,
+		RuleName:     rule.RuleName,
+		PropertyName: rule.PropertyName,
+		Priority:     rule.Priority,
+		IsActive:     rule.IsActive,
+		MergeMode:    rule.MergeMode,
+	}
+	utils.RespondJSON(w, http.StatusOK, ruleResponse, constants.UnificationRuleResource)
+}
+
+// SetUnificationRulePriority handles changing only the priority of a rule
+func (urh *UnificationRulesHandler) SetUnificationRulePriority(w http.ResponseWriter, r *http.Request) {
+
+	var request model.UnificationRulePriorityRequest
+	urh.updateUnificationRuleField(w, r, &request, "unification rule priority",
+		func(ruleService service.UnificationRuleServiceInterface, ruleId, orgHandle string) error {
+			return ruleService.SetRulePriority(ruleId, orgHandle, request.Priority)
+		})
+}
+
+// RenameUnificationRule handles changing only the name of a rule
+func (urh *UnificationRulesHandler) RenameUnificationRule(w http.ResponseWriter, r *http.Request) {
+
+	var request model.UnificationRuleNameRequest
+	urh.updateUnificationRuleField(w, r, &request, "unification rule name",
+		func(ruleService service.UnificationRuleServiceInterface, ruleId, orgHandle string) error {
+			return ruleService.RenameRule(ruleId, orgHandle, request.RuleName)
+		})
+}
+
+// SetUnificationRuleProperty handles changing only the property a rule matches profiles by
+func (urh *UnificationRulesHandler) SetUnificationRuleProperty(w http.ResponseWriter, r *http.Request) {
+
+	var request model.UnificationRulePropertyRequest
+	urh.updateUnificationRuleField(w, r, &request, "unification rule property",
+		func(ruleService service.UnificationRuleServiceInterface, ruleId, orgHandle string) error {
+			return ruleService.SetRuleProperty(ruleId, orgHandle, request.PropertyName)
+		})
+}
+
+// updateUnificationRuleField decodes the request body into the given request, applies the update and responds
+// with the updated rule.
+func (urh *UnificationRulesHandler) updateUnificationRuleField(w http.ResponseWriter, r *http.Request,
+	request interface{}, resourceName string,
+	update func(ruleService service.UnificationRuleServiceInterface, ruleId, orgHandle string) error) {
+
+	r, err := security.AuthnAndAuthz(r, "unification_rules:update")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	ruleId := r.PathValue("ruleId")
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(request); err != nil {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.BAD_REQUEST.Code,
+			Message:     errors2.BAD_REQUEST.Message,
+			Description: utils.HandleDecodeError(err, resourceName),
+		}, http.StatusBadRequest)
+		utils.WriteErrorResponse(w, clientError)
+		return
+	}
+
+	ruleService := provider.NewUnificationRuleProvider().GetUnificationRuleService()
+	if err = update(ruleService, ruleId, orgHandle); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	rule, err := ruleService.GetUnificationRule(ruleId, orgHandle)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	ruleResponse := model.UnificationRuleAPIResponse{
+		RuleId:       rule.RuleId,
+		RuleName:     rule.RuleName,
+		PropertyName: rule.PropertyName,
+		Priority:     rule.Priority,
+		IsActive:     rule.IsActive,
+		MergeMode:    rule.MergeMode,
+	}
+	utils.RespondJSON(w, http.StatusOK, ruleResponse, constants.UnificationRuleResource)
+}
+
+// ApplyUnificationRule handles merging the existing profiles that a rule matches
+func (urh *UnificationRulesHandler) ApplyUnificationRule(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "unification_rules:update")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	ruleId := r.PathValue("ruleId")
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	profilesService := profileProvider.NewProfilesProvider().GetProfilesService()
+	mergedCount, err := profilesService.ApplyUnificationRule(ruleId, orgHandle)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	response := model.UnificationRuleApplyResponse{
+		RuleId:             ruleId,
+		MergedProfileCount: mergedCount,
+	}
+	utils.RespondJSON(w, http.StatusOK, response, constants.UnificationRuleResource)
+}
+
+// ReorderUnificationRules reassigns the priorities of all rules in the order given in the request
+func (urh *UnificationRulesHandler) ReorderUnificationRules(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "unification_rules:update")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	orgHandle := utils.ExtractOrgHandleFromPath(r)
+	if !isCDSEnabled(orgHandle) {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.CDS_NOT_ENABLED.Code,
+			Message:     errors2.CDS_NOT_ENABLED.Message,
+			Description: errors2.CDS_NOT_ENABLED.Description,
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return
+	}
+
+	var reorderRequest model.UnificationRuleReorderRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&reorderRequest); err != nil {
+		clientError := errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.BAD_REQUEST.Code,
+			Message:     errors2.BAD_REQUEST.Message,
+			Description: utils.HandleDecodeError(err, "unification rule order"),
+		}, http.StatusBadRequest)
+		utils.WriteErrorResponse(w, clientError)
+		return
+	}
+
+	ruleProvider := provider.NewUnificationRuleProvider()
+	ruleService := ruleProvider.GetUnificationRuleService()
+	if err = ruleService.ReorderUnificationRules(orgHandle, reorderRequest.RuleIds); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // DeleteUnificationRule removes a resolution rule.
 func (urh *UnificationRulesHandler) DeleteUnificationRule(w http.ResponseWriter, r *http.Request) {
 
-	err := security.AuthnAndAuthz(r, "unification_rules:delete")
+	r, err := security.AuthnAndAuthz(r, "unification_rules:delete")
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -301,7 +677,7 @@ func (urh *UnificationRulesHandler) DeleteUnificationRule(w http.ResponseWriter,
 	}
 	ruleProvider := provider.NewUnificationRuleProvider()
 	ruleService := ruleProvider.GetUnificationRuleService()
-	err = ruleService.DeleteUnificationRule(ruleId)
+	err = ruleService.DeleteUnificationRule(ruleId, orgHandle)
 	if err != nil {
 		utils.HandleError(w, err)
 		return
@@ -29,6 +29,23 @@ type UnificationRule struct {
 	PropertyId   string    `json:"property_id" bson:"property_id" binding:"required"`
 	Priority     int       `json:"priority" bson:"priority" binding:"required"`
 	IsActive     bool      `json:"is_active" bson:"is_active" binding:"required"`
+	MergeMode    string    `json:"merge_mode" bson:"merge_mode"`
 	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" bson:"updated_at"`
 }
+
+// SharedPropertyValue is a property value of a profile that is shared with at least one other profile.
+type SharedPropertyValue struct {
+	ProfileId string
+	UserId    string
+	Value     string
+}
+
+// MergeCandidate is a group of profiles that a unification rule would merge together. MasterProfileId is empty
+// when a new master profile would be created for the group.
+type MergeCandidate struct {
+	PropertyValues  []string `json:"property_values"`
+	MasterProfileId string   `json:"master_profile_id,omitempty"`
+	ChildProfileIds []string `json:"child_profile_ids"`
+	ProfileCount    int      `json:"profile_count"`
+}
@@ -23,6 +23,7 @@ type UnificationRuleAPIRequest struct {
 	PropertyName string `json:"property_name" bson:"property_name" binding:"required"`
 	Priority     int    `json:"priority" bson:"priority" binding:"required"`
 	IsActive     bool   `json:"is_active" bson:"is_active" binding:"required"`
+	MergeMode    string `json:"merge_mode,omitempty" bson:"merge_mode"`
 }
 
 type UnificationRuleAPIResponse struct {
@@ -31,10 +32,39 @@ type UnificationRuleAPIResponse struct {
 	PropertyName string `json:"property_name" bson:"property_name" binding:"required"`
 	Priority     int    `json:"priority" bson:"priority" binding:"required"`
 	IsActive     bool   `json:"is_active" bson:"is_active" binding:"required"`
+	MergeMode    string `json:"merge_mode" bson:"merge_mode"`
+}
+
+// UnificationRuleReorderRequest lists the Ids of all the rules of an organization from the highest priority to
+// the lowest.
+type UnificationRuleReorderRequest struct {
+	RuleIds []string `json:"rule_ids" bson:"rule_ids" binding:"required"`
+}
+
+// UnificationRuleApplyResponse reports the number of existing profiles merged by applying a rule.
+type UnificationRuleApplyResponse struct {
+	RuleId             string `json:"rule_id"`
+	MergedProfileCount int    `json:"merged_profile_count"`
+}
+
+// UnificationRulePriorityRequest sets the priority of a rule.
+type UnificationRulePriorityRequest struct {
+	Priority int `json:"priority" bson:"priority" binding:"required"`
+}
+
+// UnificationRuleNameRequest renames a rule.
+type UnificationRuleNameRequest struct {
+	RuleName string `json:"rule_name" bson:"rule_name" binding:"required"`
+}
+
+// UnificationRulePropertyRequest sets the property a rule matches profiles by.
+type UnificationRulePropertyRequest struct {
+	PropertyName string `json:"property_name" bson:"property_name" binding:"required"`
 }
 
 type UnificationRuleUpdateRequest struct {
-	RuleName *string `json:"rule_name" bson:"rule_name"`
-	Priority *int    `json:"priority" bson:"priority"`
-	IsActive *bool   `json:"is_active" bson:"is_active"`
+	RuleName  *string `json:"rule_name" bson:"rule_name"`
+	Priority  *int    `json:"priority" bson:"priority"`
+	IsActive  *bool   `json:"is_active" bson:"is_active"`
+	MergeMode *string `json:"merge_mode" bson:"merge_mode"`
 }
@@ -19,10 +19,14 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"slices"
+	"sort"
 	"strings"
 
+	schemaModel "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
 	"github.com/wso2/identity-customer-data-service/internal/profile_schema/provider"
 	"github.com/wso2/identity-customer-data-service/internal/system/constants"
 	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
@@ -33,10 +37,20 @@ import (
 
 type UnificationRuleServiceInterface interface {
 	AddUnificationRule(rule model.UnificationRule, orgHandle string) error
+	AddUnificationRules(rules []model.UnificationRule, orgHandle string) error
 	GetUnificationRules(orgHandle string) ([]model.UnificationRule, error)
-	GetUnificationRule(ruleId string) (*model.UnificationRule, error)
+	GetActiveUnificationRules(orgHandle string) ([]model.UnificationRule, error)
+	GetUnificationRule(ruleId, orgHandle string) (*model.UnificationRule, error)
+	GetUnificationRuleByName(ruleName, orgHandle string) (*model.UnificationRule, error)
 	PatchUnificationRule(ruleId, orgHandle string, updatedRule model.UnificationRule) error
-	DeleteUnificationRule(ruleId string) error
+	SetRulePriority(ruleId, orgHandle string, priority int) error
+	RenameRule(ruleId, orgHandle, ruleName string) error
+	SetRuleProperty(ruleId, orgHandle, propertyName string) error
+	ReorderUnificationRules(orgHandle string, orderedIds []string) error
+	ActivateUnificationRule(ruleId, orgHandle string) error
+	DeactivateUnificationRule(ruleId, orgHandle string) error
+	DeleteUnificationRule(ruleId, orgHandle string) error
+	PreviewUnificationRule(ctx context.Context, rule model.UnificationRule) ([]model.MergeCandidate, error)
 }
 
 // UnificationRuleService is the default implementation of the UnificationRuleServiceInterface.
@@ -51,7 +65,6 @@ func GetUnificationRuleService() UnificationRuleServiceInterface {
 // AddUnificationRule Adds a new unification rule.
 func (urs *UnificationRuleService) AddUnificationRule(rule model.UnificationRule, orgHandle string) error {
 
-	logger := log.GetLogger()
 	// Need to specifically prevent
 	if rule.PropertyName == "user_id" {
 		return errors2.NewClientError(errors2.ErrorMessage{
@@ -61,8 +74,119 @@ func (urs *UnificationRuleService) AddUnificationRule(rule model.UnificationRule
 		}, http.StatusConflict)
 	}
 
-	if strings.HasPrefix(rule.PropertyName, constants.ApplicationData+".") {
+	schemaAttribute, err := resolveRuleProperty(rule)
+	if err != nil {
+		return err
+	}
+	if rule.MergeMode, err = resolveMergeMode(rule); err != nil {
+		return err
+	}
+
+	// Check if a similar unification rule already exists
+	existingRules, err := store.GetUnificationRules(orgHandle)
+	if err != nil {
+		return err
+	}
+	if err := checkRuleConflicts(rule, existingRules); err != nil {
+		return err
+	}
+	rule.PropertyId = schemaAttribute.AttributeId
+	return store.AddUnificationRule(rule, orgHandle)
+}
+
+// AddUnificationRules adds a batch of unification rules. The whole batch is validated before anything is added,
+// and either all the rules are added or none of them.
+func (urs *UnificationRuleService) AddUnificationRules(rules []model.UnificationRule, orgHandle string) error {
+
+	existingRules, err := store.GetUnificationRules(orgHandle)
+	if err != nil {
+		return err
+	}
+
+	for i, rule := range rules {
+		if rule.PropertyName == "user_id" {
+			return errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.UNIFICATION_RULE_ALREADY_EXISTS.Code,
+				Message:     errors2.UNIFICATION_RULE_ALREADY_EXISTS.Message,
+				Description: fmt.Sprintf("Unification rule with property %s already exists", rule.PropertyName),
+			}, http.StatusConflict)
+		}
+		schemaAttribute, err := resolveRuleProperty(rule)
+		if err != nil {
+			return err
+		}
+		if rules[i].MergeMode, err = resolveMergeMode(rule); err != nil {
+			return err
+		}
+		if err := checkRuleConflicts(rule, existingRules); err != nil {
+			return err
+		}
+		rules[i].PropertyId = schemaAttribute.AttributeId
+		existingRules = append(existingRules, rules[i])
+	}
+	return store.AddUnificationRules(rules)
+}
+
+// checkRuleConflicts rejects a rule that uses the name or the property of another rule, a priority that is not
+// positive, or, when the rule is active, the priority of another active rule.
+func checkRuleConflicts(rule model.UnificationRule, existingRules []model.UnificationRule) error {
+
+	if rule.Priority <= 0 {
 		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UNIFICATION_RULE_INVALID_PRIORITY.Code,
+			Message:     errors2.UNIFICATION_RULE_INVALID_PRIORITY.Message,
+			Description: fmt.Sprintf("Priority of unification rule %s must be a positive integer", rule.RuleName),
+		}, http.StatusBadRequest)
+	}
+	for _, existingRule := range existingRules {
+		if existingRule.RuleId == rule.RuleId {
+			continue
+		}
+		if existingRule.RuleName == rule.RuleName {
+			return store.RuleNameConflictError(rule.RuleName)
+		}
+		if existingRule.PropertyName == rule.PropertyName {
+			return errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.UNIFICATION_RULE_ALREADY_EXISTS.Code,
+				Message:     errors2.UNIFICATION_RULE_ALREADY_EXISTS.Message,
+				Description: fmt.Sprintf("Unification rule with property %s already exists", rule.PropertyName),
+			}, http.StatusConflict)
+		}
+		if rule.IsActive && existingRule.IsActive && existingRule.Priority == rule.Priority {
+			return errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.UNIFICATION_RULE_PRIORITY_EXISTS.Code,
+				Message:     errors2.UNIFICATION_RULE_PRIORITY_EXISTS.Message,
+				Description: "Unification rule with same priority exist.",
+			}, http.StatusBadRequest)
+		}
+	}
+	return nil
+}
+
+// resolveMergeMode returns the merge mode of a rule, which is a full merge when none is given.
+func resolveMergeMode(rule model.UnificationRule) (string, error) {
+
+	switch rule.MergeMode {
+	case "":
+		return constants.MergeModeFull, nil
+	case constants.MergeModeFull, constants.MergeModeLinkOnly, constants.MergeModeTraitUnion:
+		return rule.MergeMode, nil
+	}
+	return "", errors2.NewClientError(errors2.ErrorMessage{
+		Code:    errors2.UNIFICATION_RULE_INVALID_MERGE_MODE.Code,
+		Message: errors2.UNIFICATION_RULE_INVALID_MERGE_MODE.Message,
+		Description: fmt.Sprintf("Merge mode of unification rule %s must be one of %s, %s or %s", rule.RuleName,
+			constants.MergeModeFull, constants.MergeModeLinkOnly, constants.MergeModeTraitUnion),
+	}, http.StatusBadRequest)
+}
+
+// resolveRuleProperty validates that the property of a rule can be used for unification and returns its schema
+// attribute.
+func resolveRuleProperty(rule model.UnificationRule) (*schemaModel.ProfileSchemaAttribute, error) {
+
+	logger := log.GetLogger()
+	if strings.HasPrefix(rule.PropertyName, constants.ApplicationData+".") {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
 			Code:        errors2.ADD_UNIFICATION_RULE.Code,
 			Message:     errors2.ADD_UNIFICATION_RULE.Message,
 			Description: "Creating unification rules based on application data is not supported.",
@@ -80,18 +204,18 @@ func (urs *UnificationRuleService) AddUnificationRule(rule model.UnificationRule
 			Message:     errors2.ADD_UNIFICATION_RULE.Message,
 			Description: errorMsg,
 		}, err)
-		return serverError
+		return nil, serverError
 	}
 
 	if schemaAttribute == nil {
-		return errors2.NewClientError(errors2.ErrorMessage{
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
 			Code:        errors2.ADD_UNIFICATION_RULE.Code,
 			Message:     errors2.ADD_UNIFICATION_RULE.Message,
 			Description: fmt.Sprintf("PropertyName  '%s' is not found in schema", rule.PropertyName),
 		}, http.StatusBadRequest)
 	}
 	if schemaAttribute.ValueType == constants.ComplexDataType {
-		return errors2.NewClientError(errors2.ErrorMessage{
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
 			Code:    errors2.ADD_UNIFICATION_RULE.Code,
 			Message: errors2.ADD_UNIFICATION_RULE.Message,
 			Description: "Unification rule with property " + rule.PropertyName + " is not allowed as it is a complex data type. " +
@@ -99,29 +223,7 @@ func (urs *UnificationRuleService) AddUnificationRule(rule model.UnificationRule
 		}, http.StatusBadRequest)
 	}
 
-	// Check if a similar unification rule already exists
-	existingRules, err := store.GetUnificationRules(orgHandle)
-	if err != nil {
-		return err
-	}
-	for _, existingRule := range existingRules {
-		if existingRule.PropertyName == rule.PropertyName {
-			return errors2.NewClientError(errors2.ErrorMessage{
-				Code:        errors2.UNIFICATION_RULE_ALREADY_EXISTS.Code,
-				Message:     errors2.UNIFICATION_RULE_ALREADY_EXISTS.Message,
-				Description: fmt.Sprintf("Unification rule with property %s already exists", rule.PropertyName),
-			}, http.StatusConflict)
-		}
-		if existingRule.Priority == rule.Priority {
-			return errors2.NewClientError(errors2.ErrorMessage{
-				Code:        errors2.UNIFICATION_RULE_PRIORITY_EXISTS.Code,
-				Message:     errors2.UNIFICATION_RULE_PRIORITY_EXISTS.Message,
-				Description: "Unification rule with same priority exist.",
-			}, http.StatusBadRequest)
-		}
-	}
-	rule.PropertyId = schemaAttribute.AttributeId
-	return store.AddUnificationRule(rule, orgHandle)
+	return schemaAttribute, nil
 }
 
 // GetUnificationRules Fetches all resolution rules.
@@ -129,14 +231,21 @@ func (urs *UnificationRuleService) GetUnificationRules(orgHandle string) ([]mode
 	return store.GetUnificationRules(orgHandle)
 }
 
-// GetUnificationRule Fetches a specific resolution rule.
-func (urs *UnificationRuleService) GetUnificationRule(ruleId string) (*model.UnificationRule, error) {
+// GetActiveUnificationRules Fetches the active unification rules ordered by priority.
+func (urs *UnificationRuleService) GetActiveUnificationRules(orgHandle string) ([]model.UnificationRule, error) {
+
+	return store.GetActiveUnificationRules(orgHandle)
+}
+
+// GetUnificationRule Fetches a specific resolution rule of an organization. Rules of other organizations are
+// reported as not found.
+func (urs *UnificationRuleService) GetUnificationRule(ruleId, orgHandle string) (*model.UnificationRule, error) {
 
 	unificationRule, err := store.GetUnificationRule(ruleId)
 	if err != nil {
 		return nil, err
 	}
-	if unificationRule == nil {
+	if unificationRule == nil || unificationRule.OrgHandle != orgHandle {
 		return nil, errors2.NewClientError(errors2.ErrorMessage{
 			Code:        errors2.UNIFICATION_RULE_NOT_FOUND.Code,
 			Message:     errors2.UNIFICATION_RULE_NOT_FOUND.Message,
@@ -146,6 +255,23 @@ func (urs *UnificationRuleService) GetUnificationRule(ruleId string) (*model.Uni
 	return unificationRule, err
 }
 
+// GetUnificationRuleByName Fetches the unification rule of an organization by its name.
+func (urs *UnificationRuleService) GetUnificationRuleByName(ruleName, orgHandle string) (*model.UnificationRule, error) {
+
+	unificationRule, err := store.GetUnificationRuleByName(orgHandle, ruleName)
+	if err != nil {
+		return nil, err
+	}
+	if unificationRule == nil {
+		return nil, errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UNIFICATION_RULE_NOT_FOUND.Code,
+			Message:     errors2.UNIFICATION_RULE_NOT_FOUND.Message,
+			Description: fmt.Sprintf("Unification rule with name: '%s' not found", ruleName),
+		}, http.StatusNotFound)
+	}
+	return unificationRule, nil
+}
+
 // PatchUnificationRule Applies a partial update on a specific resolution rule.
 func (urs *UnificationRuleService) PatchUnificationRule(ruleId, orgHandle string, updatedRule model.UnificationRule) error {
 
@@ -157,25 +283,268 @@ func (urs *UnificationRuleService) PatchUnificationRule(ruleId, orgHandle string
 		}, http.StatusBadRequest)
 	}
 
-	// Validate that the priority is not already in use
+	if _, err := urs.GetUnificationRule(ruleId, orgHandle); err != nil {
+		return err
+	}
+	// Validate that the name and the priority are not already in use
 	existingRules, err := store.GetUnificationRules(orgHandle)
 	if err != nil {
 		return err
 	}
+	updatedRule.RuleId = ruleId
+	if updatedRule.MergeMode, err = resolveMergeMode(updatedRule); err != nil {
+		return err
+	}
+	if err := checkRuleConflicts(updatedRule, existingRules); err != nil {
+		return err
+	}
+	return store.PatchUnificationRule(ruleId, updatedRule)
+}
+
+// SetRulePriority changes the priority of a unification rule, which must be positive and, for an active rule, not
+// taken by another active rule.
+func (urs *UnificationRuleService) SetRulePriority(ruleId, orgHandle string, priority int) error {
+
+	rule, existingRules, err := urs.getRuleForUpdate(ruleId, orgHandle)
+	if err != nil {
+		return err
+	}
+	rule.Priority = priority
+	if err := checkRuleConflicts(*rule, existingRules); err != nil {
+		return err
+	}
+	return store.SetUnificationRulePriority(ruleId, orgHandle, priority)
+}
+
+// RenameRule changes the name of a unification rule, which must not be empty or taken by another rule.
+func (urs *UnificationRuleService) RenameRule(ruleId, orgHandle, ruleName string) error {
+
+	ruleName = strings.TrimSpace(ruleName)
+	if ruleName == "" {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UNIFICATION_UPDATE_FAILED.Code,
+			Message:     errors2.UNIFICATION_UPDATE_FAILED.Message,
+			Description: "Name of a unification rule can not be empty.",
+		}, http.StatusBadRequest)
+	}
+	rule, existingRules, err := urs.getRuleForUpdate(ruleId, orgHandle)
+	if err != nil {
+		return err
+	}
+	rule.RuleName = ruleName
+	if err := checkRuleConflicts(*rule, existingRules); err != nil {
+		return err
+	}
+	return store.RenameUnificationRule(ruleId, ruleName)
+}
+
+// SetRuleProperty changes the property a unification rule matches profiles by. The property must be usable for
+// unification as when adding a rule, and not used by another rule. Profiles already merged by the rule stay merged.
+func (urs *UnificationRuleService) SetRuleProperty(ruleId, orgHandle, propertyName string) error {
+
+	rule, existingRules, err := urs.getRuleForUpdate(ruleId, orgHandle)
+	if err != nil {
+		return err
+	}
+	if rule.PropertyName == "user_id" || propertyName == "user_id" {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UNIFICATION_UPDATE_FAILED.Code,
+			Message:     errors2.UNIFICATION_UPDATE_FAILED.Message,
+			Description: "Property of the user_id based unification rule can not be changed.",
+		}, http.StatusBadRequest)
+	}
+	rule.PropertyName = propertyName
+	schemaAttribute, err := resolveRuleProperty(*rule)
+	if err != nil {
+		return err
+	}
+	if err := checkRuleConflicts(*rule, existingRules); err != nil {
+		return err
+	}
+	return store.SetUnificationRuleProperty(ruleId, propertyName, schemaAttribute.AttributeId)
+}
+
+// getRuleForUpdate returns the unification rule of the organization along with all of its rules, to check an
+// update of the rule against the others.
+func (urs *UnificationRuleService) getRuleForUpdate(ruleId, orgHandle string) (*model.UnificationRule,
+	[]model.UnificationRule, error) {
+
+	rule, err := urs.GetUnificationRule(ruleId, orgHandle)
+	if err != nil {
+		return nil, nil, err
+	}
+	existingRules, err := store.GetUnificationRules(orgHandle)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rule, existingRules, nil
+}
+
+// ReorderUnificationRules assigns the priorities 1..n to the rules of an organization in the given order. The
+// order must list every rule of the organization exactly once, and the priorities are changed atomically.
+func (urs *UnificationRuleService) ReorderUnificationRules(orgHandle string, orderedIds []string) error {
+
+	existingRules, err := store.GetUnificationRules(orgHandle)
+	if err != nil {
+		return err
+	}
+	pending := make(map[string]bool, len(existingRules))
 	for _, existingRule := range existingRules {
-		if existingRule.RuleId != ruleId && existingRule.Priority == updatedRule.Priority {
+		pending[existingRule.RuleId] = true
+	}
+	for _, ruleId := range orderedIds {
+		if !pending[ruleId] {
 			return errors2.NewClientError(errors2.ErrorMessage{
-				Code:        errors2.UNIFICATION_RULE_PRIORITY_EXISTS.Code,
-				Message:     errors2.UNIFICATION_RULE_PRIORITY_EXISTS.Message,
-				Description: "Unification rule with same priority exist.",
+				Code:        errors2.UNIFICATION_UPDATE_FAILED.Code,
+				Message:     errors2.UNIFICATION_UPDATE_FAILED.Message,
+				Description: fmt.Sprintf("Unification rule: '%s' is unknown or listed more than once", ruleId),
 			}, http.StatusBadRequest)
 		}
+		delete(pending, ruleId)
 	}
-	return store.PatchUnificationRule(ruleId, updatedRule)
+	if len(pending) > 0 {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UNIFICATION_UPDATE_FAILED.Code,
+			Message:     errors2.UNIFICATION_UPDATE_FAILED.Message,
+			Description: fmt.Sprintf("The new order must list all %d unification rules", len(existingRules)),
+		}, http.StatusBadRequest)
+	}
+	return store.ReorderUnificationRules(orgHandle, orderedIds)
+}
+
+// ActivateUnificationRule Activates a unification rule unless its priority is taken by another active rule.
+func (urs *UnificationRuleService) ActivateUnificationRule(ruleId, orgHandle string) error {
+
+	rule, err := urs.GetUnificationRule(ruleId, orgHandle)
+	if err != nil {
+		return err
+	}
+	if rule.IsActive {
+		return nil
+	}
+	existingRules, err := store.GetActiveUnificationRules(orgHandle)
+	if err != nil {
+		return err
+	}
+	rule.IsActive = true
+	if err := checkRuleConflicts(*rule, existingRules); err != nil {
+		return err
+	}
+	return setUnificationRuleStatus(ruleId, true)
+}
+
+// DeactivateUnificationRule Deactivates a unification rule so that it is no longer used to unify profiles.
+func (urs *UnificationRuleService) DeactivateUnificationRule(ruleId, orgHandle string) error {
+
+	if _, err := urs.GetUnificationRule(ruleId, orgHandle); err != nil {
+		return err
+	}
+	return setUnificationRuleStatus(ruleId, false)
+}
+
+func setUnificationRuleStatus(ruleId string, isActive bool) error {
+
+	found, err := store.SetUnificationRuleStatus(ruleId, isActive)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.UNIFICATION_RULE_NOT_FOUND.Code,
+			Message:     errors2.UNIFICATION_RULE_NOT_FOUND.Message,
+			Description: fmt.Sprintf("Unification rule: '%s' not found", ruleId),
+		}, http.StatusNotFound)
+	}
+	return nil
 }
 
 // DeleteUnificationRule Removes a unification rule.
-func (urs *UnificationRuleService) DeleteUnificationRule(ruleId string) error {
+func (urs *UnificationRuleService) DeleteUnificationRule(ruleId, orgHandle string) error {
 
+	if _, err := urs.GetUnificationRule(ruleId, orgHandle); err != nil {
+		return err
+	}
 	return store.DeleteUnificationRule(ruleId)
 }
+
+// PreviewUnificationRule reports the groups of existing profiles that the rule would merge, without merging them.
+// Profiles sharing a value of the rule's property are grouped transitively. The master of a group is the only
+// permanent profile in it, or a new profile would be created when there is none or more than one.
+func (urs *UnificationRuleService) PreviewUnificationRule(ctx context.Context, rule model.UnificationRule) ([]model.MergeCandidate, error) {
+
+	scope, path := "user_id", ""
+	if rule.PropertyName != "user_id" {
+		if _, err := resolveRuleProperty(rule); err != nil {
+			return nil, err
+		}
+		scopeKey := strings.SplitN(rule.PropertyName, ".", 2)
+		scope, path = scopeKey[0], scopeKey[1]
+	}
+
+	sharedValues, err := store.GetProfilesSharingPropertyValue(ctx, rule.OrgHandle, scope, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Union profiles sharing a value so that profiles linked through different values end up in one group.
+	parent := make(map[string]string)
+	var find func(id string) string
+	find = func(id string) string {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+	firstOfValue := make(map[string]string)
+	userIds := make(map[string]string)
+	for _, shared := range sharedValues {
+		if _, ok := parent[shared.ProfileId]; !ok {
+			parent[shared.ProfileId] = shared.ProfileId
+		}
+		userIds[shared.ProfileId] = shared.UserId
+		if first, ok := firstOfValue[shared.Value]; ok {
+			parent[find(shared.ProfileId)] = find(first)
+		} else {
+			firstOfValue[shared.Value] = shared.ProfileId
+		}
+	}
+
+	groups := make(map[string]*model.MergeCandidate)
+	var roots []string
+	for _, shared := range sharedValues {
+		root := find(shared.ProfileId)
+		group, ok := groups[root]
+		if !ok {
+			group = &model.MergeCandidate{}
+			groups[root] = group
+			roots = append(roots, root)
+		}
+		if !slices.Contains(group.PropertyValues, shared.Value) {
+			group.PropertyValues = append(group.PropertyValues, shared.Value)
+		}
+		if !slices.Contains(group.ChildProfileIds, shared.ProfileId) {
+			group.ChildProfileIds = append(group.ChildProfileIds, shared.ProfileId)
+		}
+	}
+
+	candidates := make([]model.MergeCandidate, 0, len(roots))
+	for _, root := range roots {
+		group := groups[root]
+		group.ProfileCount = len(group.ChildProfileIds)
+		var permanent []string
+		for _, profileId := range group.ChildProfileIds {
+			if userIds[profileId] != "" {
+				permanent = append(permanent, profileId)
+			}
+		}
+		if len(permanent) == 1 {
+			group.MasterProfileId = permanent[0]
+			group.ChildProfileIds = slices.DeleteFunc(group.ChildProfileIds, func(id string) bool {
+				return id == permanent[0]
+			})
+		}
+		sort.Strings(group.ChildProfileIds)
+		candidates = append(candidates, *group)
+	}
+	return candidates, nil
+}
@@ -19,10 +19,14 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/wso2/identity-customer-data-service/internal/system/database/client"
 	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
 	"github.com/wso2/identity-customer-data-service/internal/system/database/scripts"
 	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
@@ -50,7 +54,10 @@ func AddUnificationRule(rule model.UnificationRule, orgId string) error {
 	query := scripts.InsertUnificationRule[provider.NewDBProvider().GetDBType()]
 
 	_, err = dbClient.ExecuteQuery(query, rule.RuleId, orgId, rule.RuleName, rule.PropertyName, rule.PropertyId, rule.Priority, rule.IsActive,
-		rule.CreatedAt, rule.UpdatedAt)
+		rule.MergeMode, rule.CreatedAt, rule.UpdatedAt)
+	if client.IsUniqueViolation(err) {
+		return RuleNameConflictError(rule.RuleName)
+	}
 	if err != nil {
 		errorMsg := fmt.Sprintf("Error occurred while adding unification rule: %s", rule.RuleName)
 		logger.Debug(errorMsg, log.Error(err))
@@ -66,10 +73,95 @@ func AddUnificationRule(rule model.UnificationRule, orgId string) error {
 	return nil
 }
 
+// AddUnificationRules adds the given unification rules with a single insert. Either all the rules are added or
+// none of them.
+func AddUnificationRules(rules []model.UnificationRule) error {
+
+	if len(rules) == 0 {
+		return nil
+	}
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := "Failed to get database client for adding a batch of unification rules"
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_UNIFICATION_RULE.Code,
+			Message:     errors2.ADD_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	const columns = 10
+	placeholders := make([]string, 0, len(rules))
+	args := make([]interface{}, 0, len(rules)*columns)
+	for i, rule := range rules {
+		base := i * columns
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9, base+10))
+		args = append(args, rule.RuleId, rule.OrgHandle, rule.RuleName, rule.PropertyName, rule.PropertyId,
+			rule.Priority, rule.IsActive, rule.MergeMode, rule.CreatedAt, rule.UpdatedAt)
+	}
+	query := scripts.InsertUnificationRulesBase[provider.NewDBProvider().GetDBType()] +
+		strings.Join(placeholders, ", ")
+
+	tx, err := dbClient.BeginTx()
+	if err != nil {
+		errorMsg := "Failed to begin transaction for adding a batch of unification rules"
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_UNIFICATION_RULE.Code,
+			Message:     errors2.ADD_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if _, err = tx.Exec(query, args...); err != nil {
+		_ = tx.Rollback()
+		if client.IsUniqueViolation(err) {
+			return errors2.NewClientError(errors2.ErrorMessage{
+				Code:        errors2.UNIFICATION_RULE_NAME_EXISTS.Code,
+				Message:     errors2.UNIFICATION_RULE_NAME_EXISTS.Message,
+				Description: "A unification rule of the batch uses the name of an existing rule",
+			}, http.StatusConflict)
+		}
+		errorMsg := fmt.Sprintf("Error occurred while adding a batch of %d unification rules", len(rules))
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_UNIFICATION_RULE.Code,
+			Message:     errors2.ADD_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if err = tx.Commit(); err != nil {
+		errorMsg := "Failed to commit the batch of unification rules"
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_UNIFICATION_RULE.Code,
+			Message:     errors2.ADD_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	logger.Info(fmt.Sprintf("%d unification rules added successfully", len(rules)))
+	return nil
+}
+
 // GetUnificationRules fetches all unification rules from the database
 func GetUnificationRules(orgHandle string) ([]model.UnificationRule, error) {
 
-	dbClient, err := provider.NewDBProvider().GetDBClient()
+	return fetchUnificationRules(scripts.GetUnificationRules, orgHandle)
+}
+
+// GetActiveUnificationRules fetches the active unification rules of an organization ordered by priority
+func GetActiveUnificationRules(orgHandle string) ([]model.UnificationRule, error) {
+
+	return fetchUnificationRules(scripts.GetActiveUnificationRules, orgHandle)
+}
+
+func fetchUnificationRules(queries map[string]string, orgHandle string) ([]model.UnificationRule, error) {
+
+	dbClient, err := provider.NewDBProvider().GetReadDBClient()
 	logger := log.GetLogger()
 	if err != nil {
 		errorMsg := fmt.Sprintf("Failed to get database client for fetching unification rules for organization: %s", orgHandle)
@@ -83,8 +175,8 @@ func GetUnificationRules(orgHandle string) ([]model.UnificationRule, error) {
 	}
 	defer dbClient.Close()
 
-	query := scripts.GetUnificationRules[provider.NewDBProvider().GetDBType()]
-	results, err := dbClient.ExecuteQuery(query, orgHandle)
+	query := queries[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQueryWithRetry(client.RetryOptions{}, query, orgHandle)
 	if err != nil {
 		errorMsg := fmt.Sprintf("Failed in fetching all unification rules for organization: %s", orgHandle)
 		logger.Debug(errorMsg, log.Error(err))
@@ -96,18 +188,18 @@ func GetUnificationRules(orgHandle string) ([]model.UnificationRule, error) {
 		return nil, serverError
 	}
 
-	var rules []model.UnificationRule
+	rules := make([]model.UnificationRule, 0, len(results))
 	for _, row := range results {
-		var rule model.UnificationRule
-		rule.RuleId = row["rule_id"].(string)
-		rule.RuleName = row["rule_name"].(string)
-		rule.PropertyName = row["property_name"].(string)
-		rule.PropertyId = row["property_id"].(string)
-		rule.Priority = int(row["priority"].(int64))
-		rule.IsActive = row["is_active"].(bool)
-		rule.CreatedAt = row["created_at"].(time.Time)
-		rule.UpdatedAt = row["updated_at"].(time.Time)
-
+		rule, err := scanUnificationRule(row)
+		if err != nil {
+			errorMsg := fmt.Sprintf("Failed to read a unification rule of organization: %s", orgHandle)
+			logger.Debug(errorMsg, log.Error(err))
+			return nil, errors2.NewServerError(errors2.ErrorMessage{
+				Code:        errors2.GET_UNIFICATION_RULE.Code,
+				Message:     errors2.GET_UNIFICATION_RULE.Message,
+				Description: errorMsg,
+			}, err)
+		}
 		rules = append(rules, rule)
 	}
 
@@ -133,7 +225,7 @@ func GetUnificationRule(ruleId string) (*model.UnificationRule, error) {
 	defer dbClient.Close()
 
 	query := scripts.GetUnificationRule[provider.NewDBProvider().GetDBType()]
-	results, err := dbClient.ExecuteQuery(query, ruleId)
+	results, err := dbClient.ExecuteQueryWithRetry(client.RetryOptions{}, query, ruleId)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			logger.Debug(fmt.Sprintf("No unification rule found for rule_id: %s ", ruleId))
@@ -157,11 +249,13 @@ func GetUnificationRule(ruleId string) (*model.UnificationRule, error) {
 	row := results[0]
 	var rule model.UnificationRule
 	rule.RuleId = row["rule_id"].(string)
+	rule.OrgHandle = row["org_handle"].(string)
 	rule.RuleName = row["rule_name"].(string)
 	rule.PropertyName = row["property_name"].(string)
 	rule.PropertyId = row["property_id"].(string)
 	rule.Priority = int(row["priority"].(int64))
 	rule.IsActive = row["is_active"].(bool)
+	rule.MergeMode = row["merge_mode"].(string)
 	rule.CreatedAt = row["created_at"].(time.Time)
 	rule.UpdatedAt = row["updated_at"].(time.Time)
 
@@ -169,6 +263,51 @@ func GetUnificationRule(ruleId string) (*model.UnificationRule, error) {
 	return &rule, nil
 }
 
+// GetUnificationRuleByName fetches the unification rule of an organization by its name
+func GetUnificationRuleByName(orgHandle, ruleName string) (*model.UnificationRule, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get database client for fetching unification rule: %s", ruleName)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_UNIFICATION_RULE.Code,
+			Message:     errors2.GET_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.GetUnificationRuleByName[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQueryWithRetry(client.RetryOptions{}, query, orgHandle, ruleName)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed in fetching unification rule with name: %s", ruleName)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_UNIFICATION_RULE.Code,
+			Message:     errors2.GET_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if len(results) == 0 {
+		logger.Debug(fmt.Sprintf("No unification rule found with name: %s", ruleName))
+		return nil, nil
+	}
+
+	rule, err := scanUnificationRule(results[0])
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to read unification rule with name: %s", ruleName)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.GET_UNIFICATION_RULE.Code,
+			Message:     errors2.GET_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return &rule, nil
+}
+
 // PatchUnificationRule applies partial updates to a unification rule.
 func PatchUnificationRule(ruleId string, updatedRule model.UnificationRule) error {
 
@@ -187,8 +326,11 @@ func PatchUnificationRule(ruleId string, updatedRule model.UnificationRule) erro
 	defer dbClient.Close()
 
 	query := scripts.UpdateUnificationRule[provider.NewDBProvider().GetDBType()]
-	_, err = dbClient.ExecuteQuery(query, updatedRule.RuleName, updatedRule.Priority, updatedRule.IsActive, time.Now().UTC(), ruleId)
-
+	_, err = dbClient.ExecuteQuery(query, updatedRule.RuleName, updatedRule.Priority, updatedRule.IsActive,
+		updatedRule.MergeMode, time.Now().UTC(), ruleId)
+	if client.IsUniqueViolation(err) {
+		return RuleNameConflictError(updatedRule.RuleName)
+	}
 	if err != nil {
 		errorMsg := fmt.Sprintf("Error occurred while updating unification rule for rule_id: %s", ruleId)
 		logger.Debug(errorMsg, log.Error(err))
@@ -204,6 +346,155 @@ func PatchUnificationRule(ruleId string, updatedRule model.UnificationRule) erro
 	return nil
 }
 
+// SetUnificationRulePriority changes only the priority of a unification rule.
+func SetUnificationRulePriority(ruleId, orgHandle string, priority int) error {
+
+	query := scripts.UpdateUnificationRulePriority[provider.NewDBProvider().GetDBType()]
+	return updateUnificationRuleField(ruleId, "priority", query, priority, time.Now().UTC(), ruleId, orgHandle)
+}
+
+// RenameUnificationRule changes only the name of a unification rule.
+func RenameUnificationRule(ruleId, ruleName string) error {
+
+	query := scripts.UpdateUnificationRuleName[provider.NewDBProvider().GetDBType()]
+	err := updateUnificationRuleField(ruleId, "name", query, ruleName, time.Now().UTC(), ruleId)
+	if client.IsUniqueViolation(err) {
+		return RuleNameConflictError(ruleName)
+	}
+	return err
+}
+
+// SetUnificationRuleProperty changes the property that a unification rule matches profiles by.
+func SetUnificationRuleProperty(ruleId, propertyName, propertyId string) error {
+
+	query := scripts.UpdateUnificationRuleProperty[provider.NewDBProvider().GetDBType()]
+	return updateUnificationRuleField(ruleId, "property", query, propertyName, propertyId, time.Now().UTC(), ruleId)
+}
+
+// updateUnificationRuleField runs a query updating a single field of a unification rule.
+func updateUnificationRuleField(ruleId, field, query string, args ...interface{}) error {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get database client for updating the %s of unification rule: %s",
+			field, ruleId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_UNIFICATION_RULE.Code,
+			Message:     errors2.UPDATE_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	if _, err = dbClient.ExecuteQuery(query, args...); err != nil {
+		errorMsg := fmt.Sprintf("Failed to update the %s of unification rule: %s", field, ruleId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_UNIFICATION_RULE.Code,
+			Message:     errors2.UPDATE_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	logger.Info(fmt.Sprintf("Updated the %s of unification rule: %s", field, ruleId))
+	return nil
+}
+
+// SetUnificationRuleStatus activates or deactivates a unification rule. Returns false when the rule does not exist.
+func SetUnificationRuleStatus(ruleId string, isActive bool) (bool, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get database client for updating status of unification rule: %s", ruleId)
+		logger.Debug(errorMsg, log.Error(err))
+		return false, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_UNIFICATION_RULE.Code,
+			Message:     errors2.UPDATE_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.UpdateUnificationRuleStatus[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, isActive, time.Now().UTC(), ruleId)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to update status of unification rule: %s", ruleId)
+		logger.Debug(errorMsg, log.Error(err))
+		return false, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_UNIFICATION_RULE.Code,
+			Message:     errors2.UPDATE_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	logger.Info(fmt.Sprintf("Set is_active of unification rule: %s to %t", ruleId, isActive))
+	return len(results) > 0, nil
+}
+
+// ReorderUnificationRules sets the priority of each rule to its position (starting at 1) in the given order, in
+// a single transaction.
+func ReorderUnificationRules(orgHandle string, orderedIds []string) error {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get database client for reordering unification rules of: %s", orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_UNIFICATION_RULE.Code,
+			Message:     errors2.UPDATE_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	tx, err := dbClient.BeginTx()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to begin transaction for reordering unification rules of: %s", orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_UNIFICATION_RULE.Code,
+			Message:     errors2.UPDATE_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	query := scripts.UpdateUnificationRulePriority[provider.NewDBProvider().GetDBType()]
+	now := time.Now().UTC()
+	for i, ruleId := range orderedIds {
+		result, err := tx.Exec(query, i+1, now, ruleId, orgHandle)
+		if err == nil {
+			var affected int64
+			if affected, err = result.RowsAffected(); err == nil && affected != 1 {
+				err = fmt.Errorf("unification rule %s was not found", ruleId)
+			}
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			errorMsg := fmt.Sprintf("Failed to update the priority of unification rule: %s", ruleId)
+			logger.Debug(errorMsg, log.Error(err))
+			return errors2.NewServerError(errors2.ErrorMessage{
+				Code:        errors2.UPDATE_UNIFICATION_RULE.Code,
+				Message:     errors2.UPDATE_UNIFICATION_RULE.Message,
+				Description: errorMsg,
+			}, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		errorMsg := fmt.Sprintf("Failed to commit the new order of unification rules of: %s", orgHandle)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_UNIFICATION_RULE.Code,
+			Message:     errors2.UPDATE_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	logger.Info(fmt.Sprintf("Successfully reordered %d unification rules of: %s", len(orderedIds), orgHandle))
+	return nil
+}
+
 // DeleteUnificationRule deletes a unification rule by its Id
 func DeleteUnificationRule(ruleId string) error {
 
@@ -236,3 +527,97 @@ func DeleteUnificationRule(ruleId string) error {
 	logger.Info("Successfully deleted unification rule with rule_id: " + ruleId)
 	return nil
 }
+
+// GetProfilesSharingPropertyValue retrieves the values of a property that are shared by more than one reference
+// profile of the organization. The property is given by its scope (traits, identity_attributes or user_id) and the
+// dot separated path within the scope. This is a read only operation that stops once ctx is done.
+func GetProfilesSharingPropertyValue(ctx context.Context, orgHandle, scope, path string) ([]model.SharedPropertyValue, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get database client for previewing unification on: %s.%s", scope, path)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.PREVIEW_UNIFICATION_RULE.Code,
+			Message:     errors2.PREVIEW_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.GetProfilesSharingPropertyValue[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQueryContext(ctx, query, orgHandle, path, scope)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to fetch profiles sharing values of: %s.%s", scope, path)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.PREVIEW_UNIFICATION_RULE.Code,
+			Message:     errors2.PREVIEW_UNIFICATION_RULE.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	values := make([]model.SharedPropertyValue, 0, len(results))
+	for _, row := range results {
+		value := model.SharedPropertyValue{
+			ProfileId: row["profile_id"].(string),
+			Value:     row["value"].(string),
+		}
+		if userId, ok := row["user_id"].(string); ok {
+			value.UserId = userId
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// RuleNameConflictError is returned when a rule is saved with the name of another rule of the organization.
+func RuleNameConflictError(ruleName string) error {
+
+	return errors2.NewClientError(errors2.ErrorMessage{
+		Code:        errors2.UNIFICATION_RULE_NAME_EXISTS.Code,
+		Message:     errors2.UNIFICATION_RULE_NAME_EXISTS.Message,
+		Description: fmt.Sprintf("Unification rule with name %s already exists", ruleName),
+	}, http.StatusConflict)
+}
+
+// scanUnificationRule builds a unification rule from a result row, failing on NULL or unexpected column types.
+func scanUnificationRule(row map[string]interface{}) (model.UnificationRule, error) {
+
+	var rule model.UnificationRule
+	var err error
+	if rule.RuleId, err = client.GetString(row, "rule_id"); err != nil {
+		return rule, err
+	}
+	if rule.OrgHandle, err = client.GetString(row, "org_handle"); err != nil {
+		return rule, err
+	}
+	if rule.RuleName, err = client.GetString(row, "rule_name"); err != nil {
+		return rule, err
+	}
+	if rule.PropertyName, err = client.GetString(row, "property_name"); err != nil {
+		return rule, err
+	}
+	if rule.PropertyId, err = client.GetString(row, "property_id"); err != nil {
+		return rule, err
+	}
+	priority, err := client.GetInt64(row, "priority")
+	if err != nil {
+		return rule, err
+	}
+	rule.Priority = int(priority)
+	if rule.IsActive, err = client.GetBool(row, "is_active"); err != nil {
+		return rule, err
+	}
+	if rule.MergeMode, err = client.GetString(row, "merge_mode"); err != nil {
+		return rule, err
+	}
+	if rule.CreatedAt, err = client.GetTime(row, "created_at"); err != nil {
+		return rule, err
+	}
+	if rule.UpdatedAt, err = client.GetTime(row, "updated_at"); err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
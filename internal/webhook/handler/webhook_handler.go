@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/security"
+	"github.com/wso2/identity-customer-data-service/internal/system/utils"
+	"github.com/wso2/identity-customer-data-service/internal/webhook/model"
+	"github.com/wso2/identity-customer-data-service/internal/webhook/provider"
+)
+
+type WebhookHandler struct{}
+
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{}
+}
+
+// GetWebhooks handles GET /webhooks
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "webhook:view")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	service := provider.NewWebhookProvider().GetWebhookService()
+	webhooks, err := service.GetWebhooks(utils.ExtractOrgHandleFromPath(r))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(webhooks)
+}
+
+// AddWebhook handles POST /webhooks
+func (h *WebhookHandler) AddWebhook(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "webhook:create")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	request, ok := decodeWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	service := provider.NewWebhookProvider().GetWebhookService()
+	webhook, err := service.AddWebhook(utils.ExtractOrgHandleFromPath(r), request)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(webhook)
+}
+
+// GetWebhook handles GET /webhooks/{webhookId}
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "webhook:view")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+
+	service := provider.NewWebhookProvider().GetWebhookService()
+	webhook, err := service.GetWebhook(utils.ExtractOrgHandleFromPath(r), r.PathValue("webhookId"))
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(webhook)
+}
+
+// UpdateWebhook handles PUT /webhooks/{webhookId}
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "webhook:update")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	request, ok := decodeWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	service := provider.NewWebhookProvider().GetWebhookService()
+	webhook, err := service.UpdateWebhook(utils.ExtractOrgHandleFromPath(r), r.PathValue("webhookId"), request)
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(webhook)
+}
+
+// DeleteWebhook handles DELETE /webhooks/{webhookId}
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+
+	r, err := security.AuthnAndAuthz(r, "webhook:delete")
+	if err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+
+	service := provider.NewWebhookProvider().GetWebhookService()
+	if err := service.DeleteWebhook(utils.ExtractOrgHandleFromPath(r), r.PathValue("webhookId")); err != nil {
+		utils.HandleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeWebhookRequest reads the webhook payload, writing a bad request response when it can not be decoded.
+func decodeWebhookRequest(w http.ResponseWriter, r *http.Request) (model.WebhookRequest, bool) {
+
+	var request model.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		clientError := errors.NewClientError(errors.ErrorMessage{
+			Code:        errors.WEBHOOK_VALIDATION.Code,
+			Message:     errors.WEBHOOK_VALIDATION.Message,
+			Description: utils.HandleDecodeError(err, "webhook"),
+		}, http.StatusBadRequest)
+		utils.HandleError(w, clientError)
+		return request, false
+	}
+	return request, true
+}
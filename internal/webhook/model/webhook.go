@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package model
+
+import "time"
+
+// Webhook is an endpoint of an organization that is notified of the profile events it subscribes to.
+type Webhook struct {
+	WebhookId string   `json:"webhook_id"`
+	OrgHandle string   `json:"org_handle"`
+	Url       string   `json:"url"`
+	Events    []string `json:"events"`
+	// Secret signs the deliveries to the webhook. It is only returned when the webhook is created.
+	Secret    string    `json:"secret,omitempty"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookRequest is the payload of adding or updating a webhook. A secret is generated when none is given.
+type WebhookRequest struct {
+	Url      string   `json:"url"`
+	Events   []string `json:"events"`
+	Secret   string   `json:"secret,omitempty"`
+	IsActive *bool    `json:"is_active,omitempty"`
+}
+
+// WebhookEvent is the body posted to the webhooks subscribed to the event type.
+type WebhookEvent struct {
+	EventId    string           `json:"event_id"`
+	EventType  string           `json:"event_type"`
+	OrgHandle  string           `json:"org_handle"`
+	OccurredAt time.Time        `json:"occurred_at"`
+	Data       WebhookEventData `json:"data"`
+}
+
+// WebhookEventData identifies the profiles of the event. Merges carry the reference profile and the profiles
+// merged into it; creations and deletions carry the profile.
+type WebhookEventData struct {
+	ProfileId       string   `json:"profile_id,omitempty"`
+	MasterProfileId string   `json:"master_profile_id,omitempty"`
+	ChildProfileIds []string `json:"child_profile_ids,omitempty"`
+	Reason          string   `json:"reason,omitempty"`
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package provider
+
+import "github.com/wso2/identity-customer-data-service/internal/webhook/service"
+
+// WebhookProviderInterface defines the interface for the webhook provider.
+type WebhookProviderInterface interface {
+	GetWebhookService() service.WebhookServiceInterface
+}
+
+// WebhookProvider is the default implementation of the WebhookProviderInterface.
+type WebhookProvider struct{}
+
+// NewWebhookProvider creates a new instance of WebhookProvider.
+func NewWebhookProvider() WebhookProviderInterface {
+	return &WebhookProvider{}
+}
+
+// GetWebhookService returns the webhook service instance.
+func (wp *WebhookProvider) GetWebhookService() service.WebhookServiceInterface {
+	return service.GetWebhookService()
+}
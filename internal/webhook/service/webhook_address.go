@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+// nonPublicPrefixes are the ranges, besides the loopback, private, link-local and multicast ones the net package
+// knows of, that do not reach the public internet.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which maps onto IPv4 addresses
+}
+
+// isPublicAddress reports whether the address is routable on the public internet, so that a webhook pointing at
+// it cannot reach the services running next to this one.
+func isPublicAddress(addr netip.Addr) bool {
+
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkWebhookHost resolves the host of a webhook url and fails if any of its addresses is not public, unless
+// private networks are allowed.
+func checkWebhookHost(ctx context.Context, host string) error {
+
+	if config.GetCDSRuntime().Config.Webhooks.AllowPrivateNetworks {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, constants.WebhookResolveTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("host %s could not be resolved", host)
+	}
+	for _, addr := range addrs {
+		if !isPublicAddress(addr) {
+			return fmt.Errorf("host %s resolves to the non-public address %s", host, addr.Unmap())
+		}
+	}
+	return nil
+}
+
+// newWebhookClient returns the client that posts the events. The address is checked again when connecting, as the
+// host may resolve differently than when the webhook was registered, and redirects are not followed so that a
+// webhook cannot send the delivery on to an internal address. Each attempt is bounded by the configured delivery
+// timeout instead of a client wide timeout.
+func newWebhookClient() *http.Client {
+
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if config.GetCDSRuntime().Config.Webhooks.AllowPrivateNetworks {
+				return nil
+			}
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddress(addrPort.Addr()) {
+				return fmt.Errorf("webhook address %s is not public", addrPort.Addr().Unmap())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the dialer check the proxy instead of the webhook
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,313 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/system/metrics"
+	"github.com/wso2/identity-customer-data-service/internal/webhook/model"
+	"github.com/wso2/identity-customer-data-service/internal/webhook/store"
+)
+
+var webhookClient = newWebhookClient()
+
+// dispatcher delivers the queued events with a fixed number of workers.
+type dispatcher struct {
+	events  chan model.WebhookEvent
+	workers sync.WaitGroup
+}
+
+// activeDispatcher is started by the first event and cleared by StopDispatcher, after which events are dropped. All
+// access is guarded by dispatcherMu so that no event is queued on a stopped dispatcher.
+var (
+	dispatcherMu      sync.Mutex
+	activeDispatcher  *dispatcher
+	dispatcherStopped bool
+)
+
+// NotifyProfileCreated notifies the webhooks of the organization that a profile was created.
+func NotifyProfileCreated(orgHandle, profileId string) {
+
+	dispatch(newWebhookEvent(constants.WebhookEventProfileCreated, orgHandle,
+		model.WebhookEventData{ProfileId: profileId}))
+}
+
+// NotifyProfileMerged notifies the webhooks of the organization that profiles were merged into a reference profile.
+// The reason is the unification rule that matched them, or the manual merge reason.
+func NotifyProfileMerged(orgHandle, masterProfileId string, childProfileIds []string, reason string) {
+
+	dispatch(newWebhookEvent(constants.WebhookEventProfileMerged, orgHandle, model.WebhookEventData{
+		MasterProfileId: masterProfileId,
+		ChildProfileIds: childProfileIds,
+		Reason:          reason,
+	}))
+}
+
+// NotifyProfileDeleted notifies the webhooks of the organization that a profile was deleted.
+func NotifyProfileDeleted(orgHandle, profileId string) {
+
+	dispatch(newWebhookEvent(constants.WebhookEventProfileDeleted, orgHandle,
+		model.WebhookEventData{ProfileId: profileId}))
+}
+
+// SignWebhookPayload returns the signature sent in the X-CDS-Signature header: the hex encoded HMAC-SHA256 of the
+// X-CDS-Timestamp header value, a dot and the body, keyed with the secret of the webhook.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookEvent(eventType, orgHandle string, data model.WebhookEventData) model.WebhookEvent {
+
+	return model.WebhookEvent{
+		EventId:    uuid.New().String(),
+		EventType:  eventType,
+		OrgHandle:  orgHandle,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
+
+// StopDispatcher stops accepting events and waits for the queued ones to be delivered, until the context is done.
+// Events raised afterwards are dropped.
+func StopDispatcher(ctx context.Context) error {
+
+	dispatcherMu.Lock()
+	d := activeDispatcher
+	activeDispatcher = nil
+	dispatcherStopped = true
+	if d != nil {
+		close(d.events)
+	}
+	dispatcherMu.Unlock()
+	if d == nil {
+		return nil
+	}
+	drained := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("webhook events were still being delivered: %w", ctx.Err())
+	}
+}
+
+// dispatch queues the event for delivery to the subscribed webhooks so that the mutation that raised it is not
+// held up by slow or failing endpoints. It never blocks the caller: the event is dropped when the queue is full.
+func dispatch(event model.WebhookEvent) {
+
+	dispatcherMu.Lock()
+	defer dispatcherMu.Unlock()
+	if dispatcherStopped {
+		return
+	}
+	if activeDispatcher == nil {
+		activeDispatcher = startDispatcher()
+	}
+	select {
+	case activeDispatcher.events <- event:
+	default:
+		metrics.WebhookDeliveries.Inc(event.EventType, "dropped")
+		log.GetLogger().Error(fmt.Sprintf("Webhook event queue is full, dropping %s event: %s of organization: %s",
+			event.EventType, event.EventId, event.OrgHandle))
+	}
+}
+
+// startDispatcher starts the configured number of workers delivering the queued events.
+func startDispatcher() *dispatcher {
+
+	workers := config.GetCDSRuntime().Config.Webhooks.Workers
+	if workers <= 0 {
+		workers = constants.DefaultWebhookWorkers
+	}
+	d := &dispatcher{events: make(chan model.WebhookEvent, constants.WebhookQueueSize)}
+	d.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer d.workers.Done()
+			for event := range d.events {
+				deliverEvent(event)
+			}
+		}()
+	}
+	return d
+}
+
+// deliverEvent delivers the event to each webhook of the organization subscribed to it.
+func deliverEvent(event model.WebhookEvent) {
+
+	logger := log.GetLogger()
+	webhooks, err := store.GetActiveWebhooksForEvent(event.OrgHandle, event.EventType)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to fetch webhooks for event: %s of organization: %s",
+			event.EventId, event.OrgHandle), log.Error(err))
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to serialize webhook event: %s", event.EventId), log.Error(err))
+		return
+	}
+	for _, webhook := range webhooks {
+		deliver(webhook, event, body)
+	}
+}
+
+// deliver posts the event to the webhook, retrying with exponential backoff while the failure is transient. An
+// event that could not be delivered is written to the dead-letter log together with its body so that it can be
+// replayed.
+func deliver(webhook model.Webhook, event model.WebhookEvent, body []byte) {
+
+	settings := deliverySettings()
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		err = post(webhook, event, body, settings.Timeout)
+		if err == nil {
+			metrics.WebhookDeliveries.Inc(event.EventType, "success")
+			return
+		}
+		if attempt >= settings.MaxAttempts || !isRetryableDelivery(err) {
+			break
+		}
+		metrics.WebhookDeliveries.Inc(event.EventType, "retry")
+		time.Sleep(deliveryBackoff(settings, attempt))
+	}
+	metrics.WebhookDeliveries.Inc(event.EventType, "dead_letter")
+	log.GetLogger().Error("Webhook delivery failed permanently, dead-lettering the event",
+		log.String("webhook_id", webhook.WebhookId),
+		log.String("org_handle", event.OrgHandle),
+		log.String("url", webhook.Url),
+		log.String("event_id", event.EventId),
+		log.String("event_type", event.EventType),
+		log.Int("attempts", attempt),
+		log.String("payload", string(body)),
+		log.Error(err))
+}
+
+// deliveryError is a response of a webhook that was not successful.
+type deliveryError struct {
+	status int
+}
+
+func (e *deliveryError) Error() string {
+	return fmt.Sprintf("webhook responded with status %d", e.status)
+}
+
+// post makes a single delivery attempt. Any 2xx response acknowledges the event.
+func post(webhook model.Webhook, event model.WebhookEvent, body []byte, timeout time.Duration) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(constants.WebhookEventHeader, event.EventType)
+	request.Header.Set(constants.WebhookDeliveryHeader, event.EventId)
+	request.Header.Set(constants.WebhookTimestampHeader, timestamp)
+	request.Header.Set(constants.WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	response, err := webhookClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return &deliveryError{status: response.StatusCode}
+	}
+	return nil
+}
+
+// isRetryableDelivery reports whether a failed delivery may succeed later. Connection failures, timeouts, server
+// errors and throttling are retried; other client errors mean the webhook will keep rejecting the event.
+func isRetryableDelivery(err error) bool {
+
+	var deliveryErr *deliveryError
+	if !errors.As(err, &deliveryErr) {
+		return true
+	}
+	return deliveryErr.status >= 500 || deliveryErr.status == http.StatusTooManyRequests ||
+		deliveryErr.status == http.StatusRequestTimeout
+}
+
+// deliverySettings returns the configured delivery settings, falling back to the defaults for the settings that
+// are not configured.
+func deliverySettings() config.WebhookConfig {
+
+	settings := config.GetCDSRuntime().Config.Webhooks
+	if settings.MaxAttempts <= 0 {
+		settings.MaxAttempts = constants.DefaultWebhookMaxAttempts
+	}
+	if settings.InitialBackoff <= 0 {
+		settings.InitialBackoff = constants.DefaultWebhookInitialBackoff
+	}
+	if settings.MaxBackoff <= 0 {
+		settings.MaxBackoff = constants.DefaultWebhookMaxBackoff
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = constants.DefaultWebhookTimeout
+	}
+	return settings
+}
+
+// deliveryBackoff returns how long to wait before the given retry (starting at 1), doubling from the initial
+// backoff up to the maximum.
+func deliveryBackoff(settings config.WebhookConfig, retry int) time.Duration {
+
+	backoff := settings.InitialBackoff
+	for i := 1; i < retry && backoff < settings.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > settings.MaxBackoff {
+		backoff = settings.MaxBackoff
+	}
+	return backoff
+}
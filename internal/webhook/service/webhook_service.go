@@ -0,0 +1,228 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/webhook/model"
+	"github.com/wso2/identity-customer-data-service/internal/webhook/store"
+)
+
+// supportedWebhookEvents are the profile events a webhook can subscribe to.
+var supportedWebhookEvents = map[string]bool{
+	constants.WebhookEventProfileCreated: true,
+	constants.WebhookEventProfileMerged:  true,
+	constants.WebhookEventProfileDeleted: true,
+}
+
+// WebhookServiceInterface defines the service interface.
+type WebhookServiceInterface interface {
+	GetWebhooks(orgHandle string) ([]model.Webhook, error)
+	GetWebhook(orgHandle, webhookId string) (*model.Webhook, error)
+	AddWebhook(orgHandle string, request model.WebhookRequest) (*model.Webhook, error)
+	UpdateWebhook(orgHandle, webhookId string, request model.WebhookRequest) (*model.Webhook, error)
+	DeleteWebhook(orgHandle, webhookId string) error
+}
+
+// WebhookService is the default implementation.
+type WebhookService struct{}
+
+// GetWebhookService returns a new instance.
+func GetWebhookService() WebhookServiceInterface {
+	return &WebhookService{}
+}
+
+// GetWebhooks retrieves the webhooks of the organization without their secrets.
+func (ws *WebhookService) GetWebhooks(orgHandle string) ([]model.Webhook, error) {
+
+	webhooks, err := store.GetWebhooks(orgHandle)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+// GetWebhook retrieves a webhook of the organization without its secret.
+func (ws *WebhookService) GetWebhook(orgHandle, webhookId string) (*model.Webhook, error) {
+
+	webhook, err := store.GetWebhook(orgHandle, webhookId)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, webhookNotFoundError(webhookId)
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// AddWebhook registers a webhook of the organization. A secret is generated unless one is given, and it is only
+// returned in the response of this call.
+func (ws *WebhookService) AddWebhook(orgHandle string, request model.WebhookRequest) (*model.Webhook, error) {
+
+	if err := validateWebhookRequest(request); err != nil {
+		return nil, err
+	}
+	webhook := model.Webhook{
+		WebhookId: uuid.New().String(),
+		OrgHandle: orgHandle,
+		Url:       request.Url,
+		Events:    uniqueEvents(request.Events),
+		Secret:    request.Secret,
+		IsActive:  request.IsActive == nil || *request.IsActive,
+		CreatedAt: time.Now().UTC(),
+	}
+	if webhook.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return nil, errors2.NewServerError(errors2.ErrorMessage{
+				Code:        errors2.ADD_WEBHOOK.Code,
+				Message:     errors2.ADD_WEBHOOK.Message,
+				Description: "Failed to generate the webhook secret.",
+			}, err)
+		}
+		webhook.Secret = secret
+	}
+	if err := store.AddWebhook(webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// UpdateWebhook replaces the url, events and state of a webhook. The secret is kept unless a new one is given.
+func (ws *WebhookService) UpdateWebhook(orgHandle, webhookId string, request model.WebhookRequest) (*model.Webhook,
+	error) {
+
+	if err := validateWebhookRequest(request); err != nil {
+		return nil, err
+	}
+	webhook, err := store.GetWebhook(orgHandle, webhookId)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, webhookNotFoundError(webhookId)
+	}
+	webhook.Url = request.Url
+	webhook.Events = uniqueEvents(request.Events)
+	if request.Secret != "" {
+		webhook.Secret = request.Secret
+	}
+	if request.IsActive != nil {
+		webhook.IsActive = *request.IsActive
+	}
+	updated, err := store.UpdateWebhook(*webhook)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, webhookNotFoundError(webhookId)
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook of the organization.
+func (ws *WebhookService) DeleteWebhook(orgHandle, webhookId string) error {
+
+	deleted, err := store.DeleteWebhook(orgHandle, webhookId)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return webhookNotFoundError(webhookId)
+	}
+	return nil
+}
+
+// validateWebhookRequest checks that the webhook has an absolute http(s) url of a public host and subscribes to
+// supported events.
+func validateWebhookRequest(request model.WebhookRequest) error {
+
+	invalid := func(description string) error {
+		return errors2.NewClientError(errors2.ErrorMessage{
+			Code:        errors2.WEBHOOK_VALIDATION.Code,
+			Message:     errors2.WEBHOOK_VALIDATION.Message,
+			Description: description,
+		}, http.StatusBadRequest)
+	}
+	parsed, err := url.Parse(request.Url)
+	if request.Url == "" || err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return invalid("url must be an absolute http or https URL.")
+	}
+	if err := checkWebhookHost(context.Background(), parsed.Hostname()); err != nil {
+		return invalid(fmt.Sprintf("url must point at a public address: %s.", err.Error()))
+	}
+	if len(request.Events) == 0 {
+		return invalid("At least one event is required.")
+	}
+	for _, event := range request.Events {
+		if !supportedWebhookEvents[event] {
+			return invalid(fmt.Sprintf("Unsupported event: %s. Supported events are %s, %s and %s.", event,
+				constants.WebhookEventProfileCreated, constants.WebhookEventProfileMerged,
+				constants.WebhookEventProfileDeleted))
+		}
+	}
+	return nil
+}
+
+// uniqueEvents drops repeated events, keeping the order they were given in.
+func uniqueEvents(events []string) []string {
+
+	seen := make(map[string]bool, len(events))
+	unique := make([]string, 0, len(events))
+	for _, event := range events {
+		if !seen[event] {
+			seen[event] = true
+			unique = append(unique, event)
+		}
+	}
+	return unique
+}
+
+func generateWebhookSecret() (string, error) {
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+func webhookNotFoundError(webhookId string) error {
+
+	return errors2.NewClientError(errors2.ErrorMessage{
+		Code:        errors2.WEBHOOK_NOT_FOUND.Code,
+		Message:     errors2.WEBHOOK_NOT_FOUND.Message,
+		Description: fmt.Sprintf("Webhook not found for the provided webhookId: %s", webhookId),
+	}, http.StatusNotFound)
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package store
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/client"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/scripts"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/log"
+	"github.com/wso2/identity-customer-data-service/internal/webhook/model"
+)
+
+// AddWebhook inserts a new webhook into the database.
+func AddWebhook(webhook model.Webhook) error {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for inserting webhook: %s", webhook.WebhookId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_WEBHOOK.Code,
+			Message:     errors2.ADD_WEBHOOK.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.InsertWebhook[provider.NewDBProvider().GetDBType()]
+	_, err = dbClient.ExecuteQuery(query, webhook.WebhookId, webhook.OrgHandle, webhook.Url, pq.Array(webhook.Events),
+		webhook.Secret, webhook.IsActive, webhook.CreatedAt)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to insert webhook: %s", webhook.WebhookId)
+		logger.Debug(errorMsg, log.Error(err))
+		return errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.ADD_WEBHOOK.Code,
+			Message:     errors2.ADD_WEBHOOK.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	logger.Info(fmt.Sprintf("Successfully inserted webhook: %s", webhook.WebhookId))
+	return nil
+}
+
+// GetWebhooks retrieves the webhooks of the organization in the order they were added.
+func GetWebhooks(orgHandle string) ([]model.Webhook, error) {
+
+	return fetchWebhooks(scripts.GetWebhooks, fmt.Sprintf("webhooks of organization: %s", orgHandle), orgHandle)
+}
+
+// GetActiveWebhooksForEvent retrieves the active webhooks of the organization subscribed to the event type.
+func GetActiveWebhooksForEvent(orgHandle, eventType string) ([]model.Webhook, error) {
+
+	return fetchWebhooks(scripts.GetActiveWebhooksForEvent,
+		fmt.Sprintf("webhooks of organization: %s subscribed to: %s", orgHandle, eventType), orgHandle, eventType)
+}
+
+// GetWebhook retrieves a webhook of the organization. Returns nil if the webhook does not exist.
+func GetWebhook(orgHandle, webhookId string) (*model.Webhook, error) {
+
+	webhooks, err := fetchWebhooks(scripts.GetWebhook, fmt.Sprintf("webhook: %s", webhookId), orgHandle, webhookId)
+	if err != nil || len(webhooks) == 0 {
+		return nil, err
+	}
+	return &webhooks[0], nil
+}
+
+// UpdateWebhook replaces the url, events, secret and state of a webhook. Returns false if the webhook does not exist.
+func UpdateWebhook(webhook model.Webhook) (bool, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for updating webhook: %s", webhook.WebhookId)
+		logger.Debug(errorMsg, log.Error(err))
+		return false, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_WEBHOOK.Code,
+			Message:     errors2.UPDATE_WEBHOOK.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.UpdateWebhook[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, webhook.OrgHandle, webhook.WebhookId, webhook.Url,
+		pq.Array(webhook.Events), webhook.Secret, webhook.IsActive)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to update webhook: %s", webhook.WebhookId)
+		logger.Debug(errorMsg, log.Error(err))
+		return false, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.UPDATE_WEBHOOK.Code,
+			Message:     errors2.UPDATE_WEBHOOK.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	return len(results) > 0, nil
+}
+
+// DeleteWebhook deletes a webhook of the organization. Returns false if the webhook does not exist.
+func DeleteWebhook(orgHandle, webhookId string) (bool, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for deleting webhook: %s", webhookId)
+		logger.Debug(errorMsg, log.Error(err))
+		return false, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_WEBHOOK.Code,
+			Message:     errors2.DELETE_WEBHOOK.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	query := scripts.DeleteWebhook[provider.NewDBProvider().GetDBType()]
+	results, err := dbClient.ExecuteQuery(query, orgHandle, webhookId)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to delete webhook: %s", webhookId)
+		logger.Debug(errorMsg, log.Error(err))
+		return false, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.DELETE_WEBHOOK.Code,
+			Message:     errors2.DELETE_WEBHOOK.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	if len(results) > 0 {
+		logger.Info("Successfully deleted webhook with webhook_id: " + webhookId)
+	}
+	return len(results) > 0, nil
+}
+
+// fetchWebhooks runs a query selecting webhooks. The subject describes the webhooks in error messages.
+func fetchWebhooks(queries map[string]string, subject string, args ...interface{}) ([]model.Webhook, error) {
+
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	logger := log.GetLogger()
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to get db client for fetching %s", subject)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.FETCH_WEBHOOKS.Code,
+			Message:     errors2.FETCH_WEBHOOKS.Message,
+			Description: errorMsg,
+		}, err)
+	}
+	defer dbClient.Close()
+
+	results, err := dbClient.ExecuteQuery(queries[provider.NewDBProvider().GetDBType()], args...)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Failed to fetch %s", subject)
+		logger.Debug(errorMsg, log.Error(err))
+		return nil, errors2.NewServerError(errors2.ErrorMessage{
+			Code:        errors2.FETCH_WEBHOOKS.Code,
+			Message:     errors2.FETCH_WEBHOOKS.Message,
+			Description: errorMsg,
+		}, err)
+	}
+
+	webhooks := make([]model.Webhook, 0, len(results))
+	for _, row := range results {
+		webhook, err := scanWebhook(row)
+		if err != nil {
+			errorMsg := fmt.Sprintf("Failed to read %s", subject)
+			logger.Debug(errorMsg, log.Error(err))
+			return nil, errors2.NewServerError(errors2.ErrorMessage{
+				Code:        errors2.FETCH_WEBHOOKS.Code,
+				Message:     errors2.FETCH_WEBHOOKS.Message,
+				Description: errorMsg,
+			}, err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+// scanWebhook builds a webhook from a result row, failing on NULL or unexpected column types.
+func scanWebhook(row map[string]interface{}) (model.Webhook, error) {
+
+	var webhook model.Webhook
+	var err error
+	if webhook.WebhookId, err = client.GetString(row, "webhook_id"); err != nil {
+		return webhook, err
+	}
+	if webhook.OrgHandle, err = client.GetString(row, "org_handle"); err != nil {
+		return webhook, err
+	}
+	if webhook.Url, err = client.GetString(row, "url"); err != nil {
+		return webhook, err
+	}
+	var events pq.StringArray
+	if err = events.Scan(row["events"]); err != nil {
+		return webhook, fmt.Errorf("column events does not hold a text array: %w", err)
+	}
+	webhook.Events = events
+	if webhook.Secret, err = client.GetString(row, "secret"); err != nil {
+		return webhook, err
+	}
+	if webhook.IsActive, err = client.GetBool(row, "is_active"); err != nil {
+		return webhook, err
+	}
+	if webhook.CreatedAt, err = client.GetTime(row, "created_at"); err != nil {
+		return webhook, err
+	}
+	return webhook, nil
+}
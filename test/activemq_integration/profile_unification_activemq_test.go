@@ -108,9 +108,9 @@ func Test_ActiveMQ_ProfileUnification_EmailBased(t *testing.T) {
 	time.Sleep(5 * time.Second)
 
 	// ── Assertions ───────────────────────────────────────────────────────────
-	merged1, err := profileSvc.GetProfile(p1.ProfileId)
+	merged1, err := profileSvc.GetProfile(p1.ProfileId, profileModel.AllApplications)
 	require.NoError(t, err)
-	merged2, err := profileSvc.GetProfile(p2.ProfileId)
+	merged2, err := profileSvc.GetProfile(p2.ProfileId, profileModel.AllApplications)
 	require.NoError(t, err)
 
 	// Both profiles should point to the same master profile.
@@ -120,7 +120,7 @@ func Test_ActiveMQ_ProfileUnification_EmailBased(t *testing.T) {
 		"both profiles should be unified into the same master profile")
 
 	// The master profile should carry the combined interests.
-	master, err := profileSvc.GetProfile(merged1.MergedTo.ProfileId)
+	master, err := profileSvc.GetProfile(merged1.MergedTo.ProfileId, profileModel.AllApplications)
 	require.NoError(t, err)
 	interests, ok := master.Traits["interests"].([]interface{})
 	require.True(t, ok, "interests should be a slice")
@@ -129,8 +129,8 @@ func Test_ActiveMQ_ProfileUnification_EmailBased(t *testing.T) {
 
 	// ── Cleanup ───────────────────────────────────────────────────────────────
 	t.Cleanup(func() {
-		_ = unificationSvc.DeleteUnificationRule(emailRule.RuleId)
-		profiles, _, _ := profileSvc.GetAllProfilesCursor(orgHandle, 20, nil)
+		_ = unificationSvc.DeleteUnificationRule(emailRule.RuleId, orgHandle)
+		profiles, _, _ := profileSvc.GetAllProfilesCursor(orgHandle, false, 20, nil, profileModel.AllApplications)
 		for _, p := range profiles {
 			_ = profileSvc.DeleteProfile(p.ProfileId)
 		}
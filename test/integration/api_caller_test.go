@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"context"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	adminConfigModel "github.com/wso2/identity-customer-data-service/internal/admin_config/model"
+	adminConfigStore "github.com/wso2/identity-customer-data-service/internal/admin_config/store"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+// apiCaller calls the HTTP handlers of an organization with CDS enabled, the way the tenant router would, with a
+// token granting the scopes of the given operations. The token is introspected against a local identity server.
+type apiCaller struct {
+	orgHandle string
+	token     string
+}
+
+func newAPICaller(t *testing.T, orgHandle string, operations ...string) *apiCaller {
+
+	identityServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"active": true}`))
+	}))
+	t.Cleanup(identityServer.Close)
+	certDir := t.TempDir()
+	trustStore := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: identityServer.Certificate().Raw})
+	require.NoError(t, os.WriteFile(filepath.Join(certDir, "ca.pem"), trustStore, 0o600))
+	host, port, err := net.SplitHostPort(identityServer.Listener.Addr().String())
+	require.NoError(t, err)
+
+	original := config.GetCDSRuntime().Config
+	conf := original
+	conf.AuthServer.Host = host
+	conf.AuthServer.Port = port
+	conf.AuthServer.IntrospectionEndPoint = "/oauth2/introspect"
+	conf.AuthServer.JWKSEndpoint = ""
+	conf.AuthServer.IsSystemAppGrantEnabled = false
+	conf.AuthServer.RequiredScopes = map[string][]string{}
+	for _, operation := range operations {
+		conf.AuthServer.RequiredScopes[operation] = []string{operation}
+	}
+	conf.TLS.CertDir = certDir
+	conf.TLS.TrustStore = "ca.pem"
+	conf.TLS.MTLSEnabled = false
+	config.OverrideCDSRuntime(conf)
+	t.Cleanup(func() { config.OverrideCDSRuntime(original) })
+
+	require.NoError(t, adminConfigStore.UpdateAdminConfig(adminConfigModel.AdminConfig{CDSEnabled: true}, orgHandle))
+
+	return &apiCaller{orgHandle: orgHandle, token: apiToken(t, orgHandle, "", operations)}
+}
+
+// newAppAPICaller is newAPICaller for a caller whose token was issued to the given application.
+func newAppAPICaller(t *testing.T, orgHandle, appId string, operations ...string) *apiCaller {
+
+	caller := newAPICaller(t, orgHandle, operations...)
+	caller.token = apiToken(t, orgHandle, appId, operations)
+	return caller
+}
+
+// apiToken returns an unsigned token of the organization granting the scopes of the operations, issued to the
+// application unless appId is empty.
+func apiToken(t *testing.T, orgHandle, appId string, operations []string) string {
+
+	claims := jwt.MapClaims{
+		constants.OrgHandleClaim: orgHandle,
+		constants.AudienceClaim:  "iam-cds",
+		constants.ExpiryClaim:    time.Now().Add(time.Hour).Unix(),
+		constants.ScopeClaim:     strings.Join(operations, " "),
+	}
+	if appId != "" {
+		claims[constants.AZPClaim] = appId
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	return token
+}
+
+// serve runs handler for the request, writing the response to w.
+func (c *apiCaller) serve(w http.ResponseWriter, r *http.Request, handler http.HandlerFunc) {
+
+	r.Header.Set("Authorization", "Bearer "+c.token)
+	handler(w, r.WithContext(context.WithValue(r.Context(), constants.TenantContextKey, c.orgHandle)))
+}
+
+// call runs handler for the request and returns the recorded response.
+func (c *apiCaller) call(r *http.Request, handler http.HandlerFunc) *httptest.ResponseRecorder {
+
+	recorder := httptest.NewRecorder()
+	c.serve(recorder, r, handler)
+	return recorder
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/wso2/identity-customer-data-service/internal/system/authn"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/security"
+)
+
+func Test_JWT_Verification_With_JWKS(t *testing.T) {
+
+	encode := func(value *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(value.Bytes())
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	jwks := fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa-key", "use": "sig", "n": %q, "e": %q},
+		{"kty": "EC", "kid": "ec-key", "crv": "P-256", "x": %q, "y": %q},
+		{"kty": "RSA", "kid": "encryption-key", "use": "enc", "n": %q, "e": %q}
+	]}`, encode(rsaKey.N), encode(big.NewInt(int64(rsaKey.E))), encode(ecKey.X), encode(ecKey.Y),
+		encode(rsaKey.N), encode(big.NewInt(int64(rsaKey.E))))
+
+	keys, err := authn.ParseJWKS([]byte(jwks))
+	require.NoError(t, err)
+	require.Len(t, keys, 2, "Encryption keys are not used to verify tokens")
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, expiresAt time.Time) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{
+			"sub":        "alice",
+			"azp":        "console",
+			"org_handle": "carbon.super",
+			"exp":        expiresAt.Unix(),
+		})
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+	valid := time.Now().Add(time.Hour)
+
+	t.Run("Tokens_signed_with_a_published_key_are_accepted", func(t *testing.T) {
+		claims, err := authn.VerifyJWT(sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, valid), keys)
+		require.NoError(t, err)
+		require.Equal(t, "alice", claims["sub"])
+
+		_, err = authn.VerifyJWT(sign(jwt.SigningMethodES256, "ec-key", ecKey, valid), keys)
+		require.NoError(t, err)
+	})
+
+	t.Run("Forged_and_expired_tokens_are_rejected", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		_, err = authn.VerifyJWT(sign(jwt.SigningMethodRS256, "rsa-key", otherKey, valid), keys)
+		require.Error(t, err)
+
+		_, err = authn.VerifyJWT(sign(jwt.SigningMethodRS256, "unknown-key", rsaKey, valid), keys)
+		require.Error(t, err)
+
+		_, err = authn.VerifyJWT(sign(jwt.SigningMethodRS256, "rsa-key", rsaKey, time.Now().Add(-time.Minute)), keys)
+		require.Error(t, err)
+
+		unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": "alice"}).
+			SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+		_, err = authn.VerifyJWT(unsigned, keys)
+		require.Error(t, err)
+	})
+}
+
+func Test_JWKS_Fetch(t *testing.T) {
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwks := fmt.Sprintf(`{"keys": [{"kty": "RSA", "kid": "rsa-key", "n": %q, "e": %q}]}`,
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()))
+
+	// The identity server serves the JWKS of the organizations in served, after a delay so that verifications
+	// overlap, and counts the fetches per organization.
+	suffix := time.Now().UnixNano()
+	knownOrg := fmt.Sprintf("jwks-known-%d", suffix)
+	largeOrg := fmt.Sprintf("jwks-large-%d", suffix)
+	served := map[string]string{
+		knownOrg: jwks,
+		largeOrg: `{"keys": [], "padding": "` + strings.Repeat("x", constants.MaxJWKSSize) + `"}`,
+	}
+	var mu sync.Mutex
+	fetches := map[string]int{}
+	identityServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.HasSuffix(r.URL.Path, "/oauth2/jwks") {
+			_, _ = w.Write([]byte(`{"active": true}`))
+			return
+		}
+		orgHandle := strings.Split(strings.TrimPrefix(r.URL.Path, "/t/"), "/")[0]
+		mu.Lock()
+		fetches[orgHandle]++
+		mu.Unlock()
+		time.Sleep(100 * time.Millisecond)
+		body, ok := served[orgHandle]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer identityServer.Close()
+	fetchesOf := func(orgHandle string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return fetches[orgHandle]
+	}
+
+	certDir := t.TempDir()
+	trustStore := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: identityServer.Certificate().Raw})
+	require.NoError(t, os.WriteFile(filepath.Join(certDir, "ca.pem"), trustStore, 0o600))
+	host, port, err := net.SplitHostPort(identityServer.Listener.Addr().String())
+	require.NoError(t, err)
+	original := config.GetCDSRuntime().Config
+	conf := original
+	conf.AuthServer.Host = host
+	conf.AuthServer.Port = port
+	conf.AuthServer.IntrospectionEndPoint = "/oauth2/introspect"
+	conf.AuthServer.JWKSEndpoint = "/oauth2/jwks"
+	conf.AuthServer.IsSystemAppGrantEnabled = false
+	conf.TLS.CertDir = certDir
+	conf.TLS.TrustStore = "ca.pem"
+	conf.TLS.MTLSEnabled = false
+	config.OverrideCDSRuntime(conf)
+	defer config.OverrideCDSRuntime(original)
+
+	sign := func(orgHandle string) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			constants.OrgHandleClaim: orgHandle,
+			constants.AudienceClaim:  "iam-cds",
+			constants.ExpiryClaim:    time.Now().Add(time.Hour).Unix(),
+		}).SignedString(key)
+		require.NoError(t, err)
+		return token
+	}
+
+	t.Run("Concurrent_verifications_share_a_fetch", func(t *testing.T) {
+		token := sign(knownOrg)
+		var wg sync.WaitGroup
+		var failed atomic.Int32
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := authn.ValidateAuthenticationAndReturnClaims(token, knownOrg); err != nil {
+					failed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		require.Zero(t, failed.Load())
+		require.Equal(t, 1, fetchesOf(knownOrg))
+	})
+
+	t.Run("Organizations_without_a_JWKS_are_not_asked_again", func(t *testing.T) {
+		unknownOrg := fmt.Sprintf("jwks-unknown-%d", suffix)
+		for i := 0; i < 3; i++ {
+			_, err := authn.ValidateAuthenticationAndReturnClaims(sign(unknownOrg), unknownOrg)
+			require.Error(t, err)
+		}
+		require.Equal(t, 1, fetchesOf(unknownOrg))
+	})
+
+	t.Run("Tokens_of_other_organizations_fetch_nothing", func(t *testing.T) {
+		otherOrg := fmt.Sprintf("jwks-other-%d", suffix)
+		_, err := authn.ValidateAuthenticationAndReturnClaims(sign(knownOrg), otherOrg)
+		require.Error(t, err)
+		require.Zero(t, fetchesOf(otherOrg))
+	})
+
+	t.Run("Oversized_key_sets_are_rejected", func(t *testing.T) {
+		_, err := authn.ValidateAuthenticationAndReturnClaims(sign(largeOrg), largeOrg)
+		require.Error(t, err)
+	})
+}
+
+func Test_AuthnAndAuthz_Returns_Request_With_Identity(t *testing.T) {
+
+	caller := newAPICaller(t, fmt.Sprintf("carbon.super-identity-%d", time.Now().UnixNano()), "profile:view")
+	recorder := caller.call(httptest.NewRequest(http.MethodGet, "/profiles", nil),
+		func(w http.ResponseWriter, r *http.Request) {
+			authenticated, err := security.AuthnAndAuthz(r, "profile:view")
+			require.NoError(t, err)
+			_, ok := security.IdentityFromContext(authenticated.Context())
+			require.True(t, ok)
+			_, ok = security.IdentityFromContext(r.Context())
+			require.False(t, ok, "The request of the caller is not modified")
+			w.WriteHeader(http.StatusNoContent)
+		})
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+}
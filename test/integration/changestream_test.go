@@ -0,0 +1,262 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/changestream"
+	"github.com/wso2/identity-customer-data-service/internal/system/changestream/kafka"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+// recordingPublisher keeps the changes published to it together with their keys.
+type recordingPublisher struct {
+	mu      sync.Mutex
+	keys    []string
+	changes []changestream.ProfileChange
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, key string, change changestream.ProfileChange) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = append(p.keys, key)
+	p.changes = append(p.changes, change)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	return nil
+}
+
+func Test_Profile_Change_Stream(t *testing.T) {
+
+	SuperTenantOrg := fmt.Sprintf("carbon.super-changestream-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	t.Run("Changes_are_not_published_when_unconfigured", func(t *testing.T) {
+		publisher, err := changestream.NewPublisher(config.ChangeStreamConfig{})
+		require.NoError(t, err)
+		require.Nil(t, publisher)
+		_, err = changestream.NewPublisher(config.ChangeStreamConfig{Type: "unknown"})
+		require.Error(t, err)
+
+		require.NoError(t, changestream.Start(config.ChangeStreamConfig{}, nil))
+		changestream.PublishProfileChange(constants.ProfileChangeCreated, SuperTenantOrg, "unpublished", nil)
+		require.NoError(t, changestream.Stop())
+	})
+
+	t.Run("Profile_changes_are_published_in_order_by_master", func(t *testing.T) {
+		recorder := &recordingPublisher{}
+		changestream.RegisterPublisher("recording", func(config.ChangeStreamConfig) (changestream.ProfileChangePublisher, error) {
+			return recorder, nil
+		})
+		require.NoError(t, changestream.Start(config.ChangeStreamConfig{Type: "recording"},
+			func(profileId string) (*profileModel.ProfileResponse, error) {
+				return profileSvc.GetProfile(profileId, profileModel.AllApplications)
+			}))
+
+		master, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, SuperTenantOrg)
+		require.NoError(t, err)
+		child, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, SuperTenantOrg)
+		require.NoError(t, err)
+		require.NoError(t, profileSvc.MergeProfiles(master.ProfileId, child.ProfileId))
+		require.NoError(t, profileSvc.DeleteProfile(master.ProfileId))
+		require.NoError(t, changestream.Stop(), "Stopping publishes the queued changes")
+
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		require.Len(t, recorder.changes, 4)
+		types := []string{}
+		for _, change := range recorder.changes {
+			types = append(types, change.EventType)
+		}
+		require.Equal(t, []string{constants.ProfileChangeCreated, constants.ProfileChangeCreated,
+			constants.ProfileChangeMerged, constants.ProfileChangeDeleted}, types)
+
+		created := recorder.changes[0]
+		require.Equal(t, master.ProfileId, recorder.keys[0])
+		require.Equal(t, SuperTenantOrg, created.OrgHandle)
+		require.Equal(t, master.ProfileId, created.Profile.ProfileId)
+
+		merged := recorder.changes[2]
+		require.Equal(t, master.ProfileId, recorder.keys[2])
+		require.Equal(t, []string{child.ProfileId}, merged.ChildProfileIds)
+		require.NotNil(t, merged.Profile, "Merges carry the snapshot of the merged profile")
+		require.Len(t, merged.Profile.MergedFrom, 1)
+
+		deleted := recorder.changes[3]
+		require.Equal(t, master.ProfileId, recorder.keys[3])
+		require.Nil(t, deleted.Profile)
+	})
+
+	t.Run("Kafka_publisher_produces_through_the_rest_proxy", func(t *testing.T) {
+		var mu sync.Mutex
+		var paths, contentTypes []string
+		var records []map[string]interface{}
+		status, errorCode := http.StatusOK, "null"
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Records []map[string]interface{} `json:"records"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			paths = append(paths, r.URL.Path)
+			contentTypes = append(contentTypes, r.Header.Get("Content-Type"))
+			records = append(records, body.Records...)
+			respondWith, recordError := status, errorCode
+			mu.Unlock()
+			w.WriteHeader(respondWith)
+			_, _ = fmt.Fprintf(w, `{"offsets":[{"partition":0,"offset":7,"error_code":%s,"error":null}]}`, recordError)
+		}))
+		defer proxy.Close()
+
+		_, err := kafka.NewPublisher(config.KafkaStreamConfig{})
+		require.Error(t, err, "A REST proxy is required")
+
+		publisher, err := kafka.NewPublisher(config.KafkaStreamConfig{RestProxyURL: proxy.URL + "/"})
+		require.NoError(t, err)
+		defer publisher.Close()
+		change := changestream.ProfileChange{EventId: "event-1", EventType: constants.ProfileChangeUpdated,
+			ProfileId: "child", MasterProfileId: "master"}
+		require.NoError(t, publisher.Publish(context.Background(), "master", change))
+
+		mu.Lock()
+		require.Equal(t, []string{"/topics/" + constants.DefaultChangeStreamKafkaTopic}, paths)
+		require.Equal(t, "application/vnd.kafka.json.v2+json", contentTypes[0])
+		require.Len(t, records, 1)
+		require.Equal(t, "master", records[0]["key"])
+		require.Equal(t, "event-1", records[0]["value"].(map[string]interface{})["event_id"])
+		errorCode = "40403"
+		mu.Unlock()
+		require.Error(t, publisher.Publish(context.Background(), "master", change), "Rejected records fail")
+
+		mu.Lock()
+		status = http.StatusInternalServerError
+		mu.Unlock()
+		require.Error(t, publisher.Publish(context.Background(), "master", change))
+	})
+
+	t.Run("Every_profile_mutation_publishes_a_change", func(t *testing.T) {
+		orgHandle := fmt.Sprintf("carbon.super-changestream-mutations-%d", time.Now().UnixNano())
+		_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(
+			[]profileSchema.ProfileSchemaAttribute{{
+				OrgId:         orgHandle,
+				AttributeId:   uuid.New().String(),
+				AttributeName: "traits.tier",
+				ValueType:     constants.StringDataType,
+				MergeStrategy: "overwrite",
+				Mutability:    constants.MutabilityReadWrite,
+			}}, constants.Traits, orgHandle)
+		require.NoError(t, err)
+
+		recorder := &recordingPublisher{}
+		changestream.RegisterPublisher("recording-mutations",
+			func(config.ChangeStreamConfig) (changestream.ProfileChangePublisher, error) {
+				return recorder, nil
+			})
+		// published runs the mutation and returns the event types published for each profile by it.
+		published := func(mutate func()) map[string][]string {
+			require.NoError(t, changestream.Start(config.ChangeStreamConfig{Type: "recording-mutations"},
+				profileSvc.GetProfileForChangeStream))
+			mutate()
+			require.NoError(t, changestream.Stop(), "Stopping publishes the queued changes")
+
+			recorder.mu.Lock()
+			defer recorder.mu.Unlock()
+			types := map[string][]string{}
+			for _, change := range recorder.changes {
+				types[change.ProfileId] = append(types[change.ProfileId], change.EventType)
+			}
+			recorder.keys, recorder.changes = nil, nil
+			return types
+		}
+		create := func(tier string) string {
+			profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+				Traits: map[string]interface{}{"tier": tier},
+			}, orgHandle)
+			require.NoError(t, err)
+			return profile.ProfileId
+		}
+		master, child := create("gold"), create("silver")
+		require.NoError(t, profileSvc.MergeProfiles(master, child))
+
+		changes := published(func() {
+			_, err := profileSvc.UnmergeProfile(child, profileModel.AllApplications)
+			require.NoError(t, err)
+		})
+		require.Contains(t, changes[child], constants.ProfileChangeUpdated, "Unmerge")
+		require.Contains(t, changes[master], constants.ProfileChangeUpdated, "Unmerge")
+
+		changes = published(func() { require.NoError(t, profileSvc.AnonymizeProfile(master)) })
+		require.Contains(t, changes[master], constants.ProfileChangeUpdated, "Anonymize")
+
+		require.NoError(t, profileSvc.DeleteProfile(master))
+		changes = published(func() { require.NoError(t, profileSvc.RestoreProfile(master)) })
+		require.Contains(t, changes[master], constants.ProfileChangeUpdated, "Restore")
+
+		changes = published(func() {
+			patched, err := profileSvc.PatchProfilesByFilter(orgHandle, []string{"traits.tier eq gold"},
+				map[string]interface{}{"tier": "platinum"})
+			require.NoError(t, err)
+			require.EqualValues(t, 1, patched)
+		})
+		require.Contains(t, changes[master], constants.ProfileChangeUpdated, "Patch by filter")
+
+		changes = published(func() {
+			results := profileSvc.ApplyProfilesBatch(context.Background(), orgHandle, constants.DefaultImportSource,
+				[]profileModel.ProfileImportRecord{
+					{ProfileId: child, ProfileRequest: profileModel.ProfileRequest{
+						Traits: map[string]interface{}{"tier": "bronze"}}},
+					{ProfileRequest: profileModel.ProfileRequest{Traits: map[string]interface{}{"tier": "bronze"}}},
+				})
+			require.Equal(t, http.StatusOK, results[0].Status)
+			require.Equal(t, http.StatusCreated, results[1].Status)
+		})
+		require.Contains(t, changes[child], constants.ProfileChangeUpdated, "Import")
+		require.Len(t, changes, 2, "Import")
+
+		changes = published(func() {
+			require.NoError(t, profileSvc.UpdateProfileConsents(child, []profileModel.ConsentRecord{}))
+		})
+		require.Contains(t, changes[child], constants.ProfileChangeUpdated, "Consents")
+
+		changes = published(func() {
+			deleted, err := profileSvc.DeleteProfilesByFilter(orgHandle, []string{"traits.tier eq bronze"})
+			require.NoError(t, err)
+			require.EqualValues(t, 2, deleted)
+		})
+		require.Contains(t, changes[child], constants.ProfileChangeDeleted, "Delete by filter")
+		require.Len(t, changes, 2, "Delete by filter")
+	})
+}
@@ -19,6 +19,7 @@
 package integration
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -150,10 +151,10 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 
 		time.Sleep(3 * time.Second)
 
-		merged1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(prof2.ProfileId)
-		merged3, _ := profileSvc.GetProfile(prof3.ProfileId)
-		merged4, _ := profileSvc.GetProfile(prof4.ProfileId)
+		merged1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
+		merged3, _ := profileSvc.GetProfile(prof3.ProfileId, profileModel.AllApplications)
+		merged4, _ := profileSvc.GetProfile(prof4.ProfileId, profileModel.AllApplications)
 
 		// All profiles should be merged (either same master or same MergedTo)
 		require.NotEmpty(t, merged1.MergedTo.ProfileId, "Profile 1 should be merged")
@@ -187,8 +188,8 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		time.Sleep(2 * time.Second)
 
 		// Verify initial merge happened
-		merged1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(prof2.ProfileId)
+		merged1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
 		require.Equal(t, merged1.MergedTo.ProfileId, merged2.MergedTo.ProfileId, "T1 and T2 should merge")
 
 		// Now add T3 with matching phone
@@ -196,7 +197,7 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		prof3, _ := profileSvc.CreateProfile(t3, SuperTenantOrg)
 		time.Sleep(2 * time.Second)
 
-		merged3, _ := profileSvc.GetProfile(prof3.ProfileId)
+		merged3, _ := profileSvc.GetProfile(prof3.ProfileId, profileModel.AllApplications)
 
 		// T3 should be merged (a new master may be created when merging with existing hierarchy)
 		require.NotEmpty(t, merged3.MergedTo.ProfileId, "T3 should be merged")
@@ -229,8 +230,8 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		prof2, _ := profileSvc.CreateProfile(p2, SuperTenantOrg)
 		time.Sleep(2 * time.Second)
 
-		merged1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(prof2.ProfileId)
+		merged1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
 
 		require.Equal(t, merged1.MergedTo.ProfileId, merged2.MergedTo.ProfileId, "Profiles should merge")
 
@@ -259,8 +260,8 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		time.Sleep(2 * time.Second)
 
 		// Verify they are NOT merged initially
-		merged1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(prof2.ProfileId)
+		merged1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
 		if merged1.MergedTo != nil {
 			require.Empty(t, merged1.MergedTo.ProfileId, "P1 should not be merged initially")
 		}
@@ -277,12 +278,12 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 				"interests": []interface{}{"hiking"},
 			},
 		}
-		_, _ = profileSvc.UpdateProfile(prof1.ProfileId, SuperTenantOrg, updateReq)
+		_, _ = profileSvc.UpdateProfile(context.Background(), prof1.ProfileId, SuperTenantOrg, updateReq, 0)
 		time.Sleep(2 * time.Second)
 
 		// After update, profiles should be unified
-		afterUpdate1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		afterUpdate2, _ := profileSvc.GetProfile(prof2.ProfileId)
+		afterUpdate1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		afterUpdate2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
 
 		// At least one should show merge status
 		mergeHappened := afterUpdate1.MergedTo.ProfileId != "" || afterUpdate2.MergedTo.ProfileId != ""
@@ -303,8 +304,8 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		prof2, _ := profileSvc.CreateProfile(p2, SuperTenantOrg)
 		time.Sleep(2 * time.Second)
 
-		merged1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(prof2.ProfileId)
+		merged1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
 
 		require.Equal(t, merged1.MergedTo.ProfileId, merged2.MergedTo.ProfileId, "Profiles should merge")
 		// The merge reason should be email_based (higher priority)
@@ -332,10 +333,10 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		profD, _ := profileSvc.CreateProfile(pD, SuperTenantOrg)
 		time.Sleep(3 * time.Second)
 
-		mergedA, _ := profileSvc.GetProfile(profA.ProfileId)
-		mergedB, _ := profileSvc.GetProfile(profB.ProfileId)
-		mergedC, _ := profileSvc.GetProfile(profC.ProfileId)
-		mergedD, _ := profileSvc.GetProfile(profD.ProfileId)
+		mergedA, _ := profileSvc.GetProfile(profA.ProfileId, profileModel.AllApplications)
+		mergedB, _ := profileSvc.GetProfile(profB.ProfileId, profileModel.AllApplications)
+		mergedC, _ := profileSvc.GetProfile(profC.ProfileId, profileModel.AllApplications)
+		mergedD, _ := profileSvc.GetProfile(profD.ProfileId, profileModel.AllApplications)
 
 		// A and B should be merged (email match)
 		require.NotEmpty(t, mergedA.MergedTo.ProfileId, "A should be merged")
@@ -347,7 +348,7 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		require.NotEmpty(t, mergedD.MergedTo.ProfileId, "D should be merged")
 
 		// All should ultimately point to the same master or be part of the same hierarchy
-		masterProfile, _ := profileSvc.GetProfile(mergedA.MergedTo.ProfileId)
+		masterProfile, _ := profileSvc.GetProfile(mergedA.MergedTo.ProfileId, profileModel.AllApplications)
 		require.NotNil(t, masterProfile, "Master profile should exist")
 
 		// Verify preferences from all profiles are combined
@@ -377,10 +378,10 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		profT3, _ := profileSvc.CreateProfile(t3, SuperTenantOrg)
 		time.Sleep(3 * time.Second)
 
-		mergedPerm, _ := profileSvc.GetProfile(profPerm.ProfileId)
-		mergedT1, _ := profileSvc.GetProfile(profT1.ProfileId)
-		mergedT2, _ := profileSvc.GetProfile(profT2.ProfileId)
-		mergedT3, _ := profileSvc.GetProfile(profT3.ProfileId)
+		mergedPerm, _ := profileSvc.GetProfile(profPerm.ProfileId, profileModel.AllApplications)
+		mergedT1, _ := profileSvc.GetProfile(profT1.ProfileId, profileModel.AllApplications)
+		mergedT2, _ := profileSvc.GetProfile(profT2.ProfileId, profileModel.AllApplications)
+		mergedT3, _ := profileSvc.GetProfile(profT3.ProfileId, profileModel.AllApplications)
 
 		// Permanent profile should be the master
 		require.Empty(t, mergedPerm.MergedTo, "Permanent profile should be master")
@@ -413,7 +414,7 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		_, _ = profileSvc.CreateProfile(p2, SuperTenantOrg)
 		time.Sleep(2 * time.Second)
 
-		merged1, _ := profileSvc.GetProfile(prof1.ProfileId)
+		merged1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
 
 		// Interests should be combined (combine strategy)
 		interests := merged1.Traits["interests"].([]interface{})
@@ -446,8 +447,8 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		time.Sleep(2 * time.Second)
 
 		// Verify no unification happened
-		check1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		check2, _ := profileSvc.GetProfile(prof2.ProfileId)
+		check1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		check2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
 		require.Empty(t, check1.MergedTo, "Should not merge while rule inactive")
 		require.Empty(t, check2.MergedTo, "Should not merge while rule inactive")
 
@@ -461,7 +462,7 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		time.Sleep(2 * time.Second)
 
 		// Third profile should trigger unification
-		merged3, _ := profileSvc.GetProfile(prof3.ProfileId)
+		merged3, _ := profileSvc.GetProfile(prof3.ProfileId, profileModel.AllApplications)
 		require.NotEmpty(t, merged3.MergedTo.ProfileId, "Third profile should trigger unification")
 
 		cleanProfiles(profileSvc, SuperTenantOrg)
@@ -487,9 +488,9 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		time.Sleep(3 * time.Second)
 
 		// Verify first hierarchy is created
-		merged1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(prof2.ProfileId)
-		merged3, _ := profileSvc.GetProfile(prof3.ProfileId)
+		merged1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
+		merged3, _ := profileSvc.GetProfile(prof3.ProfileId, profileModel.AllApplications)
 
 		require.NotEmpty(t, merged1.MergedTo.ProfileId, "P1 should be merged")
 		require.NotEmpty(t, merged2.MergedTo.ProfileId, "P2 should be merged")
@@ -500,7 +501,7 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		require.Equal(t, master1Id, merged2.MergedTo.ProfileId, "P1 and P2 should have same master")
 		require.Equal(t, master1Id, merged3.MergedTo.ProfileId, "P1 and P3 should have same master")
 
-		master1, _ := profileSvc.GetProfile(master1Id)
+		master1, _ := profileSvc.GetProfile(master1Id, profileModel.AllApplications)
 		require.NotNil(t, master1, "Master1 should exist")
 		require.GreaterOrEqual(t, len(master1.MergedFrom), 3, "Master1 should have at least 3 children")
 
@@ -514,8 +515,8 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		time.Sleep(3 * time.Second)
 
 		// Verify second hierarchy is created
-		merged4, _ := profileSvc.GetProfile(prof4.ProfileId)
-		merged5, _ := profileSvc.GetProfile(prof5.ProfileId)
+		merged4, _ := profileSvc.GetProfile(prof4.ProfileId, profileModel.AllApplications)
+		merged5, _ := profileSvc.GetProfile(prof5.ProfileId, profileModel.AllApplications)
 
 		require.NotEmpty(t, merged4.MergedTo.ProfileId, "P4 should be merged")
 		require.NotEmpty(t, merged5.MergedTo.ProfileId, "P5 should be merged")
@@ -523,7 +524,7 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		master2Id := merged4.MergedTo.ProfileId
 		require.Equal(t, master2Id, merged5.MergedTo.ProfileId, "P4 and P5 should have same master")
 
-		master2, _ := profileSvc.GetProfile(master2Id)
+		master2, _ := profileSvc.GetProfile(master2Id, profileModel.AllApplications)
 		require.NotNil(t, master2, "Master2 should exist")
 		require.GreaterOrEqual(t, len(master2.MergedFrom), 2, "Master2 should have at least 2 children")
 		for i, child := range master2.MergedFrom {
@@ -540,12 +541,12 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		time.Sleep(5 * time.Second)
 
 		// Step 4: Verify all profiles are now in unified hierarchy
-		finalMerged1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		finalMerged2, _ := profileSvc.GetProfile(prof2.ProfileId)
-		finalMerged3, _ := profileSvc.GetProfile(prof3.ProfileId)
-		finalMerged4, _ := profileSvc.GetProfile(prof4.ProfileId)
-		finalMerged5, _ := profileSvc.GetProfile(prof5.ProfileId)
-		finalMerged6, _ := profileSvc.GetProfile(prof6.ProfileId)
+		finalMerged1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		finalMerged2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
+		finalMerged3, _ := profileSvc.GetProfile(prof3.ProfileId, profileModel.AllApplications)
+		finalMerged4, _ := profileSvc.GetProfile(prof4.ProfileId, profileModel.AllApplications)
+		finalMerged5, _ := profileSvc.GetProfile(prof5.ProfileId, profileModel.AllApplications)
+		finalMerged6, _ := profileSvc.GetProfile(prof6.ProfileId, profileModel.AllApplications)
 
 		// All profiles should have a master (merged)
 		require.NotEmpty(t, finalMerged1.MergedTo.ProfileId, "P1 should be in unified hierarchy")
@@ -557,7 +558,7 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 
 		// Find the final master
 		finalMasterId := finalMerged6.MergedTo.ProfileId
-		finalMaster, _ := profileSvc.GetProfile(finalMasterId)
+		finalMaster, _ := profileSvc.GetProfile(finalMasterId, profileModel.AllApplications)
 		require.NotNil(t, finalMaster, "Final master should exist")
 
 		// Show all children of final master
@@ -581,11 +582,165 @@ func Test_Complex_Unification_Scenarios(t *testing.T) {
 		cleanProfiles(profileSvc, SuperTenantOrg)
 	})
 
+	t.Run("Scenario20_UnmergeProfile", func(t *testing.T) {
+		// Scenario: Two temporary profiles are wrongly merged by email and then unmerged
+		// Expected: Both profiles stand on their own, the unification created master is removed
+		// and an update does not merge them again by the same rule
+
+		p1 := mustUnmarshalProfile(`{"identity_attributes":{"email":["shared@wso2.com"]},"traits":{"interests":["chess"]}}`)
+		p2 := mustUnmarshalProfile(`{"identity_attributes":{"email":["shared@wso2.com"]},"traits":{"interests":["golf"]}}`)
+
+		prof1, _ := profileSvc.CreateProfile(p1, SuperTenantOrg)
+		prof2, _ := profileSvc.CreateProfile(p2, SuperTenantOrg)
+		time.Sleep(2 * time.Second)
+
+		merged1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		require.NotEmpty(t, merged1.MergedTo.ProfileId, "P1 should be merged")
+		masterId := merged1.MergedTo.ProfileId
+
+		unmerged, err := profileSvc.UnmergeProfile(prof1.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Nil(t, unmerged.MergedTo)
+		require.Equal(t, []interface{}{"chess"}, unmerged.Traits["interests"])
+
+		// The master only referred to P2, so it is dissolved
+		other, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
+		require.Nil(t, other.MergedTo)
+		require.Equal(t, []interface{}{"golf"}, other.Traits["interests"])
+		_, err = profileSvc.GetProfile(masterId, profileModel.AllApplications)
+		require.Error(t, err)
+
+		_, err = profileSvc.UnmergeProfile(prof1.ProfileId, profileModel.AllApplications)
+		require.Error(t, err, "A profile that is not merged can not be unmerged")
+
+		updateReq := mustUnmarshalProfile(`{"identity_attributes":{"email":["shared@wso2.com"]},"traits":{"interests":["chess","go"]}}`)
+		_, err = profileSvc.UpdateProfile(context.Background(), prof1.ProfileId, SuperTenantOrg, updateReq, 0)
+		require.NoError(t, err)
+		time.Sleep(2 * time.Second)
+
+		afterUpdate, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		require.Nil(t, afterUpdate.MergedTo, "Unmerged profiles should not be merged again by the same rule")
+
+		cleanProfiles(profileSvc, SuperTenantOrg)
+	})
+
+	t.Run("Scenario21_ManualMerge", func(t *testing.T) {
+		// Scenario: Two profiles of the same person that no rule matches are merged manually
+		// Expected: The child is merged to the master with combined data, and merges that would
+		// create a cycle are rejected
+
+		p1 := mustUnmarshalProfile(`{"identity_attributes":{"email":["manual@wso2.com"]},"traits":{"interests":["sailing"]}}`)
+		p2 := mustUnmarshalProfile(`{"identity_attributes":{"email":["manaul@wso2.com"]},"traits":{"interests":["rowing"]}}`)
+		p3 := mustUnmarshalProfile(`{"traits":{"interests":["diving"]}}`)
+
+		prof1, _ := profileSvc.CreateProfile(p1, SuperTenantOrg)
+		prof2, _ := profileSvc.CreateProfile(p2, SuperTenantOrg)
+		prof3, _ := profileSvc.CreateProfile(p3, SuperTenantOrg)
+		time.Sleep(2 * time.Second)
+
+		require.Error(t, profileSvc.MergeProfiles(prof1.ProfileId, prof1.ProfileId))
+		require.NoError(t, profileSvc.MergeProfiles(prof1.ProfileId, prof2.ProfileId))
+
+		merged2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
+		require.Equal(t, prof1.ProfileId, merged2.MergedTo.ProfileId)
+		require.Equal(t, constants.ManualMergeReason, merged2.MergedTo.Reason)
+		require.ElementsMatch(t, []interface{}{"manual@wso2.com", "manaul@wso2.com"}, merged2.IdentityAttributes["email"])
+
+		// P2 is under P1, so P1 can not be merged into it, nor can it be merged again
+		require.Error(t, profileSvc.MergeProfiles(prof2.ProfileId, prof1.ProfileId))
+		require.Error(t, profileSvc.MergeProfiles(prof3.ProfileId, prof2.ProfileId))
+		require.Error(t, profileSvc.MergeProfiles(prof2.ProfileId, prof3.ProfileId))
+
+		// Profiles merged to the child move under the master
+		require.NoError(t, profileSvc.MergeProfiles(prof3.ProfileId, prof1.ProfileId))
+		merged2, _ = profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
+		require.Equal(t, prof3.ProfileId, merged2.MergedTo.ProfileId)
+		master, _ := profileSvc.GetProfile(prof3.ProfileId, profileModel.AllApplications)
+		require.Len(t, master.MergedFrom, 2)
+
+		cleanProfiles(profileSvc, SuperTenantOrg)
+	})
+
+	t.Run("Scenario22_ApplicationScopedReads", func(t *testing.T) {
+		// Scenario: A merged profile is read on behalf of an application
+		// Expected: Only the requesting application's data is returned, from the master as well as from the list
+
+		p1 := mustUnmarshalProfile(`{
+			"identity_attributes":{"email":["scoped@wso2.com"]},
+			"application_data":{"` + AppId + `":{"device_id":["device-101"]}}
+		}`)
+		p2 := mustUnmarshalProfile(`{"identity_attributes":{"email":["scoped@wso2.com"]}}`)
+
+		prof1, _ := profileSvc.CreateProfile(p1, SuperTenantOrg)
+		prof2, _ := profileSvc.CreateProfile(p2, SuperTenantOrg)
+		time.Sleep(2 * time.Second)
+
+		ownApp, err := profileSvc.GetProfile(prof2.ProfileId, profileModel.ApplicationScope(AppId))
+		require.NoError(t, err)
+		require.NotNil(t, ownApp.MergedTo)
+		require.Contains(t, ownApp.ApplicationData, AppId)
+
+		otherApp, err := profileSvc.GetProfile(prof1.ProfileId, profileModel.ApplicationScope("other-app"))
+		require.NoError(t, err)
+		require.Empty(t, otherApp.ApplicationData)
+
+		profiles, _, err := profileSvc.GetAllProfilesCursor(SuperTenantOrg, false, 10, nil,
+			profileModel.ApplicationScope("other-app"))
+		require.NoError(t, err)
+		require.NotEmpty(t, profiles)
+		for _, profile := range profiles {
+			require.Empty(t, profile.ApplicationData)
+		}
+
+		cleanProfiles(profileSvc, SuperTenantOrg)
+	})
+
+	t.Run("Scenario23_ProfileLineage", func(t *testing.T) {
+		// Scenario: Two profiles are unified by the email rule
+		// Expected: The lineage of either profile shows the hierarchy with the rule and the matched email
+
+		p1 := mustUnmarshalProfile(`{"identity_attributes":{"email":["lineage@wso2.com"]}}`)
+		p2 := mustUnmarshalProfile(`{"identity_attributes":{"email":["lineage@wso2.com"]}}`)
+
+		_, _ = profileSvc.CreateProfile(p1, SuperTenantOrg)
+		prof2, _ := profileSvc.CreateProfile(p2, SuperTenantOrg)
+		time.Sleep(2 * time.Second)
+
+		merged, err := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.NotNil(t, merged.MergedTo)
+
+		lineage, err := profileSvc.GetProfileLineage(prof2.ProfileId)
+		require.NoError(t, err)
+		require.Equal(t, merged.MergedTo.ProfileId, lineage.ReferenceProfile.ProfileId)
+		require.NotEmpty(t, lineage.MergedProfiles)
+		for _, link := range lineage.MergedProfiles {
+			require.Equal(t, EmailBased, link.Reason)
+			require.NotEmpty(t, link.RuleId)
+			require.Equal(t, "identity_attributes.email", link.Property)
+			require.Equal(t, "lineage@wso2.com", link.MatchedValue)
+		}
+
+		fromReference, err := profileSvc.GetProfileLineage(lineage.ReferenceProfile.ProfileId)
+		require.NoError(t, err)
+		require.ElementsMatch(t, lineage.MergedProfiles, fromReference.MergedProfiles)
+
+		master, err := profileSvc.GetProfile(lineage.ReferenceProfile.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.NotEmpty(t, master.MergedFrom)
+		for _, child := range master.MergedFrom {
+			require.NotEmpty(t, child.RuleId)
+			require.Equal(t, "lineage@wso2.com", child.MatchedValue)
+		}
+
+		cleanProfiles(profileSvc, SuperTenantOrg)
+	})
+
 	// Cleanup
 	t.Cleanup(func() {
 		rules, _ := unificationSvc.GetUnificationRules(SuperTenantOrg)
 		for _, r := range rules {
-			_ = unificationSvc.DeleteUnificationRule(r.RuleId)
+			_ = unificationSvc.DeleteUnificationRule(r.RuleId, SuperTenantOrg)
 		}
 		cleanProfiles(profileSvc, SuperTenantOrg)
 		_ = profileSchemaSvc.DeleteProfileSchema(SuperTenantOrg)
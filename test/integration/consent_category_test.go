@@ -19,7 +19,9 @@
 package integration
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	model "github.com/wso2/identity-customer-data-service/internal/consent/model"
@@ -28,8 +30,10 @@ import (
 
 func Test_Consent(t *testing.T) {
 	svc := service.GetConsentCategoryService()
+	SuperTenantOrg := fmt.Sprintf("carbon.super-consent-%d", time.Now().UnixNano())
 
 	category := model.ConsentCategory{
+		OrgHandle:          SuperTenantOrg,
 		CategoryName:       "Test Category",
 		Purpose:            "profiling",
 		CategoryIdentifier: "test-cat-001",
@@ -43,13 +47,13 @@ func Test_Consent(t *testing.T) {
 	})
 
 	t.Run("Get_all_consent_categories", func(t *testing.T) {
-		cats, err := svc.GetAllConsentCategories()
+		cats, err := svc.GetAllConsentCategories(SuperTenantOrg)
 		assert.NoError(t, err)
 		assert.NotEmpty(t, cats, "Expected at least one consent category")
 	})
 
 	t.Run("Get_single_category", func(t *testing.T) {
-		fetched, err := svc.GetConsentCategory(category.CategoryIdentifier)
+		fetched, err := svc.GetConsentCategory(category.CategoryIdentifier, SuperTenantOrg)
 		assert.NoError(t, err)
 		assert.Equal(t, category.CategoryName, fetched.CategoryName)
 	})
@@ -59,16 +63,36 @@ func Test_Consent(t *testing.T) {
 		err := svc.UpdateConsentCategory(category)
 		assert.NoError(t, err, "Failed to update consent category")
 
-		updated, err := svc.GetConsentCategory(category.CategoryIdentifier)
+		updated, err := svc.GetConsentCategory(category.CategoryIdentifier, SuperTenantOrg)
 		assert.NoError(t, err)
 		assert.Equal(t, "Updated Test Category", updated.CategoryName)
 	})
 
+	t.Run("Categories_are_not_visible_to_other_organizations", func(t *testing.T) {
+		otherOrg := SuperTenantOrg + "-other"
+		cats, err := svc.GetAllConsentCategories(otherOrg)
+		assert.NoError(t, err)
+		assert.Empty(t, cats)
+
+		_, err = svc.GetConsentCategory(category.CategoryIdentifier, otherOrg)
+		assert.Error(t, err)
+
+		foreign := category
+		foreign.OrgHandle = otherOrg
+		foreign.CategoryName = "Hijacked Category"
+		assert.Error(t, svc.UpdateConsentCategory(foreign))
+
+		assert.NoError(t, svc.DeleteConsentCategory(category.CategoryIdentifier, otherOrg))
+		fetched, err := svc.GetConsentCategory(category.CategoryIdentifier, SuperTenantOrg)
+		assert.NoError(t, err)
+		assert.Equal(t, "Updated Test Category", fetched.CategoryName)
+	})
+
 	t.Run("Delete_consent_category", func(t *testing.T) {
-		err := svc.DeleteConsentCategory(category.CategoryIdentifier)
+		err := svc.DeleteConsentCategory(category.CategoryIdentifier, SuperTenantOrg)
 		assert.NoError(t, err, "Failed to delete consent category")
 
-		deleted, _ := svc.GetConsentCategory(category.CategoryIdentifier)
+		deleted, _ := svc.GetConsentCategory(category.CategoryIdentifier, SuperTenantOrg)
 		assert.Nil(t, deleted, "Expected category to be nil after deletion")
 	})
 }
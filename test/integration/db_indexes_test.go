@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+)
+
+func Test_DB_Recommended_Indexes(t *testing.T) {
+
+	indexes := []string{"idx_profiles_traits", "idx_profiles_identity_attributes", "idx_profiles_org_updated_at",
+		"idx_profiles_email_lower"}
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	require.NoError(t, err)
+	defer dbClient.Close()
+
+	existingIndexes := func() []string {
+		rows, err := dbClient.ExecuteQuery(`SELECT indexname FROM pg_indexes WHERE tablename = 'profiles' 
+			AND indexname = ANY($1)`, pq.Array(indexes))
+		require.NoError(t, err)
+		names := make([]string, 0, len(rows))
+		for _, row := range rows {
+			names = append(names, row["indexname"].(string))
+		}
+		return names
+	}
+
+	t.Run("Schema_creates_the_indexes", func(t *testing.T) {
+		require.ElementsMatch(t, indexes, existingIndexes())
+	})
+
+	t.Run("Missing_indexes_are_created", func(t *testing.T) {
+		_, err := dbClient.ExecuteQuery(`DROP INDEX IF EXISTS idx_profiles_traits`)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"idx_profiles_identity_attributes", "idx_profiles_org_updated_at",
+			"idx_profiles_email_lower"}, existingIndexes())
+
+		require.NoError(t, provider.EnsureRecommendedIndexes(context.Background()))
+		require.ElementsMatch(t, indexes, existingIndexes())
+
+		// Indexes that already exist are left as they are
+		require.NoError(t, provider.EnsureRecommendedIndexes(context.Background()))
+		require.ElementsMatch(t, indexes, existingIndexes())
+	})
+
+	t.Run("Invalid_indexes_are_rebuilt", func(t *testing.T) {
+		isValid := func(name string) bool {
+			rows, err := dbClient.ExecuteQuery(`SELECT i.indisvalid FROM pg_index i JOIN pg_class c 
+				ON c.oid = i.indexrelid WHERE c.relname = $1`, name)
+			require.NoError(t, err)
+			require.Len(t, rows, 1)
+			return rows[0]["indisvalid"].(bool)
+		}
+		// Mark the index invalid, as a failed concurrent build leaves it
+		_, err := dbClient.ExecuteQuery(`UPDATE pg_index SET indisvalid = false 
+			WHERE indexrelid = 'idx_profiles_traits'::regclass`)
+		require.NoError(t, err)
+		require.False(t, isValid("idx_profiles_traits"))
+
+		require.NoError(t, provider.EnsureRecommendedIndexes(context.Background()))
+		require.True(t, isValid("idx_profiles_traits"))
+		require.ElementsMatch(t, indexes, existingIndexes())
+	})
+
+	t.Run("Concurrent_builds_take_turns", func(t *testing.T) {
+		_, err := dbClient.ExecuteQuery(`DROP INDEX IF EXISTS idx_profiles_traits`)
+		require.NoError(t, err)
+
+		const instances = 3
+		errs := make(chan error, instances)
+		for i := 0; i < instances; i++ {
+			go func() { errs <- provider.EnsureRecommendedIndexes(context.Background()) }()
+		}
+		for i := 0; i < instances; i++ {
+			require.NoError(t, <-errs)
+		}
+		require.ElementsMatch(t, indexes, existingIndexes())
+	})
+
+	t.Run("Slow_queries_are_logged_without_failing", func(t *testing.T) {
+		conf := config.GetCDSRuntime().Config
+		slow := conf
+		slow.DataSource.SlowQueryThreshold = time.Nanosecond
+		config.OverrideCDSRuntime(slow)
+		defer config.OverrideCDSRuntime(conf)
+
+		slowClient, err := provider.NewDBProvider().GetDBClient()
+		require.NoError(t, err)
+		defer slowClient.Close()
+		rows, err := slowClient.ExecuteQuery(`SELECT $1::text AS email, $2::int AS attempt`, "john@example.com", 2)
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		require.Equal(t, "john@example.com", rows[0]["email"])
+	})
+}
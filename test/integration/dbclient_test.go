@@ -0,0 +1,640 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/client"
+	"github.com/wso2/identity-customer-data-service/internal/system/metrics"
+)
+
+// blockingDriver is a stub driver whose queries only return once their context is done, standing in for a
+// long-running statement on the database.
+type blockingDriver struct {
+	started chan struct{}
+}
+
+type blockingConn struct {
+	driver *blockingDriver
+}
+
+func (d *blockingDriver) Open(string) (driver.Conn, error) {
+	return &blockingConn{driver: d}, nil
+}
+
+func (c *blockingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported by the stub driver")
+}
+
+func (c *blockingConn) Close() error {
+	return nil
+}
+
+func (c *blockingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported by the stub driver")
+}
+
+func (c *blockingConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+
+	select {
+	case c.driver.started <- struct{}{}:
+	default:
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func init() {
+	sql.Register("cds-blocking-stub", &blockingDriver{started: make(chan struct{}, 1)})
+}
+
+// flakyDriver is a stub driver whose queries fail with the given error a number of times before succeeding,
+// standing in for transient database failures.
+type flakyDriver struct {
+	err      error
+	failures int
+	attempts int
+}
+
+type flakyConn struct {
+	driver *flakyDriver
+}
+
+type emptyRows struct{}
+
+func (d *flakyDriver) Open(string) (driver.Conn, error) {
+	return &flakyConn{driver: d}, nil
+}
+
+func (c *flakyConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported by the stub driver")
+}
+
+func (c *flakyConn) Close() error {
+	return nil
+}
+
+func (c *flakyConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported by the stub driver")
+}
+
+func (c *flakyConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+
+	c.driver.attempts++
+	if c.driver.attempts <= c.driver.failures {
+		return nil, c.driver.err
+	}
+	return emptyRows{}, nil
+}
+
+func (emptyRows) Columns() []string {
+	return nil
+}
+
+func (emptyRows) Close() error {
+	return nil
+}
+
+func (emptyRows) Next([]driver.Value) error {
+	return io.EOF
+}
+
+func openFlakyDB(t *testing.T, err error, failures int) (*sql.DB, *flakyDriver) {
+
+	stub := &flakyDriver{err: err, failures: failures}
+	db := sql.OpenDB(stubConnector{stub})
+	t.Cleanup(func() { _ = db.Close() })
+	return db, stub
+}
+
+type stubConnector struct {
+	driver *flakyDriver
+}
+
+func (c stubConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open("")
+}
+
+func (c stubConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func openBlockingDB(t *testing.T) (*sql.DB, *blockingDriver) {
+
+	db, err := sql.Open("cds-blocking-stub", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db, db.Driver().(*blockingDriver)
+}
+
+func Test_DBClient_QueryContext(t *testing.T) {
+
+	t.Run("Query_is_cancelled_with_its_context", func(t *testing.T) {
+		db, stub := openBlockingDB(t)
+		dbClient := client.NewDBClient(db, time.Minute, client.RetryPolicy{}, 0, 0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			<-stub.started
+			cancel()
+		}()
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := dbClient.ExecuteQueryContext(ctx, "SELECT 1")
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			require.ErrorIs(t, err, context.Canceled)
+		case <-time.After(5 * time.Second):
+			t.Fatal("query did not stop after its context was cancelled")
+		}
+	})
+
+	t.Run("ExecuteQuery_applies_default_timeout", func(t *testing.T) {
+		db, _ := openBlockingDB(t)
+		dbClient := client.NewDBClient(db, 50*time.Millisecond, client.RetryPolicy{}, 0, 0)
+
+		start := time.Now()
+		_, err := dbClient.ExecuteQuery("SELECT 1")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(start), 5*time.Second)
+	})
+}
+
+func Test_DBClient_QueryWithRetry(t *testing.T) {
+
+	policy := client.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	serializationFailure := &pq.Error{Code: "40001"}
+
+	t.Run("Transient_read_failures_are_retried", func(t *testing.T) {
+		db, stub := openFlakyDB(t, serializationFailure, 2)
+		dbClient := client.NewDBClient(db, time.Minute, policy, 0, 0)
+
+		_, err := dbClient.ExecuteQueryWithRetry(client.RetryOptions{}, "SELECT 1")
+		require.NoError(t, err)
+		require.Equal(t, 3, stub.attempts)
+	})
+
+	t.Run("Retries_stop_after_max_attempts", func(t *testing.T) {
+		db, stub := openFlakyDB(t, serializationFailure, 5)
+		dbClient := client.NewDBClient(db, time.Minute, policy, 0, 0)
+
+		_, err := dbClient.ExecuteQueryWithRetry(client.RetryOptions{}, "SELECT 1")
+		require.ErrorIs(t, err, serializationFailure)
+		require.Equal(t, 3, stub.attempts)
+	})
+
+	t.Run("Non_transient_failures_are_not_retried", func(t *testing.T) {
+		db, stub := openFlakyDB(t, &pq.Error{Code: "23505"}, 1)
+		dbClient := client.NewDBClient(db, time.Minute, policy, 0, 0)
+
+		_, err := dbClient.ExecuteQueryWithRetry(client.RetryOptions{}, "SELECT 1")
+		require.Error(t, err)
+		require.Equal(t, 1, stub.attempts)
+	})
+
+	t.Run("Writes_are_retried_only_when_allowed", func(t *testing.T) {
+		db, stub := openFlakyDB(t, serializationFailure, 1)
+		dbClient := client.NewDBClient(db, time.Minute, policy, 0, 0)
+
+		_, err := dbClient.ExecuteQueryWithRetry(client.RetryOptions{}, "UPDATE t SET v = 1")
+		require.Error(t, err)
+		require.Equal(t, 1, stub.attempts)
+
+		_, err = dbClient.ExecuteQueryWithRetry(client.RetryOptions{AllowWrites: true}, "UPDATE t SET v = 1")
+		require.NoError(t, err)
+		require.Equal(t, 2, stub.attempts)
+	})
+
+	t.Run("Cancelled_context_stops_retrying", func(t *testing.T) {
+		db, stub := openFlakyDB(t, serializationFailure, 5)
+		slowPolicy := client.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Minute, MaxBackoff: time.Minute}
+		dbClient := client.NewDBClient(db, time.Minute, slowPolicy, 0, 0)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := dbClient.ExecuteQueryWithRetryContext(ctx, client.RetryOptions{}, "SELECT 1")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, 1, stub.attempts)
+	})
+
+	t.Run("Attempts_and_durations_are_recorded_in_metrics", func(t *testing.T) {
+		db, _ := openFlakyDB(t, serializationFailure, 2)
+		dbClient := client.NewDBClient(db, time.Minute, policy, 0, 0)
+		retries := metrics.DBQueryAttempts.Value("retry")
+		successes := metrics.DBQueryAttempts.Value("success")
+		durations := metrics.DBQueryDuration.Count("select metrics_probe")
+
+		_, err := dbClient.ExecuteQueryWithRetry(client.RetryOptions{}, "SELECT v FROM metrics_probe")
+		require.NoError(t, err)
+		require.Equal(t, retries+2, metrics.DBQueryAttempts.Value("retry"))
+		require.Equal(t, successes+1, metrics.DBQueryAttempts.Value("success"))
+		require.Equal(t, durations+3, metrics.DBQueryDuration.Count("select metrics_probe"))
+
+		var exposition strings.Builder
+		metrics.Write(&exposition)
+		require.Contains(t, exposition.String(), "# TYPE cds_db_query_duration_seconds histogram")
+		require.Contains(t, exposition.String(), `cds_db_query_duration_seconds_bucket{query="select metrics_probe",le="+Inf"}`)
+		require.Contains(t, exposition.String(), `cds_db_query_retry_attempts_total{outcome="retry"}`)
+	})
+}
+
+// txDriver is a stub driver whose transactions fail to commit with the given error a number of times before
+// committing, standing in for transactions aborted by concurrent ones.
+type txDriver struct {
+	err       error
+	failures  int
+	begins    int
+	commits   int
+	rollbacks int
+}
+
+type txConn struct {
+	driver *txDriver
+}
+
+type stubTx struct {
+	driver *txDriver
+}
+
+func (d *txDriver) Open(string) (driver.Conn, error) {
+	return &txConn{driver: d}, nil
+}
+
+func (c *txConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported by the stub driver")
+}
+
+func (c *txConn) Close() error {
+	return nil
+}
+
+func (c *txConn) Begin() (driver.Tx, error) {
+
+	c.driver.begins++
+	return &stubTx{driver: c.driver}, nil
+}
+
+func (tx *stubTx) Commit() error {
+
+	if tx.driver.begins <= tx.driver.failures {
+		return tx.driver.err
+	}
+	tx.driver.commits++
+	return nil
+}
+
+func (tx *stubTx) Rollback() error {
+
+	tx.driver.rollbacks++
+	return nil
+}
+
+type txConnector struct {
+	driver *txDriver
+}
+
+func (c txConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open("")
+}
+
+func (c txConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func openTxDB(t *testing.T, err error, failures int) (*sql.DB, *txDriver) {
+
+	stub := &txDriver{err: err, failures: failures}
+	db := sql.OpenDB(txConnector{stub})
+	t.Cleanup(func() { _ = db.Close() })
+	return db, stub
+}
+
+func Test_DBClient_RunInTx(t *testing.T) {
+
+	policy := client.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	serializationFailure := &pq.Error{Code: "40001"}
+
+	t.Run("Conflicting_transactions_are_run_again", func(t *testing.T) {
+		db, stub := openTxDB(t, serializationFailure, 2)
+		dbClient := client.NewDBClient(db, time.Minute, policy, 0, 0)
+
+		runs := 0
+		err := dbClient.RunInTx(func(*sql.Tx) error {
+			runs++
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, runs)
+		require.Equal(t, 1, stub.commits)
+	})
+
+	t.Run("Retries_stop_after_max_attempts", func(t *testing.T) {
+		db, stub := openTxDB(t, &pq.Error{Code: "40P01"}, 5)
+		dbClient := client.NewDBClient(db, time.Minute, policy, 0, 0)
+
+		err := dbClient.RunInTx(func(*sql.Tx) error { return nil })
+		require.True(t, client.IsTxConflictError(err))
+		require.Equal(t, 3, stub.begins)
+		require.Zero(t, stub.commits)
+	})
+
+	t.Run("Failing_functions_are_rolled_back_and_not_retried", func(t *testing.T) {
+		db, stub := openTxDB(t, nil, 0)
+		dbClient := client.NewDBClient(db, time.Minute, policy, 0, 0)
+		failure := errors.New("constraint violated")
+
+		err := dbClient.RunInTx(func(*sql.Tx) error { return failure })
+		require.ErrorIs(t, err, failure)
+		require.Equal(t, 1, stub.begins)
+		require.Equal(t, 1, stub.rollbacks)
+		require.Zero(t, stub.commits)
+	})
+
+	t.Run("Conflicts_wrapped_by_the_function_are_retried", func(t *testing.T) {
+		db, stub := openTxDB(t, nil, 0)
+		dbClient := client.NewDBClient(db, time.Minute, policy, 0, 0)
+
+		runs := 0
+		err := dbClient.RunInTx(func(*sql.Tx) error {
+			runs++
+			if runs == 1 {
+				return fmt.Errorf("failed to update profile: %w", serializationFailure)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, runs)
+		require.Equal(t, 1, stub.rollbacks)
+		require.Equal(t, 1, stub.commits)
+	})
+
+	t.Run("Panicking_functions_are_rolled_back", func(t *testing.T) {
+		db, stub := openTxDB(t, nil, 0)
+		dbClient := client.NewDBClient(db, time.Minute, policy, 0, 0)
+
+		require.Panics(t, func() {
+			_ = dbClient.RunInTx(func(*sql.Tx) error { panic("boom") })
+		})
+		require.Equal(t, 1, stub.rollbacks)
+	})
+}
+
+// preparingDriver is a stub driver that records how queries reach it: through a prepared statement or directly. Its
+// prepared statements fail with a stale plan error while stalePlans is positive.
+type preparingDriver struct {
+	mu         sync.Mutex
+	prepares   int
+	prepared   int
+	direct     int
+	closed     int
+	stalePlans int
+}
+
+type preparingConn struct {
+	driver *preparingDriver
+}
+
+type preparedStmt struct {
+	driver *preparingDriver
+}
+
+func (c *preparingConn) Prepare(string) (driver.Stmt, error) {
+
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	c.driver.prepares++
+	return &preparedStmt{driver: c.driver}, nil
+}
+
+func (c *preparingConn) Close() error {
+	return nil
+}
+
+func (c *preparingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported by the stub driver")
+}
+
+func (c *preparingConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	c.driver.direct++
+	return emptyRows{}, nil
+}
+
+func (s *preparedStmt) Close() error {
+
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	s.driver.closed++
+	return nil
+}
+
+func (s *preparedStmt) NumInput() int {
+	return -1
+}
+
+func (s *preparedStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("exec is not supported by the stub driver")
+}
+
+func (s *preparedStmt) Query([]driver.Value) (driver.Rows, error) {
+
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	if s.driver.stalePlans > 0 {
+		s.driver.stalePlans--
+		return nil, &pq.Error{Code: "0A000", Message: "cached plan must not change result type"}
+	}
+	s.driver.prepared++
+	return emptyRows{}, nil
+}
+
+func (d *preparingDriver) Open(string) (driver.Conn, error) {
+	return &preparingConn{driver: d}, nil
+}
+
+type preparingConnector struct {
+	driver *preparingDriver
+}
+
+func (c preparingConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open("")
+}
+
+func (c preparingConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func openPreparingDB(t *testing.T) (*sql.DB, *preparingDriver) {
+
+	stub := &preparingDriver{}
+	db := sql.OpenDB(preparingConnector{stub})
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		_ = client.CloseStatements(db)
+		_ = db.Close()
+	})
+	return db, stub
+}
+
+func Test_DBClient_StatementCache(t *testing.T) {
+
+	const query = "SELECT v FROM t WHERE id = $1"
+
+	t.Run("Statements_are_prepared_once_per_pool", func(t *testing.T) {
+		db, stub := openPreparingDB(t)
+		for i := 0; i < 3; i++ {
+			dbClient := client.NewDBClient(db, time.Minute, client.RetryPolicy{}, 0, 8)
+			_, err := dbClient.ExecuteQuery(query, i)
+			require.NoError(t, err)
+		}
+		require.Equal(t, 1, stub.prepares)
+		require.Equal(t, 3, stub.prepared)
+		require.Equal(t, 0, stub.direct)
+	})
+
+	t.Run("Queries_without_arguments_are_not_prepared", func(t *testing.T) {
+		db, stub := openPreparingDB(t)
+		dbClient := client.NewDBClient(db, time.Minute, client.RetryPolicy{}, 0, 8)
+
+		_, err := dbClient.ExecuteQuery("SELECT 1; SELECT 2")
+		require.NoError(t, err)
+		require.Equal(t, 0, stub.prepares)
+		require.Equal(t, 1, stub.direct)
+	})
+
+	t.Run("Queries_beyond_the_cache_size_run_unprepared", func(t *testing.T) {
+		db, stub := openPreparingDB(t)
+		dbClient := client.NewDBClient(db, time.Minute, client.RetryPolicy{}, 0, 1)
+
+		_, err := dbClient.ExecuteQuery(query, 1)
+		require.NoError(t, err)
+		_, err = dbClient.ExecuteQuery("SELECT v FROM t WHERE id = $1 AND v > $2", 1, 2)
+		require.NoError(t, err)
+		require.Equal(t, 1, stub.prepares)
+		require.Equal(t, 1, stub.prepared)
+		require.Equal(t, 1, stub.direct)
+	})
+
+	t.Run("Disabled_cache_runs_every_query_unprepared", func(t *testing.T) {
+		db, stub := openPreparingDB(t)
+		dbClient := client.NewDBClient(db, time.Minute, client.RetryPolicy{}, 0, 0)
+
+		_, err := dbClient.ExecuteQuery(query, 1)
+		require.NoError(t, err)
+		require.Equal(t, 0, stub.prepares)
+		require.Equal(t, 1, stub.direct)
+	})
+
+	t.Run("Stale_statements_are_prepared_again", func(t *testing.T) {
+		db, stub := openPreparingDB(t)
+		dbClient := client.NewDBClient(db, time.Minute, client.RetryPolicy{}, 0, 8)
+		_, err := dbClient.ExecuteQuery(query, 1)
+		require.NoError(t, err)
+
+		stub.stalePlans = 1
+		_, err = dbClient.ExecuteQuery(query, 2)
+		require.NoError(t, err, "A stale plan falls back to running the query unprepared")
+		require.Equal(t, 1, stub.direct)
+
+		_, err = dbClient.ExecuteQuery(query, 3)
+		require.NoError(t, err)
+		require.Equal(t, 2, stub.prepares)
+		require.Equal(t, 2, stub.prepared)
+	})
+
+	t.Run("Statements_are_closed_with_the_pool", func(t *testing.T) {
+		db, stub := openPreparingDB(t)
+		dbClient := client.NewDBClient(db, time.Minute, client.RetryPolicy{}, 0, 8)
+		_, err := dbClient.ExecuteQuery(query, 1)
+		require.NoError(t, err)
+
+		require.NoError(t, client.CloseStatements(db))
+		require.Equal(t, 1, stub.closed)
+
+		// The pool prepares afresh afterwards
+		_, err = client.NewDBClient(db, time.Minute, client.RetryPolicy{}, 0, 8).ExecuteQuery(query, 2)
+		require.NoError(t, err)
+		require.Equal(t, 2, stub.prepares)
+	})
+}
+
+func Test_DBClient_RowAccessors(t *testing.T) {
+
+	createdAt := time.Now()
+	row := map[string]interface{}{
+		"rule_id":    []byte("rule-1"),
+		"rule_name":  "email",
+		"priority":   int32(3),
+		"is_active":  true,
+		"created_at": createdAt,
+		"deleted_at": nil,
+	}
+
+	t.Run("Driver_variants_are_accepted", func(t *testing.T) {
+		ruleId, err := client.GetString(row, "rule_id")
+		require.NoError(t, err)
+		require.Equal(t, "rule-1", ruleId)
+
+		priority, err := client.GetInt64(row, "priority")
+		require.NoError(t, err)
+		require.Equal(t, int64(3), priority)
+
+		active, err := client.GetBool(row, "is_active")
+		require.NoError(t, err)
+		require.True(t, active)
+
+		at, err := client.GetTime(row, "created_at")
+		require.NoError(t, err)
+		require.Equal(t, createdAt, at)
+	})
+
+	t.Run("Null_missing_and_mistyped_columns_return_errors", func(t *testing.T) {
+		_, err := client.GetTime(row, "deleted_at")
+		require.ErrorContains(t, err, "is null")
+
+		_, err = client.GetString(row, "property_id")
+		require.ErrorContains(t, err, "not in the result")
+
+		_, err = client.GetInt64(row, "rule_name")
+		require.ErrorContains(t, err, "unexpected type")
+
+		value, err := client.GetNullableString(row, "deleted_at")
+		require.NoError(t, err)
+		require.Empty(t, value)
+	})
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	consentService "github.com/wso2/identity-customer-data-service/internal/consent/service"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	unificationService "github.com/wso2/identity-customer-data-service/internal/unification_rules/service"
+	webhookService "github.com/wso2/identity-customer-data-service/internal/webhook/service"
+)
+
+// Test_Empty_List_Encoding verifies that list operations of an organization without any data encode as an empty
+// JSON array rather than null.
+func Test_Empty_List_Encoding(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-empty-lists-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	requireEmptyArray := func(t *testing.T, list interface{}, err error) {
+		require.NoError(t, err)
+		encoded, err := json.Marshal(list)
+		require.NoError(t, err)
+		require.JSONEq(t, "[]", string(encoded))
+	}
+
+	t.Run("Unification_rules", func(t *testing.T) {
+		rules, err := unificationService.GetUnificationRuleService().GetUnificationRules(orgHandle)
+		requireEmptyArray(t, rules, err)
+	})
+
+	t.Run("Active_unification_rules", func(t *testing.T) {
+		rules, err := unificationService.GetUnificationRuleService().GetActiveUnificationRules(orgHandle)
+		requireEmptyArray(t, rules, err)
+	})
+
+	t.Run("Consent_categories", func(t *testing.T) {
+		categories, err := consentService.GetConsentCategoryService().GetAllConsentCategories(orgHandle)
+		requireEmptyArray(t, categories, err)
+	})
+
+	t.Run("Webhooks", func(t *testing.T) {
+		webhooks, err := webhookService.GetWebhookService().GetWebhooks(orgHandle)
+		requireEmptyArray(t, webhooks, err)
+	})
+
+	t.Run("Profiles", func(t *testing.T) {
+		profiles, _, err := profileSvc.GetAllProfilesCursor(orgHandle, false, 10, nil, profileModel.AllApplications)
+		requireEmptyArray(t, profiles, err)
+	})
+
+	t.Run("Filtered_profiles", func(t *testing.T) {
+		filters := []string{"user_id eq nobody"}
+		profiles, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+			Filters:  filters,
+			Limit:    10,
+			AppScope: profileModel.AllApplications,
+		})
+		requireEmptyArray(t, profiles, err)
+	})
+
+	t.Run("Quarantined_import_records", func(t *testing.T) {
+		records, err := profileSvc.GetQuarantinedImportRecords(orgHandle, "")
+		requireEmptyArray(t, records, err)
+	})
+
+	t.Run("Children_of_a_profile", func(t *testing.T) {
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+		children, err := profileSvc.GetChildProfiles(profile.ProfileId)
+		requireEmptyArray(t, children, err)
+	})
+
+	t.Run("Consents_of_a_profile", func(t *testing.T) {
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+		consents, err := profileSvc.GetProfileConsents(profile.ProfileId)
+		requireEmptyArray(t, consents, err)
+	})
+}
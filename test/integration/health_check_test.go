@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wso2/identity-customer-data-service/internal/health_check/handler"
+	"github.com/wso2/identity-customer-data-service/internal/health_check/service"
+)
+
+func Test_Health_Check(t *testing.T) {
+
+	healthHandler := handler.NewHealthHandler()
+
+	t.Run("Liveness_does_not_depend_on_components", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		healthHandler.HandleHealth(recorder, httptest.NewRequest(http.MethodGet, "/cds/api/v1/health/live", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("Readiness_reports_every_component_up", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		healthHandler.HandleReadiness(recorder, httptest.NewRequest(http.MethodGet, "/cds/api/v1/health/ready", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var body struct {
+			Status     string                             `json:"status"`
+			Components map[string]service.ComponentStatus `json:"components"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		require.Equal(t, "ready", body.Status)
+		for _, component := range []string{service.ComponentServer, service.ComponentDatabase,
+			service.ComponentConnectionPool, service.ComponentIdempotencyKeys} {
+			require.Equal(t, service.StatusUp, body.Components[component].Status, component)
+		}
+	})
+
+	t.Run("A_component_down_fails_readiness", func(t *testing.T) {
+		report := service.ReadinessReport{Components: map[string]service.ComponentStatus{
+			service.ComponentDatabase:       {Status: service.StatusUp},
+			service.ComponentConnectionPool: {Status: service.StatusDown, Error: "all 25 connections are in use"},
+		}}
+		require.False(t, report.Ready())
+	})
+}
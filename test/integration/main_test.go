@@ -33,6 +33,9 @@ import (
 	"github.com/wso2/identity-customer-data-service/test/setup"
 )
 
+// testPostgres is the database the tests run against.
+var testPostgres *setup.TestPostgres
+
 func TestMain(m *testing.M) {
 	ctx := context.Background()
 	os.Setenv("TEST_MODE", "true")
@@ -65,6 +68,7 @@ func TestMain(m *testing.M) {
 		os.Exit(1)
 	}
 
+	testPostgres = pg
 	provider.SetTestDB(pg.DB)
 	err = utils.CreateTablesFromFile(pg.DB, utils.GetSchemaPath())
 	if err != nil {
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Alias(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-alias-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	newProfile := func(request profileModel.ProfileRequest) string {
+		profile, err := profileSvc.CreateProfile(request, orgHandle)
+		require.NoError(t, err)
+		return profile.ProfileId
+	}
+	requireStatus := func(t *testing.T, err error, status int) {
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, status, clientErr.StatusCode)
+	}
+
+	known := newProfile(profileModel.ProfileRequest{UserId: "alias-user"})
+	anonymous := newProfile(profileModel.ProfileRequest{
+		ApplicationData: map[string]map[string]interface{}{"storefront": {"cart_id": "c-42"}},
+	})
+
+	t.Run("Merges_the_anonymous_profile_into_the_known_profile", func(t *testing.T) {
+		profile, err := profileSvc.AliasProfiles(anonymous, known, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, known, profile.ProfileId)
+		require.Equal(t, "c-42", profile.ApplicationData["storefront"]["cart_id"])
+
+		children, err := profileSvc.GetChildProfiles(known)
+		require.NoError(t, err)
+		require.Len(t, children, 1)
+		require.Equal(t, anonymous, children[0].ProfileId)
+		require.Equal(t, constants.AliasMergeReason, children[0].Reason)
+	})
+
+	t.Run("Repeated_alias_is_harmless", func(t *testing.T) {
+		profile, err := profileSvc.AliasProfiles(anonymous, known, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, known, profile.ProfileId)
+		profile, err = profileSvc.AliasProfiles(known, anonymous, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, known, profile.ProfileId)
+	})
+
+	t.Run("Alias_to_a_merged_profile_links_to_its_reference_profile", func(t *testing.T) {
+		another := newProfile(profileModel.ProfileRequest{})
+		profile, err := profileSvc.AliasProfiles(another, anonymous, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, known, profile.ProfileId)
+
+		children, err := profileSvc.GetChildProfiles(known)
+		require.NoError(t, err)
+		require.Len(t, children, 2)
+	})
+
+	t.Run("Profile_linked_elsewhere", func(t *testing.T) {
+		other := newProfile(profileModel.ProfileRequest{UserId: "alias-other-user"})
+		_, err := profileSvc.AliasProfiles(anonymous, other, profileModel.AllApplications)
+		requireStatus(t, err, http.StatusConflict)
+	})
+
+	t.Run("Permanent_profiles_of_different_users", func(t *testing.T) {
+		other := newProfile(profileModel.ProfileRequest{UserId: "alias-third-user"})
+		_, err := profileSvc.AliasProfiles(other, known, profileModel.AllApplications)
+		requireStatus(t, err, http.StatusConflict)
+	})
+
+	t.Run("Unknown_profile", func(t *testing.T) {
+		_, err := profileSvc.AliasProfiles(uuid.NewString(), known, profileModel.AllApplications)
+		requireStatus(t, err, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileHandler "github.com/wso2/identity-customer-data-service/internal/profile/handler"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+// Test_Profile_App_Scope_Of_Write_Responses checks that the profiles returned by the write endpoints are restricted
+// to the application of the caller like the profiles it reads.
+func Test_Profile_App_Scope_Of_Write_Responses(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-appscope-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	handler := profileHandler.NewProfileHandler()
+
+	addSchemaAttributes(t, orgHandle, constants.ApplicationData,
+		appDataAttribute(orgHandle, "app-a", "theme", constants.StringDataType),
+		appDataAttribute(orgHandle, "app-b", "theme", constants.StringDataType))
+	addSchemaAttributes(t, orgHandle, constants.Traits,
+		profileSchema.ProfileSchemaAttribute{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.riskScore",
+			ValueType:     constants.IntegerDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+			AllowedApps:   []string{"app-b"},
+		})
+	caller := newAppAPICaller(t, orgHandle, "app-a", "profile:create", "profile:update")
+
+	// Every profile below holds the data of both applications and a trait only app-b may read.
+	otherAppData := profileModel.ProfileRequest{
+		ApplicationData: map[string]map[string]interface{}{"app-b": {"theme": "dark"}},
+		Traits:          map[string]interface{}{"riskScore": 90},
+	}
+	requireScoped := func(t *testing.T, recorder *httptest.ResponseRecorder, status int) {
+		require.Equal(t, status, recorder.Code, recorder.Body.String())
+		var profile profileModel.ProfileResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &profile))
+		require.Equal(t, map[string]map[string]interface{}{"app-a": {"theme": "light"}}, profile.ApplicationData)
+		require.NotContains(t, profile.Traits, "riskScore")
+	}
+
+	t.Run("Alias_returns_the_profile_restricted_to_the_caller", func(t *testing.T) {
+		from, err := profileSvc.CreateProfile(otherAppData, orgHandle)
+		require.NoError(t, err)
+		to, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			ApplicationData: map[string]map[string]interface{}{"app-a": {"theme": "light"}},
+		}, orgHandle)
+		require.NoError(t, err)
+
+		body, err := json.Marshal(map[string]string{"from_profile_id": from.ProfileId, "to_profile_id": to.ProfileId})
+		require.NoError(t, err)
+		requireScoped(t, caller.call(httptest.NewRequest(http.MethodPost, "/profiles/alias", bytes.NewReader(body)),
+			handler.AliasProfiles), http.StatusOK)
+	})
+
+	t.Run("Unmerge_returns_the_profile_restricted_to_the_caller", func(t *testing.T) {
+		master, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+		child, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			ApplicationData: map[string]map[string]interface{}{
+				"app-a": {"theme": "light"},
+				"app-b": {"theme": "dark"},
+			},
+			Traits: otherAppData.Traits,
+		}, orgHandle)
+		require.NoError(t, err)
+		require.NoError(t, profileSvc.MergeProfiles(master.ProfileId, child.ProfileId))
+
+		request := httptest.NewRequest(http.MethodPost, "/profiles/"+child.ProfileId+"/unmerge", nil)
+		request.SetPathValue("profileId", child.ProfileId)
+		requireScoped(t, caller.call(request, handler.UnmergeProfile), http.StatusOK)
+	})
+
+	t.Run("Idempotent_replay_returns_the_profile_restricted_to_the_caller", func(t *testing.T) {
+		key := uuid.NewString()
+		create := func() *httptest.ResponseRecorder {
+			request := httptest.NewRequest(http.MethodPost, "/profiles",
+				bytes.NewReader([]byte(`{"application_data": {"app-a": {"theme": "light"}}}`)))
+			request.Header.Set(constants.IdempotencyKeyHeader, key)
+			return caller.call(request, handler.InitProfile)
+		}
+		created := create()
+		require.Equal(t, http.StatusCreated, created.Code, created.Body.String())
+		var profile profileModel.ProfileResponse
+		require.NoError(t, json.Unmarshal(created.Body.Bytes(), &profile))
+
+		// Another application writes to the profile after it was created with the key
+		require.NoError(t, profileSvc.PatchApplicationData(profile.ProfileId, "app-b",
+			otherAppData.ApplicationData["app-b"]))
+		_, err := profileSvc.PatchProfile(context.Background(), profile.ProfileId, orgHandle,
+			map[string]interface{}{"traits": otherAppData.Traits}, 0)
+		require.NoError(t, err)
+
+		requireScoped(t, create(), http.StatusCreated)
+	})
+}
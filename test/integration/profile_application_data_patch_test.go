@@ -0,0 +1,183 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+// appDataAttribute returns a read-write application data attribute of the application with the given key.
+func appDataAttribute(orgHandle, appId, key, valueType string) profileSchema.ProfileSchemaAttribute {
+	return profileSchema.ProfileSchemaAttribute{
+		OrgId:                 orgHandle,
+		AttributeId:           uuid.New().String(),
+		AttributeName:         constants.ApplicationData + "." + key,
+		ValueType:             valueType,
+		MergeStrategy:         "overwrite",
+		Mutability:            constants.MutabilityReadWrite,
+		ApplicationIdentifier: appId,
+	}
+}
+
+// addSchemaAttributes adds the attributes of the scope to the schema of the organization.
+func addSchemaAttributes(t *testing.T, orgHandle, scope string, attributes ...profileSchema.ProfileSchemaAttribute) {
+	restore := schemaService.OverrideValidateApplicationIdentifierForTest(
+		func(appID, org string) (error, bool) { return nil, true })
+	defer restore()
+	_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(attributes, scope, orgHandle)
+	require.NoError(t, err)
+}
+
+func Test_Profile_Application_Data_Patch(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-appdatapatch-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	lang := appDataAttribute(orgHandle, "storefront", "prefs.lang", constants.StringDataType)
+	tz := appDataAttribute(orgHandle, "storefront", "prefs.tz", constants.StringDataType)
+	font := appDataAttribute(orgHandle, "storefront", "prefs.font", constants.StringDataType)
+	addSchemaAttributes(t, orgHandle, constants.ApplicationData, lang, tz, font)
+	prefs := appDataAttribute(orgHandle, "storefront", "prefs", constants.ComplexDataType)
+	for _, sub := range []profileSchema.ProfileSchemaAttribute{lang, tz, font} {
+		prefs.SubAttributes = append(prefs.SubAttributes,
+			profileSchema.SubAttribute{AttributeId: sub.AttributeId, AttributeName: sub.AttributeName})
+	}
+	accountRef := appDataAttribute(orgHandle, "storefront", "account_ref", constants.StringDataType)
+	accountRef.Mutability = constants.MutabilityImmutable
+	attributes := []profileSchema.ProfileSchemaAttribute{
+		prefs, accountRef,
+		appDataAttribute(orgHandle, "storefront", "theme", constants.StringDataType),
+		appDataAttribute(orgHandle, "storefront", "legacy", constants.BooleanDataType),
+		appDataAttribute(orgHandle, "storefront", "visits", constants.IntegerDataType),
+		appDataAttribute(orgHandle, "helpdesk", "tier", constants.StringDataType),
+		appDataAttribute(orgHandle, "mobile", "push_enabled", constants.BooleanDataType),
+	}
+	for i := 0; i < 8; i++ {
+		attributes = append(attributes,
+			appDataAttribute(orgHandle, "analytics", fmt.Sprintf("counter_%d", i), constants.IntegerDataType))
+	}
+	addSchemaAttributes(t, orgHandle, constants.ApplicationData, attributes...)
+
+	created, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		ApplicationData: map[string]map[string]interface{}{
+			"storefront": {
+				"theme":       "dark",
+				"legacy":      true,
+				"prefs":       map[string]interface{}{"lang": "en", "tz": "UTC"},
+				"account_ref": "acc-1",
+			},
+			"helpdesk": {"tier": "gold"},
+		},
+	}, orgHandle)
+	require.NoError(t, err)
+
+	appData := func(appId string) map[string]interface{} {
+		profile, err := profileSvc.GetProfile(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		return profile.ApplicationData[appId]
+	}
+
+	t.Run("Merges_nested_objects_and_removes_null_keys", func(t *testing.T) {
+		err := profileSvc.PatchApplicationData(created.ProfileId, "storefront", map[string]interface{}{
+			"legacy": nil,
+			"visits": 3,
+			"prefs":  map[string]interface{}{"tz": nil, "font": "mono"},
+		})
+		require.NoError(t, err)
+
+		data := appData("storefront")
+		require.Equal(t, "dark", data["theme"])
+		require.Equal(t, "acc-1", data["account_ref"])
+		require.EqualValues(t, 3, data["visits"])
+		require.NotContains(t, data, "legacy")
+		require.Equal(t, map[string]interface{}{"lang": "en", "font": "mono"}, data["prefs"])
+		require.Equal(t, map[string]interface{}{"tier": "gold"}, appData("helpdesk"))
+	})
+
+	t.Run("Creates_the_data_of_a_new_application", func(t *testing.T) {
+		err := profileSvc.PatchApplicationData(created.ProfileId, "mobile", map[string]interface{}{
+			"push_enabled": true,
+			"ignored":      nil,
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"push_enabled": true}, appData("mobile"))
+	})
+
+	t.Run("Keeps_concurrent_patches_of_different_keys", func(t *testing.T) {
+		const workers = 8
+		var wg sync.WaitGroup
+		errs := make(chan error, workers)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs <- profileSvc.PatchApplicationData(created.ProfileId, "analytics",
+					map[string]interface{}{fmt.Sprintf("counter_%d", i): i})
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		require.Len(t, appData("analytics"), workers)
+	})
+
+	t.Run("Rejects_patches_the_schema_does_not_allow", func(t *testing.T) {
+		before := appData("storefront")
+		for name, patch := range map[string]map[string]interface{}{
+			"unknown key":          {"undeclared": "x"},
+			"wrong type":           {"visits": "many"},
+			"not an object":        {"prefs": "compact"},
+			"changed immutable":    {"account_ref": "acc-2"},
+			"removed immutable":    {"account_ref": nil},
+			"valid and wrong type": {"theme": "light", "legacy": "yes"},
+		} {
+			err := profileSvc.PatchApplicationData(created.ProfileId, "storefront", patch)
+			var clientErr *errors2.ClientError
+			require.ErrorAs(t, err, &clientErr, name)
+			require.Equal(t, http.StatusBadRequest, clientErr.StatusCode, name)
+		}
+		require.Equal(t, before, appData("storefront"))
+	})
+
+	t.Run("Rejects_unknown_profiles_and_missing_application", func(t *testing.T) {
+		err := profileSvc.PatchApplicationData(uuid.New().String(), "storefront", map[string]interface{}{"a": 1})
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, errors2.PROFILE_NOT_FOUND.Code, clientErr.Code)
+
+		err = profileSvc.PatchApplicationData(created.ProfileId, "", map[string]interface{}{"a": 1})
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, errors2.UPDATE_APP_DATA.Code, clientErr.Code)
+	})
+}
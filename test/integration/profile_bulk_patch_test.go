@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/unification_rules/model"
+	unificationService "github.com/wso2/identity-customer-data-service/internal/unification_rules/service"
+)
+
+func Test_Profile_Patch_By_Filter(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-bulkpatch-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	trait := func(name, valueType, mutability string) profileSchema.ProfileSchemaAttribute {
+		return profileSchema.ProfileSchemaAttribute{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: name,
+			ValueType:     valueType,
+			MergeStrategy: "overwrite",
+			Mutability:    mutability,
+		}
+	}
+	_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(
+		[]profileSchema.ProfileSchemaAttribute{
+			trait("traits.tier", constants.StringDataType, constants.MutabilityReadWrite),
+			trait("traits.campaignGroup", constants.StringDataType, constants.MutabilityReadWrite),
+			trait("traits.signupChannel", constants.StringDataType, constants.MutabilityWriteOnce),
+			trait("traits.loyaltyCard", constants.StringDataType, constants.MutabilityReadWrite),
+		}, constants.Traits, orgHandle)
+	require.NoError(t, err)
+
+	create := func(tier string) *profileModel.ProfileResponse {
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"tier": tier},
+		}, orgHandle)
+		require.NoError(t, err)
+		return profile
+	}
+	gold1, gold2, silver := create("gold"), create("gold"), create("silver")
+
+	t.Run("Traits_are_merged_into_matching_profiles", func(t *testing.T) {
+		updated, err := profileSvc.PatchProfilesByFilter(orgHandle, []string{"traits.tier eq gold"},
+			map[string]interface{}{"campaignGroup": "A"})
+		require.NoError(t, err)
+		require.EqualValues(t, 2, updated)
+
+		for _, profile := range []*profileModel.ProfileResponse{gold1, gold2} {
+			fetched, err := profileSvc.GetProfileFromPrimary(profile.ProfileId, profileModel.AllApplications)
+			require.NoError(t, err)
+			require.Equal(t, "A", fetched.Traits["campaignGroup"])
+			require.Equal(t, "gold", fetched.Traits["tier"], "Other traits are kept")
+			require.Greater(t, fetched.Meta.Version, profile.Meta.Version, "The version is bumped")
+		}
+		fetched, err := profileSvc.GetProfileFromPrimary(silver.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.NotContains(t, fetched.Traits, "campaignGroup")
+	})
+
+	t.Run("Profiles_already_holding_the_traits_are_not_counted", func(t *testing.T) {
+		updated, err := profileSvc.PatchProfilesByFilter(orgHandle, []string{"traits.tier eq gold"},
+			map[string]interface{}{"campaignGroup": "A"})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, updated)
+	})
+
+	t.Run("Patched_profiles_are_unified", func(t *testing.T) {
+		now := time.Now().UTC()
+		require.NoError(t, unificationService.GetUnificationRuleService().AddUnificationRule(model.UnificationRule{
+			RuleId:       uuid.New().String(),
+			OrgHandle:    orgHandle,
+			RuleName:     "Loyalty card",
+			PropertyName: "traits.loyaltyCard",
+			Priority:     1,
+			IsActive:     true,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}, orgHandle))
+		first, second := create("bronze"), create("bronze")
+
+		updated, err := profileSvc.PatchProfilesByFilter(orgHandle, []string{"traits.tier eq bronze"},
+			map[string]interface{}{"loyaltyCard": "LC-1"})
+		require.NoError(t, err)
+		require.EqualValues(t, 2, updated)
+
+		require.Eventually(t, func() bool {
+			firstProfile, err := profileStore.GetProfile(first.ProfileId)
+			require.NoError(t, err)
+			secondProfile, err := profileStore.GetProfile(second.ProfileId)
+			require.NoError(t, err)
+			return firstProfile.ProfileStatus.ReferenceProfileId != "" &&
+				firstProfile.ProfileStatus.ReferenceProfileId == secondProfile.ProfileStatus.ReferenceProfileId
+		}, 10*time.Second, 50*time.Millisecond, "The patched profiles should be merged by the rule")
+	})
+
+	t.Run("Invalid_patches_are_rejected", func(t *testing.T) {
+		cases := []struct {
+			filters []string
+			traits  map[string]interface{}
+		}{
+			{nil, map[string]interface{}{"campaignGroup": "B"}},
+			{[]string{"traits.tier eq gold"}, nil},
+			{[]string{"traits.tier eq gold"}, map[string]interface{}{"signupChannel": "web"}},
+			{[]string{"traits.tier eq gold"}, map[string]interface{}{"campaignGroup": 7}},
+		}
+		for _, c := range cases {
+			_, err := profileSvc.PatchProfilesByFilter(orgHandle, c.filters, c.traits)
+			var clientErr *errors2.ClientError
+			require.True(t, errors.As(err, &clientErr), "%v %v", c.filters, c.traits)
+			require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+		}
+	})
+}
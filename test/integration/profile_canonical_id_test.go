@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+)
+
+func Test_Profile_Canonical_Id(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-canonical-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	newProfile := func() string {
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+		require.Equal(t, profile.ProfileId, profile.CanonicalProfileId)
+		return profile.ProfileId
+	}
+	master, child := newProfile(), newProfile()
+
+	t.Run("Reference_profile_is_its_own_canonical_profile", func(t *testing.T) {
+		profile, err := profileSvc.GetProfile(master, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, master, profile.CanonicalProfileId)
+	})
+
+	t.Run("Merged_profile_reports_its_master", func(t *testing.T) {
+		require.NoError(t, profileSvc.MergeProfiles(master, child))
+
+		profile, err := profileSvc.GetProfile(child, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, child, profile.ProfileId, "The requested id is kept")
+		require.Equal(t, master, profile.CanonicalProfileId)
+		require.Equal(t, master, profile.MergedTo.ProfileId)
+
+		encoded, err := json.Marshal(profile)
+		require.NoError(t, err)
+		require.Contains(t, string(encoded), fmt.Sprintf(`"canonical_profile_id":%q`, master))
+	})
+
+	t.Run("Every_response_carries_the_canonical_profile", func(t *testing.T) {
+		listed, _, err := profileSvc.GetAllProfilesCursor(orgHandle, false, 10, nil, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		require.Equal(t, master, listed[0].CanonicalProfileId)
+
+		var streamed []profileModel.ProfileResponse
+		require.NoError(t, profileSvc.StreamProfiles(context.Background(), orgHandle, nil, profileModel.AllApplications,
+			func(profile profileModel.ProfileResponse) error {
+				streamed = append(streamed, profile)
+				return nil
+			}))
+		require.Len(t, streamed, 1)
+		require.Equal(t, master, streamed[0].CanonicalProfileId)
+
+		userId := "canonical-user-" + uuid.NewString()
+		owned, err := profileSvc.CreateProfile(profileModel.ProfileRequest{UserId: userId}, orgHandle)
+		require.NoError(t, err)
+		found, err := profileSvc.FindProfileByUserId(userId)
+		require.NoError(t, err)
+		require.Equal(t, owned.ProfileId, found.CanonicalProfileId)
+	})
+
+	t.Run("Unmerged_profile_is_canonical_again", func(t *testing.T) {
+		profile, err := profileSvc.UnmergeProfile(child, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, child, profile.CanonicalProfileId)
+	})
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+func Test_Profile_Case_Insensitive_Filters(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-casefilter-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	traits := []profileSchema.ProfileSchemaAttribute{
+		{
+			OrgId:           orgHandle,
+			AttributeId:     uuid.New().String(),
+			AttributeName:   "traits.email",
+			ValueType:       constants.StringDataType,
+			MergeStrategy:   "overwrite",
+			Mutability:      constants.MutabilityReadWrite,
+			CaseInsensitive: true,
+		},
+		{
+			OrgId:           orgHandle,
+			AttributeId:     uuid.New().String(),
+			AttributeName:   "traits.emailaddresses",
+			ValueType:       constants.StringDataType,
+			MergeStrategy:   "combine",
+			Mutability:      constants.MutabilityReadWrite,
+			MultiValued:     true,
+			CaseInsensitive: true,
+		},
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.nickname",
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+		},
+	}
+	_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(traits, constants.Traits,
+		orgHandle)
+	require.NoError(t, err)
+
+	john, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{"email": "john@example.com", "nickname": "Johnny",
+			"emailaddresses": []interface{}{"john@example.com", "john@work.example.com"}},
+	}, orgHandle)
+	require.NoError(t, err)
+	jane, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{"email": "jane@example.com"},
+	}, orgHandle)
+	require.NoError(t, err)
+
+	filter := func(filters ...string) []string {
+		profiles, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+			Filters:  filters,
+			Limit:    10,
+			AppScope: profileModel.AllApplications,
+		})
+		require.NoError(t, err)
+		ids := make([]string, 0, len(profiles))
+		for _, profile := range profiles {
+			ids = append(ids, profile.ProfileId)
+		}
+		return ids
+	}
+
+	t.Run("Eqi_ignores_case", func(t *testing.T) {
+		require.Equal(t, []string{john.ProfileId}, filter("traits.nickname eqi JOHNNY"))
+	})
+
+	t.Run("Eq_is_case_sensitive_by_default", func(t *testing.T) {
+		require.Empty(t, filter("traits.nickname eq JOHNNY"))
+		require.Equal(t, []string{john.ProfileId}, filter("traits.nickname eq Johnny"))
+	})
+
+	t.Run("Eq_ignores_case_for_case_insensitive_attributes", func(t *testing.T) {
+		require.Equal(t, []string{john.ProfileId}, filter("traits.email eq John@Example.com"))
+	})
+
+	t.Run("Eq_matches_an_element_of_multi_valued_attributes", func(t *testing.T) {
+		require.Equal(t, []string{john.ProfileId}, filter("traits.emailaddresses eq john@work.example.com"))
+	})
+
+	t.Run("Nei_ignores_case_and_matches_missing_values", func(t *testing.T) {
+		require.Equal(t, []string{jane.ProfileId}, filter("traits.email nei JOHN@example.com"))
+		require.Equal(t, []string{jane.ProfileId}, filter("traits.nickname nei johnny"))
+	})
+}
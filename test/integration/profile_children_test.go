@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Children(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-children-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	newProfile := func() string {
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+		return profile.ProfileId
+	}
+	master, first, second := newProfile(), newProfile(), newProfile()
+	require.NoError(t, profileSvc.MergeProfiles(master, first))
+	require.NoError(t, profileSvc.MergeProfiles(master, second))
+
+	childIds := func(children []profileModel.ChildProfile) []string {
+		ids := make([]string, 0, len(children))
+		for _, child := range children {
+			require.Equal(t, constants.ManualMergeReason, child.Reason)
+			ids = append(ids, child.ProfileId)
+		}
+		return ids
+	}
+
+	t.Run("Children_of_a_master", func(t *testing.T) {
+		children, err := profileSvc.GetChildProfiles(master)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{first, second}, childIds(children))
+	})
+
+	t.Run("Children_are_resolved_from_a_child", func(t *testing.T) {
+		children, err := profileSvc.GetChildProfiles(first)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{first, second}, childIds(children))
+	})
+
+	t.Run("Master_without_children", func(t *testing.T) {
+		children, err := profileSvc.GetChildProfiles(newProfile())
+		require.NoError(t, err)
+		require.NotNil(t, children, "An empty list rather than null is returned")
+		require.Empty(t, children)
+	})
+
+	t.Run("Unknown_profile", func(t *testing.T) {
+		_, err := profileSvc.GetChildProfiles(uuid.NewString())
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+	})
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/utils"
+)
+
+func Test_Profile_Concurrent_Create(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-concurrentcreate-%d", time.Now().UnixNano())
+	newProfile := func(profileId, org string, traits map[string]interface{}) profileModel.Profile {
+		now := time.Now().UTC()
+		return profileModel.Profile{
+			ProfileId:          profileId,
+			OrgHandle:          org,
+			CreatedAt:          now,
+			UpdatedAt:          now,
+			Location:           utils.BuildProfileLocation(org, profileId),
+			Traits:             traits,
+			IdentityAttributes: map[string]interface{}{},
+			ProfileStatus: &profileModel.ProfileStatus{
+				IsReferenceProfile: true,
+				ListProfile:        true,
+			},
+		}
+	}
+
+	t.Run("Concurrent_creates_of_the_same_id_converge", func(t *testing.T) {
+		profileId := uuid.NewString()
+		const workers = 10
+		var wg sync.WaitGroup
+		inserts := make(chan bool, workers)
+		errs := make(chan error, workers)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, inserted, err := profileStore.InsertProfileContext(t.Context(), newProfile(profileId, orgHandle,
+					map[string]interface{}{fmt.Sprintf("source_%d", i): true}))
+				inserts <- inserted
+				errs <- err
+			}(i)
+		}
+		wg.Wait()
+		close(inserts)
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		insertedCount := 0
+		for inserted := range inserts {
+			if inserted {
+				insertedCount++
+			}
+		}
+		require.Equal(t, 1, insertedCount, "Exactly one create should add the profile")
+
+		profile, err := profileService.GetProfilesService().GetProfile(profileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Len(t, profile.Traits, workers, "The traits of all creates should be kept")
+	})
+
+	t.Run("Profile_of_another_organization_is_not_merged_into", func(t *testing.T) {
+		profileId := uuid.NewString()
+		_, err := profileStore.InsertProfile(newProfile(profileId, orgHandle, map[string]interface{}{}))
+		require.NoError(t, err)
+
+		_, err = profileStore.InsertProfile(newProfile(profileId, orgHandle+"-other",
+			map[string]interface{}{"source": "other"}))
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusConflict, clientErr.StatusCode)
+
+		profile, err := profileService.GetProfilesService().GetProfile(profileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Empty(t, profile.Traits)
+	})
+
+	t.Run("Failed_create_leaves_no_profile_behind", func(t *testing.T) {
+		profileId := uuid.NewString()
+		profile := newProfile(profileId, orgHandle, map[string]interface{}{})
+		// The app id does not fit its column, so the application data is the last write and fails
+		profile.ApplicationData = []profileModel.ApplicationData{{
+			AppId:           strings.Repeat("a", 300),
+			AppSpecificData: map[string]interface{}{"theme": "dark"},
+		}}
+		_, err := profileStore.InsertProfile(profile)
+		require.Error(t, err)
+
+		stored, err := profileStore.GetProfile(profileId)
+		require.NoError(t, err)
+		require.Nil(t, stored, "The profile must be rolled back with its application data")
+	})
+}
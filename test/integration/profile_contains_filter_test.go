@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Contains_Filter(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-containsfilter-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	schemaSvc := schemaService.GetProfileSchemaService()
+
+	attribute := func(name, valueType string, multiValued bool) profileSchema.ProfileSchemaAttribute {
+		return profileSchema.ProfileSchemaAttribute{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: name,
+			ValueType:     valueType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+			MultiValued:   multiValued,
+		}
+	}
+	_, err := schemaSvc.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{
+		attribute("traits.tags", constants.StringDataType, true),
+		attribute("traits.scores", constants.IntegerDataType, true),
+		attribute("traits.segment", constants.StringDataType, false),
+	}, constants.Traits, orgHandle)
+	require.NoError(t, err)
+
+	vip, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{
+			"tags":    []interface{}{"vip", "newsletter"},
+			"scores":  []interface{}{10, 42},
+			"segment": "gold",
+		},
+	}, orgHandle)
+	require.NoError(t, err)
+	regular, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{
+			"tags":   []interface{}{"newsletter"},
+			"scores": []interface{}{7},
+		},
+	}, orgHandle)
+	require.NoError(t, err)
+
+	filter := func(filters ...string) []string {
+		profiles, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+			Filters:  filters,
+			Limit:    10,
+			AppScope: profileModel.AllApplications,
+		})
+		require.NoError(t, err)
+		ids := make([]string, 0, len(profiles))
+		for _, profile := range profiles {
+			ids = append(ids, profile.ProfileId)
+		}
+		return ids
+	}
+
+	t.Run("Matches_any_element_of_the_list", func(t *testing.T) {
+		require.Equal(t, []string{vip.ProfileId}, filter("traits.tags contains vip"))
+		require.ElementsMatch(t, []string{vip.ProfileId, regular.ProfileId}, filter("traits.tags contains newsletter"))
+		require.Empty(t, filter("traits.tags contains churned"))
+	})
+
+	t.Run("Coerces_the_value_to_the_element_type", func(t *testing.T) {
+		require.Equal(t, []string{vip.ProfileId}, filter("traits.scores contains 42"))
+		require.Equal(t, []string{regular.ProfileId}, filter("traits.scores contains 7", "traits.tags contains newsletter"))
+	})
+
+	t.Run("Rejects_invalid_contains_filters", func(t *testing.T) {
+		for _, f := range []string{
+			"traits.segment contains gold",
+			"traits.scores contains many",
+			"traits.undefined contains x",
+		} {
+			_, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+				Filters:  []string{f},
+				Limit:    10,
+				AppScope: profileModel.AllApplications,
+			})
+			var clientErr *errors2.ClientError
+			require.ErrorAs(t, err, &clientErr, f)
+			require.Equal(t, errors2.FILTER_PROFILE.Code, clientErr.Code, f)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Count(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-count-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	traits := []profileSchema.ProfileSchemaAttribute{
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.country",
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+		},
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.interests",
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "combine",
+			Mutability:    constants.MutabilityReadWrite,
+			MultiValued:   true,
+		},
+	}
+	_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(traits, constants.Traits,
+		orgHandle)
+	require.NoError(t, err)
+
+	for _, country := range []string{"US", "US", "US", "LK"} {
+		_, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{
+				"country":   country,
+				"interests": []interface{}{"count-" + country},
+			},
+		}, orgHandle)
+		require.NoError(t, err)
+	}
+	_, err = profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{"interests": []interface{}{"count-none"}},
+	}, orgHandle)
+	require.NoError(t, err)
+
+	t.Run("Counts_profiles_by_trait_value", func(t *testing.T) {
+		counts, err := profileSvc.CountProfilesGroupedBy(orgHandle, "country", nil, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, map[string]int64{"US": 3, "LK": 1}, counts)
+
+		prefixed, err := profileSvc.CountProfilesGroupedBy(orgHandle, "traits.country", nil, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, counts, prefixed)
+	})
+
+	t.Run("Counts_only_profiles_matching_the_filters", func(t *testing.T) {
+		counts, err := profileSvc.CountProfilesGroupedBy(orgHandle, "country",
+			[]string{"traits.interests co count-LK"}, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, map[string]int64{"LK": 1}, counts)
+	})
+
+	t.Run("Counts_are_scoped_to_the_organization", func(t *testing.T) {
+		otherOrg := orgHandle + "-other"
+		otherTraits := []profileSchema.ProfileSchemaAttribute{traits[0]}
+		otherTraits[0].OrgId = otherOrg
+		otherTraits[0].AttributeId = uuid.New().String()
+		_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(otherTraits,
+			constants.Traits, otherOrg)
+		require.NoError(t, err)
+		_, err = profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"country": "US"},
+		}, otherOrg)
+		require.NoError(t, err)
+
+		counts, err := profileSvc.CountProfilesGroupedBy(otherOrg, "country", nil, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, map[string]int64{"US": 1}, counts)
+	})
+
+	t.Run("Rejects_traits_that_cannot_be_grouped_by", func(t *testing.T) {
+		for _, trait := range []string{"", "nickname", "interests", "country;drop"} {
+			_, err := profileSvc.CountProfilesGroupedBy(orgHandle, trait, nil, profileModel.AllApplications)
+			var clientErr *errors2.ClientError
+			require.ErrorAs(t, err, &clientErr, trait)
+			require.Equal(t, http.StatusBadRequest, clientErr.StatusCode, trait)
+		}
+
+		_, err := profileSvc.CountProfilesGroupedBy(orgHandle, "country", []string{"traits.country gt 1"},
+			profileModel.AllApplications)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, errors2.FILTER_PROFILE.Code, clientErr.Code)
+	})
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_CSV_Export(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-csv-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	traits := []profileSchema.ProfileSchemaAttribute{
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.country",
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+		},
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.score",
+			ValueType:     constants.IntegerDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+		},
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.interests",
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "combine",
+			Mutability:    constants.MutabilityReadWrite,
+			MultiValued:   true,
+		},
+	}
+	_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(traits, constants.Traits,
+		orgHandle)
+	require.NoError(t, err)
+
+	lk, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{"country": "LK", "score": 7, "interests": []interface{}{"music", "chess"}},
+	}, orgHandle)
+	require.NoError(t, err)
+	us, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{"country": "=HYPERLINK(\"x\")"},
+	}, orgHandle)
+	require.NoError(t, err)
+
+	export := func(filters, fields []string) [][]string {
+		var out bytes.Buffer
+		err := profileSvc.ExportProfilesCSV(context.Background(), orgHandle, filters, fields,
+			profileModel.AllApplications, &out)
+		require.NoError(t, err)
+		records, err := csv.NewReader(&out).ReadAll()
+		require.NoError(t, err)
+		return records
+	}
+
+	t.Run("Exports_the_selected_traits_as_columns", func(t *testing.T) {
+		records := export(nil, []string{"profile_id", "traits.country", "traits.score", "traits.interests"})
+		require.Equal(t, []string{"profile_id", "traits.country", "traits.score", "traits.interests"}, records[0])
+		require.ElementsMatch(t, [][]string{
+			{us.ProfileId, "'=HYPERLINK(\"x\")", "", ""},
+			{lk.ProfileId, "LK", "7", `["music","chess"]`},
+		}, records[1:])
+	})
+
+	t.Run("Exports_only_profiles_matching_the_filters", func(t *testing.T) {
+		records := export([]string{"traits.country eq LK"}, []string{"profile_id"})
+		require.Equal(t, [][]string{{"profile_id"}, {lk.ProfileId}}, records)
+	})
+
+	t.Run("Exports_the_core_fields_by_default", func(t *testing.T) {
+		records := export(nil, nil)
+		require.Len(t, records, 3)
+		require.Equal(t, []string{"profile_id", "user_id", "created_at", "updated_at"}, records[0])
+	})
+
+	t.Run("Rejects_invalid_fields_before_writing", func(t *testing.T) {
+		var out bytes.Buffer
+		err := profileSvc.ExportProfilesCSV(context.Background(), orgHandle, nil,
+			[]string{"traits.country", "password", "traits.a;drop"}, profileModel.AllApplications, &out)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+		require.Equal(t, errors2.INVALID_EXPORT_FIELD.Code, clientErr.Code)
+		require.Len(t, clientErr.Details, 2)
+		require.Zero(t, out.Len())
+	})
+
+	t.Run("Resolves_merged_traits_across_chunks", func(t *testing.T) {
+		for i := 0; i < constants.ProfileExportFlushRows; i++ {
+			_, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+				Traits: map[string]interface{}{"country": "NP"},
+			}, orgHandle)
+			require.NoError(t, err)
+		}
+		master, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"country": "NP"},
+		}, orgHandle)
+		require.NoError(t, err)
+		child, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"score": 9},
+		}, orgHandle)
+		require.NoError(t, err)
+		require.NoError(t, profileSvc.MergeProfiles(master.ProfileId, child.ProfileId))
+
+		records := export([]string{"traits.country eq NP"}, []string{"profile_id", "traits.score"})
+		require.Len(t, records, constants.ProfileExportFlushRows+2, "The header and every matching profile")
+		require.Contains(t, records, []string{master.ProfileId, "9"}, "The trait of the merged profile is resolved")
+	})
+}
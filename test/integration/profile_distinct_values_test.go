@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Distinct_Trait_Values(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-distinct-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	traits := []profileSchema.ProfileSchemaAttribute{
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.country",
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+		},
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.interests",
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "combine",
+			Mutability:    constants.MutabilityReadWrite,
+			MultiValued:   true,
+		},
+	}
+	_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(traits, constants.Traits,
+		orgHandle)
+	require.NoError(t, err)
+
+	for _, country := range []string{"LK", "US", "US", "US", "IN", "IN"} {
+		_, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"country": country},
+		}, orgHandle)
+		require.NoError(t, err)
+	}
+	_, err = profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{"interests": []interface{}{"none"}},
+	}, orgHandle)
+	require.NoError(t, err)
+
+	t.Run("Lists_distinct_values_in_lexical_order", func(t *testing.T) {
+		values, err := profileSvc.GetDistinctTraitValues(orgHandle, "country", 10, false, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, []string{"IN", "LK", "US"}, values)
+	})
+
+	t.Run("Lists_the_most_common_values_first", func(t *testing.T) {
+		values, err := profileSvc.GetDistinctTraitValues(orgHandle, "traits.country", 10, true, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, []string{"US", "IN", "LK"}, values)
+	})
+
+	t.Run("Caps_the_values_at_the_limit", func(t *testing.T) {
+		values, err := profileSvc.GetDistinctTraitValues(orgHandle, "country", 2, true, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, []string{"US", "IN"}, values)
+	})
+
+	t.Run("Rejects_invalid_requests", func(t *testing.T) {
+		for _, trait := range []string{"", "nickname", "interests", "country;drop"} {
+			_, err := profileSvc.GetDistinctTraitValues(orgHandle, trait, 10, false, profileModel.AllApplications)
+			var clientErr *errors2.ClientError
+			require.ErrorAs(t, err, &clientErr, trait)
+			require.Equal(t, http.StatusBadRequest, clientErr.StatusCode, trait)
+		}
+
+		_, err := profileSvc.GetDistinctTraitValues(orgHandle, "country", 0, false, profileModel.AllApplications)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, errors2.INVALID_DISTINCT_VALUES_REQUEST.Code, clientErr.Code)
+	})
+}
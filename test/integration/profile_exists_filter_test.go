@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Existence_Filters(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-existsfilter-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	traits := []profileSchema.ProfileSchemaAttribute{
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.phone",
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+		},
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.email",
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+		},
+	}
+	_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(traits, constants.Traits,
+		orgHandle)
+	require.NoError(t, err)
+
+	withPhone, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{"phone": "+94770000000"},
+	}, orgHandle)
+	require.NoError(t, err)
+	withEmail, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{"email": "jane@example.com"},
+	}, orgHandle)
+	require.NoError(t, err)
+
+	filter := func(filters ...string) []string {
+		profiles, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+			Filters:  filters,
+			Limit:    10,
+			AppScope: profileModel.AllApplications,
+		})
+		require.NoError(t, err)
+		ids := make([]string, 0, len(profiles))
+		for _, profile := range profiles {
+			ids = append(ids, profile.ProfileId)
+		}
+		return ids
+	}
+
+	t.Run("Exists_matches_profiles_with_the_trait", func(t *testing.T) {
+		require.Equal(t, []string{withPhone.ProfileId}, filter("traits.phone exists"))
+	})
+
+	t.Run("Notexists_matches_profiles_without_the_trait", func(t *testing.T) {
+		require.Equal(t, []string{withPhone.ProfileId}, filter("traits.email notexists"))
+		require.Equal(t, []string{withEmail.ProfileId}, filter("traits.phone notexists", "traits.email exists"))
+	})
+
+	t.Run("Null_values_count_as_missing", func(t *testing.T) {
+		withNullPhone, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+		dbClient, err := provider.NewDBProvider().GetDBClient()
+		require.NoError(t, err)
+		defer dbClient.Close()
+		_, err = dbClient.ExecuteQuery(`UPDATE profiles SET traits = traits || '{"phone": null}' WHERE profile_id = $1`,
+			withNullPhone.ProfileId)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{withPhone.ProfileId}, filter("traits.phone exists"))
+		require.ElementsMatch(t, []string{withEmail.ProfileId, withNullPhone.ProfileId}, filter("traits.phone notexists"))
+	})
+
+	t.Run("Rejects_invalid_existence_filters", func(t *testing.T) {
+		for _, f := range []string{"traits.phone exists yes", "profile_id exists", "traits.phone;drop notexists"} {
+			_, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+				Filters:  []string{f},
+				Limit:    10,
+				AppScope: profileModel.AllApplications,
+			})
+			var clientErr *errors2.ClientError
+			require.ErrorAs(t, err, &clientErr, f)
+			require.Equal(t, errors2.FILTER_PROFILE.Code, clientErr.Code, f)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+)
+
+func Test_Profile_Hierarchy_Stats(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-hierarchystats-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	stats, err := profileSvc.GetHierarchyStats(orgHandle)
+	require.NoError(t, err)
+	require.Equal(t, profileModel.HierarchyStats{}, *stats, "An organization without profiles has empty statistics")
+
+	newProfile := func() string {
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+		return profile.ProfileId
+	}
+	merge := func(parent string, children ...string) {
+		references := make([]profileModel.Reference, 0, len(children))
+		for _, child := range children {
+			references = append(references, profileModel.Reference{ProfileId: child,
+				Reason: constants.ManualMergeReason})
+		}
+		require.NoError(t, profileStore.UpdateProfileReferences(profileModel.Profile{ProfileId: parent},
+			references))
+	}
+
+	// A master with three merged profiles, another with one, an unmerged profile and an orphan whose master is gone
+	large, small, orphanParent := newProfile(), newProfile(), newProfile()
+	newProfile()
+	merge(large, newProfile(), newProfile(), newProfile())
+	merge(small, newProfile())
+	orphan := newProfile()
+	merge(orphanParent, orphan)
+	dbClient, err := provider.NewDBProvider().GetDBClient()
+	require.NoError(t, err)
+	defer dbClient.Close()
+	_, err = dbClient.ExecuteQuery(`UPDATE profiles SET deleted_at = $1 WHERE profile_id = $2`,
+		time.Now().UTC(), orphanParent)
+	require.NoError(t, err)
+
+	stats, err = profileSvc.GetHierarchyStats(orgHandle)
+	require.NoError(t, err)
+	require.Equal(t, profileModel.HierarchyStats{
+		TotalProfiles:            8,
+		ReferenceProfiles:        3,
+		MergedProfiles:           5,
+		MasterProfiles:           2,
+		OrphanedProfiles:         1,
+		AverageChildrenPerMaster: 2,
+		LargestClusterSize:       4,
+	}, *stats)
+}
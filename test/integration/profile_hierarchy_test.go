@@ -0,0 +1,309 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+)
+
+func Test_Profile_Hierarchy(t *testing.T) {
+
+	// references maps a profile to the profile it is merged to. Profiles not in the map are reference profiles.
+	lookupFrom := func(references map[string]string) profileModel.ReferenceLookup {
+		return func(profileId string) (string, error) {
+			return references[profileId], nil
+		}
+	}
+
+	t.Run("Resolve_Valid_Hierarchy", func(t *testing.T) {
+		lookup := lookupFrom(map[string]string{"child": "master"})
+		root, err := profileModel.ResolveReferenceProfileId("child", lookup)
+		require.NoError(t, err)
+		require.Equal(t, "master", root)
+
+		require.NoError(t, profileModel.CheckReferenceLink("master", "other", lookup))
+	})
+
+	t.Run("Reject_Self_Reference", func(t *testing.T) {
+		lookup := lookupFrom(map[string]string{})
+		require.ErrorIs(t, profileModel.CheckReferenceLink("a", "a", lookup), profileModel.ErrProfileHierarchyCycle)
+
+		corrupted := lookupFrom(map[string]string{"a": "a"})
+		_, err := profileModel.ResolveReferenceProfileId("a", corrupted)
+		require.ErrorIs(t, err, profileModel.ErrProfileHierarchyCycle)
+	})
+
+	t.Run("Reject_Link_To_Descendant", func(t *testing.T) {
+		// b is merged to a, so a can not be merged to b
+		lookup := lookupFrom(map[string]string{"b": "a"})
+		require.ErrorIs(t, profileModel.CheckReferenceLink("b", "a", lookup), profileModel.ErrProfileHierarchyCycle)
+	})
+
+	t.Run("Detect_Corrupted_Cycle", func(t *testing.T) {
+		lookup := lookupFrom(map[string]string{"a": "b", "b": "c", "c": "a"})
+		_, err := profileModel.ResolveReferenceProfileId("a", lookup)
+		require.ErrorIs(t, err, profileModel.ErrProfileHierarchyCycle)
+		require.ErrorIs(t, profileModel.CheckReferenceLink("a", "x", lookup), profileModel.ErrProfileHierarchyCycle)
+	})
+
+	t.Run("Bound_Hierarchy_Depth", func(t *testing.T) {
+		references := map[string]string{}
+		for i := 0; i <= profileModel.MaxProfileHierarchyDepth; i++ {
+			references[fmt.Sprintf("p%d", i)] = fmt.Sprintf("p%d", i+1)
+		}
+		_, err := profileModel.ResolveReferenceProfileId("p0", lookupFrom(references))
+		require.ErrorIs(t, err, profileModel.ErrProfileHierarchyCycle)
+	})
+
+	t.Run("Resolve_Trait_Conflicts", func(t *testing.T) {
+		now := time.Now().UTC()
+		master := profileModel.Profile{
+			ProfileId:     "master",
+			Traits:        map[string]interface{}{"tier": "gold", "city": ""},
+			UpdatedAt:     now.Add(-time.Hour),
+			ProfileStatus: &profileModel.ProfileStatus{IsReferenceProfile: true},
+		}
+		byEmail := profileModel.Profile{
+			ProfileId:     "by-email",
+			Traits:        map[string]interface{}{"tier": "silver", "city": "Colombo", "age": 30},
+			UpdatedAt:     now,
+			ProfileStatus: &profileModel.ProfileStatus{ReferenceReason: "email_rule"},
+		}
+		byPhone := profileModel.Profile{
+			ProfileId:     "by-phone",
+			Traits:        map[string]interface{}{"city": "Kandy", "age": 40},
+			UpdatedAt:     now.Add(-2 * time.Hour),
+			ProfileStatus: &profileModel.ProfileStatus{ReferenceReason: "phone_rule"},
+		}
+		priorities := map[string]int{"phone_rule": 1, "email_rule": 2}
+		rulePriority := func(ruleName string) (int, bool) {
+			priority, ok := priorities[ruleName]
+			return priority, ok
+		}
+		children := []profileModel.Profile{byEmail, byPhone}
+
+		// The master wins, then the profile unified by the phone rule which has the higher priority
+		resolved := profileModel.ResolveHierarchyTraits(master, children, constants.TraitConflictHighestPriority,
+			rulePriority)
+		require.Equal(t, map[string]interface{}{"tier": "gold", "city": "", "age": 40}, resolved)
+
+		resolved = profileModel.ResolveHierarchyTraits(master, children, constants.TraitConflictLatestUpdated,
+			rulePriority)
+		require.Equal(t, map[string]interface{}{"tier": "silver", "city": "Colombo", "age": 30}, resolved)
+
+		resolved = profileModel.ResolveHierarchyTraits(master, children, constants.TraitConflictNonNull, rulePriority)
+		require.Equal(t, map[string]interface{}{"tier": "gold", "city": "Kandy", "age": 40}, resolved)
+
+		// Resolving does not modify the traits of the master
+		require.Equal(t, "", master.Traits["city"])
+		require.Equal(t, "silver", profileModel.ResolveTraitConflicts(master, byEmail,
+			constants.TraitConflictLatestUpdated)["tier"])
+	})
+
+	t.Run("GetProfile_With_Corrupted_Hierarchy", func(t *testing.T) {
+		orgHandle := fmt.Sprintf("carbon.super-hierarchy-%d", time.Now().UnixNano())
+		restore := schemaService.OverrideValidateApplicationIdentifierForTest(
+			func(appID, org string) (error, bool) { return nil, true })
+		defer restore()
+		profileSvc := profileService.GetProfilesService()
+
+		first, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+		second, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+
+		// Linking the profiles to each other is rejected by the store
+		require.NoError(t, profileStore.UpdateProfileReferences(profileModel.Profile{ProfileId: first.ProfileId},
+			[]profileModel.Reference{{ProfileId: second.ProfileId, Reason: constants.ManualMergeReason}}))
+		err = profileStore.UpdateProfileReferences(profileModel.Profile{ProfileId: second.ProfileId},
+			[]profileModel.Reference{{ProfileId: first.ProfileId, Reason: constants.ManualMergeReason}})
+		require.ErrorIs(t, err, profileModel.ErrProfileHierarchyCycle)
+
+		// Corrupt the hierarchy directly so that the profiles refer to each other
+		dbClient, err := provider.NewDBProvider().GetDBClient()
+		require.NoError(t, err)
+		defer dbClient.Close()
+		_, err = dbClient.ExecuteQuery(`UPDATE profile_reference SET profile_status = $1, reference_profile_id = $2 
+			WHERE profile_id = $3`, constants.MergedTo, second.ProfileId, first.ProfileId)
+		require.NoError(t, err)
+
+		_, err = profileSvc.GetProfile(second.ProfileId, profileModel.AllApplications)
+		require.ErrorIs(t, err, profileModel.ErrProfileHierarchyCycle)
+
+		for _, profileId := range []string{first.ProfileId, second.ProfileId} {
+			_, err = dbClient.ExecuteQuery(`DELETE FROM profile_reference WHERE profile_id = $1`, profileId)
+			require.NoError(t, err)
+			_ = profileStore.DeleteProfile(profileId)
+		}
+	})
+
+	t.Run("GetProfile_Reports_Master_Of_Nested_Hierarchy", func(t *testing.T) {
+		orgHandle := fmt.Sprintf("carbon.super-nested-%d", time.Now().UnixNano())
+		profileSvc := profileService.GetProfilesService()
+
+		var ids []string
+		for i := 0; i < 3; i++ {
+			profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+			require.NoError(t, err)
+			ids = append(ids, profile.ProfileId)
+		}
+		master, intermediate, leaf := ids[0], ids[1], ids[2]
+
+		// Link the leaf to the master through the intermediate profile
+		require.NoError(t, profileStore.UpdateProfileReferences(profileModel.Profile{ProfileId: master},
+			[]profileModel.Reference{{ProfileId: intermediate, Reason: constants.ManualMergeReason}}))
+		require.NoError(t, profileStore.UpdateProfileReferences(profileModel.Profile{ProfileId: intermediate},
+			[]profileModel.Reference{{ProfileId: leaf, Reason: constants.ManualMergeReason}}))
+
+		for _, profileId := range []string{intermediate, leaf} {
+			merged, err := profileSvc.GetProfile(profileId, profileModel.AllApplications)
+			require.NoError(t, err)
+			require.Equal(t, profileId, merged.ProfileId, "The merged view keeps the id of the requested profile")
+			require.NotNil(t, merged.MergedTo)
+			require.Equal(t, master, merged.MergedTo.ProfileId, "The merged view reports the master profile")
+			require.Equal(t, constants.ManualMergeReason, merged.MergedTo.Reason)
+		}
+	})
+
+	t.Run("Repair_Orphaned_Hierarchies", func(t *testing.T) {
+		orgHandle := fmt.Sprintf("carbon.super-orphans-%d", time.Now().UnixNano())
+		restore := schemaService.OverrideValidateApplicationIdentifierForTest(
+			func(appID, org string) (error, bool) { return nil, true })
+		defer restore()
+		profileSvc := profileService.GetProfilesService()
+
+		var created []string
+		newProfile := func() string {
+			profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+			require.NoError(t, err)
+			created = append(created, profile.ProfileId)
+			return profile.ProfileId
+		}
+		dbClient, err := provider.NewDBProvider().GetDBClient()
+		require.NoError(t, err)
+		defer dbClient.Close()
+		orphan := func(profileId string) {
+			_, err := dbClient.ExecuteQuery(`UPDATE profile_reference SET profile_status = $1, reference_profile_id = $2 
+				WHERE profile_id = $3`, constants.MergedTo, "missing-"+profileId, profileId)
+			require.NoError(t, err)
+		}
+
+		// Partially deleted hierarchy: the parent is deleted while its child is not
+		parent, child := newProfile(), newProfile()
+		require.NoError(t, profileStore.UpdateProfileReferences(profileModel.Profile{ProfileId: parent},
+			[]profileModel.Reference{{ProfileId: child, Reason: constants.ManualMergeReason}}))
+		_, err = dbClient.ExecuteQuery(`UPDATE profiles SET deleted_at = $1 WHERE profile_id = $2`,
+			time.Now().UTC(), parent)
+		require.NoError(t, err)
+
+		fetched, err := profileSvc.GetProfile(child, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, child, fetched.ProfileId)
+		require.Nil(t, fetched.MergedTo)
+		stored, err := profileStore.GetProfile(child)
+		require.NoError(t, err)
+		require.True(t, stored.ProfileStatus.IsReferenceProfile, "orphaned child should be promoted on read")
+
+		// Profiles merged to a profile that does not exist are not listed until they are repaired
+		orphans := []string{newProfile(), newProfile()}
+		for _, profileId := range orphans {
+			orphan(profileId)
+		}
+		repaired, err := profileSvc.RepairOrphanedProfiles(orgHandle)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, repaired)
+		listed, _, err := profileSvc.GetAllProfilesCursor(orgHandle, false, 50, nil, profileModel.AllApplications)
+		require.NoError(t, err)
+		listedIds := make([]string, 0, len(listed))
+		for _, profile := range listed {
+			listedIds = append(listedIds, profile.ProfileId)
+		}
+		require.Subset(t, listedIds, orphans)
+
+		// An orphaned profile can be deleted without its parent
+		deletable := newProfile()
+		orphan(deletable)
+		require.NoError(t, profileSvc.DeleteProfile(deletable))
+		deleted, err := profileStore.GetProfile(deletable)
+		require.NoError(t, err)
+		require.Nil(t, deleted)
+
+		for _, profileId := range created {
+			_ = profileStore.DeleteProfile(profileId)
+		}
+	})
+
+	t.Run("Failed_Deletion_Keeps_The_Hierarchy", func(t *testing.T) {
+		orgHandle := fmt.Sprintf("carbon.super-delete-rollback-%d", time.Now().UnixNano())
+		profileSvc := profileService.GetProfilesService()
+
+		var ids []string
+		for i := 0; i < 3; i++ {
+			profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+			require.NoError(t, err)
+			ids = append(ids, profile.ProfileId)
+		}
+		master, children := ids[0], ids[1:]
+		for _, child := range children {
+			require.NoError(t, profileStore.UpdateProfileReferences(profileModel.Profile{ProfileId: master},
+				[]profileModel.Reference{{ProfileId: child, Reason: constants.ManualMergeReason}}))
+		}
+
+		// Deleting the master, which happens after its children are deleted, fails
+		dbClient, err := provider.NewDBProvider().GetDBClient()
+		require.NoError(t, err)
+		defer dbClient.Close()
+		_, err = dbClient.ExecuteQuery(`CREATE OR REPLACE FUNCTION fail_profile_deletion() RETURNS trigger AS $$
+			BEGIN RAISE EXCEPTION 'deletion of % failed', NEW.profile_id; END; $$ LANGUAGE plpgsql`)
+		require.NoError(t, err)
+		_, err = dbClient.ExecuteQuery(fmt.Sprintf(`CREATE TRIGGER fail_profile_deletion BEFORE UPDATE ON profiles
+			FOR EACH ROW WHEN (NEW.profile_id = '%s' AND NEW.deleted_at IS NOT NULL)
+			EXECUTE FUNCTION fail_profile_deletion()`, master))
+		require.NoError(t, err)
+		dropTrigger := func() {
+			_, _ = dbClient.ExecuteQuery(`DROP TRIGGER IF EXISTS fail_profile_deletion ON profiles`)
+		}
+		defer dropTrigger()
+
+		require.Error(t, profileSvc.DeleteProfile(master))
+		for _, profileId := range ids {
+			stored, err := profileStore.GetProfile(profileId)
+			require.NoError(t, err)
+			require.NotNil(t, stored, "The deletion of profile: %s is rolled back", profileId)
+		}
+
+		dropTrigger()
+		require.NoError(t, profileSvc.DeleteProfile(master))
+		for _, profileId := range ids {
+			stored, err := profileStore.GetProfile(profileId)
+			require.NoError(t, err)
+			require.Nil(t, stored)
+		}
+	})
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+func Test_Profile_History(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-history-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	traits := []profileSchema.ProfileSchemaAttribute{
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.city",
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+		},
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.visits",
+			ValueType:     constants.IntegerDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+		},
+	}
+	_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(traits, constants.Traits,
+		orgHandle)
+	require.NoError(t, err)
+
+	created, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{"city": "Colombo"},
+	}, orgHandle)
+	require.NoError(t, err)
+
+	t.Run("A_new_profile_has_only_its_current_version", func(t *testing.T) {
+		history, err := profileSvc.GetProfileHistory(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		require.Equal(t, "Colombo", history[0].Traits["city"])
+		require.Empty(t, history[0].Changes)
+	})
+
+	t.Run("Each_change_of_traits_is_recorded_with_its_diff", func(t *testing.T) {
+		_, err := profileSvc.PatchProfile(context.Background(), created.ProfileId, orgHandle,
+			map[string]interface{}{"traits": map[string]interface{}{"city": "Kandy"}}, 0)
+		require.NoError(t, err)
+		_, err = profileSvc.IncrementAttribute(created.ProfileId, "traits.visits", 2)
+		require.NoError(t, err)
+
+		history, err := profileSvc.GetProfileHistory(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		for i := 1; i < len(history); i++ {
+			require.Greater(t, history[i].Version, history[i-1].Version)
+		}
+		require.Equal(t, []profileModel.TraitChange{
+			{Trait: "city", Change: profileModel.TraitModified, OldValue: "Colombo", NewValue: "Kandy"},
+		}, history[1].Changes)
+		require.Len(t, history[2].Changes, 1)
+		require.Equal(t, "visits", history[2].Changes[0].Trait)
+		require.Equal(t, profileModel.TraitAdded, history[2].Changes[0].Change)
+
+		current, err := profileSvc.GetProfile(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, current.Meta.Version, history[2].Version)
+	})
+
+	t.Run("Unknown_profile_has_no_history", func(t *testing.T) {
+		_, err := profileSvc.GetProfileHistory(uuid.New().String(), profileModel.AllApplications)
+		require.Error(t, err)
+	})
+
+	t.Run("History_is_pruned_by_count", func(t *testing.T) {
+		_, err := profileSvc.PruneProfileHistory(0, 1)
+		require.NoError(t, err)
+
+		history, err := profileSvc.GetProfileHistory(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Len(t, history, 2, "the latest earlier version and the current one are kept")
+		require.Empty(t, history[0].Changes)
+		require.Equal(t, "Kandy", history[0].Traits["city"])
+	})
+
+	t.Run("History_is_pruned_by_age", func(t *testing.T) {
+		_, err := profileSvc.PruneProfileHistory(time.Nanosecond, 0)
+		require.NoError(t, err)
+
+		history, err := profileSvc.GetProfileHistory(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+	})
+
+	t.Run("Nested_traits_are_compared_by_path", func(t *testing.T) {
+		changes := profileModel.DiffTraits(
+			map[string]interface{}{"address": map[string]interface{}{"city": "Colombo", "zip": "00100"},
+				"tags": []interface{}{"a"}},
+			map[string]interface{}{"address": map[string]interface{}{"city": "Galle"}, "tags": []interface{}{"a"},
+				"tier": "gold"})
+		require.Equal(t, []profileModel.TraitChange{
+			{Trait: "address.city", Change: profileModel.TraitModified, OldValue: "Colombo", NewValue: "Galle"},
+			{Trait: "address.zip", Change: profileModel.TraitRemoved, OldValue: "00100"},
+			{Trait: "tier", Change: profileModel.TraitAdded, NewValue: "gold"},
+		}, changes)
+	})
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Id_Strategy(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-profile-ids-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	conf := config.GetCDSRuntime().Config
+	withStrategy := func(strategy string) {
+		override := conf
+		override.ProfileIds.Strategy = strategy
+		config.OverrideCDSRuntime(override)
+	}
+	defer config.OverrideCDSRuntime(conf)
+
+	requireClientError := func(t *testing.T, err error, status int) {
+		var clientErr *errors2.ClientError
+		require.True(t, errors.As(err, &clientErr), "expected a client error, got: %v", err)
+		require.Equal(t, status, clientErr.StatusCode)
+	}
+
+	t.Run("Generators_follow_their_strategy", func(t *testing.T) {
+		for strategy, version := range map[string]uuid.Version{
+			constants.ProfileIdUUIDv4:         4,
+			constants.ProfileIdUUIDv7:         7,
+			constants.ProfileIdCallerProvided: 7,
+			"unknown":                         4,
+		} {
+			id, err := profileModel.ProfileIdGeneratorFor(strategy).NewProfileId()
+			require.NoError(t, err)
+			require.Equal(t, version, uuid.MustParse(id).Version(), strategy)
+		}
+
+		// Time-ordered ids sort in the order they were generated
+		generator := profileModel.ProfileIdGeneratorFor(constants.ProfileIdUUIDv7)
+		previous, err := generator.NewProfileId()
+		require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			next, err := generator.NewProfileId()
+			require.NoError(t, err)
+			require.Less(t, previous, next)
+			previous = next
+		}
+	})
+
+	t.Run("Created_profiles_get_ids_of_the_configured_strategy", func(t *testing.T) {
+		withStrategy(constants.ProfileIdUUIDv7)
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+		require.Equal(t, uuid.Version(7), uuid.MustParse(profile.ProfileId).Version())
+
+		withStrategy("")
+		profile, err = profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+		require.Equal(t, uuid.Version(4), uuid.MustParse(profile.ProfileId).Version())
+	})
+
+	t.Run("Ids_can_only_be_provided_when_the_caller_provides_them", func(t *testing.T) {
+		withStrategy(constants.ProfileIdUUIDv4)
+		_, err := profileSvc.CreateProfile(profileModel.ProfileRequest{ProfileId: "crm-" + uuid.NewString()}, orgHandle)
+		requireClientError(t, err, http.StatusBadRequest)
+
+		withStrategy(constants.ProfileIdCallerProvided)
+		requested := "crm-" + uuid.NewString()
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{ProfileId: requested}, orgHandle)
+		require.NoError(t, err)
+		require.Equal(t, requested, profile.ProfileId)
+
+		// Repeating the create merges into the profile, as a create racing the first one does
+		repeated, err := profileSvc.CreateProfile(profileModel.ProfileRequest{ProfileId: requested}, orgHandle)
+		require.NoError(t, err)
+		require.Equal(t, requested, repeated.ProfileId)
+
+		// The id is not available to other organizations, without telling that a profile has it
+		_, err = profileSvc.CreateProfile(profileModel.ProfileRequest{ProfileId: requested}, orgHandle+"-other")
+		requireClientError(t, err, http.StatusConflict)
+		var clientErr *errors2.ClientError
+		require.True(t, errors.As(err, &clientErr))
+		require.NotContains(t, clientErr.Description, "exists")
+
+		for _, invalid := range []string{"crm 1", "crm/1", strings.Repeat("x", constants.MaxProfileIdLength+1)} {
+			_, err = profileSvc.CreateProfile(profileModel.ProfileRequest{ProfileId: invalid}, orgHandle)
+			requireClientError(t, err, http.StatusBadRequest)
+		}
+
+		generated, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+		require.Equal(t, uuid.Version(7), uuid.MustParse(generated.ProfileId).Version())
+	})
+
+	t.Run("Id_of_a_profile_can_not_be_changed", func(t *testing.T) {
+		withStrategy(constants.ProfileIdUUIDv4)
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+
+		_, err = profileSvc.UpdateProfile(t.Context(), profile.ProfileId, orgHandle,
+			profileModel.ProfileRequest{ProfileId: uuid.NewString()}, 0)
+		requireClientError(t, err, http.StatusBadRequest)
+
+		updated, err := profileSvc.UpdateProfile(t.Context(), profile.ProfileId, orgHandle,
+			profileModel.ProfileRequest{ProfileId: profile.ProfileId}, 0)
+		require.NoError(t, err)
+		require.Equal(t, profile.ProfileId, updated.ProfileId)
+	})
+}
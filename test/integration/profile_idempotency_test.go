@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Idempotency_Key_Claims(t *testing.T) {
+
+	SuperTenantOrg := fmt.Sprintf("carbon.super-idempotency-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	ctx := context.Background()
+
+	conf := config.GetCDSRuntime().Config
+	defer config.OverrideCDSRuntime(conf)
+
+	t.Run("Claim_of_a_crashed_request_is_taken_over_after_its_lease", func(t *testing.T) {
+		key := "crashed-claim"
+		past := time.Now().UTC().Add(-time.Minute)
+		claimed, err := profileStore.ClaimIdempotencyKey(ctx, SuperTenantOrg, key, uuid.New().String(),
+			past, past.Add(time.Second))
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		profile, err := profileSvc.CreateProfileIdempotently(ctx, profileModel.ProfileRequest{}, SuperTenantOrg, key,
+			profileModel.AllApplications)
+		require.NoError(t, err, "An expired claim must not block the retry")
+
+		retried, err := profileSvc.CreateProfileIdempotently(ctx, profileModel.ProfileRequest{}, SuperTenantOrg, key,
+			profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, profile.ProfileId, retried.ProfileId, "The completed key outlives the claim lease")
+	})
+
+	t.Run("Retry_of_a_key_in_progress_fails_fast_by_default", func(t *testing.T) {
+		key := "in-progress"
+		now := time.Now().UTC()
+		claimed, err := profileStore.ClaimIdempotencyKey(ctx, SuperTenantOrg, key, uuid.New().String(),
+			now, now.Add(time.Minute))
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		start := time.Now()
+		_, err = profileSvc.CreateProfileIdempotently(ctx, profileModel.ProfileRequest{}, SuperTenantOrg, key,
+			profileModel.AllApplications)
+		var clientErr *errors2.ClientError
+		require.True(t, errors.As(err, &clientErr))
+		require.Equal(t, http.StatusConflict, clientErr.StatusCode)
+		require.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("Retry_waits_for_the_key_in_progress_to_complete", func(t *testing.T) {
+		waiting := conf
+		waiting.Idempotency.WaitTimeout = 5 * time.Second
+		config.OverrideCDSRuntime(waiting)
+		defer config.OverrideCDSRuntime(conf)
+
+		key, claimToken := "awaited", uuid.New().String()
+		now := time.Now().UTC()
+		claimed, err := profileStore.ClaimIdempotencyKey(ctx, SuperTenantOrg, key, claimToken, now, now.Add(time.Minute))
+		require.NoError(t, err)
+		require.True(t, claimed)
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, SuperTenantOrg)
+		require.NoError(t, err)
+
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			_ = profileStore.CompleteIdempotencyKey(SuperTenantOrg, key, claimToken, profile.ProfileId,
+				time.Now().Add(time.Hour))
+		}()
+		retried, err := profileSvc.CreateProfileIdempotently(ctx, profileModel.ProfileRequest{}, SuperTenantOrg, key,
+			profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, profile.ProfileId, retried.ProfileId)
+	})
+
+	t.Run("Request_whose_claim_was_taken_over_does_not_complete_the_key", func(t *testing.T) {
+		key, slowToken := "taken-over", uuid.New().String()
+		past := time.Now().UTC().Add(-time.Minute)
+		claimed, err := profileStore.ClaimIdempotencyKey(ctx, SuperTenantOrg, key, slowToken, past, past.Add(time.Second))
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		profile, err := profileSvc.CreateProfileIdempotently(ctx, profileModel.ProfileRequest{}, SuperTenantOrg, key,
+			profileModel.AllApplications)
+		require.NoError(t, err, "The expired claim is taken over")
+
+		slowProfile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, SuperTenantOrg)
+		require.NoError(t, err)
+		err = profileStore.CompleteIdempotencyKey(SuperTenantOrg, key, slowToken, slowProfile.ProfileId,
+			time.Now().Add(time.Hour))
+		require.Error(t, err, "The slow request no longer holds the key")
+		require.NoError(t, profileStore.ReleaseIdempotencyKey(SuperTenantOrg, key, slowToken))
+
+		retried, err := profileSvc.CreateProfileIdempotently(ctx, profileModel.ProfileRequest{}, SuperTenantOrg, key,
+			profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, profile.ProfileId, retried.ProfileId, "The key keeps the profile of the request that took it over")
+	})
+
+	t.Run("Expired_keys_are_purged", func(t *testing.T) {
+		now := time.Now().UTC()
+		past := now.Add(-time.Minute)
+		claimed, err := profileStore.ClaimIdempotencyKey(ctx, SuperTenantOrg, "abandoned", uuid.New().String(),
+			past, past.Add(time.Second))
+		require.NoError(t, err)
+		require.True(t, claimed)
+		claimed, err = profileStore.ClaimIdempotencyKey(ctx, SuperTenantOrg, "live", uuid.New().String(),
+			now, now.Add(time.Hour))
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		purged, err := profileSvc.PurgeExpiredIdempotencyKeys()
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, purged, int64(1))
+
+		dbClient, err := provider.NewDBProvider().GetDBClient()
+		require.NoError(t, err)
+		defer dbClient.Close()
+		rows, err := dbClient.ExecuteQuery(`SELECT idempotency_key FROM profile_idempotency_keys 
+			WHERE org_handle = $1 AND idempotency_key IN ('abandoned', 'live')`, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Len(t, rows, 1, "Only the live key must remain")
+		require.Equal(t, "live", rows[0]["idempotency_key"])
+	})
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Invalid_Filter(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-invalidfilter-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	_, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+	require.NoError(t, err)
+
+	requireInvalidFilter := func(t *testing.T, err error, filters ...string) {
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+		require.Equal(t, errors2.FILTER_PROFILE.Code, clientErr.Code)
+		for _, f := range filters {
+			require.Contains(t, clientErr.Description, fmt.Sprintf("%q", f))
+		}
+	}
+
+	t.Run("Unknown_properties_are_rejected_instead_of_ignored", func(t *testing.T) {
+		for _, f := range []string{"nickname eq john", "profile.nickname eq john", "traits. eq john"} {
+			_, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+				Filters:  []string{f},
+				Limit:    10,
+				AppScope: profileModel.AllApplications,
+			})
+			requireInvalidFilter(t, err, f)
+		}
+	})
+
+	t.Run("Every_invalid_clause_is_quoted", func(t *testing.T) {
+		filters := []string{"user_id eq someone", "traits.age gt 3", "nickname eq john"}
+		_, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+			Filters:  filters,
+			Limit:    10,
+			AppScope: profileModel.AllApplications,
+		})
+		requireInvalidFilter(t, err, filters[1], filters[2])
+		require.NotContains(t, err.(*errors2.ClientError).Description, filters[0])
+	})
+
+	t.Run("Bulk_operations_reject_unknown_properties", func(t *testing.T) {
+		_, err := profileSvc.DeleteProfilesByFilter(orgHandle, []string{"nickname eq john"})
+		requireInvalidFilter(t, err, "nickname eq john")
+
+		profiles, _, err := profileSvc.GetAllProfilesCursor(orgHandle, false, 10, nil, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Len(t, profiles, 1, "No profile is deleted by a rejected filter")
+	})
+}
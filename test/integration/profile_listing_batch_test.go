@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	schemaModel "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+func Test_Profile_Listing_Batched_Hierarchy(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-listing-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(
+		[]schemaModel.ProfileSchemaAttribute{
+			{OrgId: orgHandle, AttributeId: uuid.New().String(), AttributeName: "traits.interests",
+				ValueType: constants.StringDataType, MergeStrategy: "combine",
+				Mutability: constants.MutabilityReadWrite, MultiValued: true},
+		}, constants.Traits, orgHandle)
+	require.NoError(t, err)
+
+	create := func(interest string) string {
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"interests": []interface{}{interest}},
+		}, orgHandle)
+		require.NoError(t, err)
+		return profile.ProfileId
+	}
+	firstMaster, firstChild := create("music"), create("sports")
+	secondMaster, secondChild := create("travel"), create("art")
+	loneProfile := create("food")
+	require.NoError(t, profileSvc.MergeProfiles(firstMaster, firstChild))
+	require.NoError(t, profileSvc.MergeProfiles(secondMaster, secondChild))
+
+	t.Run("Store_fetches_references_and_profiles_in_batches", func(t *testing.T) {
+		references, err := profileStore.FetchReferencedProfilesBatch([]string{firstMaster, secondMaster, loneProfile})
+		require.NoError(t, err)
+		require.Len(t, references[firstMaster], 1)
+		require.Equal(t, firstChild, references[firstMaster][0].ProfileId)
+		require.Equal(t, constants.ManualMergeReason, references[firstMaster][0].Reason)
+		require.Len(t, references[secondMaster], 1)
+		require.Equal(t, secondChild, references[secondMaster][0].ProfileId)
+		require.NotContains(t, references, loneProfile)
+
+		profiles, err := profileStore.GetProfilesBatch([]string{firstChild, secondChild, uuid.New().String()})
+		require.NoError(t, err)
+		require.Len(t, profiles, 2)
+		require.Equal(t, firstMaster, profiles[firstChild].ProfileStatus.ReferenceProfileId)
+		require.Equal(t, secondMaster, profiles[secondChild].ProfileStatus.ReferenceProfileId)
+	})
+
+	t.Run("Listing_resolves_each_master_like_a_single_fetch", func(t *testing.T) {
+		listed, _, err := profileSvc.GetAllProfilesCursor(orgHandle, false, 10, nil, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Len(t, listed, 3, "Merged profiles must be listed only through their masters")
+
+		for _, profile := range listed {
+			fetched, err := profileSvc.GetProfile(profile.ProfileId, profileModel.AllApplications)
+			require.NoError(t, err)
+			require.ElementsMatch(t, fetched.Traits["interests"], profile.Traits["interests"])
+			require.Len(t, profile.MergedFrom, len(fetched.MergedFrom))
+		}
+
+		filtered, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+			Filters:  []string{"traits.interests eq art"},
+			Limit:    10,
+			AppScope: profileModel.AllApplications,
+		})
+		require.NoError(t, err)
+		require.Len(t, filtered, 1)
+		require.Equal(t, secondMaster, filtered[0].ProfileId)
+		require.Len(t, filtered[0].MergedFrom, 1)
+		require.Equal(t, secondChild, filtered[0].MergedFrom[0].ProfileId)
+	})
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+func Test_Profiles_Modified_Since(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-modifiedsince-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	addSchemaAttributes(t, orgHandle, constants.ApplicationData,
+		appDataAttribute(orgHandle, "storefront", "visits", constants.IntegerDataType))
+	newProfile := func() string {
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+		return profile.ProfileId
+	}
+	modifiedSince := func(since time.Time) []string {
+		profiles, hasMore, err := profileSvc.GetProfilesModifiedSince(orgHandle, since, profileModel.ProfileQuery{
+			Limit:    50,
+			AppScope: profileModel.AllApplications,
+		})
+		require.NoError(t, err)
+		require.False(t, hasMore)
+		ids := make([]string, 0, len(profiles))
+		for _, profile := range profiles {
+			ids = append(ids, profile.ProfileId)
+		}
+		return ids
+	}
+
+	untouched, patched, master, child := newProfile(), newProfile(), newProfile(), newProfile()
+	time.Sleep(10 * time.Millisecond)
+	mark := time.Now().UTC()
+	time.Sleep(10 * time.Millisecond)
+
+	t.Run("Nothing_is_modified_since_the_mark", func(t *testing.T) {
+		require.Empty(t, modifiedSince(mark))
+		require.ElementsMatch(t, []string{untouched, patched, master, child}, modifiedSince(mark.Add(-time.Hour)))
+	})
+
+	t.Run("Application_data_writes_mark_the_profile_updated", func(t *testing.T) {
+		require.NoError(t, profileSvc.PatchApplicationData(patched, "storefront", map[string]interface{}{"visits": 1}))
+		require.Equal(t, []string{patched}, modifiedSince(mark))
+
+		profile, err := profileSvc.GetProfile(patched, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.True(t, profile.Meta.UpdatedAt.After(mark))
+	})
+
+	t.Run("Merges_mark_the_master_updated_after_earlier_changes", func(t *testing.T) {
+		time.Sleep(10 * time.Millisecond)
+		require.NoError(t, profileSvc.MergeProfiles(master, child))
+		require.Equal(t, []string{patched, master}, modifiedSince(mark), "Least recently updated first")
+	})
+
+	t.Run("Filters_narrow_the_listing", func(t *testing.T) {
+		profiles, _, err := profileSvc.GetProfilesModifiedSince(orgHandle, mark, profileModel.ProfileQuery{
+			Filters:  []string{"profile_id eq " + master},
+			Limit:    50,
+			AppScope: profileModel.AllApplications,
+		})
+		require.NoError(t, err)
+		require.Len(t, profiles, 1)
+		require.Equal(t, master, profiles[0].ProfileId)
+	})
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Nested_Trait_Filters(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-nestedfilter-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	schemaSvc := schemaService.GetProfileSchemaService()
+
+	attribute := func(name, valueType string, multiValued bool,
+		subAttributes ...profileSchema.ProfileSchemaAttribute) profileSchema.ProfileSchemaAttribute {
+		attr := profileSchema.ProfileSchemaAttribute{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: name,
+			ValueType:     valueType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+			MultiValued:   multiValued,
+		}
+		for _, sub := range subAttributes {
+			attr.SubAttributes = append(attr.SubAttributes,
+				profileSchema.SubAttribute{AttributeId: sub.AttributeId, AttributeName: sub.AttributeName})
+		}
+		return attr
+	}
+	// Sub-attributes have to exist before the attributes that contain them.
+	zone := attribute("traits.address.geo.zone", constants.IntegerDataType, false)
+	tags := attribute("traits.address.geo.tags", constants.StringDataType, true)
+	city := attribute("traits.address.city", constants.StringDataType, false)
+	geo := attribute("traits.address.geo", constants.ComplexDataType, false, zone, tags)
+	address := attribute("traits.address", constants.ComplexDataType, false, city, geo)
+	for _, attrs := range [][]profileSchema.ProfileSchemaAttribute{{zone, tags, city}, {geo}, {address}} {
+		_, err := schemaSvc.AddProfileSchemaAttributesForScope(attrs, constants.Traits, orgHandle)
+		require.NoError(t, err)
+	}
+
+	boston, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{"address": map[string]interface{}{
+			"city": "Boston",
+			"geo":  map[string]interface{}{"zone": 7, "tags": []interface{}{"harbor", "historic"}},
+		}},
+	}, orgHandle)
+	require.NoError(t, err)
+	_, err = profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{"address": map[string]interface{}{
+			"city": "Denver",
+			"geo":  map[string]interface{}{"zone": 2, "tags": []interface{}{"mountain"}},
+		}},
+	}, orgHandle)
+	require.NoError(t, err)
+
+	filter := func(filters ...string) []string {
+		profiles, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+			Filters:  filters,
+			Limit:    10,
+			AppScope: profileModel.AllApplications,
+		})
+		require.NoError(t, err)
+		ids := make([]string, 0, len(profiles))
+		for _, profile := range profiles {
+			ids = append(ids, profile.ProfileId)
+		}
+		return ids
+	}
+
+	t.Run("Matches_nested_string_traits", func(t *testing.T) {
+		for _, f := range []string{
+			"traits.address.city eq Boston",
+			"traits.address.city eqi BOSTON",
+			"traits.address.city co ost",
+			"traits.address.city sw Bos",
+			"traits.address.city nei denver",
+			"traits.address.city exists",
+		} {
+			require.Contains(t, filter(f), boston.ProfileId, f)
+		}
+		require.Len(t, filter("traits.address.city exists"), 2)
+		require.Len(t, filter("traits.address.geo exists"), 2, "Objects are values too")
+		require.Empty(t, filter("traits.address.geo.zone notexists"))
+	})
+
+	t.Run("Coerces_deeply_nested_values_to_their_schema_type", func(t *testing.T) {
+		require.Equal(t, []string{boston.ProfileId}, filter("traits.address.geo.zone eq 7"))
+		require.Equal(t, []string{boston.ProfileId}, filter("traits.address.geo.zone gte 5"))
+		require.Equal(t, []string{boston.ProfileId}, filter("traits.address.geo.tags eq historic"))
+		require.Empty(t, filter("traits.address.geo.zone eq 3"))
+	})
+
+	t.Run("Rejects_values_that_do_not_fit_the_schema_type", func(t *testing.T) {
+		_, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+			Filters:  []string{"traits.address.geo.zone eq seven"},
+			Limit:    10,
+			AppScope: profileModel.AllApplications,
+		})
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, errors2.FILTER_PROFILE.Code, clientErr.Code)
+	})
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/utils"
+)
+
+func Test_Profile_Number_Precision(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-numbers-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	conf := config.GetCDSRuntime().Config
+	defer config.OverrideCDSRuntime(conf)
+	withPrecision := func(preserve bool) {
+		override := conf
+		override.Validation.PreserveNumberPrecision = preserve
+		config.OverrideCDSRuntime(override)
+	}
+
+	_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(
+		[]profileSchema.ProfileSchemaAttribute{
+			{
+				OrgId:         orgHandle,
+				AttributeId:   uuid.New().String(),
+				AttributeName: "traits.external_id",
+				ValueType:     constants.IntegerDataType,
+				MergeStrategy: "overwrite",
+				Mutability:    constants.MutabilityReadWrite,
+			},
+		}, constants.Traits, orgHandle)
+	require.NoError(t, err)
+
+	// 2^53 + 1 is the smallest integer a float64 can not hold.
+	const externalId = "9007199254740993"
+	body := `{"traits": {"external_id": ` + externalId + `}}`
+	decodeRequest := func() profileModel.ProfileRequest {
+		var req profileModel.ProfileRequest
+		require.NoError(t, utils.NewJSONDecoder(strings.NewReader(body)).Decode(&req))
+		return req
+	}
+
+	t.Run("Large_Integers_Are_Kept_Exact", func(t *testing.T) {
+		withPrecision(true)
+		created, err := profileSvc.CreateProfile(decodeRequest(), orgHandle)
+		require.NoError(t, err)
+
+		profile, err := profileSvc.GetProfile(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, json.Number(externalId), profile.Traits["external_id"])
+
+		encoded, err := json.Marshal(profile.Traits)
+		require.NoError(t, err)
+		require.Contains(t, string(encoded), externalId)
+	})
+
+	t.Run("Non_Integers_Are_Still_Rejected", func(t *testing.T) {
+		withPrecision(true)
+		var req profileModel.ProfileRequest
+		require.NoError(t, utils.NewJSONDecoder(strings.NewReader(`{"traits": {"external_id": 1.5}}`)).Decode(&req))
+		_, err := profileSvc.CreateProfile(req, orgHandle)
+		require.Error(t, err)
+	})
+
+	t.Run("Computed_Traits_Use_Exact_Numbers", func(t *testing.T) {
+		withPrecision(true)
+		computed := func(name, valueType, expression string) profileSchema.ProfileSchemaAttribute {
+			return profileSchema.ProfileSchemaAttribute{
+				OrgId:                 orgHandle,
+				AttributeId:           uuid.New().String(),
+				AttributeName:         name,
+				ValueType:             valueType,
+				MergeStrategy:         "overwrite",
+				Mutability:            constants.MutabilityReadOnly,
+				ComputationExpression: expression,
+			}
+		}
+		_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(
+			[]profileSchema.ProfileSchemaAttribute{
+				computed("traits.next_id", constants.IntegerDataType, "traits.external_id + 1"),
+				computed("traits.negated_id", constants.IntegerDataType, "-traits.external_id"),
+				computed("traits.id_band", constants.StringDataType, `traits.external_id > 1000 ? "large" : "small"`),
+				computed("traits.id_copy", constants.IntegerDataType, "traits.external_id"),
+			}, constants.Traits, orgHandle)
+		require.NoError(t, err)
+
+		created, err := profileSvc.CreateProfile(decodeRequest(), orgHandle)
+		require.NoError(t, err)
+		profile, err := profileSvc.GetProfile(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.NotNil(t, profile.Traits["next_id"], "Arithmetic on an exact number must not drop the trait")
+		require.NotNil(t, profile.Traits["negated_id"])
+		require.Equal(t, "large", profile.Traits["id_band"])
+		require.Equal(t, json.Number(externalId), profile.Traits["id_copy"])
+	})
+
+	t.Run("Numbers_Are_Floats_By_Default", func(t *testing.T) {
+		withPrecision(false)
+		req := decodeRequest()
+		require.IsType(t, float64(0), req.Traits["external_id"])
+		_, err := profileSvc.CreateProfile(req, orgHandle)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Range_Filters(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-rangefilter-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	attribute := func(name, valueType string) profileSchema.ProfileSchemaAttribute {
+		return profileSchema.ProfileSchemaAttribute{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: name,
+			ValueType:     valueType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+		}
+	}
+	traits := []profileSchema.ProfileSchemaAttribute{
+		attribute("traits.last_seen", constants.EpochDataType),
+		attribute("traits.signed_up", constants.DateTimeDataType),
+		attribute("traits.score", constants.IntegerDataType),
+		attribute("traits.nickname", constants.StringDataType),
+	}
+	_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(traits, constants.Traits,
+		orgHandle)
+	require.NoError(t, err)
+
+	early, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{"last_seen": "1700000000", "signed_up": "2023-01-15T10:00:00Z", "score": 5},
+	}, orgHandle)
+	require.NoError(t, err)
+	late, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		Traits: map[string]interface{}{"last_seen": "1710000000", "signed_up": "2024-06-01T08:30:00Z", "score": 50},
+	}, orgHandle)
+	require.NoError(t, err)
+
+	filter := func(filters ...string) []string {
+		profiles, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+			Filters:  filters,
+			Limit:    10,
+			AppScope: profileModel.AllApplications,
+		})
+		require.NoError(t, err)
+		ids := make([]string, 0, len(profiles))
+		for _, profile := range profiles {
+			ids = append(ids, profile.ProfileId)
+		}
+		return ids
+	}
+
+	t.Run("Filters_epoch_attributes_by_range", func(t *testing.T) {
+		require.Equal(t, []string{early.ProfileId}, filter("traits.last_seen between 1690000000 and 1705000000"))
+		require.Equal(t, []string{late.ProfileId}, filter("traits.last_seen gte 2024-01-01T00:00:00Z"))
+	})
+
+	t.Run("Filters_date_attributes_by_range", func(t *testing.T) {
+		require.Equal(t, []string{early.ProfileId}, filter("traits.signed_up lte 2023-12-31"))
+		require.Equal(t, []string{late.ProfileId}, filter("traits.signed_up gte 1704067200"))
+	})
+
+	t.Run("Filters_numeric_attributes_by_range", func(t *testing.T) {
+		require.Equal(t, []string{late.ProfileId}, filter("traits.score gte 10"))
+		require.ElementsMatch(t, []string{early.ProfileId, late.ProfileId}, filter("traits.score between 1 and 100"))
+	})
+
+	t.Run("Filters_by_creation_time", func(t *testing.T) {
+		require.ElementsMatch(t, []string{early.ProfileId, late.ProfileId},
+			filter(fmt.Sprintf("created_at gte %d", time.Now().Add(-time.Hour).Unix())))
+		require.Empty(t, filter("created_at lte 2000-01-01T00:00:00Z"))
+	})
+
+	t.Run("Skips_stored_values_that_are_not_timestamps", func(t *testing.T) {
+		malformed, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+		dbClient, err := provider.NewDBProvider().GetDBClient()
+		require.NoError(t, err)
+		defer dbClient.Close()
+		// The value has the form of a date, but is not one
+		_, err = dbClient.ExecuteQuery(`UPDATE profiles SET traits = traits || '{"signed_up": "2023-02-30T25:00:00Z"}'
+			WHERE profile_id = $1`, malformed.ProfileId)
+		require.NoError(t, err)
+
+		require.ElementsMatch(t, []string{early.ProfileId, late.ProfileId},
+			filter("traits.signed_up gte 2000-01-01T00:00:00Z"))
+	})
+
+	t.Run("Rejects_invalid_range_filters", func(t *testing.T) {
+		for _, f := range []string{
+			"traits.signed_up gte yesterday",
+			"traits.score between 1",
+			"traits.nickname gte a",
+			"traits.unknown lte 5",
+			"created_at eq 2024-01-01",
+		} {
+			_, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+				Filters:  []string{f},
+				Limit:    10,
+				AppScope: profileModel.AllApplications,
+			})
+			var clientErr *errors2.ClientError
+			require.ErrorAs(t, err, &clientErr, f)
+			require.Equal(t, errors2.FILTER_PROFILE.Code, clientErr.Code, f)
+		}
+	})
+}
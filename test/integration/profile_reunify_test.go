@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Reunify_All(t *testing.T) {
+
+	profileSvc := profileService.GetProfilesService()
+	waitForJob := func(jobId, orgHandle string) *profileModel.ProfileJob {
+		var job *profileModel.ProfileJob
+		require.Eventually(t, func() bool {
+			var err error
+			job, err = profileSvc.GetProfileJob(jobId, orgHandle)
+			require.NoError(t, err)
+			return job.Status != constants.ProfileJobRunning
+		}, 10*time.Second, 50*time.Millisecond, "Job %s should end", jobId)
+		return job
+	}
+
+	t.Run("Split_merged_profiles", func(t *testing.T) {
+		orgHandle := fmt.Sprintf("carbon.super-reunify-%d", time.Now().UnixNano())
+		newProfile := func() string {
+			profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+			require.NoError(t, err)
+			return profile.ProfileId
+		}
+		master, first, second, manual, alias := newProfile(), newProfile(), newProfile(), newProfile(), newProfile()
+		require.NoError(t, profileStore.UpdateProfileReferences(profileModel.Profile{ProfileId: master},
+			[]profileModel.Reference{
+				{ProfileId: first, Reason: "email_based"},
+				{ProfileId: second, Reason: "phone_based"},
+				{ProfileId: manual, Reason: constants.ManualMergeReason},
+				{ProfileId: alias, Reason: constants.AliasMergeReason},
+			}))
+
+		job, err := profileSvc.ReunifyAll(orgHandle)
+		require.NoError(t, err)
+		require.Equal(t, constants.ProfileJobRunning, job.Status)
+
+		job = waitForJob(job.JobId, orgHandle)
+		require.Equal(t, constants.ProfileJobCompleted, job.Status, job.Error)
+		require.Equal(t, constants.ProfileJobPhaseMerge, job.Phase)
+		require.Zero(t, job.MergedProfiles, "Nothing is merged again without active rules")
+
+		stats, err := profileSvc.GetHierarchyStats(orgHandle)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), stats.ReferenceProfiles)
+		require.Equal(t, int64(2), stats.MergedProfiles)
+		for _, profileId := range []string{first, second} {
+			profile, err := profileSvc.GetProfile(profileId, profileModel.AllApplications)
+			require.NoError(t, err)
+			require.Nil(t, profile.MergedTo, "Profile %s should be split from its master", profileId)
+		}
+		for _, profileId := range []string{manual, alias} {
+			profile, err := profileSvc.GetProfile(profileId, profileModel.AllApplications)
+			require.NoError(t, err)
+			require.NotNil(t, profile.MergedTo, "Profile %s was not merged by a rule", profileId)
+			require.Equal(t, master, profile.MergedTo.ProfileId)
+		}
+
+		_, err = profileSvc.GetProfileJob(job.JobId, "carbon.super-other")
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusNotFound, clientErr.StatusCode, "A job of another organization is not visible")
+	})
+
+	t.Run("Single_running_job_cancelled_on_resume", func(t *testing.T) {
+		orgHandle := fmt.Sprintf("carbon.super-reunify-%d", time.Now().UnixNano())
+		// A job left running by a stopped instance
+		startedAt := time.Now().UTC().Add(-2 * constants.ProfileJobStaleAfter)
+		stale := profileModel.ProfileJob{
+			JobId:     uuid.New().String(),
+			OrgHandle: orgHandle,
+			Type:      constants.ProfileJobReunifyAll,
+			Status:    constants.ProfileJobRunning,
+			Phase:     constants.ProfileJobPhaseSplit,
+			CreatedAt: startedAt,
+		}
+		added, err := profileStore.InsertProfileJob(stale)
+		require.NoError(t, err)
+		require.True(t, added)
+
+		_, err = profileSvc.ReunifyAll(orgHandle)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusConflict, clientErr.StatusCode, "Only one job may run for an organization")
+
+		job, err := profileSvc.CancelProfileJob(stale.JobId, orgHandle)
+		require.NoError(t, err)
+		require.True(t, job.CancelRequested)
+		require.Equal(t, constants.ProfileJobRunning, job.Status, "A job stops at its next checkpoint")
+
+		resumed, err := profileSvc.ResumeProfileJobs()
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, resumed, 1)
+		job = waitForJob(stale.JobId, orgHandle)
+		require.Equal(t, constants.ProfileJobCancelled, job.Status)
+
+		job, err = profileSvc.ReunifyAll(orgHandle)
+		require.NoError(t, err, "A job may start once the previous one ended")
+		require.Equal(t, constants.ProfileJobCompleted, waitForJob(job.JobId, orgHandle).Status)
+	})
+
+	t.Run("Split_in_progress_completes_before_cancelling", func(t *testing.T) {
+		orgHandle := fmt.Sprintf("carbon.super-reunify-%d", time.Now().UnixNano())
+		var masters, children []string
+		for i := 0; i < constants.ProfileJobBatchSize+1; i++ {
+			master, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+			require.NoError(t, err)
+			child, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+			require.NoError(t, err)
+			require.NoError(t, profileStore.UpdateProfileReferences(profileModel.Profile{ProfileId: master.ProfileId},
+				[]profileModel.Reference{{ProfileId: child.ProfileId, Reason: "email_based"}}))
+			masters, children = append(masters, master.ProfileId), append(children, child.ProfileId)
+		}
+
+		// A job stopped midway through the split, asked to be cancelled before it is resumed
+		stale := profileModel.ProfileJob{
+			JobId:     uuid.New().String(),
+			OrgHandle: orgHandle,
+			Type:      constants.ProfileJobReunifyAll,
+			Status:    constants.ProfileJobRunning,
+			Phase:     constants.ProfileJobPhaseSplit,
+			Processed: 1,
+			CreatedAt: time.Now().UTC().Add(-2 * constants.ProfileJobStaleAfter),
+		}
+		added, err := profileStore.InsertProfileJob(stale)
+		require.NoError(t, err)
+		require.True(t, added)
+		_, _, err = profileStore.UpdateProfileJobProgress(stale, stale.CreatedAt)
+		require.NoError(t, err)
+		_, err = profileSvc.CancelProfileJob(stale.JobId, orgHandle)
+		require.NoError(t, err)
+
+		_, err = profileSvc.ResumeProfileJobs()
+		require.NoError(t, err)
+		job := waitForJob(stale.JobId, orgHandle)
+		require.Equal(t, constants.ProfileJobCancelled, job.Status)
+		require.Equal(t, constants.ProfileJobPhaseMerge, job.Phase, "The job should stop once the split is done")
+
+		stats, err := profileSvc.GetHierarchyStats(orgHandle)
+		require.NoError(t, err)
+		require.Zero(t, stats.MergedProfiles, "Every cluster should be split, not only the first batch")
+		require.Equal(t, int64(len(masters)+len(children)), stats.ReferenceProfiles)
+	})
+}
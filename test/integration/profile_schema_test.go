@@ -2,6 +2,7 @@ package integration
 
 import (
 	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
 	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
 	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
 	"github.com/wso2/identity-customer-data-service/test/integration/utils"
 )
 
@@ -415,6 +417,31 @@ func Test_ProfileSchemaService(t *testing.T) {
 			require.Equal(t, "integer", patched.ValueType)
 			require.Equal(t, constants.MergeStrategyOverwrite, patched.MergeStrategy)
 		})
+
+		t.Run("Patch_ProfileSchemaAttribute_Rejects_Unknown_Columns", func(t *testing.T) {
+			attrId := uuid.New().String()
+			attr := createAttr(SuperTenantOrg, "identity_attributes.injection_field", constants.StringDataType, "combine", constants.MutabilityReadWrite)
+			attr.AttributeId = attrId
+
+			_, err := svc.AddProfileSchemaAttributesForScope([]model.ProfileSchemaAttribute{attr}, constants.IdentityAttributes, SuperTenantOrg)
+			require.NoError(t, err)
+
+			updates := map[string]interface{}{
+				"attribute_name":                     "identity_attributes.injection_field",
+				"value_type":                         constants.StringDataType,
+				"merge_strategy":                     constants.MergeStrategyOverwrite,
+				"mutability":                         constants.MutabilityReadWrite,
+				"mutability = 'x'; DROP TABLE users": "x",
+			}
+			err = svc.UpdateProfileSchemaAttributeById(SuperTenantOrg, attrId, updates, "")
+			var clientErr *errors2.ClientError
+			require.ErrorAs(t, err, &clientErr)
+			require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+
+			unchanged, err := svc.GetProfileSchemaAttributeById(SuperTenantOrg, attrId)
+			require.NoError(t, err)
+			require.Equal(t, constants.MutabilityReadWrite, unchanged.Mutability)
+		})
 	})
 
 	t.Run("Delete Operations", func(t *testing.T) {
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	schemaModel "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/unification_rules/model"
+	unificationService "github.com/wso2/identity-customer-data-service/internal/unification_rules/service"
+)
+
+func Test_Profile_Simulation(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-simulate-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	schemaSvc := schemaService.GetProfileSchemaService()
+	ctx := context.Background()
+
+	_, err := schemaSvc.AddProfileSchemaAttributesForScope([]schemaModel.ProfileSchemaAttribute{
+		{OrgId: orgHandle, AttributeId: uuid.New().String(), AttributeName: "identity_attributes.email",
+			ValueType: constants.StringDataType, MergeStrategy: "combine", Mutability: constants.MutabilityReadWrite,
+			MultiValued: true},
+	}, constants.IdentityAttributes, orgHandle)
+	require.NoError(t, err)
+	_, err = schemaSvc.AddProfileSchemaAttributesForScope([]schemaModel.ProfileSchemaAttribute{
+		{OrgId: orgHandle, AttributeId: uuid.New().String(), AttributeName: "traits.interests",
+			ValueType: constants.StringDataType, MergeStrategy: "combine", Mutability: constants.MutabilityReadWrite,
+			MultiValued: true},
+	}, constants.Traits, orgHandle)
+	require.NoError(t, err)
+
+	existing, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		IdentityAttributes: map[string]interface{}{"email": []interface{}{"john@example.com"}},
+		Traits:             map[string]interface{}{"interests": []interface{}{"music"}},
+	}, orgHandle)
+	require.NoError(t, err)
+
+	countProfiles := func() int {
+		profiles, _, err := profileSvc.GetAllProfilesCursor(orgHandle, false, 10, nil, profileModel.AllApplications)
+		require.NoError(t, err)
+		return len(profiles)
+	}
+
+	t.Run("Profile_without_a_match_is_computed", func(t *testing.T) {
+		simulation, err := profileSvc.SimulateProfile(ctx, profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"interests": []interface{}{"sports"}},
+		}, orgHandle, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Empty(t, simulation.Profile.ProfileId)
+		require.ElementsMatch(t, []interface{}{"sports"}, simulation.Profile.Traits["interests"])
+		require.Nil(t, simulation.Profile.MergedTo)
+		require.Nil(t, simulation.UnifiedProfile)
+	})
+
+	ruleId := uuid.New().String()
+	require.NoError(t, unificationService.GetUnificationRuleService().AddUnificationRule(model.UnificationRule{
+		RuleName:     "email_based",
+		RuleId:       ruleId,
+		OrgHandle:    orgHandle,
+		PropertyName: "identity_attributes.email",
+		Priority:     1,
+		IsActive:     true,
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+	}, orgHandle))
+
+	t.Run("Matching_profile_is_unified_without_being_stored", func(t *testing.T) {
+		simulation, err := profileSvc.SimulateProfile(ctx, profileModel.ProfileRequest{
+			IdentityAttributes: map[string]interface{}{"email": []interface{}{"john@example.com"}},
+			Traits:             map[string]interface{}{"interests": []interface{}{"sports"}},
+		}, orgHandle, profileModel.AllApplications)
+		require.NoError(t, err)
+
+		// Two temporary profiles are unified into a new master, which does not have an id yet
+		require.NotNil(t, simulation.Profile.MergedTo)
+		require.Empty(t, simulation.Profile.MergedTo.ProfileId)
+		require.Equal(t, ruleId, simulation.Profile.MergedTo.RuleId)
+		require.Equal(t, "john@example.com", simulation.Profile.MergedTo.MatchedValue)
+		require.NotNil(t, simulation.UnifiedProfile)
+		require.Empty(t, simulation.UnifiedProfile.ProfileId)
+		require.Len(t, simulation.UnifiedProfile.MergedFrom, 1)
+		require.Equal(t, existing.ProfileId, simulation.UnifiedProfile.MergedFrom[0].ProfileId)
+		require.ElementsMatch(t, []interface{}{"music", "sports"}, simulation.UnifiedProfile.Traits["interests"])
+
+		require.Equal(t, 1, countProfiles(), "A simulation must not store a profile")
+		stored, err := profileSvc.GetProfileFromPrimary(existing.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []interface{}{"music"}, stored.Traits["interests"])
+	})
+
+	t.Run("Permanent_profile_becomes_the_master_of_a_temporary_match", func(t *testing.T) {
+		simulation, err := profileSvc.SimulateProfile(ctx, profileModel.ProfileRequest{
+			UserId:             "simulated-user",
+			IdentityAttributes: map[string]interface{}{"email": []interface{}{"john@example.com"}},
+		}, orgHandle, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Nil(t, simulation.Profile.MergedTo)
+		require.Len(t, simulation.Profile.MergedFrom, 1)
+		require.Equal(t, existing.ProfileId, simulation.Profile.MergedFrom[0].ProfileId)
+		require.NotNil(t, simulation.UnifiedProfile)
+		require.Empty(t, simulation.UnifiedProfile.ProfileId)
+		require.Equal(t, "simulated-user", simulation.UnifiedProfile.UserId)
+	})
+
+	permanent, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		UserId:             "stored-user",
+		IdentityAttributes: map[string]interface{}{"email": []interface{}{"jane@example.com"}},
+	}, orgHandle)
+	require.NoError(t, err)
+
+	t.Run("Temporary_profile_is_merged_to_a_permanent_match", func(t *testing.T) {
+		simulation, err := profileSvc.SimulateProfile(ctx, profileModel.ProfileRequest{
+			IdentityAttributes: map[string]interface{}{"email": []interface{}{"jane@example.com"}},
+		}, orgHandle, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.NotNil(t, simulation.Profile.MergedTo)
+		require.Equal(t, permanent.ProfileId, simulation.Profile.MergedTo.ProfileId)
+		require.Equal(t, permanent.ProfileId, simulation.Profile.CanonicalProfileId)
+		require.NotNil(t, simulation.UnifiedProfile)
+		require.Equal(t, permanent.ProfileId, simulation.UnifiedProfile.ProfileId)
+		require.Equal(t, permanent.ProfileId, simulation.UnifiedProfile.CanonicalProfileId)
+		require.Equal(t, "stored-user", simulation.UnifiedProfile.UserId)
+	})
+
+	t.Run("Permanent_profiles_of_different_users_are_not_unified", func(t *testing.T) {
+		simulation, err := profileSvc.SimulateProfile(ctx, profileModel.ProfileRequest{
+			UserId:             "other-user",
+			IdentityAttributes: map[string]interface{}{"email": []interface{}{"jane@example.com"}},
+		}, orgHandle, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Nil(t, simulation.Profile.MergedTo)
+		require.Empty(t, simulation.Profile.MergedFrom)
+		require.Nil(t, simulation.UnifiedProfile)
+	})
+}
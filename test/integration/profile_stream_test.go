@@ -0,0 +1,206 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileHandler "github.com/wso2/identity-customer-data-service/internal/profile/handler"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Stream(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-stream-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+
+	traits := []profileSchema.ProfileSchemaAttribute{
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.tier",
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+		},
+	}
+	_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(traits, constants.Traits,
+		orgHandle)
+	require.NoError(t, err)
+
+	created := map[string]string{}
+	for _, tier := range []string{"gold", "gold", "silver"} {
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"tier": tier},
+		}, orgHandle)
+		require.NoError(t, err)
+		created[profile.ProfileId] = tier
+	}
+
+	stream := func(filters []string) map[string]string {
+		streamed := map[string]string{}
+		err := profileSvc.StreamProfiles(context.Background(), orgHandle, filters, profileModel.AllApplications,
+			func(profile profileModel.ProfileResponse) error {
+				streamed[profile.ProfileId] = profile.Traits["tier"].(string)
+				return nil
+			})
+		require.NoError(t, err)
+		return streamed
+	}
+
+	t.Run("Streams_every_profile", func(t *testing.T) {
+		require.Equal(t, created, stream(nil))
+	})
+
+	t.Run("Streams_only_profiles_matching_the_filters", func(t *testing.T) {
+		streamed := stream([]string{"traits.tier eq silver"})
+		require.Len(t, streamed, 1)
+		for _, tier := range streamed {
+			require.Equal(t, "silver", tier)
+		}
+	})
+
+	t.Run("Stops_when_the_handler_fails", func(t *testing.T) {
+		stop := errors.New("client went away")
+		calls := 0
+		err := profileSvc.StreamProfiles(context.Background(), orgHandle, nil, profileModel.AllApplications,
+			func(profile profileModel.ProfileResponse) error {
+				calls++
+				return stop
+			})
+		require.ErrorIs(t, err, stop)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("Rejects_invalid_filters_before_streaming", func(t *testing.T) {
+		err := profileSvc.StreamProfiles(context.Background(), orgHandle, []string{"traits.tier;drop eq x"},
+			profileModel.AllApplications,
+			func(profile profileModel.ProfileResponse) error {
+				t.Fatal("no profile should be streamed")
+				return nil
+			})
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+	})
+
+	caller := newAPICaller(t, orgHandle, "profile:view")
+	streamHandler := profileHandler.NewProfileHandler().StreamProfiles
+
+	t.Run("Handler_writes_the_profiles_as_one_json_array", func(t *testing.T) {
+		recorder := caller.call(httptest.NewRequest(http.MethodGet, "/profiles/stream", nil), streamHandler)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+		var streamed []map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &streamed))
+		require.Len(t, streamed, len(created))
+		for _, profile := range streamed {
+			require.Contains(t, created, profile["profile_id"])
+		}
+
+		query := url.Values{constants.Filter: {"traits.tier eq bronze"}}.Encode()
+		recorder = caller.call(httptest.NewRequest(http.MethodGet, "/profiles/stream?"+query, nil), streamHandler)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.JSONEq(t, "[]", recorder.Body.String())
+	})
+
+	t.Run("Handler_reports_invalid_filters_as_an_error_response", func(t *testing.T) {
+		query := url.Values{constants.Filter: {"traits.tier;drop eq x"}}.Encode()
+		recorder := caller.call(httptest.NewRequest(http.MethodGet, "/profiles/stream?"+query, nil), streamHandler)
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Handler_gives_callers_without_an_application_no_application_data", func(t *testing.T) {
+		appDataOrg := fmt.Sprintf("carbon.super-stream-appdata-%d", time.Now().UnixNano())
+		addSchemaAttributes(t, appDataOrg, constants.ApplicationData,
+			appDataAttribute(appDataOrg, "app-a", "theme", constants.StringDataType))
+		_, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			ApplicationData: map[string]map[string]interface{}{"app-a": {"theme": "dark"}},
+		}, appDataOrg)
+		require.NoError(t, err)
+
+		// The token of the caller does not identify an application
+		recorder := newAPICaller(t, appDataOrg, "profile:view").call(
+			httptest.NewRequest(http.MethodGet, "/profiles/stream", nil), streamHandler)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var streamed []map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &streamed))
+		require.Len(t, streamed, 1)
+		require.NotContains(t, streamed[0], "application_data")
+	})
+
+	t.Run("Handler_closes_a_failed_stream_with_an_error_trailer", func(t *testing.T) {
+		largeOrg := fmt.Sprintf("carbon.super-stream-large-%d", time.Now().UnixNano())
+		for i := 0; i <= constants.ProfileExportFlushRows; i++ {
+			_, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, largeOrg)
+			require.NoError(t, err)
+		}
+
+		// The request is cancelled once the first profiles reached the client, so reading the next chunk fails.
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		writer := &cancellingWriter{ResponseRecorder: httptest.NewRecorder(), cancel: cancel}
+		request := httptest.NewRequest(http.MethodGet, "/profiles/stream", nil).WithContext(ctx)
+		newAPICaller(t, largeOrg, "profile:view").serve(writer, request, streamHandler)
+
+		require.Equal(t, http.StatusOK, writer.Code)
+		var streamed []map[string]interface{}
+		require.NoError(t, json.Unmarshal(writer.Body.Bytes(), &streamed))
+		require.Len(t, streamed, constants.ProfileExportFlushRows+1)
+		for _, profile := range streamed[:constants.ProfileExportFlushRows] {
+			require.NotEmpty(t, profile["profile_id"])
+		}
+		trailer, ok := streamed[constants.ProfileExportFlushRows]["error"].(map[string]interface{})
+		require.True(t, ok, "the last element should be the error trailer")
+		require.NotEmpty(t, trailer["code"])
+		require.NotEmpty(t, trailer["message"])
+	})
+}
+
+// cancellingWriter cancels the request on its first write, giving the database a moment to abort the query.
+type cancellingWriter struct {
+	*httptest.ResponseRecorder
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (w *cancellingWriter) Write(p []byte) (int, error) {
+
+	w.once.Do(func() {
+		w.cancel()
+		time.Sleep(100 * time.Millisecond)
+	})
+	return w.ResponseRecorder.Write(p)
+}
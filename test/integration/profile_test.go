@@ -19,19 +19,41 @@
 package integration
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	profileHandler "github.com/wso2/identity-customer-data-service/internal/profile/handler"
 	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
 	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
 	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
 	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
 	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/tracing"
 	unificationService "github.com/wso2/identity-customer-data-service/internal/unification_rules/service"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func Test_Profile(t *testing.T) {
@@ -110,18 +132,136 @@ func Test_Profile(t *testing.T) {
 		require.NotNil(t, profile)
 		require.Equal(t, email, profile.IdentityAttributes["email"].([]interface{})[0])
 		require.Contains(t, profile.Traits["interests"], "reading")
+
+		// The response is built from the inserted row, so it must match what a read returns.
+		fetched, err := profileSvc.GetProfile(profile.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, fetched.Traits, profile.Traits)
+		require.Equal(t, fetched.IdentityAttributes, profile.IdentityAttributes)
+		require.Equal(t, fetched.Meta.Location, profile.Meta.Location)
+		require.True(t, fetched.Meta.CreatedAt.Equal(profile.Meta.CreatedAt))
+	})
+
+	t.Run("Create_Profile_Reports_All_Schema_Violations", func(t *testing.T) {
+		invalid := profileModel.ProfileRequest{
+			IdentityAttributes: map[string]interface{}{"email": 42},
+			Traits: map[string]interface{}{
+				"interests": "not-a-list",
+				"nickname":  "unknown",
+			},
+		}
+		_, err := profileSvc.CreateProfile(invalid, SuperTenantOrg)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+		require.Contains(t, clientErr.Description, "identity attribute 'email': type mismatch")
+		require.Contains(t, clientErr.Description, "trait 'interests': type mismatch")
+		require.Contains(t, clientErr.Description, "trait 'traits.nickname' not defined in schema")
+		fields := make([]string, 0, len(clientErr.Details))
+		for _, detail := range clientErr.Details {
+			fields = append(fields, detail.Field)
+		}
+		require.ElementsMatch(t, []string{"identity_attributes.email", "traits.interests", "traits.nickname"}, fields)
+
+		// Unknown attributes are stored as they are when passed through
+		conf := config.GetCDSRuntime().Config
+		passThroughConf := conf
+		passThroughConf.Validation.UnknownAttributes = constants.UnknownAttributesPassThrough
+		config.OverrideCDSRuntime(passThroughConf)
+		defer config.OverrideCDSRuntime(conf)
+
+		created, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"nickname": "unknown"},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Equal(t, "unknown", created.Traits["nickname"])
+		require.NoError(t, profileSvc.DeleteProfile(created.ProfileId))
+
+		// Unknown attributes are stored as strings when coerced, while known ones keep their types
+		coerceConf := conf
+		coerceConf.Validation.UnknownAttributes = constants.UnknownAttributesCoerce
+		config.OverrideCDSRuntime(coerceConf)
+
+		created, err = profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{
+				"nickname":  "unknown",
+				"age":       42,
+				"verified":  true,
+				"tags":      []interface{}{"a", "b"},
+				"interests": []interface{}{"reading"},
+			},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Equal(t, "unknown", created.Traits["nickname"])
+		require.Equal(t, "42", created.Traits["age"])
+		require.Equal(t, "true", created.Traits["verified"])
+		require.Equal(t, `["a","b"]`, created.Traits["tags"])
+		require.Equal(t, []interface{}{"reading"}, created.Traits["interests"])
+		require.NoError(t, profileSvc.DeleteProfile(created.ProfileId))
 	})
 
 	t.Run("Get_Profile_Success", func(t *testing.T) {
-		profiles, _, err := profileSvc.GetAllProfilesCursor(SuperTenantOrg, 10, nil)
+		profiles, _, err := profileSvc.GetAllProfilesCursor(SuperTenantOrg, false, 10, nil, profileModel.AllApplications)
 		require.NoError(t, err)
 		require.NotEmpty(t, profiles)
-		profile, err := profileSvc.GetProfile(profiles[0].ProfileId)
+		profile, err := profileSvc.GetProfile(profiles[0].ProfileId, profileModel.AllApplications)
 		require.NoError(t, err)
 		require.NotNil(t, profile)
 		require.Contains(t, profile.IdentityAttributes["email"], email)
 	})
 
+	t.Run("Profile_Is_Not_Visible_To_Other_Organizations", func(t *testing.T) {
+		profiles, _, err := profileSvc.GetAllProfilesCursor(SuperTenantOrg, false, 10, nil, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.NotEmpty(t, profiles)
+		require.NoError(t, profileSvc.EnsureProfileInOrg(profiles[0].ProfileId, SuperTenantOrg))
+
+		for _, profileId := range []string{profiles[0].ProfileId, uuid.New().String()} {
+			err = profileSvc.EnsureProfileInOrg(profileId, SuperTenantOrg+"-other")
+			var clientErr *errors2.ClientError
+			require.ErrorAs(t, err, &clientErr)
+			require.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+		}
+	})
+
+	t.Run("Trace_Profile_Retrieval", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		defer otel.SetTracerProvider(previous)
+
+		created, err := profileSvc.CreateProfile(profileRequest, SuperTenantOrg)
+		require.NoError(t, err)
+		ctx, root := tracing.Start(context.Background(), "request")
+		_, err = profileSvc.GetProfileContext(ctx, created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		root.End()
+
+		spans := make(map[string]sdktrace.ReadOnlySpan)
+		for _, span := range recorder.Ended() {
+			if span.SpanContext().TraceID() == root.SpanContext().TraceID() {
+				spans[span.Name()] = span
+			}
+		}
+		require.Contains(t, spans, "ProfilesService.GetProfile")
+		require.Contains(t, spans, "store.GetProfile")
+		require.Contains(t, spans, "db.query")
+		require.Contains(t, spans["store.GetProfile"].Attributes(), tracing.ProfileIdKey.String(created.ProfileId))
+		require.Equal(t, spans["ProfilesService.GetProfile"].SpanContext().SpanID(),
+			spans["store.GetProfile"].Parent().SpanID())
+		require.Contains(t, spans["db.query"].Attributes(), tracing.QueryNameKey.String("select profiles"))
+	})
+
+	t.Run("Get_Profile_Falls_Back_To_Primary_Without_Replica", func(t *testing.T) {
+		created, err := profileSvc.CreateProfile(profileRequest, SuperTenantOrg)
+		require.NoError(t, err)
+		fromPrimary, err := profileSvc.GetProfileFromPrimary(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		fromReplica, err := profileSvc.GetProfile(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, fromPrimary, fromReplica)
+	})
+
 	t.Run("Update_Profile_Success", func(t *testing.T) {
 		_, err := profileSvc.CreateProfile(profileRequest, SuperTenantOrg)
 		require.NoError(t, err)
@@ -137,34 +277,844 @@ func Test_Profile(t *testing.T) {
 	}`)
 		_ = json.Unmarshal(jsonData, &updatedRequest)
 
-		profiles, _, err := profileSvc.GetAllProfilesCursor(SuperTenantOrg, 10, nil)
+		profiles, _, err := profileSvc.GetAllProfilesCursor(SuperTenantOrg, false, 10, nil, profileModel.AllApplications)
 		require.NoError(t, err)
 		p := profiles[0]
 
-		updated, err := profileSvc.UpdateProfile(p.ProfileId, SuperTenantOrg, updatedRequest)
+		updated, err := profileSvc.UpdateProfile(context.Background(), p.ProfileId, SuperTenantOrg, updatedRequest, 0)
 		require.NoError(t, err)
 		require.Contains(t, updated.Traits["interests"], "travel")
 		require.Equal(t, "updated@wso2.com", updated.IdentityAttributes["email"].([]interface{})[0])
 	})
 
+	t.Run("Update_Profile_With_Expected_Version", func(t *testing.T) {
+		created, err := profileSvc.CreateProfile(profileRequest, SuperTenantOrg)
+		require.NoError(t, err)
+		read, err := profileSvc.GetProfile(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Positive(t, read.Meta.Version)
+
+		updated, err := profileSvc.PatchProfile(context.Background(), created.ProfileId, SuperTenantOrg,
+			map[string]interface{}{"traits": map[string]interface{}{"interests": []interface{}{"music"}}}, read.Meta.Version)
+		require.NoError(t, err)
+		require.Equal(t, read.Meta.Version+1, updated.Meta.Version)
+
+		// A second writer holding the old version must not overwrite the change
+		_, err = profileSvc.PatchProfile(context.Background(), created.ProfileId, SuperTenantOrg,
+			map[string]interface{}{"traits": map[string]interface{}{"interests": []interface{}{"chess"}}}, read.Meta.Version)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusConflict, clientErr.StatusCode)
+
+		current, err := profileSvc.GetProfile(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Contains(t, current.Traits["interests"], "music")
+		require.NotContains(t, current.Traits["interests"], "chess")
+	})
+
+	t.Run("Sort_Profiles_By_Trait", func(t *testing.T) {
+		_, err := profileSchemaSvc.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{
+			{
+				OrgId:         SuperTenantOrg,
+				AttributeId:   uuid.New().String(),
+				AttributeName: "traits.loyalty_points",
+				ValueType:     constants.IntegerDataType,
+				MergeStrategy: "overwrite",
+				Mutability:    constants.MutabilityReadWrite,
+			},
+		}, constants.Traits, SuperTenantOrg)
+		require.NoError(t, err)
+
+		for _, points := range []int{9, 120, 35} {
+			var req profileModel.ProfileRequest
+			_ = json.Unmarshal([]byte(fmt.Sprintf(`{"traits": {"loyalty_points": %d}}`, points)), &req)
+			_, err := profileSvc.CreateProfile(req, SuperTenantOrg)
+			require.NoError(t, err)
+		}
+
+		sort, err := profileModel.ParseProfileSort("traits.loyalty_points desc")
+		require.NoError(t, err)
+		profiles, _, err := profileSvc.GetAllProfilesWithFilterCursor(SuperTenantOrg, profileModel.ProfileQuery{
+			Sort:     sort,
+			Limit:    2,
+			AppScope: profileModel.AllApplications,
+		})
+		require.NoError(t, err)
+		require.Len(t, profiles, 2)
+		// Numeric ordering, not lexical: 120 must come before 35
+		require.EqualValues(t, 120, profiles[0].Traits["loyalty_points"])
+		require.EqualValues(t, 35, profiles[1].Traits["loyalty_points"])
+
+		invalidSort, err := profileModel.ParseProfileSort("traits.unknown asc")
+		require.NoError(t, err)
+		_, _, err = profileSvc.GetAllProfilesWithFilterCursor(SuperTenantOrg, profileModel.ProfileQuery{
+			Sort:     invalidSort,
+			Limit:    10,
+			AppScope: profileModel.AllApplications,
+		})
+		require.Error(t, err)
+
+		_, err = profileModel.ParseProfileSort("traits.loyalty_points sideways")
+		require.Error(t, err)
+	})
+
+	t.Run("Filter_Profiles_Normalizes_Values", func(t *testing.T) {
+		_, err := profileSchemaSvc.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{
+			{
+				OrgId:         SuperTenantOrg,
+				AttributeId:   uuid.New().String(),
+				AttributeName: "traits.city",
+				ValueType:     constants.StringDataType,
+				MergeStrategy: "overwrite",
+				Mutability:    constants.MutabilityReadWrite,
+			},
+		}, constants.Traits, SuperTenantOrg)
+		require.NoError(t, err)
+
+		// Stored in NFD ("u" + combining diaeresis) with trailing whitespace
+		created, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"city": "Zu\u0308rich \u00a0"},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Equal(t, "Z\u00fcrich", created.Traits["city"])
+
+		for _, value := range []string{"Z\u00fcrich", "Zu\u0308rich", "Z\u00fcrich\u00a0"} {
+			profiles, _, err := profileSvc.GetAllProfilesWithFilterCursor(SuperTenantOrg, profileModel.ProfileQuery{
+				Filters:  []string{"traits.city eq " + value},
+				Limit:    10,
+				AppScope: profileModel.AllApplications,
+			})
+			require.NoError(t, err)
+			require.Len(t, profiles, 1, "expected a match for %q", value)
+			require.Equal(t, created.ProfileId, profiles[0].ProfileId)
+		}
+	})
+
+	t.Run("Computed_Traits", func(t *testing.T) {
+		_, err := profileSchemaSvc.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{
+			{
+				OrgId:                 SuperTenantOrg,
+				AttributeId:           uuid.New().String(),
+				AttributeName:         "traits.loyalty_tier",
+				ValueType:             constants.StringDataType,
+				MergeStrategy:         "overwrite",
+				Mutability:            constants.MutabilityReadOnly,
+				ComputationExpression: `traits.loyalty_points >= 100 ? "gold" : "standard"`,
+			},
+		}, constants.Traits, SuperTenantOrg)
+		require.NoError(t, err)
+
+		created, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"loyalty_points": 150},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Equal(t, "gold", created.Traits["loyalty_tier"])
+
+		patched, err := profileSvc.PatchProfile(context.Background(), created.ProfileId, SuperTenantOrg, map[string]interface{}{
+			"traits": map[string]interface{}{"loyalty_points": 20},
+		}, 0)
+		require.NoError(t, err)
+		require.Equal(t, "standard", patched.Traits["loyalty_tier"])
+
+		// Computed traits are read-only
+		_, err = profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"loyalty_points": 1, "loyalty_tier": "gold"},
+		}, SuperTenantOrg)
+		require.Error(t, err)
+
+		_, err = profileSchemaSvc.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{
+			{
+				OrgId:                 SuperTenantOrg,
+				AttributeId:           uuid.New().String(),
+				AttributeName:         "traits.score_a",
+				ValueType:             constants.DecimalDataType,
+				MergeStrategy:         "overwrite",
+				Mutability:            constants.MutabilityReadOnly,
+				ComputationExpression: "traits.score_b + 1",
+			},
+			{
+				OrgId:                 SuperTenantOrg,
+				AttributeId:           uuid.New().String(),
+				AttributeName:         "traits.score_b",
+				ValueType:             constants.DecimalDataType,
+				MergeStrategy:         "overwrite",
+				Mutability:            constants.MutabilityReadOnly,
+				ComputationExpression: "traits.score_a * 2",
+			},
+		}, constants.Traits, SuperTenantOrg)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Contains(t, clientErr.Description, "cycle")
+
+		_, err = profileSchemaSvc.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{
+			{
+				OrgId:                 SuperTenantOrg,
+				AttributeId:           uuid.New().String(),
+				AttributeName:         "traits.score_c",
+				ValueType:             constants.DecimalDataType,
+				MergeStrategy:         "overwrite",
+				Mutability:            constants.MutabilityReadOnly,
+				ComputationExpression: "traits.undefined_trait / 2",
+			},
+		}, constants.Traits, SuperTenantOrg)
+		require.Error(t, err)
+	})
+
+	t.Run("Create_Profile_Idempotently", func(t *testing.T) {
+		request := profileModel.ProfileRequest{Traits: map[string]interface{}{"loyalty_points": 10}}
+		key := uuid.New().String()
+
+		first, err := profileSvc.CreateProfileIdempotently(context.Background(), request, SuperTenantOrg, key,
+			profileModel.AllApplications)
+		require.NoError(t, err)
+		retried, err := profileSvc.CreateProfileIdempotently(context.Background(), request, SuperTenantOrg, key,
+			profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, first.ProfileId, retried.ProfileId, "Retry should return the profile created first")
+
+		other, err := profileSvc.CreateProfileIdempotently(context.Background(), request, SuperTenantOrg,
+			uuid.New().String(), profileModel.AllApplications)
+		require.NoError(t, err)
+		require.NotEqual(t, first.ProfileId, other.ProfileId)
+
+		_, err = profileSvc.CreateProfileIdempotently(context.Background(), request, SuperTenantOrg,
+			strings.Repeat("k", 256), profileModel.AllApplications)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+	})
+
 	t.Run("Delete_Profile_Success", func(t *testing.T) {
-		profiles, _, err := profileSvc.GetAllProfilesCursor(SuperTenantOrg, 10, nil)
+		profiles, _, err := profileSvc.GetAllProfilesCursor(SuperTenantOrg, false, 10, nil, profileModel.AllApplications)
 		require.NoError(t, err)
 		p := profiles[0]
 
 		err = profileSvc.DeleteProfile(p.ProfileId)
 		require.NoError(t, err)
 
-		_, err = profileSvc.GetProfile(p.ProfileId)
+		_, err = profileSvc.GetProfile(p.ProfileId, profileModel.AllApplications)
+		require.Error(t, err)
+	})
+
+	t.Run("Restore_Deleted_Profile", func(t *testing.T) {
+		created, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"interests": []interface{}{"hiking"}},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+
+		require.NoError(t, profileSvc.DeleteProfile(created.ProfileId))
+		_, err = profileSvc.GetProfile(created.ProfileId, profileModel.AllApplications)
 		require.Error(t, err)
+
+		profiles, _, err := profileSvc.GetAllProfilesCursor(SuperTenantOrg, true, 50, nil, profileModel.AllApplications)
+		require.NoError(t, err)
+		found := false
+		for _, p := range profiles {
+			if p.ProfileId == created.ProfileId {
+				found = true
+				require.NotNil(t, p.Meta.DeletedAt)
+			}
+		}
+		require.True(t, found, "deleted profile should be listed when includeDeleted is set")
+
+		require.NoError(t, profileSvc.RestoreProfile(created.ProfileId))
+		restored, err := profileSvc.GetProfile(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, created.ProfileId, restored.ProfileId)
+
+		// Restoring a profile that is not deleted is a conflict
+		require.Error(t, profileSvc.RestoreProfile(created.ProfileId))
+
+		// Purging only removes profiles past the retention period
+		require.NoError(t, profileSvc.DeleteProfile(created.ProfileId))
+		purged, err := profileSvc.PurgeDeletedProfiles(constants.DeletedProfileRetentionPeriod)
+		require.NoError(t, err)
+		require.Zero(t, purged)
+		_, err = profileSvc.PurgeDeletedProfiles(0)
+		require.NoError(t, err)
+		require.Error(t, profileSvc.RestoreProfile(created.ProfileId))
+	})
+
+	t.Run("Delete_Profiles_By_Filter", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			_, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+				Traits: map[string]interface{}{"interests": []interface{}{"bulk-delete"}},
+			}, SuperTenantOrg)
+			require.NoError(t, err)
+		}
+
+		_, err := profileSvc.DeleteProfilesByFilter(SuperTenantOrg, nil)
+		require.Error(t, err, "deleting without a filter should be rejected")
+
+		_, err = profileSvc.DeleteProfilesByFilter(SuperTenantOrg,
+			[]string{"traits.interests co bulk-delete", "traits.interests gt 1", "bad filter"})
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, []errors2.FieldError{
+			{Field: "filter[1]", Message: "Unsupported operator: gt"},
+			{Field: "filter[2]", Message: "Invalid filter format when filtering profiles."},
+		}, clientErr.Details)
+
+		deleted, err := profileSvc.DeleteProfilesByFilter(SuperTenantOrg, []string{"traits.interests co bulk-delete"})
+		require.NoError(t, err)
+		require.EqualValues(t, 2, deleted)
+
+		remaining, _, err := profileSvc.GetAllProfilesWithFilterCursor(SuperTenantOrg, profileModel.ProfileQuery{
+			Filters:  []string{"traits.interests co bulk-delete"},
+			Limit:    50,
+			AppScope: profileModel.AllApplications,
+		})
+		require.NoError(t, err)
+		require.Empty(t, remaining)
+
+		deleted, err = profileSvc.DeleteProfilesByFilter(SuperTenantOrg, []string{"traits.interests co bulk-delete"})
+		require.NoError(t, err)
+		require.Zero(t, deleted)
+	})
+
+	t.Run("Import_Profiles_Stream", func(t *testing.T) {
+		// ~3MB of profile data streamed through the import pipeline
+		padding := strings.Repeat("x", 16*1024)
+		const total = 200
+
+		target, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"interests": []interface{}{"import-0"}},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+
+		records := make(chan profileModel.ProfileImportRecord)
+		go func() {
+			defer close(records)
+			for i := 1; i <= total; i++ {
+				record := profileModel.ProfileImportRecord{Line: i}
+				if i%10 == 0 {
+					// updates of the same profile must be applied in order
+					record.ProfileId = target.ProfileId
+					record.Traits = map[string]interface{}{"interests": []interface{}{fmt.Sprintf("import-%d", i)}}
+				} else {
+					record.Traits = map[string]interface{}{"interests": []interface{}{"import-stream", padding}}
+				}
+				records <- record
+			}
+			records <- profileModel.ProfileImportRecord{Line: total + 1, ProfileId: uuid.New().String()}
+		}()
+
+		seen := make(map[int]profileModel.ProfileImportResult)
+		for result := range profileSvc.ImportProfiles(context.Background(), SuperTenantOrg, constants.DefaultImportSource, records) {
+			seen[result.Line] = result
+		}
+		require.Len(t, seen, total+1)
+		for line := 1; line <= total; line++ {
+			require.Contains(t, []int{http.StatusCreated, http.StatusOK}, seen[line].Status, "line %d", line)
+			require.NotEmpty(t, seen[line].ProfileId)
+		}
+		require.Equal(t, http.StatusNotFound, seen[total+1].Status)
+
+		updated, err := profileSvc.GetProfile(target.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{fmt.Sprintf("import-%d", total)}, updated.Traits["interests"])
+	})
+
+	t.Run("Apply_Profiles_Batch", func(t *testing.T) {
+		target, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"interests": []interface{}{"batch-0"}},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+
+		record := func(profileId string, traits map[string]interface{}) profileModel.ProfileImportRecord {
+			return profileModel.ProfileImportRecord{ProfileId: profileId, ProfileRequest: profileModel.ProfileRequest{Traits: traits}}
+		}
+		records := []profileModel.ProfileImportRecord{
+			record("", map[string]interface{}{"interests": []interface{}{"batch-new"}}),
+			record(target.ProfileId, map[string]interface{}{"interests": []interface{}{"batch-1"}}),
+			record(uuid.New().String(), map[string]interface{}{"interests": []interface{}{"batch-missing"}}),
+			record("", map[string]interface{}{"undefined_trait": "x"}),
+			record(target.ProfileId, map[string]interface{}{"interests": []interface{}{"batch-2"}}),
+		}
+
+		results := profileSvc.ApplyProfilesBatch(context.Background(), SuperTenantOrg, constants.DefaultImportSource, records)
+		require.Len(t, results, len(records))
+		for i, result := range results {
+			require.Equal(t, i+1, result.Line)
+		}
+		require.Equal(t, http.StatusCreated, results[0].Status)
+		require.Equal(t, http.StatusOK, results[1].Status)
+		require.Equal(t, http.StatusNotFound, results[2].Status)
+		require.Equal(t, http.StatusBadRequest, results[3].Status)
+		require.Equal(t, http.StatusOK, results[4].Status)
+
+		// The handler reports a batch with failed records as a multi-status response with the result of every
+		// record, and a batch without failures as a plain success
+		caller := newAPICaller(t, SuperTenantOrg, "profile:create")
+		applyBatch := func(records []profileModel.ProfileImportRecord) *httptest.ResponseRecorder {
+			body, err := json.Marshal(records)
+			require.NoError(t, err)
+			return caller.call(httptest.NewRequest(http.MethodPost, "/profiles/batch", bytes.NewReader(body)),
+				profileHandler.NewProfileHandler().ApplyProfilesBatch)
+		}
+		var batchResponse struct {
+			Results []profileModel.ProfileImportResult `json:"results"`
+			Failed  int                                `json:"failed"`
+		}
+		recorder := applyBatch([]profileModel.ProfileImportRecord{
+			record("", map[string]interface{}{"interests": []interface{}{"batch-handler"}}),
+			record(uuid.New().String(), map[string]interface{}{"interests": []interface{}{"batch-missing"}}),
+			record("", map[string]interface{}{"undefined_trait": "x"}),
+		})
+		require.Equal(t, http.StatusMultiStatus, recorder.Code)
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&batchResponse))
+		require.Len(t, batchResponse.Results, 3)
+		require.Equal(t, http.StatusCreated, batchResponse.Results[0].Status)
+		require.Equal(t, http.StatusNotFound, batchResponse.Results[1].Status)
+		require.Equal(t, http.StatusBadRequest, batchResponse.Results[2].Status)
+		require.Equal(t, 2, batchResponse.Failed)
+
+		batchResponse.Results, batchResponse.Failed = nil, 0
+		recorder = applyBatch([]profileModel.ProfileImportRecord{
+			record("", map[string]interface{}{"interests": []interface{}{"batch-handler"}}),
+		})
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&batchResponse))
+		require.Len(t, batchResponse.Results, 1)
+		require.Equal(t, http.StatusCreated, batchResponse.Results[0].Status)
+		require.Zero(t, batchResponse.Failed)
+
+		// Updates of the same profile are applied in the order of the batch
+		updated, err := profileSvc.GetProfile(target.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"batch-2"}, updated.Traits["interests"])
+
+		// Records are not applied once the request is cancelled or has timed out
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+		for _, result := range profileSvc.ApplyProfilesBatch(cancelled, SuperTenantOrg, constants.DefaultImportSource, records) {
+			require.Equal(t, errors2.REQUEST_CANCELLED.Code, result.Code)
+		}
+		expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancelExpired()
+		for _, result := range profileSvc.ApplyProfilesBatch(expired, SuperTenantOrg, constants.DefaultImportSource, records) {
+			require.Equal(t, errors2.REQUEST_TIMEOUT.Code, result.Code)
+		}
+		updated, err = profileSvc.GetProfile(target.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"batch-2"}, updated.Traits["interests"])
+	})
+
+	t.Run("Import_Source_Quarantine", func(t *testing.T) {
+		conf := config.GetCDSRuntime().Config
+		quarantineConf := conf
+		quarantineConf.ImportQuarantine = config.ImportQuarantineConfig{
+			Enabled: true,
+			Sources: map[string]config.QuarantinePolicy{
+				"faulty-crm": {FailureRateThreshold: 0.5, MinRecords: 5, Window: time.Minute},
+			},
+		}
+		config.OverrideCDSRuntime(quarantineConf)
+		defer config.OverrideCDSRuntime(conf)
+
+		importRecords := func(build func(i int) profileModel.ProfileImportRecord, count int) []profileModel.ProfileImportResult {
+			records := make(chan profileModel.ProfileImportRecord)
+			go func() {
+				defer close(records)
+				for i := 1; i <= count; i++ {
+					record := build(i)
+					record.Line = i
+					records <- record
+				}
+			}()
+			var results []profileModel.ProfileImportResult
+			for result := range profileSvc.ImportProfiles(context.Background(), SuperTenantOrg, "faulty-crm", records) {
+				results = append(results, result)
+			}
+			return results
+		}
+		invalid := func(i int) profileModel.ProfileImportRecord {
+			return profileModel.ProfileImportRecord{ProfileRequest: profileModel.ProfileRequest{
+				Traits: map[string]interface{}{"undefined_trait": i},
+			}}
+		}
+		valid := func(i int) profileModel.ProfileImportRecord {
+			return profileModel.ProfileImportRecord{ProfileRequest: profileModel.ProfileRequest{
+				Traits: map[string]interface{}{"interests": []interface{}{"quarantine"}},
+			}}
+		}
+
+		// A burst of invalid records trips the quarantine
+		for _, result := range importRecords(invalid, 5) {
+			require.Equal(t, http.StatusBadRequest, result.Status)
+		}
+
+		// Further records of the source are held back instead of applied
+		for _, result := range importRecords(valid, 3) {
+			require.Equal(t, http.StatusAccepted, result.Status)
+			require.Equal(t, errors2.PROFILE_IMPORT_QUARANTINED.Code, result.Code)
+		}
+		quarantined, err := profileSvc.GetQuarantinedImportRecords(SuperTenantOrg, "faulty-crm")
+		require.NoError(t, err)
+		require.Len(t, quarantined, 3)
+
+		// Other sources are not affected
+		records := make(chan profileModel.ProfileImportRecord, 1)
+		records <- valid(1)
+		close(records)
+		for result := range profileSvc.ImportProfiles(context.Background(), SuperTenantOrg, constants.DefaultImportSource, records) {
+			require.Equal(t, http.StatusCreated, result.Status)
+		}
+
+		replayed, err := profileSvc.ReplayQuarantinedImportRecord(SuperTenantOrg, quarantined[0].RecordId)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, replayed.Status)
+		require.NoError(t, profileSvc.DiscardQuarantinedImportRecord(SuperTenantOrg, quarantined[1].RecordId))
+		require.Error(t, profileSvc.DiscardQuarantinedImportRecord(SuperTenantOrg, quarantined[1].RecordId))
+
+		remaining, err := profileSvc.GetQuarantinedImportRecords(SuperTenantOrg, "faulty-crm")
+		require.NoError(t, err)
+		require.Len(t, remaining, 1)
+
+		profileSvc.ReleaseImportSource(SuperTenantOrg, "faulty-crm")
+		for _, result := range importRecords(valid, 2) {
+			require.Equal(t, http.StatusCreated, result.Status)
+		}
+	})
+
+	t.Run("Increment_Attribute_Concurrently", func(t *testing.T) {
+		created, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"loyalty_points": 10},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+
+		const workers = 50
+		var wg sync.WaitGroup
+		errs := make(chan error, workers*2)
+		for i := 0; i < workers; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				_, err := profileSvc.IncrementAttribute(created.ProfileId, "traits.loyalty_points", 3)
+				errs <- err
+			}()
+			go func() {
+				defer wg.Done()
+				_, err := profileSvc.IncrementAttribute(created.ProfileId, "traits.loyalty_points", -1)
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		updated, err := profileSvc.GetProfile(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.EqualValues(t, 10+workers*2, updated.Traits["loyalty_points"])
+
+		// Missing attributes start from zero
+		other, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, SuperTenantOrg)
+		require.NoError(t, err)
+		value, err := profileSvc.IncrementAttribute(other.ProfileId, "traits.loyalty_points", 5)
+		require.NoError(t, err)
+		require.EqualValues(t, 5, value)
+
+		_, err = profileSvc.IncrementAttribute(created.ProfileId, "traits.loyalty_points", 0.5)
+		require.Error(t, err)
+		_, err = profileSvc.IncrementAttribute(created.ProfileId, "traits.interests", 1)
+		require.Error(t, err)
+		_, err = profileSvc.IncrementAttribute(uuid.New().String(), "traits.loyalty_points", 1)
+		require.Error(t, err)
+	})
+
+	t.Run("Increment_Attribute_Recomputes_Computed_Traits", func(t *testing.T) {
+		_, err := profileSchemaSvc.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{
+			{
+				OrgId:         SuperTenantOrg,
+				AttributeId:   uuid.New().String(),
+				AttributeName: "traits.stamp_count",
+				ValueType:     constants.IntegerDataType,
+				MergeStrategy: "overwrite",
+				Mutability:    constants.MutabilityReadWrite,
+			},
+			{
+				OrgId:         SuperTenantOrg,
+				AttributeId:   uuid.New().String(),
+				AttributeName: "traits.visit_count",
+				ValueType:     constants.IntegerDataType,
+				MergeStrategy: "overwrite",
+				Mutability:    constants.MutabilityReadWrite,
+			},
+			{
+				OrgId:                 SuperTenantOrg,
+				AttributeId:           uuid.New().String(),
+				AttributeName:         "traits.stamp_card",
+				ValueType:             constants.StringDataType,
+				MergeStrategy:         "overwrite",
+				Mutability:            constants.MutabilityReadOnly,
+				ComputationExpression: `traits.stamp_count >= 10 ? "full" : "open"`,
+			},
+		}, constants.Traits, SuperTenantOrg)
+		require.NoError(t, err)
+
+		created, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"stamp_count": 8},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Equal(t, "open", created.Traits["stamp_card"])
+
+		// Incrementing a trait no computed trait depends on leaves the computed traits as they are
+		_, err = profileSvc.IncrementAttribute(created.ProfileId, "traits.visit_count", 1)
+		require.NoError(t, err)
+		current, err := profileSvc.GetProfile(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, "open", current.Traits["stamp_card"])
+
+		value, err := profileSvc.IncrementAttribute(created.ProfileId, "traits.stamp_count", 2)
+		require.NoError(t, err)
+		require.EqualValues(t, 10, value)
+		current, err = profileSvc.GetProfile(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.EqualValues(t, 10, current.Traits["stamp_count"])
+		require.Equal(t, "full", current.Traits["stamp_card"])
+
+		// The increment and the computed trait it changed are recorded as a single version
+		history, err := profileSvc.GetProfileHistory(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		require.Equal(t, current.Meta.Version, history[2].Version)
+		var changed []string
+		for _, change := range history[2].Changes {
+			changed = append(changed, change.Trait)
+		}
+		require.ElementsMatch(t, []string{"stamp_count", "stamp_card"}, changed)
+	})
+
+	t.Run("Export_Portable_Profile", func(t *testing.T) {
+		certFile, keyFile := writeSigningKeyPair(t, t.TempDir())
+		conf := config.GetCDSRuntime().Config
+		exportConf := conf
+		exportConf.PortableExport = config.PortableExportConfig{SigningCert: certFile, SigningKey: keyFile}
+		config.OverrideCDSRuntime(exportConf)
+		defer config.OverrideCDSRuntime(conf)
+
+		created, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"interests": []interface{}{"portability"}},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+
+		exported, err := profileSvc.ExportPortableProfile(created.ProfileId)
+		require.NoError(t, err)
+
+		manifest, content, _, err := profileService.VerifyPortableProfile(exported)
+		require.NoError(t, err)
+		require.Equal(t, created.ProfileId, manifest.ProfileId)
+		require.Equal(t, SuperTenantOrg, manifest.OrgHandle)
+		require.Equal(t, created.ProfileId, content.Profile.ProfileId)
+		require.Equal(t, []interface{}{"portability"}, content.Profile.Traits["interests"])
+		require.Contains(t, content.Schema, constants.Traits)
+
+		// Altering the content or the manifest breaks verification
+		var pkg profileModel.PortablePackage
+		require.NoError(t, json.Unmarshal(exported, &pkg))
+		tampered := pkg
+		tampered.Content = []byte(strings.Replace(string(pkg.Content), "portability", "tampered", 1))
+		tamperedBytes, err := json.Marshal(tampered)
+		require.NoError(t, err)
+		_, _, _, err = profileService.VerifyPortableProfile(tamperedBytes)
+		require.Error(t, err)
+
+		tampered = pkg
+		tampered.Manifest = []byte(strings.Replace(string(pkg.Manifest), created.ProfileId, uuid.New().String(), 1))
+		tamperedBytes, err = json.Marshal(tampered)
+		require.NoError(t, err)
+		_, _, _, err = profileService.VerifyPortableProfile(tamperedBytes)
+		require.Error(t, err)
+
+		_, err = profileSvc.ExportPortableProfile(uuid.New().String())
+		require.Error(t, err)
+	})
+
+	t.Run("Get_Profile_Projected", func(t *testing.T) {
+		master, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			IdentityAttributes: map[string]interface{}{"email": []interface{}{"projection@wso2.com"}},
+			Traits: map[string]interface{}{
+				"interests":      []interface{}{"projection"},
+				"loyalty_points": 42,
+			},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+
+		projection, err := profileSvc.GetProfileProjected(master.ProfileId, []string{"traits.loyalty_points",
+			"identity_attributes.email"}, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, master.ProfileId, projection.ProfileId)
+		require.Equal(t, map[string]interface{}{"loyalty_points": float64(42)}, projection.Traits)
+		require.Equal(t, map[string]interface{}{"email": []interface{}{"projection@wso2.com"}},
+			projection.IdentityAttributes)
+
+		// A merged profile is projected from its reference profile
+		child, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, SuperTenantOrg)
+		require.NoError(t, err)
+		require.NoError(t, profileSvc.MergeProfiles(master.ProfileId, child.ProfileId))
+		projection, err = profileSvc.GetProfileProjected(child.ProfileId, []string{"traits.interests"},
+			profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, child.ProfileId, projection.ProfileId)
+		require.Equal(t, []interface{}{"projection"}, projection.Traits["interests"])
+		require.Empty(t, projection.IdentityAttributes)
+
+		var clientErr *errors2.ClientError
+		_, err = profileSvc.GetProfileProjected(master.ProfileId, []string{"traits.address.city"},
+			profileModel.AllApplications)
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+		_, err = profileSvc.GetProfileProjected(uuid.New().String(), []string{"traits.interests"},
+			profileModel.AllApplications)
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+	})
+
+	t.Run("Resolve_Profile_By_Identifier", func(t *testing.T) {
+		first, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			IdentityAttributes: map[string]interface{}{"email": []interface{}{"resolve@wso2.com"}},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+		second, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			IdentityAttributes: map[string]interface{}{"email": []interface{}{"other@wso2.com", "resolve@wso2.com"}},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+
+		// Profiles that are not unified are all returned as candidates
+		candidates, err := profileSvc.ResolveProfileByIdentifier(SuperTenantOrg, "email", "resolve@wso2.com",
+			profileModel.AllApplications)
+		require.NoError(t, err)
+		ids := make([]string, 0, len(candidates))
+		for _, candidate := range candidates {
+			ids = append(ids, candidate.ProfileId)
+		}
+		require.ElementsMatch(t, []string{first.ProfileId, second.ProfileId}, ids)
+
+		require.NoError(t, profileSvc.MergeProfiles(first.ProfileId, second.ProfileId))
+		resolved, err := profileSvc.ResolveProfileByIdentifier(SuperTenantOrg, "email", "other@wso2.com",
+			profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Len(t, resolved, 1)
+		require.Equal(t, first.ProfileId, resolved[0].ProfileId, "The merged profile should resolve to its master")
+
+		var clientErr *errors2.ClientError
+		_, err = profileSvc.ResolveProfileByIdentifier(SuperTenantOrg, "email", "unknown@wso2.com",
+			profileModel.AllApplications)
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+		_, err = profileSvc.ResolveProfileByIdentifier(SuperTenantOrg, "unknown_attribute", "resolve@wso2.com",
+			profileModel.AllApplications)
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+	})
+
+	t.Run("Validate_Profile_Sync_Event", func(t *testing.T) {
+		require.NoError(t, profileService.ValidateProfileSync(profileModel.ProfileSync{
+			Event: constants.AddUserEvent, UserId: "sync-user", OrgHandle: SuperTenantOrg,
+		}))
+
+		err := profileService.ValidateProfileSync(profileModel.ProfileSync{Event: "POST_UNKNOWN_EVENT"})
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+		fields := make([]string, 0, len(clientErr.Details))
+		for _, detail := range clientErr.Details {
+			fields = append(fields, detail.Field)
+		}
+		require.ElementsMatch(t, []string{"event", "orgHandle", "userId"}, fields)
+	})
+
+	t.Run("Anonymize_Profile_Replaces_PII", func(t *testing.T) {
+		_, err := profileSchemaSvc.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{
+			{
+				OrgId:         SuperTenantOrg,
+				AttributeId:   uuid.New().String(),
+				AttributeName: "traits.nickname",
+				ValueType:     constants.StringDataType,
+				MergeStrategy: "overwrite",
+				Mutability:    constants.MutabilityReadWrite,
+				IsPII:         true,
+			},
+		}, constants.Traits, SuperTenantOrg)
+		require.NoError(t, err)
+
+		master, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"nickname": "anon-master", "interests": []interface{}{"reporting"}},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+		child, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"nickname": "anon-child"},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+		require.NoError(t, profileSvc.MergeProfiles(master.ProfileId, child.ProfileId))
+		_, err = profileSvc.PatchProfile(context.Background(), master.ProfileId, SuperTenantOrg,
+			map[string]interface{}{"traits": map[string]interface{}{"nickname": "anon-renamed"}}, 0)
+		require.NoError(t, err)
+
+		// Anonymizing any profile of the person covers all of them
+		require.NoError(t, profileSvc.AnonymizeProfile(child.ProfileId))
+		exported, err := profileSvc.ExportProfile(master.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		var export profileModel.ProfileExport
+		require.NoError(t, json.Unmarshal(exported, &export))
+		require.True(t, strings.HasPrefix(export.Profile.Traits["nickname"].(string), constants.AnonymizedValuePrefix))
+		require.Equal(t, []interface{}{"reporting"}, export.Profile.Traits["interests"], "Non PII data should be kept")
+		require.Len(t, export.ChildProfiles, 1)
+		require.True(t, strings.HasPrefix(export.ChildProfiles[0].Traits["nickname"].(string),
+			constants.AnonymizedValuePrefix))
+
+		// The earlier versions holding the erased values are not kept in the history
+		for _, profileId := range []string{master.ProfileId, child.ProfileId} {
+			history, err := profileSvc.GetProfileHistory(profileId, profileModel.AllApplications)
+			require.NoError(t, err)
+			require.Len(t, history, 1, "Only the anonymized version should remain")
+			require.True(t, strings.HasPrefix(history[0].Traits["nickname"].(string), constants.AnonymizedValuePrefix))
+		}
+
+		err = profileSvc.AnonymizeProfile(uuid.New().String())
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+	})
+
+	t.Run("Export_Profile_Follows_Merged_Profiles", func(t *testing.T) {
+		master, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"interests": []interface{}{"export-master"}},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+		child, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"interests": []interface{}{"export-child"}},
+		}, SuperTenantOrg)
+		require.NoError(t, err)
+		require.NoError(t, profileSvc.MergeProfiles(master.ProfileId, child.ProfileId))
+
+		// Exporting the merged profile exports the whole person
+		exported, err := profileSvc.ExportProfile(child.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		var export profileModel.ProfileExport
+		require.NoError(t, json.Unmarshal(exported, &export))
+		require.Equal(t, master.ProfileId, export.ProfileId)
+		require.Equal(t, SuperTenantOrg, export.OrgHandle)
+		require.Equal(t, master.ProfileId, export.Profile.ProfileId)
+		require.Len(t, export.ChildProfiles, 1)
+		require.Equal(t, child.ProfileId, export.ChildProfiles[0].ProfileId)
+		require.Equal(t, []interface{}{"export-child"}, export.ChildProfiles[0].Traits["interests"])
+		require.NotNil(t, export.Consents)
+
+		_, err = profileSvc.ExportProfile(uuid.New().String(), profileModel.AllApplications)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusNotFound, clientErr.StatusCode)
 	})
 
 	t.Cleanup(func() {
 		rules, _ := unificationSvc.GetUnificationRules(SuperTenantOrg)
 		for _, r := range rules {
-			_ = unificationSvc.DeleteUnificationRule(r.RuleId)
+			_ = unificationSvc.DeleteUnificationRule(r.RuleId, SuperTenantOrg)
 		}
-		profiles, _, _ := profileSvc.GetAllProfilesCursor(SuperTenantOrg, 10, nil)
+		profiles, _, _ := profileSvc.GetAllProfilesCursor(SuperTenantOrg, false, 10, nil, profileModel.AllApplications)
 		for _, p := range profiles {
 			_ = profileSvc.DeleteProfile(p.ProfileId)
 		}
@@ -172,3 +1122,27 @@ func Test_Profile(t *testing.T) {
 		_ = profileSchemaSvc.DeleteProfileSchemaAttributesByScope(SuperTenantOrg, constants.IdentityAttributes)
 	})
 }
+
+// writeSigningKeyPair writes a self-signed ECDSA certificate and key in PEM form to dir.
+func writeSigningKeyPair(t *testing.T, dir string) (string, string) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "cds-export-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "export.crt")
+	keyFile := filepath.Join(dir, "export.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+	return certFile, keyFile
+}
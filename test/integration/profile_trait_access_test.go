@@ -0,0 +1,258 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Trait_Access_Control(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-traitacl-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	schemaSvc := schemaService.GetProfileSchemaService()
+
+	attribute := func(name, valueType string, allowedApps []string,
+		subAttributes ...profileSchema.ProfileSchemaAttribute) profileSchema.ProfileSchemaAttribute {
+		attr := profileSchema.ProfileSchemaAttribute{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: name,
+			ValueType:     valueType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+			AllowedApps:   allowedApps,
+		}
+		for _, sub := range subAttributes {
+			attr.SubAttributes = append(attr.SubAttributes,
+				profileSchema.SubAttribute{AttributeId: sub.AttributeId, AttributeName: sub.AttributeName})
+		}
+		return attr
+	}
+	riskApps := []string{"risk-app"}
+	city := attribute("traits.address.city", constants.StringDataType, nil)
+	zone := attribute("traits.address.zone", constants.StringDataType, riskApps)
+	_, err := schemaSvc.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{city, zone},
+		constants.Traits, orgHandle)
+	require.NoError(t, err)
+	_, err = schemaSvc.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{
+		attribute("traits.address", constants.ComplexDataType, nil, city, zone),
+		attribute("traits.nickname", constants.StringDataType, nil),
+		attribute("traits.internalRiskScore", constants.IntegerDataType, riskApps),
+	}, constants.Traits, orgHandle)
+	require.NoError(t, err)
+	email := attribute("identity_attributes.email", constants.StringDataType, nil)
+	email.MultiValued = true
+	_, err = schemaSvc.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{email},
+		constants.IdentityAttributes, orgHandle)
+	require.NoError(t, err)
+
+	profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+		IdentityAttributes: map[string]interface{}{"email": []interface{}{"johnny@wso2.com"}},
+		Traits: map[string]interface{}{
+			"nickname":          "Johnny",
+			"internalRiskScore": 87,
+			"address":           map[string]interface{}{"city": "Colombo", "zone": "red"},
+		},
+	}, orgHandle)
+	require.NoError(t, err)
+
+	t.Run("Allowed_app_reads_restricted_traits", func(t *testing.T) {
+		fetched, err := profileSvc.GetProfile(profile.ProfileId, profileModel.ApplicationScope("risk-app"))
+		require.NoError(t, err)
+		require.EqualValues(t, 87, fetched.Traits["internalRiskScore"])
+		require.Equal(t, "red", fetched.Traits["address"].(map[string]interface{})["zone"])
+	})
+
+	t.Run("Other_app_does_not_see_restricted_traits", func(t *testing.T) {
+		fetched, err := profileSvc.GetProfile(profile.ProfileId, profileModel.ApplicationScope("marketing-app"))
+		require.NoError(t, err)
+		require.NotContains(t, fetched.Traits, "internalRiskScore")
+		require.Equal(t, "Johnny", fetched.Traits["nickname"])
+		address := fetched.Traits["address"].(map[string]interface{})
+		require.NotContains(t, address, "zone")
+		require.Equal(t, "Colombo", address["city"])
+	})
+
+	t.Run("Unscoped_caller_sees_every_trait", func(t *testing.T) {
+		fetched, err := profileSvc.GetProfile(profile.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Contains(t, fetched.Traits, "internalRiskScore")
+	})
+
+	t.Run("Listing_redacts_restricted_traits", func(t *testing.T) {
+		profiles, _, err := profileSvc.GetAllProfilesCursor(orgHandle, false, 10, nil,
+			profileModel.ApplicationScope("marketing-app"))
+		require.NoError(t, err)
+		require.Len(t, profiles, 1)
+		require.NotContains(t, profiles[0].Traits, "internalRiskScore")
+
+		profiles, _, err = profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+			Filters:  []string{"traits.nickname eq Johnny"},
+			Limit:    10,
+			AppScope: profileModel.ApplicationScope("marketing-app"),
+		})
+		require.NoError(t, err)
+		require.Len(t, profiles, 1)
+		require.NotContains(t, profiles[0].Traits["address"], "zone")
+	})
+
+	t.Run("Filtering_on_restricted_traits_is_forbidden", func(t *testing.T) {
+		for _, filter := range []string{"traits.internalRiskScore gte 80", "traits.address.zone eq red"} {
+			_, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+				Filters:  []string{filter},
+				Limit:    10,
+				AppScope: profileModel.ApplicationScope("marketing-app"),
+			})
+			var clientErr *errors2.ClientError
+			require.True(t, errors.As(err, &clientErr), filter)
+			require.Equal(t, http.StatusForbidden, clientErr.StatusCode)
+		}
+
+		profiles, _, err := profileSvc.GetAllProfilesWithFilterCursor(orgHandle, profileModel.ProfileQuery{
+			Filters:  []string{"traits.internalRiskScore gte 80"},
+			Limit:    10,
+			AppScope: profileModel.ApplicationScope("risk-app"),
+		})
+		require.NoError(t, err)
+		require.Len(t, profiles, 1)
+	})
+
+	requireForbidden := func(t *testing.T, err error) {
+		var clientErr *errors2.ClientError
+		require.True(t, errors.As(err, &clientErr))
+		require.Equal(t, http.StatusForbidden, clientErr.StatusCode)
+	}
+
+	t.Run("Projection_redacts_restricted_traits", func(t *testing.T) {
+		projection, err := profileSvc.GetProfileProjected(profile.ProfileId,
+			[]string{"traits.internalRiskScore", "traits.address",
+				"traits.nickname"}, profileModel.ApplicationScope("marketing-app"))
+		require.NoError(t, err)
+		require.NotContains(t, projection.Traits, "internalRiskScore")
+		require.NotContains(t, projection.Traits["address"], "zone")
+		require.Equal(t, "Johnny", projection.Traits["nickname"])
+
+		projection, err = profileSvc.GetProfileProjected(profile.ProfileId, []string{"traits.internalRiskScore"},
+			profileModel.ApplicationScope("risk-app"))
+		require.NoError(t, err)
+		require.EqualValues(t, 87, projection.Traits["internalRiskScore"])
+	})
+
+	t.Run("History_redacts_restricted_traits", func(t *testing.T) {
+		history, err := profileSvc.GetProfileHistory(profile.ProfileId, profileModel.ApplicationScope("marketing-app"))
+		require.NoError(t, err)
+		require.NotEmpty(t, history)
+		for _, snapshot := range history {
+			require.NotContains(t, snapshot.Traits, "internalRiskScore")
+			for _, change := range snapshot.Changes {
+				require.NotEqual(t, "internalRiskScore", change.Trait)
+			}
+		}
+	})
+
+	t.Run("Resolving_by_identifier_redacts_restricted_traits", func(t *testing.T) {
+		resolved, err := profileSvc.ResolveProfileByIdentifier(orgHandle, "email", "johnny@wso2.com",
+			profileModel.ApplicationScope("marketing-app"))
+		require.NoError(t, err)
+		require.Len(t, resolved, 1)
+		require.NotContains(t, resolved[0].Traits, "internalRiskScore")
+	})
+
+	t.Run("Export_redacts_restricted_traits", func(t *testing.T) {
+		exported, err := profileSvc.ExportProfile(profile.ProfileId, profileModel.ApplicationScope("marketing-app"))
+		require.NoError(t, err)
+		var export profileModel.ProfileExport
+		require.NoError(t, json.Unmarshal(exported, &export))
+		require.NotContains(t, export.Profile.Traits, "internalRiskScore")
+	})
+
+	t.Run("Change_stream_snapshot_leaves_out_app_restricted_traits", func(t *testing.T) {
+		snapshot, err := profileSvc.GetProfileForChangeStream(profile.ProfileId)
+		require.NoError(t, err)
+		require.NotContains(t, snapshot.Traits, "internalRiskScore")
+		require.NotContains(t, snapshot.Traits["address"], "zone")
+		require.Equal(t, "Johnny", snapshot.Traits["nickname"])
+	})
+
+	t.Run("CSV_export_of_restricted_traits_is_forbidden", func(t *testing.T) {
+		var out bytes.Buffer
+		requireForbidden(t, profileSvc.ExportProfilesCSV(context.Background(), orgHandle, nil,
+			[]string{"profile_id", "traits.internalRiskScore"}, profileModel.ApplicationScope("marketing-app"), &out))
+		requireForbidden(t, profileSvc.ExportProfilesCSV(context.Background(), orgHandle,
+			[]string{"traits.address.zone eq red"}, []string{"profile_id"}, profileModel.ApplicationScope("marketing-app"),
+			&out))
+
+		out.Reset()
+		require.NoError(t, profileSvc.ExportProfilesCSV(context.Background(), orgHandle, nil,
+			[]string{"traits.address"}, profileModel.ApplicationScope("marketing-app"), &out))
+		require.NotContains(t, out.String(), "red")
+		require.Contains(t, out.String(), "Colombo")
+	})
+
+	t.Run("Counting_on_restricted_traits_is_forbidden", func(t *testing.T) {
+		_, err := profileSvc.CountProfilesGroupedBy(orgHandle, "internalRiskScore", nil,
+			profileModel.ApplicationScope("marketing-app"))
+		requireForbidden(t, err)
+		_, err = profileSvc.CountProfilesGroupedBy(orgHandle, "nickname", []string{"traits.internalRiskScore gte 80"},
+			profileModel.ApplicationScope("marketing-app"))
+		requireForbidden(t, err)
+
+		counts, err := profileSvc.CountProfilesGroupedBy(orgHandle, "nickname", nil,
+			profileModel.ApplicationScope("marketing-app"))
+		require.NoError(t, err)
+		require.EqualValues(t, 1, counts["Johnny"])
+	})
+
+	t.Run("Distinct_values_of_restricted_traits_are_forbidden", func(t *testing.T) {
+		_, err := profileSvc.GetDistinctTraitValues(orgHandle, "traits.internalRiskScore", 10, false,
+			profileModel.ApplicationScope("marketing-app"))
+		requireForbidden(t, err)
+
+		values, err := profileSvc.GetDistinctTraitValues(orgHandle, "internalRiskScore", 10, false,
+			profileModel.ApplicationScope("risk-app"))
+		require.NoError(t, err)
+		require.Equal(t, []string{"87"}, values)
+	})
+
+	t.Run("Allowed_apps_are_only_supported_for_traits", func(t *testing.T) {
+		_, err := schemaSvc.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{
+			attribute("identity_attributes.riskLevel", constants.StringDataType, riskApps),
+		}, constants.IdentityAttributes, orgHandle)
+		var clientErr *errors2.ClientError
+		require.True(t, errors.As(err, &clientErr))
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+	})
+}
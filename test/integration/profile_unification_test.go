@@ -137,9 +137,9 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 
 		time.Sleep(2 * time.Second)
 
-		merged1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(prof2.ProfileId)
-		merged3, _ := profileSvc.GetProfile(prof3.ProfileId)
+		merged1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
+		merged3, _ := profileSvc.GetProfile(prof3.ProfileId, profileModel.AllApplications)
 
 		require.Equal(t, merged1.MergedTo.ProfileId, merged2.MergedTo.ProfileId)
 		require.Equal(t, RuleNameEmailBased, merged1.MergedTo.Reason)
@@ -161,8 +161,8 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 		p2, _ := profileSvc.CreateProfile(perm, SuperTenantOrg)
 		time.Sleep(2 * time.Second)
 
-		merged1, _ := profileSvc.GetProfile(p1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(p2.ProfileId)
+		merged1, _ := profileSvc.GetProfile(p1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(p2.ProfileId, profileModel.AllApplications)
 
 		require.Equal(t, merged1.MergedTo.ProfileId, merged2.ProfileId)
 		require.Equal(t, RuleNameEmailBased, merged1.MergedTo.Reason)
@@ -186,9 +186,9 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 		p3, _ := profileSvc.CreateProfile(perm, SuperTenantOrg)
 		time.Sleep(2 * time.Second)
 
-		merged1, _ := profileSvc.GetProfile(p1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(p2.ProfileId)
-		merged3, _ := profileSvc.GetProfile(p3.ProfileId)
+		merged1, _ := profileSvc.GetProfile(p1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(p2.ProfileId, profileModel.AllApplications)
+		merged3, _ := profileSvc.GetProfile(p3.ProfileId, profileModel.AllApplications)
 
 		require.Equal(t, merged1.MergedTo.ProfileId, merged2.MergedTo.ProfileId)
 		require.Equal(t, RuleNameEmailBased, merged1.MergedTo.Reason)
@@ -216,9 +216,9 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 		p3, _ := profileSvc.CreateProfile(temp2, SuperTenantOrg)
 		time.Sleep(2 * time.Second)
 
-		merged1, _ := profileSvc.GetProfile(p1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(p2.ProfileId)
-		merged3, _ := profileSvc.GetProfile(p3.ProfileId)
+		merged1, _ := profileSvc.GetProfile(p1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(p2.ProfileId, profileModel.AllApplications)
+		merged3, _ := profileSvc.GetProfile(p3.ProfileId, profileModel.AllApplications)
 
 		require.Equal(t, merged1.ProfileId, merged2.MergedTo.ProfileId)
 		require.Equal(t, RuleNameEmailBased, merged2.MergedTo.Reason)
@@ -257,8 +257,8 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 
 		time.Sleep(2 * time.Second)
 
-		merged1, _ := profileSvc.GetProfile(p1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(p2.ProfileId)
+		merged1, _ := profileSvc.GetProfile(p1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(p2.ProfileId, profileModel.AllApplications)
 
 		require.Equal(t, merged1.MergedTo.ProfileId, merged2.MergedTo.ProfileId)
 		require.Equal(t, RuleNameEmailBased, merged2.MergedTo.Reason)
@@ -271,7 +271,7 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 		emailBasedRule.IsActive = false
 		err = unificationSvc.PatchUnificationRule(emailRuleId, SuperTenantOrg, emailBasedRule)
 		require.NoError(t, err, "Failed to deactivate email based unification rule")
-		rule, _ := unificationSvc.GetUnificationRule(emailRuleId)
+		rule, _ := unificationSvc.GetUnificationRule(emailRuleId, SuperTenantOrg)
 		require.Equal(t, false, rule.IsActive)
 
 		p1 := mustUnmarshalProfile(`{"identity_attributes":{"email":["g@wso2.com"]}}`)
@@ -281,8 +281,8 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 		prof2, _ := profileSvc.CreateProfile(p2, SuperTenantOrg)
 		time.Sleep(2 * time.Second)
 
-		mergedProfile1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		mergedProfile2, _ := profileSvc.GetProfile(prof2.ProfileId)
+		mergedProfile1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		mergedProfile2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
 
 		require.Empty(t, mergedProfile1.MergedTo)
 		require.Empty(t, mergedProfile2.MergedTo)
@@ -302,17 +302,17 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 		prof2, _ := profileSvc.CreateProfile(p2, SuperTenantOrg)
 		time.Sleep(2 * time.Second)
 
-		merged1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(prof2.ProfileId)
+		merged1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
 		require.Equal(t, merged1.MergedTo.ProfileId, merged2.MergedTo.ProfileId)
 
-		_ = unificationSvc.DeleteUnificationRule(emailRuleId)
+		_ = unificationSvc.DeleteUnificationRule(emailRuleId, SuperTenantOrg)
 
-		after1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		after2, _ := profileSvc.GetProfile(prof2.ProfileId)
+		after1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		after2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
 
-		merged1, _ = profileSvc.GetProfile(after1.ProfileId)
-		merged2, _ = profileSvc.GetProfile(after2.ProfileId)
+		merged1, _ = profileSvc.GetProfile(after1.ProfileId, profileModel.AllApplications)
+		merged2, _ = profileSvc.GetProfile(after2.ProfileId, profileModel.AllApplications)
 		require.Equal(t, merged1.MergedTo.ProfileId, merged2.MergedTo.ProfileId)
 		cleanProfiles(profileSvc, SuperTenantOrg)
 
@@ -353,8 +353,8 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 		prof2, _ := profileSvc.CreateProfile(p2, OtherTenant)
 		time.Sleep(5 * time.Second)
 
-		merged1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(prof2.ProfileId)
+		merged1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
 
 		require.Empty(t, merged1.MergedTo)
 		require.Empty(t, merged2.MergedTo)
@@ -377,8 +377,8 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 
 		time.Sleep(2 * time.Second)
 
-		merged1, _ := profileSvc.GetProfile(prof1.ProfileId)
-		merged2, _ := profileSvc.GetProfile(prof2.ProfileId)
+		merged1, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		merged2, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
 
 		require.NotEmpty(t, merged1.MergedTo.ProfileId, "Merged1 should be merged")
 		require.NotEmpty(t, merged2.MergedTo.ProfileId, "Merged2 should be merged")
@@ -388,7 +388,7 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 		if merged1.MergedTo.ProfileId == "" {
 			master = merged1
 		} else {
-			master, _ = profileSvc.GetProfile(merged1.MergedTo.ProfileId)
+			master, _ = profileSvc.GetProfile(merged1.MergedTo.ProfileId, profileModel.AllApplications)
 		}
 
 		// Master profile should have both app attributes intact
@@ -401,8 +401,8 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 		require.Equal(t, "dark", appAData["ui_mode"], "App A ui_mode should be 'dark'")
 
 		// Profile 1 and Profile 2 should have their respective app attributes but not the others
-		p1Final, _ := profileSvc.GetProfile(prof1.ProfileId)
-		p2Final, _ := profileSvc.GetProfile(prof2.ProfileId)
+		p1Final, _ := profileSvc.GetProfile(prof1.ProfileId, profileModel.AllApplications)
+		p2Final, _ := profileSvc.GetProfile(prof2.ProfileId, profileModel.AllApplications)
 
 		p1AppData := p1Final.ApplicationData
 		p2AppData := p2Final.ApplicationData
@@ -420,7 +420,7 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 	t.Cleanup(func() {
 		rules, _ := unificationSvc.GetUnificationRules(SuperTenantOrg)
 		for _, r := range rules {
-			_ = unificationSvc.DeleteUnificationRule(r.RuleId)
+			_ = unificationSvc.DeleteUnificationRule(r.RuleId, SuperTenantOrg)
 		}
 		cleanProfiles(profileSvc, SuperTenantOrg)
 		_ = profileSchemaSvc.DeleteProfileSchema(SuperTenantOrg)
@@ -430,7 +430,7 @@ func Test_Profile_Unification_Scenarios(t *testing.T) {
 
 func cleanProfiles(profileSvc profileService.ProfilesServiceInterface, org string) {
 
-	profiles, _, _ := profileSvc.GetAllProfilesCursor(org, 10, nil)
+	profiles, _, _ := profileSvc.GetAllProfilesCursor(org, false, 10, nil, profileModel.AllApplications)
 	for _, p := range profiles {
 		_ = profileSvc.DeleteProfile(p.ProfileId)
 	}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+)
+
+func Test_Profile_Mutations_Advance_Updated_At(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-updatedat-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	interests := createAttr(orgHandle, "traits.interests", constants.StringDataType, "combine",
+		constants.MutabilityReadWrite)
+	interests.MultiValued = true
+	addSchemaAttributes(t, orgHandle, constants.Traits, interests, createAttr(orgHandle, "traits.loyalty_points",
+		constants.IntegerDataType, "overwrite", constants.MutabilityReadWrite))
+	addSchemaAttributes(t, orgHandle, constants.ApplicationData,
+		appDataAttribute(orgHandle, "storefront", "visits", constants.IntegerDataType))
+	newProfile := func() string {
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"loyalty_points": 1},
+		}, orgHandle)
+		require.NoError(t, err)
+		return profile.ProfileId
+	}
+	updatedAt := func(profileId string) time.Time {
+		profile, err := profileSvc.GetProfile(profileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		return profile.Meta.UpdatedAt
+	}
+	// requireAdvances runs the mutation and asserts it moved updated_at of every given profile forward.
+	requireAdvances := func(t *testing.T, mutate func(), profileIds ...string) {
+		before := make(map[string]time.Time, len(profileIds))
+		for _, profileId := range profileIds {
+			before[profileId] = updatedAt(profileId)
+		}
+		time.Sleep(10 * time.Millisecond)
+		mutate()
+		for _, profileId := range profileIds {
+			require.True(t, updatedAt(profileId).After(before[profileId]),
+				"updated_at of profile: %s should advance", profileId)
+		}
+	}
+
+	profileId := newProfile()
+
+	t.Run("Patch", func(t *testing.T) {
+		requireAdvances(t, func() {
+			_, err := profileSvc.PatchProfile(context.Background(), profileId, orgHandle,
+				map[string]interface{}{"traits": map[string]interface{}{"interests": []interface{}{"music"}}}, 0)
+			require.NoError(t, err)
+		}, profileId)
+	})
+
+	t.Run("Increment_Attribute", func(t *testing.T) {
+		requireAdvances(t, func() {
+			_, err := profileSvc.IncrementAttribute(profileId, "traits.loyalty_points", 2)
+			require.NoError(t, err)
+		}, profileId)
+	})
+
+	t.Run("Application_Data", func(t *testing.T) {
+		requireAdvances(t, func() {
+			require.NoError(t, profileSvc.PatchApplicationData(profileId, "storefront",
+				map[string]interface{}{"visits": 1}))
+		}, profileId)
+		requireAdvances(t, func() {
+			require.NoError(t, profileSvc.PatchApplicationData(profileId, "storefront",
+				map[string]interface{}{"visits": 2}))
+		}, profileId)
+	})
+
+	t.Run("Delete_And_Restore", func(t *testing.T) {
+		requireAdvances(t, func() {
+			require.NoError(t, profileSvc.DeleteProfile(profileId))
+			require.NoError(t, profileSvc.RestoreProfile(profileId))
+		}, profileId)
+	})
+
+	master, child := newProfile(), newProfile()
+
+	t.Run("Merge", func(t *testing.T) {
+		requireAdvances(t, func() {
+			require.NoError(t, profileSvc.MergeProfiles(master, child))
+		}, master)
+	})
+
+	t.Run("Unmerge", func(t *testing.T) {
+		requireAdvances(t, func() {
+			unmerged, err := profileSvc.UnmergeProfile(child, profileModel.AllApplications)
+			require.NoError(t, err)
+			require.Nil(t, unmerged.MergedTo)
+		}, master)
+		require.True(t, updatedAt(child).After(updatedAt(profileId)), "The detached profile is marked updated")
+	})
+}
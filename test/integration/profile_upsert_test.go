@@ -0,0 +1,171 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+)
+
+func Test_Profile_Get_Or_Create(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-upsert-%d", time.Now().UnixNano())
+	profileSvc := profileService.GetProfilesService()
+	ctx := context.Background()
+
+	traits := []profileSchema.ProfileSchemaAttribute{
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.nickname",
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+		},
+		{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: "traits.city",
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+		},
+	}
+	_, err := schemaService.GetProfileSchemaService().AddProfileSchemaAttributesForScope(traits, constants.Traits,
+		orgHandle)
+	require.NoError(t, err)
+
+	userId := uuid.New().String()
+	request := profileModel.ProfileRequest{
+		UserId: userId,
+		Traits: map[string]interface{}{"nickname": "Johnny", "city": "Colombo"},
+	}
+
+	created, isNew, err := profileSvc.GetOrCreateProfile(ctx, request, orgHandle, profileModel.AllApplications)
+	require.NoError(t, err)
+	require.True(t, isNew)
+
+	t.Run("Unchanged_request_returns_the_profile_without_a_write", func(t *testing.T) {
+		profile, isNew, err := profileSvc.GetOrCreateProfile(ctx, request, orgHandle, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.False(t, isNew)
+		require.Equal(t, created.ProfileId, profile.ProfileId)
+
+		stored, err := profileSvc.GetProfileFromPrimary(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, created.Meta.Version, stored.Meta.Version, "An unchanged profile must not be updated")
+	})
+
+	t.Run("Subset_of_the_stored_values_is_unchanged", func(t *testing.T) {
+		_, isNew, err := profileSvc.GetOrCreateProfile(ctx, profileModel.ProfileRequest{
+			UserId: userId,
+			Traits: map[string]interface{}{"city": "Colombo"},
+		}, orgHandle, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.False(t, isNew)
+
+		stored, err := profileSvc.GetProfileFromPrimary(created.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Equal(t, created.Meta.Version, stored.Meta.Version)
+	})
+
+	t.Run("Changed_value_is_merged_into_the_profile", func(t *testing.T) {
+		profile, isNew, err := profileSvc.GetOrCreateProfile(ctx, profileModel.ProfileRequest{
+			UserId: userId,
+			Traits: map[string]interface{}{"city": "Kandy"},
+		}, orgHandle, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.False(t, isNew)
+		require.Equal(t, created.ProfileId, profile.ProfileId)
+		require.Equal(t, "Kandy", profile.Traits["city"])
+		require.Equal(t, "Johnny", profile.Traits["nickname"], "Values missing from the request are kept")
+		require.Greater(t, profile.Meta.Version, created.Meta.Version)
+	})
+
+	t.Run("Profile_of_the_user_in_another_organization_is_not_matched", func(t *testing.T) {
+		sharedUserId := uuid.New().String()
+		other, err := profileSvc.CreateProfile(profileModel.ProfileRequest{UserId: sharedUserId},
+			orgHandle+"-other")
+		require.NoError(t, err)
+
+		first, isNew, err := profileSvc.GetOrCreateProfile(ctx, profileModel.ProfileRequest{UserId: sharedUserId},
+			orgHandle, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.True(t, isNew)
+		require.NotEqual(t, other.ProfileId, first.ProfileId)
+
+		second, isNew, err := profileSvc.GetOrCreateProfile(ctx, profileModel.ProfileRequest{UserId: sharedUserId},
+			orgHandle, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.False(t, isNew, "The profile of the user in the organization must be found")
+		require.Equal(t, first.ProfileId, second.ProfileId)
+	})
+
+	t.Run("Concurrent_calls_for_a_new_user_create_one_profile", func(t *testing.T) {
+		newUserId := uuid.New().String()
+		const callers = 5
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		profileIds := map[string]bool{}
+		createdCount := 0
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				profile, isNew, err := profileSvc.GetOrCreateProfile(ctx, profileModel.ProfileRequest{
+					UserId: newUserId,
+					Traits: map[string]interface{}{"city": "Jaffna"},
+				}, orgHandle, profileModel.AllApplications)
+				require.NoError(t, err)
+				mu.Lock()
+				defer mu.Unlock()
+				profileIds[profile.ProfileId] = true
+				if isNew {
+					createdCount++
+				}
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, 1, createdCount)
+		require.Len(t, profileIds, 1)
+	})
+
+	t.Run("Request_without_a_user_id_is_rejected", func(t *testing.T) {
+		_, _, err := profileSvc.GetOrCreateProfile(ctx, profileModel.ProfileRequest{
+			Traits: map[string]interface{}{"city": "Galle"},
+		}, orgHandle, profileModel.AllApplications)
+		var clientErr *errors2.ClientError
+		require.True(t, errors.As(err, &clientErr))
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+	})
+}
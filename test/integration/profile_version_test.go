@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+package integration
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+)
+
+func Test_Profile_Version(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-version-%d", time.Now().UnixNano())
+	addSchemaAttributes(t, orgHandle, constants.ApplicationData,
+		appDataAttribute(orgHandle, "app-a", "theme", constants.StringDataType))
+	profileSvc := profileService.GetProfilesService()
+
+	// requireStale checks that the stored profile moved past the version, so that an update expecting it fails.
+	requireStale := func(t *testing.T, profileId string, version int64) {
+		stored, err := profileStore.GetProfile(profileId)
+		require.NoError(t, err)
+		require.Greater(t, stored.Version, version)
+		stored.Version = version
+		require.ErrorIs(t, profileStore.UpdateProfile(*stored), profileModel.ErrProfileVersionConflict)
+	}
+
+	t.Run("Created_Version_Counts_Application_Data", func(t *testing.T) {
+		created, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+			ApplicationData: map[string]map[string]interface{}{"app-a": {"theme": "dark"}},
+		}, orgHandle)
+		require.NoError(t, err)
+
+		stored, err := profileStore.GetProfile(created.ProfileId)
+		require.NoError(t, err)
+		require.Equal(t, stored.Version, created.Meta.Version)
+	})
+
+	t.Run("Application_Data_Writes_Bump_The_Version", func(t *testing.T) {
+		created, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+
+		require.NoError(t, profileStore.InsertApplicationData(created.ProfileId, []profileModel.ApplicationData{
+			{AppId: "app-a", AppSpecificData: map[string]interface{}{"theme": "light"}},
+		}))
+		requireStale(t, created.ProfileId, created.Meta.Version)
+	})
+
+	t.Run("Deletes_And_Restores_Bump_The_Version", func(t *testing.T) {
+		created, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, orgHandle)
+		require.NoError(t, err)
+
+		dbClient, err := provider.NewDBProvider().GetDBClient()
+		require.NoError(t, err)
+		defer dbClient.Close()
+		deletedAt := time.Now().UTC().Truncate(time.Microsecond)
+		require.NoError(t, dbClient.RunInTx(func(tx *sql.Tx) error {
+			return profileStore.SoftDeleteProfile(tx, created.ProfileId, deletedAt)
+		}))
+		restored, err := profileStore.RestoreProfiles(created.ProfileId, deletedAt)
+		require.NoError(t, err)
+		require.Contains(t, restored, created.ProfileId)
+
+		// Both the deletion and the restore are changes an update must not overwrite
+		requireStale(t, created.ProfileId, created.Meta.Version+1)
+	})
+}
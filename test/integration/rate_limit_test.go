@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/ratelimit"
+	"github.com/wso2/identity-customer-data-service/internal/system/utils"
+)
+
+func Test_Rate_Limit(t *testing.T) {
+
+	conf := config.GetCDSRuntime().Config
+	modified := conf
+	modified.RateLimit = config.RateLimitConfig{
+		Enabled:      true,
+		Organization: config.RateLimit{RequestsPerSecond: 0.01, Burst: 3},
+		Application:  config.RateLimit{RequestsPerSecond: 0.01, Burst: 2},
+	}
+	config.OverrideCDSRuntime(modified)
+	defer config.OverrideCDSRuntime(conf)
+
+	t.Run("Requests_beyond_the_burst_of_an_application_are_rejected", func(t *testing.T) {
+		orgHandle := fmt.Sprintf("carbon.super-ratelimit-%d", time.Now().UnixNano())
+		require.NoError(t, ratelimit.Allow(orgHandle, "app-a"))
+		require.NoError(t, ratelimit.Allow(orgHandle, "app-a"))
+
+		err := ratelimit.Allow(orgHandle, "app-a")
+		var clientErr *errors.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusTooManyRequests, clientErr.StatusCode)
+		require.Equal(t, errors.RATE_LIMIT_EXCEEDED.Code, clientErr.Code)
+		require.Greater(t, clientErr.RetryAfter, time.Duration(0))
+
+		// The organization still has a token left for another application
+		require.NoError(t, ratelimit.Allow(orgHandle, "app-b"))
+		require.Error(t, ratelimit.Allow(orgHandle, "app-c"))
+	})
+
+	t.Run("Organizations_do_not_share_buckets", func(t *testing.T) {
+		busyOrg := fmt.Sprintf("carbon.super-ratelimit-busy-%d", time.Now().UnixNano())
+		for i := 0; i < 3; i++ {
+			require.NoError(t, ratelimit.Allow(busyOrg, ""))
+		}
+		require.Error(t, ratelimit.Allow(busyOrg, ""))
+
+		quietOrg := fmt.Sprintf("carbon.super-ratelimit-quiet-%d", time.Now().UnixNano())
+		require.NoError(t, ratelimit.Allow(quietOrg, ""))
+	})
+
+	t.Run("Organization_specific_limit_overrides_the_default", func(t *testing.T) {
+		orgHandle := fmt.Sprintf("carbon.super-ratelimit-vip-%d", time.Now().UnixNano())
+		vip := modified
+		vip.RateLimit.Organizations = map[string]config.RateLimit{orgHandle: {RequestsPerSecond: 0.01, Burst: 5}}
+		config.OverrideCDSRuntime(vip)
+		defer config.OverrideCDSRuntime(modified)
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, ratelimit.Allow(orgHandle, ""))
+		}
+		require.Error(t, ratelimit.Allow(orgHandle, ""))
+	})
+
+	t.Run("Usage_reports_the_buckets_of_the_organization", func(t *testing.T) {
+		orgHandle := fmt.Sprintf("carbon.super-ratelimit-usage-%d", time.Now().UnixNano())
+		require.NoError(t, ratelimit.Allow(orgHandle, "app-a"))
+		require.NoError(t, ratelimit.Allow(orgHandle, "app-a"))
+		require.Error(t, ratelimit.Allow(orgHandle, "app-a"))
+
+		usage := ratelimit.GetUsage(orgHandle)
+		require.True(t, usage.Enabled)
+		require.Equal(t, uint64(2), usage.Organization.AllowedRequests)
+		require.Equal(t, 3, usage.Organization.Burst)
+		require.Len(t, usage.Applications, 1)
+		require.Equal(t, "app-a", usage.Applications[0].AppId)
+		require.Equal(t, uint64(2), usage.Applications[0].AllowedRequests)
+		require.Equal(t, uint64(1), usage.Applications[0].RejectedRequests)
+		require.Less(t, usage.Applications[0].AvailableTokens, 1.0)
+	})
+
+	t.Run("Rejection_is_sent_with_retry_after", func(t *testing.T) {
+		orgHandle := fmt.Sprintf("carbon.super-ratelimit-header-%d", time.Now().UnixNano())
+		var err error
+		for i := 0; i < 4 && err == nil; i++ {
+			err = ratelimit.Allow(orgHandle, "")
+		}
+		require.Error(t, err)
+
+		recorder := httptest.NewRecorder()
+		utils.HandleError(recorder, err)
+		require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+	})
+
+	t.Run("Disabled_limiter_allows_every_request", func(t *testing.T) {
+		disabled := modified
+		disabled.RateLimit.Enabled = false
+		config.OverrideCDSRuntime(disabled)
+		defer config.OverrideCDSRuntime(modified)
+
+		orgHandle := fmt.Sprintf("carbon.super-ratelimit-off-%d", time.Now().UnixNano())
+		for i := 0; i < 10; i++ {
+			require.NoError(t, ratelimit.Allow(orgHandle, "app-a"))
+		}
+	})
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+package integration
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wso2/identity-customer-data-service/internal/system/shutdown"
+)
+
+func Test_Shutdown_Drain(t *testing.T) {
+
+	// start serves the handler like the server does, with the requests tracked and cancelled by the drainer.
+	start := func(t *testing.T, handler http.HandlerFunc) (*httptest.Server, shutdown.Drainer, chan struct{}) {
+		requestCtx, abortRequests := context.WithCancel(context.Background())
+		tracker := &shutdown.RequestTracker{}
+		server := httptest.NewUnstartedServer(tracker.Track(handler))
+		server.Config.BaseContext = func(net.Listener) context.Context { return requestCtx }
+		server.Start()
+		t.Cleanup(server.Close)
+		notReady := make(chan struct{})
+		return server, shutdown.Drainer{
+			Server:           server.Config,
+			Tracker:          tracker,
+			MarkNotReady:     func() { close(notReady) },
+			AbortRequests:    abortRequests,
+			ReadinessDelay:   300 * time.Millisecond,
+			Timeout:          time.Second,
+			AbortGracePeriod: time.Second,
+		}, notReady
+	}
+
+	t.Run("Requests_are_served_until_the_readiness_delay_passes", func(t *testing.T) {
+		server, drainer, notReady := start(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		drained := make(chan bool, 1)
+		go func() {
+			returned, _ := drainer.Drain()
+			drained <- returned
+		}()
+
+		<-notReady
+		response, err := server.Client().Get(server.URL)
+		require.NoError(t, err)
+		_ = response.Body.Close()
+		require.Equal(t, http.StatusNoContent, response.StatusCode)
+
+		require.True(t, <-drained)
+		_, err = server.Client().Get(server.URL)
+		require.Error(t, err, "The server no longer accepts requests once drained")
+	})
+
+	t.Run("Requests_in_flight_complete_within_the_timeout", func(t *testing.T) {
+		started := make(chan struct{})
+		server, drainer, _ := start(t, func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(500 * time.Millisecond)
+			w.WriteHeader(http.StatusNoContent)
+		})
+		drainer.ReadinessDelay = 0
+		responses := make(chan *http.Response, 1)
+		go func() {
+			response, err := server.Client().Get(server.URL)
+			if err == nil {
+				_ = response.Body.Close()
+			}
+			responses <- response
+		}()
+
+		<-started
+		returned, err := drainer.Drain()
+		require.NoError(t, err)
+		require.True(t, returned)
+		response := <-responses
+		require.NotNil(t, response)
+		require.Equal(t, http.StatusNoContent, response.StatusCode)
+	})
+
+	t.Run("Requests_outliving_the_timeout_are_cancelled", func(t *testing.T) {
+		started := make(chan struct{})
+		var cancelled atomic.Bool
+		server, drainer, _ := start(t, func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-r.Context().Done()
+			cancelled.Store(true)
+		})
+		drainer.ReadinessDelay = 0
+		drainer.Timeout = 100 * time.Millisecond
+		go func() {
+			if response, err := server.Client().Get(server.URL); err == nil {
+				_ = response.Body.Close()
+			}
+		}()
+
+		<-started
+		returned, err := drainer.Drain()
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.True(t, returned, "The cancelled request returns within the abort grace period")
+		require.True(t, cancelled.Load())
+	})
+
+	t.Run("Requests_ignoring_the_cancellation_are_reported", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+		server, drainer, _ := start(t, func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		})
+		drainer.ReadinessDelay = 0
+		drainer.Timeout = 100 * time.Millisecond
+		drainer.AbortGracePeriod = 100 * time.Millisecond
+		go func() {
+			if response, err := server.Client().Get(server.URL); err == nil {
+				_ = response.Body.Close()
+			}
+		}()
+
+		<-started
+		returned, _ := drainer.Drain()
+		require.False(t, returned)
+	})
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	profileStore "github.com/wso2/identity-customer-data-service/internal/profile/store"
+	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
+	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/workers"
+	"github.com/wso2/identity-customer-data-service/internal/unification_rules/model"
+	"github.com/wso2/identity-customer-data-service/internal/unification_rules/service"
+)
+
+func Test_Unification_Merge_Mode(t *testing.T) {
+
+	orgHandle := fmt.Sprintf("carbon.super-mergemode-%d", time.Now().UnixNano())
+	attribute := func(name string, multiValued bool) profileSchema.ProfileSchemaAttribute {
+		return profileSchema.ProfileSchemaAttribute{
+			OrgId:         orgHandle,
+			AttributeId:   uuid.New().String(),
+			AttributeName: name,
+			ValueType:     constants.StringDataType,
+			MergeStrategy: "overwrite",
+			Mutability:    constants.MutabilityReadWrite,
+			MultiValued:   multiValued,
+		}
+	}
+	traitAttributes := []profileSchema.ProfileSchemaAttribute{
+		attribute("traits.city", false),
+		attribute("traits.interests", true),
+	}
+	schemaSvc := schemaService.GetProfileSchemaService()
+	_, err := schemaSvc.AddProfileSchemaAttributesForScope(traitAttributes, constants.Traits, orgHandle)
+	require.NoError(t, err)
+	_, err = schemaSvc.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{
+		attribute("identity_attributes.device_id", false),
+	}, constants.IdentityAttributes, orgHandle)
+	require.NoError(t, err)
+
+	ruleSvc := service.GetUnificationRuleService()
+	newRule := func(name, property, mergeMode string, priority int) model.UnificationRule {
+		now := time.Now().UTC()
+		return model.UnificationRule{
+			RuleId:       uuid.New().String(),
+			OrgHandle:    orgHandle,
+			RuleName:     name,
+			PropertyName: property,
+			Priority:     priority,
+			IsActive:     true,
+			MergeMode:    mergeMode,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+	}
+
+	t.Run("Merge_mode_round_trips", func(t *testing.T) {
+		deviceRule := newRule("Device link", "identity_attributes.device_id", constants.MergeModeLinkOnly, 1)
+		require.NoError(t, ruleSvc.AddUnificationRule(deviceRule, orgHandle))
+		fetched, err := ruleSvc.GetUnificationRule(deviceRule.RuleId, orgHandle)
+		require.NoError(t, err)
+		require.Equal(t, constants.MergeModeLinkOnly, fetched.MergeMode)
+
+		fetched.MergeMode = constants.MergeModeTraitUnion
+		require.NoError(t, ruleSvc.PatchUnificationRule(deviceRule.RuleId, orgHandle, *fetched))
+		fetched, err = ruleSvc.GetUnificationRule(deviceRule.RuleId, orgHandle)
+		require.NoError(t, err)
+		require.Equal(t, constants.MergeModeTraitUnion, fetched.MergeMode)
+
+		fetched.MergeMode = "partial"
+		err = ruleSvc.PatchUnificationRule(deviceRule.RuleId, orgHandle, *fetched)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+
+		cityRule := newRule("City", "traits.city", "", 2)
+		require.NoError(t, ruleSvc.AddUnificationRule(cityRule, orgHandle))
+		fetched, err = ruleSvc.GetUnificationRule(cityRule.RuleId, orgHandle)
+		require.NoError(t, err)
+		require.Equal(t, constants.MergeModeFull, fetched.MergeMode, "Rules merge fully by default")
+		require.NoError(t, ruleSvc.DeleteUnificationRule(cityRule.RuleId, orgHandle))
+	})
+
+	t.Run("Merge_engine_honors_merge_mode", func(t *testing.T) {
+		master := profileModel.Profile{
+			ProfileId: uuid.NewString(),
+			OrgHandle: orgHandle,
+			Traits:    map[string]interface{}{"city": "Colombo", "interests": []interface{}{"music"}},
+		}
+		incoming := profileModel.Profile{
+			ProfileId: uuid.NewString(),
+			OrgHandle: orgHandle,
+			Traits:    map[string]interface{}{"city": "Kandy", "interests": []interface{}{"travel"}},
+		}
+		clone := func(profile profileModel.Profile) profileModel.Profile {
+			profile.Traits = map[string]interface{}{"city": profile.Traits["city"],
+				"interests": profile.Traits["interests"]}
+			return profile
+		}
+
+		full := workers.MergeProfilesByMode(clone(master), clone(incoming), traitAttributes, constants.MergeModeFull)
+		require.Equal(t, "Kandy", full.Traits["city"], "A full merge follows the schema merge strategies")
+
+		union := workers.MergeProfilesByMode(clone(master), clone(incoming), traitAttributes,
+			constants.MergeModeTraitUnion)
+		require.Equal(t, "Colombo", union.Traits["city"], "A trait union keeps the values of the master")
+		require.ElementsMatch(t, []string{"music", "travel"}, union.Traits["interests"])
+
+		linked := workers.MergeProfilesByMode(clone(master), clone(incoming), traitAttributes,
+			constants.MergeModeLinkOnly)
+		require.Nil(t, linked.Traits, "A link only merge combines no data")
+	})
+
+	t.Run("Link_only_rule_links_profiles", func(t *testing.T) {
+		profileSvc := profileService.GetProfilesService()
+		rule, err := ruleSvc.GetUnificationRuleByName("Device link", orgHandle)
+		require.NoError(t, err)
+		rule.MergeMode = constants.MergeModeLinkOnly
+		require.NoError(t, ruleSvc.PatchUnificationRule(rule.RuleId, orgHandle, *rule))
+
+		newProfile := func(city string) string {
+			profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{
+				IdentityAttributes: map[string]interface{}{"device_id": "device-1"},
+				Traits:             map[string]interface{}{"city": city},
+			}, orgHandle)
+			require.NoError(t, err)
+			return profile.ProfileId
+		}
+		first, second := newProfile("Colombo"), newProfile("Kandy")
+
+		merged, err := profileSvc.ApplyUnificationRule(rule.RuleId, orgHandle)
+		require.NoError(t, err)
+		require.Equal(t, 2, merged)
+
+		child, err := profileStore.GetProfile(first)
+		require.NoError(t, err)
+		require.Equal(t, "Colombo", child.Traits["city"], "Linked profiles keep their own data")
+		masterProfile, err := profileStore.GetProfile(child.ProfileStatus.ReferenceProfileId)
+		require.NoError(t, err)
+		require.Empty(t, masterProfile.Traits, "No data is combined into the master of linked profiles")
+		sibling, err := profileStore.GetProfile(second)
+		require.NoError(t, err)
+		require.Equal(t, masterProfile.ProfileId, sibling.ProfileStatus.ReferenceProfileId)
+
+		// Reads do not fold the traits of linked profiles into the master either
+		resolved, err := profileSvc.GetProfile(masterProfile.ProfileId, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Empty(t, resolved.Traits)
+		require.Len(t, resolved.MergedFrom, 2)
+		listed, _, err := profileSvc.GetAllProfilesCursor(orgHandle, false, 10, nil, profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		require.Equal(t, masterProfile.ProfileId, listed[0].ProfileId)
+		require.Empty(t, listed[0].Traits)
+	})
+}
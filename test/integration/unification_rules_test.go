@@ -1,19 +1,27 @@
 package integration
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
 	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	"github.com/wso2/identity-customer-data-service/internal/system/database/provider"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
 	"github.com/wso2/identity-customer-data-service/test/integration/utils"
 
 	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
 	profileSchema "github.com/wso2/identity-customer-data-service/internal/profile_schema/model"
 	schemaService "github.com/wso2/identity-customer-data-service/internal/profile_schema/service"
 	"github.com/wso2/identity-customer-data-service/internal/unification_rules/model"
 	"github.com/wso2/identity-customer-data-service/internal/unification_rules/service"
+	unificationStore "github.com/wso2/identity-customer-data-service/internal/unification_rules/store"
 )
 
 func Test_UnificationRule(t *testing.T) {
@@ -58,6 +66,43 @@ func Test_UnificationRule(t *testing.T) {
 		require.NoError(t, err, "Failed to add unification rule")
 	})
 
+	t.Run("Rule_is_not_visible_to_other_organizations", func(t *testing.T) {
+		otherOrg := SuperTenantOrg + "-other"
+		_, getErr := unificationRuleService.GetUnificationRule(rule.RuleId, otherOrg)
+		for _, err := range []error{
+			getErr,
+			unificationRuleService.DeactivateUnificationRule(rule.RuleId, otherOrg),
+			unificationRuleService.DeleteUnificationRule(rule.RuleId, otherOrg),
+		} {
+			var clientErr *errors2.ClientError
+			require.ErrorAs(t, err, &clientErr)
+			require.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+		}
+		fetched, err := unificationRuleService.GetUnificationRule(rule.RuleId, SuperTenantOrg)
+		require.NoError(t, err)
+		require.True(t, fetched.IsActive)
+	})
+
+	t.Run("Reject_duplicate_rule_name", func(t *testing.T) {
+		duplicate := rule
+		duplicate.RuleId = uuid.New().String()
+		duplicate.Priority = 5
+		err := unificationRuleService.AddUnificationRule(duplicate, SuperTenantOrg)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusConflict, clientErr.StatusCode)
+
+		existing, err := unificationRuleService.GetUnificationRuleByName(rule.RuleName, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Equal(t, rule.RuleId, existing.RuleId)
+
+		// The unique constraint is mapped to a conflict when the service checks are bypassed
+		duplicate.PropertyId = existing.PropertyId
+		err = unificationStore.AddUnificationRule(duplicate, SuperTenantOrg)
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusConflict, clientErr.StatusCode)
+	})
+
 	t.Run("Reject_complex_attribute_in_unification_rule", func(t *testing.T) {
 		//  Add a valid sub-attribute
 		subAttr := profileSchema.ProfileSchemaAttribute{
@@ -112,27 +157,342 @@ func Test_UnificationRule(t *testing.T) {
 		require.NotEmpty(t, rules, "Unification rule list is empty")
 	})
 
+	t.Run("Sequential_store_calls_reuse_connection_pool", func(t *testing.T) {
+		// Connect through the pool the provider opens in production instead of the one set for the tests. With a
+		// single connection in the pool, clients sharing it are served by the same database backend.
+		conf := config.GetCDSRuntime().Config
+		production := conf
+		production.DataSource.Hostname = testPostgres.Host
+		production.DataSource.Port = testPostgres.Port
+		production.DataSource.Name = "testdb"
+		production.DataSource.Username = "testuser"
+		production.DataSource.Password = "testpass"
+		production.DataSource.SSLMode = "disable"
+		production.DataSource.MaxOpenConns = 1
+		production.DataSource.MaxIdleConns = 1
+		config.OverrideCDSRuntime(production)
+		provider.SetTestDB(nil)
+		defer func() {
+			require.NoError(t, provider.CloseDB())
+			provider.SetTestDB(testPostgres.DB)
+			config.OverrideCDSRuntime(conf)
+		}()
+
+		backendPid := func() int64 {
+			dbClient, err := provider.NewDBProvider().GetDBClient()
+			require.NoError(t, err)
+			defer dbClient.Close()
+			rows, err := dbClient.ExecuteQuery(`SELECT pg_backend_pid() AS pid`)
+			require.NoError(t, err)
+			require.Len(t, rows, 1)
+			return rows[0]["pid"].(int64)
+		}
+
+		_, err := unificationStore.GetUnificationRules(SuperTenantOrg)
+		require.NoError(t, err, "First store call failed")
+		_, err = unificationStore.GetUnificationRules(SuperTenantOrg)
+		require.NoError(t, err, "Second store call failed after the first client was closed")
+		require.Equal(t, backendPid(), backendPid(), "Closing a client should keep the shared pool open")
+		stats, opened := provider.PoolStats()
+		require.True(t, opened)
+		require.Equal(t, 1, stats.OpenConnections)
+	})
+
 	t.Run("Update_unification_rule", func(t *testing.T) {
 		rule.IsActive = false // reflect change in local object
 		err := unificationRuleService.PatchUnificationRule(rule.RuleId, SuperTenantOrg, rule)
 		require.NoError(t, err, "Failed to patch unification rule")
 
-		updated, err := unificationRuleService.GetUnificationRule(rule.RuleId)
+		updated, err := unificationRuleService.GetUnificationRule(rule.RuleId, SuperTenantOrg)
 		require.NoError(t, err, "Failed to fetch updated rule")
 		require.False(t, updated.IsActive, "Expected is_active to be false")
 	})
 
 	t.Run("Delete_unification_rule", func(t *testing.T) {
-		err := unificationRuleService.DeleteUnificationRule(rule.RuleId)
+		err := unificationRuleService.DeleteUnificationRule(rule.RuleId, SuperTenantOrg)
 		require.NoError(t, err, "Failed to delete unification rule")
 	})
 
+	t.Run("Preview_unification_rule", func(t *testing.T) {
+		profileSvc := profileService.GetProfilesService()
+		ids := make(map[string]string)
+		for name, req := range map[string]profileModel.ProfileRequest{
+			"permanent": {UserId: "preview-user", IdentityAttributes: map[string]interface{}{"email": "shared@wso2.com"}},
+			"temporary": {IdentityAttributes: map[string]interface{}{"email": "shared@wso2.com"}},
+			"anon1":     {IdentityAttributes: map[string]interface{}{"email": "anon@wso2.com"}},
+			"anon2":     {IdentityAttributes: map[string]interface{}{"email": "anon@wso2.com"}},
+			"unique":    {IdentityAttributes: map[string]interface{}{"email": "unique@wso2.com"}},
+		} {
+			profile, err := profileSvc.CreateProfile(req, SuperTenantOrg)
+			require.NoError(t, err)
+			ids[name] = profile.ProfileId
+		}
+
+		preview := model.UnificationRule{OrgHandle: SuperTenantOrg, PropertyName: "identity_attributes.email"}
+		candidates, err := unificationRuleService.PreviewUnificationRule(context.Background(), preview)
+		require.NoError(t, err, "Failed to preview unification rule")
+		require.Len(t, candidates, 2)
+
+		byValue := make(map[string]model.MergeCandidate)
+		for _, candidate := range candidates {
+			require.Len(t, candidate.PropertyValues, 1)
+			byValue[candidate.PropertyValues[0]] = candidate
+		}
+		require.Equal(t, ids["permanent"], byValue["shared@wso2.com"].MasterProfileId)
+		require.Equal(t, []string{ids["temporary"]}, byValue["shared@wso2.com"].ChildProfileIds)
+		require.Equal(t, 2, byValue["anon@wso2.com"].ProfileCount)
+		require.Empty(t, byValue["anon@wso2.com"].MasterProfileId)
+		require.Len(t, byValue["anon@wso2.com"].ChildProfileIds, 2)
+
+		// Previewing must not merge anything
+		profile, err := profileSvc.GetProfile(ids["temporary"], profileModel.AllApplications)
+		require.NoError(t, err)
+		require.Empty(t, profile.MergedFrom)
+
+		_, err = unificationRuleService.PreviewUnificationRule(context.Background(), model.UnificationRule{
+			OrgHandle: SuperTenantOrg, PropertyName: "traits.unknown"})
+		require.Error(t, err)
+	})
+
+	t.Run("Add_unification_rules_in_batch", func(t *testing.T) {
+		_, err := profileSchemaService.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{
+			{
+				OrgId:         SuperTenantOrg,
+				AttributeName: "identity_attributes.phone_number",
+				AttributeId:   uuid.New().String(),
+				ValueType:     constants.StringDataType,
+				MergeStrategy: "combine",
+				Mutability:    constants.MutabilityReadWrite,
+			},
+		}, constants.IdentityAttributes, SuperTenantOrg)
+		require.NoError(t, err)
+
+		newRule := func(name, property string, priority int) model.UnificationRule {
+			return model.UnificationRule{
+				RuleId:       uuid.New().String(),
+				OrgHandle:    SuperTenantOrg,
+				RuleName:     name,
+				PropertyName: property,
+				Priority:     priority,
+				IsActive:     true,
+				CreatedAt:    time.Now().UTC(),
+				UpdatedAt:    time.Now().UTC(),
+			}
+		}
+
+		// A duplicate name rejects the whole batch
+		err = unificationRuleService.AddUnificationRules([]model.UnificationRule{
+			newRule("Batch email", "identity_attributes.email", 10),
+			newRule("Batch email", "identity_attributes.phone_number", 11),
+		}, SuperTenantOrg)
+		require.Error(t, err)
+		rules, err := unificationRuleService.GetUnificationRules(SuperTenantOrg)
+		require.NoError(t, err)
+		require.Empty(t, rules, "No rule of a rejected batch should be added")
+
+		batch := []model.UnificationRule{
+			newRule("Batch email", "identity_attributes.email", 10),
+			newRule("Batch phone", "identity_attributes.phone_number", 11),
+		}
+		require.NoError(t, unificationRuleService.AddUnificationRules(batch, SuperTenantOrg))
+		rules, err = unificationRuleService.GetUnificationRules(SuperTenantOrg)
+		require.NoError(t, err)
+		require.Len(t, rules, 2)
+		for _, added := range batch {
+			fetched, err := unificationRuleService.GetUnificationRule(added.RuleId, SuperTenantOrg)
+			require.NoError(t, err)
+			require.Equal(t, added.RuleName, fetched.RuleName)
+			require.NotEmpty(t, fetched.PropertyId)
+		}
+	})
+
+	t.Run("Validate_and_reorder_priorities", func(t *testing.T) {
+		rules, err := unificationRuleService.GetUnificationRules(SuperTenantOrg)
+		require.NoError(t, err)
+		require.Len(t, rules, 2)
+		require.Less(t, rules[0].Priority, rules[1].Priority, "Rules should be listed by priority")
+
+		invalid := rules[0]
+		invalid.Priority = 0
+		err = unificationRuleService.PatchUnificationRule(invalid.RuleId, SuperTenantOrg, invalid)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+
+		clash := rules[0]
+		clash.Priority = rules[1].Priority
+		require.Error(t, unificationRuleService.PatchUnificationRule(clash.RuleId, SuperTenantOrg, clash),
+			"Active rules should not share a priority")
+		clash.IsActive = false
+		require.NoError(t, unificationRuleService.PatchUnificationRule(clash.RuleId, SuperTenantOrg, clash),
+			"An inactive rule may share the priority of an active rule")
+
+		err = unificationRuleService.ReorderUnificationRules(SuperTenantOrg, []string{rules[1].RuleId})
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+
+		require.NoError(t, unificationRuleService.ReorderUnificationRules(SuperTenantOrg,
+			[]string{rules[1].RuleId, rules[0].RuleId}))
+		first, err := unificationRuleService.GetUnificationRule(rules[1].RuleId, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Equal(t, 1, first.Priority)
+		second, err := unificationRuleService.GetUnificationRule(rules[0].RuleId, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Equal(t, 2, second.Priority)
+	})
+
+	t.Run("Activate_and_deactivate_unification_rule", func(t *testing.T) {
+		rules, err := unificationRuleService.GetUnificationRules(SuperTenantOrg)
+		require.NoError(t, err)
+		require.Len(t, rules, 2)
+
+		for _, rule := range rules {
+			require.NoError(t, unificationRuleService.ActivateUnificationRule(rule.RuleId, SuperTenantOrg))
+		}
+		active, err := unificationRuleService.GetActiveUnificationRules(SuperTenantOrg)
+		require.NoError(t, err)
+		require.Len(t, active, 2)
+		require.Less(t, active[0].Priority, active[1].Priority)
+
+		require.NoError(t, unificationRuleService.DeactivateUnificationRule(rules[0].RuleId, SuperTenantOrg))
+		active, err = unificationRuleService.GetActiveUnificationRules(SuperTenantOrg)
+		require.NoError(t, err)
+		require.Len(t, active, 1)
+		require.Equal(t, rules[1].RuleId, active[0].RuleId)
+		require.NoError(t, unificationRuleService.ActivateUnificationRule(rules[0].RuleId, SuperTenantOrg))
+
+		err = unificationRuleService.DeactivateUnificationRule(uuid.New().String(), SuperTenantOrg)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+	})
+
+	t.Run("Update_single_fields_of_unification_rule", func(t *testing.T) {
+		emailRule, err := unificationRuleService.GetUnificationRuleByName("Batch email", SuperTenantOrg)
+		require.NoError(t, err)
+		phoneRule, err := unificationRuleService.GetUnificationRuleByName("Batch phone", SuperTenantOrg)
+		require.NoError(t, err)
+		requireClientError := func(t *testing.T, err error, status int) {
+			var clientErr *errors2.ClientError
+			require.ErrorAs(t, err, &clientErr)
+			require.Equal(t, status, clientErr.StatusCode)
+		}
+
+		// Priority
+		requireClientError(t, unificationRuleService.SetRulePriority(emailRule.RuleId, SuperTenantOrg, 0),
+			http.StatusBadRequest)
+		requireClientError(t, unificationRuleService.SetRulePriority(emailRule.RuleId, SuperTenantOrg,
+			phoneRule.Priority), http.StatusBadRequest)
+		require.NoError(t, unificationRuleService.SetRulePriority(emailRule.RuleId, SuperTenantOrg, 7))
+		updated, err := unificationRuleService.GetUnificationRule(emailRule.RuleId, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Equal(t, 7, updated.Priority)
+		require.Equal(t, emailRule.RuleName, updated.RuleName)
+		require.True(t, updated.IsActive)
+
+		// Name
+		requireClientError(t, unificationRuleService.RenameRule(emailRule.RuleId, SuperTenantOrg, "  "),
+			http.StatusBadRequest)
+		requireClientError(t, unificationRuleService.RenameRule(emailRule.RuleId, SuperTenantOrg, phoneRule.RuleName),
+			http.StatusConflict)
+		require.NoError(t, unificationRuleService.RenameRule(emailRule.RuleId, SuperTenantOrg, "Renamed email"))
+		updated, err = unificationRuleService.GetUnificationRule(emailRule.RuleId, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Equal(t, "Renamed email", updated.RuleName)
+		require.Equal(t, 7, updated.Priority)
+
+		// Property
+		requireClientError(t, unificationRuleService.SetRuleProperty(emailRule.RuleId, SuperTenantOrg,
+			phoneRule.PropertyName), http.StatusConflict)
+		requireClientError(t, unificationRuleService.SetRuleProperty(emailRule.RuleId, SuperTenantOrg,
+			"identity_attributes.not_in_schema"), http.StatusBadRequest)
+		requireClientError(t, unificationRuleService.SetRuleProperty(emailRule.RuleId, SuperTenantOrg, "user_id"),
+			http.StatusBadRequest)
+		_, err = profileSchemaService.AddProfileSchemaAttributesForScope([]profileSchema.ProfileSchemaAttribute{
+			{
+				OrgId:         SuperTenantOrg,
+				AttributeName: "identity_attributes.customer_number",
+				AttributeId:   uuid.New().String(),
+				ValueType:     constants.StringDataType,
+				MergeStrategy: "combine",
+				Mutability:    constants.MutabilityReadWrite,
+			},
+		}, constants.IdentityAttributes, SuperTenantOrg)
+		require.NoError(t, err)
+		require.NoError(t, unificationRuleService.SetRuleProperty(emailRule.RuleId, SuperTenantOrg,
+			"identity_attributes.customer_number"))
+		updated, err = unificationRuleService.GetUnificationRule(emailRule.RuleId, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Equal(t, "identity_attributes.customer_number", updated.PropertyName)
+		require.NotEqual(t, emailRule.PropertyId, updated.PropertyId)
+
+		requireClientError(t, unificationRuleService.SetRulePriority(uuid.New().String(), SuperTenantOrg, 9),
+			http.StatusNotFound)
+
+		// Restore the rule for the following tests
+		require.NoError(t, unificationRuleService.SetRuleProperty(emailRule.RuleId, SuperTenantOrg,
+			emailRule.PropertyName))
+		require.NoError(t, unificationRuleService.RenameRule(emailRule.RuleId, SuperTenantOrg, emailRule.RuleName))
+		require.NoError(t, unificationRuleService.SetRulePriority(emailRule.RuleId, SuperTenantOrg,
+			emailRule.Priority))
+		restored, err := unificationRuleService.GetUnificationRule(emailRule.RuleId, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Equal(t, emailRule.PropertyId, restored.PropertyId)
+	})
+
+	t.Run("Apply_unification_rule_to_existing_profiles", func(t *testing.T) {
+		profileSvc := profileService.GetProfilesService()
+		emailRule, err := unificationRuleService.GetUnificationRuleByName("Batch email", SuperTenantOrg)
+		require.NoError(t, err)
+		require.NotNil(t, emailRule)
+
+		require.NoError(t, unificationRuleService.DeactivateUnificationRule(emailRule.RuleId, SuperTenantOrg))
+		_, err = profileSvc.ApplyUnificationRule(emailRule.RuleId, SuperTenantOrg)
+		var clientErr *errors2.ClientError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode, "An inactive rule should not be applied")
+		require.NoError(t, unificationRuleService.ActivateUnificationRule(emailRule.RuleId, SuperTenantOrg))
+
+		// The profiles of the preview were added before the rule, so they are only merged once it is applied
+		candidates, err := unificationRuleService.PreviewUnificationRule(context.Background(), *emailRule)
+		require.NoError(t, err)
+		require.Len(t, candidates, 2)
+		mergedCount, err := profileSvc.ApplyUnificationRule(emailRule.RuleId, SuperTenantOrg)
+		require.NoError(t, err, "Failed to apply unification rule")
+		require.Equal(t, 3, mergedCount)
+
+		for _, candidate := range candidates {
+			var masterId string
+			for _, childId := range candidate.ChildProfileIds {
+				child, err := profileSvc.GetProfile(childId, profileModel.AllApplications)
+				require.NoError(t, err)
+				require.NotNil(t, child.MergedTo, "Profile %s should be merged", childId)
+				require.Equal(t, emailRule.RuleName, child.MergedTo.Reason)
+				if candidate.MasterProfileId != "" {
+					require.Equal(t, candidate.MasterProfileId, child.MergedTo.ProfileId)
+				}
+				if masterId != "" {
+					require.Equal(t, masterId, child.MergedTo.ProfileId, "A group should share one master")
+				}
+				masterId = child.MergedTo.ProfileId
+			}
+		}
+
+		mergedCount, err = profileSvc.ApplyUnificationRule(emailRule.RuleId, SuperTenantOrg)
+		require.NoError(t, err)
+		require.Zero(t, mergedCount, "Applying a rule again should not merge anything")
+
+		_, err = profileSvc.ApplyUnificationRule(uuid.New().String(), SuperTenantOrg)
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+	})
+
 	// Todo : Add cases for each unification rule and ensure they are functioning correct
 
 	t.Cleanup(func() {
 		rules, _ := unificationRuleService.GetUnificationRules(SuperTenantOrg)
 		for _, r := range rules {
-			_ = unificationRuleService.DeleteUnificationRule(r.RuleId)
+			_ = unificationRuleService.DeleteUnificationRule(r.RuleId, SuperTenantOrg)
 		}
 		_ = profileSchemaService.DeleteProfileSchema(SuperTenantOrg)
 		_ = profileSchemaService.DeleteProfileSchemaAttributesByScope(SuperTenantOrg, constants.IdentityAttributes)
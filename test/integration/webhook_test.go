@@ -0,0 +1,297 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (http://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	profileModel "github.com/wso2/identity-customer-data-service/internal/profile/model"
+	profileService "github.com/wso2/identity-customer-data-service/internal/profile/service"
+	"github.com/wso2/identity-customer-data-service/internal/system/config"
+	"github.com/wso2/identity-customer-data-service/internal/system/constants"
+	errors2 "github.com/wso2/identity-customer-data-service/internal/system/errors"
+	"github.com/wso2/identity-customer-data-service/internal/system/metrics"
+	webhookModel "github.com/wso2/identity-customer-data-service/internal/webhook/model"
+	webhookService "github.com/wso2/identity-customer-data-service/internal/webhook/service"
+)
+
+// webhookReceiver records the events posted to it, answering with the given status.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	attempts int
+	events   []webhookModel.WebhookEvent
+	headers  []http.Header
+	bodies   [][]byte
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	body, _ := io.ReadAll(r.Body)
+	var event webhookModel.WebhookEvent
+	_ = json.Unmarshal(body, &event)
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.attempts++
+	rcv.events = append(rcv.events, event)
+	rcv.headers = append(rcv.headers, r.Header.Clone())
+	rcv.bodies = append(rcv.bodies, body)
+	w.WriteHeader(rcv.status)
+}
+
+// find returns the index of the first event of the type that matches, or -1.
+func (rcv *webhookReceiver) find(eventType string, matches func(webhookModel.WebhookEventData) bool) int {
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	for i, event := range rcv.events {
+		if event.EventType == eventType && matches(event.Data) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (rcv *webhookReceiver) attemptCount() int {
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return rcv.attempts
+}
+
+func Test_Webhooks(t *testing.T) {
+
+	SuperTenantOrg := fmt.Sprintf("carbon.super-webhooks-%d", time.Now().UnixNano())
+	svc := webhookService.GetWebhookService()
+	profileSvc := profileService.GetProfilesService()
+
+	conf := config.GetCDSRuntime().Config
+	fastRetries := conf
+	// The receivers of the tests listen on the loopback address
+	fastRetries.Webhooks = config.WebhookConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond, Timeout: time.Second, AllowPrivateNetworks: true}
+	publicOnly := fastRetries
+	publicOnly.Webhooks.AllowPrivateNetworks = false
+	config.OverrideCDSRuntime(fastRetries)
+	defer config.OverrideCDSRuntime(conf)
+
+	t.Run("Manage_webhooks", func(t *testing.T) {
+		var clientErr *errors2.ClientError
+		_, err := svc.AddWebhook(SuperTenantOrg, webhookModel.WebhookRequest{Url: "ftp://example.com",
+			Events: []string{constants.WebhookEventProfileCreated}})
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+		_, err = svc.AddWebhook(SuperTenantOrg, webhookModel.WebhookRequest{Url: "https://example.com/hook",
+			Events: []string{"profile.updated"}})
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+
+		created, err := svc.AddWebhook(SuperTenantOrg, webhookModel.WebhookRequest{Url: "https://example.com/hook",
+			Events: []string{constants.WebhookEventProfileCreated, constants.WebhookEventProfileCreated}})
+		require.NoError(t, err)
+		require.NotEmpty(t, created.Secret, "A secret is generated and returned on creation")
+		require.Equal(t, []string{constants.WebhookEventProfileCreated}, created.Events)
+		require.True(t, created.IsActive)
+
+		fetched, err := svc.GetWebhook(SuperTenantOrg, created.WebhookId)
+		require.NoError(t, err)
+		require.Empty(t, fetched.Secret, "The secret is not returned once created")
+		require.Equal(t, created.Url, fetched.Url)
+
+		inactive := false
+		updated, err := svc.UpdateWebhook(SuperTenantOrg, created.WebhookId, webhookModel.WebhookRequest{
+			Url: "https://example.com/other", Events: []string{constants.WebhookEventProfileDeleted},
+			IsActive: &inactive})
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com/other", updated.Url)
+		require.False(t, updated.IsActive)
+
+		webhooks, err := svc.GetWebhooks(SuperTenantOrg)
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+
+		_, err = svc.GetWebhook("other-org", created.WebhookId)
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+
+		require.NoError(t, svc.DeleteWebhook(SuperTenantOrg, created.WebhookId))
+		err = svc.DeleteWebhook(SuperTenantOrg, created.WebhookId)
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, http.StatusNotFound, clientErr.StatusCode)
+	})
+
+	t.Run("Profile_events_are_delivered_signed", func(t *testing.T) {
+		receiver := &webhookReceiver{status: http.StatusOK}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+		webhook, err := svc.AddWebhook(SuperTenantOrg, webhookModel.WebhookRequest{Url: server.URL,
+			Events: []string{constants.WebhookEventProfileCreated, constants.WebhookEventProfileMerged,
+				constants.WebhookEventProfileDeleted}})
+		require.NoError(t, err)
+		defer func() { _ = svc.DeleteWebhook(SuperTenantOrg, webhook.WebhookId) }()
+
+		master, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, SuperTenantOrg)
+		require.NoError(t, err)
+		child, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, SuperTenantOrg)
+		require.NoError(t, err)
+
+		var created int
+		require.Eventually(t, func() bool {
+			created = receiver.find(constants.WebhookEventProfileCreated, func(data webhookModel.WebhookEventData) bool {
+				return data.ProfileId == master.ProfileId
+			})
+			return created >= 0
+		}, 5*time.Second, 20*time.Millisecond)
+
+		receiver.mu.Lock()
+		header, body := receiver.headers[created], receiver.bodies[created]
+		receiver.mu.Unlock()
+		require.Equal(t, constants.WebhookEventProfileCreated, header.Get(constants.WebhookEventHeader))
+		require.Equal(t, webhookService.SignWebhookPayload(webhook.Secret,
+			header.Get(constants.WebhookTimestampHeader), body), header.Get(constants.WebhookSignatureHeader))
+
+		require.NoError(t, profileSvc.MergeProfiles(master.ProfileId, child.ProfileId))
+		require.Eventually(t, func() bool {
+			return receiver.find(constants.WebhookEventProfileMerged, func(data webhookModel.WebhookEventData) bool {
+				return data.MasterProfileId == master.ProfileId && len(data.ChildProfileIds) == 1 &&
+					data.ChildProfileIds[0] == child.ProfileId && data.Reason == constants.ManualMergeReason
+			}) >= 0
+		}, 5*time.Second, 20*time.Millisecond)
+
+		require.NoError(t, profileSvc.DeleteProfile(master.ProfileId))
+		require.Eventually(t, func() bool {
+			return receiver.find(constants.WebhookEventProfileDeleted, func(data webhookModel.WebhookEventData) bool {
+				return data.ProfileId == master.ProfileId
+			}) >= 0
+		}, 5*time.Second, 20*time.Millisecond)
+	})
+
+	t.Run("Failing_deliveries_are_retried_then_dead_lettered", func(t *testing.T) {
+		failing := &webhookReceiver{status: http.StatusServiceUnavailable}
+		failingServer := httptest.NewServer(failing)
+		defer failingServer.Close()
+		rejecting := &webhookReceiver{status: http.StatusGone}
+		rejectingServer := httptest.NewServer(rejecting)
+		defer rejectingServer.Close()
+		for _, url := range []string{failingServer.URL, rejectingServer.URL} {
+			webhook, err := svc.AddWebhook(SuperTenantOrg, webhookModel.WebhookRequest{Url: url,
+				Events: []string{constants.WebhookEventProfileCreated}})
+			require.NoError(t, err)
+			defer func() { _ = svc.DeleteWebhook(SuperTenantOrg, webhook.WebhookId) }()
+		}
+		deadLetters := metrics.WebhookDeliveries.Value(constants.WebhookEventProfileCreated, "dead_letter")
+
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, SuperTenantOrg)
+		require.NoError(t, err)
+		defer func() { _ = profileSvc.DeleteProfile(profile.ProfileId) }()
+
+		require.Eventually(t, func() bool {
+			return metrics.WebhookDeliveries.Value(constants.WebhookEventProfileCreated, "dead_letter") == deadLetters+2
+		}, 5*time.Second, 20*time.Millisecond)
+		require.Equal(t, 3, failing.attemptCount(), "Server errors are retried up to the max attempts")
+		require.Equal(t, 1, rejecting.attemptCount(), "Client errors other than throttling are not retried")
+	})
+
+	t.Run("Urls_of_non_public_addresses_are_rejected", func(t *testing.T) {
+		config.OverrideCDSRuntime(publicOnly)
+		defer config.OverrideCDSRuntime(fastRetries)
+
+		for _, url := range []string{"http://127.0.0.1:8080/hook", "http://localhost/hook",
+			"http://169.254.169.254/latest/meta-data", "http://10.0.0.5/hook", "http://192.168.1.10/hook",
+			"http://[::1]/hook", "http://[::ffff:127.0.0.1]/hook", "http://0.0.0.0/hook"} {
+			_, err := svc.AddWebhook(SuperTenantOrg, webhookModel.WebhookRequest{Url: url,
+				Events: []string{constants.WebhookEventProfileCreated}})
+			var clientErr *errors2.ClientError
+			require.ErrorAs(t, err, &clientErr, url)
+			require.Equal(t, http.StatusBadRequest, clientErr.StatusCode, url)
+		}
+	})
+
+	t.Run("Deliveries_to_non_public_addresses_are_blocked_when_connecting", func(t *testing.T) {
+		receiver := &webhookReceiver{status: http.StatusOK}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+		webhook, err := svc.AddWebhook(SuperTenantOrg, webhookModel.WebhookRequest{Url: server.URL,
+			Events: []string{constants.WebhookEventProfileCreated}})
+		require.NoError(t, err)
+		defer func() { _ = svc.DeleteWebhook(SuperTenantOrg, webhook.WebhookId) }()
+
+		// The host now resolving to a private address is the same as private networks no longer being allowed
+		config.OverrideCDSRuntime(publicOnly)
+		defer config.OverrideCDSRuntime(fastRetries)
+		deadLetters := metrics.WebhookDeliveries.Value(constants.WebhookEventProfileCreated, "dead_letter")
+
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, SuperTenantOrg)
+		require.NoError(t, err)
+		defer func() { _ = profileSvc.DeleteProfile(profile.ProfileId) }()
+
+		require.Eventually(t, func() bool {
+			return metrics.WebhookDeliveries.Value(constants.WebhookEventProfileCreated, "dead_letter") == deadLetters+1
+		}, 5*time.Second, 20*time.Millisecond)
+		require.Zero(t, receiver.attemptCount())
+	})
+
+	t.Run("Redirects_are_not_followed", func(t *testing.T) {
+		target := &webhookReceiver{status: http.StatusOK}
+		targetServer := httptest.NewServer(target)
+		defer targetServer.Close()
+		redirecting := httptest.NewServer(http.RedirectHandler(targetServer.URL, http.StatusTemporaryRedirect))
+		defer redirecting.Close()
+		webhook, err := svc.AddWebhook(SuperTenantOrg, webhookModel.WebhookRequest{Url: redirecting.URL,
+			Events: []string{constants.WebhookEventProfileCreated}})
+		require.NoError(t, err)
+		defer func() { _ = svc.DeleteWebhook(SuperTenantOrg, webhook.WebhookId) }()
+		deadLetters := metrics.WebhookDeliveries.Value(constants.WebhookEventProfileCreated, "dead_letter")
+
+		profile, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, SuperTenantOrg)
+		require.NoError(t, err)
+		defer func() { _ = profileSvc.DeleteProfile(profile.ProfileId) }()
+
+		require.Eventually(t, func() bool {
+			return metrics.WebhookDeliveries.Value(constants.WebhookEventProfileCreated, "dead_letter") == deadLetters+1
+		}, 5*time.Second, 20*time.Millisecond)
+		require.Zero(t, target.attemptCount(), "The redirect must not be followed")
+	})
+
+	t.Run("Queued_events_are_delivered_when_the_dispatcher_stops", func(t *testing.T) {
+		receiver := &webhookReceiver{status: http.StatusOK}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+		webhook, err := svc.AddWebhook(SuperTenantOrg, webhookModel.WebhookRequest{Url: server.URL,
+			Events: []string{constants.WebhookEventProfileCreated}})
+		require.NoError(t, err)
+		defer func() { _ = svc.DeleteWebhook(SuperTenantOrg, webhook.WebhookId) }()
+
+		const profiles = 10
+		for i := 0; i < profiles; i++ {
+			_, err := profileSvc.CreateProfile(profileModel.ProfileRequest{}, SuperTenantOrg)
+			require.NoError(t, err)
+		}
+		require.NoError(t, webhookService.StopDispatcher(t.Context()))
+		require.Equal(t, profiles, receiver.attemptCount(), "Stopping delivers the queued events")
+	})
+}
@@ -32,6 +32,9 @@ import (
 type TestPostgres struct {
 	Container testcontainers.Container
 	DB        *sql.DB
+	// Host and Port are where the database is reached, for tests opening connections of their own.
+	Host string
+	Port int
 }
 
 func SetupTestPostgres(ctx context.Context) (*TestPostgres, error) {
@@ -75,5 +78,7 @@ func SetupTestPostgres(ctx context.Context) (*TestPostgres, error) {
 	return &TestPostgres{
 		Container: container,
 		DB:        db,
+		Host:      host,
+		Port:      port.Int(),
 	}, nil
 }
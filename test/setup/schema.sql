@@ -29,9 +29,16 @@ CREATE TABLE profiles
     list_profile        BOOLEAN DEFAULT TRUE,
     delete_profile      BOOLEAN DEFAULT FALSE,
     traits              JSONB   DEFAULT '{}'::jsonb,
-    identity_attributes JSONB   DEFAULT '{}'::jsonb
+    identity_attributes JSONB   DEFAULT '{}'::jsonb,
+    deleted_at          TIMESTAMPTZ,
+    version             BIGINT  NOT NULL DEFAULT 1
 );
 
+CREATE INDEX idx_profiles_traits ON profiles USING GIN (traits);
+CREATE INDEX idx_profiles_identity_attributes ON profiles USING GIN (identity_attributes);
+CREATE INDEX idx_profiles_org_updated_at ON profiles (org_handle, updated_at, profile_id);
+CREATE INDEX idx_profiles_email_lower ON profiles (org_handle, LOWER((identity_attributes #>> '{email}')));
+
 CREATE TABLE profile_reference
 (
     profile_id                  VARCHAR(255) PRIMARY KEY,
@@ -39,7 +46,9 @@ CREATE TABLE profile_reference
     profile_status              VARCHAR(255),
     reference_profile_id        VARCHAR(255),
     reference_profile_org_handle VARCHAR(255),
-    reference_reason            VARCHAR(255)
+    reference_reason            VARCHAR(255),
+    matched_rule_id             VARCHAR(255),
+    matched_value               TEXT
 );
 
 CREATE TABLE profile_schema
@@ -56,7 +65,11 @@ CREATE TABLE profile_schema
     multi_valued           BOOLEAN DEFAULT FALSE,
     canonical_values       JSONB   DEFAULT '[]'::jsonb,
     sub_attributes         JSONB   DEFAULT '[]'::jsonb,
-    scim_dialect VARCHAR(255)
+    scim_dialect VARCHAR(255),
+    computation_expression TEXT    NOT NULL DEFAULT '',
+    is_pii                 BOOLEAN NOT NULL DEFAULT FALSE,
+    case_insensitive       BOOLEAN NOT NULL DEFAULT FALSE,
+    allowed_apps           JSONB   DEFAULT '[]'::jsonb
 );
 
 CREATE TABLE unification_rules
@@ -68,8 +81,12 @@ CREATE TABLE unification_rules
     property_id  VARCHAR(255) REFERENCES profile_schema(attribute_id) ON DELETE CASCADE,
     priority      INT          NOT NULL,
     is_active     BOOLEAN      NOT NULL,
+    -- Rules added before merge modes existed merge fully. Existing databases are upgraded with:
+    -- ALTER TABLE unification_rules ADD COLUMN merge_mode VARCHAR(32) NOT NULL DEFAULT 'full';
+    merge_mode    VARCHAR(32)  NOT NULL DEFAULT 'full',
     created_at    TIMESTAMPTZ  NOT NULL DEFAULT now(),
-    updated_at    TIMESTAMPTZ  NOT NULL DEFAULT now()
+    updated_at    TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    UNIQUE (org_handle, rule_name)
 );
 
 -- Application Data Table
@@ -118,3 +135,95 @@ CREATE TABLE cds_config (
     value VARCHAR(500),
     PRIMARY KEY (org_handle, config)
 );
+
+-- Profile import records held back from quarantined import sources
+CREATE TABLE profile_import_quarantine (
+    record_id      VARCHAR(255) PRIMARY KEY,
+    org_handle     VARCHAR(255) NOT NULL,
+    source         VARCHAR(255) NOT NULL,
+    line           INTEGER      NOT NULL,
+    payload        JSONB        NOT NULL,
+    reason         TEXT,
+    quarantined_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_profile_import_quarantine_source ON profile_import_quarantine (org_handle, source);
+
+-- Profile pairs that were unmerged and must not be unified again by the same rule
+CREATE TABLE profile_unmerge_exclusions (
+    profile_id          VARCHAR(255) NOT NULL,
+    excluded_profile_id VARCHAR(255) NOT NULL,
+    org_handle          VARCHAR(255) NOT NULL,
+    rule_name           VARCHAR(255) NOT NULL,
+    created_at          TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    PRIMARY KEY (profile_id, excluded_profile_id, rule_name)
+);
+
+CREATE INDEX idx_profile_unmerge_exclusions_excluded ON profile_unmerge_exclusions (excluded_profile_id);
+
+-- Idempotency keys of profile creations, mapped to the created profile until they expire. A key without a profile
+-- is held by a creation in progress and expires after the short claim lease instead.
+CREATE TABLE profile_idempotency_keys (
+    org_handle      VARCHAR(255) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    claim_token     VARCHAR(255) NOT NULL,
+    profile_id      VARCHAR(255),
+    created_at      TIMESTAMPTZ  NOT NULL,
+    expires_at      TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (org_handle, idempotency_key)
+);
+
+-- Webhooks notified of profile events of the organization
+CREATE TABLE webhooks (
+    webhook_id VARCHAR(255) PRIMARY KEY,
+    org_handle VARCHAR(255) NOT NULL,
+    url        TEXT         NOT NULL,
+    events     TEXT[]       NOT NULL,
+    secret     VARCHAR(255) NOT NULL,
+    is_active  BOOLEAN      NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_webhooks_org_handle ON webhooks (org_handle);
+
+-- Traits of the earlier versions of a profile, recorded when a change replaces them
+CREATE TABLE profile_trait_history (
+    profile_id  VARCHAR(255) NOT NULL REFERENCES profiles (profile_id) ON DELETE CASCADE,
+    version     BIGINT       NOT NULL,
+    traits      JSONB        NOT NULL DEFAULT '{}'::jsonb,
+    recorded_at TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (profile_id, version)
+);
+
+CREATE INDEX idx_profile_trait_history_recorded_at ON profile_trait_history (recorded_at);
+
+-- Long running maintenance jobs on the profiles of an organization, such as re-applying the unification rules.
+-- The cursor is where a resumed job continues from, and updated_at is the heartbeat of the instance running it.
+CREATE TABLE profile_jobs (
+    job_id           VARCHAR(255) PRIMARY KEY,
+    org_handle       VARCHAR(255) NOT NULL,
+    job_type         VARCHAR(50)  NOT NULL,
+    status           VARCHAR(50)  NOT NULL,
+    phase            VARCHAR(50)  NOT NULL,
+    processed        BIGINT       NOT NULL DEFAULT 0,
+    total            BIGINT       NOT NULL DEFAULT 0,
+    merged           BIGINT       NOT NULL DEFAULT 0,
+    job_cursor       TEXT         NOT NULL DEFAULT '',
+    cancel_requested BOOLEAN      NOT NULL DEFAULT false,
+    error            TEXT         NOT NULL DEFAULT '',
+    created_at       TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+-- At most one job of a type runs for an organization at a time
+CREATE UNIQUE INDEX idx_profile_jobs_running ON profile_jobs (org_handle, job_type) WHERE status = 'RUNNING';
+
+-- Casts a stored attribute to a timestamp for range filters, giving NULL instead of failing the query when the
+-- value is not a valid timestamp.
+CREATE OR REPLACE FUNCTION try_cast_timestamptz(value TEXT) RETURNS TIMESTAMPTZ AS $$
+BEGIN
+    RETURN value::timestamptz;
+EXCEPTION WHEN OTHERS THEN
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql STABLE;